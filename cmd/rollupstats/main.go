@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"yun-nian-memorial/internal/config"
+	"yun-nian-memorial/internal/database"
+	"yun-nian-memorial/internal/services"
+
+	"github.com/joho/godotenv"
+)
+
+// 祭扫统计汇总的回填和一致性校验
+//
+//	go run ./cmd/rollupstats -action backfill                 # 按原始记录重建全部汇总
+//	go run ./cmd/rollupstats -action backfill -memorial <id>  # 只重建某个纪念馆
+//	go run ./cmd/rollupstats -action check -days 30           # 校验最近30天，-days 0 校验全部历史
+//	go run ./cmd/rollupstats -action check -repair            # 校验并重建不一致的纪念馆和用户
+//
+// 重建期间新写入的祭扫记录可能被覆盖，建议在低峰期执行，完成后再运行一次校验
+func main() {
+	action := flag.String("action", "check", "操作类型: backfill(回填), check(一致性校验)")
+	memorialID := flag.String("memorial", "", "只处理指定纪念馆")
+	userID := flag.String("user", "", "只处理指定用户")
+	days := flag.Int("days", 30, "校验最近多少天的汇总，0 表示全部历史")
+	repair := flag.Bool("repair", false, "校验发现不一致时重建相应的纪念馆和用户汇总")
+	verbose := flag.Bool("verbose", false, "输出完整的校验报告(JSON)")
+	flag.Parse()
+
+	// 加载环境变量
+	if err := godotenv.Load(); err != nil {
+		log.Println("警告: 未找到.env文件，使用默认配置")
+	}
+
+	cfg := config.Load()
+
+	db, err := database.InitMySQL(cfg.Database.MySQL)
+	if err != nil {
+		log.Fatalf("连接数据库失败: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatalf("获取数据库连接失败: %v", err)
+	}
+	defer sqlDB.Close()
+
+	statsService := services.NewWorshipStatsService(db)
+
+	switch *action {
+	case "backfill":
+		start := time.Now()
+		switch {
+		case *memorialID != "":
+			if err := statsService.RebuildMemorialRollups(*memorialID); err != nil {
+				log.Fatalf("重建纪念馆 %s 汇总失败: %v", *memorialID, err)
+			}
+		case *userID != "":
+			if err := statsService.RebuildUserRollups(*userID); err != nil {
+				log.Fatalf("重建用户 %s 汇总失败: %v", *userID, err)
+			}
+		default:
+			var done, failed int
+			err := statsService.BackfillRollups(func(scope, id string, err error) {
+				done++
+				if err != nil {
+					failed++
+					log.Printf("重建%s %s 汇总失败: %v", scopeName(scope), id, err)
+				}
+				if done%100 == 0 {
+					log.Printf("已处理 %d 个纪念馆/用户", done)
+				}
+			})
+			if err != nil {
+				log.Fatalf("回填失败: %v", err)
+			}
+			if failed > 0 {
+				log.Fatalf("回填完成，%d 个纪念馆/用户失败，请重新执行", failed)
+			}
+		}
+		log.Printf("✅ 回填完成，耗时 %s", time.Since(start).Round(time.Second))
+
+	case "check":
+		opts := services.RollupCheckOptions{MemorialID: *memorialID, UserID: *userID}
+		if *days > 0 {
+			opts.Since = time.Now().AddDate(0, 0, -*days+1)
+		}
+
+		report, err := statsService.CheckRollupConsistency(opts)
+		if err != nil {
+			log.Fatalf("一致性校验失败: %v", err)
+		}
+		if *verbose {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			encoder.Encode(report)
+		}
+
+		log.Printf("扫描祭扫记录 %d 条", report.RecordsScanned)
+		if report.Consistent {
+			log.Println("✅ 汇总数据与原始记录一致")
+			return
+		}
+		log.Printf("❌ 发现不一致：纪念馆汇总 %d 项，每日访客 %d 项，用户汇总 %d 项，访客累计 %d 项",
+			len(report.MemorialDiffs), len(report.VisitorDiffs), len(report.UserDiffs), len(report.VisitorTotalDiffs))
+		log.Printf("涉及纪念馆 %d 个，用户 %d 个", len(report.InconsistentMemorials), len(report.InconsistentUsers))
+
+		if !*repair {
+			os.Exit(1)
+		}
+		for _, id := range report.InconsistentMemorials {
+			if err := statsService.RebuildMemorialRollups(id); err != nil {
+				log.Printf("重建纪念馆 %s 汇总失败: %v", id, err)
+			}
+		}
+		for _, id := range report.InconsistentUsers {
+			if err := statsService.RebuildUserRollups(id); err != nil {
+				log.Printf("重建用户 %s 汇总失败: %v", id, err)
+			}
+		}
+		log.Println("✅ 已重建不一致的汇总，请重新运行校验确认")
+
+	default:
+		log.Fatalf("未知操作: %s", *action)
+	}
+}
+
+func scopeName(scope string) string {
+	if scope == "memorial" {
+		return "纪念馆"
+	}
+	return "用户"
+}
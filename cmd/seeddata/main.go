@@ -75,6 +75,7 @@ func main() {
 	}
 
 	log.Println("✅ 测试数据插入成功")
+	log.Println("提示: 测试数据直接写入祭扫记录，请运行 go run ./cmd/rollupstats -action backfill 生成统计汇总")
 	fmt.Println("\n测试账号信息:")
 	fmt.Println("用户1: 张三 (test-user-1)")
 	fmt.Println("用户2: 李四 (test-user-2)")
@@ -2,16 +2,20 @@ package config
 
 import (
 	"os"
+	"strconv"
 )
 
 type Config struct {
-	Server     ServerConfig     `json:"server"`
-	Database   DatabaseConfig   `json:"database"`
-	JWT        JWTConfig        `json:"jwt"`
-	Wechat     WechatConfig     `json:"wechat"`
-	COS        COSConfig        `json:"cos"`
-	Encryption EncryptionConfig `json:"encryption"`
-	Security   SecurityConfig   `json:"security"`
+	Server       ServerConfig       `json:"server"`
+	Database     DatabaseConfig     `json:"database"`
+	JWT          JWTConfig          `json:"jwt"`
+	Wechat       WechatConfig       `json:"wechat"`
+	COS          COSConfig          `json:"cos"`
+	Encryption   EncryptionConfig   `json:"encryption"`
+	Security     SecurityConfig     `json:"security"`
+	NLP          NLPConfig          `json:"nlp"`
+	Family       FamilyConfig       `json:"family"`
+	Notification NotificationConfig `json:"notification"`
 }
 
 type ServerConfig struct {
@@ -67,6 +71,30 @@ type SecurityConfig struct {
 	EnableHTTPS      bool     `json:"enable_https"`
 }
 
+type NLPConfig struct {
+	SentimentLexiconPath string `json:"sentiment_lexicon_path"` // 自定义情感词典，与内置词典合并
+}
+
+type FamilyConfig struct {
+	InviteLinkBaseURL string `json:"invite_link_base_url"` // 邀请链接落地页地址，链接为 <地址>/<令牌>，同时用于生成二维码
+	OwnerInactiveDays int    `json:"owner_inactive_days"`  // 所有者超过该天数未登录时自动移交，0 表示不按活跃度移交
+}
+
+type NotificationConfig struct {
+	SMTP            SMTPConfig        `json:"smtp"`
+	WechatTemplates map[string]string `json:"wechat_templates"` // 通知分类对应的订阅消息模板ID，未配置的分类不发送微信消息
+	WechatPage      string            `json:"wechat_page"`      // 点击订阅消息打开的小程序页面
+	LogChannel      bool              `json:"log_channel"`      // 是否把通知写入日志，用于开发调试
+}
+
+type SMTPConfig struct {
+	Host     string `json:"host"` // 为空时不发送邮件；使用 STARTTLS，端口一般为 587
+	Port     int    `json:"port"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	From     string `json:"from"`
+}
+
 func Load() *Config {
 	return &Config{
 		Server: ServerConfig{
@@ -111,6 +139,30 @@ func Load() *Config {
 			MaxRequestSize:   10 * 1024 * 1024,             // 10MB
 			EnableHTTPS:      getEnv("ENABLE_HTTPS", "false") == "true",
 		},
+		NLP: NLPConfig{
+			SentimentLexiconPath: getEnv("SENTIMENT_LEXICON_PATH", ""),
+		},
+		Family: FamilyConfig{
+			InviteLinkBaseURL: getEnv("FAMILY_INVITE_LINK_BASE_URL", "/family/invite"),
+			OwnerInactiveDays: getEnvInt("FAMILY_OWNER_INACTIVE_DAYS", 180),
+		},
+		Notification: NotificationConfig{
+			SMTP: SMTPConfig{
+				Host:     getEnv("SMTP_HOST", ""),
+				Port:     getEnvInt("SMTP_PORT", 587),
+				Username: getEnv("SMTP_USERNAME", ""),
+				Password: getEnv("SMTP_PASSWORD", ""),
+				From:     getEnv("SMTP_FROM", ""),
+			},
+			WechatTemplates: map[string]string{
+				"invitation":     getEnv("WECHAT_TEMPLATE_INVITATION", ""),
+				"access_request": getEnv("WECHAT_TEMPLATE_ACCESS_REQUEST", ""),
+				"reminder":       getEnv("WECHAT_TEMPLATE_REMINDER", ""),
+				"moderation":     getEnv("WECHAT_TEMPLATE_MODERATION", ""),
+			},
+			WechatPage: getEnv("WECHAT_NOTIFICATION_PAGE", "pages/notifications/index"),
+			LogChannel: getEnv("NOTIFICATION_LOG_CHANNEL", "false") == "true",
+		},
 	}
 }
 
@@ -120,3 +172,12 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return defaultValue
+}
@@ -5,6 +5,7 @@ import (
 	"strconv"
 	"strings"
 	"yun-nian-memorial/internal/services"
+	"yun-nian-memorial/internal/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -398,4 +399,360 @@ func (c *AdminController) BatchModerateContent(ctx *gin.Context) {
 		Code:    0,
 		Message: message,
 	})
-}
\ No newline at end of file
+}
+// GetSensitiveWords 获取敏感词库
+func (c *AdminController) GetSensitiveWords(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	lists, err := c.adminService.GetSensitiveWordLists(userID.(string))
+	if err != nil {
+		if err.Error() == "权限不足" {
+			ctx.JSON(http.StatusForbidden, APIResponse{
+				Code:    1003,
+				Message: err.Error(),
+			})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, APIResponse{
+				Code:    1005,
+				Message: err.Error(),
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "获取成功",
+		Data:    lists,
+	})
+}
+
+// SetSensitiveWords 替换某个分类的敏感词
+func (c *AdminController) SetSensitiveWords(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	var req struct {
+		Words []utils.SensitiveWord `json:"words"`
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	err := c.adminService.SetSensitiveWords(userID.(string), ctx.Param("category"), req.Words)
+	if err != nil {
+		if err.Error() == "权限不足" {
+			ctx.JSON(http.StatusForbidden, APIResponse{
+				Code:    1003,
+				Message: err.Error(),
+			})
+		} else if strings.Contains(err.Error(), "无效") || strings.Contains(err.Error(), "分类名") {
+			ctx.JSON(http.StatusBadRequest, APIResponse{
+				Code:    1001,
+				Message: err.Error(),
+			})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, APIResponse{
+				Code:    1005,
+				Message: err.Error(),
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "敏感词库已更新",
+	})
+}
+
+// TestSensitiveText 使用当前词库检测文本
+func (c *AdminController) TestSensitiveText(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	var req struct {
+		Text string `json:"text" binding:"required"`
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	matches, err := c.adminService.TestSensitiveText(userID.(string), req.Text)
+	if err != nil {
+		if err.Error() == "权限不足" {
+			ctx.JSON(http.StatusForbidden, APIResponse{
+				Code:    1003,
+				Message: err.Error(),
+			})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, APIResponse{
+				Code:    1005,
+				Message: err.Error(),
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "检测完成",
+		Data: gin.H{
+			"matches": matches,
+			"blocked": utils.HasBlockingMatch(matches),
+		},
+	})
+}
+
+// GetContentFilterHits 获取敏感词命中记录
+func (c *AdminController) GetContentFilterHits(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	var req services.ContentFilterHitQuery
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.PageSize < 1 || req.PageSize > 100 {
+		req.PageSize = 20
+	}
+
+	hits, total, err := c.adminService.GetContentFilterHits(userID.(string), &req)
+	if err != nil {
+		if err.Error() == "权限不足" {
+			ctx.JSON(http.StatusForbidden, APIResponse{
+				Code:    1003,
+				Message: err.Error(),
+			})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, APIResponse{
+				Code:    1005,
+				Message: err.Error(),
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "获取成功",
+		Data: gin.H{
+			"list":      hits,
+			"total":     total,
+			"page":      req.Page,
+			"page_size": req.PageSize,
+		},
+	})
+}
+
+// ReviewContentFilterHit 复核敏感词命中记录
+func (c *AdminController) ReviewContentFilterHit(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	var req services.ContentFilterReviewRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	err := c.adminService.ReviewContentFilterHit(userID.(string), ctx.Param("hit_id"), &req)
+	if err != nil {
+		if err.Error() == "权限不足" {
+			ctx.JSON(http.StatusForbidden, APIResponse{
+				Code:    1003,
+				Message: err.Error(),
+			})
+		} else if err.Error() == "命中记录不存在" {
+			ctx.JSON(http.StatusNotFound, APIResponse{
+				Code:    1004,
+				Message: err.Error(),
+			})
+		} else if err.Error() == "该记录已复核" {
+			ctx.JSON(http.StatusBadRequest, APIResponse{
+				Code:    1001,
+				Message: err.Error(),
+			})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, APIResponse{
+				Code:    1005,
+				Message: err.Error(),
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "复核完成",
+	})
+}
+
+// GetMourningRuleSets 获取守丧规则
+func (c *AdminController) GetMourningRuleSets(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	ruleSets, err := c.adminService.GetMourningRuleSets(userID.(string))
+	if err != nil {
+		if err.Error() == "权限不足" {
+			ctx.JSON(http.StatusForbidden, APIResponse{
+				Code:    1003,
+				Message: err.Error(),
+			})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, APIResponse{
+				Code:    1005,
+				Message: err.Error(),
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "获取成功",
+		Data:    ruleSets,
+	})
+}
+
+// SetMourningRuleSet 新增或覆盖一套守丧规则
+func (c *AdminController) SetMourningRuleSet(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	var req utils.MourningRuleSet
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	err := c.adminService.SetMourningRuleSet(userID.(string), ctx.Param("key"), &req)
+	if err != nil {
+		if err.Error() == "权限不足" {
+			ctx.JSON(http.StatusForbidden, APIResponse{
+				Code:    1003,
+				Message: err.Error(),
+			})
+		} else if strings.HasPrefix(err.Error(), "无效的守丧规则") || strings.HasPrefix(err.Error(), "规则标识") {
+			ctx.JSON(http.StatusBadRequest, APIResponse{
+				Code:    1001,
+				Message: err.Error(),
+			})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, APIResponse{
+				Code:    1005,
+				Message: err.Error(),
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "守丧规则已保存",
+		Data:    req,
+	})
+}
+
+// DeleteMourningRuleSet 删除系统配置中的守丧规则，内置规则恢复默认
+func (c *AdminController) DeleteMourningRuleSet(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	err := c.adminService.DeleteMourningRuleSet(userID.(string), ctx.Param("key"))
+	if err != nil {
+		if err.Error() == "权限不足" {
+			ctx.JSON(http.StatusForbidden, APIResponse{
+				Code:    1003,
+				Message: err.Error(),
+			})
+		} else if err.Error() == "守丧规则不存在" {
+			ctx.JSON(http.StatusNotFound, APIResponse{
+				Code:    1004,
+				Message: err.Error(),
+			})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, APIResponse{
+				Code:    1005,
+				Message: err.Error(),
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "守丧规则已删除",
+	})
+}
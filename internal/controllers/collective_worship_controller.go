@@ -0,0 +1,387 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+	"yun-nian-memorial/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CollectiveWorshipController struct {
+	collectiveWorshipService *services.CollectiveWorshipService
+}
+
+func NewCollectiveWorshipController(collectiveWorshipService *services.CollectiveWorshipService) *CollectiveWorshipController {
+	return &CollectiveWorshipController{
+		collectiveWorshipService: collectiveWorshipService,
+	}
+}
+
+// ScheduleCollectiveWorship 发起（预约）集体祭扫
+func (c *CollectiveWorshipController) ScheduleCollectiveWorship(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	var req services.ScheduleCollectiveWorshipRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	event, err := c.collectiveWorshipService.ScheduleCollectiveWorship(userID.(string), ctx.Param("family_id"), &req)
+	if err != nil {
+		respondCollectiveWorshipError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "集体祭扫发起成功",
+		Data:    event,
+	})
+}
+
+// GetCollectiveWorships 获取家族圈集体祭扫列表
+func (c *CollectiveWorshipController) GetCollectiveWorships(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	// 获取分页参数
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	events, total, err := c.collectiveWorshipService.GetCollectiveWorships(userID.(string), ctx.Param("family_id"), ctx.Query("status"), page, pageSize)
+	if err != nil {
+		respondCollectiveWorshipError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "获取成功",
+		Data: gin.H{
+			"list":      events,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
+
+// GetCollectiveWorship 获取集体祭扫详情及报名情况
+func (c *CollectiveWorshipController) GetCollectiveWorship(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	event, err := c.collectiveWorshipService.GetCollectiveWorship(userID.(string), ctx.Param("family_id"), ctx.Param("worship_id"))
+	if err != nil {
+		respondCollectiveWorshipError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "获取成功",
+		Data:    event,
+	})
+}
+
+// RSVPCollectiveWorship 报名集体祭扫
+func (c *CollectiveWorshipController) RSVPCollectiveWorship(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	var req services.CollectiveWorshipRSVPRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	err := c.collectiveWorshipService.RSVPCollectiveWorship(userID.(string), ctx.Param("family_id"), ctx.Param("worship_id"), &req)
+	if err != nil {
+		respondCollectiveWorshipError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "报名成功",
+	})
+}
+
+// CancelCollectiveWorship 取消集体祭扫
+func (c *CollectiveWorshipController) CancelCollectiveWorship(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	err := c.collectiveWorshipService.CancelCollectiveWorship(userID.(string), ctx.Param("family_id"), ctx.Param("worship_id"))
+	if err != nil {
+		respondCollectiveWorshipError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "已取消",
+	})
+}
+
+// StartCollectiveWorship 提前开始集体祭扫
+func (c *CollectiveWorshipController) StartCollectiveWorship(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	err := c.collectiveWorshipService.StartCollectiveWorship(userID.(string), ctx.Param("family_id"), ctx.Param("worship_id"))
+	if err != nil {
+		respondCollectiveWorshipError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "集体祭扫已开始",
+	})
+}
+
+// EndCollectiveWorship 结束集体祭扫并发布总结
+func (c *CollectiveWorshipController) EndCollectiveWorship(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	err := c.collectiveWorshipService.EndCollectiveWorship(userID.(string), ctx.Param("family_id"), ctx.Param("worship_id"))
+	if err != nil {
+		respondCollectiveWorshipError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "集体祭扫已结束",
+	})
+}
+
+// JoinCollectiveWorship 进入祭扫现场
+func (c *CollectiveWorshipController) JoinCollectiveWorship(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	state, err := c.collectiveWorshipService.JoinCollectiveWorship(userID.(string), ctx.Param("family_id"), ctx.Param("worship_id"))
+	if err != nil {
+		respondCollectiveWorshipError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "参与成功",
+		Data:    state,
+	})
+}
+
+// GetLiveState 获取祭扫现场状态，since(RFC3339) 用于增量获取新祭品
+func (c *CollectiveWorshipController) GetLiveState(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	var since *time.Time
+	if value := ctx.Query("since"); value != "" {
+		parsed, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, APIResponse{
+				Code:    1001,
+				Message: "since 时间格式错误",
+			})
+			return
+		}
+		since = &parsed
+	}
+
+	state, err := c.collectiveWorshipService.GetLiveState(userID.(string), ctx.Param("family_id"), ctx.Param("worship_id"), since)
+	if err != nil {
+		respondCollectiveWorshipError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "获取成功",
+		Data:    state,
+	})
+}
+
+// StartMomentOfSilence 开始默哀
+func (c *CollectiveWorshipController) StartMomentOfSilence(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	state, err := c.collectiveWorshipService.StartMomentOfSilence(userID.(string), ctx.Param("family_id"), ctx.Param("worship_id"))
+	if err != nil {
+		respondCollectiveWorshipError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "默哀开始",
+		Data:    state,
+	})
+}
+
+// OfferCollectiveWorship 现场献祭
+func (c *CollectiveWorshipController) OfferCollectiveWorship(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	var req services.CollectiveOfferingRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	offering, err := c.collectiveWorshipService.OfferCollectiveWorship(userID.(string), ctx.Param("family_id"), ctx.Param("worship_id"), &req)
+	if err != nil {
+		respondCollectiveWorshipError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "献祭成功",
+		Data:    offering,
+	})
+}
+
+// GetCollectiveWorshipReminders 获取当前用户即将开始的集体祭扫提醒
+func (c *CollectiveWorshipController) GetCollectiveWorshipReminders(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	events, err := c.collectiveWorshipService.GetCollectiveWorshipReminders(userID.(string))
+	if err != nil {
+		respondCollectiveWorshipError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "获取成功",
+		Data:    events,
+	})
+}
+
+func respondCollectiveWorshipError(ctx *gin.Context, err error) {
+	switch err.Error() {
+	case "集体祭扫活动不存在":
+		ctx.JSON(http.StatusNotFound, APIResponse{
+			Code:    1004,
+			Message: err.Error(),
+		})
+	case "您不是此家族圈的成员", "您没有发起集体祭扫的权限", "只有发起人或管理员可以操作此活动":
+		ctx.JSON(http.StatusForbidden, APIResponse{
+			Code:    1003,
+			Message: err.Error(),
+		})
+	case "纪念馆未关联到此家族圈", "无效的祭扫类型", "约定时间不能早于当前时间", "活动已结束",
+		"活动已开始或已结束", "活动未在进行中", "默哀正在进行中", "默哀进行中，请稍后献祭",
+		"请先进入祭扫现场", "定时时间格式错误", "定时时间不能早于当前时间", services.ErrContentBlocked.Error():
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: err.Error(),
+		})
+	default:
+		ctx.JSON(http.StatusInternalServerError, APIResponse{
+			Code:    1005,
+			Message: err.Error(),
+		})
+	}
+}
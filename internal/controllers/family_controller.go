@@ -3,6 +3,8 @@ package controllers
 import (
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 	"yun-nian-memorial/internal/services"
 
 	"github.com/gin-gonic/gin"
@@ -207,7 +209,7 @@ func (c *FamilyController) UpdateFamily(ctx *gin.Context) {
 				Code:    1004,
 				Message: err.Error(),
 			})
-		} else if err.Error() == "只有管理员可以修改家族圈" {
+		} else if err.Error() == "您没有修改家族圈信息的权限" {
 			ctx.JSON(http.StatusForbidden, APIResponse{
 				Code:    1003,
 				Message: err.Error(),
@@ -254,11 +256,16 @@ func (c *FamilyController) DeleteFamily(ctx *gin.Context) {
 				Code:    1004,
 				Message: err.Error(),
 			})
-		} else if err.Error() == "只有创建者可以删除家族圈" {
+		} else if err.Error() == "您没有删除家族圈的权限" {
 			ctx.JSON(http.StatusForbidden, APIResponse{
 				Code:    1003,
 				Message: err.Error(),
 			})
+		} else if err.Error() == "请先删除下级支系" {
+			ctx.JSON(http.StatusBadRequest, APIResponse{
+				Code:    1001,
+				Message: err.Error(),
+			})
 		} else {
 			ctx.JSON(http.StatusInternalServerError, APIResponse{
 				Code:    1005,
@@ -305,7 +312,7 @@ func (c *FamilyController) InviteMembers(ctx *gin.Context) {
 
 	err := c.familyService.InviteMembers(userID.(string), familyID, &req)
 	if err != nil {
-		if err.Error() == "只有管理员可以邀请成员" {
+		if err.Error() == "您没有邀请成员的权限" {
 			ctx.JSON(http.StatusForbidden, APIResponse{
 				Code:    1003,
 				Message: err.Error(),
@@ -374,6 +381,51 @@ func (c *FamilyController) JoinFamilyByCode(ctx *gin.Context) {
 	})
 }
 
+// RotateInviteCode 重置家族圈邀请码
+func (c *FamilyController) RotateInviteCode(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	familyID := ctx.Param("family_id")
+	if familyID == "" {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "家族圈ID不能为空",
+		})
+		return
+	}
+
+	inviteCode, err := c.familyService.RotateInviteCode(userID.(string), familyID)
+	if err != nil {
+		if err.Error() == "您没有邀请成员的权限" {
+			ctx.JSON(http.StatusForbidden, APIResponse{
+				Code:    1003,
+				Message: err.Error(),
+			})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, APIResponse{
+				Code:    1005,
+				Message: err.Error(),
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "邀请码已重置",
+		Data: gin.H{
+			"inviteCode": inviteCode,
+		},
+	})
+}
+
 // RespondToInvitation 响应邀请
 func (c *FamilyController) RespondToInvitation(ctx *gin.Context) {
 	userID, exists := ctx.Get("user_id")
@@ -455,11 +507,16 @@ func (c *FamilyController) RemoveMember(ctx *gin.Context) {
 
 	err := c.familyService.RemoveMember(userID.(string), familyID, memberID)
 	if err != nil {
-		if err.Error() == "只有管理员可以移除成员" || err.Error() == "不能移除家族圈创建者" {
+		if err.Error() == "您没有移除成员的权限" || err.Error() == "不能移除同级或更高角色的成员" {
 			ctx.JSON(http.StatusForbidden, APIResponse{
 				Code:    1003,
 				Message: err.Error(),
 			})
+		} else if err.Error() == "成员不存在" {
+			ctx.JSON(http.StatusNotFound, APIResponse{
+				Code:    1004,
+				Message: err.Error(),
+			})
 		} else {
 			ctx.JSON(http.StatusInternalServerError, APIResponse{
 				Code:    1005,
@@ -497,11 +554,16 @@ func (c *FamilyController) LeaveFamily(ctx *gin.Context) {
 
 	err := c.familyService.LeaveFamily(userID.(string), familyID)
 	if err != nil {
-		if err.Error() == "创建者不能离开家族圈" {
+		if err.Error() == "您不是此家族圈的成员" {
 			ctx.JSON(http.StatusForbidden, APIResponse{
 				Code:    1003,
 				Message: err.Error(),
 			})
+		} else if err.Error() == "家族圈没有其他成员，请直接删除家族圈" || err.Error() == "继承自其他家族圈的成员身份不能在此退出" {
+			ctx.JSON(http.StatusBadRequest, APIResponse{
+				Code:    1001,
+				Message: err.Error(),
+			})
 		} else {
 			ctx.JSON(http.StatusInternalServerError, APIResponse{
 				Code:    1005,
@@ -517,6 +579,69 @@ func (c *FamilyController) LeaveFamily(ctx *gin.Context) {
 	})
 }
 
+// TransferOwnership 转让家族圈
+func (c *FamilyController) TransferOwnership(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	familyID := ctx.Param("family_id")
+	if familyID == "" {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "家族圈ID不能为空",
+		})
+		return
+	}
+
+	var req struct {
+		NewOwnerID string `json:"new_owner_id" binding:"required"`
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	err := c.familyService.TransferOwnership(userID.(string), familyID, req.NewOwnerID)
+	if err != nil {
+		if err.Error() == "您没有转让家族圈的权限" {
+			ctx.JSON(http.StatusForbidden, APIResponse{
+				Code:    1003,
+				Message: err.Error(),
+			})
+		} else if err.Error() == "成员不存在" {
+			ctx.JSON(http.StatusNotFound, APIResponse{
+				Code:    1004,
+				Message: err.Error(),
+			})
+		} else if err.Error() == "不能将家族圈转让给自己" || err.Error() == "所有者已变更" {
+			ctx.JSON(http.StatusBadRequest, APIResponse{
+				Code:    1001,
+				Message: err.Error(),
+			})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, APIResponse{
+				Code:    1005,
+				Message: err.Error(),
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "转让成功",
+	})
+}
+
 // SetMemberRole 设置成员角色
 func (c *FamilyController) SetMemberRole(ctx *gin.Context) {
 	userID, exists := ctx.Get("user_id")
@@ -551,11 +676,16 @@ func (c *FamilyController) SetMemberRole(ctx *gin.Context) {
 
 	err := c.familyService.SetMemberRole(userID.(string), familyID, memberID, req.Role)
 	if err != nil {
-		if err.Error() == "只有创建者可以设置成员角色" || err.Error() == "不能修改创建者的角色" {
+		if err.Error() == "您没有设置成员角色的权限" || err.Error() == "不能修改所有者的角色" {
 			ctx.JSON(http.StatusForbidden, APIResponse{
 				Code:    1003,
 				Message: err.Error(),
 			})
+		} else if err.Error() == "成员不存在" {
+			ctx.JSON(http.StatusNotFound, APIResponse{
+				Code:    1004,
+				Message: err.Error(),
+			})
 		} else if err.Error() == "无效的角色" {
 			ctx.JSON(http.StatusBadRequest, APIResponse{
 				Code:    1001,
@@ -576,8 +706,8 @@ func (c *FamilyController) SetMemberRole(ctx *gin.Context) {
 	})
 }
 
-// GetFamilyMembers 获取家族成员列表
-func (c *FamilyController) GetFamilyMembers(ctx *gin.Context) {
+// GetFamilyPermissions 获取家族圈权限矩阵
+func (c *FamilyController) GetFamilyPermissions(ctx *gin.Context) {
 	userID, exists := ctx.Get("user_id")
 	if !exists {
 		ctx.JSON(http.StatusUnauthorized, APIResponse{
@@ -596,18 +726,7 @@ func (c *FamilyController) GetFamilyMembers(ctx *gin.Context) {
 		return
 	}
 
-	// 获取分页参数
-	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "20"))
-
-	if page < 1 {
-		page = 1
-	}
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 20
-	}
-
-	members, total, err := c.familyService.GetFamilyMembers(userID.(string), familyID, page, pageSize)
+	matrix, err := c.familyService.GetPermissionMatrix(userID.(string), familyID)
 	if err != nil {
 		if err.Error() == "您不是此家族圈的成员" {
 			ctx.JSON(http.StatusForbidden, APIResponse{
@@ -626,17 +745,12 @@ func (c *FamilyController) GetFamilyMembers(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, APIResponse{
 		Code:    0,
 		Message: "获取成功",
-		Data: gin.H{
-			"list":      members,
-			"total":     total,
-			"page":      page,
-			"page_size": pageSize,
-		},
+		Data:    matrix,
 	})
 }
 
-// GetFamilyActivities 获取家族活动
-func (c *FamilyController) GetFamilyActivities(ctx *gin.Context) {
+// UpdateFamilyPermissions 更新家族圈权限矩阵
+func (c *FamilyController) UpdateFamilyPermissions(ctx *gin.Context) {
 	userID, exists := ctx.Get("user_id")
 	if !exists {
 		ctx.JSON(http.StatusUnauthorized, APIResponse{
@@ -655,24 +769,27 @@ func (c *FamilyController) GetFamilyActivities(ctx *gin.Context) {
 		return
 	}
 
-	// 获取分页参数
-	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "20"))
-
-	if page < 1 {
-		page = 1
-	}
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 20
+	var req services.UpdateFamilyPermissionsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
 	}
 
-	activities, total, err := c.familyService.GetFamilyActivities(userID.(string), familyID, page, pageSize)
+	err := c.familyService.UpdatePermissionMatrix(userID.(string), familyID, &req)
 	if err != nil {
-		if err.Error() == "您不是此家族圈的成员" {
+		if err.Error() == "您没有修改权限设置的权限" {
 			ctx.JSON(http.StatusForbidden, APIResponse{
 				Code:    1003,
 				Message: err.Error(),
 			})
+		} else if strings.HasPrefix(err.Error(), "无效的操作") || strings.HasPrefix(err.Error(), "无效的角色") {
+			ctx.JSON(http.StatusBadRequest, APIResponse{
+				Code:    1001,
+				Message: err.Error(),
+			})
 		} else {
 			ctx.JSON(http.StatusInternalServerError, APIResponse{
 				Code:    1005,
@@ -682,20 +799,24 @@ func (c *FamilyController) GetFamilyActivities(ctx *gin.Context) {
 		return
 	}
 
+	matrix, err := c.familyService.GetPermissionMatrix(userID.(string), familyID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, APIResponse{
+			Code:    1005,
+			Message: err.Error(),
+		})
+		return
+	}
+
 	ctx.JSON(http.StatusOK, APIResponse{
 		Code:    0,
-		Message: "获取成功",
-		Data: gin.H{
-			"list":      activities,
-			"total":     total,
-			"page":      page,
-			"page_size": pageSize,
-		},
+		Message: "更新成功",
+		Data:    matrix,
 	})
 }
 
-// AddMemorialToFamily 关联纪念馆到家族圈
-func (c *FamilyController) AddMemorialToFamily(ctx *gin.Context) {
+// CreateFamilyBranch 创建支系
+func (c *FamilyController) CreateFamilyBranch(ctx *gin.Context) {
 	userID, exists := ctx.Get("user_id")
 	if !exists {
 		ctx.JSON(http.StatusUnauthorized, APIResponse{
@@ -714,9 +835,7 @@ func (c *FamilyController) AddMemorialToFamily(ctx *gin.Context) {
 		return
 	}
 
-	var req struct {
-		MemorialID string `json:"memorial_id" binding:"required"`
-	}
+	var req services.CreateFamilyBranchRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, APIResponse{
 			Code:    1001,
@@ -725,19 +844,14 @@ func (c *FamilyController) AddMemorialToFamily(ctx *gin.Context) {
 		return
 	}
 
-	err := c.familyService.AddMemorialToFamily(userID.(string), familyID, req.MemorialID)
+	branch, err := c.familyService.CreateFamilyBranch(userID.(string), familyID, &req)
 	if err != nil {
-		if err.Error() == "只有管理员可以关联纪念馆" {
+		if err.Error() == "您不是此家族圈的成员" || err.Error() == "您没有创建支系的权限" {
 			ctx.JSON(http.StatusForbidden, APIResponse{
 				Code:    1003,
 				Message: err.Error(),
 			})
-		} else if err.Error() == "纪念馆不存在或无权操作" {
-			ctx.JSON(http.StatusNotFound, APIResponse{
-				Code:    1004,
-				Message: err.Error(),
-			})
-		} else if err.Error() == "纪念馆已经关联到此家族圈" {
+		} else if strings.HasPrefix(err.Error(), "支系最多") {
 			ctx.JSON(http.StatusBadRequest, APIResponse{
 				Code:    1001,
 				Message: err.Error(),
@@ -753,12 +867,13 @@ func (c *FamilyController) AddMemorialToFamily(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, APIResponse{
 		Code:    0,
-		Message: "关联成功",
+		Message: "创建成功",
+		Data:    branch,
 	})
 }
 
-// RemoveMemorialFromFamily 移除纪念馆关联
-func (c *FamilyController) RemoveMemorialFromFamily(ctx *gin.Context) {
+// GetFamilyBranches 获取支系树
+func (c *FamilyController) GetFamilyBranches(ctx *gin.Context) {
 	userID, exists := ctx.Get("user_id")
 	if !exists {
 		ctx.JSON(http.StatusUnauthorized, APIResponse{
@@ -769,18 +884,17 @@ func (c *FamilyController) RemoveMemorialFromFamily(ctx *gin.Context) {
 	}
 
 	familyID := ctx.Param("family_id")
-	memorialID := ctx.Param("memorial_id")
-	if familyID == "" || memorialID == "" {
+	if familyID == "" {
 		ctx.JSON(http.StatusBadRequest, APIResponse{
 			Code:    1001,
-			Message: "家族圈ID和纪念馆ID不能为空",
+			Message: "家族圈ID不能为空",
 		})
 		return
 	}
 
-	err := c.familyService.RemoveMemorialFromFamily(userID.(string), familyID, memorialID)
+	branches, err := c.familyService.GetFamilyBranches(userID.(string), familyID)
 	if err != nil {
-		if err.Error() == "只有管理员可以移除纪念馆关联" {
+		if err.Error() == "您不是此家族圈的成员" {
 			ctx.JSON(http.StatusForbidden, APIResponse{
 				Code:    1003,
 				Message: err.Error(),
@@ -796,12 +910,13 @@ func (c *FamilyController) RemoveMemorialFromFamily(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, APIResponse{
 		Code:    0,
-		Message: "移除成功",
+		Message: "获取成功",
+		Data:    branches,
 	})
 }
 
-// SetMemorialReminder 设置纪念日提醒
-func (c *FamilyController) SetMemorialReminder(ctx *gin.Context) {
+// GetFamilyMembers 获取家族成员列表
+func (c *FamilyController) GetFamilyMembers(ctx *gin.Context) {
 	userID, exists := ctx.Get("user_id")
 	if !exists {
 		ctx.JSON(http.StatusUnauthorized, APIResponse{
@@ -820,27 +935,24 @@ func (c *FamilyController) SetMemorialReminder(ctx *gin.Context) {
 		return
 	}
 
-	var req services.SetReminderRequest
-	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, APIResponse{
-			Code:    1001,
-			Message: "请求参数错误: " + err.Error(),
-		})
-		return
+	// 获取分页参数
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
 	}
 
-	err := c.familyService.SetMemorialReminder(userID.(string), familyID, &req)
+	members, total, err := c.familyService.GetFamilyMembers(userID.(string), familyID, page, pageSize)
 	if err != nil {
-		if err.Error() == "只有管理员可以设置纪念日提醒" {
+		if err.Error() == "您不是此家族圈的成员" {
 			ctx.JSON(http.StatusForbidden, APIResponse{
 				Code:    1003,
 				Message: err.Error(),
 			})
-		} else if err.Error() == "纪念馆未关联到此家族圈" || err.Error() == "无效的提醒类型" {
-			ctx.JSON(http.StatusBadRequest, APIResponse{
-				Code:    1001,
-				Message: err.Error(),
-			})
 		} else {
 			ctx.JSON(http.StatusInternalServerError, APIResponse{
 				Code:    1005,
@@ -852,12 +964,18 @@ func (c *FamilyController) SetMemorialReminder(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, APIResponse{
 		Code:    0,
-		Message: "设置成功",
+		Message: "获取成功",
+		Data: gin.H{
+			"list":      members,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
 	})
 }
 
-// GetFamilyReminders 获取家族纪念日提醒
-func (c *FamilyController) GetFamilyReminders(ctx *gin.Context) {
+// GetFamilyActivities 获取家族活动
+func (c *FamilyController) GetFamilyActivities(ctx *gin.Context) {
 	userID, exists := ctx.Get("user_id")
 	if !exists {
 		ctx.JSON(http.StatusUnauthorized, APIResponse{
@@ -887,7 +1005,7 @@ func (c *FamilyController) GetFamilyReminders(ctx *gin.Context) {
 		pageSize = 20
 	}
 
-	reminders, total, err := c.familyService.GetFamilyReminders(userID.(string), familyID, page, pageSize)
+	activities, total, err := c.familyService.GetFamilyActivities(userID.(string), familyID, page, pageSize)
 	if err != nil {
 		if err.Error() == "您不是此家族圈的成员" {
 			ctx.JSON(http.StatusForbidden, APIResponse{
@@ -907,7 +1025,7 @@ func (c *FamilyController) GetFamilyReminders(ctx *gin.Context) {
 		Code:    0,
 		Message: "获取成功",
 		Data: gin.H{
-			"list":      reminders,
+			"list":      activities,
 			"total":     total,
 			"page":      page,
 			"page_size": pageSize,
@@ -915,8 +1033,517 @@ func (c *FamilyController) GetFamilyReminders(ctx *gin.Context) {
 	})
 }
 
-// GetUpcomingReminders 获取即将到来的提醒
-func (c *FamilyController) GetUpcomingReminders(ctx *gin.Context) {
+// AddMemorialToFamily 关联纪念馆到家族圈
+func (c *FamilyController) AddMemorialToFamily(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	familyID := ctx.Param("family_id")
+	if familyID == "" {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "家族圈ID不能为空",
+		})
+		return
+	}
+
+	var req struct {
+		MemorialID string `json:"memorial_id" binding:"required"`
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	err := c.familyService.AddMemorialToFamily(userID.(string), familyID, req.MemorialID)
+	if err != nil {
+		if err.Error() == "您没有关联纪念馆的权限" {
+			ctx.JSON(http.StatusForbidden, APIResponse{
+				Code:    1003,
+				Message: err.Error(),
+			})
+		} else if err.Error() == "纪念馆不存在或无权操作" {
+			ctx.JSON(http.StatusNotFound, APIResponse{
+				Code:    1004,
+				Message: err.Error(),
+			})
+		} else if err.Error() == "纪念馆已经关联到此家族圈" {
+			ctx.JSON(http.StatusBadRequest, APIResponse{
+				Code:    1001,
+				Message: err.Error(),
+			})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, APIResponse{
+				Code:    1005,
+				Message: err.Error(),
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "关联成功",
+	})
+}
+
+// RemoveMemorialFromFamily 移除纪念馆关联
+func (c *FamilyController) RemoveMemorialFromFamily(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	familyID := ctx.Param("family_id")
+	memorialID := ctx.Param("memorial_id")
+	if familyID == "" || memorialID == "" {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "家族圈ID和纪念馆ID不能为空",
+		})
+		return
+	}
+
+	err := c.familyService.RemoveMemorialFromFamily(userID.(string), familyID, memorialID)
+	if err != nil {
+		if err.Error() == "您没有关联纪念馆的权限" {
+			ctx.JSON(http.StatusForbidden, APIResponse{
+				Code:    1003,
+				Message: err.Error(),
+			})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, APIResponse{
+				Code:    1005,
+				Message: err.Error(),
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "移除成功",
+	})
+}
+
+// SetMemorialReminder 设置纪念日提醒
+func (c *FamilyController) SetMemorialReminder(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	familyID := ctx.Param("family_id")
+	if familyID == "" {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "家族圈ID不能为空",
+		})
+		return
+	}
+
+	var req services.SetReminderRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	err := c.familyService.SetMemorialReminder(userID.(string), familyID, &req)
+	if err != nil {
+		if err.Error() == "您没有设置纪念日提醒的权限" {
+			ctx.JSON(http.StatusForbidden, APIResponse{
+				Code:    1003,
+				Message: err.Error(),
+			})
+		} else if err.Error() == "纪念馆未关联到此家族圈" || err.Error() == "无效的提醒类型" || isReminderRuleError(err) {
+			ctx.JSON(http.StatusBadRequest, APIResponse{
+				Code:    1001,
+				Message: err.Error(),
+			})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, APIResponse{
+				Code:    1005,
+				Message: err.Error(),
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "设置成功",
+	})
+}
+
+// isReminderRuleError 判断是否为提醒规则、提前量或发送时刻的校验错误
+func isReminderRuleError(err error) bool {
+	switch err.Error() {
+	case "无效的提醒规则", "无效的提前提醒天数", "无效的提醒时间", "纪念馆未填写逝世日期", "请填写提醒日期":
+		return true
+	}
+	return false
+}
+
+// GetFamilyReminders 获取家族纪念日提醒
+func (c *FamilyController) GetFamilyReminders(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	familyID := ctx.Param("family_id")
+	if familyID == "" {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "家族圈ID不能为空",
+		})
+		return
+	}
+
+	// 获取分页参数
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	reminders, total, err := c.familyService.GetFamilyReminders(userID.(string), familyID, page, pageSize)
+	if err != nil {
+		if err.Error() == "您不是此家族圈的成员" {
+			ctx.JSON(http.StatusForbidden, APIResponse{
+				Code:    1003,
+				Message: err.Error(),
+			})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, APIResponse{
+				Code:    1005,
+				Message: err.Error(),
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "获取成功",
+		Data: gin.H{
+			"list":      reminders,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
+
+// GetUpcomingReminders 获取即将到来的提醒
+func (c *FamilyController) GetUpcomingReminders(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	familyID := ctx.Param("family_id")
+	if familyID == "" {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "家族圈ID不能为空",
+		})
+		return
+	}
+
+	reminders, err := c.familyService.GetUpcomingReminders(userID.(string), familyID)
+	if err != nil {
+		if err.Error() == "您不是此家族圈的成员" {
+			ctx.JSON(http.StatusForbidden, APIResponse{
+				Code:    1003,
+				Message: err.Error(),
+			})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, APIResponse{
+				Code:    1005,
+				Message: err.Error(),
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "获取成功",
+		Data:    reminders,
+	})
+}
+
+// DeleteReminder 删除纪念日提醒
+func (c *FamilyController) DeleteReminder(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	familyID := ctx.Param("family_id")
+	reminderID := ctx.Param("reminder_id")
+	if familyID == "" || reminderID == "" {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "家族圈ID和提醒ID不能为空",
+		})
+		return
+	}
+
+	err := c.familyService.DeleteReminder(userID.(string), familyID, reminderID)
+	if err != nil {
+		if err.Error() == "您没有设置纪念日提醒的权限" {
+			ctx.JSON(http.StatusForbidden, APIResponse{
+				Code:    1003,
+				Message: err.Error(),
+			})
+		} else if err.Error() == "提醒不存在" {
+			ctx.JSON(http.StatusNotFound, APIResponse{
+				Code:    1004,
+				Message: err.Error(),
+			})
+		} else if err.Error() == "无权删除此提醒" {
+			ctx.JSON(http.StatusForbidden, APIResponse{
+				Code:    1003,
+				Message: err.Error(),
+			})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, APIResponse{
+				Code:    1005,
+				Message: err.Error(),
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "删除成功",
+	})
+}
+
+// CreateGenealogy 创建家族谱系成员
+func (c *FamilyController) CreateGenealogy(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	familyID := ctx.Param("family_id")
+	if familyID == "" {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "家族圈ID不能为空",
+		})
+		return
+	}
+
+	var req services.CreateGenealogyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	genealogy, err := c.familyService.CreateGenealogy(userID.(string), familyID, &req)
+	if err != nil {
+		if err.Error() == "您没有编辑家族谱系的权限" {
+			ctx.JSON(http.StatusForbidden, APIResponse{
+				Code:    1003,
+				Message: err.Error(),
+			})
+		} else if err.Error() == "无效的性别" || err.Error() == "指定的父辈不存在" || err.Error() == "纪念馆未关联到此家族圈" ||
+			err.Error() == "该纪念馆已关联其他谱系成员" || strings.HasPrefix(err.Error(), "谱系数据不一致") {
+			ctx.JSON(http.StatusBadRequest, APIResponse{
+				Code:    1001,
+				Message: err.Error(),
+			})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, APIResponse{
+				Code:    1005,
+				Message: err.Error(),
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "创建成功",
+		Data:    genealogy,
+	})
+}
+
+// GetFamilyGenealogy 获取家族谱系
+func (c *FamilyController) GetFamilyGenealogy(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	familyID := ctx.Param("family_id")
+	if familyID == "" {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "家族圈ID不能为空",
+		})
+		return
+	}
+
+	graph, err := c.familyService.GetFamilyGenealogy(userID.(string), familyID, ctx.Query("region"))
+	if err != nil {
+		if err.Error() == "您不是此家族圈的成员" {
+			ctx.JSON(http.StatusForbidden, APIResponse{
+				Code:    1003,
+				Message: err.Error(),
+			})
+		} else if err.Error() == "无效的称谓地区" {
+			ctx.JSON(http.StatusBadRequest, APIResponse{
+				Code:    1001,
+				Message: err.Error(),
+			})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, APIResponse{
+				Code:    1005,
+				Message: err.Error(),
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "获取成功",
+		Data:    graph,
+	})
+}
+
+// GetGenealogyAncestors 获取谱系成员的祖先（max_depth 限制代数，默认不限）
+func (c *FamilyController) GetGenealogyAncestors(ctx *gin.Context) {
+	c.getGenealogyKin(ctx, true)
+}
+
+// GetGenealogyDescendants 获取谱系成员的后代（max_depth 限制代数，默认不限）
+func (c *FamilyController) GetGenealogyDescendants(ctx *gin.Context) {
+	c.getGenealogyKin(ctx, false)
+}
+
+func (c *FamilyController) getGenealogyKin(ctx *gin.Context, ancestors bool) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	familyID := ctx.Param("family_id")
+	genealogyID := ctx.Param("genealogy_id")
+	maxDepth, _ := strconv.Atoi(ctx.DefaultQuery("max_depth", "0"))
+
+	var nodes []*services.GenealogyKinNode
+	var err error
+	if ancestors {
+		nodes, err = c.familyService.GetGenealogyAncestors(userID.(string), familyID, genealogyID, maxDepth)
+	} else {
+		nodes, err = c.familyService.GetGenealogyDescendants(userID.(string), familyID, genealogyID, maxDepth)
+	}
+	if err != nil {
+		respondGenealogyRelationError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "获取成功",
+		Data:    nodes,
+	})
+}
+
+// GetGenealogyPath 获取两位谱系成员之间的关系路径
+func (c *FamilyController) GetGenealogyPath(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	fromID := ctx.Query("from")
+	toID := ctx.Query("to")
+	if fromID == "" || toID == "" {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "请指定 from 和 to 两位谱系成员",
+		})
+		return
+	}
+
+	steps, err := c.familyService.GetGenealogyPath(userID.(string), ctx.Param("family_id"), fromID, toID)
+	if err != nil {
+		respondGenealogyRelationError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "获取成功",
+		Data:    steps,
+	})
+}
+
+// GetGenealogyKinship 计算称谓（from 为空时从当前用户关联的谱系成员出发，region 为称谓地区）
+func (c *FamilyController) GetGenealogyKinship(ctx *gin.Context) {
 	userID, exists := ctx.Get("user_id")
 	if !exists {
 		ctx.JSON(http.StatusUnauthorized, APIResponse{
@@ -926,40 +1553,30 @@ func (c *FamilyController) GetUpcomingReminders(ctx *gin.Context) {
 		return
 	}
 
-	familyID := ctx.Param("family_id")
-	if familyID == "" {
+	toID := ctx.Query("to")
+	if toID == "" {
 		ctx.JSON(http.StatusBadRequest, APIResponse{
 			Code:    1001,
-			Message: "家族圈ID不能为空",
+			Message: "请指定要称呼的谱系成员",
 		})
 		return
 	}
 
-	reminders, err := c.familyService.GetUpcomingReminders(userID.(string), familyID)
+	kinship, err := c.familyService.GetGenealogyKinship(userID.(string), ctx.Param("family_id"), ctx.Query("from"), toID, ctx.Query("region"))
 	if err != nil {
-		if err.Error() == "您不是此家族圈的成员" {
-			ctx.JSON(http.StatusForbidden, APIResponse{
-				Code:    1003,
-				Message: err.Error(),
-			})
-		} else {
-			ctx.JSON(http.StatusInternalServerError, APIResponse{
-				Code:    1005,
-				Message: err.Error(),
-			})
-		}
+		respondGenealogyRelationError(ctx, err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, APIResponse{
 		Code:    0,
 		Message: "获取成功",
-		Data:    reminders,
+		Data:    kinship,
 	})
 }
 
-// DeleteReminder 删除纪念日提醒
-func (c *FamilyController) DeleteReminder(ctx *gin.Context) {
+// AuditGenealogy 检查谱系的一致性
+func (c *FamilyController) AuditGenealogy(ctx *gin.Context) {
 	userID, exists := ctx.Get("user_id")
 	if !exists {
 		ctx.JSON(http.StatusUnauthorized, APIResponse{
@@ -969,50 +1586,21 @@ func (c *FamilyController) DeleteReminder(ctx *gin.Context) {
 		return
 	}
 
-	familyID := ctx.Param("family_id")
-	reminderID := ctx.Param("reminder_id")
-	if familyID == "" || reminderID == "" {
-		ctx.JSON(http.StatusBadRequest, APIResponse{
-			Code:    1001,
-			Message: "家族圈ID和提醒ID不能为空",
-		})
-		return
-	}
-
-	err := c.familyService.DeleteReminder(userID.(string), familyID, reminderID)
+	audit, err := c.familyService.AuditGenealogy(userID.(string), ctx.Param("family_id"))
 	if err != nil {
-		if err.Error() == "只有管理员可以删除纪念日提醒" {
-			ctx.JSON(http.StatusForbidden, APIResponse{
-				Code:    1003,
-				Message: err.Error(),
-			})
-		} else if err.Error() == "提醒不存在" {
-			ctx.JSON(http.StatusNotFound, APIResponse{
-				Code:    1004,
-				Message: err.Error(),
-			})
-		} else if err.Error() == "无权删除此提醒" {
-			ctx.JSON(http.StatusForbidden, APIResponse{
-				Code:    1003,
-				Message: err.Error(),
-			})
-		} else {
-			ctx.JSON(http.StatusInternalServerError, APIResponse{
-				Code:    1005,
-				Message: err.Error(),
-			})
-		}
+		respondGenealogyRelationError(ctx, err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, APIResponse{
 		Code:    0,
-		Message: "删除成功",
+		Message: "获取成功",
+		Data:    audit,
 	})
 }
 
-// InitiateCollectiveWorship 发起集体祭扫
-func (c *FamilyController) InitiateCollectiveWorship(ctx *gin.Context) {
+// GetGenealogyMemorialSuggestions 按姓名和生卒日期推荐谱系成员可关联的纪念馆
+func (c *FamilyController) GetGenealogyMemorialSuggestions(ctx *gin.Context) {
 	userID, exists := ctx.Get("user_id")
 	if !exists {
 		ctx.JSON(http.StatusUnauthorized, APIResponse{
@@ -1022,37 +1610,50 @@ func (c *FamilyController) InitiateCollectiveWorship(ctx *gin.Context) {
 		return
 	}
 
-	familyID := ctx.Param("family_id")
-	if familyID == "" {
-		ctx.JSON(http.StatusBadRequest, APIResponse{
-			Code:    1001,
-			Message: "家族圈ID不能为空",
-		})
+	suggestions, err := c.familyService.GetGenealogyMemorialSuggestions(userID.(string), ctx.Param("family_id"))
+	if err != nil {
+		respondGenealogyRelationError(ctx, err)
 		return
 	}
 
-	var req services.CollectiveWorshipRequest
-	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, APIResponse{
-			Code:    1001,
-			Message: "请求参数错误: " + err.Error(),
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "获取成功",
+		Data:    suggestions,
+	})
+}
+
+// GetRelatedMemorials 按谱系关系获取亲属的纪念馆
+func (c *FamilyController) GetRelatedMemorials(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
 		})
 		return
 	}
 
-	err := c.familyService.InitiateCollectiveWorship(userID.(string), familyID, &req)
+	maxDistance, _ := strconv.Atoi(ctx.DefaultQuery("max_distance", "4"))
+	related, err := c.familyService.GetRelatedMemorials(userID.(string), ctx.Param("id"), maxDistance, ctx.Query("region"))
 	if err != nil {
-		if err.Error() == "只有管理员可以发起集体祭扫" {
+		switch err.Error() {
+		case "纪念馆不存在":
+			ctx.JSON(http.StatusNotFound, APIResponse{
+				Code:    1004,
+				Message: err.Error(),
+			})
+		case "无权访问私密纪念馆", "无权访问此纪念馆":
 			ctx.JSON(http.StatusForbidden, APIResponse{
 				Code:    1003,
 				Message: err.Error(),
 			})
-		} else if err.Error() == "纪念馆未关联到此家族圈" || err.Error() == "无效的祭扫类型" {
+		case "无效的称谓地区":
 			ctx.JSON(http.StatusBadRequest, APIResponse{
 				Code:    1001,
 				Message: err.Error(),
 			})
-		} else {
+		default:
 			ctx.JSON(http.StatusInternalServerError, APIResponse{
 				Code:    1005,
 				Message: err.Error(),
@@ -1063,12 +1664,13 @@ func (c *FamilyController) InitiateCollectiveWorship(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, APIResponse{
 		Code:    0,
-		Message: "集体祭扫发起成功",
+		Message: "获取成功",
+		Data:    related,
 	})
 }
 
-// JoinCollectiveWorship 参与集体祭扫
-func (c *FamilyController) JoinCollectiveWorship(ctx *gin.Context) {
+// SetMyGenealogy 关联本人在谱系中对应的成员
+func (c *FamilyController) SetMyGenealogy(ctx *gin.Context) {
 	userID, exists := ctx.Get("user_id")
 	if !exists {
 		ctx.JSON(http.StatusUnauthorized, APIResponse{
@@ -1078,50 +1680,28 @@ func (c *FamilyController) JoinCollectiveWorship(ctx *gin.Context) {
 		return
 	}
 
-	familyID := ctx.Param("family_id")
-	activityID := ctx.Param("activity_id")
-	if familyID == "" || activityID == "" {
+	var req services.SetMyGenealogyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, APIResponse{
 			Code:    1001,
-			Message: "家族圈ID和活动ID不能为空",
+			Message: "请求参数错误: " + err.Error(),
 		})
 		return
 	}
 
-	err := c.familyService.JoinCollectiveWorship(userID.(string), familyID, activityID)
-	if err != nil {
-		if err.Error() == "您不是此家族圈的成员" {
-			ctx.JSON(http.StatusForbidden, APIResponse{
-				Code:    1003,
-				Message: err.Error(),
-			})
-		} else if err.Error() == "集体祭扫活动不存在" {
-			ctx.JSON(http.StatusNotFound, APIResponse{
-				Code:    1004,
-				Message: err.Error(),
-			})
-		} else if err.Error() == "活动已结束" || err.Error() == "您已经参与了此活动" {
-			ctx.JSON(http.StatusBadRequest, APIResponse{
-				Code:    1001,
-				Message: err.Error(),
-			})
-		} else {
-			ctx.JSON(http.StatusInternalServerError, APIResponse{
-				Code:    1005,
-				Message: err.Error(),
-			})
-		}
+	if err := c.familyService.SetMyGenealogy(userID.(string), ctx.Param("family_id"), &req); err != nil {
+		respondGenealogyRelationError(ctx, err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, APIResponse{
 		Code:    0,
-		Message: "参与成功",
+		Message: "设置成功",
 	})
 }
 
-// CreateGenealogy 创建家族谱系成员
-func (c *FamilyController) CreateGenealogy(ctx *gin.Context) {
+// CreateGenealogyRelation 建立谱系成员之间的父母子女或配偶关系
+func (c *FamilyController) CreateGenealogyRelation(ctx *gin.Context) {
 	userID, exists := ctx.Get("user_id")
 	if !exists {
 		ctx.JSON(http.StatusUnauthorized, APIResponse{
@@ -1131,16 +1711,7 @@ func (c *FamilyController) CreateGenealogy(ctx *gin.Context) {
 		return
 	}
 
-	familyID := ctx.Param("family_id")
-	if familyID == "" {
-		ctx.JSON(http.StatusBadRequest, APIResponse{
-			Code:    1001,
-			Message: "家族圈ID不能为空",
-		})
-		return
-	}
-
-	var req services.CreateGenealogyRequest
+	var req services.CreateGenealogyRelationRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, APIResponse{
 			Code:    1001,
@@ -1149,36 +1720,21 @@ func (c *FamilyController) CreateGenealogy(ctx *gin.Context) {
 		return
 	}
 
-	genealogy, err := c.familyService.CreateGenealogy(userID.(string), familyID, &req)
+	relation, err := c.familyService.CreateGenealogyRelation(userID.(string), ctx.Param("family_id"), &req)
 	if err != nil {
-		if err.Error() == "只有管理员可以创建家族谱系" {
-			ctx.JSON(http.StatusForbidden, APIResponse{
-				Code:    1003,
-				Message: err.Error(),
-			})
-		} else if err.Error() == "无效的性别" || err.Error() == "指定的父辈不存在" || err.Error() == "纪念馆未关联到此家族圈" {
-			ctx.JSON(http.StatusBadRequest, APIResponse{
-				Code:    1001,
-				Message: err.Error(),
-			})
-		} else {
-			ctx.JSON(http.StatusInternalServerError, APIResponse{
-				Code:    1005,
-				Message: err.Error(),
-			})
-		}
+		respondGenealogyRelationError(ctx, err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, APIResponse{
 		Code:    0,
 		Message: "创建成功",
-		Data:    genealogy,
+		Data:    relation,
 	})
 }
 
-// GetFamilyGenealogy 获取家族谱系
-func (c *FamilyController) GetFamilyGenealogy(ctx *gin.Context) {
+// UpdateGenealogyRelation 更新谱系关系
+func (c *FamilyController) UpdateGenealogyRelation(ctx *gin.Context) {
 	userID, exists := ctx.Get("user_id")
 	if !exists {
 		ctx.JSON(http.StatusUnauthorized, APIResponse{
@@ -1188,38 +1744,83 @@ func (c *FamilyController) GetFamilyGenealogy(ctx *gin.Context) {
 		return
 	}
 
-	familyID := ctx.Param("family_id")
-	if familyID == "" {
+	var req services.UpdateGenealogyRelationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, APIResponse{
 			Code:    1001,
-			Message: "家族圈ID不能为空",
+			Message: "请求参数错误: " + err.Error(),
 		})
 		return
 	}
 
-	genealogies, err := c.familyService.GetFamilyGenealogy(userID.(string), familyID)
+	err := c.familyService.UpdateGenealogyRelation(userID.(string), ctx.Param("family_id"), ctx.Param("relation_id"), &req)
 	if err != nil {
-		if err.Error() == "您不是此家族圈的成员" {
-			ctx.JSON(http.StatusForbidden, APIResponse{
-				Code:    1003,
-				Message: err.Error(),
-			})
-		} else {
-			ctx.JSON(http.StatusInternalServerError, APIResponse{
-				Code:    1005,
-				Message: err.Error(),
-			})
-		}
+		respondGenealogyRelationError(ctx, err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, APIResponse{
 		Code:    0,
-		Message: "获取成功",
-		Data:    genealogies,
+		Message: "更新成功",
+	})
+}
+
+// DeleteGenealogyRelation 删除谱系关系
+func (c *FamilyController) DeleteGenealogyRelation(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	err := c.familyService.DeleteGenealogyRelation(userID.(string), ctx.Param("family_id"), ctx.Param("relation_id"))
+	if err != nil {
+		respondGenealogyRelationError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "删除成功",
 	})
 }
 
+func respondGenealogyRelationError(ctx *gin.Context, err error) {
+	if strings.HasPrefix(err.Error(), "谱系数据不一致") {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: err.Error(),
+		})
+		return
+	}
+	switch err.Error() {
+	case "您不是此家族圈的成员", "您没有编辑家族谱系的权限":
+		ctx.JSON(http.StatusForbidden, APIResponse{
+			Code:    1003,
+			Message: err.Error(),
+		})
+	case "谱系成员不存在", "关系不存在":
+		ctx.JSON(http.StatusNotFound, APIResponse{
+			Code:    1004,
+			Message: err.Error(),
+		})
+	case "不能与自己建立关系", "关系已存在", "不能将后代设为父辈", "亲生父母最多两位", "离婚日期不能早于结婚日期", "两人之间没有亲属关系",
+		"无效的称谓地区", "请先在谱系中关联本人":
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: err.Error(),
+		})
+	default:
+		ctx.JSON(http.StatusInternalServerError, APIResponse{
+			Code:    1005,
+			Message: err.Error(),
+		})
+	}
+}
+
 // UpdateGenealogy 更新家族谱系成员
 func (c *FamilyController) UpdateGenealogy(ctx *gin.Context) {
 	userID, exists := ctx.Get("user_id")
@@ -1252,7 +1853,7 @@ func (c *FamilyController) UpdateGenealogy(ctx *gin.Context) {
 
 	err := c.familyService.UpdateGenealogy(userID.(string), familyID, genealogyID, &req)
 	if err != nil {
-		if err.Error() == "只有管理员可以更新家族谱系" {
+		if err.Error() == "您没有编辑家族谱系的权限" {
 			ctx.JSON(http.StatusForbidden, APIResponse{
 				Code:    1003,
 				Message: err.Error(),
@@ -1262,6 +1863,12 @@ func (c *FamilyController) UpdateGenealogy(ctx *gin.Context) {
 				Code:    1004,
 				Message: err.Error(),
 			})
+		} else if err.Error() == "纪念馆未关联到此家族圈" || err.Error() == "该纪念馆已关联其他谱系成员" ||
+			strings.HasPrefix(err.Error(), "谱系数据不一致") {
+			ctx.JSON(http.StatusBadRequest, APIResponse{
+				Code:    1001,
+				Message: err.Error(),
+			})
 		} else {
 			ctx.JSON(http.StatusInternalServerError, APIResponse{
 				Code:    1005,
@@ -1300,7 +1907,7 @@ func (c *FamilyController) DeleteGenealogy(ctx *gin.Context) {
 
 	err := c.familyService.DeleteGenealogy(userID.(string), familyID, genealogyID)
 	if err != nil {
-		if err.Error() == "只有管理员可以删除家族谱系" {
+		if err.Error() == "您没有编辑家族谱系的权限" {
 			ctx.JSON(http.StatusForbidden, APIResponse{
 				Code:    1003,
 				Message: err.Error(),
@@ -1361,7 +1968,7 @@ func (c *FamilyController) CreateFamilyStory(ctx *gin.Context) {
 
 	story, err := c.familyService.CreateFamilyStory(userID.(string), familyID, &req)
 	if err != nil {
-		if err.Error() == "您不是此家族圈的成员" {
+		if err.Error() == "您没有发布家族故事的权限" {
 			ctx.JSON(http.StatusForbidden, APIResponse{
 				Code:    1003,
 				Message: err.Error(),
@@ -1371,6 +1978,11 @@ func (c *FamilyController) CreateFamilyStory(ctx *gin.Context) {
 				Code:    1001,
 				Message: err.Error(),
 			})
+		} else if err == services.ErrContentBlocked {
+			ctx.JSON(http.StatusBadRequest, APIResponse{
+				Code:    1001,
+				Message: err.Error(),
+			})
 		} else {
 			ctx.JSON(http.StatusInternalServerError, APIResponse{
 				Code:    1005,
@@ -1528,7 +2140,7 @@ func (c *FamilyController) UpdateFamilyStory(ctx *gin.Context) {
 
 	err := c.familyService.UpdateFamilyStory(userID.(string), familyID, storyID, &req)
 	if err != nil {
-		if err.Error() == "只有作者或管理员可以更新故事" {
+		if err.Error() == "您没有管理家族故事的权限" || err.Error() == "您不是此家族圈的成员" {
 			ctx.JSON(http.StatusForbidden, APIResponse{
 				Code:    1003,
 				Message: err.Error(),
@@ -1538,6 +2150,11 @@ func (c *FamilyController) UpdateFamilyStory(ctx *gin.Context) {
 				Code:    1004,
 				Message: err.Error(),
 			})
+		} else if err == services.ErrContentBlocked {
+			ctx.JSON(http.StatusBadRequest, APIResponse{
+				Code:    1001,
+				Message: err.Error(),
+			})
 		} else {
 			ctx.JSON(http.StatusInternalServerError, APIResponse{
 				Code:    1005,
@@ -1576,7 +2193,7 @@ func (c *FamilyController) DeleteFamilyStory(ctx *gin.Context) {
 
 	err := c.familyService.DeleteFamilyStory(userID.(string), familyID, storyID)
 	if err != nil {
-		if err.Error() == "只有作者或管理员可以删除故事" {
+		if err.Error() == "您没有管理家族故事的权限" || err.Error() == "您不是此家族圈的成员" {
 			ctx.JSON(http.StatusForbidden, APIResponse{
 				Code:    1003,
 				Message: err.Error(),
@@ -1632,12 +2249,12 @@ func (c *FamilyController) CreateFamilyTradition(ctx *gin.Context) {
 
 	tradition, err := c.familyService.CreateFamilyTradition(userID.(string), familyID, &req)
 	if err != nil {
-		if err.Error() == "只有管理员可以创建家族传统" {
+		if err.Error() == "您没有编辑家族传统的权限" {
 			ctx.JSON(http.StatusForbidden, APIResponse{
 				Code:    1003,
 				Message: err.Error(),
 			})
-		} else if err.Error() == "无效的传统分类" {
+		} else if err.Error() == "无效的传统分类" || isTraditionScheduleError(err) {
 			ctx.JSON(http.StatusBadRequest, APIResponse{
 				Code:    1001,
 				Message: err.Error(),
@@ -1750,7 +2367,7 @@ func (c *FamilyController) UpdateFamilyTradition(ctx *gin.Context) {
 
 	err := c.familyService.UpdateFamilyTradition(userID.(string), familyID, traditionID, &req)
 	if err != nil {
-		if err.Error() == "只有管理员可以更新家族传统" {
+		if err.Error() == "您没有编辑家族传统的权限" {
 			ctx.JSON(http.StatusForbidden, APIResponse{
 				Code:    1003,
 				Message: err.Error(),
@@ -1760,6 +2377,11 @@ func (c *FamilyController) UpdateFamilyTradition(ctx *gin.Context) {
 				Code:    1004,
 				Message: err.Error(),
 			})
+		} else if isTraditionScheduleError(err) {
+			ctx.JSON(http.StatusBadRequest, APIResponse{
+				Code:    1001,
+				Message: err.Error(),
+			})
 		} else {
 			ctx.JSON(http.StatusInternalServerError, APIResponse{
 				Code:    1005,
@@ -1775,6 +2397,81 @@ func (c *FamilyController) UpdateFamilyTradition(ctx *gin.Context) {
 	})
 }
 
+// isTraditionScheduleError 传统日期安排的校验错误
+func isTraditionScheduleError(err error) bool {
+	switch err.Error() {
+	case "无效的历法", "无效的重复方式", "无效的传统日期", "仅一次的传统须指定年份":
+		return true
+	}
+	return false
+}
+
+// GetFamilyCalendar 获取家族日历（from、to 为 YYYY-MM-DD，默认今天起30天）
+func (c *FamilyController) GetFamilyCalendar(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	if value := ctx.Query("from"); value != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, APIResponse{
+				Code:    1001,
+				Message: "日期格式错误，应为 YYYY-MM-DD",
+			})
+			return
+		}
+		from = parsed
+	}
+	to := from.AddDate(0, 0, 30)
+	if value := ctx.Query("to"); value != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, APIResponse{
+				Code:    1001,
+				Message: "日期格式错误，应为 YYYY-MM-DD",
+			})
+			return
+		}
+		to = parsed
+	}
+
+	events, err := c.familyService.GetFamilyCalendar(userID.(string), ctx.Param("family_id"), from, to)
+	if err != nil {
+		switch err.Error() {
+		case "您不是此家族圈的成员":
+			ctx.JSON(http.StatusForbidden, APIResponse{
+				Code:    1003,
+				Message: err.Error(),
+			})
+		case "结束日期不能早于开始日期", "日历范围不能超过一年":
+			ctx.JSON(http.StatusBadRequest, APIResponse{
+				Code:    1001,
+				Message: err.Error(),
+			})
+		default:
+			ctx.JSON(http.StatusInternalServerError, APIResponse{
+				Code:    1005,
+				Message: err.Error(),
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "获取成功",
+		Data:    events,
+	})
+}
+
 // DeleteFamilyTradition 删除家族传统
 func (c *FamilyController) DeleteFamilyTradition(ctx *gin.Context) {
 	userID, exists := ctx.Get("user_id")
@@ -1798,7 +2495,7 @@ func (c *FamilyController) DeleteFamilyTradition(ctx *gin.Context) {
 
 	err := c.familyService.DeleteFamilyTradition(userID.(string), familyID, traditionID)
 	if err != nil {
-		if err.Error() == "只有管理员可以删除家族传统" {
+		if err.Error() == "您没有编辑家族传统的权限" {
 			ctx.JSON(http.StatusForbidden, APIResponse{
 				Code:    1003,
 				Message: err.Error(),
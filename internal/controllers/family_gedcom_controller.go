@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"yun-nian-memorial/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FamilyGedcomController struct {
+	gedcomService *services.FamilyGedcomService
+}
+
+func NewFamilyGedcomController(gedcomService *services.FamilyGedcomService) *FamilyGedcomController {
+	return &FamilyGedcomController{
+		gedcomService: gedcomService,
+	}
+}
+
+// PreviewGedcomImport 预览 GEDCOM 导入
+func (c *FamilyGedcomController) PreviewGedcomImport(ctx *gin.Context) {
+	c.handleImport(ctx, true)
+}
+
+// ImportGedcom 导入 GEDCOM 文件
+func (c *FamilyGedcomController) ImportGedcom(ctx *gin.Context) {
+	c.handleImport(ctx, false)
+}
+
+func (c *FamilyGedcomController) handleImport(ctx *gin.Context, preview bool) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	file, err := ctx.FormFile("file")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "请选择要导入的 GEDCOM 文件",
+		})
+		return
+	}
+	if file.Size > services.MaxGedcomFileSize {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "GEDCOM 文件不能超过 10MB",
+		})
+		return
+	}
+
+	var req services.GedcomImportRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+	if decisions := ctx.PostForm("decisions"); decisions != "" {
+		if err := json.Unmarshal([]byte(decisions), &req.Decisions); err != nil {
+			ctx.JSON(http.StatusBadRequest, APIResponse{
+				Code:    1001,
+				Message: "请求参数错误: decisions 格式错误",
+			})
+			return
+		}
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, APIResponse{
+			Code:    1005,
+			Message: "读取文件失败",
+		})
+		return
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, services.MaxGedcomFileSize+1))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, APIResponse{
+			Code:    1005,
+			Message: "读取文件失败",
+		})
+		return
+	}
+
+	var plan *services.GedcomImportPlan
+	message := "导入成功"
+	if preview {
+		plan, err = c.gedcomService.PreviewGedcomImport(userID.(string), ctx.Param("family_id"), data, &req)
+		message = "获取成功"
+	} else {
+		plan, err = c.gedcomService.ImportGedcom(userID.(string), ctx.Param("family_id"), data, &req)
+	}
+	if err != nil {
+		respondGedcomError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: message,
+		Data:    plan,
+	})
+}
+
+// ExportGedcom 导出家族谱系为 GEDCOM 文件（version=5.5.1|7.0）
+func (c *FamilyGedcomController) ExportGedcom(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	data, err := c.gedcomService.ExportGedcom(userID.(string), ctx.Param("family_id"), ctx.Query("version"))
+	if err != nil {
+		respondGedcomError(ctx, err)
+		return
+	}
+
+	ctx.Header("Content-Disposition", "attachment; filename=family_genealogy.ged")
+	ctx.Data(http.StatusOK, "text/vnd.familysearch.gedcom; charset=utf-8", data)
+}
+
+func respondGedcomError(ctx *gin.Context, err error) {
+	msg := err.Error()
+	switch {
+	case msg == "您不是此家族圈的成员" || msg == "您没有编辑家族谱系的权限":
+		ctx.JSON(http.StatusForbidden, APIResponse{
+			Code:    1003,
+			Message: msg,
+		})
+	case strings.Contains(msg, "GEDCOM") || strings.HasPrefix(msg, "无效的处理方式") || strings.Contains(msg, "没有可合并的谱系成员") ||
+		strings.HasPrefix(msg, "谱系数据不一致"):
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: msg,
+		})
+	default:
+		ctx.JSON(http.StatusInternalServerError, APIResponse{
+			Code:    1005,
+			Message: msg,
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"net/http"
+	"yun-nian-memorial/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FamilyGenealogyRenderController struct {
+	renderService *services.FamilyGenealogyRenderService
+}
+
+func NewFamilyGenealogyRenderController(renderService *services.FamilyGenealogyRenderService) *FamilyGenealogyRenderController {
+	return &FamilyGenealogyRenderController{
+		renderService: renderService,
+	}
+}
+
+// GetGenealogyChart 获取谱系图（format=svg|png，style=modern|traditional）
+func (c *FamilyGenealogyRenderController) GetGenealogyChart(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	var req services.GenealogyChartRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	chart, err := c.renderService.GetGenealogyChart(userID.(string), ctx.Param("family_id"), &req, ctx.GetHeader("If-None-Match"))
+	if err != nil {
+		msg := err.Error()
+		switch msg {
+		case "您不是此家族圈的成员":
+			ctx.JSON(http.StatusForbidden, APIResponse{
+				Code:    1003,
+				Message: msg,
+			})
+		case "谱系成员不存在":
+			ctx.JSON(http.StatusNotFound, APIResponse{
+				Code:    1004,
+				Message: msg,
+			})
+		case "谱系图过大，请折叠部分支系或导出 SVG":
+			ctx.JSON(http.StatusBadRequest, APIResponse{
+				Code:    1001,
+				Message: msg,
+			})
+		default:
+			ctx.JSON(http.StatusInternalServerError, APIResponse{
+				Code:    1005,
+				Message: msg,
+			})
+		}
+		return
+	}
+
+	ctx.Header("ETag", chart.ETag)
+	ctx.Header("Cache-Control", "private, no-cache")
+	if chart.Data == nil {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+	if ctx.Query("download") == "1" {
+		ext := "svg"
+		if req.Format == "png" {
+			ext = "png"
+		}
+		ctx.Header("Content-Disposition", "attachment; filename=family_genealogy."+ext)
+	}
+	ctx.Data(http.StatusOK, chart.ContentType, chart.Data)
+}
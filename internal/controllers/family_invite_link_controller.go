@@ -0,0 +1,321 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"yun-nian-memorial/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FamilyInviteLinkController struct {
+	inviteLinkService *services.FamilyInviteLinkService
+}
+
+func NewFamilyInviteLinkController(inviteLinkService *services.FamilyInviteLinkService) *FamilyInviteLinkController {
+	return &FamilyInviteLinkController{
+		inviteLinkService: inviteLinkService,
+	}
+}
+
+// CreateInviteLink 创建邀请链接
+func (c *FamilyInviteLinkController) CreateInviteLink(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	var req services.CreateInviteLinkRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	link, err := c.inviteLinkService.CreateInviteLink(userID.(string), ctx.Param("family_id"), &req)
+	if err != nil {
+		respondInviteLinkError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "创建成功",
+		Data:    link,
+	})
+}
+
+// GetInviteLinks 获取邀请链接列表
+func (c *FamilyInviteLinkController) GetInviteLinks(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	page, pageSize := inviteLinkPagination(ctx)
+
+	links, total, err := c.inviteLinkService.GetInviteLinks(userID.(string), ctx.Param("family_id"), page, pageSize)
+	if err != nil {
+		respondInviteLinkError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "获取成功",
+		Data: gin.H{
+			"list":      links,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
+
+// RevokeInviteLink 撤销邀请链接
+func (c *FamilyInviteLinkController) RevokeInviteLink(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	err := c.inviteLinkService.RevokeInviteLink(userID.(string), ctx.Param("family_id"), ctx.Param("link_id"))
+	if err != nil {
+		respondInviteLinkError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "已撤销",
+	})
+}
+
+// GetInviteLinkUses 获取通过邀请链接加入的用户
+func (c *FamilyInviteLinkController) GetInviteLinkUses(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	page, pageSize := inviteLinkPagination(ctx)
+
+	uses, total, err := c.inviteLinkService.GetInviteLinkUses(userID.(string), ctx.Param("family_id"), ctx.Param("link_id"), page, pageSize)
+	if err != nil {
+		respondInviteLinkError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "获取成功",
+		Data: gin.H{
+			"list":      uses,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
+
+// GetInviteLinkQRCode 获取邀请链接二维码图片（format=png|svg，scale 为每个模块的像素数）
+func (c *FamilyInviteLinkController) GetInviteLinkQRCode(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	scale, _ := strconv.Atoi(ctx.DefaultQuery("scale", "8"))
+	if scale < 1 || scale > 20 {
+		scale = 8
+	}
+
+	data, contentType, err := c.inviteLinkService.GetInviteLinkQRCode(userID.(string), ctx.Param("family_id"), ctx.Param("link_id"), ctx.Query("format"), scale)
+	if err != nil {
+		respondInviteLinkError(ctx, err)
+		return
+	}
+
+	ctx.Header("Cache-Control", "private, max-age=3600")
+	ctx.Data(http.StatusOK, contentType, data)
+}
+
+// GetJoinRequests 获取待审核的加入申请
+func (c *FamilyInviteLinkController) GetJoinRequests(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	page, pageSize := inviteLinkPagination(ctx)
+
+	requests, total, err := c.inviteLinkService.GetJoinRequests(userID.(string), ctx.Param("family_id"), page, pageSize)
+	if err != nil {
+		respondInviteLinkError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "获取成功",
+		Data: gin.H{
+			"list":      requests,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
+
+// ReviewJoinRequest 审核加入申请
+func (c *FamilyInviteLinkController) ReviewJoinRequest(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	var req services.ReviewJoinRequestRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	err := c.inviteLinkService.ReviewJoinRequest(userID.(string), ctx.Param("family_id"), ctx.Param("request_id"), req.Approve)
+	if err != nil {
+		respondInviteLinkError(ctx, err)
+		return
+	}
+
+	message := "已拒绝"
+	if req.Approve {
+		message = "已同意加入"
+	}
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: message,
+	})
+}
+
+// PreviewInviteLink 查看邀请链接对应的家族圈
+func (c *FamilyInviteLinkController) PreviewInviteLink(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	preview, err := c.inviteLinkService.PreviewInviteLink(userID.(string), ctx.Param("token"))
+	if err != nil {
+		respondInviteLinkError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "获取成功",
+		Data:    preview,
+	})
+}
+
+// JoinByInviteLink 通过邀请链接加入家族圈
+func (c *FamilyInviteLinkController) JoinByInviteLink(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	result, err := c.inviteLinkService.JoinByInviteLink(userID.(string), ctx.Param("token"))
+	if err != nil {
+		respondInviteLinkError(ctx, err)
+		return
+	}
+
+	message := "加入成功"
+	if result.Status == services.InviteLinkUsePending {
+		message = "已提交加入申请，等待管理员审核"
+	}
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: message,
+		Data:    result,
+	})
+}
+
+func inviteLinkPagination(ctx *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	return page, pageSize
+}
+
+func respondInviteLinkError(ctx *gin.Context, err error) {
+	switch err.Error() {
+	case "邀请链接不存在", "加入申请不存在", "邀请链接无效":
+		ctx.JSON(http.StatusNotFound, APIResponse{
+			Code:    1004,
+			Message: err.Error(),
+		})
+	case "您没有邀请成员的权限", "不能创建与自己同级或更高角色的邀请链接":
+		ctx.JSON(http.StatusForbidden, APIResponse{
+			Code:    1003,
+			Message: err.Error(),
+		})
+	case "邀请链接已撤销", "加入申请已处理", "邀请链接已失效", "邀请链接已过期", "邀请链接使用次数已满",
+		"您已经是此家族圈的成员", "您的加入申请正在审核中", "二维码内容过长":
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: err.Error(),
+		})
+	default:
+		ctx.JSON(http.StatusInternalServerError, APIResponse{
+			Code:    1005,
+			Message: err.Error(),
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package controllers
+
+import (
+	"net/http"
+	"yun-nian-memorial/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GuestTokenHeader 访客设备令牌请求头
+const GuestTokenHeader = "X-Guest-Token"
+
+type GuestWorshipController struct {
+	guestWorshipService *services.GuestWorshipService
+}
+
+func NewGuestWorshipController(guestWorshipService *services.GuestWorshipService) *GuestWorshipController {
+	return &GuestWorshipController{
+		guestWorshipService: guestWorshipService,
+	}
+}
+
+// CreateChallenge 获取访客验证题
+func (c *GuestWorshipController) CreateChallenge(ctx *gin.Context) {
+	challenge, err := c.guestWorshipService.CreateChallenge(ctx.ClientIP())
+	if err != nil {
+		respondGuestWorshipError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "获取成功",
+		Data:    challenge,
+	})
+}
+
+// CreateSession 回答验证题，获取访客设备令牌
+func (c *GuestWorshipController) CreateSession(ctx *gin.Context) {
+	var req services.CreateGuestSessionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	session, err := c.guestWorshipService.CreateSession(ctx.ClientIP(), ctx.GetHeader("User-Agent"), &req)
+	if err != nil {
+		respondGuestWorshipError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "验证成功",
+		Data:    session,
+	})
+}
+
+// OfferFlowers 访客献花
+func (c *GuestWorshipController) OfferFlowers(ctx *gin.Context) {
+	var req services.OfferFlowersRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	if err := c.guestWorshipService.OfferFlowers(ctx.GetHeader(GuestTokenHeader), ctx.Param("memorial_id"), &req); err != nil {
+		respondGuestWorshipError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "献花成功",
+	})
+}
+
+// LightCandle 访客点烛
+func (c *GuestWorshipController) LightCandle(ctx *gin.Context) {
+	var req services.LightCandleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	if err := c.guestWorshipService.LightCandle(ctx.GetHeader(GuestTokenHeader), ctx.Param("memorial_id"), &req); err != nil {
+		respondGuestWorshipError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "点烛成功",
+	})
+}
+
+// OfferIncense 访客上香
+func (c *GuestWorshipController) OfferIncense(ctx *gin.Context) {
+	var req services.OfferIncenseRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	if err := c.guestWorshipService.OfferIncense(ctx.GetHeader(GuestTokenHeader), ctx.Param("memorial_id"), &req); err != nil {
+		respondGuestWorshipError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "上香成功",
+	})
+}
+
+// OfferTribute 访客供奉供品
+func (c *GuestWorshipController) OfferTribute(ctx *gin.Context) {
+	var req services.OfferTributeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	if err := c.guestWorshipService.OfferTribute(ctx.GetHeader(GuestTokenHeader), ctx.Param("memorial_id"), &req); err != nil {
+		respondGuestWorshipError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "供奉成功",
+	})
+}
+
+// GetGuestWorshipSettings 获取访客祭扫设置（馆主）
+func (c *GuestWorshipController) GetGuestWorshipSettings(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	settings, err := c.guestWorshipService.GetGuestWorshipSettings(userID.(string), ctx.Param("memorial_id"))
+	if err != nil {
+		respondGuestWorshipError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "获取成功",
+		Data:    settings,
+	})
+}
+
+// UpdateGuestWorshipSettings 开启或关闭访客祭扫（馆主）
+func (c *GuestWorshipController) UpdateGuestWorshipSettings(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	var req services.UpdateGuestWorshipSettingsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	settings, err := c.guestWorshipService.UpdateGuestWorshipSettings(userID.(string), ctx.Param("memorial_id"), &req)
+	if err != nil {
+		respondGuestWorshipError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "设置成功",
+		Data:    settings,
+	})
+}
+
+func respondGuestWorshipError(ctx *gin.Context, err error) {
+	switch err.Error() {
+	case "纪念馆不存在":
+		ctx.JSON(http.StatusNotFound, APIResponse{
+			Code:    3001,
+			Message: err.Error(),
+		})
+	case "该纪念馆未开放访客祭扫", "只有纪念馆创建者可以设置访客祭扫":
+		ctx.JSON(http.StatusForbidden, APIResponse{
+			Code:    3002,
+			Message: err.Error(),
+		})
+	case "访客身份无效或已过期":
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: err.Error(),
+		})
+	case "请求过于频繁，请稍后再试", "访客身份创建过于频繁，请稍后再试", "访客祭扫过于频繁，请稍后再试":
+		ctx.JSON(http.StatusTooManyRequests, APIResponse{
+			Code:    1006,
+			Message: err.Error(),
+		})
+	case "验证题不存在或已失效", "验证答案错误，请重新获取验证题", "访客留言不能超过100字",
+		"定时时间格式错误", "定时时间不能早于当前时间", services.ErrContentBlocked.Error():
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: err.Error(),
+		})
+	default:
+		ctx.JSON(http.StatusInternalServerError, APIResponse{
+			Code:    1005,
+			Message: err.Error(),
+		})
+	}
+}
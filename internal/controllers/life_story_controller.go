@@ -59,6 +59,11 @@ func (c *LifeStoryController) CreateLifeStory(ctx *gin.Context) {
 				Code:    3002,
 				Message: err.Error(),
 			})
+		} else if err == services.ErrContentBlocked {
+			ctx.JSON(http.StatusBadRequest, APIResponse{
+				Code:    1001,
+				Message: err.Error(),
+			})
 		} else {
 			ctx.JSON(http.StatusInternalServerError, APIResponse{
 				Code:    1005,
@@ -228,6 +233,11 @@ func (c *LifeStoryController) UpdateLifeStory(ctx *gin.Context) {
 				Code:    1003,
 				Message: err.Error(),
 			})
+		} else if err == services.ErrContentBlocked {
+			ctx.JSON(http.StatusBadRequest, APIResponse{
+				Code:    1001,
+				Message: err.Error(),
+			})
 		} else {
 			ctx.JSON(http.StatusInternalServerError, APIResponse{
 				Code:    1005,
@@ -40,10 +40,17 @@ func (c *MemorialController) CreateMemorial(ctx *gin.Context) {
 
 	memorial, err := c.memorialService.CreateMemorial(userID.(string), &req)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, APIResponse{
-			Code:    1005,
-			Message: err.Error(),
-		})
+		if err == services.ErrContentBlocked {
+			ctx.JSON(http.StatusBadRequest, APIResponse{
+				Code:    1001,
+				Message: err.Error(),
+			})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, APIResponse{
+				Code:    1005,
+				Message: err.Error(),
+			})
+		}
 		return
 	}
 
@@ -174,6 +181,11 @@ func (c *MemorialController) UpdateMemorial(ctx *gin.Context) {
 				Code:    3002,
 				Message: err.Error(),
 			})
+		} else if err == services.ErrContentBlocked {
+			ctx.JSON(http.StatusBadRequest, APIResponse{
+				Code:    1001,
+				Message: err.Error(),
+			})
 		} else {
 			ctx.JSON(http.StatusInternalServerError, APIResponse{
 				Code:    1005,
@@ -461,6 +473,11 @@ func (c *MemorialController) UpdateEpitaph(ctx *gin.Context) {
 				Code:    3002,
 				Message: err.Error(),
 			})
+		} else if err == services.ErrContentBlocked {
+			ctx.JSON(http.StatusBadRequest, APIResponse{
+				Code:    1001,
+				Message: err.Error(),
+			})
 		} else {
 			ctx.JSON(http.StatusInternalServerError, APIResponse{
 				Code:    1005,
@@ -618,6 +618,11 @@ func (c *MemorialServiceController) SendChatMessage(ctx *gin.Context) {
 				Code:    1003,
 				Message: err.Error(),
 			})
+		} else if err == services.ErrContentBlocked {
+			ctx.JSON(http.StatusBadRequest, APIResponse{
+				Code:    1001,
+				Message: err.Error(),
+			})
 		} else {
 			ctx.JSON(http.StatusInternalServerError, APIResponse{
 				Code:    1005,
@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+	"yun-nian-memorial/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MourningController struct {
+	mourningService *services.MourningService
+}
+
+func NewMourningController(mourningService *services.MourningService) *MourningController {
+	return &MourningController{
+		mourningService: mourningService,
+	}
+}
+
+// GetMourningRuleSets 获取可选的守丧规则
+func (c *MourningController) GetMourningRuleSets(ctx *gin.Context) {
+	ruleSets, err := c.mourningService.GetMourningRuleSets()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, APIResponse{
+			Code:    1005,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "获取成功",
+		Data:    ruleSets,
+	})
+}
+
+// GetMourningSchedule 获取纪念馆的守丧日程，尚未生成时返回按 rule_set 推算的预览
+func (c *MourningController) GetMourningSchedule(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	schedule, err := c.mourningService.GetMourningSchedule(userID.(string), ctx.Param("family_id"), ctx.Param("memorial_id"), ctx.Query("rule_set"))
+	if err != nil {
+		respondMourningError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "获取成功",
+		Data:    schedule,
+	})
+}
+
+// GenerateMourningSchedule 生成或重新生成纪念馆的守丧日程
+func (c *MourningController) GenerateMourningSchedule(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	var req services.GenerateMourningScheduleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	schedule, err := c.mourningService.GenerateMourningSchedule(userID.(string), ctx.Param("family_id"), ctx.Param("memorial_id"), &req)
+	if err != nil {
+		respondMourningError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "守丧日程已生成",
+		Data:    schedule,
+	})
+}
+
+// DeleteMourningSchedule 删除纪念馆的守丧日程
+func (c *MourningController) DeleteMourningSchedule(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	if err := c.mourningService.DeleteMourningSchedule(userID.(string), ctx.Param("family_id"), ctx.Param("memorial_id")); err != nil {
+		respondMourningError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "删除成功",
+	})
+}
+
+// ScheduleMourningCollectiveWorship 为守丧日程的某一天安排集体祭扫
+func (c *MourningController) ScheduleMourningCollectiveWorship(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	var req services.MourningCollectiveWorshipRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	event, err := c.mourningService.ScheduleMourningCollectiveWorship(userID.(string), ctx.Param("family_id"), ctx.Param("memorial_id"), ctx.Param("key"), &req)
+	if err != nil {
+		respondMourningError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "集体祭扫发起成功",
+		Data:    event,
+	})
+}
+
+func respondMourningError(ctx *gin.Context, err error) {
+	switch {
+	case err.Error() == "守丧日程不存在", err.Error() == "守丧日程项不存在", err.Error() == "守丧规则不存在",
+		err.Error() == "纪念馆不存在":
+		ctx.JSON(http.StatusNotFound, APIResponse{
+			Code:    1004,
+			Message: err.Error(),
+		})
+	case err.Error() == "您不是此家族圈的成员", err.Error() == "无权访问此纪念馆", strings.HasPrefix(err.Error(), "您没有"):
+		ctx.JSON(http.StatusForbidden, APIResponse{
+			Code:    1003,
+			Message: err.Error(),
+		})
+	case err.Error() == "纪念馆未关联到此家族圈", err.Error() == "纪念馆未填写逝世日期", err.Error() == "这一天已安排集体祭扫",
+		err.Error() == "无效的开始时刻", err.Error() == "约定时间不能早于当前时间", isReminderRuleError(err),
+		err.Error() == services.ErrContentBlocked.Error():
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: err.Error(),
+		})
+	default:
+		ctx.JSON(http.StatusInternalServerError, APIResponse{
+			Code:    1005,
+			Message: err.Error(),
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"yun-nian-memorial/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationController struct {
+	notificationService *services.NotificationService
+}
+
+func NewNotificationController(notificationService *services.NotificationService) *NotificationController {
+	return &NotificationController{
+		notificationService: notificationService,
+	}
+}
+
+// GetNotifications 获取通知列表
+func (c *NotificationController) GetNotifications(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	category := ctx.Query("category")
+	unreadOnly := ctx.Query("unread") == "true"
+
+	notifications, total, err := c.notificationService.GetNotifications(userID.(string), category, unreadOnly, page, pageSize)
+	if err != nil {
+		if err.Error() == "无效的通知分类" {
+			ctx.JSON(http.StatusBadRequest, APIResponse{
+				Code:    1001,
+				Message: err.Error(),
+			})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, APIResponse{
+				Code:    1005,
+				Message: err.Error(),
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "获取成功",
+		Data: gin.H{
+			"list":      notifications,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
+
+// GetUnreadCount 获取未读通知数
+func (c *NotificationController) GetUnreadCount(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	count, err := c.notificationService.GetUnreadCount(userID.(string))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, APIResponse{
+			Code:    1005,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "获取成功",
+		Data:    count,
+	})
+}
+
+// MarkAsRead 标记通知为已读
+func (c *NotificationController) MarkAsRead(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	err := c.notificationService.MarkAsRead(userID.(string), ctx.Param("id"))
+	if err != nil {
+		if err.Error() == "通知不存在" {
+			ctx.JSON(http.StatusNotFound, APIResponse{
+				Code:    1004,
+				Message: err.Error(),
+			})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, APIResponse{
+				Code:    1005,
+				Message: err.Error(),
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "已标记为已读",
+	})
+}
+
+// MarkAllAsRead 全部标记为已读，可按分类
+func (c *NotificationController) MarkAllAsRead(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	updated, err := c.notificationService.MarkAllAsRead(userID.(string), ctx.Query("category"))
+	if err != nil {
+		if err.Error() == "无效的通知分类" {
+			ctx.JSON(http.StatusBadRequest, APIResponse{
+				Code:    1001,
+				Message: err.Error(),
+			})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, APIResponse{
+				Code:    1005,
+				Message: err.Error(),
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "已全部标记为已读",
+		Data:    gin.H{"updated": updated},
+	})
+}
+
+// DeleteNotification 删除通知
+func (c *NotificationController) DeleteNotification(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	err := c.notificationService.DeleteNotification(userID.(string), ctx.Param("id"))
+	if err != nil {
+		if err.Error() == "通知不存在" {
+			ctx.JSON(http.StatusNotFound, APIResponse{
+				Code:    1004,
+				Message: err.Error(),
+			})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, APIResponse{
+				Code:    1005,
+				Message: err.Error(),
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "删除成功",
+	})
+}
+
+// GetSettings 获取通知设置
+func (c *NotificationController) GetSettings(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	setting, err := c.notificationService.GetSettings(userID.(string))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, APIResponse{
+			Code:    1005,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "获取成功",
+		Data:    setting,
+	})
+}
+
+// UpdateSettings 更新通知设置
+func (c *NotificationController) UpdateSettings(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	var req services.UpdateNotificationSettingsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	setting, err := c.notificationService.UpdateSettings(userID.(string), &req)
+	if err != nil {
+		if err.Error() == "无效的通知分类" || err.Error() == "开启邮件通知须填写邮箱" {
+			ctx.JSON(http.StatusBadRequest, APIResponse{
+				Code:    1001,
+				Message: err.Error(),
+			})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, APIResponse{
+				Code:    1005,
+				Message: err.Error(),
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "设置成功",
+		Data:    setting,
+	})
+}
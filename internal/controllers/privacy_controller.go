@@ -176,7 +176,12 @@ func (c *PrivacyController) RequestAccess(ctx *gin.Context) {
 
 	err := c.privacyService.RequestAccess(userID.(string), memorialID, req.Message)
 	if err != nil {
-		if err.Error() == "已有待处理的访问申请" {
+		if err.Error() == "纪念馆不存在" {
+			ctx.JSON(http.StatusNotFound, APIResponse{
+				Code:    1004,
+				Message: err.Error(),
+			})
+		} else if err.Error() == "已有待处理的访问申请" {
 			ctx.JSON(http.StatusBadRequest, APIResponse{
 				Code:    1001,
 				Message: err.Error(),
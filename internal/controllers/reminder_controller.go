@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"net/http"
+	"yun-nian-memorial/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReminderController struct {
+	reminderService *services.ReminderService
+}
+
+func NewReminderController(reminderService *services.ReminderService) *ReminderController {
+	return &ReminderController{
+		reminderService: reminderService,
+	}
+}
+
+// SnoozeReminder 某次纪念日提醒稍后再提醒
+func (c *ReminderController) SnoozeReminder(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	var req services.SnoozeReminderRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	action, err := c.reminderService.SnoozeReminder(userID.(string), ctx.Param("reminder_id"), &req)
+	if err != nil {
+		respondReminderError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "将稍后提醒",
+		Data:    action,
+	})
+}
+
+// DismissReminder 某次纪念日提醒不再提醒
+func (c *ReminderController) DismissReminder(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	var req services.DismissReminderRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	action, err := c.reminderService.DismissReminder(userID.(string), ctx.Param("reminder_id"), &req)
+	if err != nil {
+		respondReminderError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "本次不再提醒",
+		Data:    action,
+	})
+}
+
+func respondReminderError(ctx *gin.Context, err error) {
+	switch err.Error() {
+	case "提醒不存在":
+		ctx.JSON(http.StatusNotFound, APIResponse{
+			Code:    1004,
+			Message: err.Error(),
+		})
+	case "无权操作此提醒":
+		ctx.JSON(http.StatusForbidden, APIResponse{
+			Code:    1003,
+			Message: err.Error(),
+		})
+	case "日期格式错误，应为YYYY-MM-DD", "该日期没有此提醒", "提醒已过期", "稍后提醒不能晚于提醒当天":
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: err.Error(),
+		})
+	default:
+		ctx.JSON(http.StatusInternalServerError, APIResponse{
+			Code:    1005,
+			Message: err.Error(),
+		})
+	}
+}
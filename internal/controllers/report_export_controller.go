@@ -0,0 +1,186 @@
+package controllers
+
+import (
+	"net/http"
+	"yun-nian-memorial/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReportExportController struct {
+	reportExportService *services.ReportExportService
+}
+
+func NewReportExportController(reportExportService *services.ReportExportService) *ReportExportController {
+	return &ReportExportController{
+		reportExportService: reportExportService,
+	}
+}
+
+// CreateReportExport 创建祭扫报告导出任务
+func (c *ReportExportController) CreateReportExport(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	memorialID := ctx.Param("memorial_id")
+	if memorialID == "" {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "纪念馆ID不能为空",
+		})
+		return
+	}
+
+	var req services.CreateReportExportRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	job, err := c.reportExportService.CreateReportExport(userID.(string), memorialID, &req)
+	if err != nil {
+		if err.Error() == "纪念馆不存在" {
+			ctx.JSON(http.StatusNotFound, APIResponse{
+				Code:    3001,
+				Message: err.Error(),
+			})
+		} else if err.Error() == "无权访问此纪念馆" {
+			ctx.JSON(http.StatusForbidden, APIResponse{
+				Code:    3002,
+				Message: err.Error(),
+			})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, APIResponse{
+				Code:    1005,
+				Message: "创建导出任务失败: " + err.Error(),
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, APIResponse{
+		Code:    0,
+		Message: "导出任务已创建，请稍后下载",
+		Data:    job,
+	})
+}
+
+// GetReportExports 获取纪念馆的导出任务列表
+func (c *ReportExportController) GetReportExports(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	memorialID := ctx.Param("memorial_id")
+	if memorialID == "" {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "纪念馆ID不能为空",
+		})
+		return
+	}
+
+	jobs, err := c.reportExportService.GetReportExports(userID.(string), memorialID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, APIResponse{
+			Code:    1005,
+			Message: "获取导出任务失败: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "获取成功",
+		Data:    jobs,
+	})
+}
+
+// GetReportExport 查询导出任务状态
+func (c *ReportExportController) GetReportExport(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	job, err := c.reportExportService.GetReportExport(userID.(string), ctx.Param("export_id"))
+	if err != nil {
+		if err.Error() == "导出任务不存在" {
+			ctx.JSON(http.StatusNotFound, APIResponse{
+				Code:    1004,
+				Message: err.Error(),
+			})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, APIResponse{
+				Code:    1005,
+				Message: "获取导出任务失败: " + err.Error(),
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "获取成功",
+		Data:    job,
+	})
+}
+
+// DownloadReportExport 下载导出文件
+func (c *ReportExportController) DownloadReportExport(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	job, err := c.reportExportService.DownloadReportExport(userID.(string), ctx.Param("export_id"))
+	if err != nil {
+		switch err.Error() {
+		case "导出任务不存在", "导出文件不存在":
+			ctx.JSON(http.StatusNotFound, APIResponse{
+				Code:    1004,
+				Message: err.Error(),
+			})
+		case "导出尚未完成":
+			ctx.JSON(http.StatusConflict, APIResponse{
+				Code:    1001,
+				Message: err.Error(),
+			})
+		case "下载链接已过期":
+			ctx.JSON(http.StatusGone, APIResponse{
+				Code:    1001,
+				Message: err.Error(),
+			})
+		default:
+			ctx.JSON(http.StatusInternalServerError, APIResponse{
+				Code:    1005,
+				Message: "下载失败: " + err.Error(),
+			})
+		}
+		return
+	}
+
+	ctx.FileAttachment(job.FilePath, job.FileName)
+}
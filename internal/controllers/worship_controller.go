@@ -3,6 +3,7 @@ package controllers
 import (
 	"net/http"
 	"strconv"
+	"strings"
 	"yun-nian-memorial/internal/services"
 
 	"github.com/gin-gonic/gin"
@@ -348,6 +349,59 @@ func (c *WorshipController) OfferTribute(ctx *gin.Context) {
 	})
 }
 
+// OfferFamilyWorship 家族合祭，同一份祭品献给多位先人
+func (c *WorshipController) OfferFamilyWorship(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	var req services.FamilyWorshipRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	records, err := c.worshipService.OfferFamilyWorship(userID.(string), &req)
+	if err != nil {
+		if err.Error() == "纪念馆不存在" {
+			ctx.JSON(http.StatusNotFound, APIResponse{
+				Code:    3001,
+				Message: err.Error(),
+			})
+		} else if err.Error() == "无权访问此纪念馆" {
+			ctx.JSON(http.StatusForbidden, APIResponse{
+				Code:    3002,
+				Message: err.Error(),
+			})
+		} else if err == services.ErrContentBlocked || err.Error() == "无效的祭扫类型" {
+			ctx.JSON(http.StatusBadRequest, APIResponse{
+				Code:    1001,
+				Message: err.Error(),
+			})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, APIResponse{
+				Code:    1005,
+				Message: err.Error(),
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "合祭成功",
+		Data:    records,
+	})
+}
+
 // CreatePrayer 创建祈福
 func (c *WorshipController) CreatePrayer(ctx *gin.Context) {
 	userID, exists := ctx.Get("user_id")
@@ -389,6 +443,11 @@ func (c *WorshipController) CreatePrayer(ctx *gin.Context) {
 				Code:    3002,
 				Message: err.Error(),
 			})
+		} else if err == services.ErrContentBlocked {
+			ctx.JSON(http.StatusBadRequest, APIResponse{
+				Code:    1001,
+				Message: err.Error(),
+			})
 		} else {
 			ctx.JSON(http.StatusInternalServerError, APIResponse{
 				Code:    1005,
@@ -679,6 +738,91 @@ func (c *WorshipController) GetTimeMessages(ctx *gin.Context) {
 	})
 }
 
+// GetReceivedTimeCapsules 获取寄给我的已解锁时光胶囊
+func (c *WorshipController) GetReceivedTimeCapsules(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	// 获取分页参数
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+	unreadOnly := ctx.Query("unread") == "true"
+
+	capsules, total, err := c.worshipService.GetReceivedTimeCapsules(userID.(string), unreadOnly, page, pageSize)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, APIResponse{
+			Code:    1005,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "获取成功",
+		Data: gin.H{
+			"list":      capsules,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
+
+// MarkTimeCapsuleRead 标记时光胶囊已读
+func (c *WorshipController) MarkTimeCapsuleRead(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	messageID := ctx.Param("message_id")
+	if messageID == "" {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "留言ID不能为空",
+		})
+		return
+	}
+
+	if err := c.worshipService.MarkTimeCapsuleRead(userID.(string), messageID); err != nil {
+		if err.Error() == "时光胶囊不存在" {
+			ctx.JSON(http.StatusNotFound, APIResponse{
+				Code:    1004,
+				Message: err.Error(),
+			})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, APIResponse{
+				Code:    1005,
+				Message: err.Error(),
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "已标记为已读",
+	})
+}
+
 // GetWorshipStatistics 获取祭扫统计
 func (c *WorshipController) GetWorshipStatistics(ctx *gin.Context) {
 	userID, exists := ctx.Get("user_id")
@@ -907,7 +1051,10 @@ func (c *WorshipController) GetPopularPrayerContents(ctx *gin.Context) {
 		limit = 10
 	}
 
-	contents, err := c.worshipService.GetPopularPrayerContents(limit)
+	// 与逝者的关系，用于个性化排序
+	relationship := ctx.Query("relationship")
+
+	contents, err := c.worshipService.GetPopularPrayerContents(limit, relationship)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, APIResponse{
 			Code:    1005,
@@ -919,9 +1066,7 @@ func (c *WorshipController) GetPopularPrayerContents(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, APIResponse{
 		Code:    0,
 		Message: "获取成功",
-		Data: gin.H{
-			"contents": contents,
-		},
+		Data:    contents,
 	})
 }
 
@@ -1203,3 +1348,182 @@ func (c *WorshipController) GenerateWorshipReport(ctx *gin.Context) {
 		Data:    report,
 	})
 }
+
+// GetModerationSettings 获取纪念馆留言审核设置
+func (c *WorshipController) GetModerationSettings(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	settings, err := c.worshipService.GetModerationSettings(userID.(string), ctx.Param("memorial_id"))
+	if err != nil {
+		respondModerationError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "获取成功",
+		Data:    settings,
+	})
+}
+
+// UpdateModerationSettings 开启或关闭先审后发
+func (c *WorshipController) UpdateModerationSettings(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	var req services.UpdateModerationSettingsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	settings, err := c.worshipService.UpdateModerationSettings(userID.(string), ctx.Param("memorial_id"), &req)
+	if err != nil {
+		respondModerationError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "设置成功",
+		Data:    settings,
+	})
+}
+
+// GetModerationQueue 获取馆主审核列表
+func (c *WorshipController) GetModerationQueue(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	contentType := ctx.DefaultQuery("content_type", "message")
+	status := ctx.Query("status")
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	list, total, err := c.worshipService.GetModerationQueue(userID.(string), ctx.Param("memorial_id"), contentType, status, page, pageSize)
+	if err != nil {
+		respondModerationError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: "获取成功",
+		Data: gin.H{
+			"list":         list,
+			"total":        total,
+			"page":         page,
+			"page_size":    pageSize,
+			"content_type": contentType,
+		},
+	})
+}
+
+// ReviewMessage 馆主审核、隐藏或置顶留言
+func (c *WorshipController) ReviewMessage(ctx *gin.Context) {
+	c.reviewWorshipContent(ctx, "message", ctx.Param("message_id"))
+}
+
+// ReviewPrayer 馆主审核、隐藏或置顶祈福
+func (c *WorshipController) ReviewPrayer(ctx *gin.Context) {
+	c.reviewWorshipContent(ctx, "prayer", ctx.Param("prayer_id"))
+}
+
+func (c *WorshipController) reviewWorshipContent(ctx *gin.Context, contentType, contentID string) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, APIResponse{
+			Code:    1002,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	var req services.ReviewWorshipContentRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	if err := c.worshipService.ReviewWorshipContent(userID.(string), contentType, contentID, &req); err != nil {
+		respondModerationError(ctx, err)
+		return
+	}
+
+	actionMessages := map[string]string{
+		services.ModerationActionApprove: "已通过并公开",
+		services.ModerationActionReject:  "已拒绝",
+		services.ModerationActionHide:    "已隐藏",
+		services.ModerationActionPin:     "已置顶",
+		services.ModerationActionUnpin:   "已取消置顶",
+	}
+
+	ctx.JSON(http.StatusOK, APIResponse{
+		Code:    0,
+		Message: actionMessages[req.Action],
+	})
+}
+
+// respondModerationError 统一处理馆主审核相关错误
+func respondModerationError(ctx *gin.Context, err error) {
+	switch {
+	case err.Error() == "纪念馆不存在":
+		ctx.JSON(http.StatusNotFound, APIResponse{
+			Code:    3001,
+			Message: err.Error(),
+		})
+	case err.Error() == "只有纪念馆创建者可以管理留言和祈福":
+		ctx.JSON(http.StatusForbidden, APIResponse{
+			Code:    3002,
+			Message: err.Error(),
+		})
+	case err.Error() == "留言不存在" || err.Error() == "祈福不存在":
+		ctx.JSON(http.StatusNotFound, APIResponse{
+			Code:    1004,
+			Message: err.Error(),
+		})
+	case strings.HasPrefix(err.Error(), "只能") || strings.Contains(err.Error(), "无效") ||
+		strings.Contains(err.Error(), "不支持") || strings.Contains(err.Error(), "无需"):
+		ctx.JSON(http.StatusBadRequest, APIResponse{
+			Code:    1001,
+			Message: err.Error(),
+		})
+	default:
+		ctx.JSON(http.StatusInternalServerError, APIResponse{
+			Code:    1005,
+			Message: err.Error(),
+		})
+	}
+}
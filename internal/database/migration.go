@@ -30,13 +30,29 @@ func (m *Migration) AutoMigrate() error {
 		&models.WorshipRecord{},
 		&models.Family{},
 		&models.FamilyMember{},
+		&models.FamilyPermission{},
 		&models.FamilyActivity{},
+		&models.CollectiveWorship{},
+		&models.CollectiveWorshipParticipant{},
+		&models.CollectiveWorshipOffering{},
 		&models.MediaFile{},
 		&models.Prayer{},
 		&models.Message{},
+		&models.MessageRecipient{},
+		&models.WorshipReportExport{},
+		&models.MemorialWorshipDaily{},
+		&models.UserWorshipDaily{},
+		&models.MemorialVisitorStat{},
 		&models.MemorialReminder{},
+		&models.ReminderOccurrence{},
+		&models.ReminderOccurrenceAction{},
+		&models.MourningSchedule{},
+		&models.MourningObservance{},
 		&models.VisitorRecord{},
 		&models.MemorialFamily{},
+		&models.FamilyInvitation{},
+		&models.FamilyInviteLink{},
+		&models.FamilyInviteLinkUse{},
 		&models.Album{},
 		&models.AlbumPhoto{},
 		&models.LifeStory{},
@@ -49,6 +65,7 @@ func (m *Migration) AutoMigrate() error {
 		&models.ServiceRecording{},
 		&models.ServiceChat{},
 		&models.FamilyGenealogy{},
+		&models.GenealogyRelation{},
 		&models.FamilyStory{},
 		&models.FamilyTradition{},
 		&models.VisitorPermissionSetting{},
@@ -77,6 +94,17 @@ func (m *Migration) AutoMigrate() error {
 		&models.CustomDesignRequest{},
 		&models.ServiceReview{},
 		&models.ServiceStaff{},
+		// 内容审核相关模型
+		&models.ContentFilterHit{},
+		// 接口幂等相关模型
+		&models.IdempotencyRecord{},
+		// 访客祭扫相关模型
+		&models.GuestIdentity{},
+		&models.GuestChallenge{},
+		// 通知相关模型
+		&models.Notification{},
+		&models.NotificationDelivery{},
+		&models.NotificationSetting{},
 	}
 
 	// 执行自动迁移
@@ -87,10 +115,48 @@ func (m *Migration) AutoMigrate() error {
 		log.Printf("成功迁移模型: %T", model)
 	}
 
+	// 家族圈角色细分后，原先以管理员身份记录的创建者升级为所有者
+	if err := m.db.Exec("UPDATE family_members fm JOIN families f ON f.id = fm.family_id SET fm.role = ? WHERE fm.user_id = f.creator_id AND fm.role = ?",
+		"owner", "admin").Error; err != nil {
+		return fmt.Errorf("升级家族圈所有者角色失败: %v", err)
+	}
+
+	if err := m.migrateGenealogyParents(); err != nil {
+		return fmt.Errorf("迁移家族谱系父辈关系失败: %v", err)
+	}
+
 	log.Println("数据库自动迁移完成")
 	return nil
 }
 
+// migrateGenealogyParents 将谱系成员原有的单一父辈（family_genealogies.parent_id）迁移为亲生父母关系，然后删除该列
+func (m *Migration) migrateGenealogyParents() error {
+	migrator := m.db.Migrator()
+	if !migrator.HasColumn(&models.FamilyGenealogy{}, "parent_id") {
+		return nil
+	}
+
+	// 唯一索引保证重复执行时不会产生重复关系
+	if err := m.db.Exec(`INSERT IGNORE INTO genealogy_relations (id, family_id, type, from_id, to_id, parent_type, created_at, updated_at)
+		SELECT UUID(), family_id, 'parent', parent_id, id, 'biological', NOW(), NOW()
+		FROM family_genealogies
+		WHERE parent_id IS NOT NULL AND parent_id <> '' AND deleted_at IS NULL`).Error; err != nil {
+		return err
+	}
+
+	// 旧模型的自关联外键
+	for _, name := range []string{"fk_family_genealogies_children", "fk_family_genealogies_parent"} {
+		if migrator.HasConstraint(&models.FamilyGenealogy{}, name) {
+			if err := m.db.Exec("ALTER TABLE family_genealogies DROP FOREIGN KEY " + name).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	log.Println("家族谱系父辈关系已迁移到 genealogy_relations")
+	return migrator.DropColumn(&models.FamilyGenealogy{}, "parent_id")
+}
+
 // CreateIndexes 创建额外的索引
 func (m *Migration) CreateIndexes() error {
 	log.Println("开始创建数据库索引...")
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strings"
 	"yun-nian-memorial/internal/models"
+	"yun-nian-memorial/internal/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
@@ -170,14 +171,8 @@ func RequireMemorialAccess(db *gorm.DB) gin.HandlerFunc {
 			hasAccess = true
 			c.Set("access_level", "owner")
 		} else if memorial.PrivacyLevel == 1 {
-			// 2. 家族可见的纪念馆，检查是否为家族成员
-			var count int64
-			db.Table("memorial_families mf").
-				Joins("JOIN family_members fm ON mf.family_id = fm.family_id").
-				Where("mf.memorial_id = ? AND fm.user_id = ?", memorialID, userID).
-				Count(&count)
-			
-			if count > 0 {
+			// 2. 家族可见的纪念馆，检查是否为家族成员（含宗族与支系继承的成员身份）
+			if utils.NewPermissionManager(db).IsMemorialFamilyMember(userID.(string), memorialID) {
 				hasAccess = true
 				c.Set("access_level", "family")
 			}
@@ -241,7 +236,7 @@ func RequireFamilyAccess(db *gorm.DB, requireAdmin bool) gin.HandlerFunc {
 		}
 
 		// 如果需要管理员权限
-		if requireAdmin && member.Role != "admin" {
+		if requireAdmin && !utils.IsFamilyManagerRole(member.Role) {
 			c.JSON(http.StatusForbidden, gin.H{
 				"code":    1003,
 				"message": "需要管理员权限",
@@ -251,7 +246,7 @@ func RequireFamilyAccess(db *gorm.DB, requireAdmin bool) gin.HandlerFunc {
 		}
 
 		c.Set("family_member", member)
-		c.Set("is_family_admin", member.Role == "admin")
+		c.Set("is_family_admin", utils.IsFamilyManagerRole(member.Role))
 		c.Next()
 	}
 }
\ No newline at end of file
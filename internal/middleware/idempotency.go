@@ -0,0 +1,238 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+	"yun-nian-memorial/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// IdempotencyKeyHeader 客户端携带的幂等键请求头
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader 重放已保存响应时附带的响应头
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	idempotencyStatusProcessing = "processing"
+	idempotencyStatusCompleted  = "completed"
+
+	idempotencyKeyMaxLength = 128
+	idempotencyTTL          = 24 * time.Hour
+	// 超过该大小的响应不保存，幂等键随之释放
+	idempotencyMaxBody = 1 << 20
+)
+
+// idempotencyWriter 在写出响应的同时保留一份副本
+type idempotencyWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (w *idempotencyWriter) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *idempotencyWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *idempotencyWriter) capture(data []byte) {
+	if w.overflow {
+		return
+	}
+	if w.body.Len()+len(data) > idempotencyMaxBody {
+		w.overflow = true
+		w.body.Reset()
+		return
+	}
+	w.body.Write(data)
+}
+
+// Idempotency 幂等键中间件
+// 对携带 Idempotency-Key 的 POST/PUT 请求，按用户和幂等键保存首次响应：
+// 相同内容的重试直接重放保存的响应，同一幂等键提交不同内容时返回错误。
+// 需在 JWTAuth 之后使用；服务端错误(5xx)不保存，客户端可用同一幂等键重试。
+func Idempotency(db *gorm.DB) gin.HandlerFunc {
+	// 定期清理过期的幂等记录
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := db.Where("expires_at < ?", time.Now()).Delete(&models.IdempotencyRecord{}).Error; err != nil {
+				fmt.Printf("清理过期幂等记录失败: %v\n", err)
+			}
+		}
+	}()
+
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodPost && c.Request.Method != http.MethodPut {
+			c.Next()
+			return
+		}
+
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if !validIdempotencyKey(key) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    1001,
+				"message": fmt.Sprintf("Idempotency-Key 格式错误，应为不超过%d个字符的可见ASCII字符", idempotencyKeyMaxLength),
+			})
+			c.Abort()
+			return
+		}
+
+		userID := c.GetString("user_id")
+		if userID == "" {
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    1001,
+				"message": "读取请求体失败",
+			})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		path := c.Request.URL.RequestURI()
+		hash := idempotencyFingerprint(c.Request.Method, path, body)
+
+		record, existing, err := reserveIdempotencyKey(db, userID, key, c.Request.Method, path, hash)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    1005,
+				"message": "幂等键校验失败",
+			})
+			c.Abort()
+			return
+		}
+
+		if existing != nil {
+			switch {
+			case existing.RequestHash != hash:
+				c.JSON(http.StatusUnprocessableEntity, gin.H{
+					"code":    1001,
+					"message": "该 Idempotency-Key 已用于内容不同的请求，请为新请求使用新的幂等键",
+				})
+			case existing.Status != idempotencyStatusCompleted:
+				c.JSON(http.StatusConflict, gin.H{
+					"code":    1001,
+					"message": "使用该 Idempotency-Key 的请求正在处理中，请稍后重试",
+				})
+			default:
+				c.Header(IdempotentReplayedHeader, "true")
+				c.Data(existing.ResponseStatus, existing.ContentType, []byte(existing.ResponseBody))
+			}
+			c.Abort()
+			return
+		}
+
+		writer := &idempotencyWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		completed := false
+		defer func() {
+			// 处理失败或发生panic时释放幂等键，允许客户端重试
+			if !completed {
+				db.Delete(&models.IdempotencyRecord{}, "id = ?", record.ID)
+			}
+		}()
+
+		c.Next()
+
+		status := writer.Status()
+		if status >= http.StatusInternalServerError || writer.overflow {
+			return
+		}
+
+		if err := db.Model(&models.IdempotencyRecord{}).Where("id = ?", record.ID).Updates(map[string]interface{}{
+			"status":          idempotencyStatusCompleted,
+			"response_status": status,
+			"content_type":    writer.Header().Get("Content-Type"),
+			"response_body":   writer.body.String(),
+		}).Error; err != nil {
+			fmt.Printf("保存幂等响应失败: %v\n", err)
+			return
+		}
+		completed = true
+	}
+}
+
+// reserveIdempotencyKey 占用幂等键；幂等键已被占用时返回已有记录，已过期的记录会被删除后重新占用
+func reserveIdempotencyKey(db *gorm.DB, userID, key, method, path, hash string) (*models.IdempotencyRecord, *models.IdempotencyRecord, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		record := &models.IdempotencyRecord{
+			ID:          uuid.New().String(),
+			UserID:      userID,
+			IdemKey:     key,
+			Method:      method,
+			Path:        path,
+			RequestHash: hash,
+			Status:      idempotencyStatusProcessing,
+			ExpiresAt:   time.Now().Add(idempotencyTTL),
+		}
+		createErr := db.Create(record).Error
+		if createErr == nil {
+			return record, nil, nil
+		}
+
+		// 插入失败时确认是否为唯一索引冲突
+		var existing models.IdempotencyRecord
+		if err := db.Where("user_id = ? AND idem_key = ?", userID, key).First(&existing).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil, nil, createErr
+			}
+			return nil, nil, err
+		}
+		if existing.ExpiresAt.After(time.Now()) {
+			return nil, &existing, nil
+		}
+		if err := db.Where("id = ? AND expires_at <= ?", existing.ID, time.Now()).
+			Delete(&models.IdempotencyRecord{}).Error; err != nil {
+			return nil, nil, err
+		}
+	}
+	return nil, nil, fmt.Errorf("幂等键占用失败")
+}
+
+// idempotencyFingerprint 请求指纹：方法、路径（含查询参数）和请求体的SHA-256
+func idempotencyFingerprint(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{'\n'})
+	h.Write([]byte(path))
+	h.Write([]byte{'\n'})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// validIdempotencyKey 幂等键限制为可见ASCII字符
+func validIdempotencyKey(key string) bool {
+	if len(key) > idempotencyKeyMaxLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x21 || key[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
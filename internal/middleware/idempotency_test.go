@@ -0,0 +1,176 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"yun-nian-memorial/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func setupIdempotencyTestDB(t *testing.T) *gorm.DB {
+	host := getEnvOrDefault("MYSQL_HOST", "127.0.0.1")
+	port := getEnvOrDefault("MYSQL_PORT", "3306")
+	user := getEnvOrDefault("MYSQL_USERNAME", "root")
+	password := getEnvOrDefault("MYSQL_PASSWORD", "root")
+	database := getEnvOrDefault("MYSQL_DATABASE", "yun_nian_memorial") + "_test"
+
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+		user, password, host, port, database)
+
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Skipf("Database not available for testing: %v", err)
+	}
+	require.NoError(t, db.AutoMigrate(&models.IdempotencyRecord{}))
+	return db
+}
+
+// setupIdempotencyRouter 每个测试使用独立的用户，handler 为被保护的写接口
+func setupIdempotencyRouter(t *testing.T, handler gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	db := setupIdempotencyTestDB(t)
+	userID := uuid.New().String()
+	t.Cleanup(func() {
+		db.Where("user_id = ?", userID).Delete(&models.IdempotencyRecord{})
+	})
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Next()
+	})
+	router.Use(Idempotency(db))
+	router.POST("/api/v1/memorials/m1/worship/flowers", handler)
+	return router
+}
+
+func doIdempotentRequest(router *gin.Engine, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/memorials/m1/worship/flowers", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(IdempotencyKeyHeader, key)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	var calls int32
+	router := setupIdempotencyRouter(t, func(c *gin.Context) {
+		n := atomic.AddInt32(&calls, 1)
+		c.JSON(http.StatusCreated, gin.H{"code": 0, "data": gin.H{"call": n}})
+	})
+
+	first := doIdempotentRequest(router, "flower-1", `{"flower_type":"chrysanthemum"}`)
+	require.Equal(t, http.StatusCreated, first.Code)
+	assert.Empty(t, first.Header().Get(IdempotentReplayedHeader))
+
+	// 相同内容的重试重放首次响应，不再执行 handler
+	retry := doIdempotentRequest(router, "flower-1", `{"flower_type":"chrysanthemum"}`)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, "true", retry.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// 新的幂等键是新的请求
+	other := doIdempotentRequest(router, "flower-2", `{"flower_type":"chrysanthemum"}`)
+	assert.Equal(t, http.StatusCreated, other.Code)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestIdempotencyConflict(t *testing.T) {
+	var calls int32
+	router := setupIdempotencyRouter(t, func(c *gin.Context) {
+		atomic.AddInt32(&calls, 1)
+		c.JSON(http.StatusCreated, gin.H{"code": 0})
+	})
+
+	require.Equal(t, http.StatusCreated, doIdempotentRequest(router, "flower-1", `{"quantity":1}`).Code)
+
+	// 同一幂等键提交不同内容
+	w := doIdempotentRequest(router, "flower-1", `{"quantity":9}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Empty(t, w.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestIdempotencyInFlight(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var calls int32
+	router := setupIdempotencyRouter(t, func(c *gin.Context) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+			<-release
+		}
+		c.JSON(http.StatusCreated, gin.H{"code": 0})
+	})
+
+	var wg sync.WaitGroup
+	var first *httptest.ResponseRecorder
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		first = doIdempotentRequest(router, "flower-1", `{"quantity":1}`)
+	}()
+	<-started
+
+	// 首次请求尚未完成时，同一幂等键的重试返回处理中
+	w := doIdempotentRequest(router, "flower-1", `{"quantity":1}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	close(release)
+	wg.Wait()
+	assert.Equal(t, http.StatusCreated, first.Code)
+
+	// 完成后重放
+	w = doIdempotentRequest(router, "flower-1", `{"quantity":1}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "true", w.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestIdempotencyServerErrorReleasesKey(t *testing.T) {
+	var calls int32
+	router := setupIdempotencyRouter(t, func(c *gin.Context) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			c.JSON(http.StatusInternalServerError, gin.H{"code": 1005})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"code": 0})
+	})
+
+	assert.Equal(t, http.StatusInternalServerError, doIdempotentRequest(router, "flower-1", `{}`).Code)
+	// 5xx 不保存，同一幂等键可以重试
+	w := doIdempotentRequest(router, "flower-1", `{}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Empty(t, w.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestIdempotencyInvalidKey(t *testing.T) {
+	router := setupIdempotencyRouter(t, func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"code": 0})
+	})
+
+	assert.Equal(t, http.StatusBadRequest, doIdempotentRequest(router, "含中文", `{}`).Code)
+	assert.Equal(t, http.StatusBadRequest, doIdempotentRequest(router, strings.Repeat("k", 129), `{}`).Code)
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+)
+
+// CollectiveWorship 家族集体祭扫活动：约定时间、成员报名、到点开始的共同祭扫
+type CollectiveWorship struct {
+	ID                  string     `json:"id" gorm:"primaryKey;type:varchar(36);comment:集体祭扫ID"`
+	FamilyID            string     `json:"family_id" gorm:"type:varchar(36);not null;index;comment:家族ID"`
+	MemorialID          string     `json:"memorial_id" gorm:"type:varchar(36);not null;index;comment:纪念馆ID"`
+	InitiatorID         string     `json:"initiator_id" gorm:"type:varchar(36);not null;index;comment:发起人ID"`
+	Title               string     `json:"title" gorm:"type:varchar(100);not null;comment:活动标题"`
+	Description         string     `json:"description" gorm:"type:text;comment:活动说明"`
+	WorshipType         string     `json:"worship_type" gorm:"type:varchar(20);comment:建议的祭扫方式:flower|candle|incense|tribute，为空不限"`
+	ScheduledAt         time.Time  `json:"scheduled_at" gorm:"not null;index;comment:约定开始时间"`
+	DurationMinutes     int        `json:"duration_minutes" gorm:"not null;comment:活动时长(分钟)，开始后到时自动结束"`
+	RemindBeforeMinutes int        `json:"remind_before_minutes" gorm:"not null;comment:提前提醒(分钟)，0表示不提醒"`
+	SilenceSeconds      int        `json:"silence_seconds" gorm:"not null;comment:默哀时长(秒)"`
+	Status              string     `json:"status" gorm:"type:varchar(20);not null;index;comment:scheduled待开始|ongoing进行中|completed已结束|cancelled已取消"`
+	StartedAt           *time.Time `json:"started_at" gorm:"comment:实际开始时间"`
+	EndedAt             *time.Time `json:"ended_at" gorm:"comment:结束时间"`
+	SilenceStartedAt    *time.Time `json:"silence_started_at" gorm:"comment:默哀开始时间(服务端计时)"`
+	SilenceEndsAt       *time.Time `json:"silence_ends_at" gorm:"comment:默哀结束时间(服务端计时)"`
+	SummaryActivityID   string     `json:"summary_activity_id,omitempty" gorm:"type:varchar(36);comment:结束后发布到家族动态的总结ID"`
+	CreatedAt           time.Time  `json:"created_at" gorm:"comment:创建时间"`
+	UpdatedAt           time.Time  `json:"updated_at" gorm:"comment:更新时间"`
+
+	// 关联关系
+	Memorial     Memorial                       `json:"memorial" gorm:"foreignKey:MemorialID"`
+	Initiator    User                           `json:"initiator" gorm:"foreignKey:InitiatorID"`
+	Participants []CollectiveWorshipParticipant `json:"participants,omitempty" gorm:"foreignKey:CollectiveWorshipID"`
+}
+
+func (CollectiveWorship) TableName() string {
+	return "collective_worships"
+}
+
+// CollectiveWorshipParticipant 集体祭扫报名及到场情况
+type CollectiveWorshipParticipant struct {
+	ID                  string     `json:"id" gorm:"primaryKey;type:varchar(36);comment:参与记录ID"`
+	CollectiveWorshipID string     `json:"collective_worship_id" gorm:"type:varchar(36);not null;uniqueIndex:idx_collective_worship_user;comment:集体祭扫ID"`
+	UserID              string     `json:"user_id" gorm:"type:varchar(36);not null;uniqueIndex:idx_collective_worship_user;index;comment:用户ID"`
+	RSVP                string     `json:"rsvp" gorm:"column:rsvp;type:varchar(20);not null;comment:报名状态:going参加|maybe待定|declined不参加"`
+	RespondedAt         time.Time  `json:"responded_at" gorm:"comment:报名时间"`
+	RemindedAt          *time.Time `json:"reminded_at" gorm:"comment:开始前提醒时间"`
+	JoinedAt            *time.Time `json:"joined_at" gorm:"comment:进入现场时间"`
+	CreatedAt           time.Time  `json:"created_at" gorm:"comment:创建时间"`
+	UpdatedAt           time.Time  `json:"updated_at" gorm:"comment:更新时间"`
+
+	// 关联关系
+	User User `json:"user" gorm:"foreignKey:UserID"`
+}
+
+func (CollectiveWorshipParticipant) TableName() string {
+	return "collective_worship_participants"
+}
+
+// CollectiveWorshipOffering 集体祭扫现场的祭品，对应一条普通祭扫记录
+type CollectiveWorshipOffering struct {
+	ID                  string    `json:"id" gorm:"primaryKey;type:varchar(36);comment:祭品ID"`
+	CollectiveWorshipID string    `json:"collective_worship_id" gorm:"type:varchar(36);not null;index;comment:集体祭扫ID"`
+	UserID              string    `json:"user_id" gorm:"type:varchar(36);not null;index;comment:用户ID"`
+	WorshipRecordID     string    `json:"worship_record_id" gorm:"type:varchar(36);not null;comment:祭扫记录ID"`
+	WorshipType         string    `json:"worship_type" gorm:"type:varchar(20);not null;comment:祭扫类型"`
+	CreatedAt           time.Time `json:"created_at" gorm:"index;comment:创建时间"`
+
+	// 关联关系
+	User          User          `json:"user" gorm:"foreignKey:UserID"`
+	WorshipRecord WorshipRecord `json:"worship_record" gorm:"foreignKey:WorshipRecordID"`
+}
+
+func (CollectiveWorshipOffering) TableName() string {
+	return "collective_worship_offerings"
+}
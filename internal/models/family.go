@@ -7,14 +7,17 @@ import (
 )
 
 type Family struct {
-	ID          string         `json:"id" gorm:"primaryKey;type:varchar(36);comment:家族ID"`
-	Name        string         `json:"name" gorm:"type:varchar(100);not null;comment:家族名称"`
-	CreatorID   string         `json:"creatorId" gorm:"type:varchar(36);not null;index;comment:创建者ID"`
-	Description string         `json:"description" gorm:"type:text;comment:家族描述"`
-	InviteCode  string         `json:"inviteCode" gorm:"uniqueIndex;type:varchar(20);comment:邀请码"`
-	CreatedAt   time.Time      `json:"createdAt" gorm:"comment:创建时间"`
-	UpdatedAt   time.Time      `json:"updatedAt" gorm:"comment:更新时间"`
-	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index;comment:删除时间"`
+	ID               string         `json:"id" gorm:"primaryKey;type:varchar(36);comment:家族ID"`
+	Name             string         `json:"name" gorm:"type:varchar(100);not null;comment:家族名称"`
+	CreatorID        string         `json:"creatorId" gorm:"type:varchar(36);not null;index;comment:创建者ID"`
+	Description      string         `json:"description" gorm:"type:text;comment:家族描述"`
+	InviteCode       string         `json:"inviteCode" gorm:"uniqueIndex;type:varchar(20);comment:邀请码"`
+	ParentID         *string        `json:"parentId" gorm:"type:varchar(36);index;comment:上级家族圈ID，为空表示宗族"`
+	Visibility       string         `json:"visibility" gorm:"type:varchar(20);default:clan;comment:对上级家族圈的可见性:clan可见 private私密"`
+	GenealogyVersion int            `json:"genealogyVersion" gorm:"default:0;comment:谱系版本，谱系修改时递增，用于失效谱系图缓存"`
+	CreatedAt        time.Time      `json:"createdAt" gorm:"comment:创建时间"`
+	UpdatedAt        time.Time      `json:"updatedAt" gorm:"comment:更新时间"`
+	DeletedAt        gorm.DeletedAt `json:"-" gorm:"index;comment:删除时间"`
 
 	// 关联关系
 	Creator User           `json:"creator" gorm:"foreignKey:CreatorID"`
@@ -26,11 +29,12 @@ func (Family) TableName() string {
 }
 
 type FamilyMember struct {
-	ID       string    `json:"id" gorm:"primaryKey;type:varchar(36);comment:成员ID"`
-	FamilyID string    `json:"familyId" gorm:"column:family_id;type:varchar(36);not null;index;comment:家族ID"`
-	UserID   string    `json:"userId" gorm:"column:user_id;type:varchar(36);not null;index;comment:用户ID"`
-	Role     string    `json:"role" gorm:"type:varchar(20);default:member;comment:角色:admin管理员 member成员"`
-	JoinedAt time.Time `json:"joinedAt" gorm:"column:joined_at;comment:加入时间"`
+	ID          string    `json:"id" gorm:"primaryKey;type:varchar(36);comment:成员ID"`
+	FamilyID    string    `json:"familyId" gorm:"column:family_id;type:varchar(36);not null;index;comment:家族ID"`
+	UserID      string    `json:"userId" gorm:"column:user_id;type:varchar(36);not null;index;comment:用户ID"`
+	Role        string    `json:"role" gorm:"type:varchar(20);default:member;comment:角色:owner所有者 admin管理员 editor编辑 member成员 guest访客"`
+	JoinedAt    time.Time `json:"joinedAt" gorm:"column:joined_at;comment:加入时间"`
+	GenealogyID string    `json:"genealogyId" gorm:"column:genealogy_id;type:varchar(36);index;comment:本人在谱系中对应的成员ID，用于计算称谓"`
 
 	// 关联关系
 	Family Family `json:"family" gorm:"foreignKey:FamilyID"`
@@ -41,6 +45,21 @@ func (FamilyMember) TableName() string {
 	return "family_members"
 }
 
+// FamilyPermission 家族圈权限矩阵中被所有者修改过的项，未修改的操作使用默认设置
+type FamilyPermission struct {
+	ID        string    `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	FamilyID  string    `json:"family_id" gorm:"type:varchar(36);not null;uniqueIndex:idx_family_permission_action"`
+	Action    string    `json:"action" gorm:"type:varchar(30);not null;uniqueIndex:idx_family_permission_action;comment:操作"`
+	Roles     string    `json:"roles" gorm:"type:varchar(100);not null;comment:允许执行的角色，逗号分隔"`
+	UpdatedBy string    `json:"updated_by" gorm:"type:varchar(36);comment:最后修改人ID"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (FamilyPermission) TableName() string {
+	return "family_permissions"
+}
+
 type MemorialReminder struct {
 	ID           string         `json:"id" gorm:"primaryKey;type:varchar(36)"`
 	MemorialID   string         `json:"memorial_id" gorm:"type:varchar(36);not null;index"`
@@ -49,10 +68,18 @@ type MemorialReminder struct {
 	Title        string         `json:"title" gorm:"type:varchar(100)"`
 	Content      string         `json:"content" gorm:"type:text"`
 	IsActive     bool           `json:"is_active" gorm:"default:true"`
+	CreatorID    string         `json:"creator_id" gorm:"type:varchar(36);index;comment:设置人，定时祈福只提醒设置人"`
+	Rule         string         `json:"rule" gorm:"type:varchar(200);comment:重复规则(RRULE子集)，为空时生辰忌日每年、其他类型只提醒一次"`
+	LeadDays     string         `json:"lead_days" gorm:"type:json;comment:提前提醒天数，如[3,0]，为空只在当天提醒"`
+	RemindTime   string         `json:"remind_time" gorm:"type:varchar(5);default:09:00;comment:提醒发送时刻"`
 	CreatedAt    time.Time      `json:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
 
+	// 计算字段
+	NextDate *time.Time `json:"next_date,omitempty" gorm:"-"` // 下一次提醒日期
+	RuleText string     `json:"rule_text,omitempty" gorm:"-"` // 重复规则的中文描述
+
 	// 关联关系
 	Memorial Memorial `json:"memorial" gorm:"foreignKey:MemorialID"`
 }
@@ -61,6 +88,36 @@ func (MemorialReminder) TableName() string {
 	return "memorial_reminders"
 }
 
+// ReminderOccurrence 纪念日提醒的某一次，记录已发送的提前量，保证每个提前量只发送一次
+type ReminderOccurrence struct {
+	ID             string    `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	ReminderID     string    `json:"reminder_id" gorm:"type:varchar(36);not null;uniqueIndex:idx_reminder_occurrence,priority:1;comment:提醒ID"`
+	OccurrenceDate time.Time `json:"occurrence_date" gorm:"type:date;not null;uniqueIndex:idx_reminder_occurrence,priority:2;comment:本次提醒日期"`
+	NotifiedLead   *int      `json:"notified_lead" gorm:"comment:已发送的最近提前天数，为空表示未发送"`
+	Version        int       `json:"-" gorm:"not null;default:0;comment:乐观锁版本，调度实例以此认领发送"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func (ReminderOccurrence) TableName() string {
+	return "reminder_occurrences"
+}
+
+// ReminderOccurrenceAction 用户对某次提醒的处理：稍后提醒或不再提醒
+type ReminderOccurrenceAction struct {
+	ID           string     `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	OccurrenceID string     `json:"occurrence_id" gorm:"type:varchar(36);not null;uniqueIndex:idx_reminder_occurrence_user,priority:1;comment:提醒的某一次"`
+	UserID       string     `json:"user_id" gorm:"type:varchar(36);not null;uniqueIndex:idx_reminder_occurrence_user,priority:2;comment:用户ID"`
+	Status       string     `json:"status" gorm:"type:varchar(20);not null;index;comment:snoozed稍后提醒|dismissed不再提醒"`
+	SnoozedUntil *time.Time `json:"snoozed_until" gorm:"index;comment:稍后提醒的时间"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+func (ReminderOccurrenceAction) TableName() string {
+	return "reminder_occurrence_actions"
+}
+
 // FamilyInvitation 家族邀请
 type FamilyInvitation struct {
 	ID        string         `json:"id" gorm:"primaryKey;type:varchar(36)"`
@@ -84,6 +141,54 @@ func (FamilyInvitation) TableName() string {
 	return "family_invitations"
 }
 
+// FamilyInviteLink 家族圈邀请链接，可限定有效期、使用次数、加入后的角色，并可要求管理员审核
+type FamilyInviteLink struct {
+	ID              string     `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	FamilyID        string     `json:"family_id" gorm:"type:varchar(36);not null;index"`
+	CreatorID       string     `json:"creator_id" gorm:"type:varchar(36);not null;index"`
+	Token           string     `json:"token" gorm:"type:varchar(32);not null;uniqueIndex;comment:链接令牌"`
+	Role            string     `json:"role" gorm:"type:varchar(20);not null;comment:通过链接加入后的角色"`
+	MaxUses         int        `json:"max_uses" gorm:"not null;comment:最大使用次数，0表示不限"`
+	UseCount        int        `json:"use_count" gorm:"not null;comment:已使用次数，含待审核的申请"`
+	RequireApproval bool       `json:"require_approval" gorm:"not null;comment:是否需要管理员审核后加入"`
+	Note            string     `json:"note" gorm:"type:varchar(100);comment:备注"`
+	ExpiresAt       time.Time  `json:"expires_at" gorm:"not null;index;comment:过期时间"`
+	RevokedAt       *time.Time `json:"revoked_at" gorm:"comment:撤销时间"`
+	RevokedBy       string     `json:"revoked_by,omitempty" gorm:"type:varchar(36);comment:撤销人ID"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+
+	// 关联关系
+	Family  Family `json:"family" gorm:"foreignKey:FamilyID"`
+	Creator User   `json:"creator" gorm:"foreignKey:CreatorID"`
+}
+
+func (FamilyInviteLink) TableName() string {
+	return "family_invite_links"
+}
+
+// FamilyInviteLinkUse 邀请链接的使用记录，需要审核的链接在此记录待审核的加入申请
+type FamilyInviteLinkUse struct {
+	ID         string     `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	LinkID     string     `json:"link_id" gorm:"type:varchar(36);not null;index"`
+	FamilyID   string     `json:"family_id" gorm:"type:varchar(36);not null;index"`
+	UserID     string     `json:"user_id" gorm:"type:varchar(36);not null;index"`
+	Role       string     `json:"role" gorm:"type:varchar(20);not null;comment:加入后的角色"`
+	Status     string     `json:"status" gorm:"type:varchar(20);not null;index;comment:pending待审核|joined已加入|rejected已拒绝"`
+	ReviewedBy string     `json:"reviewed_by,omitempty" gorm:"type:varchar(36);comment:审核人ID"`
+	ReviewedAt *time.Time `json:"reviewed_at" gorm:"comment:审核时间"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+
+	// 关联关系
+	Link FamilyInviteLink `json:"link" gorm:"foreignKey:LinkID"`
+	User User             `json:"user" gorm:"foreignKey:UserID"`
+}
+
+func (FamilyInviteLinkUse) TableName() string {
+	return "family_invite_link_uses"
+}
+
 // FamilyActivity 家族活动记录
 type FamilyActivity struct {
 	ID           string         `json:"id" gorm:"primaryKey;type:varchar(36)"`
@@ -112,7 +217,6 @@ type FamilyGenealogy struct {
 	FamilyID     string         `json:"family_id" gorm:"type:varchar(36);not null;index"`
 	PersonName   string         `json:"person_name" gorm:"type:varchar(100);not null"`
 	Generation   int            `json:"generation" gorm:"not null;comment:辈分，数字越小辈分越高"`
-	ParentID     string         `json:"parent_id" gorm:"type:varchar(36);index;comment:父辈ID"`
 	Gender       string         `json:"gender" gorm:"type:varchar(10);comment:male|female"`
 	BirthDate    *time.Time     `json:"birth_date"`
 	DeathDate    *time.Time     `json:"death_date"`
@@ -125,17 +229,33 @@ type FamilyGenealogy struct {
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
 
-	// 关联关系
-	Family   Family            `json:"family" gorm:"foreignKey:FamilyID"`
-	Parent   *FamilyGenealogy  `json:"parent" gorm:"foreignKey:ParentID"`
-	Children []FamilyGenealogy `json:"children" gorm:"foreignKey:ParentID"`
-	Memorial *Memorial         `json:"memorial" gorm:"foreignKey:MemorialID"`
+	// 关联关系（父母、子女、配偶见 GenealogyRelation）
+	Family   Family    `json:"family" gorm:"foreignKey:FamilyID"`
+	Memorial *Memorial `json:"memorial" gorm:"foreignKey:MemorialID"`
 }
 
 func (FamilyGenealogy) TableName() string {
 	return "family_genealogies"
 }
 
+// GenealogyRelation 谱系成员之间的关系：父母与子女、配偶
+type GenealogyRelation struct {
+	ID           string     `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	FamilyID     string     `json:"family_id" gorm:"type:varchar(36);not null;index;comment:创建关系的家族圈ID"`
+	Type         string     `json:"type" gorm:"type:varchar(20);not null;uniqueIndex:idx_genealogy_relation,priority:3;comment:关系类型:parent父母与子女 spouse配偶"`
+	FromID       string     `json:"from_id" gorm:"type:varchar(36);not null;uniqueIndex:idx_genealogy_relation,priority:1;comment:parent为父母ID，spouse为ID较小的一方"`
+	ToID         string     `json:"to_id" gorm:"type:varchar(36);not null;uniqueIndex:idx_genealogy_relation,priority:2;index;comment:parent为子女ID，spouse为另一方"`
+	ParentType   string     `json:"parent_type" gorm:"type:varchar(20);comment:父母关系:biological亲生 adopted收养或过继 step继父母"`
+	MarriageDate *time.Time `json:"marriage_date" gorm:"comment:结婚日期"`
+	DivorceDate  *time.Time `json:"divorce_date" gorm:"comment:离婚日期，为空表示婚姻存续或因丧偶结束"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+func (GenealogyRelation) TableName() string {
+	return "genealogy_relations"
+}
+
 // FamilyStory 家族故事
 type FamilyStory struct {
 	ID         string         `json:"id" gorm:"primaryKey;type:varchar(36)"`
@@ -181,6 +301,17 @@ type FamilyTradition struct {
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
 
+	// 日期安排，未设定日期的传统不出现在家族日历中
+	CalendarType  string     `json:"calendar_type" gorm:"type:varchar(10);comment:历法:solar公历 lunar农历，为空表示未设定日期"`
+	Recurrence    string     `json:"recurrence" gorm:"type:varchar(10);comment:重复方式:yearly每年 monthly每月 once仅一次"`
+	ScheduleYear  int        `json:"schedule_year" gorm:"default:0;comment:年份，仅一次时使用，农历为农历年"`
+	ScheduleMonth int        `json:"schedule_month" gorm:"default:0;comment:月份，每月重复时不使用"`
+	ScheduleDay   int        `json:"schedule_day" gorm:"default:0;comment:日，该月没有这一天时取月末"`
+	RemindDays    int        `json:"remind_days" gorm:"default:3;comment:提前几天提醒"`
+	RemindedFor   *time.Time `json:"reminded_for" gorm:"comment:已发送提醒的那一次日期，避免重复提醒"`
+	NextDate      *time.Time `json:"next_date,omitempty" gorm:"-"`     // 下一次的公历日期，查询时填充
+	ScheduleText  string     `json:"schedule_text,omitempty" gorm:"-"` // 日期安排的中文描述，查询时填充
+
 	// 关联关系
 	Family Family `json:"family" gorm:"foreignKey:FamilyID"`
 }
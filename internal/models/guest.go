@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+)
+
+// GuestIdentity 访客匿名身份，凭设备令牌在公开纪念馆祭扫，无需注册
+type GuestIdentity struct {
+	ID          string     `json:"id" gorm:"primaryKey;type:varchar(36);comment:访客身份ID"`
+	TokenHash   string     `json:"-" gorm:"type:varchar(64);not null;uniqueIndex;comment:设备令牌摘要"`
+	DisplayName string     `json:"display_name" gorm:"type:varchar(50);not null;comment:展示名称"`
+	IPAddress   string     `json:"-" gorm:"type:varchar(45);index;comment:创建时的IP地址"`
+	UserAgent   string     `json:"-" gorm:"type:varchar(255);comment:创建时的用户代理"`
+	LastSeenAt  *time.Time `json:"last_seen_at" gorm:"comment:最近使用时间"`
+	ExpiresAt   time.Time  `json:"expires_at" gorm:"index;comment:过期时间"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"index;comment:创建时间"`
+}
+
+func (GuestIdentity) TableName() string {
+	return "guest_identities"
+}
+
+// GuestChallenge 访客身份签发前的人机验证题，一次性使用
+type GuestChallenge struct {
+	ID         string     `json:"id" gorm:"primaryKey;type:varchar(36);comment:验证题ID"`
+	Question   string     `json:"question" gorm:"type:varchar(50);not null;comment:题目"`
+	AnswerHash string     `json:"-" gorm:"type:varchar(64);not null;comment:答案摘要"`
+	IPAddress  string     `json:"-" gorm:"type:varchar(45);index;comment:请求IP地址"`
+	UsedAt     *time.Time `json:"-" gorm:"comment:作答时间，作答后即失效"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"index;comment:过期时间"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"index;comment:创建时间"`
+}
+
+func (GuestChallenge) TableName() string {
+	return "guest_challenges"
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+// IdempotencyRecord 幂等键记录，按用户和幂等键保存首次请求的响应，供重试时重放
+type IdempotencyRecord struct {
+	ID             string    `json:"id" gorm:"primaryKey;type:varchar(36);comment:记录ID"`
+	UserID         string    `json:"user_id" gorm:"type:varchar(36);not null;uniqueIndex:idx_idempotency_user_key;comment:用户ID"`
+	IdemKey        string    `json:"idem_key" gorm:"type:varchar(128);not null;uniqueIndex:idx_idempotency_user_key;comment:幂等键"`
+	Method         string    `json:"method" gorm:"type:varchar(10);not null;comment:请求方法"`
+	Path           string    `json:"path" gorm:"type:varchar(500);not null;comment:请求路径"`
+	RequestHash    string    `json:"request_hash" gorm:"type:varchar(64);not null;comment:请求指纹(方法+路径+请求体的SHA-256)"`
+	Status         string    `json:"status" gorm:"type:varchar(20);not null;default:processing;comment:状态:processing处理中 completed已完成"`
+	ResponseStatus int       `json:"response_status" gorm:"comment:响应状态码"`
+	ContentType    string    `json:"content_type" gorm:"type:varchar(100);comment:响应类型"`
+	ResponseBody   string    `json:"-" gorm:"type:mediumtext;comment:响应内容"`
+	ExpiresAt      time.Time `json:"expires_at" gorm:"index;comment:过期时间"`
+	CreatedAt      time.Time `json:"created_at" gorm:"comment:创建时间"`
+	UpdatedAt      time.Time `json:"updated_at" gorm:"comment:更新时间"`
+}
+
+func (IdempotencyRecord) TableName() string {
+	return "idempotency_records"
+}
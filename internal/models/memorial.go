@@ -7,21 +7,23 @@ import (
 )
 
 type Memorial struct {
-	ID             string         `json:"id" gorm:"primaryKey;type:varchar(36);comment:纪念馆ID"`
-	CreatorID      string         `json:"creatorId" gorm:"type:varchar(36);not null;index;comment:创建者ID"`
-	DeceasedName   string         `json:"deceasedName" gorm:"type:varchar(50);not null;comment:逝者姓名"`
-	BirthDate      *time.Time     `json:"birthDate" gorm:"comment:出生日期"`
-	DeathDate      *time.Time     `json:"deathDate" gorm:"comment:逝世日期"`
-	Biography      string         `json:"biography" gorm:"type:text;comment:生平简介"`
-	AvatarURL      string         `json:"avatarUrl" gorm:"type:varchar(255);comment:头像URL"`
-	ThemeStyle     string         `json:"themeStyle" gorm:"type:varchar(50);default:traditional;comment:主题风格"`
-	TombstoneStyle string         `json:"tombstoneStyle" gorm:"type:varchar(50);default:marble;comment:墓碑样式"`
-	Epitaph        string         `json:"epitaph" gorm:"type:text;comment:墓志铭"`
-	PrivacyLevel   int            `json:"privacyLevel" gorm:"default:1;comment:隐私级别:1家族可见 2私密"`
-	Status         int            `json:"status" gorm:"default:1;comment:状态:1正常 0禁用"`
-	CreatedAt      time.Time      `json:"createdAt" gorm:"comment:创建时间"`
-	UpdatedAt      time.Time      `json:"updatedAt" gorm:"comment:更新时间"`
-	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index;comment:删除时间"`
+	ID                string         `json:"id" gorm:"primaryKey;type:varchar(36);comment:纪念馆ID"`
+	CreatorID         string         `json:"creatorId" gorm:"type:varchar(36);not null;index;comment:创建者ID"`
+	DeceasedName      string         `json:"deceasedName" gorm:"type:varchar(50);not null;comment:逝者姓名"`
+	BirthDate         *time.Time     `json:"birthDate" gorm:"comment:出生日期"`
+	DeathDate         *time.Time     `json:"deathDate" gorm:"comment:逝世日期"`
+	Biography         string         `json:"biography" gorm:"type:text;comment:生平简介"`
+	AvatarURL         string         `json:"avatarUrl" gorm:"type:varchar(255);comment:头像URL"`
+	ThemeStyle        string         `json:"themeStyle" gorm:"type:varchar(50);default:traditional;comment:主题风格"`
+	TombstoneStyle    string         `json:"tombstoneStyle" gorm:"type:varchar(50);default:marble;comment:墓碑样式"`
+	Epitaph           string         `json:"epitaph" gorm:"type:text;comment:墓志铭"`
+	PrivacyLevel      int            `json:"privacyLevel" gorm:"default:1;comment:隐私级别:1家族可见 2私密"`
+	RequireApproval   bool           `json:"requireApproval" gorm:"default:false;comment:留言和祈福是否需馆主审核后公开"`
+	AllowGuestWorship bool           `json:"allowGuestWorship" gorm:"default:true;comment:公开纪念馆是否允许未注册访客匿名祭扫"`
+	Status            int            `json:"status" gorm:"default:1;comment:状态:1正常 0禁用"`
+	CreatedAt         time.Time      `json:"createdAt" gorm:"comment:创建时间"`
+	UpdatedAt         time.Time      `json:"updatedAt" gorm:"comment:更新时间"`
+	DeletedAt         gorm.DeletedAt `json:"-" gorm:"index;comment:删除时间"`
 
 	// 关联关系
 	Creator User `json:"creator" gorm:"foreignKey:CreatorID"`
@@ -39,6 +41,14 @@ type MediaFile struct {
 	FileName    string         `json:"fileName" gorm:"type:varchar(255);comment:文件名"`
 	FileSize    int64          `json:"fileSize" gorm:"comment:文件大小(字节)"`
 	Description string         `json:"description" gorm:"type:text;comment:文件描述"`
+	Container   string         `json:"container" gorm:"type:varchar(20);comment:容器格式:mp4 mov mp3 wav"`
+	Codec       string         `json:"codec" gorm:"type:varchar(30);comment:主编码格式"`
+	Duration    int            `json:"duration" gorm:"comment:探测所得时长(秒)"`
+	Width       int            `json:"width" gorm:"comment:视频宽度"`
+	Height      int            `json:"height" gorm:"comment:视频高度"`
+	PosterURL   string         `json:"posterUrl" gorm:"type:varchar(255);comment:视频封面URL"`
+	Waveform    string         `json:"waveform" gorm:"type:text;comment:音频波形峰值(JSON数组)"`
+	ProbeStatus string         `json:"probeStatus" gorm:"type:varchar(20);comment:探测状态:ready可播放 failed失败"`
 	CreatedAt   time.Time      `json:"createdAt" gorm:"comment:创建时间"`
 	UpdatedAt   time.Time      `json:"updatedAt" gorm:"comment:更新时间"`
 	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index;comment:删除时间"`
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+// ContentFilterHit 敏感词命中记录，供管理员复核
+type ContentFilterHit struct {
+	ID           string     `json:"id" gorm:"primaryKey;type:varchar(36);comment:命中记录ID"`
+	ContentType  string     `json:"content_type" gorm:"type:varchar(20);not null;index;comment:内容类型:message留言 prayer祈福 epitaph墓志铭 life_story生平故事 family_story家族故事 chat追思会聊天 guest_worship访客祭扫 collective_worship集体祭扫 family_worship家族合祭"`
+	ContentID    string     `json:"content_id" gorm:"type:varchar(36);index;comment:内容ID(被拦截的内容为空)"`
+	UserID       string     `json:"user_id" gorm:"type:varchar(36);index;comment:提交用户ID"`
+	MemorialID   string     `json:"memorial_id" gorm:"type:varchar(36);index;comment:纪念馆ID"`
+	Excerpt      string     `json:"excerpt" gorm:"type:text;comment:内容摘录"`
+	Matches      string     `json:"matches" gorm:"type:text;comment:命中详情(JSON数组)"`
+	Action       string     `json:"action" gorm:"type:varchar(20);not null;index;comment:处理动作:blocked已拦截 flagged待复核"`
+	ReviewStatus string     `json:"review_status" gorm:"type:varchar(20);not null;default:pending;index;comment:复核状态:pending待复核 confirmed确认违规 dismissed误判"`
+	ReviewerID   string     `json:"reviewer_id" gorm:"type:varchar(36);comment:复核人ID"`
+	ReviewNote   string     `json:"review_note" gorm:"type:varchar(500);comment:复核备注"`
+	ReviewedAt   *time.Time `json:"reviewed_at" gorm:"comment:复核时间"`
+	CreatedAt    time.Time  `json:"created_at" gorm:"index;comment:创建时间"`
+}
+
+func (ContentFilterHit) TableName() string {
+	return "content_filter_hits"
+}
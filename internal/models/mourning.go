@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// MourningSchedule 纪念馆的守丧日程（做七、百日、周年），按逝世日期和守丧规则生成，每个纪念馆一份
+type MourningSchedule struct {
+	ID          string    `json:"id" gorm:"primaryKey;type:varchar(36);comment:守丧日程ID"`
+	MemorialID  string    `json:"memorial_id" gorm:"type:varchar(36);not null;uniqueIndex;comment:纪念馆ID"`
+	FamilyID    string    `json:"family_id" gorm:"type:varchar(36);not null;index;comment:生成日程的家族圈ID"`
+	CreatorID   string    `json:"creator_id" gorm:"type:varchar(36);not null;comment:生成人ID"`
+	RuleSet     string    `json:"rule_set" gorm:"type:varchar(32);not null;comment:守丧规则标识，如secular民间通行|buddhist佛教"`
+	RuleSetName string    `json:"rule_set_name" gorm:"type:varchar(50);comment:守丧规则名称"`
+	DeathDate   time.Time `json:"death_date" gorm:"type:date;not null;comment:推算所依据的逝世日期"`
+	CreatedAt   time.Time `json:"created_at" gorm:"comment:创建时间"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"comment:更新时间"`
+
+	// 关联关系
+	Observances []MourningObservance `json:"observances" gorm:"foreignKey:ScheduleID"`
+}
+
+func (MourningSchedule) TableName() string {
+	return "mourning_schedules"
+}
+
+// MourningObservance 守丧日程中的一项，如头七、百日，关联生成的纪念日提醒和安排的集体祭扫
+type MourningObservance struct {
+	ID                  string    `json:"id" gorm:"primaryKey;type:varchar(36);comment:日程项ID"`
+	ScheduleID          string    `json:"schedule_id" gorm:"type:varchar(36);not null;index;comment:守丧日程ID"`
+	Key                 string    `json:"key" gorm:"column:observance_key;type:varchar(32);not null;comment:规则中的标识，如first_seven"`
+	Name                string    `json:"name" gorm:"type:varchar(50);not null;comment:名称，如头七"`
+	Days                int       `json:"days,omitempty" gorm:"comment:逝世第N天"`
+	Years               int       `json:"years,omitempty" gorm:"comment:第N个忌日"`
+	Calendar            string    `json:"calendar,omitempty" gorm:"type:varchar(10);comment:忌日的历法:lunar|solar"`
+	Date                time.Time `json:"date" gorm:"type:date;not null;comment:日期"`
+	Description         string    `json:"description" gorm:"type:text;comment:说明"`
+	Rites               string    `json:"rites" gorm:"type:json;comment:建议的仪式"`
+	Offerings           string    `json:"offerings" gorm:"type:json;comment:建议的供品"`
+	WorshipType         string    `json:"worship_type" gorm:"type:varchar(20);comment:安排集体祭扫时建议的祭扫方式"`
+	SortOrder           int       `json:"sort_order" gorm:"comment:排序"`
+	ReminderID          string    `json:"reminder_id,omitempty" gorm:"type:varchar(36);index;comment:生成的纪念日提醒ID，生成时已过去的为空"`
+	CollectiveWorshipID string    `json:"collective_worship_id,omitempty" gorm:"type:varchar(36);comment:为这一天安排的集体祭扫ID"`
+	CreatedAt           time.Time `json:"created_at" gorm:"comment:创建时间"`
+	UpdatedAt           time.Time `json:"updated_at" gorm:"comment:更新时间"`
+
+	// 计算字段
+	LunarDate string `json:"lunar_date,omitempty" gorm:"-"` // 农历日期
+	Timing    string `json:"timing,omitempty" gorm:"-"`     // 推算方式，如逝世第7天
+}
+
+func (MourningObservance) TableName() string {
+	return "mourning_observances"
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Notification 站内通知
+type Notification struct {
+	ID        string         `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	UserID    string         `json:"user_id" gorm:"type:varchar(36);not null;index:idx_notification_user_read,priority:1;comment:接收人"`
+	Category  string         `json:"category" gorm:"type:varchar(20);not null;index;comment:invitation|access_request|reminder|moderation"`
+	Type      string         `json:"type" gorm:"type:varchar(50);not null;comment:具体通知类型，如 family_invitation"`
+	Title     string         `json:"title" gorm:"type:varchar(100);not null;comment:标题"`
+	Content   string         `json:"content" gorm:"type:text;comment:正文"`
+	Data      string         `json:"data" gorm:"type:json;comment:跳转所需的业务数据"`
+	ReadAt    *time.Time     `json:"read_at" gorm:"index:idx_notification_user_read,priority:2;comment:已读时间，为空表示未读"`
+	CreatedAt time.Time      `json:"created_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+func (Notification) TableName() string {
+	return "notifications"
+}
+
+// NotificationDelivery 通知在各渠道的投递记录
+type NotificationDelivery struct {
+	ID             string     `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	NotificationID string     `json:"notification_id" gorm:"type:varchar(36);not null;index;comment:通知ID"`
+	Channel        string     `json:"channel" gorm:"type:varchar(20);not null;comment:wechat|email|log"`
+	Status         string     `json:"status" gorm:"type:varchar(20);not null;index;comment:sent|failed|skipped"`
+	Error          string     `json:"error" gorm:"type:varchar(500);comment:失败或跳过原因"`
+	Attempts       int        `json:"attempts" gorm:"default:0;comment:已尝试次数"`
+	SentAt         *time.Time `json:"sent_at" gorm:"comment:发送成功时间"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+func (NotificationDelivery) TableName() string {
+	return "notification_deliveries"
+}
+
+// NotificationSetting 用户的通知偏好，没有记录时按默认值（微信开启、邮件关闭）
+type NotificationSetting struct {
+	UserID          string    `json:"user_id" gorm:"primaryKey;type:varchar(36)"`
+	Email           string    `json:"email" gorm:"type:varchar(100);comment:接收通知的邮箱"`
+	EmailEnabled    bool      `json:"email_enabled" gorm:"default:false;comment:是否发送邮件"`
+	WechatEnabled   bool      `json:"wechat_enabled" gorm:"default:true;comment:是否发送微信订阅消息"`
+	MutedCategories string    `json:"muted_categories" gorm:"type:json;comment:不推送到外部渠道的通知分类，站内信照常保存"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+func (NotificationSetting) TableName() string {
+	return "notification_settings"
+}
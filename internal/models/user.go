@@ -14,6 +14,7 @@ type User struct {
 	AvatarURL     string         `json:"avatarUrl" gorm:"column:avatar_url;type:varchar(255);comment:头像URL"`
 	Phone         string         `json:"phone" gorm:"type:varchar(20);comment:手机号"`
 	Status        int            `json:"status" gorm:"default:1;comment:状态:1正常 0禁用"`
+	LastLoginAt   *time.Time     `json:"lastLoginAt" gorm:"column:last_login_at;comment:最后登录时间"`
 	CreatedAt     time.Time      `json:"createdAt" gorm:"column:created_at;comment:创建时间"`
 	UpdatedAt     time.Time      `json:"updatedAt" gorm:"column:updated_at;comment:更新时间"`
 	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index;comment:删除时间"`
@@ -9,7 +9,8 @@ import (
 type WorshipRecord struct {
 	ID          string         `json:"id" gorm:"primaryKey;type:varchar(36);comment:祭扫记录ID"`
 	MemorialID  string         `json:"memorialId" gorm:"type:varchar(36);not null;index;comment:纪念馆ID"`
-	UserID      string         `json:"userId" gorm:"type:varchar(36);not null;index;comment:用户ID"`
+	UserID      string         `json:"userId" gorm:"type:varchar(36);not null;index;comment:用户ID(访客祭扫时为匿名访客用户)"`
+	GuestID     string         `json:"guestId,omitempty" gorm:"type:varchar(36);index;comment:访客身份ID，访客匿名祭扫时填写"`
 	WorshipType string         `json:"worshipType" gorm:"type:varchar(20);not null;comment:祭扫类型:flower献花 candle点烛 incense上香 tribute供品 prayer祈福"`
 	Content     string         `json:"content" gorm:"type:json;comment:祭扫内容(JSON格式)"`
 	CreatedAt   time.Time      `json:"createdAt" gorm:"comment:创建时间"`
@@ -26,14 +27,21 @@ func (WorshipRecord) TableName() string {
 }
 
 type Prayer struct {
-	ID         string         `json:"id" gorm:"primaryKey;type:varchar(36);comment:祈福ID"`
-	MemorialID string         `json:"memorial_id" gorm:"type:varchar(36);not null;index;comment:纪念馆ID"`
-	UserID     string         `json:"user_id" gorm:"type:varchar(36);not null;index;comment:用户ID"`
-	Content    string         `json:"content" gorm:"type:text;not null;comment:祈福内容"`
-	IsPublic   bool           `json:"is_public" gorm:"default:true;comment:是否公开显示"`
-	CreatedAt  time.Time      `json:"created_at" gorm:"comment:创建时间"`
-	UpdatedAt  time.Time      `json:"updated_at" gorm:"comment:更新时间"`
-	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index;comment:删除时间"`
+	ID               string         `json:"id" gorm:"primaryKey;type:varchar(36);comment:祈福ID"`
+	MemorialID       string         `json:"memorial_id" gorm:"type:varchar(36);not null;index;comment:纪念馆ID"`
+	UserID           string         `json:"user_id" gorm:"type:varchar(36);not null;index;comment:用户ID"`
+	Content          string         `json:"content" gorm:"type:text;not null;comment:祈福内容"`
+	IsPublic         bool           `json:"is_public" gorm:"default:true;comment:是否公开显示"`
+	Relationship     string         `json:"relationship,omitempty" gorm:"type:varchar(20);comment:与逝者关系:parent父母 grandparent祖辈 spouse配偶 sibling兄弟姐妹 child子女 relative亲属 friend朋友 other其他"`
+	ModerationStatus string         `json:"moderation_status" gorm:"type:varchar(20);not null;default:approved;index;comment:审核状态:pending待审核 approved已通过 rejected已拒绝 hidden已隐藏"`
+	ModerationNote   string         `json:"moderation_note,omitempty" gorm:"type:varchar(255);comment:审核备注"`
+	ModeratedBy      string         `json:"moderated_by,omitempty" gorm:"type:varchar(36);comment:审核人ID"`
+	ModeratedAt      *time.Time     `json:"moderated_at,omitempty" gorm:"comment:审核时间"`
+	IsPinned         bool           `json:"is_pinned" gorm:"default:false;comment:是否置顶"`
+	PinnedAt         *time.Time     `json:"pinned_at,omitempty" gorm:"comment:置顶时间"`
+	CreatedAt        time.Time      `json:"created_at" gorm:"comment:创建时间"`
+	UpdatedAt        time.Time      `json:"updated_at" gorm:"comment:更新时间"`
+	DeletedAt        gorm.DeletedAt `json:"-" gorm:"index;comment:删除时间"`
 
 	// 关联关系
 	Memorial Memorial `json:"memorial" gorm:"foreignKey:MemorialID"`
@@ -45,22 +53,80 @@ func (Prayer) TableName() string {
 }
 
 type Message struct {
-	ID          string         `json:"id" gorm:"primaryKey;type:varchar(36);comment:留言ID"`
-	MemorialID  string         `json:"memorial_id" gorm:"type:varchar(36);not null;index;comment:纪念馆ID"`
-	UserID      string         `json:"user_id" gorm:"type:varchar(36);not null;index;comment:用户ID"`
-	MessageType string         `json:"message_type" gorm:"type:varchar(20);not null;comment:留言类型:text文字 audio音频 video视频"`
-	Content     string         `json:"content" gorm:"type:text;comment:留言内容"`
-	MediaURL    string         `json:"media_url" gorm:"type:varchar(255);comment:媒体文件URL"`
-	Duration    int            `json:"duration" gorm:"comment:音频/视频时长(秒)"`
-	CreatedAt   time.Time      `json:"created_at" gorm:"comment:创建时间"`
-	UpdatedAt   time.Time      `json:"updated_at" gorm:"comment:更新时间"`
-	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index;comment:删除时间"`
+	ID                string         `json:"id" gorm:"primaryKey;type:varchar(36);comment:留言ID"`
+	MemorialID        string         `json:"memorial_id" gorm:"type:varchar(36);not null;index;comment:纪念馆ID"`
+	UserID            string         `json:"user_id" gorm:"type:varchar(36);not null;index;comment:用户ID"`
+	MessageType       string         `json:"message_type" gorm:"type:varchar(20);not null;comment:留言类型:text文字 audio音频 video视频"`
+	Content           string         `json:"content" gorm:"type:text;comment:留言内容"`
+	MediaURL          string         `json:"media_url" gorm:"type:varchar(255);comment:媒体文件URL"`
+	MediaFileID       string         `json:"media_file_id" gorm:"type:varchar(36);index;comment:关联媒体文件ID"`
+	Duration          int            `json:"duration" gorm:"comment:音频/视频时长(秒)"`
+	Emotion           string         `json:"emotion" gorm:"type:varchar(20);index;comment:情感分类:sad悲伤 happy快乐 nostalgic怀旧 grateful感恩 peaceful平静"`
+	EmotionConfidence float64        `json:"emotion_confidence" gorm:"comment:情感分析置信度"`
+	EmotionPolarity   float64        `json:"emotion_polarity" gorm:"comment:情感倾向(-1消极到1积极)"`
+	ModerationStatus  string         `json:"moderation_status" gorm:"type:varchar(20);not null;default:approved;index;comment:审核状态:pending待审核 approved已通过 rejected已拒绝 hidden已隐藏"`
+	ModerationNote    string         `json:"moderation_note,omitempty" gorm:"type:varchar(255);comment:审核备注"`
+	ModeratedBy       string         `json:"moderated_by,omitempty" gorm:"type:varchar(36);comment:审核人ID"`
+	ModeratedAt       *time.Time     `json:"moderated_at,omitempty" gorm:"comment:审核时间"`
+	IsPinned          bool           `json:"is_pinned" gorm:"default:false;comment:是否置顶"`
+	PinnedAt          *time.Time     `json:"pinned_at,omitempty" gorm:"comment:置顶时间"`
+	UnlockAt          *time.Time     `json:"unlock_at,omitempty" gorm:"index;comment:时光胶囊解锁时间，为空表示立即可见"`
+	Audience          string         `json:"audience" gorm:"type:varchar(20);not null;default:everyone;comment:可见范围:everyone所有人 family家人 users指定用户"`
+	UnlockNotifiedAt  *time.Time     `json:"-" gorm:"index;comment:解锁通知发送时间"`
+	CreatedAt         time.Time      `json:"created_at" gorm:"comment:创建时间"`
+	UpdatedAt         time.Time      `json:"updated_at" gorm:"comment:更新时间"`
+	DeletedAt         gorm.DeletedAt `json:"-" gorm:"index;comment:删除时间"`
 
 	// 关联关系
-	Memorial Memorial `json:"memorial" gorm:"foreignKey:MemorialID"`
-	User     User     `json:"user" gorm:"foreignKey:UserID"`
+	Memorial     Memorial   `json:"memorial" gorm:"foreignKey:MemorialID"`
+	User         User       `json:"user" gorm:"foreignKey:UserID"`
+	MediaFile    *MediaFile `json:"media_file,omitempty" gorm:"-"`    // 音视频探测信息（封面、波形），查询时填充
+	IsLocked     bool       `json:"is_locked" gorm:"-"`               // 是否尚未解锁，仅作者能看到未解锁的留言
+	RecipientIDs []string   `json:"recipient_ids,omitempty" gorm:"-"` // 指定接收人，仅作者可见
 }
 
 func (Message) TableName() string {
 	return "messages"
 }
+
+// MessageRecipient 时光胶囊的接收人。指定用户的胶囊在创建时登记，
+// 面向家人或所有人的胶囊在解锁时按当时的家族成员登记
+type MessageRecipient struct {
+	ID         string     `json:"id" gorm:"primaryKey;type:varchar(36);comment:ID"`
+	MessageID  string     `json:"message_id" gorm:"type:varchar(36);not null;uniqueIndex:idx_message_recipient;comment:留言ID"`
+	UserID     string     `json:"user_id" gorm:"type:varchar(36);not null;uniqueIndex:idx_message_recipient;index;comment:接收人ID"`
+	NotifiedAt *time.Time `json:"notified_at" gorm:"comment:解锁通知时间"`
+	ReadAt     *time.Time `json:"read_at" gorm:"comment:阅读时间"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"comment:创建时间"`
+
+	// 关联关系
+	Message *Message `json:"message,omitempty" gorm:"foreignKey:MessageID"`
+}
+
+func (MessageRecipient) TableName() string {
+	return "message_recipients"
+}
+
+// WorshipReportExport 祭扫报告导出任务，异步生成文件后通过下载链接获取
+type WorshipReportExport struct {
+	ID           string     `json:"id" gorm:"primaryKey;type:varchar(36);comment:导出任务ID"`
+	MemorialID   string     `json:"memorial_id" gorm:"type:varchar(36);not null;index;comment:纪念馆ID"`
+	UserID       string     `json:"user_id" gorm:"type:varchar(36);not null;index;comment:发起用户ID"`
+	Source       string     `json:"source" gorm:"type:varchar(20);not null;comment:数据来源:report周期报告 statistics详细统计"`
+	Period       string     `json:"period" gorm:"type:varchar(20);comment:统计周期:week month quarter year"`
+	Format       string     `json:"format" gorm:"type:varchar(10);not null;comment:文件格式:csv xlsx pdf"`
+	Status       string     `json:"status" gorm:"type:varchar(20);not null;default:pending;index;comment:任务状态:pending processing completed failed"`
+	FileName     string     `json:"file_name" gorm:"type:varchar(255);comment:下载文件名"`
+	FilePath     string     `json:"-" gorm:"type:varchar(500);comment:服务器文件路径"`
+	FileSize     int64      `json:"file_size" gorm:"comment:文件大小(字节)"`
+	DownloadURL  string     `json:"download_url" gorm:"type:varchar(255);comment:下载链接"`
+	ErrorMessage string     `json:"error_message,omitempty" gorm:"type:text;comment:失败原因"`
+	ExpiresAt    *time.Time `json:"expires_at" gorm:"comment:文件过期时间"`
+	CompletedAt  *time.Time `json:"completed_at" gorm:"comment:完成时间"`
+	CreatedAt    time.Time  `json:"created_at" gorm:"comment:创建时间"`
+	UpdatedAt    time.Time  `json:"updated_at" gorm:"comment:更新时间"`
+}
+
+func (WorshipReportExport) TableName() string {
+	return "worship_report_exports"
+}
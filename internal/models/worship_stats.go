@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+)
+
+// MemorialWorshipDaily 纪念馆每日祭扫汇总，按小时和祭扫类型细分，祭扫时增量维护
+type MemorialWorshipDaily struct {
+	MemorialID   string    `json:"memorial_id" gorm:"primaryKey;type:varchar(36);comment:纪念馆ID"`
+	StatDate     time.Time `json:"stat_date" gorm:"primaryKey;type:date;index;comment:统计日期"`
+	StatHour     int       `json:"stat_hour" gorm:"primaryKey;autoIncrement:false;comment:小时(0-23)"`
+	WorshipType  string    `json:"worship_type" gorm:"primaryKey;type:varchar(20);comment:祭扫类型"`
+	WorshipCount int64     `json:"worship_count" gorm:"not null;default:0;comment:祭扫次数"`
+	VisitorCount int64     `json:"visitor_count" gorm:"not null;default:0;comment:当日首次祭扫落在该行的访客数，按日期相加即为当日独立访客数"`
+	UpdatedAt    time.Time `json:"updated_at" gorm:"comment:更新时间"`
+}
+
+func (MemorialWorshipDaily) TableName() string {
+	return "memorial_worship_dailies"
+}
+
+// UserWorshipDaily 用户每日祭扫汇总，按小时和祭扫类型细分
+type UserWorshipDaily struct {
+	UserID       string    `json:"user_id" gorm:"primaryKey;type:varchar(36);comment:用户ID"`
+	StatDate     time.Time `json:"stat_date" gorm:"primaryKey;type:date;index;comment:统计日期"`
+	StatHour     int       `json:"stat_hour" gorm:"primaryKey;autoIncrement:false;comment:小时(0-23)"`
+	WorshipType  string    `json:"worship_type" gorm:"primaryKey;type:varchar(20);comment:祭扫类型"`
+	WorshipCount int64     `json:"worship_count" gorm:"not null;default:0;comment:祭扫次数"`
+	UpdatedAt    time.Time `json:"updated_at" gorm:"comment:更新时间"`
+}
+
+func (UserWorshipDaily) TableName() string {
+	return "user_worship_dailies"
+}
+
+// MemorialVisitorStat 访客在纪念馆的累计祭扫，用于独立访客数、访客排行和用户参与的纪念馆数
+type MemorialVisitorStat struct {
+	MemorialID     string    `json:"memorial_id" gorm:"primaryKey;type:varchar(36);comment:纪念馆ID"`
+	UserID         string    `json:"user_id" gorm:"primaryKey;type:varchar(36);index;comment:用户ID"`
+	WorshipCount   int64     `json:"worship_count" gorm:"not null;default:0;index;comment:累计祭扫次数"`
+	FirstWorshipAt time.Time `json:"first_worship_at" gorm:"comment:首次祭扫时间"`
+	LastWorshipAt  time.Time `json:"last_worship_at" gorm:"comment:最近祭扫时间"`
+	// 本次更新前的最近祭扫时间，用于在同一条 upsert 中判断是否当日首次祭扫
+	PreviousWorshipAt *time.Time `json:"-" gorm:"comment:更新前的最近祭扫时间"`
+	UpdatedAt         time.Time  `json:"updated_at" gorm:"comment:更新时间"`
+}
+
+func (MemorialVisitorStat) TableName() string {
+	return "memorial_visitor_stats"
+}
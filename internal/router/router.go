@@ -1,10 +1,13 @@
 package router
 
 import (
+	"log"
+	"time"
 	"yun-nian-memorial/internal/config"
 	"yun-nian-memorial/internal/controllers"
 	"yun-nian-memorial/internal/middleware"
 	"yun-nian-memorial/internal/services"
+	"yun-nian-memorial/internal/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-redis/redis/v8"
@@ -40,21 +43,107 @@ func Setup(db *gorm.DB, rdb *redis.Client, cfg *config.Config) *gin.Engine {
 	familyService := services.NewFamilyService(db)
 	privacyService := services.NewPrivacyService(db)
 	adminService := services.NewAdminService(db)
+	contentFilterService := services.NewContentFilterService(db)
+	reportExportService := services.NewReportExportService(db, "exports/reports") // 报告导出目录
+	guestWorshipService := services.NewGuestWorshipService(db)
+	collectiveWorshipService := services.NewCollectiveWorshipService(db)
+	familyInviteLinkService := services.NewFamilyInviteLinkService(db, cfg.Family.InviteLinkBaseURL)
+	familyGedcomService := services.NewFamilyGedcomService(db)
+	familyGenealogyRenderService := services.NewFamilyGenealogyRenderService(db, "uploads")
+	notificationService := services.NewNotificationService(db)
+	reminderService := services.NewReminderService(db)
+	mourningService := services.NewMourningService(db)
 
 	// 设置服务依赖关系（避免循环依赖）
 	worshipService.SetFamilyService(familyService)
+	worshipService.SetMediaService(mediaService)
+	reportExportService.SetWorshipService(worshipService)
+	collectiveWorshipService.SetFamilyService(familyService)
+	familyInviteLinkService.SetFamilyService(familyService)
+	familyGedcomService.SetFamilyService(familyService)
+	familyGenealogyRenderService.SetFamilyService(familyService)
+	familyService.SetOwnerInactiveDays(cfg.Family.OwnerInactiveDays)
+	mourningService.SetFamilyService(familyService)
+	mourningService.SetCollectiveWorshipService(collectiveWorshipService)
+	memorialService.SetMourningService(mourningService)
+	adminService.SetMourningService(mourningService)
+
+	// 通知：站内信之外按配置推送到微信订阅消息、邮件
+	if cfg.Wechat.AppID != "" && cfg.Wechat.AppSecret != "" {
+		wechatClient := services.NewWechatSubscribeClient(cfg.Wechat.AppID, cfg.Wechat.AppSecret)
+		notificationService.AddChannel(services.NewWechatChannel(wechatClient, cfg.Notification.WechatTemplates, cfg.Notification.WechatPage))
+	}
+	if cfg.Notification.SMTP.Host != "" {
+		notificationService.AddChannel(services.NewEmailChannel(cfg.Notification.SMTP))
+	}
+	if cfg.Notification.LogChannel {
+		notificationService.AddChannel(services.NewLogChannel())
+	}
+	familyService.SetNotificationService(notificationService)
+	privacyService.SetNotificationService(notificationService)
+	memorialServiceService.SetNotificationService(notificationService)
+	collectiveWorshipService.SetNotificationService(notificationService)
+	worshipService.SetNotificationService(notificationService)
+	reminderService.SetNotificationService(notificationService)
+	mourningService.SetNotificationService(notificationService)
+	adminService.SetNotificationService(notificationService)
+
+	// 敏感词过滤（留言、祈福、墓志铭、故事、追思会聊天）
+	worshipService.SetContentFilter(contentFilterService)
+	memorialService.SetContentFilter(contentFilterService)
+	lifeStoryService.SetContentFilter(contentFilterService)
+	familyService.SetContentFilter(contentFilterService)
+	memorialServiceService.SetContentFilter(contentFilterService)
+	guestWorshipService.SetContentFilter(contentFilterService)
+	collectiveWorshipService.SetContentFilter(contentFilterService)
+	adminService.SetContentFilter(contentFilterService)
+
+	// 定期解锁到期的时光胶囊并通知接收人
+	worshipService.StartTimeCapsuleDispatcher(time.Minute)
+	// 定期根据公开祈福重建热门祈福推荐
+	worshipService.StartPrayerSuggestionRefresher(time.Hour)
+	// 集体祭扫开始前提醒、到点开始、到时结束并发布总结
+	collectiveWorshipService.StartCollectiveWorshipScheduler(time.Minute)
+	// 所有者账号失效或长期未登录时移交家族圈
+	familyService.StartOwnerSuccessionChecker(time.Hour)
+	// 家族传统临近时在家族动态中提醒
+	familyService.StartTraditionReminderScheduler(time.Hour)
+	// 重试投递失败的站外通知
+	notificationService.StartNotificationRetrier(10 * time.Minute)
+	// 按规则和提前量发送纪念日提醒，每次每个提前量只发一次
+	reminderService.StartReminderDispatcher(time.Minute)
+
+	// 自定义情感词典与内置词典合并，加载失败时沿用内置词典
+	if cfg.NLP.SentimentLexiconPath != "" {
+		if lexicon, err := utils.LoadSentimentLexicon(cfg.NLP.SentimentLexiconPath); err != nil {
+			log.Printf("加载情感词典失败，使用内置词典: %v", err)
+		} else {
+			merged := utils.DefaultSentimentLexicon()
+			merged.Merge(lexicon)
+			worshipService.SetSentimentAnalyzer(utils.NewLexiconSentimentAnalyzer(merged))
+		}
+	}
 
 	// 初始化控制器
 	userController := controllers.NewUserController(userService)
 	memorialController := controllers.NewMemorialController(memorialService)
 	mediaController := controllers.NewMediaController(mediaService)
 	worshipController := controllers.NewWorshipController(worshipService)
+	reportExportController := controllers.NewReportExportController(reportExportService)
 	albumController := controllers.NewAlbumController(albumService)
 	lifeStoryController := controllers.NewLifeStoryController(lifeStoryService)
 	memorialServiceController := controllers.NewMemorialServiceController(memorialServiceService)
 	familyController := controllers.NewFamilyController(familyService)
 	privacyController := controllers.NewPrivacyController(privacyService)
 	adminController := controllers.NewAdminController(adminService)
+	guestWorshipController := controllers.NewGuestWorshipController(guestWorshipService)
+	collectiveWorshipController := controllers.NewCollectiveWorshipController(collectiveWorshipService)
+	familyInviteLinkController := controllers.NewFamilyInviteLinkController(familyInviteLinkService)
+	familyGedcomController := controllers.NewFamilyGedcomController(familyGedcomService)
+	familyGenealogyRenderController := controllers.NewFamilyGenealogyRenderController(familyGenealogyRenderService)
+	notificationController := controllers.NewNotificationController(notificationService)
+	reminderController := controllers.NewReminderController(reminderService)
+	mourningController := controllers.NewMourningController(mourningService)
 
 	// 静态文件服务
 	r.Static("/uploads", "./uploads")
@@ -77,9 +166,22 @@ func Setup(db *gorm.DB, rdb *redis.Client, cfg *config.Config) *gin.Engine {
 			auth.POST("/wechat-login", userController.WechatLogin)
 		}
 
+		// 访客匿名祭扫（无需登录，仅限公开纪念馆，凭 X-Guest-Token 设备令牌）
+		guest := api.Group("/guest")
+		{
+			guest.POST("/challenges", guestWorshipController.CreateChallenge)
+			guest.POST("/sessions", guestWorshipController.CreateSession)
+			guest.POST("/memorials/:memorial_id/flowers", guestWorshipController.OfferFlowers)
+			guest.POST("/memorials/:memorial_id/candles", guestWorshipController.LightCandle)
+			guest.POST("/memorials/:memorial_id/incense", guestWorshipController.OfferIncense)
+			guest.POST("/memorials/:memorial_id/tributes", guestWorshipController.OfferTribute)
+		}
+
 		// 需要认证的路由
 		protected := api.Group("/")
 		protected.Use(middleware.JWTAuth(cfg.JWT.Secret))
+		// 创建类接口支持 Idempotency-Key，重试时重放首次响应
+		idempotency := middleware.Idempotency(db)
 		{
 			// 用户相关路由
 			users := protected.Group("/users")
@@ -98,10 +200,13 @@ func Setup(db *gorm.DB, rdb *redis.Client, cfg *config.Config) *gin.Engine {
 				users.GET("/families", userController.GetUserFamilies)
 				users.GET("/memorials/:memorial_id/visitors", userController.GetMemorialVisitors)
 				users.GET("/reminders/upcoming", userController.GetUpcomingReminders)
+				users.POST("/reminders/:reminder_id/snooze", reminderController.SnoozeReminder)
+				users.POST("/reminders/:reminder_id/dismiss", reminderController.DismissReminder)
 			}
 
 			// 纪念馆相关路由
 			memorials := protected.Group("/memorials")
+			memorials.Use(idempotency)
 			{
 				memorials.GET("/recent", memorialController.GetRecentMemorials) // 最近访问的纪念馆
 				memorials.GET("/", memorialController.GetMemorialList)
@@ -110,6 +215,7 @@ func Setup(db *gorm.DB, rdb *redis.Client, cfg *config.Config) *gin.Engine {
 				memorials.PUT("/:id", memorialController.UpdateMemorial)
 				memorials.DELETE("/:id", memorialController.DeleteMemorial)
 				memorials.GET("/:id/visitors", memorialController.GetMemorialVisitors)
+				memorials.GET("/:id/related", familyController.GetRelatedMemorials) // 按家族谱系查找亲属的纪念馆
 
 				// 墓碑定制相关路由
 				memorials.PUT("/:id/tombstone-style", memorialController.UpdateTombstoneStyle)
@@ -145,6 +251,7 @@ func Setup(db *gorm.DB, rdb *redis.Client, cfg *config.Config) *gin.Engine {
 
 			// 祭扫相关路由
 			worship := protected.Group("/worship")
+			worship.Use(idempotency)
 			{
 				// 传统祭扫功能
 				worship.POST("/memorials/:memorial_id/flowers", worshipController.OfferFlowers)
@@ -153,6 +260,7 @@ func Setup(db *gorm.DB, rdb *redis.Client, cfg *config.Config) *gin.Engine {
 				worship.GET("/memorials/:memorial_id/candles/status", worshipController.GetCandleStatus)
 				worship.POST("/memorials/:memorial_id/incense", worshipController.OfferIncense)
 				worship.POST("/memorials/:memorial_id/tributes", worshipController.OfferTribute)
+				worship.POST("/family-offerings", worshipController.OfferFamilyWorship) // 家族合祭
 
 				// 祈福和留言功能
 				worship.POST("/memorials/:memorial_id/prayers", worshipController.CreatePrayer)
@@ -173,6 +281,17 @@ func Setup(db *gorm.DB, rdb *redis.Client, cfg *config.Config) *gin.Engine {
 				// 内容审核
 				worship.POST("/messages/:message_id/moderate", worshipController.ModerateMessage)
 
+				// 馆主审核（先审后发、隐藏、置顶）
+				worship.GET("/memorials/:memorial_id/moderation-settings", worshipController.GetModerationSettings)
+				worship.PUT("/memorials/:memorial_id/moderation-settings", worshipController.UpdateModerationSettings)
+				worship.GET("/memorials/:memorial_id/moderation-queue", worshipController.GetModerationQueue)
+				worship.PUT("/messages/:message_id/status", worshipController.ReviewMessage)
+				worship.PUT("/prayers/:prayer_id/status", worshipController.ReviewPrayer)
+
+				// 馆主设置访客祭扫
+				worship.GET("/memorials/:memorial_id/guest-settings", guestWorshipController.GetGuestWorshipSettings)
+				worship.PUT("/memorials/:memorial_id/guest-settings", guestWorshipController.UpdateGuestWorshipSettings)
+
 				// 查询功能
 				worship.GET("/memorials/:memorial_id/records", worshipController.GetWorshipRecords)
 				worship.GET("/memorials/:memorial_id/prayer-wall", worshipController.GetPrayerWall)
@@ -182,6 +301,14 @@ func Setup(db *gorm.DB, rdb *redis.Client, cfg *config.Config) *gin.Engine {
 				worship.GET("/memorials/:memorial_id/report", worshipController.GenerateWorshipReport)
 				worship.GET("/user/history", worshipController.GetUserWorshipHistory)
 				worship.GET("/user/behavior-analysis", worshipController.AnalyzeUserWorshipBehavior)
+				worship.GET("/user/time-capsules", worshipController.GetReceivedTimeCapsules)
+				worship.POST("/user/time-capsules/:message_id/read", worshipController.MarkTimeCapsuleRead)
+
+				// 报告导出（CSV/XLSX/PDF，异步生成）
+				worship.POST("/memorials/:memorial_id/report-exports", reportExportController.CreateReportExport)
+				worship.GET("/memorials/:memorial_id/report-exports", reportExportController.GetReportExports)
+				worship.GET("/report-exports/:export_id", reportExportController.GetReportExport)
+				worship.GET("/report-exports/:export_id/download", reportExportController.DownloadReportExport)
 			}
 
 			// 纪念相册相关路由
@@ -245,6 +372,7 @@ func Setup(db *gorm.DB, rdb *redis.Client, cfg *config.Config) *gin.Engine {
 
 			// 家族相关路由
 			families := protected.Group("/families")
+			families.Use(idempotency)
 			{
 				// 家族圈管理
 				families.GET("/", familyController.GetFamilies)
@@ -259,10 +387,31 @@ func Setup(db *gorm.DB, rdb *redis.Client, cfg *config.Config) *gin.Engine {
 				families.DELETE("/:family_id/members/:member_id", familyController.RemoveMember)
 				families.PUT("/:family_id/members/:member_id/role", familyController.SetMemberRole)
 				families.POST("/:family_id/leave", familyController.LeaveFamily)
+				families.POST("/:family_id/transfer", familyController.TransferOwnership)
+
+				// 权限矩阵
+				families.GET("/:family_id/permissions", familyController.GetFamilyPermissions)
+				families.PUT("/:family_id/permissions", familyController.UpdateFamilyPermissions)
+
+				// 支系
+				families.POST("/:family_id/branches", familyController.CreateFamilyBranch)
+				families.GET("/:family_id/branches", familyController.GetFamilyBranches)
 
 				// 邀请管理
 				families.POST("/join-by-code", familyController.JoinFamilyByCode)
 				families.POST("/invitations/:invitation_id/respond", familyController.RespondToInvitation)
+				families.POST("/:family_id/invite-code/rotate", familyController.RotateInviteCode)
+
+				// 邀请链接（有效期、使用次数、预设角色、加入审核、二维码）
+				families.GET("/invite-links/:token", familyInviteLinkController.PreviewInviteLink)
+				families.POST("/invite-links/:token/join", familyInviteLinkController.JoinByInviteLink)
+				families.POST("/:family_id/invite-links", familyInviteLinkController.CreateInviteLink)
+				families.GET("/:family_id/invite-links", familyInviteLinkController.GetInviteLinks)
+				families.DELETE("/:family_id/invite-links/:link_id", familyInviteLinkController.RevokeInviteLink)
+				families.GET("/:family_id/invite-links/:link_id/uses", familyInviteLinkController.GetInviteLinkUses)
+				families.GET("/:family_id/invite-links/:link_id/qrcode", familyInviteLinkController.GetInviteLinkQRCode)
+				families.GET("/:family_id/join-requests", familyInviteLinkController.GetJoinRequests)
+				families.POST("/:family_id/join-requests/:request_id/review", familyInviteLinkController.ReviewJoinRequest)
 
 				// 家族活动
 				families.GET("/:family_id/activities", familyController.GetFamilyActivities)
@@ -275,17 +424,50 @@ func Setup(db *gorm.DB, rdb *redis.Client, cfg *config.Config) *gin.Engine {
 				families.POST("/:family_id/reminders", familyController.SetMemorialReminder)
 				families.GET("/:family_id/reminders", familyController.GetFamilyReminders)
 				families.GET("/:family_id/reminders/upcoming", familyController.GetUpcomingReminders)
+				families.GET("/:family_id/calendar", familyController.GetFamilyCalendar)
 				families.DELETE("/:family_id/reminders/:reminder_id", familyController.DeleteReminder)
 
+				// 守丧日程（做七、百日、周年）
+				families.GET("/mourning-rule-sets", mourningController.GetMourningRuleSets)
+				families.GET("/:family_id/memorials/:memorial_id/mourning-schedule", mourningController.GetMourningSchedule)
+				families.POST("/:family_id/memorials/:memorial_id/mourning-schedule", mourningController.GenerateMourningSchedule)
+				families.DELETE("/:family_id/memorials/:memorial_id/mourning-schedule", mourningController.DeleteMourningSchedule)
+				families.POST("/:family_id/memorials/:memorial_id/mourning-schedule/:key/collective-worship", mourningController.ScheduleMourningCollectiveWorship)
+
 				// 集体祭扫
-				families.POST("/:family_id/collective-worship", familyController.InitiateCollectiveWorship)
-				families.POST("/:family_id/collective-worship/:activity_id/join", familyController.JoinCollectiveWorship)
+				families.GET("/collective-worship/reminders", collectiveWorshipController.GetCollectiveWorshipReminders)
+				families.POST("/:family_id/collective-worship", collectiveWorshipController.ScheduleCollectiveWorship)
+				families.GET("/:family_id/collective-worship", collectiveWorshipController.GetCollectiveWorships)
+				families.GET("/:family_id/collective-worship/:worship_id", collectiveWorshipController.GetCollectiveWorship)
+				families.PUT("/:family_id/collective-worship/:worship_id/rsvp", collectiveWorshipController.RSVPCollectiveWorship)
+				families.POST("/:family_id/collective-worship/:worship_id/cancel", collectiveWorshipController.CancelCollectiveWorship)
+				families.POST("/:family_id/collective-worship/:worship_id/start", collectiveWorshipController.StartCollectiveWorship)
+				families.POST("/:family_id/collective-worship/:worship_id/end", collectiveWorshipController.EndCollectiveWorship)
+				// 祭扫现场：进入、轮询状态、默哀、献祭
+				families.POST("/:family_id/collective-worship/:worship_id/join", collectiveWorshipController.JoinCollectiveWorship)
+				families.GET("/:family_id/collective-worship/:worship_id/live", collectiveWorshipController.GetLiveState)
+				families.POST("/:family_id/collective-worship/:worship_id/silence", collectiveWorshipController.StartMomentOfSilence)
+				families.POST("/:family_id/collective-worship/:worship_id/offerings", collectiveWorshipController.OfferCollectiveWorship)
 
 				// 家族谱系
 				families.POST("/:family_id/genealogy", familyController.CreateGenealogy)
 				families.GET("/:family_id/genealogy", familyController.GetFamilyGenealogy)
 				families.PUT("/:family_id/genealogy/:genealogy_id", familyController.UpdateGenealogy)
 				families.DELETE("/:family_id/genealogy/:genealogy_id", familyController.DeleteGenealogy)
+				families.POST("/:family_id/genealogy/import/preview", familyGedcomController.PreviewGedcomImport)
+				families.POST("/:family_id/genealogy/import", familyGedcomController.ImportGedcom)
+				families.GET("/:family_id/genealogy/export", familyGedcomController.ExportGedcom)
+				families.GET("/:family_id/genealogy/chart", familyGenealogyRenderController.GetGenealogyChart)
+				families.GET("/:family_id/genealogy/audit", familyController.AuditGenealogy)
+				families.GET("/:family_id/genealogy/memorial-suggestions", familyController.GetGenealogyMemorialSuggestions)
+				families.GET("/:family_id/genealogy/path", familyController.GetGenealogyPath)
+				families.GET("/:family_id/genealogy/kinship", familyController.GetGenealogyKinship)
+				families.PUT("/:family_id/genealogy/me", familyController.SetMyGenealogy)
+				families.GET("/:family_id/genealogy/:genealogy_id/ancestors", familyController.GetGenealogyAncestors)
+				families.GET("/:family_id/genealogy/:genealogy_id/descendants", familyController.GetGenealogyDescendants)
+				families.POST("/:family_id/genealogy/relations", familyController.CreateGenealogyRelation)
+				families.PUT("/:family_id/genealogy/relations/:relation_id", familyController.UpdateGenealogyRelation)
+				families.DELETE("/:family_id/genealogy/relations/:relation_id", familyController.DeleteGenealogyRelation)
 
 				// 家族故事
 				families.POST("/:family_id/stories", familyController.CreateFamilyStory)
@@ -321,6 +503,18 @@ func Setup(db *gorm.DB, rdb *redis.Client, cfg *config.Config) *gin.Engine {
 				privacy.DELETE("/memorials/:memorial_id/blacklist/:user_id", privacyController.RemoveFromBlacklist)
 			}
 
+			// 站内通知相关路由
+			notifications := protected.Group("/notifications")
+			{
+				notifications.GET("/", notificationController.GetNotifications)
+				notifications.GET("/unread-count", notificationController.GetUnreadCount)
+				notifications.PUT("/read-all", notificationController.MarkAllAsRead)
+				notifications.PUT("/:id/read", notificationController.MarkAsRead)
+				notifications.DELETE("/:id", notificationController.DeleteNotification)
+				notifications.GET("/settings", notificationController.GetSettings)
+				notifications.PUT("/settings", notificationController.UpdateSettings)
+			}
+
 			// 系统管理相关路由（需要管理员权限）
 			admin := protected.Group("/admin")
 			// 为管理员接口添加额外的安全保护
@@ -335,6 +529,18 @@ func Setup(db *gorm.DB, rdb *redis.Client, cfg *config.Config) *gin.Engine {
 				admin.GET("/content/pending", adminController.GetPendingContent)
 				admin.POST("/content/moderate", adminController.ModerateContent)
 				admin.POST("/content/batch-moderate", adminController.BatchModerateContent)
+				admin.GET("/content/filter-hits", adminController.GetContentFilterHits)
+				admin.POST("/content/filter-hits/:hit_id/review", adminController.ReviewContentFilterHit)
+
+				// 敏感词库管理
+				admin.GET("/sensitive-words", adminController.GetSensitiveWords)
+				admin.PUT("/sensitive-words/:category", adminController.SetSensitiveWords)
+				admin.POST("/sensitive-words/test", adminController.TestSensitiveText)
+
+				// 守丧规则管理
+				admin.GET("/mourning-rule-sets", adminController.GetMourningRuleSets)
+				admin.PUT("/mourning-rule-sets/:key", adminController.SetMourningRuleSet)
+				admin.DELETE("/mourning-rule-sets/:key", adminController.DeleteMourningRuleSet)
 
 				// 系统统计
 				admin.GET("/stats", adminController.GetSystemStats)
@@ -5,12 +5,16 @@ import (
 	"fmt"
 	"time"
 	"yun-nian-memorial/internal/models"
+	"yun-nian-memorial/internal/utils"
 
 	"gorm.io/gorm"
 )
 
 type AdminService struct {
-	db *gorm.DB
+	db            *gorm.DB
+	contentFilter *ContentFilterService
+	mourning      *MourningService
+	notifications *NotificationService
 }
 
 func NewAdminService(db *gorm.DB) *AdminService {
@@ -19,6 +23,21 @@ func NewAdminService(db *gorm.DB) *AdminService {
 	}
 }
 
+// SetContentFilter 设置敏感词过滤服务依赖
+func (s *AdminService) SetContentFilter(contentFilter *ContentFilterService) {
+	s.contentFilter = contentFilter
+}
+
+// SetMourningService 设置守丧日程服务依赖（守丧规则管理）
+func (s *AdminService) SetMourningService(mourning *MourningService) {
+	s.mourning = mourning
+}
+
+// SetNotificationService 设置通知服务依赖（通知作者留言、祈福的审核结果）
+func (s *AdminService) SetNotificationService(notifications *NotificationService) {
+	s.notifications = notifications
+}
+
 // 用户状态常量
 const (
 	UserStatusActive   = 1 // 正常
@@ -32,8 +51,17 @@ const (
 	ContentStatusPending  = 0 // 待审核
 	ContentStatusApproved = 1 // 审核通过
 	ContentStatusRejected = 2 // 审核拒绝
+	ContentStatusHidden   = 3 // 已隐藏
 )
 
+// 留言/祈福审核状态与管理后台状态码的对应关系
+var moderationStatusCodes = map[string]int{
+	ModerationStatusPending:  ContentStatusPending,
+	ModerationStatusApproved: ContentStatusApproved,
+	ModerationStatusRejected: ContentStatusRejected,
+	ModerationStatusHidden:   ContentStatusHidden,
+}
+
 // 管理员角色常量
 const (
 	RoleUser       = "user"
@@ -214,14 +242,17 @@ func (s *AdminService) GetUserDetail(adminID, userID string) (*UserDetailRespons
 
 	// 构建响应
 	lastLogin := user.CreatedAt
+	if user.LastLoginAt != nil {
+		lastLogin = *user.LastLoginAt
+	}
 	response := &UserDetailResponse{
 		User:           user,
 		MemorialCount:  memorialCount,
 		WorshipCount:   worshipCount,
 		FamilyCount:    familyCount,
-		LastLoginTime:  &lastLogin, // TODO: User模型需要添加LastLoginAt字段
-		RegistrationIP: "",         // 如果有IP记录的话
-		LastLoginIP:    "",         // 如果有IP记录的话
+		LastLoginTime:  &lastLogin,
+		RegistrationIP: "", // 如果有IP记录的话
+		LastLoginIP:    "", // 如果有IP记录的话
 	}
 
 	return response, nil
@@ -316,10 +347,12 @@ func (s *AdminService) GetSystemStats(adminID string) (*SystemStats, error) {
 	s.db.Model(&models.WorshipRecord{}).Count(&stats.TotalWorship)
 
 	// 待审核内容统计
-	var pendingMemorials, pendingMessages int64
+	var pendingMemorials, pendingMessages, pendingPrayers int64
 	s.db.Model(&models.Memorial{}).Where("status = ?", ContentStatusPending).Count(&pendingMemorials)
-	s.db.Model(&models.WorshipRecord{}).Where("status = ?", ContentStatusPending).Count(&pendingMessages)
-	stats.PendingContent = pendingMemorials + pendingMessages
+	s.db.Model(&models.Message{}).Where("moderation_status = ?", ModerationStatusPending).
+		Where(messageUnlockedClause, time.Now()).Count(&pendingMessages)
+	s.db.Model(&models.Prayer{}).Where("moderation_status = ?", ModerationStatusPending).Count(&pendingPrayers)
+	stats.PendingContent = pendingMemorials + pendingMessages + pendingPrayers
 
 	// 今日统计
 	today := time.Now().Format("2006-01-02")
@@ -415,15 +448,18 @@ func (s *AdminService) getPendingMemorials(page, pageSize int) ([]ContentDetailR
 
 // 获取待审核留言
 func (s *AdminService) getPendingMessages(page, pageSize int) ([]ContentDetailResponse, int64, error) {
-	var messages []models.WorshipRecord
+	var messages []models.Message
 	var total int64
 
-	// 计算总数
-	s.db.Model(&models.WorshipRecord{}).Where("status = ? AND message != ''", ContentStatusPending).Count(&total)
+	// 计算总数（未解锁的时光胶囊解锁后才进入审核）
+	now := time.Now()
+	s.db.Model(&models.Message{}).Where("moderation_status = ?", ModerationStatusPending).
+		Where(messageUnlockedClause, now).Count(&total)
 
 	// 分页查询
 	offset := (page - 1) * pageSize
-	err := s.db.Where("status = ? AND message != ''", ContentStatusPending).
+	err := s.db.Where("moderation_status = ?", ModerationStatusPending).
+		Where(messageUnlockedClause, now).
 		Order("created_at ASC").
 		Offset(offset).
 		Limit(pageSize).
@@ -437,27 +473,16 @@ func (s *AdminService) getPendingMessages(page, pageSize int) ([]ContentDetailRe
 	for _, message := range messages {
 		userIDs = append(userIDs, message.UserID)
 	}
-
-	var users []models.User
-	if len(userIDs) > 0 {
-		s.db.Where("id IN ?", userIDs).Find(&users)
-	}
-
-	// 创建用户ID到用户的映射
-	userMap := make(map[string]models.User)
-	for _, user := range users {
-		userMap[user.ID] = user
-	}
+	userMap := s.getUserMap(userIDs)
 
 	var contents []ContentDetailResponse
 	for _, message := range messages {
-		creator := userMap[message.UserID]
 		contents = append(contents, ContentDetailResponse{
 			ContentType: "message",
 			Content:     message,
-			Creator:     creator,
+			Creator:     userMap[message.UserID],
 			CreatedAt:   message.CreatedAt,
-			Status:      1, // TODO: Message模型需要添加Status字段，1表示active
+			Status:      moderationStatusCodes[message.ModerationStatus],
 			ReportCount: 0,
 		})
 	}
@@ -467,15 +492,15 @@ func (s *AdminService) getPendingMessages(page, pageSize int) ([]ContentDetailRe
 
 // 获取待审核祈福
 func (s *AdminService) getPendingPrayers(page, pageSize int) ([]ContentDetailResponse, int64, error) {
-	var prayers []models.WorshipRecord
+	var prayers []models.Prayer
 	var total int64
 
 	// 计算总数
-	s.db.Model(&models.WorshipRecord{}).Where("status = ? AND type = 'prayer'", ContentStatusPending).Count(&total)
+	s.db.Model(&models.Prayer{}).Where("moderation_status = ?", ModerationStatusPending).Count(&total)
 
 	// 分页查询
 	offset := (page - 1) * pageSize
-	err := s.db.Where("status = ? AND type = 'prayer'", ContentStatusPending).
+	err := s.db.Where("moderation_status = ?", ModerationStatusPending).
 		Order("created_at ASC").
 		Offset(offset).
 		Limit(pageSize).
@@ -489,27 +514,16 @@ func (s *AdminService) getPendingPrayers(page, pageSize int) ([]ContentDetailRes
 	for _, prayer := range prayers {
 		userIDs = append(userIDs, prayer.UserID)
 	}
-
-	var users []models.User
-	if len(userIDs) > 0 {
-		s.db.Where("id IN ?", userIDs).Find(&users)
-	}
-
-	// 创建用户ID到用户的映射
-	userMap := make(map[string]models.User)
-	for _, user := range users {
-		userMap[user.ID] = user
-	}
+	userMap := s.getUserMap(userIDs)
 
 	var contents []ContentDetailResponse
 	for _, prayer := range prayers {
-		creator := userMap[prayer.UserID]
 		contents = append(contents, ContentDetailResponse{
 			ContentType: "prayer",
 			Content:     prayer,
-			Creator:     creator,
+			Creator:     userMap[prayer.UserID],
 			CreatedAt:   prayer.CreatedAt,
-			Status:      1, // TODO: Prayer模型需要添加Status字段，1表示active
+			Status:      moderationStatusCodes[prayer.ModerationStatus],
 			ReportCount: 0,
 		})
 	}
@@ -517,6 +531,20 @@ func (s *AdminService) getPendingPrayers(page, pageSize int) ([]ContentDetailRes
 	return contents, total, nil
 }
 
+// 批量获取用户信息
+func (s *AdminService) getUserMap(userIDs []string) map[string]models.User {
+	var users []models.User
+	if len(userIDs) > 0 {
+		s.db.Where("id IN ?", userIDs).Find(&users)
+	}
+
+	userMap := make(map[string]models.User)
+	for _, user := range users {
+		userMap[user.ID] = user
+	}
+	return userMap
+}
+
 // 获取所有待审核内容
 func (s *AdminService) getAllPendingContent(page, pageSize int) ([]ContentDetailResponse, int64, error) {
 	var allContents []ContentDetailResponse
@@ -573,7 +601,7 @@ func (s *AdminService) ModerateContent(adminID string, req *ContentModerationReq
 	case "memorial":
 		return s.moderateMemorial(req.ContentID, newStatus, req.Reason)
 	case "message", "prayer":
-		return s.moderateWorshipRecord(req.ContentID, newStatus, req.Reason)
+		return s.moderateWorshipContent(adminID, req.ContentType, []string{req.ContentID}, newStatus, req.Reason)
 	default:
 		return errors.New("不支持的内容类型")
 	}
@@ -604,27 +632,60 @@ func (s *AdminService) moderateMemorial(memorialID string, status int, reason st
 	return nil
 }
 
-// 审核祭扫记录（包括留言和祈福）
-func (s *AdminService) moderateWorshipRecord(recordID string, status int, reason string) error {
-	var record models.WorshipRecord
-	err := s.db.Where("id = ?", recordID).First(&record).Error
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("记录不存在")
-		}
-		return err
+// 审核留言和祈福
+func (s *AdminService) moderateWorshipContent(adminID, contentType string, contentIDs []string, status int, reason string) error {
+	var model interface{}
+	switch contentType {
+	case "message":
+		model = &models.Message{}
+	case "prayer":
+		model = &models.Prayer{}
+	default:
+		return errors.New("不支持的内容类型")
 	}
 
-	err = s.db.Model(&record).Updates(map[string]interface{}{
-		"status":     status,
-		"updated_at": time.Now(),
-	}).Error
-	if err != nil {
-		return fmt.Errorf("更新记录状态失败: %v", err)
+	moderationStatus := ModerationStatusApproved
+	updates := map[string]interface{}{
+		"moderation_note": reason,
+		"moderated_by":    adminID,
+		"moderated_at":    time.Now(),
+		"updated_at":      time.Now(),
+	}
+	if status == ContentStatusRejected {
+		moderationStatus = ModerationStatusRejected
+		updates["is_pinned"] = false
+		updates["pinned_at"] = nil
+	}
+	updates["moderation_status"] = moderationStatus
+
+	// 审核前的状态，用于只通知状态有变化的作者
+	var contents []struct {
+		ID               string
+		UserID           string
+		MemorialID       string
+		ModerationStatus string
+	}
+	s.db.Model(model).Select("id, user_id, memorial_id, moderation_status").
+		Where("id IN ?", contentIDs).Scan(&contents)
+
+	result := s.db.Model(model).Where("id IN ?", contentIDs).Updates(updates)
+	if result.Error != nil {
+		return fmt.Errorf("更新记录状态失败: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("记录不存在")
 	}
 
 	// 记录审核日志
-	s.logModerationAction(recordID, "worship_record", status, reason)
+	for _, id := range contentIDs {
+		s.logModerationAction(id, contentType, status, reason)
+	}
+
+	for _, content := range contents {
+		if content.ModerationStatus != moderationStatus && content.UserID != adminID {
+			notifyModerationResult(s.notifications, content.UserID, contentType, content.ID, content.MemorialID, moderationStatus, reason)
+		}
+	}
 
 	return nil
 }
@@ -656,7 +717,7 @@ func (s *AdminService) BatchModerateContent(adminID string, contentIDs []string,
 	case "memorial":
 		return s.batchModerateMemorials(contentIDs, newStatus, reason)
 	case "message", "prayer":
-		return s.batchModerateWorshipRecords(contentIDs, newStatus, reason)
+		return s.moderateWorshipContent(adminID, contentType, contentIDs, newStatus, reason)
 	default:
 		return errors.New("不支持的内容类型")
 	}
@@ -683,27 +744,6 @@ func (s *AdminService) batchModerateMemorials(memorialIDs []string, status int,
 	return nil
 }
 
-// 批量审核祭扫记录
-func (s *AdminService) batchModerateWorshipRecords(recordIDs []string, status int, reason string) error {
-	err := s.db.Model(&models.WorshipRecord{}).
-		Where("id IN ?", recordIDs).
-		Updates(map[string]interface{}{
-			"status":     status,
-			"updated_at": time.Now(),
-		}).Error
-
-	if err != nil {
-		return fmt.Errorf("批量更新记录状态失败: %v", err)
-	}
-
-	// 记录批量审核日志
-	for _, id := range recordIDs {
-		s.logModerationAction(id, "worship_record", status, reason)
-	}
-
-	return nil
-}
-
 // 记录管理员操作日志
 func (s *AdminService) logAdminAction(adminID, actionType string, details interface{}) {
 	// 这里可以实现管理员操作日志记录
@@ -716,8 +756,196 @@ func (s *AdminService) logModerationAction(contentID, contentType string, status
 	statusText := map[int]string{
 		ContentStatusApproved: "approved",
 		ContentStatusRejected: "rejected",
+		ContentStatusHidden:   "hidden",
 	}
 
 	fmt.Printf("Content Moderation: ContentID=%s, Type=%s, Status=%s, Reason=%s, Time=%s\n",
 		contentID, contentType, statusText[status], reason, time.Now().Format("2006-01-02 15:04:05"))
 }
+
+// 敏感词管理
+
+// 敏感词命中查询请求
+type ContentFilterHitQuery struct {
+	ContentType  string `form:"content_type"`
+	Action       string `form:"action"`
+	ReviewStatus string `form:"review_status"`
+	MemorialID   string `form:"memorial_id"`
+	Page         int    `form:"page"`
+	PageSize     int    `form:"page_size"`
+}
+
+// 命中复核请求
+type ContentFilterReviewRequest struct {
+	Result string `json:"result" binding:"required,oneof=confirmed dismissed"`
+	Note   string `json:"note"`
+}
+
+// 获取敏感词库
+func (s *AdminService) GetSensitiveWordLists(adminID string) (map[string][]utils.SensitiveWord, error) {
+	isAdmin, _, err := s.CheckAdminPermission(adminID)
+	if err != nil || !isAdmin {
+		return nil, errors.New("权限不足")
+	}
+	if s.contentFilter == nil {
+		return nil, errors.New("敏感词服务未初始化")
+	}
+
+	return s.contentFilter.GetSensitiveWordLists()
+}
+
+// 替换某个分类的敏感词
+func (s *AdminService) SetSensitiveWords(adminID, category string, words []utils.SensitiveWord) error {
+	isAdmin, _, err := s.CheckAdminPermission(adminID)
+	if err != nil || !isAdmin {
+		return errors.New("权限不足")
+	}
+	if s.contentFilter == nil {
+		return errors.New("敏感词服务未初始化")
+	}
+
+	if err := s.contentFilter.SetSensitiveWords(category, words); err != nil {
+		return err
+	}
+
+	s.logAdminAction(adminID, "set_sensitive_words", map[string]interface{}{
+		"category": category,
+		"count":    len(words),
+	})
+	return nil
+}
+
+// 使用当前词库检测文本（不记录命中）
+func (s *AdminService) TestSensitiveText(adminID, text string) ([]utils.SensitiveMatch, error) {
+	isAdmin, _, err := s.CheckAdminPermission(adminID)
+	if err != nil || !isAdmin {
+		return nil, errors.New("权限不足")
+	}
+	if s.contentFilter == nil {
+		return nil, errors.New("敏感词服务未初始化")
+	}
+
+	return s.contentFilter.MatchText(text), nil
+}
+
+// 获取敏感词命中记录
+func (s *AdminService) GetContentFilterHits(adminID string, req *ContentFilterHitQuery) ([]models.ContentFilterHit, int64, error) {
+	isAdmin, _, err := s.CheckAdminPermission(adminID)
+	if err != nil || !isAdmin {
+		return nil, 0, errors.New("权限不足")
+	}
+
+	query := s.db.Model(&models.ContentFilterHit{})
+	if req.ContentType != "" {
+		query = query.Where("content_type = ?", req.ContentType)
+	}
+	if req.Action != "" {
+		query = query.Where("action = ?", req.Action)
+	}
+	if req.ReviewStatus != "" {
+		query = query.Where("review_status = ?", req.ReviewStatus)
+	}
+	if req.MemorialID != "" {
+		query = query.Where("memorial_id = ?", req.MemorialID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var hits []models.ContentFilterHit
+	offset := (req.Page - 1) * req.PageSize
+	err = query.Order("created_at DESC").Offset(offset).Limit(req.PageSize).Find(&hits).Error
+	return hits, total, err
+}
+
+// 复核敏感词命中记录
+func (s *AdminService) ReviewContentFilterHit(adminID, hitID string, req *ContentFilterReviewRequest) error {
+	isAdmin, _, err := s.CheckAdminPermission(adminID)
+	if err != nil || !isAdmin {
+		return errors.New("权限不足")
+	}
+
+	var hit models.ContentFilterHit
+	if err := s.db.Where("id = ?", hitID).First(&hit).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("命中记录不存在")
+		}
+		return err
+	}
+	if hit.ReviewStatus != ContentReviewPending {
+		return errors.New("该记录已复核")
+	}
+
+	now := time.Now()
+	if err := s.db.Model(&hit).Updates(map[string]interface{}{
+		"review_status": req.Result,
+		"reviewer_id":   adminID,
+		"review_note":   req.Note,
+		"reviewed_at":   &now,
+	}).Error; err != nil {
+		return err
+	}
+
+	s.logAdminAction(adminID, "review_filter_hit", map[string]interface{}{
+		"hit_id": hitID,
+		"result": req.Result,
+	})
+	return nil
+}
+
+// 获取守丧规则（内置规则及系统配置中的规则）
+func (s *AdminService) GetMourningRuleSets(adminID string) ([]utils.MourningRuleSet, error) {
+	isAdmin, _, err := s.CheckAdminPermission(adminID)
+	if err != nil || !isAdmin {
+		return nil, errors.New("权限不足")
+	}
+	if s.mourning == nil {
+		return nil, errors.New("守丧日程服务未初始化")
+	}
+
+	return s.mourning.GetMourningRuleSets()
+}
+
+// 保存一套守丧规则
+func (s *AdminService) SetMourningRuleSet(adminID, key string, ruleSet *utils.MourningRuleSet) error {
+	isAdmin, _, err := s.CheckAdminPermission(adminID)
+	if err != nil || !isAdmin {
+		return errors.New("权限不足")
+	}
+	if s.mourning == nil {
+		return errors.New("守丧日程服务未初始化")
+	}
+
+	if err := s.mourning.SetMourningRuleSet(key, ruleSet); err != nil {
+		return err
+	}
+
+	s.logAdminAction(adminID, "set_mourning_rule_set", map[string]interface{}{
+		"key":   key,
+		"name":  ruleSet.Name,
+		"count": len(ruleSet.Observances),
+	})
+	return nil
+}
+
+// 删除系统配置中的守丧规则
+func (s *AdminService) DeleteMourningRuleSet(adminID, key string) error {
+	isAdmin, _, err := s.CheckAdminPermission(adminID)
+	if err != nil || !isAdmin {
+		return errors.New("权限不足")
+	}
+	if s.mourning == nil {
+		return errors.New("守丧日程服务未初始化")
+	}
+
+	if err := s.mourning.DeleteMourningRuleSet(key); err != nil {
+		return err
+	}
+
+	s.logAdminAction(adminID, "delete_mourning_rule_set", map[string]interface{}{
+		"key": key,
+	})
+	return nil
+}
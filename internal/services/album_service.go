@@ -4,6 +4,7 @@ import (
 	"errors"
 	"time"
 	"yun-nian-memorial/internal/models"
+	"yun-nian-memorial/internal/utils"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -288,14 +289,8 @@ func (s *AlbumService) validateMemorialAccess(userID, memorialID string) error {
 	// 检查隐私设置
 	if memorial.PrivacyLevel == 2 { // 私密
 		if memorial.CreatorID != userID {
-			// 检查是否是家族成员
-			var count int64
-			s.db.Table("family_members fm").
-				Joins("JOIN memorial_families mf ON fm.family_id = mf.family_id").
-				Where("mf.memorial_id = ? AND fm.user_id = ?", memorialID, userID).
-				Count(&count)
-			
-			if count == 0 {
+			// 检查是否是家族成员（含宗族与支系继承的成员身份）
+			if !utils.NewPermissionManager(s.db).IsMemorialFamilyMember(userID, memorialID) {
 				return errors.New("无权访问此纪念馆")
 			}
 		}
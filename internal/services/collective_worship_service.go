@@ -0,0 +1,828 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+	"yun-nian-memorial/internal/models"
+	"yun-nian-memorial/internal/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 集体祭扫状态
+const (
+	CollectiveWorshipScheduled = "scheduled"
+	CollectiveWorshipOngoing   = "ongoing"
+	CollectiveWorshipCompleted = "completed"
+	CollectiveWorshipCancelled = "cancelled"
+)
+
+// 集体祭扫报名状态
+const (
+	CollectiveRSVPGoing    = "going"
+	CollectiveRSVPMaybe    = "maybe"
+	CollectiveRSVPDeclined = "declined"
+)
+
+const (
+	defaultCollectiveDurationMinutes = 30
+	defaultCollectiveRemindMinutes   = 30
+	defaultCollectiveSilenceSeconds  = 60
+	// 现场轮询时单次返回的祭品上限
+	collectiveOfferingPageSize = 200
+)
+
+// CollectiveWorshipService 家族集体祭扫：预约、报名、开始前提醒、现场共同祭扫与默哀、结束后发布总结
+type CollectiveWorshipService struct {
+	db            *gorm.DB
+	familyService *FamilyService
+	contentFilter *ContentFilterService
+	notifications *NotificationService
+}
+
+func NewCollectiveWorshipService(db *gorm.DB) *CollectiveWorshipService {
+	return &CollectiveWorshipService{
+		db: db,
+	}
+}
+
+// SetFamilyService 设置家族圈服务依赖（成员权限校验、家族动态）
+func (s *CollectiveWorshipService) SetFamilyService(familyService *FamilyService) {
+	s.familyService = familyService
+}
+
+// SetContentFilter 设置敏感词过滤服务依赖
+func (s *CollectiveWorshipService) SetContentFilter(contentFilter *ContentFilterService) {
+	s.contentFilter = contentFilter
+}
+
+// SetNotificationService 设置通知服务依赖（开始前提醒）
+func (s *CollectiveWorshipService) SetNotificationService(notifications *NotificationService) {
+	s.notifications = notifications
+}
+
+// 预约集体祭扫请求
+type ScheduleCollectiveWorshipRequest struct {
+	MemorialID          string     `json:"memorial_id" binding:"required"`
+	Title               string     `json:"title" binding:"max=100"`
+	Description         string     `json:"description" binding:"max=500"`
+	WorshipType         string     `json:"worship_type" binding:"omitempty,oneof=flower candle incense tribute"` // 建议的祭扫方式，为空不限
+	ScheduledAt         *time.Time `json:"scheduled_at"`                                                         // 约定开始时间，为空表示立即开始
+	DurationMinutes     int        `json:"duration_minutes" binding:"omitempty,min=5,max=180"`                   // 默认30分钟
+	RemindBeforeMinutes *int       `json:"remind_before_minutes" binding:"omitempty,min=0,max=1440"`             // 默认提前30分钟，0表示不提醒
+	SilenceSeconds      int        `json:"silence_seconds" binding:"omitempty,min=10,max=600"`                   // 默认60秒
+}
+
+// 报名请求
+type CollectiveWorshipRSVPRequest struct {
+	RSVP string `json:"rsvp" binding:"required,oneof=going maybe declined"`
+}
+
+// 现场献祭请求，未填写的细节使用默认值
+type CollectiveOfferingRequest struct {
+	WorshipType  string   `json:"worship_type" binding:"required,oneof=flower candle incense tribute"`
+	FlowerType   string   `json:"flower_type"`                                 // 默认 chrysanthemum
+	Quantity     int      `json:"quantity" binding:"omitempty,min=1,max=99"`   // 默认1
+	CandleType   string   `json:"candle_type"`                                 // 默认 white
+	Duration     int      `json:"duration" binding:"omitempty,min=1,max=1440"` // 默认60分钟
+	IncenseType  string   `json:"incense_type"`                                // 默认 traditional
+	IncenseCount int      `json:"incense_count" binding:"omitempty,oneof=3 9"` // 默认3
+	TributeType  string   `json:"tribute_type"`                                // 默认 fruit
+	Items        []string `json:"items"`
+	Message      string   `json:"message" binding:"max=200"`
+}
+
+// CollectiveSilenceState 默哀状态，以服务端时间为准
+type CollectiveSilenceState struct {
+	Active           bool       `json:"active"`
+	StartedAt        *time.Time `json:"started_at"`
+	EndsAt           *time.Time `json:"ends_at"`
+	RemainingSeconds int        `json:"remaining_seconds"`
+}
+
+// CollectiveWorshipLiveState 祭扫现场状态，客户端轮询获取
+type CollectiveWorshipLiveState struct {
+	CollectiveWorship *models.CollectiveWorship              `json:"collective_worship"`
+	ServerTime        time.Time                              `json:"server_time"`
+	RemainingSeconds  int                                    `json:"remaining_seconds"` // 距自动结束的秒数
+	Silence           CollectiveSilenceState                 `json:"silence"`
+	Attendees         []*models.CollectiveWorshipParticipant `json:"attendees"`
+	Offerings         []*models.CollectiveWorshipOffering    `json:"offerings"`
+}
+
+// ScheduleCollectiveWorship 预约集体祭扫，发起人自动报名参加
+func (s *CollectiveWorshipService) ScheduleCollectiveWorship(userID, familyID string, req *ScheduleCollectiveWorshipRequest) (*models.CollectiveWorship, error) {
+	// 验证权限
+	if err := s.familyService.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionCollectiveWorship); err != nil {
+		return nil, err
+	}
+
+	// 验证纪念馆是否属于家族圈
+	var relation models.MemorialFamily
+	err := s.db.Where("memorial_id = ? AND family_id = ?", req.MemorialID, familyID).First(&relation).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("纪念馆未关联到此家族圈")
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	event := &models.CollectiveWorship{
+		ID:                  uuid.New().String(),
+		FamilyID:            familyID,
+		MemorialID:          req.MemorialID,
+		InitiatorID:         userID,
+		Title:               req.Title,
+		Description:         req.Description,
+		WorshipType:         req.WorshipType,
+		ScheduledAt:         now,
+		DurationMinutes:     req.DurationMinutes,
+		RemindBeforeMinutes: defaultCollectiveRemindMinutes,
+		SilenceSeconds:      req.SilenceSeconds,
+		Status:              CollectiveWorshipOngoing,
+		StartedAt:           &now,
+		CreatedAt:           now,
+		UpdatedAt:           now,
+	}
+	if event.Title == "" {
+		event.Title = "集体祭扫"
+	}
+	if event.DurationMinutes == 0 {
+		event.DurationMinutes = defaultCollectiveDurationMinutes
+	}
+	if event.SilenceSeconds == 0 {
+		event.SilenceSeconds = defaultCollectiveSilenceSeconds
+	}
+	if req.RemindBeforeMinutes != nil {
+		event.RemindBeforeMinutes = *req.RemindBeforeMinutes
+	}
+	if req.ScheduledAt != nil {
+		if req.ScheduledAt.Before(now) {
+			return nil, errors.New("约定时间不能早于当前时间")
+		}
+		event.ScheduledAt = *req.ScheduledAt
+		event.Status = CollectiveWorshipScheduled
+		event.StartedAt = nil
+	}
+
+	screen, err := s.contentFilter.Screen(ContentTypeCollectiveWorship, userID, req.MemorialID, event.Title, event.Description)
+	if err != nil {
+		return nil, err
+	}
+
+	initiator := &models.CollectiveWorshipParticipant{
+		ID:                  uuid.New().String(),
+		CollectiveWorshipID: event.ID,
+		UserID:              userID,
+		RSVP:                CollectiveRSVPGoing,
+		RespondedAt:         now,
+		CreatedAt:           now,
+		UpdatedAt:           now,
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(event).Error; err != nil {
+			return err
+		}
+		return tx.Create(initiator).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.contentFilter.RecordFlagged(screen, event.ID)
+
+	// 记录活动
+	s.familyService.recordActivity(familyID, userID, req.MemorialID, "collective_worship", map[string]interface{}{
+		"collective_worship_id": event.ID,
+		"title":                 event.Title,
+		"worship_type":          event.WorshipType,
+		"scheduled_at":          event.ScheduledAt,
+		"status":                event.Status,
+	})
+
+	return event, nil
+}
+
+// GetCollectiveWorships 获取家族圈的集体祭扫列表
+func (s *CollectiveWorshipService) GetCollectiveWorships(userID, familyID, status string, page, pageSize int) ([]*models.CollectiveWorship, int64, error) {
+	if err := s.familyService.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionView); err != nil {
+		return nil, 0, err
+	}
+
+	var events []*models.CollectiveWorship
+	var total int64
+
+	offset := (page - 1) * pageSize
+
+	query := s.db.Model(&models.CollectiveWorship{}).Where("family_id = ?", familyID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	query.Count(&total)
+
+	err := query.Preload("Memorial").Preload("Initiator").
+		Order("scheduled_at DESC").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&events).Error
+
+	return events, total, err
+}
+
+// GetCollectiveWorship 获取集体祭扫详情及报名情况
+func (s *CollectiveWorshipService) GetCollectiveWorship(userID, familyID, eventID string) (*models.CollectiveWorship, error) {
+	if err := s.familyService.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionView); err != nil {
+		return nil, err
+	}
+
+	var event models.CollectiveWorship
+	err := s.db.Preload("Memorial").Preload("Initiator").
+		Preload("Participants", func(db *gorm.DB) *gorm.DB {
+			return db.Order("responded_at ASC")
+		}).
+		Preload("Participants.User").
+		First(&event, "id = ? AND family_id = ?", eventID, familyID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("集体祭扫活动不存在")
+		}
+		return nil, err
+	}
+	return &event, nil
+}
+
+// RSVPCollectiveWorship 报名（参加/待定/不参加），可重复提交修改
+func (s *CollectiveWorshipService) RSVPCollectiveWorship(userID, familyID, eventID string, req *CollectiveWorshipRSVPRequest) error {
+	event, err := s.loadEvent(userID, familyID, eventID)
+	if err != nil {
+		return err
+	}
+	if event.Status != CollectiveWorshipScheduled && event.Status != CollectiveWorshipOngoing {
+		return errors.New("活动已结束")
+	}
+
+	now := time.Now()
+	participant, err := s.findParticipant(event.ID, userID)
+	if err != nil {
+		return err
+	}
+	if participant == nil {
+		return s.db.Create(&models.CollectiveWorshipParticipant{
+			ID:                  uuid.New().String(),
+			CollectiveWorshipID: event.ID,
+			UserID:              userID,
+			RSVP:                req.RSVP,
+			RespondedAt:         now,
+			CreatedAt:           now,
+			UpdatedAt:           now,
+		}).Error
+	}
+
+	return s.db.Model(participant).Updates(map[string]interface{}{
+		"rsvp":         req.RSVP,
+		"responded_at": now,
+		"updated_at":   now,
+	}).Error
+}
+
+// CancelCollectiveWorship 取消尚未开始的集体祭扫
+func (s *CollectiveWorshipService) CancelCollectiveWorship(userID, familyID, eventID string) error {
+	event, err := s.loadManagedEvent(userID, familyID, eventID)
+	if err != nil {
+		return err
+	}
+
+	result := s.db.Model(&models.CollectiveWorship{}).
+		Where("id = ? AND status = ?", event.ID, CollectiveWorshipScheduled).
+		Updates(map[string]interface{}{
+			"status":     CollectiveWorshipCancelled,
+			"ended_at":   time.Now(),
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("活动已开始或已结束")
+	}
+	return nil
+}
+
+// StartCollectiveWorship 发起人提前开始集体祭扫（到约定时间也会自动开始）
+func (s *CollectiveWorshipService) StartCollectiveWorship(userID, familyID, eventID string) error {
+	event, err := s.loadManagedEvent(userID, familyID, eventID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	result := s.db.Model(&models.CollectiveWorship{}).
+		Where("id = ? AND status = ?", event.ID, CollectiveWorshipScheduled).
+		Updates(map[string]interface{}{
+			"status":     CollectiveWorshipOngoing,
+			"started_at": now,
+			"updated_at": now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("活动已开始或已结束")
+	}
+	return nil
+}
+
+// EndCollectiveWorship 发起人结束集体祭扫并发布总结（到时长也会自动结束）
+func (s *CollectiveWorshipService) EndCollectiveWorship(userID, familyID, eventID string) error {
+	event, err := s.loadManagedEvent(userID, familyID, eventID)
+	if err != nil {
+		return err
+	}
+	if event.Status != CollectiveWorshipOngoing {
+		return errors.New("活动未在进行中")
+	}
+
+	completed, err := s.completeCollectiveWorship(event, time.Now())
+	if err != nil {
+		return err
+	}
+	if !completed {
+		return errors.New("活动未在进行中")
+	}
+	return nil
+}
+
+// JoinCollectiveWorship 进入祭扫现场，未报名的成员视为报名参加
+func (s *CollectiveWorshipService) JoinCollectiveWorship(userID, familyID, eventID string) (*CollectiveWorshipLiveState, error) {
+	event, err := s.loadEvent(userID, familyID, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if event.Status != CollectiveWorshipOngoing {
+		return nil, errors.New("活动未在进行中")
+	}
+
+	now := time.Now()
+	participant, err := s.findParticipant(event.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if participant == nil {
+		err = s.db.Create(&models.CollectiveWorshipParticipant{
+			ID:                  uuid.New().String(),
+			CollectiveWorshipID: event.ID,
+			UserID:              userID,
+			RSVP:                CollectiveRSVPGoing,
+			RespondedAt:         now,
+			JoinedAt:            &now,
+			CreatedAt:           now,
+			UpdatedAt:           now,
+		}).Error
+	} else if participant.JoinedAt == nil {
+		err = s.db.Model(participant).Updates(map[string]interface{}{
+			"rsvp":       CollectiveRSVPGoing,
+			"joined_at":  now,
+			"updated_at": now,
+		}).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.liveState(event, nil)
+}
+
+// GetLiveState 获取祭扫现场状态，since 不为空时只返回之后的新祭品
+func (s *CollectiveWorshipService) GetLiveState(userID, familyID, eventID string, since *time.Time) (*CollectiveWorshipLiveState, error) {
+	event, err := s.loadEvent(userID, familyID, eventID)
+	if err != nil {
+		return nil, err
+	}
+	return s.liveState(event, since)
+}
+
+// StartMomentOfSilence 发起人开始默哀，起止时间由服务端决定，各端按服务端时间同步倒计时
+func (s *CollectiveWorshipService) StartMomentOfSilence(userID, familyID, eventID string) (*CollectiveWorshipLiveState, error) {
+	event, err := s.loadManagedEvent(userID, familyID, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if event.Status != CollectiveWorshipOngoing {
+		return nil, errors.New("活动未在进行中")
+	}
+
+	now := time.Now()
+	endsAt := now.Add(time.Duration(event.SilenceSeconds) * time.Second)
+	result := s.db.Model(&models.CollectiveWorship{}).
+		Where("id = ? AND status = ? AND (silence_ends_at IS NULL OR silence_ends_at <= ?)", event.ID, CollectiveWorshipOngoing, now).
+		Updates(map[string]interface{}{
+			"silence_started_at": now,
+			"silence_ends_at":    endsAt,
+			"updated_at":         now,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("默哀正在进行中")
+	}
+
+	event.SilenceStartedAt = &now
+	event.SilenceEndsAt = &endsAt
+	return s.liveState(event, nil)
+}
+
+// OfferCollectiveWorship 在祭扫现场献祭，同时记为一条普通祭扫记录
+func (s *CollectiveWorshipService) OfferCollectiveWorship(userID, familyID, eventID string, req *CollectiveOfferingRequest) (*models.CollectiveWorshipOffering, error) {
+	event, err := s.loadEvent(userID, familyID, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if event.Status != CollectiveWorshipOngoing {
+		return nil, errors.New("活动未在进行中")
+	}
+
+	now := time.Now()
+	if event.SilenceEndsAt != nil && now.Before(*event.SilenceEndsAt) {
+		return nil, errors.New("默哀进行中，请稍后献祭")
+	}
+
+	participant, err := s.findParticipant(event.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if participant == nil || participant.JoinedAt == nil {
+		return nil, errors.New("请先进入祭扫现场")
+	}
+
+	content, err := buildCollectiveOfferingContent(req)
+	if err != nil {
+		return nil, err
+	}
+
+	screen, err := s.contentFilter.Screen(ContentTypeCollectiveWorship, userID, event.MemorialID, req.Message)
+	if err != nil {
+		return nil, err
+	}
+
+	contentJSON, _ := json.Marshal(content)
+
+	record := &models.WorshipRecord{
+		ID:          uuid.New().String(),
+		MemorialID:  event.MemorialID,
+		UserID:      userID,
+		WorshipType: req.WorshipType,
+		Content:     string(contentJSON),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	offering := &models.CollectiveWorshipOffering{
+		ID:                  uuid.New().String(),
+		CollectiveWorshipID: event.ID,
+		UserID:              userID,
+		WorshipRecordID:     record.ID,
+		WorshipType:         req.WorshipType,
+		CreatedAt:           now,
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := createWorshipRecord(tx, record); err != nil {
+			return err
+		}
+		return tx.Create(offering).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.contentFilter.RecordFlagged(screen, record.ID)
+
+	offering.WorshipRecord = *record
+	return offering, nil
+}
+
+// GetCollectiveWorshipReminders 获取当前用户已到提醒时间、尚未结束的集体祭扫
+func (s *CollectiveWorshipService) GetCollectiveWorshipReminders(userID string) ([]*models.CollectiveWorship, error) {
+	var events []*models.CollectiveWorship
+	err := s.db.Joins("JOIN collective_worship_participants p ON p.collective_worship_id = collective_worships.id").
+		Where("p.user_id = ? AND p.reminded_at IS NOT NULL AND p.rsvp <> ?", userID, CollectiveRSVPDeclined).
+		Where("collective_worships.status IN ?", []string{CollectiveWorshipScheduled, CollectiveWorshipOngoing}).
+		Preload("Memorial").
+		Order("collective_worships.scheduled_at ASC").
+		Find(&events).Error
+	return events, err
+}
+
+// StartCollectiveWorshipScheduler 定期发送开始前提醒、到点开始和到时结束集体祭扫
+func (s *CollectiveWorshipService) StartCollectiveWorshipScheduler(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			if err := s.ProcessCollectiveWorships(time.Now()); err != nil {
+				fmt.Printf("处理集体祭扫失败: %v\n", err)
+			}
+		}
+	}()
+}
+
+// ProcessCollectiveWorships 处理到期的提醒、开始和结束，可重复执行
+func (s *CollectiveWorshipService) ProcessCollectiveWorships(now time.Time) error {
+	// 开始前提醒：报名参加或待定、尚未提醒的成员
+	var upcoming []*models.CollectiveWorship
+	err := s.db.Where("status = ? AND remind_before_minutes > 0 AND scheduled_at <= ?",
+		CollectiveWorshipScheduled, now.Add(24*time.Hour)).
+		Find(&upcoming).Error
+	if err != nil {
+		return err
+	}
+	for _, event := range upcoming {
+		if now.Before(event.ScheduledAt.Add(-time.Duration(event.RemindBeforeMinutes) * time.Minute)) {
+			continue
+		}
+		var participants []*models.CollectiveWorshipParticipant
+		err := s.db.Where("collective_worship_id = ? AND rsvp IN ? AND reminded_at IS NULL",
+			event.ID, []string{CollectiveRSVPGoing, CollectiveRSVPMaybe}).
+			Find(&participants).Error
+		if err != nil {
+			return err
+		}
+
+		// 逐人以条件更新认领，多个实例同时执行时每人只提醒一次
+		var remindIDs []string
+		for _, participant := range participants {
+			result := s.db.Model(&models.CollectiveWorshipParticipant{}).
+				Where("id = ? AND reminded_at IS NULL", participant.ID).
+				Update("reminded_at", now)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				remindIDs = append(remindIDs, participant.UserID)
+			}
+		}
+		err = s.notifications.Notify(remindIDs, &NotificationInput{
+			Category: NotificationCategoryReminder,
+			Type:     NotificationTypeCollectiveWorship,
+			Title:    "集体祭扫即将开始",
+			Content:  fmt.Sprintf("“%s”将于%s开始", event.Title, event.ScheduledAt.Format("01-02 15:04")),
+			Data:     map[string]interface{}{"collective_worship_id": event.ID, "family_id": event.FamilyID, "memorial_id": event.MemorialID},
+		})
+		if err != nil {
+			fmt.Printf("发送集体祭扫 %s 提醒失败: %v\n", event.ID, err)
+		}
+	}
+
+	// 到约定时间自动开始
+	err = s.db.Model(&models.CollectiveWorship{}).
+		Where("status = ? AND scheduled_at <= ?", CollectiveWorshipScheduled, now).
+		Updates(map[string]interface{}{
+			"status":     CollectiveWorshipOngoing,
+			"started_at": now,
+			"updated_at": now,
+		}).Error
+	if err != nil {
+		return err
+	}
+
+	// 超过活动时长自动结束并发布总结
+	var ongoing []*models.CollectiveWorship
+	if err := s.db.Where("status = ?", CollectiveWorshipOngoing).Find(&ongoing).Error; err != nil {
+		return err
+	}
+	for _, event := range ongoing {
+		if event.StartedAt == nil || now.Before(event.StartedAt.Add(time.Duration(event.DurationMinutes)*time.Minute)) {
+			continue
+		}
+		if _, err := s.completeCollectiveWorship(event, now); err != nil {
+			fmt.Printf("结束集体祭扫 %s 失败: %v\n", event.ID, err)
+		}
+	}
+	return nil
+}
+
+// completeCollectiveWorship 结束活动并向家族动态发布参与者和祭品总结
+// 以状态条件更新保证只结束一次，返回 false 表示活动已被其他请求结束
+func (s *CollectiveWorshipService) completeCollectiveWorship(event *models.CollectiveWorship, now time.Time) (bool, error) {
+	completed := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.CollectiveWorship{}).
+			Where("id = ? AND status = ?", event.ID, CollectiveWorshipOngoing).
+			Updates(map[string]interface{}{
+				"status":     CollectiveWorshipCompleted,
+				"ended_at":   now,
+				"updated_at": now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		completed = true
+
+		var attendees []*models.CollectiveWorshipParticipant
+		err := tx.Preload("User").
+			Where("collective_worship_id = ? AND joined_at IS NOT NULL", event.ID).
+			Order("joined_at ASC").
+			Find(&attendees).Error
+		if err != nil {
+			return err
+		}
+		var offerings []*models.CollectiveWorshipOffering
+		err = tx.Preload("User").
+			Where("collective_worship_id = ?", event.ID).
+			Order("created_at ASC").
+			Find(&offerings).Error
+		if err != nil {
+			return err
+		}
+
+		joined := make([]string, 0, len(attendees))
+		nicknames := make(map[string]string)
+		for _, attendee := range attendees {
+			joined = append(joined, attendee.UserID)
+			nicknames[attendee.UserID] = attendee.User.Nickname
+		}
+		summaryOfferings := make([]utils.CollectiveOffering, 0, len(offerings))
+		for _, offering := range offerings {
+			summaryOfferings = append(summaryOfferings, utils.CollectiveOffering{
+				UserID:      offering.UserID,
+				WorshipType: offering.WorshipType,
+			})
+			nicknames[offering.UserID] = offering.User.Nickname
+		}
+
+		summary := utils.SummarizeCollectiveWorship(joined, summaryOfferings)
+		for i := range summary.Participants {
+			summary.Participants[i].Nickname = nicknames[summary.Participants[i].UserID]
+		}
+
+		content := map[string]interface{}{
+			"collective_worship_id": event.ID,
+			"title":                 event.Title,
+			"started_at":            event.StartedAt,
+			"ended_at":              now,
+			"silence_held":          event.SilenceStartedAt != nil,
+			"silence_seconds":       event.SilenceSeconds,
+			"participant_count":     summary.ParticipantCount,
+			"offering_count":        summary.OfferingCount,
+			"offerings_by_type":     summary.OfferingsByType,
+			"participants":          summary.Participants,
+		}
+		contentJSON, _ := json.Marshal(content)
+
+		activity := &models.FamilyActivity{
+			ID:           uuid.New().String(),
+			FamilyID:     event.FamilyID,
+			UserID:       event.InitiatorID,
+			MemorialID:   event.MemorialID,
+			ActivityType: "collective_worship_summary",
+			Content:      string(contentJSON),
+			Timestamp:    now,
+			CreatedAt:    now,
+		}
+		if err := tx.Create(activity).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.CollectiveWorship{}).Where("id = ?", event.ID).
+			Update("summary_activity_id", activity.ID).Error
+	})
+	return completed, err
+}
+
+// liveState 组装现场状态：到场成员、祭品、默哀倒计时和剩余时长
+func (s *CollectiveWorshipService) liveState(event *models.CollectiveWorship, since *time.Time) (*CollectiveWorshipLiveState, error) {
+	now := time.Now()
+	state := &CollectiveWorshipLiveState{
+		CollectiveWorship: event,
+		ServerTime:        now,
+		Silence: CollectiveSilenceState{
+			StartedAt: event.SilenceStartedAt,
+			EndsAt:    event.SilenceEndsAt,
+		},
+	}
+
+	if event.Status == CollectiveWorshipOngoing && event.StartedAt != nil {
+		endsAt := event.StartedAt.Add(time.Duration(event.DurationMinutes) * time.Minute)
+		if remaining := endsAt.Sub(now); remaining > 0 {
+			state.RemainingSeconds = int(remaining.Seconds())
+		}
+	}
+	if event.Status == CollectiveWorshipOngoing && event.SilenceEndsAt != nil && now.Before(*event.SilenceEndsAt) {
+		state.Silence.Active = true
+		state.Silence.RemainingSeconds = int(event.SilenceEndsAt.Sub(now).Seconds() + 0.999)
+	}
+
+	err := s.db.Preload("User").
+		Where("collective_worship_id = ? AND joined_at IS NOT NULL", event.ID).
+		Order("joined_at ASC").
+		Find(&state.Attendees).Error
+	if err != nil {
+		return nil, err
+	}
+
+	query := s.db.Preload("User").Preload("WorshipRecord").
+		Where("collective_worship_id = ?", event.ID)
+	if since != nil {
+		query = query.Where("created_at > ?", *since)
+	}
+	err = query.Order("created_at ASC").
+		Limit(collectiveOfferingPageSize).
+		Find(&state.Offerings).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// loadEvent 加载集体祭扫并校验家族成员身份
+func (s *CollectiveWorshipService) loadEvent(userID, familyID, eventID string) (*models.CollectiveWorship, error) {
+	if err := s.familyService.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionView); err != nil {
+		return nil, err
+	}
+
+	var event models.CollectiveWorship
+	err := s.db.First(&event, "id = ? AND family_id = ?", eventID, familyID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("集体祭扫活动不存在")
+		}
+		return nil, err
+	}
+	return &event, nil
+}
+
+// loadManagedEvent 加载集体祭扫并校验发起人身份，或有发起集体祭扫权限
+func (s *CollectiveWorshipService) loadManagedEvent(userID, familyID, eventID string) (*models.CollectiveWorship, error) {
+	event, err := s.loadEvent(userID, familyID, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if event.InitiatorID != userID {
+		allowed, err := s.familyService.permissions.CanFamily(userID, familyID, utils.FamilyActionCollectiveWorship)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			return nil, errors.New("只有发起人或管理员可以操作此活动")
+		}
+	}
+	return event, nil
+}
+
+// findParticipant 查找报名记录，未报名时返回 nil
+func (s *CollectiveWorshipService) findParticipant(eventID, userID string) (*models.CollectiveWorshipParticipant, error) {
+	var participant models.CollectiveWorshipParticipant
+	err := s.db.Where("collective_worship_id = ? AND user_id = ?", eventID, userID).First(&participant).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &participant, nil
+}
+
+// buildCollectiveOfferingContent 按祭扫类型构建与普通祭扫一致的内容，细节缺省时使用默认值
+func buildCollectiveOfferingContent(req *CollectiveOfferingRequest) (interface{}, error) {
+	switch req.WorshipType {
+	case "flower":
+		flower := &OfferFlowersRequest{FlowerType: req.FlowerType, Quantity: req.Quantity, Message: req.Message}
+		if flower.FlowerType == "" {
+			flower.FlowerType = "chrysanthemum"
+		}
+		if flower.Quantity == 0 {
+			flower.Quantity = 1
+		}
+		return buildFlowerContent(flower)
+	case "candle":
+		candle := &LightCandleRequest{CandleType: req.CandleType, Duration: req.Duration, Message: req.Message}
+		if candle.CandleType == "" {
+			candle.CandleType = "white"
+		}
+		if candle.Duration == 0 {
+			candle.Duration = 60
+		}
+		return buildCandleContent(candle), nil
+	case "incense":
+		incense := &OfferIncenseRequest{IncenseType: req.IncenseType, IncenseCount: req.IncenseCount, Message: req.Message}
+		if incense.IncenseType == "" {
+			incense.IncenseType = "traditional"
+		}
+		if incense.IncenseCount == 0 {
+			incense.IncenseCount = 3
+		}
+		return buildIncenseContent(incense), nil
+	case "tribute":
+		tribute := &OfferTributeRequest{TributeType: req.TributeType, Items: req.Items, Message: req.Message}
+		if tribute.TributeType == "" {
+			tribute.TributeType = "fruit"
+		}
+		return buildTributeContent(tribute), nil
+	}
+	return nil, errors.New("无效的祭扫类型")
+}
@@ -0,0 +1,263 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"yun-nian-memorial/internal/models"
+	"yun-nian-memorial/internal/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 敏感词库在系统配置中的存储约定：config_type = sensitive_word，
+// config_key = sensitive_words.<分类>，config_value 为 SensitiveWord 的 JSON 数组
+const (
+	SensitiveWordConfigType   = "sensitive_word"
+	sensitiveWordConfigPrefix = "sensitive_words."
+
+	// 词库缓存有效期，多实例部署时各实例在此时间内自动同步
+	sensitiveWordCacheTTL = 5 * time.Minute
+	// 命中记录中保存的内容摘录长度（字符）
+	contentFilterExcerptLength = 200
+)
+
+// 审核内容类型
+const (
+	ContentTypeMessage           = "message"
+	ContentTypePrayer            = "prayer"
+	ContentTypeEpitaph           = "epitaph"
+	ContentTypeLifeStory         = "life_story"
+	ContentTypeFamilyStory       = "family_story"
+	ContentTypeChat              = "chat"
+	ContentTypeGuestWorship      = "guest_worship"
+	ContentTypeCollectiveWorship = "collective_worship"
+	ContentTypeFamilyWorship     = "family_worship"
+)
+
+// 命中处理动作与复核状态
+const (
+	ContentFilterActionBlocked = "blocked"
+	ContentFilterActionFlagged = "flagged"
+
+	ContentReviewPending   = "pending"
+	ContentReviewConfirmed = "confirmed"
+	ContentReviewDismissed = "dismissed"
+)
+
+// ErrContentBlocked 内容命中拦截级敏感词
+var ErrContentBlocked = errors.New("内容包含违规词汇，请修改后重新提交")
+
+var sensitiveCategoryPattern = regexp.MustCompile(`^[a-z0-9_]{1,32}$`)
+
+type ContentFilterService struct {
+	db *gorm.DB
+
+	mu       sync.RWMutex
+	filter   *utils.SensitiveFilter
+	loadedAt time.Time
+}
+
+func NewContentFilterService(db *gorm.DB) *ContentFilterService {
+	return &ContentFilterService{
+		db: db,
+	}
+}
+
+// ContentScreenResult 内容检测结果
+type ContentScreenResult struct {
+	ContentType string                 `json:"content_type"`
+	UserID      string                 `json:"user_id"`
+	MemorialID  string                 `json:"memorial_id"`
+	Excerpt     string                 `json:"-"`
+	Matches     []utils.SensitiveMatch `json:"matches"`
+}
+
+// Flagged 是否存在需要人工复核的命中
+func (r *ContentScreenResult) Flagged() bool {
+	return r != nil && len(r.Matches) > 0
+}
+
+// Screen 同步检测用户提交的文本。命中拦截级词汇时记录并返回 ErrContentBlocked；
+// 仅命中复核级词汇时放行，调用方在内容保存后调用 RecordFlagged 登记。
+// 服务未注入（nil）时直接放行
+func (s *ContentFilterService) Screen(contentType, userID, memorialID string, texts ...string) (*ContentScreenResult, error) {
+	result := &ContentScreenResult{
+		ContentType: contentType,
+		UserID:      userID,
+		MemorialID:  memorialID,
+	}
+	if s == nil {
+		return result, nil
+	}
+
+	filter := s.getFilter()
+	var excerpts []string
+	for _, text := range texts {
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		matches := filter.Match(text)
+		if len(matches) > 0 {
+			result.Matches = append(result.Matches, matches...)
+			excerpts = append(excerpts, text)
+		}
+	}
+	if len(result.Matches) == 0 {
+		return result, nil
+	}
+	result.Excerpt = truncateRunes(strings.Join(excerpts, "\n"), contentFilterExcerptLength)
+
+	if utils.HasBlockingMatch(result.Matches) {
+		s.recordHit(result, "", ContentFilterActionBlocked)
+		return result, ErrContentBlocked
+	}
+	return result, nil
+}
+
+// RecordFlagged 登记复核级命中，contentID 为已保存内容的ID
+func (s *ContentFilterService) RecordFlagged(result *ContentScreenResult, contentID string) {
+	if s == nil || !result.Flagged() {
+		return
+	}
+	s.recordHit(result, contentID, ContentFilterActionFlagged)
+}
+
+func (s *ContentFilterService) recordHit(result *ContentScreenResult, contentID, action string) {
+	matchesJSON, _ := json.Marshal(result.Matches)
+	hit := &models.ContentFilterHit{
+		ID:           uuid.New().String(),
+		ContentType:  result.ContentType,
+		ContentID:    contentID,
+		UserID:       result.UserID,
+		MemorialID:   result.MemorialID,
+		Excerpt:      result.Excerpt,
+		Matches:      string(matchesJSON),
+		Action:       action,
+		ReviewStatus: ContentReviewPending,
+		CreatedAt:    time.Now(),
+	}
+	// 记录失败不影响用户提交结果
+	s.db.Create(hit)
+}
+
+// MatchText 检测文本但不记录，用于管理员调试词库
+func (s *ContentFilterService) MatchText(text string) []utils.SensitiveMatch {
+	return s.getFilter().Match(text)
+}
+
+// getFilter 获取缓存的匹配器，过期后从系统配置重新加载
+func (s *ContentFilterService) getFilter() *utils.SensitiveFilter {
+	s.mu.RLock()
+	filter, loadedAt := s.filter, s.loadedAt
+	s.mu.RUnlock()
+
+	if filter != nil && time.Since(loadedAt) < sensitiveWordCacheTTL {
+		return filter
+	}
+
+	if err := s.Reload(); err != nil && filter != nil {
+		// 加载失败时沿用旧词库
+		return filter
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.filter
+}
+
+// Reload 从系统配置重新构建敏感词匹配器
+func (s *ContentFilterService) Reload() error {
+	lists, err := s.GetSensitiveWordLists()
+	if err != nil {
+		return err
+	}
+
+	var words []utils.SensitiveWord
+	for _, list := range lists {
+		words = append(words, list...)
+	}
+	filter := utils.NewSensitiveFilter(words)
+
+	s.mu.Lock()
+	s.filter = filter
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+	return nil
+}
+
+// GetSensitiveWordLists 获取按分类组织的敏感词库
+func (s *ContentFilterService) GetSensitiveWordLists() (map[string][]utils.SensitiveWord, error) {
+	var configs []models.SystemConfig
+	err := s.db.Where("config_type = ? AND is_active = ?", SensitiveWordConfigType, true).
+		Order("config_key ASC").Find(&configs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	lists := make(map[string][]utils.SensitiveWord)
+	for _, config := range configs {
+		category := strings.TrimPrefix(config.ConfigKey, sensitiveWordConfigPrefix)
+		var words []utils.SensitiveWord
+		if err := json.Unmarshal([]byte(config.ConfigValue), &words); err != nil {
+			// 单个分类格式错误不影响其它分类
+			continue
+		}
+		for i := range words {
+			words[i].Category = category
+		}
+		lists[category] = words
+	}
+	return lists, nil
+}
+
+// SetSensitiveWords 整体替换某个分类的敏感词
+func (s *ContentFilterService) SetSensitiveWords(category string, words []utils.SensitiveWord) error {
+	if !sensitiveCategoryPattern.MatchString(category) {
+		return errors.New("分类名只能包含小写字母、数字和下划线")
+	}
+
+	cleaned := make([]utils.SensitiveWord, 0, len(words))
+	seen := make(map[string]bool)
+	for _, w := range words {
+		w.Word = strings.TrimSpace(w.Word)
+		w.Pinyin = strings.ToLower(strings.Join(strings.Fields(w.Pinyin), " "))
+		if w.Word == "" || seen[w.Word] {
+			continue
+		}
+		switch w.Level {
+		case "":
+			w.Level = utils.SensitiveLevelBlock
+		case utils.SensitiveLevelBlock, utils.SensitiveLevelReview:
+		default:
+			return errors.New("无效的处理级别")
+		}
+		w.Category = category
+		seen[w.Word] = true
+		cleaned = append(cleaned, w)
+	}
+
+	value, err := json.Marshal(cleaned)
+	if err != nil {
+		return err
+	}
+
+	configService := NewSystemConfigService(s.db)
+	if err := configService.SetSystemConfig(sensitiveWordConfigPrefix+category, string(value), SensitiveWordConfigType, "敏感词库: "+category); err != nil {
+		return err
+	}
+	return s.Reload()
+}
+
+// truncateRunes 按字符截断文本
+func truncateRunes(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit]) + "…"
+}
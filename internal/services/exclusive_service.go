@@ -316,10 +316,10 @@ func (s *ExclusiveServiceService) exportMemorialData(req *models.DataExportReque
 	s.db.Where("memorial_id = ?", req.TargetID).Find(&worshipRecords)
 	s.addJSONToZip(zipWriter, "worship_records.json", worshipRecords)
 	
-	// 导出留言和祈福
+	// 导出留言和祈福，只包含导出人可见的留言（未解锁的时光胶囊、不在可见范围内的留言不导出）
 	var messages []models.Message
 	s.db.Where("memorial_id = ?", req.TargetID).Find(&messages)
-	s.addJSONToZip(zipWriter, "messages.json", messages)
+	s.addJSONToZip(zipWriter, "messages.json", filterVisibleMessages(s.db, req.UserID, req.TargetID, messages))
 	
 	return filePath, nil
 }
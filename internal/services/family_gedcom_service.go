@@ -0,0 +1,593 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"yun-nian-memorial/internal/models"
+	"yun-nian-memorial/internal/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 导入时遇到重复人员的处理方式
+const (
+	GedcomDuplicateMerge  = "merge"  // 不新建，沿用已有的谱系成员
+	GedcomDuplicateCreate = "create" // 仍然新建
+)
+
+const (
+	// MaxGedcomFileSize GEDCOM 文件大小上限
+	MaxGedcomFileSize = 10 << 20
+	// 单个文件最多导入的人数
+	maxGedcomPersons = 5000
+	// 导出文件头中的来源系统标识
+	gedcomSourceID = "YUNNIAN"
+)
+
+// FamilyGedcomService 家族谱系的 GEDCOM 导入导出
+type FamilyGedcomService struct {
+	db            *gorm.DB
+	familyService *FamilyService
+}
+
+func NewFamilyGedcomService(db *gorm.DB) *FamilyGedcomService {
+	return &FamilyGedcomService{db: db}
+}
+
+// SetFamilyService 设置家族圈服务依赖（权限校验、支系范围、家族动态）
+func (s *FamilyGedcomService) SetFamilyService(familyService *FamilyService) {
+	s.familyService = familyService
+}
+
+// GEDCOM 导入请求
+type GedcomImportRequest struct {
+	OnDuplicate string            `form:"on_duplicate" binding:"omitempty,oneof=merge create"` // 默认 merge
+	Decisions   map[string]string `form:"-"`                                                   // 按人员 xref 单独指定 merge 或 create
+	Force       bool              `form:"force"`                                               // 存在 error 级一致性问题时仍然导入
+}
+
+// GedcomDuplicate 导入人员匹配到的已有谱系成员
+type GedcomDuplicate struct {
+	GenealogyID string     `json:"genealogy_id"`
+	PersonName  string     `json:"person_name"`
+	Generation  int        `json:"generation"`
+	BirthDate   *time.Time `json:"birth_date"`
+	Match       string     `json:"match"` // exact | possible
+}
+
+// GedcomImportPerson 导入计划中的一个人
+type GedcomImportPerson struct {
+	Xref        string           `json:"xref"`
+	PersonName  string           `json:"person_name"`
+	Gender      string           `json:"gender"`
+	BirthDate   *time.Time       `json:"birth_date"`
+	DeathDate   *time.Time       `json:"death_date"`
+	Generation  int              `json:"generation"`
+	ParentXrefs []string         `json:"parent_xrefs"`
+	MemorialID  string           `json:"memorial_id"`
+	Action      string           `json:"action"` // create | merge
+	Duplicate   *GedcomDuplicate `json:"duplicate"`
+	GenealogyID string           `json:"genealogy_id"` // 新建或沿用的谱系成员ID，预览时新建人员为空
+	Warnings    []string         `json:"warnings"`
+
+	person *utils.GedcomPerson
+}
+
+// GedcomImportPlan 导入预览或导入结果
+type GedcomImportPlan struct {
+	Version       string                 `json:"version"`
+	PersonCount   int                    `json:"person_count"`
+	FamilyCount   int                    `json:"family_count"`
+	CreateCount   int                    `json:"create_count"`
+	MergeCount    int                    `json:"merge_count"`
+	RelationCount int                    `json:"relation_count"` // 新建的父母子女和配偶关系数
+	ErrorCount    int                    `json:"error_count"`    // error 级一致性问题数，大于 0 时须设置 force 才能导入
+	Issues        []utils.GenealogyIssue `json:"issues"`         // 导入人员和关系的一致性问题
+	Persons       []*GedcomImportPerson  `json:"persons"`
+	Warnings      []string               `json:"warnings"`
+
+	relations []*models.GenealogyRelation
+}
+
+// 预览 GEDCOM 导入，不写入数据
+func (s *FamilyGedcomService) PreviewGedcomImport(userID, familyID string, data []byte, req *GedcomImportRequest) (*GedcomImportPlan, error) {
+	if err := s.familyService.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionEditGenealogy); err != nil {
+		return nil, err
+	}
+
+	plan, err := s.planImport(familyID, data, req)
+	if err != nil {
+		return nil, err
+	}
+	// 预览时不返回尚未写入的ID
+	for _, p := range plan.Persons {
+		if p.Action == GedcomDuplicateCreate {
+			p.GenealogyID = ""
+		}
+	}
+	return plan, nil
+}
+
+// 导入 GEDCOM 文件到家族谱系
+func (s *FamilyGedcomService) ImportGedcom(userID, familyID string, data []byte, req *GedcomImportRequest) (*GedcomImportPlan, error) {
+	if err := s.familyService.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionEditGenealogy); err != nil {
+		return nil, err
+	}
+
+	plan, err := s.planImport(familyID, data, req)
+	if err != nil {
+		return nil, err
+	}
+	// 与新增、修改谱系成员一致，一致性错误默认阻止写入
+	if plan.ErrorCount > 0 && !req.Force {
+		for _, issue := range plan.Issues {
+			if issue.Severity == utils.GenealogySeverityError {
+				return nil, fmt.Errorf("谱系数据不一致：%s等 %d 处错误。请修正文件后重新导入，或设置 force 仍然导入", issue.Message, plan.ErrorCount)
+			}
+		}
+	}
+
+	now := time.Now()
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		for _, p := range plan.Persons {
+			if p.Action != GedcomDuplicateCreate {
+				continue
+			}
+			genealogy := &models.FamilyGenealogy{
+				ID:           p.GenealogyID,
+				FamilyID:     familyID,
+				PersonName:   p.PersonName,
+				Generation:   p.Generation,
+				Gender:       p.Gender,
+				BirthDate:    p.BirthDate,
+				DeathDate:    p.DeathDate,
+				Biography:    p.person.Biography,
+				AvatarURL:    clipRunes(p.person.AvatarURL, 255),
+				MemorialID:   p.MemorialID,
+				Position:     clipRunes(p.person.Occupation, 100),
+				Achievements: p.person.Achievements,
+				CreatedAt:    now,
+				UpdatedAt:    now,
+			}
+			if err := tx.Create(genealogy).Error; err != nil {
+				return err
+			}
+		}
+		for _, r := range plan.relations {
+			r.FamilyID = familyID
+			r.CreatedAt = now
+			r.UpdatedAt = now
+			if err := tx.Create(r).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.familyService.touchGenealogy(familyID)
+
+	// 记录活动
+	s.familyService.recordActivity(familyID, userID, "", "import_genealogy", map[string]interface{}{
+		"version":      plan.Version,
+		"create_count": plan.CreateCount,
+		"merge_count":  plan.MergeCount,
+		"relations":    plan.RelationCount,
+		"error_count":  plan.ErrorCount,
+	})
+
+	return plan, nil
+}
+
+func (s *FamilyGedcomService) planImport(familyID string, data []byte, req *GedcomImportRequest) (*GedcomImportPlan, error) {
+	if len(data) > MaxGedcomFileSize {
+		return nil, errors.New("GEDCOM 文件不能超过 10MB")
+	}
+	doc, err := utils.DecodeGedcom(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(doc.Persons) > maxGedcomPersons {
+		return nil, fmt.Errorf("GEDCOM 文件最多包含 %d 人", maxGedcomPersons)
+	}
+
+	onDuplicate := req.OnDuplicate
+	if onDuplicate == "" {
+		onDuplicate = GedcomDuplicateMerge
+	}
+
+	// 与创建谱系成员一致：父辈、查重和纪念馆的范围为本家族圈及各级上级
+	lineageIDs, err := s.familyService.genealogyLineageIDs(familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	var existing []*models.FamilyGenealogy
+	if err := s.db.Select("id, person_name, gender, birth_date, generation").
+		Where("family_id IN ?", lineageIDs).
+		Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	existingByID := make(map[string]*models.FamilyGenealogy, len(existing))
+	existingIDs := make([]string, 0, len(existing))
+	keys := make([]utils.GenealogyPersonKey, 0, len(existing))
+	for _, g := range existing {
+		existingByID[g.ID] = g
+		existingIDs = append(existingIDs, g.ID)
+		keys = append(keys, genealogyPersonKey(g.ID, g.PersonName, g.Gender, g.BirthDate))
+	}
+
+	// 合并到已有成员时，已有的关系不重复建立
+	var existingRelations []*models.GenealogyRelation
+	if len(existingIDs) > 0 {
+		if err := s.db.Where("from_id IN ? AND to_id IN ?", existingIDs, existingIDs).
+			Find(&existingRelations).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var linkedMemorials []string
+	s.db.Model(&models.MemorialFamily{}).Where("family_id IN ?", lineageIDs).Pluck("memorial_id", &linkedMemorials)
+	memorialLinked := make(map[string]bool, len(linkedMemorials))
+	for _, id := range linkedMemorials {
+		memorialLinked[id] = true
+	}
+
+	links := utils.GedcomParentLinks(doc)
+	parentsOf := map[string][]string{}
+	for _, link := range links {
+		parentsOf[link.Child] = append(parentsOf[link.Child], link.Parent)
+	}
+	relative, groups := utils.GedcomGenerations(doc)
+
+	plan := &GedcomImportPlan{
+		Version:     doc.Version,
+		PersonCount: len(doc.Persons),
+		FamilyCount: len(doc.Families),
+		Persons:     make([]*GedcomImportPerson, 0, len(doc.Persons)),
+		Warnings:    doc.Warnings,
+	}
+	byXref := make(map[string]*GedcomImportPerson, len(doc.Persons))
+
+	for _, person := range doc.Persons {
+		p := &GedcomImportPerson{
+			Xref:        person.Xref,
+			PersonName:  clipRunes(person.Name, 100),
+			Gender:      person.Gender,
+			BirthDate:   person.BirthDate,
+			DeathDate:   person.DeathDate,
+			ParentXrefs: parentsOf[person.Xref],
+			Action:      GedcomDuplicateCreate,
+			Warnings:    append([]string{}, person.Warnings...),
+			person:      person,
+		}
+
+		if person.MemorialID != "" {
+			if memorialLinked[person.MemorialID] {
+				p.MemorialID = person.MemorialID
+			} else {
+				p.Warnings = append(p.Warnings, "纪念馆未关联到此家族圈，已忽略关联")
+			}
+		}
+
+		dupID, match := utils.FindGenealogyDuplicate(genealogyPersonKey("", person.Name, person.Gender, person.BirthDate), person.RefID, keys)
+		if dupID != "" {
+			dup := existingByID[dupID]
+			p.Duplicate = &GedcomDuplicate{
+				GenealogyID: dup.ID,
+				PersonName:  dup.PersonName,
+				Generation:  dup.Generation,
+				BirthDate:   dup.BirthDate,
+				Match:       match,
+			}
+			p.Action = onDuplicate
+		}
+		if decision, ok := req.Decisions[person.Xref]; ok {
+			if decision != GedcomDuplicateMerge && decision != GedcomDuplicateCreate {
+				return nil, fmt.Errorf("无效的处理方式: %s", decision)
+			}
+			if decision == GedcomDuplicateMerge && p.Duplicate == nil {
+				return nil, fmt.Errorf("%s 没有可合并的谱系成员", person.Xref)
+			}
+			p.Action = decision
+		}
+
+		if p.Action == GedcomDuplicateMerge {
+			p.GenealogyID = p.Duplicate.GenealogyID
+			plan.MergeCount++
+		} else {
+			p.GenealogyID = uuid.New().String()
+			plan.CreateCount++
+		}
+
+		plan.Persons = append(plan.Persons, p)
+		byXref[person.Xref] = p
+	}
+
+	// 辈分：同一群体中有合并到已有成员的人时以其辈分为准，否则最高一辈为第 1 代
+	offsets := map[string]int{}
+	for _, p := range plan.Persons {
+		group := groups[p.Xref]
+		if _, ok := offsets[group]; ok || p.Action != GedcomDuplicateMerge {
+			continue
+		}
+		offsets[group] = p.Duplicate.Generation - relative[p.Xref]
+	}
+	for _, p := range plan.Persons {
+		offset, ok := offsets[groups[p.Xref]]
+		if !ok {
+			offset = 1
+		}
+		p.Generation = relative[p.Xref] + offset
+		if p.Action == GedcomDuplicateMerge {
+			p.Generation = p.Duplicate.Generation
+		}
+	}
+
+	s.planRelations(plan, doc, links, byXref, existingRelations)
+	planAudit(plan)
+	return plan, nil
+}
+
+// planAudit 对导入的人员和关系做一致性检查，问题记入计划并作为提醒附在相关人员上；
+// 有 error 级问题时由 ImportGedcom 阻止导入
+func planAudit(plan *GedcomImportPlan) {
+	plan.Issues = []utils.GenealogyIssue{}
+	byID := make(map[string]*GedcomImportPerson, len(plan.Persons))
+	persons := make([]utils.GenealogyAuditPerson, 0, len(plan.Persons))
+	for _, p := range plan.Persons {
+		if byID[p.GenealogyID] != nil {
+			continue
+		}
+		byID[p.GenealogyID] = p
+		persons = append(persons, utils.GenealogyAuditPerson{
+			ID:         p.GenealogyID,
+			Name:       p.PersonName,
+			Gender:     p.Gender,
+			Generation: p.Generation,
+			BirthDate:  p.BirthDate,
+			DeathDate:  p.DeathDate,
+		})
+	}
+	_, edges := genealogyAuditInput(nil, plan.relations)
+	for _, issue := range utils.ValidateGenealogy(persons, edges) {
+		if issue.Code == utils.GenealogyIssueDuplicatePerson || issue.Code == utils.GenealogyIssueOrphanedRelation {
+			// 重复由导入查重处理；关系另一端为未导入的已有成员
+			continue
+		}
+		plan.Issues = append(plan.Issues, issue)
+		if issue.Severity == utils.GenealogySeverityError {
+			plan.ErrorCount++
+		}
+		for _, id := range issue.PersonIDs {
+			if p := byID[id]; p != nil {
+				p.Warnings = append(p.Warnings, issue.Message)
+			}
+		}
+	}
+}
+
+// planRelations 根据家庭记录规划要新建的父母子女和配偶关系，跳过已存在、成环或超出亲生父母上限的关系
+func (s *FamilyGedcomService) planRelations(plan *GedcomImportPlan, doc *utils.GedcomDocument, links []utils.GedcomParentLink,
+	byXref map[string]*GedcomImportPerson, existing []*models.GenealogyRelation) {
+	graph := genealogyGraphOf(existing)
+
+	for _, link := range links {
+		parent, child := byXref[link.Parent], byXref[link.Child]
+		if parent.GenealogyID == child.GenealogyID {
+			continue
+		}
+		exists := false
+		for _, e := range graph.Parents(child.GenealogyID) {
+			if e.FromID == parent.GenealogyID {
+				exists = true
+				break
+			}
+		}
+		if exists {
+			continue
+		}
+		if graph.IsAncestor(child.GenealogyID, parent.GenealogyID) {
+			child.Warnings = append(child.Warnings, fmt.Sprintf("与 %s 的父母关系会与已有谱系成环，已忽略", parent.PersonName))
+			continue
+		}
+		if link.ParentType == utils.GenealogyParentBiological &&
+			graph.BiologicalParentCount(child.GenealogyID) >= utils.GenealogyMaxBiologicalParents {
+			child.Warnings = append(child.Warnings, fmt.Sprintf("亲生父母超过两位，已忽略与 %s 的关系", parent.PersonName))
+			continue
+		}
+
+		edge := utils.GenealogyEdge{
+			ID:         uuid.New().String(),
+			Type:       utils.GenealogyRelationParent,
+			FromID:     parent.GenealogyID,
+			ToID:       child.GenealogyID,
+			ParentType: link.ParentType,
+		}
+		graph.Add(edge)
+		plan.relations = append(plan.relations, &models.GenealogyRelation{
+			ID:         edge.ID,
+			Type:       edge.Type,
+			FromID:     edge.FromID,
+			ToID:       edge.ToID,
+			ParentType: edge.ParentType,
+		})
+	}
+
+	for _, f := range doc.Families {
+		husband, wife := byXref[f.Husband], byXref[f.Wife]
+		if husband == nil || wife == nil || husband.GenealogyID == wife.GenealogyID {
+			continue
+		}
+		fromID, toID := husband.GenealogyID, wife.GenealogyID
+		if fromID > toID {
+			fromID, toID = toID, fromID
+		}
+		exists := false
+		for _, e := range graph.Spouses(fromID) {
+			if e.FromID == fromID && e.ToID == toID {
+				exists = true
+				break
+			}
+		}
+		if exists {
+			continue
+		}
+
+		edge := utils.GenealogyEdge{ID: uuid.New().String(), Type: utils.GenealogyRelationSpouse, FromID: fromID, ToID: toID}
+		graph.Add(edge)
+		relation := &models.GenealogyRelation{
+			ID:           edge.ID,
+			Type:         edge.Type,
+			FromID:       edge.FromID,
+			ToID:         edge.ToID,
+			MarriageDate: f.MarriageDate,
+			DivorceDate:  f.DivorceDate,
+		}
+		if relation.MarriageDate != nil && relation.DivorceDate != nil && relation.DivorceDate.Before(*relation.MarriageDate) {
+			relation.DivorceDate = nil
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("%s 的离婚日期早于结婚日期，已忽略", f.Xref))
+		}
+		plan.relations = append(plan.relations, relation)
+	}
+
+	plan.RelationCount = len(plan.relations)
+}
+
+// 导出家族谱系为 GEDCOM 文件，version 为 5.5.1（默认）或 7.0
+func (s *FamilyGedcomService) ExportGedcom(userID, familyID, version string) ([]byte, error) {
+	if err := s.familyService.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionView); err != nil {
+		return nil, err
+	}
+	if version == "" {
+		version = utils.GedcomVersion551
+	}
+	if version == "7" {
+		version = utils.GedcomVersion7
+	}
+	if version != utils.GedcomVersion551 && version != utils.GedcomVersion7 {
+		return nil, errors.New("不支持的 GEDCOM 版本")
+	}
+
+	// 与查看谱系的范围一致
+	genealogies, relations, err := s.familyService.loadGenealogy(familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	var family models.Family
+	s.db.Select("name").Where("id = ?", familyID).First(&family)
+	doc := &utils.GedcomDocument{Submitter: family.Name}
+	persons := make(map[string]*utils.GedcomPerson, len(genealogies))
+	for i, g := range genealogies {
+		person := &utils.GedcomPerson{
+			Xref:         fmt.Sprintf("@I%d@", i+1),
+			Name:         g.PersonName,
+			Gender:       g.Gender,
+			BirthDate:    g.BirthDate,
+			DeathDate:    g.DeathDate,
+			Biography:    g.Biography,
+			Achievements: g.Achievements,
+			Occupation:   g.Position,
+			AvatarURL:    g.AvatarURL,
+			MemorialID:   g.MemorialID,
+			RefID:        g.ID,
+		}
+		persons[g.ID] = person
+		doc.Persons = append(doc.Persons, person)
+	}
+
+	// 每对配偶、每对共同的父母（或单亲）对应一个家庭记录
+	families := map[[2]string]*utils.GedcomFamily{}
+	familyOf := func(a, b string) *utils.GedcomFamily {
+		if b != "" && b < a {
+			a, b = b, a
+		}
+		key := [2]string{a, b}
+		if family := families[key]; family != nil {
+			return family
+		}
+		family := &utils.GedcomFamily{Xref: fmt.Sprintf("@F%d@", len(doc.Families)+1)}
+		for _, id := range []string{a, b} {
+			parent := persons[id]
+			if parent == nil {
+				continue
+			}
+			// 按性别区分丈夫和妻子，同性或性别未知时依次填入
+			if (parent.Gender == "female" && family.Wife == "") || family.Husband != "" {
+				family.Wife = parent.Xref
+			} else {
+				family.Husband = parent.Xref
+			}
+			parent.SpouseFams = append(parent.SpouseFams, family.Xref)
+		}
+		families[key] = family
+		doc.Families = append(doc.Families, family)
+		return family
+	}
+
+	graph := genealogyGraphOf(relations)
+	for _, r := range relations {
+		if r.Type != utils.GenealogyRelationSpouse {
+			continue
+		}
+		family := familyOf(r.FromID, r.ToID)
+		family.MarriageDate = r.MarriageDate
+		family.DivorceDate = r.DivorceDate
+	}
+	for _, g := range genealogies {
+		child := persons[g.ID]
+		// 同一类型的两位父母视为一个家庭，其余按单亲家庭输出
+		byType := map[string][]string{}
+		var types []string
+		for _, e := range graph.Parents(g.ID) {
+			if _, ok := byType[e.ParentType]; !ok {
+				types = append(types, e.ParentType)
+			}
+			byType[e.ParentType] = append(byType[e.ParentType], e.FromID)
+		}
+		for _, parentType := range types {
+			parents := byType[parentType]
+			var fams []*utils.GedcomFamily
+			if len(parents) == 2 {
+				fams = append(fams, familyOf(parents[0], parents[1]))
+			} else {
+				for _, id := range parents {
+					fams = append(fams, familyOf(id, ""))
+				}
+			}
+			for _, family := range fams {
+				family.Children = append(family.Children, child.Xref)
+				child.ParentFams = append(child.ParentFams, family.Xref)
+				if parentType != utils.GenealogyParentBiological {
+					if child.Pedigrees == nil {
+						child.Pedigrees = map[string]string{}
+					}
+					child.Pedigrees[family.Xref] = parentType
+				}
+			}
+		}
+	}
+
+	return utils.EncodeGedcom(doc, version, gedcomSourceID), nil
+}
+
+func genealogyPersonKey(id, name, gender string, birthDate *time.Time) utils.GenealogyPersonKey {
+	key := utils.GenealogyPersonKey{ID: id, Name: name, Gender: gender}
+	if birthDate != nil {
+		key.BirthYear = birthDate.Year()
+	}
+	return key
+}
+
+// clipRunes 按字段长度截断，不加省略号
+func clipRunes(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max])
+}
@@ -0,0 +1,256 @@
+package services
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"yun-nian-memorial/internal/models"
+	"yun-nian-memorial/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// 缓存的谱系图数量上限，超出时淘汰最早缓存的
+	genealogyChartCacheSize = 128
+	// PNG 单边最大像素
+	genealogyChartMaxPixels = 16384
+	// 嵌入图中的头像文件大小上限
+	genealogyAvatarMaxSize = 512 << 10
+)
+
+// FamilyGenealogyRenderService 在服务端绘制谱系图（SVG/PNG），按谱系版本缓存
+type FamilyGenealogyRenderService struct {
+	db                *gorm.DB
+	familyService     *FamilyService
+	fileUploadManager *utils.FileUploadManager
+
+	mu    sync.Mutex
+	cache map[string]*genealogyChartCacheEntry
+	order []string
+}
+
+type genealogyChartCacheEntry struct {
+	stamp string
+	chart *GenealogyChart
+}
+
+func NewFamilyGenealogyRenderService(db *gorm.DB, uploadDir string) *FamilyGenealogyRenderService {
+	return &FamilyGenealogyRenderService{
+		db:                db,
+		fileUploadManager: utils.NewFileUploadManager(uploadDir, 0),
+		cache:             map[string]*genealogyChartCacheEntry{},
+	}
+}
+
+// SetFamilyService 设置家族圈服务依赖（权限校验、支系范围、谱系加载）
+func (s *FamilyGenealogyRenderService) SetFamilyService(familyService *FamilyService) {
+	s.familyService = familyService
+}
+
+// 谱系图请求
+type GenealogyChartRequest struct {
+	Format    string `form:"format" binding:"omitempty,oneof=svg png"`           // 默认 svg
+	Style     string `form:"style" binding:"omitempty,oneof=modern traditional"` // 默认 modern
+	RootID    string `form:"root_id"`                                            // 只绘制此人一支
+	Collapsed string `form:"collapsed"`                                          // 折叠后代的谱系成员ID，逗号分隔
+	Depth     int    `form:"depth" binding:"omitempty,min=1,max=100"`            // 最多绘制的代数
+}
+
+// GenealogyChart 绘制好的谱系图
+type GenealogyChart struct {
+	Data        []byte
+	ContentType string
+	ETag        string
+}
+
+// 获取谱系图：谱系未改动时直接返回缓存；etag 与客户端缓存一致时 Data 为空
+func (s *FamilyGenealogyRenderService) GetGenealogyChart(userID, familyID string, req *GenealogyChartRequest, etag string) (*GenealogyChart, error) {
+	if err := s.familyService.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionView); err != nil {
+		return nil, err
+	}
+	if req.Format == "" {
+		req.Format = "svg"
+	}
+	if req.Style == "" {
+		req.Style = utils.GenealogyStyleModern
+	}
+
+	var collapsed []string
+	for _, id := range strings.Split(req.Collapsed, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			collapsed = append(collapsed, id)
+		}
+	}
+	sort.Strings(collapsed)
+
+	stamp, err := s.genealogyStamp(familyID)
+	if err != nil {
+		return nil, err
+	}
+	key := strings.Join([]string{familyID, req.Format, req.Style, req.RootID, strings.Join(collapsed, ","), fmt.Sprint(req.Depth)}, "|")
+	sum := sha1.Sum([]byte(key + "|" + stamp))
+	tag := `"` + hex.EncodeToString(sum[:8]) + `"`
+	contentType := "image/svg+xml"
+	if req.Format == "png" {
+		contentType = "image/png"
+	}
+	if etag == tag {
+		return &GenealogyChart{ContentType: contentType, ETag: tag}, nil
+	}
+
+	s.mu.Lock()
+	entry := s.cache[key]
+	s.mu.Unlock()
+	if entry != nil && entry.stamp == stamp {
+		return entry.chart, nil
+	}
+
+	genealogies, relations, err := s.familyService.loadGenealogy(familyID)
+	if err != nil {
+		return nil, err
+	}
+	byID := genealogyPersonsByID(genealogies)
+	if req.RootID != "" && byID[req.RootID] == nil {
+		return nil, errors.New("谱系成员不存在")
+	}
+
+	persons := make([]utils.GenealogyLayoutPerson, 0, len(genealogies))
+	for _, g := range genealogies {
+		avatar := g.AvatarURL
+		if avatar == "" && g.Memorial != nil {
+			avatar = g.Memorial.AvatarURL
+		}
+		persons = append(persons, utils.GenealogyLayoutPerson{
+			ID:         g.ID,
+			Name:       g.PersonName,
+			Gender:     g.Gender,
+			Generation: g.Generation,
+			BirthDate:  g.BirthDate,
+			DeathDate:  g.DeathDate,
+			AvatarURL:  s.embedAvatar(avatar, req.Format == "png"),
+		})
+	}
+
+	opts := utils.NewGenealogyLayoutOptions(req.Style)
+	opts.RootID = req.RootID
+	opts.MaxDepth = req.Depth
+	opts.Collapsed = map[string]bool{}
+	for _, id := range collapsed {
+		opts.Collapsed[id] = true
+	}
+	layout := utils.LayoutGenealogy(persons, genealogyGraphOf(relations), opts)
+
+	var family models.Family
+	s.db.Select("name").Where("id = ?", familyID).First(&family)
+	title := family.Name + " 家族谱系"
+	if req.Style == utils.GenealogyStyleTraditional {
+		title = family.Name + " 世系图"
+	}
+	data := utils.RenderGenealogySVG(layout, utils.GenealogySVGOptions{Style: req.Style, Title: title})
+
+	if req.Format == "png" {
+		// 尽量按两倍分辨率输出，便于打印
+		zoom := 2.0
+		width, height := utils.GenealogySVGSize(layout, req.Style)
+		longest := width
+		if height > longest {
+			longest = height
+		}
+		if longest*zoom > genealogyChartMaxPixels {
+			zoom = 1
+		}
+		if longest*zoom > genealogyChartMaxPixels {
+			return nil, errors.New("谱系图过大，请折叠部分支系或导出 SVG")
+		}
+		data, err = utils.RasterizeSVG(data, zoom)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	chart := &GenealogyChart{Data: data, ContentType: contentType, ETag: tag}
+	s.mu.Lock()
+	if _, ok := s.cache[key]; !ok {
+		s.order = append(s.order, key)
+		if len(s.order) > genealogyChartCacheSize {
+			delete(s.cache, s.order[0])
+			s.order = s.order[1:]
+		}
+	}
+	s.cache[key] = &genealogyChartCacheEntry{stamp: stamp, chart: chart}
+	s.mu.Unlock()
+	return chart, nil
+}
+
+// genealogyStamp 谱系图所用数据的版本：范围内各家族圈的谱系版本和名称更新时间，以及关联纪念馆的更新时间
+// （头像会回退到纪念馆头像），支系范围变化时也会改变
+func (s *FamilyGenealogyRenderService) genealogyStamp(familyID string) (string, error) {
+	familyIDs, err := s.familyService.familyScopeIDs(familyID, true)
+	if err != nil {
+		return "", err
+	}
+	var families []models.Family
+	err = s.db.Select("id, genealogy_version, updated_at").
+		Where("id IN ?", familyIDs).
+		Order("id ASC").
+		Find(&families).Error
+	if err != nil {
+		return "", err
+	}
+	parts := make([]string, 0, len(families))
+	for _, f := range families {
+		parts = append(parts, fmt.Sprintf("%s:%d:%d", f.ID, f.GenealogyVersion, f.UpdatedAt.UnixNano()))
+	}
+
+	var memorials []models.Memorial
+	err = s.db.Select("id, updated_at").
+		Where("id IN (?)", s.db.Model(&models.FamilyGenealogy{}).
+			Select("memorial_id").
+			Where("family_id IN ? AND memorial_id <> ''", familyIDs)).
+		Order("id ASC").
+		Find(&memorials).Error
+	if err != nil {
+		return "", err
+	}
+	for _, m := range memorials {
+		parts = append(parts, fmt.Sprintf("m%s:%d", m.ID, m.UpdatedAt.UnixNano()))
+	}
+	return strings.Join(parts, ";"), nil
+}
+
+// embedAvatar 将本站上传的头像嵌入为 data URI，使导出的图片脱离站点也能显示；
+// 转为 PNG 时无法加载外部图片，改为显示姓氏
+func (s *FamilyGenealogyRenderService) embedAvatar(url string, offline bool) string {
+	if url == "" || strings.HasPrefix(url, "data:") {
+		return url
+	}
+	relativePath, err := s.fileUploadManager.GetRelativePath(url)
+	if err != nil {
+		if offline {
+			return ""
+		}
+		return url
+	}
+	path := s.fileUploadManager.GetLocalPath(relativePath)
+	info, err := os.Stat(path)
+	if err != nil || info.Size() > genealogyAvatarMaxSize {
+		return ""
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	contentType := http.DetectContentType(data)
+	if !strings.HasPrefix(contentType, "image/") {
+		return ""
+	}
+	return "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(data)
+}
@@ -0,0 +1,522 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"yun-nian-memorial/internal/models"
+	"yun-nian-memorial/internal/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 邀请链接状态（由撤销时间、过期时间和使用次数推算）
+const (
+	InviteLinkActive    = "active"
+	InviteLinkExpired   = "expired"
+	InviteLinkRevoked   = "revoked"
+	InviteLinkExhausted = "exhausted"
+)
+
+// 邀请链接使用状态
+const (
+	InviteLinkUsePending  = "pending"
+	InviteLinkUseJoined   = "joined"
+	InviteLinkUseRejected = "rejected"
+)
+
+const (
+	inviteLinkTokenLength       = 24
+	defaultInviteLinkExpiryHour = 7 * 24
+)
+
+// FamilyInviteLinkService 家族圈邀请链接：有效期、使用次数、预设角色、加入审核和二维码
+type FamilyInviteLinkService struct {
+	db            *gorm.DB
+	familyService *FamilyService
+	baseURL       string
+}
+
+func NewFamilyInviteLinkService(db *gorm.DB, baseURL string) *FamilyInviteLinkService {
+	return &FamilyInviteLinkService{
+		db:      db,
+		baseURL: strings.TrimRight(baseURL, "/"),
+	}
+}
+
+// SetFamilyService 设置家族圈服务依赖（成员权限校验、家族动态）
+func (s *FamilyInviteLinkService) SetFamilyService(familyService *FamilyService) {
+	s.familyService = familyService
+}
+
+// 创建邀请链接请求
+type CreateInviteLinkRequest struct {
+	Role            string `json:"role" binding:"omitempty,oneof=admin editor member guest"` // 加入后的角色，默认 member
+	MaxUses         int    `json:"max_uses" binding:"min=0,max=1000"`                        // 最大使用次数，0表示不限
+	ExpiresInHours  int    `json:"expires_in_hours" binding:"omitempty,min=1,max=720"`
+	RequireApproval bool   `json:"require_approval"` // 是否需要管理员审核
+	Note            string `json:"note" binding:"max=100"`
+}
+
+// 审核加入申请请求
+type ReviewJoinRequestRequest struct {
+	Approve bool `json:"approve"`
+}
+
+// InviteLinkInfo 邀请链接及其当前状态、链接地址
+type InviteLinkInfo struct {
+	*models.FamilyInviteLink
+	Status string `json:"status"`
+	URL    string `json:"url"`
+}
+
+// InviteLinkPreview 打开邀请链接时展示的家族圈信息
+type InviteLinkPreview struct {
+	FamilyID        string    `json:"family_id"`
+	FamilyName      string    `json:"family_name"`
+	Description     string    `json:"description"`
+	MemberCount     int64     `json:"member_count"`
+	InviterNickname string    `json:"inviter_nickname"`
+	Role            string    `json:"role"`
+	RequireApproval bool      `json:"require_approval"`
+	ExpiresAt       time.Time `json:"expires_at"`
+	Status          string    `json:"status"`
+	IsMember        bool      `json:"is_member"`
+}
+
+// JoinByInviteLinkResult 使用邀请链接的结果
+type JoinByInviteLinkResult struct {
+	FamilyID string `json:"family_id"`
+	Status   string `json:"status"` // joined已加入 | pending待审核
+	Role     string `json:"role"`
+}
+
+// CreateInviteLink 创建邀请链接，链接预设的角色须低于创建人自己的角色
+func (s *FamilyInviteLinkService) CreateInviteLink(userID, familyID string, req *CreateInviteLinkRequest) (*InviteLinkInfo, error) {
+	if err := s.familyService.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionInvite); err != nil {
+		return nil, err
+	}
+
+	role := req.Role
+	if role == "" {
+		role = utils.FamilyRoleMember
+	}
+	myRole, err := s.familyService.permissions.GetFamilyRole(userID, familyID)
+	if err != nil {
+		return nil, err
+	}
+	if utils.FamilyRoleRank(role) >= utils.FamilyRoleRank(myRole) {
+		return nil, errors.New("不能创建与自己同级或更高角色的邀请链接")
+	}
+
+	expiresIn := req.ExpiresInHours
+	if expiresIn == 0 {
+		expiresIn = defaultInviteLinkExpiryHour
+	}
+
+	token, err := utils.GenerateSecureRandomString(inviteLinkTokenLength)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	link := &models.FamilyInviteLink{
+		ID:              uuid.New().String(),
+		FamilyID:        familyID,
+		CreatorID:       userID,
+		Token:           token,
+		Role:            role,
+		MaxUses:         req.MaxUses,
+		RequireApproval: req.RequireApproval,
+		Note:            req.Note,
+		ExpiresAt:       now.Add(time.Duration(expiresIn) * time.Hour),
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	if err := s.db.Create(link).Error; err != nil {
+		return nil, err
+	}
+
+	s.familyService.recordActivity(familyID, userID, "", "create_invite_link", map[string]interface{}{
+		"link_id":          link.ID,
+		"role":             link.Role,
+		"max_uses":         link.MaxUses,
+		"require_approval": link.RequireApproval,
+		"expires_at":       link.ExpiresAt,
+	})
+
+	return s.linkInfo(link, now), nil
+}
+
+// GetInviteLinks 获取家族圈的邀请链接列表
+func (s *FamilyInviteLinkService) GetInviteLinks(userID, familyID string, page, pageSize int) ([]*InviteLinkInfo, int64, error) {
+	if err := s.familyService.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionInvite); err != nil {
+		return nil, 0, err
+	}
+
+	var links []*models.FamilyInviteLink
+	var total int64
+
+	offset := (page - 1) * pageSize
+
+	s.db.Model(&models.FamilyInviteLink{}).Where("family_id = ?", familyID).Count(&total)
+
+	err := s.db.Preload("Creator").
+		Where("family_id = ?", familyID).
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&links).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	now := time.Now()
+	infos := make([]*InviteLinkInfo, 0, len(links))
+	for _, link := range links {
+		infos = append(infos, s.linkInfo(link, now))
+	}
+	return infos, total, nil
+}
+
+// RevokeInviteLink 撤销邀请链接，已加入的成员不受影响，待审核的申请仍可审核
+func (s *FamilyInviteLinkService) RevokeInviteLink(userID, familyID, linkID string) error {
+	link, err := s.loadLink(userID, familyID, linkID)
+	if err != nil {
+		return err
+	}
+	if link.RevokedAt != nil {
+		return errors.New("邀请链接已撤销")
+	}
+
+	now := time.Now()
+	err = s.db.Model(link).Updates(map[string]interface{}{
+		"revoked_at": now,
+		"revoked_by": userID,
+		"updated_at": now,
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	s.familyService.recordActivity(familyID, userID, "", "revoke_invite_link", map[string]interface{}{
+		"link_id": link.ID,
+	})
+	return nil
+}
+
+// GetInviteLinkUses 获取通过某个邀请链接加入或申请加入的用户
+func (s *FamilyInviteLinkService) GetInviteLinkUses(userID, familyID, linkID string, page, pageSize int) ([]*models.FamilyInviteLinkUse, int64, error) {
+	link, err := s.loadLink(userID, familyID, linkID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var uses []*models.FamilyInviteLinkUse
+	var total int64
+
+	offset := (page - 1) * pageSize
+
+	s.db.Model(&models.FamilyInviteLinkUse{}).Where("link_id = ?", link.ID).Count(&total)
+
+	err = s.db.Preload("User").
+		Where("link_id = ?", link.ID).
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&uses).Error
+
+	return uses, total, err
+}
+
+// GetInviteLinkQRCode 生成邀请链接二维码，format 为 png 或 svg
+func (s *FamilyInviteLinkService) GetInviteLinkQRCode(userID, familyID, linkID, format string, scale int) ([]byte, string, error) {
+	link, err := s.loadLink(userID, familyID, linkID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	qr, err := utils.EncodeQRCode(s.linkURL(link.Token))
+	if err != nil {
+		return nil, "", err
+	}
+
+	if format == "svg" {
+		return []byte(qr.SVG()), "image/svg+xml", nil
+	}
+	data, err := qr.PNG(scale)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, "image/png", nil
+}
+
+// GetJoinRequests 获取待审核的加入申请
+func (s *FamilyInviteLinkService) GetJoinRequests(userID, familyID string, page, pageSize int) ([]*models.FamilyInviteLinkUse, int64, error) {
+	if err := s.familyService.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionInvite); err != nil {
+		return nil, 0, err
+	}
+
+	var uses []*models.FamilyInviteLinkUse
+	var total int64
+
+	offset := (page - 1) * pageSize
+
+	s.db.Model(&models.FamilyInviteLinkUse{}).
+		Where("family_id = ? AND status = ?", familyID, InviteLinkUsePending).
+		Count(&total)
+
+	err := s.db.Preload("User").Preload("Link").
+		Where("family_id = ? AND status = ?", familyID, InviteLinkUsePending).
+		Order("created_at ASC").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&uses).Error
+
+	return uses, total, err
+}
+
+// ReviewJoinRequest 审核加入申请，拒绝后释放链接的使用次数
+func (s *FamilyInviteLinkService) ReviewJoinRequest(userID, familyID, requestID string, approve bool) error {
+	if err := s.familyService.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionInvite); err != nil {
+		return err
+	}
+
+	var use models.FamilyInviteLinkUse
+	err := s.db.First(&use, "id = ? AND family_id = ?", requestID, familyID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("加入申请不存在")
+		}
+		return err
+	}
+	if use.Status != InviteLinkUsePending {
+		return errors.New("加入申请已处理")
+	}
+
+	now := time.Now()
+	status := InviteLinkUseRejected
+	if approve {
+		status = InviteLinkUseJoined
+	}
+
+	joined := false
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.FamilyInviteLinkUse{}).
+			Where("id = ? AND status = ?", use.ID, InviteLinkUsePending).
+			Updates(map[string]interface{}{
+				"status":      status,
+				"reviewed_by": userID,
+				"reviewed_at": now,
+				"updated_at":  now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("加入申请已处理")
+		}
+
+		if !approve {
+			return tx.Model(&models.FamilyInviteLink{}).
+				Where("id = ? AND use_count > 0", use.LinkID).
+				Update("use_count", gorm.Expr("use_count - 1")).Error
+		}
+
+		var count int64
+		tx.Model(&models.FamilyMember{}).Where("family_id = ? AND user_id = ?", familyID, use.UserID).Count(&count)
+		if count > 0 {
+			return nil
+		}
+		joined = true
+		return tx.Create(&models.FamilyMember{
+			ID:       uuid.New().String(),
+			FamilyID: familyID,
+			UserID:   use.UserID,
+			Role:     use.Role,
+			JoinedAt: now,
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	if joined {
+		s.familyService.recordActivity(familyID, use.UserID, "", "join", map[string]interface{}{
+			"method":      "invite_link",
+			"link_id":     use.LinkID,
+			"approved_by": userID,
+		})
+	}
+	return nil
+}
+
+// PreviewInviteLink 打开邀请链接时查看家族圈信息
+func (s *FamilyInviteLinkService) PreviewInviteLink(userID, token string) (*InviteLinkPreview, error) {
+	var link models.FamilyInviteLink
+	err := s.db.Preload("Family").Preload("Creator").First(&link, "token = ?", token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("邀请链接无效")
+		}
+		return nil, err
+	}
+
+	preview := &InviteLinkPreview{
+		FamilyID:        link.FamilyID,
+		FamilyName:      link.Family.Name,
+		Description:     link.Family.Description,
+		InviterNickname: link.Creator.Nickname,
+		Role:            link.Role,
+		RequireApproval: link.RequireApproval,
+		ExpiresAt:       link.ExpiresAt,
+		Status:          inviteLinkStatus(&link, time.Now()),
+		IsMember:        s.familyService.isFamilyMember(userID, link.FamilyID),
+	}
+	s.db.Model(&models.FamilyMember{}).Where("family_id = ?", link.FamilyID).Count(&preview.MemberCount)
+
+	return preview, nil
+}
+
+// JoinByInviteLink 通过邀请链接加入家族圈，需要审核的链接先提交加入申请
+func (s *FamilyInviteLinkService) JoinByInviteLink(userID, token string) (*JoinByInviteLinkResult, error) {
+	var link models.FamilyInviteLink
+	err := s.db.First(&link, "token = ?", token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("邀请链接无效")
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := inviteLinkUsable(&link, now); err != nil {
+		return nil, err
+	}
+
+	if s.familyService.isFamilyMember(userID, link.FamilyID) {
+		return nil, errors.New("您已经是此家族圈的成员")
+	}
+
+	var pending int64
+	s.db.Model(&models.FamilyInviteLinkUse{}).
+		Where("family_id = ? AND user_id = ? AND status = ?", link.FamilyID, userID, InviteLinkUsePending).
+		Count(&pending)
+	if pending > 0 {
+		return nil, errors.New("您的加入申请正在审核中")
+	}
+
+	result := &JoinByInviteLinkResult{
+		FamilyID: link.FamilyID,
+		Status:   InviteLinkUseJoined,
+		Role:     link.Role,
+	}
+	if link.RequireApproval {
+		result.Status = InviteLinkUsePending
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// 以条件更新占用一次使用次数，并发使用时不会超出上限
+		claim := tx.Model(&models.FamilyInviteLink{}).
+			Where("id = ? AND revoked_at IS NULL AND expires_at > ? AND (max_uses = 0 OR use_count < max_uses)", link.ID, now).
+			Update("use_count", gorm.Expr("use_count + 1"))
+		if claim.Error != nil {
+			return claim.Error
+		}
+		if claim.RowsAffected == 0 {
+			return errors.New("邀请链接使用次数已满")
+		}
+
+		use := &models.FamilyInviteLinkUse{
+			ID:        uuid.New().String(),
+			LinkID:    link.ID,
+			FamilyID:  link.FamilyID,
+			UserID:    userID,
+			Role:      link.Role,
+			Status:    result.Status,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		if err := tx.Create(use).Error; err != nil {
+			return err
+		}
+		if result.Status == InviteLinkUsePending {
+			return nil
+		}
+
+		return tx.Create(&models.FamilyMember{
+			ID:       uuid.New().String(),
+			FamilyID: link.FamilyID,
+			UserID:   userID,
+			Role:     link.Role,
+			JoinedAt: now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Status == InviteLinkUseJoined {
+		s.familyService.recordActivity(link.FamilyID, userID, "", "join", map[string]interface{}{
+			"method":  "invite_link",
+			"link_id": link.ID,
+		})
+	}
+	return result, nil
+}
+
+// loadLink 加载家族圈的邀请链接并校验管理员身份
+func (s *FamilyInviteLinkService) loadLink(userID, familyID, linkID string) (*models.FamilyInviteLink, error) {
+	if err := s.familyService.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionInvite); err != nil {
+		return nil, err
+	}
+
+	var link models.FamilyInviteLink
+	err := s.db.First(&link, "id = ? AND family_id = ?", linkID, familyID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("邀请链接不存在")
+		}
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (s *FamilyInviteLinkService) linkInfo(link *models.FamilyInviteLink, now time.Time) *InviteLinkInfo {
+	return &InviteLinkInfo{
+		FamilyInviteLink: link,
+		Status:           inviteLinkStatus(link, now),
+		URL:              s.linkURL(link.Token),
+	}
+}
+
+func (s *FamilyInviteLinkService) linkURL(token string) string {
+	return s.baseURL + "/" + token
+}
+
+// inviteLinkStatus 按撤销、过期、次数用尽的优先级推算链接状态
+func inviteLinkStatus(link *models.FamilyInviteLink, now time.Time) string {
+	switch {
+	case link.RevokedAt != nil:
+		return InviteLinkRevoked
+	case !now.Before(link.ExpiresAt):
+		return InviteLinkExpired
+	case link.MaxUses > 0 && link.UseCount >= link.MaxUses:
+		return InviteLinkExhausted
+	}
+	return InviteLinkActive
+}
+
+// inviteLinkUsable 链接不可用时返回对应的提示
+func inviteLinkUsable(link *models.FamilyInviteLink, now time.Time) error {
+	switch inviteLinkStatus(link, now) {
+	case InviteLinkRevoked:
+		return errors.New("邀请链接已失效")
+	case InviteLinkExpired:
+		return errors.New("邀请链接已过期")
+	case InviteLinkExhausted:
+		return errors.New("邀请链接使用次数已满")
+	}
+	return nil
+}
@@ -5,23 +5,54 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"sort"
+	"strings"
 	"time"
 	"yun-nian-memorial/internal/models"
+	"yun-nian-memorial/internal/utils"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 type FamilyService struct {
-	db *gorm.DB
+	db                *gorm.DB
+	permissions       *utils.PermissionManager
+	contentFilter     *ContentFilterService
+	notifications     *NotificationService
+	ownerInactiveDays int
 }
 
 func NewFamilyService(db *gorm.DB) *FamilyService {
 	return &FamilyService{
-		db: db,
+		db:          db,
+		permissions: utils.NewPermissionManager(db),
 	}
 }
 
+// SetContentFilter 设置敏感词过滤服务依赖
+func (s *FamilyService) SetContentFilter(contentFilter *ContentFilterService) {
+	s.contentFilter = contentFilter
+}
+
+// SetNotificationService 设置通知服务依赖（邀请、传统提醒）
+func (s *FamilyService) SetNotificationService(notifications *NotificationService) {
+	s.notifications = notifications
+}
+
+// SetOwnerInactiveDays 设置所有者自动移交的不活跃天数，0 表示不按活跃度移交
+func (s *FamilyService) SetOwnerInactiveDays(days int) {
+	s.ownerInactiveDays = days
+}
+
+// 所有权移交原因
+const (
+	OwnershipTransferManual        = "manual"         // 所有者主动转让
+	OwnershipTransferOwnerLeft     = "owner_left"     // 所有者离开家族圈
+	OwnershipTransferOwnerDeleted  = "owner_deleted"  // 所有者账号已注销或被禁用
+	OwnershipTransferOwnerInactive = "owner_inactive" // 所有者长期未登录
+)
+
 // 创建家族圈请求
 type CreateFamilyRequest struct {
 	Name        string `json:"name" binding:"required"`
@@ -32,6 +63,25 @@ type CreateFamilyRequest struct {
 type UpdateFamilyRequest struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
+	Visibility  string `json:"visibility" binding:"omitempty,oneof=clan private"` // 支系对上级家族圈的可见性
+}
+
+// 创建支系请求
+type CreateFamilyBranchRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	Visibility  string `json:"visibility" binding:"omitempty,oneof=clan private"` // 默认 clan
+}
+
+// FamilyBranchNode 支系树中的一个节点
+type FamilyBranchNode struct {
+	ID          string              `json:"id"`
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	Visibility  string              `json:"visibility"`
+	MemberCount int64               `json:"member_count"`
+	MyRole      string              `json:"my_role"` // 当前用户在该支系的有效角色，无法查看时为空
+	Branches    []*FamilyBranchNode `json:"branches"`
 }
 
 // 邀请成员请求
@@ -42,15 +92,117 @@ type InviteFamilyMemberRequest struct {
 
 // 创建家族圈
 func (s *FamilyService) CreateFamily(userID string, req *CreateFamilyRequest) (*models.Family, error) {
+	return s.createFamily(userID, req.Name, req.Description, nil, utils.FamilyVisibilityClan)
+}
+
+// 创建支系，创建人成为支系的所有者
+func (s *FamilyService) CreateFamilyBranch(userID, parentID string, req *CreateFamilyBranchRequest) (*models.Family, error) {
+	if err := s.permissions.CheckFamilyPermission(userID, parentID, utils.FamilyActionManageBranches); err != nil {
+		return nil, err
+	}
+
+	ancestors, err := s.permissions.GetFamilyAncestorIDs(parentID)
+	if err != nil {
+		return nil, err
+	}
+	if len(ancestors)+1 >= utils.FamilyBranchMaxDepth {
+		return nil, fmt.Errorf("支系最多 %d 层", utils.FamilyBranchMaxDepth-1)
+	}
+
+	visibility := req.Visibility
+	if visibility == "" {
+		visibility = utils.FamilyVisibilityClan
+	}
+
+	branch, err := s.createFamily(userID, req.Name, req.Description, &parentID, visibility)
+	if err != nil {
+		return nil, err
+	}
+
+	// 记录活动
+	s.recordActivity(parentID, userID, "", "create_branch", map[string]interface{}{
+		"branch_id":   branch.ID,
+		"branch_name": branch.Name,
+	})
+
+	return branch, nil
+}
+
+// 获取家族圈的支系树
+func (s *FamilyService) GetFamilyBranches(userID, familyID string) ([]*FamilyBranchNode, error) {
+	if err := s.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionView); err != nil {
+		return nil, err
+	}
+	return s.loadBranchNodes(userID, familyID, 1)
+}
+
+func (s *FamilyService) loadBranchNodes(userID, parentID string, depth int) ([]*FamilyBranchNode, error) {
+	nodes := []*FamilyBranchNode{}
+	if depth > utils.FamilyBranchMaxDepth {
+		return nodes, nil
+	}
+
+	var branches []*models.Family
+	if err := s.db.Where("parent_id = ?", parentID).Order("created_at ASC").Find(&branches).Error; err != nil {
+		return nil, err
+	}
+
+	for _, branch := range branches {
+		node := &FamilyBranchNode{
+			ID:         branch.ID,
+			Name:       branch.Name,
+			Visibility: branch.Visibility,
+			Branches:   []*FamilyBranchNode{},
+		}
+		// 私密支系只对有权查看的用户展示简介和成员数
+		if role, err := s.permissions.GetFamilyRole(userID, branch.ID); err == nil {
+			node.MyRole = role
+			node.Description = branch.Description
+			s.db.Model(&models.FamilyMember{}).Where("family_id = ?", branch.ID).Count(&node.MemberCount)
+		}
+
+		children, err := s.loadBranchNodes(userID, branch.ID, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		node.Branches = children
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// familyScopeIDs 家族圈内容的汇总范围：自身及对上级可见的各级支系，withAncestors 为 true 时再加上各级上级
+func (s *FamilyService) familyScopeIDs(familyID string, withAncestors bool) ([]string, error) {
+	scope := []string{familyID}
+
+	branches, err := s.permissions.GetFamilyBranchIDs(familyID, true)
+	if err != nil {
+		return nil, err
+	}
+	scope = append(scope, branches...)
+
+	if withAncestors {
+		ancestors, err := s.permissions.GetFamilyAncestorIDs(familyID)
+		if err != nil {
+			return nil, err
+		}
+		scope = append(scope, ancestors...)
+	}
+	return scope, nil
+}
+
+func (s *FamilyService) createFamily(userID, name, description string, parentID *string, visibility string) (*models.Family, error) {
 	// 生成邀请码
 	inviteCode := s.generateInviteCode()
 
 	family := &models.Family{
 		ID:          uuid.New().String(),
-		Name:        req.Name,
+		Name:        name,
 		CreatorID:   userID,
-		Description: req.Description,
+		Description: description,
 		InviteCode:  inviteCode,
+		ParentID:    parentID,
+		Visibility:  visibility,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
@@ -62,12 +214,12 @@ func (s *FamilyService) CreateFamily(userID string, req *CreateFamilyRequest) (*
 		return nil, err
 	}
 
-	// 添加创建者为管理员
+	// 添加创建者为所有者
 	member := &models.FamilyMember{
 		ID:       uuid.New().String(),
 		FamilyID: family.ID,
 		UserID:   userID,
-		Role:     "admin",
+		Role:     utils.FamilyRoleOwner,
 		JoinedAt: time.Now(),
 	}
 
@@ -130,8 +282,8 @@ func (s *FamilyService) GetFamily(userID, familyID string) (*models.Family, erro
 	}
 
 	// 验证访问权限
-	if !s.isFamilyMember(userID, familyID) {
-		return nil, errors.New("您不是此家族圈的成员")
+	if err := s.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionView); err != nil {
+		return nil, err
 	}
 
 	return &family, nil
@@ -148,9 +300,9 @@ func (s *FamilyService) UpdateFamily(userID, familyID string, req *UpdateFamilyR
 		return err
 	}
 
-	// 验证权限（只有管理员可以修改）
-	if !s.isFamilyAdmin(userID, familyID) {
-		return errors.New("只有管理员可以修改家族圈")
+	// 验证权限
+	if err := s.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionEditFamily); err != nil {
+		return err
 	}
 
 	// 更新字段
@@ -161,6 +313,13 @@ func (s *FamilyService) UpdateFamily(userID, familyID string, req *UpdateFamilyR
 	if req.Description != "" {
 		updates["description"] = req.Description
 	}
+	if req.Visibility != "" {
+		// 可见性由支系自己的成员决定，上级继承来的管理员不能修改
+		if !s.isFamilyMember(userID, familyID) {
+			return errors.New(utils.FamilyPermissionDeniedMessage(utils.FamilyActionEditFamily))
+		}
+		updates["visibility"] = req.Visibility
+	}
 	updates["updated_at"] = time.Now()
 
 	return s.db.Model(&family).Updates(updates).Error
@@ -177,9 +336,16 @@ func (s *FamilyService) DeleteFamily(userID, familyID string) error {
 		return err
 	}
 
-	// 验证权限（只有创建者可以删除）
-	if family.CreatorID != userID {
-		return errors.New("只有创建者可以删除家族圈")
+	// 验证权限（只有所有者可以删除）
+	if err := s.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionDeleteFamily); err != nil {
+		return err
+	}
+
+	// 有下级支系时不能删除
+	var branchCount int64
+	s.db.Model(&models.Family{}).Where("parent_id = ?", familyID).Count(&branchCount)
+	if branchCount > 0 {
+		return errors.New("请先删除下级支系")
 	}
 
 	// 删除家族圈及其相关数据
@@ -220,13 +386,14 @@ func (s *FamilyService) DeleteFamily(userID, familyID string) error {
 
 // 邀请成员
 func (s *FamilyService) InviteMembers(userID, familyID string, req *InviteFamilyMemberRequest) error {
-	// 验证权限（管理员可以邀请）
-	if !s.isFamilyAdmin(userID, familyID) {
-		return errors.New("只有管理员可以邀请成员")
+	// 验证权限
+	if err := s.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionInvite); err != nil {
+		return err
 	}
 
 	tx := s.db.Begin()
 
+	var invitedIDs []string
 	for _, inviteeID := range req.UserIDs {
 		// 检查是否已经是成员
 		if s.isFamilyMember(inviteeID, familyID) {
@@ -260,9 +427,28 @@ func (s *FamilyService) InviteMembers(userID, familyID string, req *InviteFamily
 			tx.Rollback()
 			return err
 		}
+		invitedIDs = append(invitedIDs, inviteeID)
 	}
 
-	return tx.Commit().Error
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	var family models.Family
+	var inviter models.User
+	s.db.Select("id", "name").First(&family, "id = ?", familyID)
+	s.db.Select("id", "nickname").First(&inviter, "id = ?", userID)
+	err := s.notifications.Notify(invitedIDs, &NotificationInput{
+		Category: NotificationCategoryInvitation,
+		Type:     NotificationTypeFamilyInvitation,
+		Title:    "家族圈邀请",
+		Content:  fmt.Sprintf("%s邀请您加入家族圈“%s”", inviter.Nickname, family.Name),
+		Data:     map[string]interface{}{"family_id": familyID, "inviter_id": userID},
+	})
+	if err != nil {
+		fmt.Printf("发送家族圈邀请通知失败: %v\n", err)
+	}
+	return nil
 }
 
 // 通过邀请码加入家族圈
@@ -302,6 +488,27 @@ func (s *FamilyService) JoinFamilyByCode(userID, inviteCode string) error {
 	return nil
 }
 
+// 重置邀请码，旧邀请码立即失效
+func (s *FamilyService) RotateInviteCode(userID, familyID string) (string, error) {
+	if err := s.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionInvite); err != nil {
+		return "", err
+	}
+
+	inviteCode := s.generateInviteCode()
+	err := s.db.Model(&models.Family{}).Where("id = ?", familyID).Updates(map[string]interface{}{
+		"invite_code": inviteCode,
+		"updated_at":  time.Now(),
+	}).Error
+	if err != nil {
+		return "", err
+	}
+
+	// 记录活动
+	s.recordActivity(familyID, userID, "", "rotate_invite_code", nil)
+
+	return inviteCode, nil
+}
+
 // 响应邀请
 func (s *FamilyService) RespondToInvitation(userID, invitationID string, accept bool) error {
 	var invitation models.FamilyInvitation
@@ -368,22 +575,28 @@ func (s *FamilyService) RespondToInvitation(userID, invitationID string, accept
 
 // 移除成员
 func (s *FamilyService) RemoveMember(userID, familyID, memberID string) error {
-	// 验证权限（管理员可以移除成员）
-	if !s.isFamilyAdmin(userID, familyID) {
-		return errors.New("只有管理员可以移除成员")
+	// 验证权限
+	if err := s.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionManageMembers); err != nil {
+		return err
 	}
 
-	// 不能移除创建者
-	var family models.Family
-	s.db.First(&family, "id = ?", familyID)
-	if family.CreatorID == memberID {
-		return errors.New("不能移除家族圈创建者")
+	// 只能移除角色低于自己的成员，所有者不能被移除
+	role, _ := s.permissions.GetFamilyRole(userID, familyID)
+	memberRole, err := s.permissions.GetFamilyRole(memberID, familyID)
+	if err != nil {
+		return errors.New("成员不存在")
+	}
+	if utils.FamilyRoleRank(memberRole) >= utils.FamilyRoleRank(role) {
+		return errors.New("不能移除同级或更高角色的成员")
 	}
 
-	// 移除成员
-	err := s.db.Where("family_id = ? AND user_id = ?", familyID, memberID).Delete(&models.FamilyMember{}).Error
-	if err != nil {
-		return err
+	// 移除成员；继承自上级或支系的成员身份在本家族圈没有成员记录，不能在此移除
+	result := s.db.Where("family_id = ? AND user_id = ?", familyID, memberID).Delete(&models.FamilyMember{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("成员不存在")
 	}
 
 	// 记录活动
@@ -394,48 +607,262 @@ func (s *FamilyService) RemoveMember(userID, familyID, memberID string) error {
 	return nil
 }
 
-// 离开家族圈
+// 离开家族圈，所有者离开时按继任策略移交所有权
 func (s *FamilyService) LeaveFamily(userID, familyID string) error {
-	// 创建者不能离开
-	var family models.Family
-	s.db.First(&family, "id = ?", familyID)
-	if family.CreatorID == userID {
-		return errors.New("创建者不能离开家族圈")
+	role, err := s.permissions.GetFamilyRole(userID, familyID)
+	if err != nil {
+		return err
 	}
+	// 继承来的成员身份随所属家族圈的成员身份存在，不能在此退出
+	var count int64
+	s.db.Model(&models.FamilyMember{}).Where("family_id = ? AND user_id = ?", familyID, userID).Count(&count)
+	if count == 0 {
+		return errors.New("继承自其他家族圈的成员身份不能在此退出")
+	}
+
+	var successor *utils.FamilySuccessionCandidate
+	if role == utils.FamilyRoleOwner {
+		candidate, ok, err := s.pickSuccessor(familyID, userID, false)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("家族圈没有其他成员，请直接删除家族圈")
+		}
+		successor = &candidate
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if successor != nil {
+			if err := handOverFamilyOwnership(tx, familyID, userID, successor.UserID); err != nil {
+				return err
+			}
+		}
 
-	// 移除成员身份
-	err := s.db.Where("family_id = ? AND user_id = ?", familyID, userID).Delete(&models.FamilyMember{}).Error
+		// 移除成员身份
+		result := tx.Where("family_id = ? AND user_id = ?", familyID, userID).Delete(&models.FamilyMember{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("您不是此家族圈的成员")
+		}
+		return nil
+	})
 	if err != nil {
 		return err
 	}
 
 	// 记录活动
+	if successor != nil {
+		s.recordOwnershipTransfer(familyID, userID, successor, OwnershipTransferOwnerLeft)
+	}
 	s.recordActivity(familyID, userID, "", "leave", nil)
 
 	return nil
 }
 
+// 转让家族圈，原所有者成为管理员
+func (s *FamilyService) TransferOwnership(userID, familyID, newOwnerID string) error {
+	if err := s.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionTransferOwnership); err != nil {
+		return err
+	}
+	if newOwnerID == userID {
+		return errors.New("不能将家族圈转让给自己")
+	}
+
+	role, err := s.permissions.GetFamilyRole(newOwnerID, familyID)
+	if err != nil {
+		return errors.New("成员不存在")
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		return handOverFamilyOwnership(tx, familyID, userID, newOwnerID)
+	})
+	if err != nil {
+		return err
+	}
+
+	// 记录活动
+	s.recordOwnershipTransfer(familyID, userID, &utils.FamilySuccessionCandidate{UserID: newOwnerID, Role: role}, OwnershipTransferManual)
+
+	return nil
+}
+
+// StartOwnerSuccessionChecker 定期为账号失效或长期未登录的所有者指定继任者
+func (s *FamilyService) StartOwnerSuccessionChecker(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			if err := s.CheckOwnerSuccession(time.Now()); err != nil {
+				fmt.Printf("检查家族圈所有者继任失败: %v\n", err)
+			}
+		}
+	}()
+}
+
+// CheckOwnerSuccession 检查所有者账号是否已注销、被禁用或长期未登录，按继任策略移交所有权，可重复执行
+func (s *FamilyService) CheckOwnerSuccession(now time.Time) error {
+	condition := "u.id IS NULL OR u.status <> ? OR u.deleted_at IS NOT NULL"
+	args := []interface{}{utils.FamilyRoleOwner, 1}
+	if s.ownerInactiveDays > 0 {
+		condition += " OR COALESCE(u.last_login_at, u.updated_at) < ?"
+		args = append(args, now.AddDate(0, 0, -s.ownerInactiveDays))
+	}
+
+	var owners []*familyMemberActivity
+	err := s.familyMemberActivityQuery().
+		Where("fm.role = ? AND ("+condition+")", args...).
+		Scan(&owners).Error
+	if err != nil {
+		return err
+	}
+
+	for _, owner := range owners {
+		// 账号失效时任何成员都可以继任；只是不活跃时，继任者须是活跃成员
+		reason := OwnershipTransferOwnerDeleted
+		if owner.accountUsable() {
+			reason = OwnershipTransferOwnerInactive
+		}
+
+		successor, ok, err := s.pickSuccessor(owner.FamilyID, owner.UserID, reason == OwnershipTransferOwnerInactive)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		err = s.db.Transaction(func(tx *gorm.DB) error {
+			return handOverFamilyOwnership(tx, owner.FamilyID, owner.UserID, successor.UserID)
+		})
+		if err != nil {
+			if err.Error() == "所有者已变更" || err.Error() == "成员不存在" {
+				continue
+			}
+			return err
+		}
+
+		s.recordOwnershipTransfer(owner.FamilyID, owner.UserID, &successor, reason)
+	}
+
+	return nil
+}
+
+// familyMemberActivity 家族成员及其账号状态，用于所有者继任
+type familyMemberActivity struct {
+	FamilyID   string
+	UserID     string
+	Role       string
+	JoinedAt   time.Time
+	Status     *int
+	DeletedAt  *time.Time
+	LastActive *time.Time
+}
+
+// accountUsable 账号存在、未注销且未被禁用
+func (m *familyMemberActivity) accountUsable() bool {
+	return m.Status != nil && *m.Status == 1 && m.DeletedAt == nil
+}
+
+func (s *FamilyService) familyMemberActivityQuery() *gorm.DB {
+	return s.db.Table("family_members fm").
+		Select("fm.family_id, fm.user_id, fm.role, fm.joined_at, u.status, u.deleted_at, COALESCE(u.last_login_at, u.updated_at) AS last_active").
+		Joins("LEFT JOIN users u ON u.id = fm.user_id")
+}
+
+// pickSuccessor 按继任策略从其他成员中选出新的所有者，activeOnly 为 true 时只考虑活跃成员
+func (s *FamilyService) pickSuccessor(familyID, ownerID string, activeOnly bool) (utils.FamilySuccessionCandidate, bool, error) {
+	var members []*familyMemberActivity
+	err := s.familyMemberActivityQuery().
+		Where("fm.family_id = ? AND fm.user_id <> ?", familyID, ownerID).
+		Scan(&members).Error
+	if err != nil {
+		return utils.FamilySuccessionCandidate{}, false, err
+	}
+
+	var cutoff time.Time
+	if s.ownerInactiveDays > 0 {
+		cutoff = time.Now().AddDate(0, 0, -s.ownerInactiveDays)
+	}
+
+	candidates := make([]utils.FamilySuccessionCandidate, 0, len(members))
+	for _, member := range members {
+		active := member.accountUsable() &&
+			(cutoff.IsZero() || (member.LastActive != nil && member.LastActive.After(cutoff)))
+		if activeOnly && !active {
+			continue
+		}
+		candidates = append(candidates, utils.FamilySuccessionCandidate{
+			UserID:   member.UserID,
+			Role:     member.Role,
+			JoinedAt: member.JoinedAt,
+			Active:   active,
+		})
+	}
+
+	successor, ok := utils.PickFamilySuccessor(candidates)
+	return successor, ok, nil
+}
+
+// handOverFamilyOwnership 在事务中将所有权从 fromID 移交给 toID，原所有者降为管理员
+func handOverFamilyOwnership(tx *gorm.DB, familyID, fromID, toID string) error {
+	// 条件更新，避免自动移交与所有者的操作并发时出现两个所有者
+	result := tx.Model(&models.FamilyMember{}).
+		Where("family_id = ? AND user_id = ? AND role = ?", familyID, fromID, utils.FamilyRoleOwner).
+		Update("role", utils.FamilyRoleAdmin)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("所有者已变更")
+	}
+
+	result = tx.Model(&models.FamilyMember{}).
+		Where("family_id = ? AND user_id = ?", familyID, toID).
+		Update("role", utils.FamilyRoleOwner)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("成员不存在")
+	}
+	return nil
+}
+
+// recordOwnershipTransfer 在家族动态中记录所有权移交
+func (s *FamilyService) recordOwnershipTransfer(familyID, fromID string, successor *utils.FamilySuccessionCandidate, reason string) {
+	s.recordActivity(familyID, fromID, "", "ownership_transfer", map[string]interface{}{
+		"from_user_id":  fromID,
+		"to_user_id":    successor.UserID,
+		"previous_role": successor.Role,
+		"reason":        reason,
+	})
+}
+
 // 设置成员角色
 func (s *FamilyService) SetMemberRole(userID, familyID, memberID, role string) error {
-	// 验证权限（只有创建者可以设置管理员）
-	var family models.Family
-	s.db.First(&family, "id = ?", familyID)
-	if family.CreatorID != userID {
-		return errors.New("只有创建者可以设置成员角色")
+	// 验证权限（只有所有者可以设置角色）
+	if err := s.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionSetRoles); err != nil {
+		return err
 	}
 
-	// 不能修改创建者的角色
-	if family.CreatorID == memberID {
-		return errors.New("不能修改创建者的角色")
+	// 验证角色，所有者身份不能通过设置角色授予
+	if !utils.IsValidFamilyRole(role) || role == utils.FamilyRoleOwner {
+		return errors.New("无效的角色")
 	}
 
-	// 验证角色
-	if role != "admin" && role != "member" {
-		return errors.New("无效的角色")
+	// 不能修改所有者的角色
+	memberRole, err := s.permissions.GetFamilyRole(memberID, familyID)
+	if err != nil {
+		return errors.New("成员不存在")
+	}
+	if memberRole == utils.FamilyRoleOwner {
+		return errors.New("不能修改所有者的角色")
 	}
 
 	// 更新成员角色
-	err := s.db.Model(&models.FamilyMember{}).
+	err = s.db.Model(&models.FamilyMember{}).
 		Where("family_id = ? AND user_id = ?", familyID, memberID).
 		Update("role", role).Error
 
@@ -452,11 +879,118 @@ func (s *FamilyService) SetMemberRole(userID, familyID, memberID, role string) e
 	return nil
 }
 
+// 更新权限矩阵请求：操作 -> 允许执行的角色，未提交的操作保持不变
+type UpdateFamilyPermissionsRequest struct {
+	Permissions map[string][]string `json:"permissions" binding:"required"`
+}
+
+// FamilyPermissionItem 权限矩阵中的一项
+type FamilyPermissionItem struct {
+	Action       string   `json:"action"`
+	Label        string   `json:"label"`
+	Roles        []string `json:"roles"`
+	DefaultRoles []string `json:"default_roles"`
+	Customized   bool     `json:"customized"`
+	Allowed      bool     `json:"allowed"` // 当前用户能否执行
+}
+
+// FamilyPermissionMatrix 家族圈权限矩阵
+type FamilyPermissionMatrix struct {
+	MyRole      string                  `json:"my_role"`
+	Roles       []string                `json:"roles"`
+	Permissions []*FamilyPermissionItem `json:"permissions"`
+}
+
+// 获取家族圈权限矩阵
+func (s *FamilyService) GetPermissionMatrix(userID, familyID string) (*FamilyPermissionMatrix, error) {
+	role, err := s.permissions.GetFamilyRole(userID, familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	matrix, err := s.permissions.GetFamilyPermissionMatrix(familyID)
+	if err != nil {
+		return nil, err
+	}
+	defaults := utils.DefaultFamilyPermissionMatrix()
+
+	result := &FamilyPermissionMatrix{
+		MyRole: role,
+		Roles:  utils.FamilyRoles,
+	}
+	for _, action := range utils.FamilyActions {
+		roles, _ := utils.NormalizeFamilyPermissionRoles(matrix[action])
+		result.Permissions = append(result.Permissions, &FamilyPermissionItem{
+			Action:       action,
+			Label:        utils.FamilyActionLabel(action),
+			Roles:        roles,
+			DefaultRoles: defaults[action],
+			Customized:   strings.Join(roles, ",") != strings.Join(defaults[action], ","),
+			Allowed:      utils.FamilyRoleAllowed(matrix, role, action),
+		})
+	}
+
+	return result, nil
+}
+
+// 更新家族圈权限矩阵（只有所有者可以修改），与默认设置相同的项恢复为默认
+func (s *FamilyService) UpdatePermissionMatrix(userID, familyID string, req *UpdateFamilyPermissionsRequest) error {
+	if err := s.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionManagePermissions); err != nil {
+		return err
+	}
+
+	normalized := make(map[string][]string, len(req.Permissions))
+	for action, roles := range req.Permissions {
+		if !utils.IsConfigurableFamilyAction(action) {
+			return fmt.Errorf("无效的操作: %s", action)
+		}
+		list, err := utils.NormalizeFamilyPermissionRoles(roles)
+		if err != nil {
+			return err
+		}
+		normalized[action] = list
+	}
+
+	defaults := utils.DefaultFamilyPermissionMatrix()
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		for action, roles := range normalized {
+			if err := tx.Where("family_id = ? AND action = ?", familyID, action).
+				Delete(&models.FamilyPermission{}).Error; err != nil {
+				return err
+			}
+			if strings.Join(roles, ",") == strings.Join(defaults[action], ",") {
+				continue
+			}
+			permission := &models.FamilyPermission{
+				ID:        uuid.New().String(),
+				FamilyID:  familyID,
+				Action:    action,
+				Roles:     strings.Join(roles, ","),
+				UpdatedBy: userID,
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+			}
+			if err := tx.Create(permission).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// 记录活动
+	s.recordActivity(familyID, userID, "", "update_permissions", normalized)
+
+	return nil
+}
+
 // 获取家族成员列表
 func (s *FamilyService) GetFamilyMembers(userID, familyID string, page, pageSize int) ([]*models.FamilyMember, int64, error) {
 	// 验证访问权限
-	if !s.isFamilyMember(userID, familyID) {
-		return nil, 0, errors.New("您不是此家族圈的成员")
+	if err := s.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionView); err != nil {
+		return nil, 0, err
 	}
 
 	var members []*models.FamilyMember
@@ -470,7 +1004,7 @@ func (s *FamilyService) GetFamilyMembers(userID, familyID string, page, pageSize
 	// 查询成员列表
 	err := s.db.Preload("User").
 		Where("family_id = ?", familyID).
-		Order("role DESC, joined_at ASC").
+		Order("FIELD(role, 'owner', 'admin', 'editor', 'member', 'guest'), joined_at ASC").
 		Offset(offset).
 		Limit(pageSize).
 		Find(&members).Error
@@ -481,8 +1015,14 @@ func (s *FamilyService) GetFamilyMembers(userID, familyID string, page, pageSize
 // 获取家族活动
 func (s *FamilyService) GetFamilyActivities(userID, familyID string, page, pageSize int) ([]*models.FamilyActivity, int64, error) {
 	// 验证访问权限
-	if !s.isFamilyMember(userID, familyID) {
-		return nil, 0, errors.New("您不是此家族圈的成员")
+	if err := s.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionView); err != nil {
+		return nil, 0, err
+	}
+
+	// 对上级可见的支系动态汇总到上级
+	familyIDs, err := s.familyScopeIDs(familyID, false)
+	if err != nil {
+		return nil, 0, err
 	}
 
 	var activities []*models.FamilyActivity
@@ -491,12 +1031,12 @@ func (s *FamilyService) GetFamilyActivities(userID, familyID string, page, pageS
 	offset := (page - 1) * pageSize
 
 	// 查询总数
-	s.db.Model(&models.FamilyActivity{}).Where("family_id = ?", familyID).Count(&total)
+	s.db.Model(&models.FamilyActivity{}).Where("family_id IN ?", familyIDs).Count(&total)
 
 	// 查询活动列表
-	err := s.db.Preload("User").
+	err = s.db.Preload("User").
 		Preload("Memorial").
-		Where("family_id = ?", familyID).
+		Where("family_id IN ?", familyIDs).
 		Order("timestamp DESC").
 		Offset(offset).
 		Limit(pageSize).
@@ -508,8 +1048,8 @@ func (s *FamilyService) GetFamilyActivities(userID, familyID string, page, pageS
 // 关联纪念馆到家族圈
 func (s *FamilyService) AddMemorialToFamily(userID, familyID, memorialID string) error {
 	// 验证权限（管理员可以关联纪念馆）
-	if !s.isFamilyAdmin(userID, familyID) {
-		return errors.New("只有管理员可以关联纪念馆")
+	if err := s.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionLinkMemorial); err != nil {
+		return err
 	}
 
 	// 验证纪念馆权限
@@ -551,8 +1091,8 @@ func (s *FamilyService) AddMemorialToFamily(userID, familyID, memorialID string)
 // 移除纪念馆关联
 func (s *FamilyService) RemoveMemorialFromFamily(userID, familyID, memorialID string) error {
 	// 验证权限（管理员可以移除关联）
-	if !s.isFamilyAdmin(userID, familyID) {
-		return errors.New("只有管理员可以移除纪念馆关联")
+	if err := s.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionLinkMemorial); err != nil {
+		return err
 	}
 
 	// 移除关联
@@ -586,42 +1126,31 @@ func (s *FamilyService) isFamilyMember(userID, familyID string) bool {
 	return err == nil
 }
 
-// 检查是否是家族管理员
-func (s *FamilyService) isFamilyAdmin(userID, familyID string) bool {
-	var member models.FamilyMember
-	err := s.db.Where("family_id = ? AND user_id = ? AND role = ?", familyID, userID, "admin").First(&member).Error
-	if err == nil {
-		return true
+// 作者本人仍是家族成员即可修改自己的故事，修改他人的故事需要管理家族故事的权限
+func (s *FamilyService) checkStoryPermission(userID, familyID string, story *models.FamilyStory) error {
+	if story.AuthorID == userID {
+		return s.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionView)
 	}
-
-	// 检查是否是创建者
-	var family models.Family
-	err = s.db.Where("id = ? AND creator_id = ?", familyID, userID).First(&family).Error
-	return err == nil
+	return s.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionManageStories)
 }
 
 // 设置纪念日提醒请求
 type SetReminderRequest struct {
 	MemorialID   string    `json:"memorial_id" binding:"required"`
 	ReminderType string    `json:"reminder_type" binding:"required"` // birthday|death_anniversary|festival
-	ReminderDate time.Time `json:"reminder_date" binding:"required"`
+	ReminderDate time.Time `json:"reminder_date"`                    // 首次提醒日期，逝世第N天的规则可不填
 	Title        string    `json:"title" binding:"required"`
 	Content      string    `json:"content"`
-}
-
-// 集体祭扫请求
-type CollectiveWorshipRequest struct {
-	MemorialID   string                 `json:"memorial_id" binding:"required"`
-	WorshipType  string                 `json:"worship_type" binding:"required"` // flower|candle|incense|tribute|prayer
-	Content      map[string]interface{} `json:"content"`
-	ScheduleTime *time.Time             `json:"schedule_time,omitempty"`
+	Rule         string    `json:"rule"`        // 重复规则，如 FREQ=YEARLY;RSCALE=CHINESE、X-DAYS-AFTER-DEATH=49，为空时生辰忌日每年、节日一次
+	LeadDays     []int     `json:"lead_days"`   // 提前提醒天数，如 [3,0]，为空只在当天提醒
+	RemindTime   string    `json:"remind_time"` // 提醒发送时刻 HH:MM，默认 09:00
 }
 
 // 设置纪念日提醒
 func (s *FamilyService) SetMemorialReminder(userID, familyID string, req *SetReminderRequest) error {
 	// 验证权限（管理员可以设置提醒）
-	if !s.isFamilyAdmin(userID, familyID) {
-		return errors.New("只有管理员可以设置纪念日提醒")
+	if err := s.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionSetReminder); err != nil {
+		return err
 	}
 
 	// 验证纪念馆是否属于家族圈
@@ -647,6 +1176,34 @@ func (s *FamilyService) SetMemorialReminder(userID, familyID string, req *SetRem
 		return errors.New("无效的提醒类型")
 	}
 
+	// 验证重复规则、提前量和发送时刻
+	rule, err := utils.ParseReminderRule(req.Rule)
+	if err != nil {
+		return err
+	}
+	leadDays, err := utils.ParseReminderLeadDays(req.LeadDays)
+	if err != nil {
+		return err
+	}
+	remindTime := defaultRemindTime
+	if req.RemindTime != "" {
+		if _, _, err := utils.ParseRemindTime(req.RemindTime); err != nil {
+			return err
+		}
+		remindTime = req.RemindTime
+	}
+	var memorial models.Memorial
+	if err := s.db.Select("id", "death_date").First(&memorial, "id = ?", req.MemorialID).Error; err != nil {
+		return err
+	}
+	if rule.DaysAfterDeath > 0 && memorial.DeathDate == nil {
+		return errors.New("纪念馆未填写逝世日期")
+	}
+	if rule.DaysAfterDeath == 0 && req.ReminderDate.IsZero() {
+		return errors.New("请填写提醒日期")
+	}
+	leadDaysJSON, _ := json.Marshal(leadDays)
+
 	// 创建提醒
 	reminder := &models.MemorialReminder{
 		ID:           uuid.New().String(),
@@ -656,9 +1213,17 @@ func (s *FamilyService) SetMemorialReminder(userID, familyID string, req *SetRem
 		Title:        req.Title,
 		Content:      req.Content,
 		IsActive:     true,
+		CreatorID:    userID,
+		Rule:         rule.String(),
+		LeadDays:     string(leadDaysJSON),
+		RemindTime:   remindTime,
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
 	}
+	if rule.DaysAfterDeath > 0 {
+		// 逝世第N天的提醒以推算出的首次日期作为提醒日期
+		reminder.ReminderDate, _ = rule.Start(req.ReminderDate, memorial.DeathDate)
+	}
 
 	if err := s.db.Create(reminder).Error; err != nil {
 		return err
@@ -667,8 +1232,9 @@ func (s *FamilyService) SetMemorialReminder(userID, familyID string, req *SetRem
 	// 记录活动
 	s.recordActivity(familyID, userID, req.MemorialID, "set_reminder", map[string]interface{}{
 		"reminder_type": req.ReminderType,
-		"reminder_date": req.ReminderDate.Format("2006-01-02"),
+		"reminder_date": reminder.ReminderDate.Format("2006-01-02"),
 		"title":         req.Title,
+		"rule":          reminder.Rule,
 	})
 
 	return nil
@@ -677,8 +1243,8 @@ func (s *FamilyService) SetMemorialReminder(userID, familyID string, req *SetRem
 // 获取家族纪念日提醒
 func (s *FamilyService) GetFamilyReminders(userID, familyID string, page, pageSize int) ([]*models.MemorialReminder, int64, error) {
 	// 验证访问权限
-	if !s.isFamilyMember(userID, familyID) {
-		return nil, 0, errors.New("您不是此家族圈的成员")
+	if err := s.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionView); err != nil {
+		return nil, 0, err
 	}
 
 	var reminders []*models.MemorialReminder
@@ -686,10 +1252,15 @@ func (s *FamilyService) GetFamilyReminders(userID, familyID string, page, pageSi
 
 	offset := (page - 1) * pageSize
 
-	// 获取家族圈关联的纪念馆ID列表
+	// 获取家族圈关联的纪念馆ID列表，包括上级家族圈和对上级可见的支系
+	familyIDs, err := s.familyScopeIDs(familyID, true)
+	if err != nil {
+		return nil, 0, err
+	}
 	var memorialIDs []string
 	s.db.Model(&models.MemorialFamily{}).
-		Where("family_id = ?", familyID).
+		Where("family_id IN ?", familyIDs).
+		Distinct().
 		Pluck("memorial_id", &memorialIDs)
 
 	if len(memorialIDs) == 0 {
@@ -702,52 +1273,72 @@ func (s *FamilyService) GetFamilyReminders(userID, familyID string, page, pageSi
 		Count(&total)
 
 	// 查询提醒列表
-	err := s.db.Preload("Memorial").
+	err = s.db.Preload("Memorial").
 		Where("memorial_id IN (?) AND is_active = ?", memorialIDs, true).
 		Order("reminder_date ASC").
 		Offset(offset).
 		Limit(pageSize).
 		Find(&reminders).Error
+	if err != nil {
+		return nil, 0, err
+	}
 
-	return reminders, total, err
+	today := reminderDay(time.Now(), time.Local)
+	for _, reminder := range reminders {
+		fillReminderSchedule(reminder, today)
+	}
+	return reminders, total, nil
 }
 
 // 获取即将到来的提醒（3天内）
 func (s *FamilyService) GetUpcomingReminders(userID, familyID string) ([]*models.MemorialReminder, error) {
 	// 验证访问权限
-	if !s.isFamilyMember(userID, familyID) {
-		return nil, errors.New("您不是此家族圈的成员")
+	if err := s.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionView); err != nil {
+		return nil, err
 	}
 
-	// 获取家族圈关联的纪念馆ID列表
+	// 获取家族圈关联的纪念馆ID列表，包括上级家族圈和对上级可见的支系
+	familyIDs, err := s.familyScopeIDs(familyID, true)
+	if err != nil {
+		return nil, err
+	}
 	var memorialIDs []string
 	s.db.Model(&models.MemorialFamily{}).
-		Where("family_id = ?", familyID).
+		Where("family_id IN ?", familyIDs).
+		Distinct().
 		Pluck("memorial_id", &memorialIDs)
 
 	if len(memorialIDs) == 0 {
 		return []*models.MemorialReminder{}, nil
 	}
 
-	// 查询3天内的提醒
-	now := time.Now()
-	threeDaysLater := now.AddDate(0, 0, 3)
-
+	// 按重复规则推算3天内的提醒
 	var reminders []*models.MemorialReminder
-	err := s.db.Preload("Memorial").
-		Where("memorial_id IN (?) AND is_active = ? AND reminder_date BETWEEN ? AND ?",
-			memorialIDs, true, now.Format("2006-01-02"), threeDaysLater.Format("2006-01-02")).
-		Order("reminder_date ASC").
+	err = s.db.Preload("Memorial").
+		Where("memorial_id IN (?) AND is_active = ?", memorialIDs, true).
 		Find(&reminders).Error
+	if err != nil {
+		return nil, err
+	}
+
+	today := reminderDay(time.Now(), time.Local)
+	upcoming := []*models.MemorialReminder{}
+	for _, reminder := range reminders {
+		fillReminderSchedule(reminder, today)
+		if reminder.NextDate != nil && !reminder.NextDate.After(today.AddDate(0, 0, 3)) {
+			upcoming = append(upcoming, reminder)
+		}
+	}
+	sort.SliceStable(upcoming, func(i, j int) bool { return upcoming[i].NextDate.Before(*upcoming[j].NextDate) })
 
-	return reminders, err
+	return upcoming, nil
 }
 
 // 删除纪念日提醒
 func (s *FamilyService) DeleteReminder(userID, familyID, reminderID string) error {
 	// 验证权限（管理员可以删除提醒）
-	if !s.isFamilyAdmin(userID, familyID) {
-		return errors.New("只有管理员可以删除纪念日提醒")
+	if err := s.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionSetReminder); err != nil {
+		return err
 	}
 
 	var reminder models.MemorialReminder
@@ -783,111 +1374,6 @@ func (s *FamilyService) DeleteReminder(userID, familyID, reminderID string) erro
 	return nil
 }
 
-// 发起集体祭扫
-func (s *FamilyService) InitiateCollectiveWorship(userID, familyID string, req *CollectiveWorshipRequest) error {
-	// 验证权限（管理员可以发起集体祭扫）
-	if !s.isFamilyAdmin(userID, familyID) {
-		return errors.New("只有管理员可以发起集体祭扫")
-	}
-
-	// 验证纪念馆是否属于家族圈
-	var relation models.MemorialFamily
-	err := s.db.Where("memorial_id = ? AND family_id = ?", req.MemorialID, familyID).First(&relation).Error
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("纪念馆未关联到此家族圈")
-		}
-		return err
-	}
-
-	// 验证祭扫类型
-	validTypes := []string{"flower", "candle", "incense", "tribute", "prayer"}
-	isValidType := false
-	for _, validType := range validTypes {
-		if req.WorshipType == validType {
-			isValidType = true
-			break
-		}
-	}
-	if !isValidType {
-		return errors.New("无效的祭扫类型")
-	}
-
-	// 创建集体祭扫活动
-	activityContent := map[string]interface{}{
-		"worship_type":  req.WorshipType,
-		"content":       req.Content,
-		"schedule_time": req.ScheduleTime,
-		"initiator_id":  userID,
-		"participants":  []string{userID}, // 发起者自动参与
-		"status":        "active",
-	}
-
-	// 记录活动
-	s.recordActivity(familyID, userID, req.MemorialID, "collective_worship", activityContent)
-
-	return nil
-}
-
-// 参与集体祭扫
-func (s *FamilyService) JoinCollectiveWorship(userID, familyID, activityID string) error {
-	// 验证访问权限
-	if !s.isFamilyMember(userID, familyID) {
-		return errors.New("您不是此家族圈的成员")
-	}
-
-	var activity models.FamilyActivity
-	err := s.db.First(&activity, "id = ? AND family_id = ? AND activity_type = ?",
-		activityID, familyID, "collective_worship").Error
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("集体祭扫活动不存在")
-		}
-		return err
-	}
-
-	// 解析活动内容
-	var content map[string]interface{}
-	if err := json.Unmarshal([]byte(activity.Content), &content); err != nil {
-		return errors.New("活动数据格式错误")
-	}
-
-	// 检查活动状态
-	if status, ok := content["status"].(string); !ok || status != "active" {
-		return errors.New("活动已结束")
-	}
-
-	// 检查是否已经参与
-	participants, ok := content["participants"].([]interface{})
-	if !ok {
-		participants = []interface{}{}
-	}
-
-	for _, participant := range participants {
-		if participantID, ok := participant.(string); ok && participantID == userID {
-			return errors.New("您已经参与了此活动")
-		}
-	}
-
-	// 添加参与者
-	participants = append(participants, userID)
-	content["participants"] = participants
-
-	// 更新活动内容
-	contentJSON, _ := json.Marshal(content)
-	if err := s.db.Model(&activity).Update("content", string(contentJSON)).Error; err != nil {
-		return err
-	}
-
-	// 记录参与活动
-	s.recordActivity(familyID, userID, activity.MemorialID, "join_collective_worship", map[string]interface{}{
-		"activity_id":  activityID,
-		"worship_type": content["worship_type"],
-	})
-
-	return nil
-}
-
 // 同步祭扫动态到家族圈
 func (s *FamilyService) SyncWorshipActivity(userID, memorialID string, worshipType string, content interface{}) error {
 	// 查找纪念馆关联的家族圈
@@ -911,11 +1397,44 @@ func (s *FamilyService) SyncWorshipActivity(userID, memorialID string, worshipTy
 	return nil
 }
 
+// 同步家族合祭动态：每个家族圈只记录一条，附带本次祭扫的纪念馆
+func (s *FamilyService) SyncFamilyWorshipActivity(userID string, memorialIDs []string, worshipType string, content interface{}) error {
+	var relations []models.MemorialFamily
+	err := s.db.Where("memorial_id IN ?", memorialIDs).Order("created_at ASC").Find(&relations).Error
+	if err != nil {
+		return err
+	}
+
+	byFamily := make(map[string][]string)
+	var familyIDs []string
+	for _, relation := range relations {
+		if _, ok := byFamily[relation.FamilyID]; !ok {
+			familyIDs = append(familyIDs, relation.FamilyID)
+		}
+		byFamily[relation.FamilyID] = append(byFamily[relation.FamilyID], relation.MemorialID)
+	}
+
+	for _, familyID := range familyIDs {
+		if !s.isFamilyMember(userID, familyID) {
+			continue
+		}
+		ids := byFamily[familyID]
+		s.recordActivity(familyID, userID, ids[0], "family_worship", map[string]interface{}{
+			"worship_type": worshipType,
+			"memorial_ids": ids,
+			"content":      content,
+		})
+	}
+
+	return nil
+}
+
 // 家族谱系相关请求结构
 type CreateGenealogyRequest struct {
 	PersonName   string     `json:"person_name" binding:"required"`
 	Generation   int        `json:"generation" binding:"required"`
-	ParentID     string     `json:"parent_id"`
+	ParentID     string     `json:"parent_id"`                                                     // 可选，同时建立与该父辈的父母子女关系
+	ParentType   string     `json:"parent_type" binding:"omitempty,oneof=biological adopted step"` // 与 parent_id 的关系，默认 biological
 	Gender       string     `json:"gender" binding:"required,oneof=male female"`
 	BirthDate    *time.Time `json:"birth_date"`
 	DeathDate    *time.Time `json:"death_date"`
@@ -929,7 +1448,6 @@ type CreateGenealogyRequest struct {
 type UpdateGenealogyRequest struct {
 	PersonName   string     `json:"person_name"`
 	Generation   int        `json:"generation"`
-	ParentID     string     `json:"parent_id"`
 	Gender       string     `json:"gender"`
 	BirthDate    *time.Time `json:"birth_date"`
 	DeathDate    *time.Time `json:"death_date"`
@@ -940,6 +1458,84 @@ type UpdateGenealogyRequest struct {
 	Achievements string     `json:"achievements"`
 }
 
+// 谱系关系相关请求结构
+type CreateGenealogyRelationRequest struct {
+	Type         string     `json:"type" binding:"required,oneof=parent spouse"`
+	FromID       string     `json:"from_id" binding:"required"` // parent 为父母，spouse 为任一方
+	ToID         string     `json:"to_id" binding:"required"`   // parent 为子女，spouse 为另一方
+	ParentType   string     `json:"parent_type" binding:"omitempty,oneof=biological adopted step"`
+	MarriageDate *time.Time `json:"marriage_date"`
+	DivorceDate  *time.Time `json:"divorce_date"`
+}
+
+type UpdateGenealogyRelationRequest struct {
+	ParentType   string     `json:"parent_type" binding:"omitempty,oneof=biological adopted step"`
+	MarriageDate *time.Time `json:"marriage_date"`
+	DivorceDate  *time.Time `json:"divorce_date"`
+}
+
+// FamilyGenealogyGraph 家族谱系图：成员及其之间的父母子女、配偶关系
+type FamilyGenealogyGraph struct {
+	Persons   []*models.FamilyGenealogy   `json:"persons"`
+	Relations []*models.GenealogyRelation `json:"relations"`
+	MeID      string                      `json:"me_id"`  // 当前用户在谱系中对应的成员，未关联时为空
+	Labels    map[string]string           `json:"labels"` // 当前用户对各谱系成员的称谓，按成员ID
+}
+
+// GenealogyKinship 称谓计算结果
+type GenealogyKinship struct {
+	From *models.FamilyGenealogy `json:"from"`
+	To   *models.FamilyGenealogy `json:"to"`
+	Term string                  `json:"term"` // from 对 to 的称呼
+	Path []*GenealogyPathStep    `json:"path"`
+}
+
+// GenealogyAudit 谱系一致性检查结果
+type GenealogyAudit struct {
+	PersonCount   int                    `json:"person_count"`
+	RelationCount int                    `json:"relation_count"`
+	ErrorCount    int                    `json:"error_count"`
+	WarningCount  int                    `json:"warning_count"`
+	Issues        []utils.GenealogyIssue `json:"issues"`
+}
+
+// RelatedMemorial 按谱系关系找到的亲属纪念馆
+type RelatedMemorial struct {
+	Memorial    *models.Memorial `json:"memorial"`
+	GenealogyID string           `json:"genealogy_id"` // 纪念馆对应的谱系成员
+	PersonName  string           `json:"person_name"`
+	FamilyID    string           `json:"family_id"` // 经由哪个家族圈的谱系找到
+	Term        string           `json:"term"`      // 当前纪念馆逝者对此人的称谓
+	Distance    int              `json:"distance"`  // 关系路径上相隔几步，父母、子女、配偶为 1
+}
+
+// GenealogyMemorialSuggestion 谱系成员与纪念馆的关联建议
+type GenealogyMemorialSuggestion struct {
+	Person   *models.FamilyGenealogy `json:"person"`
+	Memorial *models.Memorial        `json:"memorial"`
+	Match    string                  `json:"match"`   // exact | possible
+	Reasons  []string                `json:"reasons"` // 匹配依据
+}
+
+type SetMyGenealogyRequest struct {
+	GenealogyID string `json:"genealogy_id"` // 为空时取消关联
+}
+
+// GenealogyKinNode 祖先或后代查询结果
+type GenealogyKinNode struct {
+	Person     *models.FamilyGenealogy `json:"person"`
+	Depth      int                     `json:"depth"`       // 相隔代数，父母或子女为 1
+	ViaID      string                  `json:"via_id"`      // 离查询对象近一代的那个人
+	ParentType string                  `json:"parent_type"` // 与 via_id 之间的父母关系
+}
+
+// GenealogyPathStep 两人之间关系路径上的一步
+type GenealogyPathStep struct {
+	Person     *models.FamilyGenealogy `json:"person"`
+	Relation   string                  `json:"relation"` // 此人是上一个人的 parent | child | spouse，起点为空
+	ParentType string                  `json:"parent_type"`
+}
+
 // 家族故事相关请求结构
 type CreateFamilyStoryRequest struct {
 	Title      string   `json:"title" binding:"required"`
@@ -975,6 +1571,8 @@ type CreateFamilyTraditionRequest struct {
 	Meaning     string   `json:"meaning"`
 	MediaFiles  []string `json:"media_files"`
 	IsActive    bool     `json:"is_active"`
+
+	Schedule *TraditionScheduleRequest `json:"schedule"` // 可选，设定后出现在家族日历中并按期提醒
 }
 
 type UpdateFamilyTraditionRequest struct {
@@ -986,101 +1584,914 @@ type UpdateFamilyTraditionRequest struct {
 	Meaning     string   `json:"meaning"`
 	MediaFiles  []string `json:"media_files"`
 	IsActive    *bool    `json:"is_active"`
+
+	Schedule *TraditionScheduleRequest `json:"schedule"` // 不传表示不修改，calendar 为空表示取消日期
+}
+
+// 家族传统的日期安排
+type TraditionScheduleRequest struct {
+	Calendar   string `json:"calendar" binding:"omitempty,oneof=solar lunar"`
+	Recurrence string `json:"recurrence" binding:"omitempty,oneof=yearly monthly once"` // 默认 yearly
+	Year       int    `json:"year"`                                                     // 仅一次时填写，农历为农历年
+	Month      int    `json:"month"`                                                    // 每月重复时不填
+	Day        int    `json:"day"`
+	RemindDays *int   `json:"remind_days" binding:"omitempty,min=0,max=30"` // 提前几天提醒，默认3
+}
+
+// 家族日历事项类型
+const (
+	FamilyCalendarTradition       = "tradition"        // 家族传统
+	FamilyCalendarAnniversary     = "anniversary"      // 纪念馆逝者的生辰、忌日
+	FamilyCalendarReminder        = "reminder"         // 纪念馆的节日提醒
+	FamilyCalendarFestival        = "festival"         // 系统配置的祭扫节日
+	FamilyCalendarMemorialService = "memorial_service" // 已安排的追思会
+	FamilyCalendarMourning        = "mourning"         // 守丧日程（做七、百日、周年）
+)
+
+// FamilyCalendarEvent 家族日历上的一项
+type FamilyCalendarEvent struct {
+	Date        string     `json:"date"` // YYYY-MM-DD
+	LunarDate   string     `json:"lunar_date"`
+	Type        string     `json:"type"`
+	Title       string     `json:"title"`
+	Description string     `json:"description,omitempty"`
+	SourceID    string     `json:"source_id"` // 传统、提醒、节日或追思会的ID
+	FamilyID    string     `json:"family_id,omitempty"`
+	MemorialID  string     `json:"memorial_id,omitempty"`
+	StartTime   *time.Time `json:"start_time,omitempty"` // 追思会开始时间
+
+	CollectiveWorshipID string `json:"collective_worship_id,omitempty"` // 为守丧日程这一天安排的集体祭扫ID
 }
 
 // 创建家族谱系成员
 func (s *FamilyService) CreateGenealogy(userID, familyID string, req *CreateGenealogyRequest) (*models.FamilyGenealogy, error) {
 	// 验证权限（管理员可以创建谱系）
-	if !s.isFamilyAdmin(userID, familyID) {
-		return nil, errors.New("只有管理员可以创建家族谱系")
+	if err := s.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionEditGenealogy); err != nil {
+		return nil, err
+	}
+
+	// 验证性别
+	if req.Gender != "male" && req.Gender != "female" {
+		return nil, errors.New("无效的性别")
+	}
+
+	// 谱系跨支系：父辈和纪念馆可以属于上级家族圈
+	lineageIDs, err := s.genealogyLineageIDs(familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 如果指定了父辈，验证父辈是否存在
+	if req.ParentID != "" {
+		var parent models.FamilyGenealogy
+		err := s.db.Where("id = ? AND family_id IN ?", req.ParentID, lineageIDs).First(&parent).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, errors.New("指定的父辈不存在")
+			}
+			return nil, err
+		}
+	}
+
+	// 如果指定了纪念馆，验证纪念馆是否关联到家族圈
+	if req.MemorialID != "" {
+		if err := s.checkGenealogyMemorial(lineageIDs, req.MemorialID, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	genealogy := &models.FamilyGenealogy{
+		ID:           uuid.New().String(),
+		FamilyID:     familyID,
+		PersonName:   req.PersonName,
+		Generation:   req.Generation,
+		Gender:       req.Gender,
+		BirthDate:    req.BirthDate,
+		DeathDate:    req.DeathDate,
+		Biography:    req.Biography,
+		AvatarURL:    req.AvatarURL,
+		MemorialID:   req.MemorialID,
+		Position:     req.Position,
+		Achievements: req.Achievements,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+
+	var relation *models.GenealogyRelation
+	if req.ParentID != "" {
+		parentType := req.ParentType
+		if parentType == "" {
+			parentType = utils.GenealogyParentBiological
+		}
+		relation = &models.GenealogyRelation{
+			ID:         uuid.New().String(),
+			FamilyID:   familyID,
+			Type:       utils.GenealogyRelationParent,
+			FromID:     req.ParentID,
+			ToID:       genealogy.ID,
+			ParentType: parentType,
+			CreatedAt:  time.Now(),
+			UpdatedAt:  time.Now(),
+		}
+	}
+	if err := s.validateGenealogyWrite(familyID, genealogy, relation); err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(genealogy).Error; err != nil {
+			return err
+		}
+		if relation == nil {
+			return nil
+		}
+		return tx.Create(relation).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.touchGenealogy(familyID)
+
+	// 重新查询包含关联数据
+	s.db.Preload("Memorial").First(genealogy, "id = ?", genealogy.ID)
+
+	// 记录活动
+	s.recordActivity(familyID, userID, req.MemorialID, "create_genealogy", map[string]interface{}{
+		"person_name": req.PersonName,
+		"generation":  req.Generation,
+	})
+
+	return genealogy, nil
+}
+
+// checkGenealogyMemorial 校验纪念馆已关联到谱系所在的家族圈，且未被谱系中的其他成员关联
+func (s *FamilyService) checkGenealogyMemorial(lineageIDs []string, memorialID, exceptID string) error {
+	var relation models.MemorialFamily
+	err := s.db.Where("memorial_id = ? AND family_id IN ?", memorialID, lineageIDs).First(&relation).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("纪念馆未关联到此家族圈")
+		}
+		return err
+	}
+
+	var count int64
+	err = s.db.Model(&models.FamilyGenealogy{}).
+		Where("memorial_id = ? AND family_id IN ? AND id <> ?", memorialID, lineageIDs, exceptID).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("该纪念馆已关联其他谱系成员")
+	}
+	return nil
+}
+
+// 获取家族谱系图，当前用户已关联谱系成员时附带对每个人的称谓
+func (s *FamilyService) GetFamilyGenealogy(userID, familyID, region string) (*FamilyGenealogyGraph, error) {
+	// 验证访问权限
+	if err := s.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionView); err != nil {
+		return nil, err
+	}
+	if region != "" && !utils.IsValidKinshipRegion(region) {
+		return nil, errors.New("无效的称谓地区")
+	}
+
+	persons, relations, err := s.loadGenealogy(familyID)
+	if err != nil {
+		return nil, err
+	}
+	graph := &FamilyGenealogyGraph{Persons: persons, Relations: relations, Labels: map[string]string{}}
+
+	byID := genealogyPersonsByID(persons)
+	graph.MeID = s.myGenealogyID(userID, familyID, byID)
+	if graph.MeID != "" {
+		for id, path := range genealogyGraphOf(relations).PathsFrom(graph.MeID) {
+			graph.Labels[id] = kinshipTermOf(path, byID, region)
+		}
+	}
+	return graph, nil
+}
+
+// 计算两位谱系成员之间的称谓，fromID 为空时从当前用户关联的谱系成员出发
+func (s *FamilyService) GetGenealogyKinship(userID, familyID, fromID, toID, region string) (*GenealogyKinship, error) {
+	if err := s.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionView); err != nil {
+		return nil, err
+	}
+	if region != "" && !utils.IsValidKinshipRegion(region) {
+		return nil, errors.New("无效的称谓地区")
+	}
+
+	persons, relations, err := s.loadGenealogy(familyID)
+	if err != nil {
+		return nil, err
+	}
+	byID := genealogyPersonsByID(persons)
+	if fromID == "" {
+		fromID = s.myGenealogyID(userID, familyID, byID)
+		if fromID == "" {
+			return nil, errors.New("请先在谱系中关联本人")
+		}
+	}
+	if byID[fromID] == nil || byID[toID] == nil {
+		return nil, errors.New("谱系成员不存在")
+	}
+
+	path, ok := genealogyGraphOf(relations).Path(fromID, toID)
+	if !ok {
+		return nil, errors.New("两人之间没有亲属关系")
+	}
+
+	kinship := &GenealogyKinship{
+		From: byID[fromID],
+		To:   byID[toID],
+		Term: kinshipTermOf(path, byID, region),
+		Path: make([]*GenealogyPathStep, 0, len(path)),
+	}
+	for _, step := range path {
+		kinship.Path = append(kinship.Path, &GenealogyPathStep{
+			Person:     byID[step.ID],
+			Relation:   step.Relation,
+			ParentType: step.ParentType,
+		})
+	}
+	return kinship, nil
+}
+
+// 关联或取消关联本人在谱系中对应的成员
+func (s *FamilyService) SetMyGenealogy(userID, familyID string, req *SetMyGenealogyRequest) error {
+	if err := s.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionView); err != nil {
+		return err
+	}
+
+	if req.GenealogyID != "" {
+		familyIDs, err := s.familyScopeIDs(familyID, true)
+		if err != nil {
+			return err
+		}
+		var count int64
+		s.db.Model(&models.FamilyGenealogy{}).Where("id = ? AND family_id IN ?", req.GenealogyID, familyIDs).Count(&count)
+		if count == 0 {
+			return errors.New("谱系成员不存在")
+		}
+	}
+
+	// 继承上级成员身份的用户记录在最近的上级家族圈
+	member, err := s.nearestMembership(userID, familyID)
+	if err != nil {
+		return err
+	}
+	return s.db.Model(member).Update("genealogy_id", req.GenealogyID).Error
+}
+
+// nearestMembership 返回用户在本家族圈或最近的上级家族圈中的成员记录
+func (s *FamilyService) nearestMembership(userID, familyID string) (*models.FamilyMember, error) {
+	lineageIDs, err := s.genealogyLineageIDs(familyID)
+	if err != nil {
+		return nil, err
+	}
+	var members []*models.FamilyMember
+	if err := s.db.Where("user_id = ? AND family_id IN ?", userID, lineageIDs).Find(&members).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range lineageIDs {
+		for _, m := range members {
+			if m.FamilyID == id {
+				return m, nil
+			}
+		}
+	}
+	return nil, errors.New("您不是此家族圈的成员")
+}
+
+// myGenealogyID 当前用户在谱系中对应的成员，须在可见范围内
+func (s *FamilyService) myGenealogyID(userID, familyID string, byID map[string]*models.FamilyGenealogy) string {
+	member, err := s.nearestMembership(userID, familyID)
+	if err != nil || byID[member.GenealogyID] == nil {
+		return ""
+	}
+	return member.GenealogyID
+}
+
+func kinshipTermOf(path []utils.GenealogyStep, byID map[string]*models.FamilyGenealogy, region string) string {
+	person := func(id string) utils.KinshipPerson {
+		p := byID[id]
+		if p == nil {
+			return utils.KinshipPerson{}
+		}
+		return utils.KinshipPerson{Gender: p.Gender, BirthDate: p.BirthDate}
+	}
+	steps := make([]utils.KinshipStep, 0, len(path))
+	for _, step := range path[1:] {
+		steps = append(steps, utils.KinshipStep{
+			Relation:   step.Relation,
+			ParentType: step.ParentType,
+			Person:     person(step.ID),
+		})
+	}
+	return utils.KinshipTerm(person(path[0].ID), steps, region)
+}
+
+// 获取与纪念馆逝者有亲属关系的其他纪念馆，只返回当前用户可以访问的
+func (s *FamilyService) GetRelatedMemorials(userID, memorialID string, maxDistance int, region string) ([]*RelatedMemorial, error) {
+	if _, _, err := s.permissions.CanAccessMemorial(userID, memorialID); err != nil {
+		return nil, err
+	}
+	if region != "" && !utils.IsValidKinshipRegion(region) {
+		return nil, errors.New("无效的称谓地区")
+	}
+	if maxDistance <= 0 {
+		maxDistance = 4
+	}
+
+	// 同一位逝者可能出现在多个家族圈的谱系中
+	var anchors []*models.FamilyGenealogy
+	if err := s.db.Where("memorial_id = ?", memorialID).Find(&anchors).Error; err != nil {
+		return nil, err
+	}
+
+	byMemorial := make(map[string]*RelatedMemorial)
+	generations := make(map[string]int)
+	for _, anchor := range anchors {
+		persons, relations, err := s.loadGenealogy(anchor.FamilyID)
+		if err != nil {
+			return nil, err
+		}
+		byID := genealogyPersonsByID(persons)
+		for id, path := range genealogyGraphOf(relations).PathsFrom(anchor.ID) {
+			p := byID[id]
+			distance := len(path) - 1
+			if p == nil || p.Memorial == nil || p.MemorialID == memorialID || distance > maxDistance {
+				continue
+			}
+			if found := byMemorial[p.MemorialID]; found != nil && found.Distance <= distance {
+				continue
+			}
+			byMemorial[p.MemorialID] = &RelatedMemorial{
+				Memorial:    p.Memorial,
+				GenealogyID: p.ID,
+				PersonName:  p.PersonName,
+				FamilyID:    anchor.FamilyID,
+				Term:        kinshipTermOf(path, byID, region),
+				Distance:    distance,
+			}
+			generations[p.MemorialID] = p.Generation
+		}
+	}
+
+	related := make([]*RelatedMemorial, 0, len(byMemorial))
+	for id, r := range byMemorial {
+		if ok, _, _ := s.permissions.CanAccessMemorial(userID, id); !ok {
+			continue
+		}
+		related = append(related, r)
+	}
+	sort.Slice(related, func(i, j int) bool {
+		a, b := related[i], related[j]
+		if a.Distance != b.Distance {
+			return a.Distance < b.Distance
+		}
+		if generations[a.Memorial.ID] != generations[b.Memorial.ID] {
+			return generations[a.Memorial.ID] < generations[b.Memorial.ID]
+		}
+		return a.PersonName < b.PersonName
+	})
+	return related, nil
+}
+
+// 按姓名和生卒日期，为尚未关联纪念馆的谱系成员推荐家族圈中的纪念馆
+func (s *FamilyService) GetGenealogyMemorialSuggestions(userID, familyID string) ([]*GenealogyMemorialSuggestion, error) {
+	if err := s.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionEditGenealogy); err != nil {
+		return nil, err
+	}
+	lineageIDs, err := s.genealogyLineageIDs(familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	var persons []*models.FamilyGenealogy
+	err = s.db.Where("family_id = ? AND (memorial_id = '' OR memorial_id IS NULL)", familyID).
+		Order("generation ASC, person_name ASC").
+		Find(&persons).Error
+	if err != nil || len(persons) == 0 {
+		return []*GenealogyMemorialSuggestion{}, err
+	}
+
+	// 谱系中已关联过的纪念馆不再推荐
+	linked := s.db.Model(&models.FamilyGenealogy{}).
+		Select("memorial_id").
+		Where("family_id IN ? AND memorial_id <> ''", lineageIDs)
+	var memorials []*models.Memorial
+	err = s.db.Where("status = ?", 1).
+		Where("id IN (?)", s.db.Model(&models.MemorialFamily{}).Select("memorial_id").Where("family_id IN ?", lineageIDs)).
+		Where("id NOT IN (?)", linked).
+		Find(&memorials).Error
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := []*GenealogyMemorialSuggestion{}
+	for _, p := range persons {
+		key := utils.GenealogyMemorialKey{Name: p.PersonName, BirthDate: p.BirthDate, DeathDate: p.DeathDate}
+		for _, m := range memorials {
+			match, reasons := utils.MatchGenealogyMemorial(key, utils.GenealogyMemorialKey{
+				Name:      m.DeceasedName,
+				BirthDate: m.BirthDate,
+				DeathDate: m.DeathDate,
+			})
+			if match == "" {
+				continue
+			}
+			suggestions = append(suggestions, &GenealogyMemorialSuggestion{
+				Person:   p,
+				Memorial: m,
+				Match:    match,
+				Reasons:  reasons,
+			})
+		}
+	}
+	// 确定匹配在前，同等可信度按辈分排列
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		a, b := suggestions[i], suggestions[j]
+		if a.Match != b.Match {
+			return a.Match == utils.GenealogyMatchExact
+		}
+		return a.Person.Generation < b.Person.Generation
+	})
+	return suggestions, nil
+}
+
+// 获取谱系成员的祖先，maxDepth 为 0 表示不限代数
+func (s *FamilyService) GetGenealogyAncestors(userID, familyID, genealogyID string, maxDepth int) ([]*GenealogyKinNode, error) {
+	return s.genealogyKin(userID, familyID, genealogyID, maxDepth, true)
+}
+
+// 获取谱系成员的后代，maxDepth 为 0 表示不限代数
+func (s *FamilyService) GetGenealogyDescendants(userID, familyID, genealogyID string, maxDepth int) ([]*GenealogyKinNode, error) {
+	return s.genealogyKin(userID, familyID, genealogyID, maxDepth, false)
+}
+
+func (s *FamilyService) genealogyKin(userID, familyID, genealogyID string, maxDepth int, ancestors bool) ([]*GenealogyKinNode, error) {
+	if err := s.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionView); err != nil {
+		return nil, err
+	}
+
+	persons, relations, err := s.loadGenealogy(familyID)
+	if err != nil {
+		return nil, err
+	}
+	byID := genealogyPersonsByID(persons)
+	if byID[genealogyID] == nil {
+		return nil, errors.New("谱系成员不存在")
+	}
+
+	graph := genealogyGraphOf(relations)
+	var kin []utils.GenealogyKin
+	if ancestors {
+		kin = graph.Ancestors(genealogyID, maxDepth)
+	} else {
+		kin = graph.Descendants(genealogyID, maxDepth)
+	}
+
+	nodes := make([]*GenealogyKinNode, 0, len(kin))
+	for _, k := range kin {
+		nodes = append(nodes, &GenealogyKinNode{
+			Person:     byID[k.ID],
+			Depth:      k.Depth,
+			ViaID:      k.ViaID,
+			ParentType: k.ParentType,
+		})
+	}
+	return nodes, nil
+}
+
+// 获取两位谱系成员之间的关系路径
+func (s *FamilyService) GetGenealogyPath(userID, familyID, fromID, toID string) ([]*GenealogyPathStep, error) {
+	if err := s.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionView); err != nil {
+		return nil, err
+	}
+
+	persons, relations, err := s.loadGenealogy(familyID)
+	if err != nil {
+		return nil, err
+	}
+	byID := genealogyPersonsByID(persons)
+	if byID[fromID] == nil || byID[toID] == nil {
+		return nil, errors.New("谱系成员不存在")
+	}
+
+	path, ok := genealogyGraphOf(relations).Path(fromID, toID)
+	if !ok {
+		return nil, errors.New("两人之间没有亲属关系")
+	}
+
+	steps := make([]*GenealogyPathStep, 0, len(path))
+	for _, step := range path {
+		steps = append(steps, &GenealogyPathStep{
+			Person:     byID[step.ID],
+			Relation:   step.Relation,
+			ParentType: step.ParentType,
+		})
+	}
+	return steps, nil
+}
+
+// 建立谱系成员之间的关系
+func (s *FamilyService) CreateGenealogyRelation(userID, familyID string, req *CreateGenealogyRelationRequest) (*models.GenealogyRelation, error) {
+	if err := s.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionEditGenealogy); err != nil {
+		return nil, err
+	}
+
+	if req.FromID == req.ToID {
+		return nil, errors.New("不能与自己建立关系")
+	}
+
+	// 与创建谱系成员一致，关系双方可以属于上级家族圈
+	lineageIDs, err := s.genealogyLineageIDs(familyID)
+	if err != nil {
+		return nil, err
+	}
+	var count int64
+	s.db.Model(&models.FamilyGenealogy{}).Where("id IN ? AND family_id IN ?", []string{req.FromID, req.ToID}, lineageIDs).Count(&count)
+	if count != 2 {
+		return nil, errors.New("谱系成员不存在")
+	}
+
+	relation := &models.GenealogyRelation{
+		ID:        uuid.New().String(),
+		FamilyID:  familyID,
+		Type:      req.Type,
+		FromID:    req.FromID,
+		ToID:      req.ToID,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	persons, relations, err := s.loadGenealogy(familyID)
+	if err != nil {
+		return nil, err
+	}
+	graph := genealogyGraphOf(relations)
+
+	switch req.Type {
+	case utils.GenealogyRelationParent:
+		relation.ParentType = req.ParentType
+		if relation.ParentType == "" {
+			relation.ParentType = utils.GenealogyParentBiological
+		}
+		for _, e := range graph.Parents(req.ToID) {
+			if e.FromID == req.FromID {
+				return nil, errors.New("关系已存在")
+			}
+		}
+		if graph.IsAncestor(req.ToID, req.FromID) {
+			return nil, errors.New("不能将后代设为父辈")
+		}
+		if relation.ParentType == utils.GenealogyParentBiological && graph.BiologicalParentCount(req.ToID) >= utils.GenealogyMaxBiologicalParents {
+			return nil, errors.New("亲生父母最多两位")
+		}
+	case utils.GenealogyRelationSpouse:
+		// 配偶关系不分方向，按ID排序后存储
+		if relation.FromID > relation.ToID {
+			relation.FromID, relation.ToID = relation.ToID, relation.FromID
+		}
+		for _, e := range graph.Spouses(req.FromID) {
+			if e.FromID == relation.FromID && e.ToID == relation.ToID {
+				return nil, errors.New("关系已存在")
+			}
+		}
+		if req.MarriageDate != nil && req.DivorceDate != nil && req.DivorceDate.Before(*req.MarriageDate) {
+			return nil, errors.New("离婚日期不能早于结婚日期")
+		}
+		relation.MarriageDate = req.MarriageDate
+		relation.DivorceDate = req.DivorceDate
+	}
+	if err := checkGenealogyWrite(persons, relations, nil, relation); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Create(relation).Error; err != nil {
+		return nil, err
+	}
+	s.touchGenealogy(familyID)
+	return relation, nil
+}
+
+// 更新谱系关系：父母关系类型或婚姻日期
+func (s *FamilyService) UpdateGenealogyRelation(userID, familyID, relationID string, req *UpdateGenealogyRelationRequest) error {
+	if err := s.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionEditGenealogy); err != nil {
+		return err
+	}
+
+	relation, err := s.findGenealogyRelation(familyID, relationID)
+	if err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{"updated_at": time.Now()}
+	switch relation.Type {
+	case utils.GenealogyRelationParent:
+		if req.ParentType != "" && req.ParentType != relation.ParentType {
+			if req.ParentType == utils.GenealogyParentBiological {
+				var count int64
+				s.db.Model(&models.GenealogyRelation{}).
+					Where("type = ? AND to_id = ? AND parent_type = ?", utils.GenealogyRelationParent, relation.ToID, utils.GenealogyParentBiological).
+					Count(&count)
+				if count >= utils.GenealogyMaxBiologicalParents {
+					return errors.New("亲生父母最多两位")
+				}
+			}
+			updates["parent_type"] = req.ParentType
+			changed := *relation
+			changed.ParentType = req.ParentType
+			if err := s.validateGenealogyWrite(familyID, nil, &changed); err != nil {
+				return err
+			}
+		}
+	case utils.GenealogyRelationSpouse:
+		marriage, divorce := relation.MarriageDate, relation.DivorceDate
+		if req.MarriageDate != nil {
+			marriage = req.MarriageDate
+			updates["marriage_date"] = req.MarriageDate
+		}
+		if req.DivorceDate != nil {
+			divorce = req.DivorceDate
+			updates["divorce_date"] = req.DivorceDate
+		}
+		if marriage != nil && divorce != nil && divorce.Before(*marriage) {
+			return errors.New("离婚日期不能早于结婚日期")
+		}
+	}
+
+	if err := s.db.Model(relation).Updates(updates).Error; err != nil {
+		return err
+	}
+	s.touchGenealogy(familyID)
+	return nil
+}
+
+// 删除谱系关系
+func (s *FamilyService) DeleteGenealogyRelation(userID, familyID, relationID string) error {
+	if err := s.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionEditGenealogy); err != nil {
+		return err
+	}
+
+	relation, err := s.findGenealogyRelation(familyID, relationID)
+	if err != nil {
+		return err
+	}
+	if err := s.db.Delete(relation).Error; err != nil {
+		return err
+	}
+	s.touchGenealogy(familyID)
+	return nil
+}
+
+// 关系只能由建立它的家族圈修改
+func (s *FamilyService) findGenealogyRelation(familyID, relationID string) (*models.GenealogyRelation, error) {
+	var relation models.GenealogyRelation
+	err := s.db.Where("id = ? AND family_id = ?", relationID, familyID).First(&relation).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("关系不存在")
+		}
+		return nil, err
+	}
+	return &relation, nil
+}
+
+// genealogyLineageIDs 新建谱系成员和关系时可以引用的家族圈：本家族圈及各级上级
+func (s *FamilyService) genealogyLineageIDs(familyID string) ([]string, error) {
+	ancestors, err := s.permissions.GetFamilyAncestorIDs(familyID)
+	if err != nil {
+		return nil, err
+	}
+	return append([]string{familyID}, ancestors...), nil
+}
+
+// 检查谱系的一致性：环、辈分、日期、孤立关系和重复成员
+func (s *FamilyService) AuditGenealogy(userID, familyID string) (*GenealogyAudit, error) {
+	if err := s.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionView); err != nil {
+		return nil, err
+	}
+
+	persons, _, err := s.loadGenealogy(familyID)
+	if err != nil {
+		return nil, err
+	}
+	familyIDs, err := s.familyScopeIDs(familyID, true)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(persons))
+	for _, p := range persons {
+		ids = append(ids, p.ID)
 	}
 
-	// 验证性别
-	if req.Gender != "male" && req.Gender != "female" {
-		return nil, errors.New("无效的性别")
+	// 包括一端已被删除的关系，用于发现孤立关系
+	relations := []*models.GenealogyRelation{}
+	query := s.db.Where("family_id IN ?", familyIDs)
+	if len(ids) > 0 {
+		query = query.Or("from_id IN ?", ids).Or("to_id IN ?", ids)
+	}
+	if err := query.Order("created_at ASC").Find(&relations).Error; err != nil {
+		return nil, err
 	}
 
-	// 如果指定了父辈，验证父辈是否存在
-	if req.ParentID != "" {
-		var parent models.FamilyGenealogy
-		err := s.db.Where("id = ? AND family_id = ?", req.ParentID, familyID).First(&parent).Error
-		if err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				return nil, errors.New("指定的父辈不存在")
+	// 另一端属于范围外（如私密支系）的成员不算孤立，不参与检查
+	inScope := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		inScope[id] = true
+	}
+	var outside []string
+	for _, r := range relations {
+		for _, id := range []string{r.FromID, r.ToID} {
+			if !inScope[id] {
+				outside = append(outside, id)
 			}
-			return nil, err
 		}
 	}
+	alive := map[string]bool{}
+	if len(outside) > 0 {
+		var found []string
+		s.db.Model(&models.FamilyGenealogy{}).Where("id IN ?", outside).Pluck("id", &found)
+		for _, id := range found {
+			alive[id] = true
+		}
+	}
+	checked := relations[:0]
+	for _, r := range relations {
+		if alive[r.FromID] || alive[r.ToID] {
+			continue
+		}
+		checked = append(checked, r)
+	}
 
-	// 如果指定了纪念馆，验证纪念馆是否关联到家族圈
-	if req.MemorialID != "" {
-		var relation models.MemorialFamily
-		err := s.db.Where("memorial_id = ? AND family_id = ?", req.MemorialID, familyID).First(&relation).Error
-		if err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				return nil, errors.New("纪念馆未关联到此家族圈")
-			}
-			return nil, err
+	auditPersons, edges := genealogyAuditInput(persons, checked)
+	audit := &GenealogyAudit{
+		PersonCount:   len(persons),
+		RelationCount: len(checked),
+		Issues:        utils.ValidateGenealogy(auditPersons, edges),
+	}
+	if audit.Issues == nil {
+		audit.Issues = []utils.GenealogyIssue{}
+	}
+	for _, issue := range audit.Issues {
+		if issue.Severity == utils.GenealogySeverityError {
+			audit.ErrorCount++
+		} else {
+			audit.WarningCount++
 		}
 	}
+	return audit, nil
+}
 
-	genealogy := &models.FamilyGenealogy{
-		ID:           uuid.New().String(),
-		FamilyID:     familyID,
-		PersonName:   req.PersonName,
-		Generation:   req.Generation,
-		ParentID:     req.ParentID,
-		Gender:       req.Gender,
-		BirthDate:    req.BirthDate,
-		DeathDate:    req.DeathDate,
-		Biography:    req.Biography,
-		AvatarURL:    req.AvatarURL,
-		MemorialID:   req.MemorialID,
-		Position:     req.Position,
-		Achievements: req.Achievements,
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
+// validateGenealogyWrite 写入前校验：person 为新建或修改后的成员，relation 为新建或修改后的关系，均可为空
+func (s *FamilyService) validateGenealogyWrite(familyID string, person *models.FamilyGenealogy, relation *models.GenealogyRelation) error {
+	persons, relations, err := s.loadGenealogy(familyID)
+	if err != nil {
+		return err
 	}
+	return checkGenealogyWrite(persons, relations, person, relation)
+}
 
-	if err := s.db.Create(genealogy).Error; err != nil {
-		return nil, err
+// checkGenealogyWrite 只拒绝本次修改新引入的错误，已有的问题不影响无关的修改
+func checkGenealogyWrite(persons []*models.FamilyGenealogy, relations []*models.GenealogyRelation,
+	person *models.FamilyGenealogy, relation *models.GenealogyRelation) error {
+	beforePersons, beforeEdges := genealogyAuditInput(persons, relations)
+	known := map[string]bool{}
+	for _, issue := range utils.ValidateGenealogy(beforePersons, beforeEdges) {
+		known[issue.Key()] = true
+	}
+
+	afterPersons, afterEdges := genealogyAuditInput(persons, relations)
+	if person != nil {
+		p := genealogyAuditPerson(person)
+		replaced := false
+		for i := range afterPersons {
+			if afterPersons[i].ID == p.ID {
+				afterPersons[i] = p
+				replaced = true
+			}
+		}
+		if !replaced {
+			afterPersons = append(afterPersons, p)
+		}
+	}
+	if relation != nil {
+		e := utils.GenealogyEdge{ID: relation.ID, Type: relation.Type, FromID: relation.FromID, ToID: relation.ToID, ParentType: relation.ParentType}
+		replaced := false
+		for i := range afterEdges {
+			if afterEdges[i].ID == e.ID {
+				afterEdges[i] = e
+				replaced = true
+			}
+		}
+		if !replaced {
+			afterEdges = append(afterEdges, e)
+		}
 	}
 
-	// 重新查询包含关联数据
-	s.db.Preload("Parent").Preload("Children").Preload("Memorial").First(genealogy, "id = ?", genealogy.ID)
+	for _, issue := range utils.ValidateGenealogy(afterPersons, afterEdges) {
+		if issue.Severity == utils.GenealogySeverityError && !known[issue.Key()] {
+			return fmt.Errorf("谱系数据不一致：%s。%s", issue.Message, issue.Suggestion)
+		}
+	}
+	return nil
+}
 
-	// 记录活动
-	s.recordActivity(familyID, userID, req.MemorialID, "create_genealogy", map[string]interface{}{
-		"person_name": req.PersonName,
-		"generation":  req.Generation,
-	})
+func genealogyAuditInput(persons []*models.FamilyGenealogy, relations []*models.GenealogyRelation) ([]utils.GenealogyAuditPerson, []utils.GenealogyEdge) {
+	auditPersons := make([]utils.GenealogyAuditPerson, 0, len(persons))
+	for _, p := range persons {
+		auditPersons = append(auditPersons, genealogyAuditPerson(p))
+	}
+	edges := make([]utils.GenealogyEdge, 0, len(relations))
+	for _, r := range relations {
+		edges = append(edges, utils.GenealogyEdge{ID: r.ID, Type: r.Type, FromID: r.FromID, ToID: r.ToID, ParentType: r.ParentType})
+	}
+	return auditPersons, edges
+}
 
-	return genealogy, nil
+func genealogyAuditPerson(p *models.FamilyGenealogy) utils.GenealogyAuditPerson {
+	return utils.GenealogyAuditPerson{
+		ID:         p.ID,
+		Name:       p.PersonName,
+		Gender:     p.Gender,
+		Generation: p.Generation,
+		BirthDate:  p.BirthDate,
+		DeathDate:  p.DeathDate,
+	}
 }
 
-// 获取家族谱系
-func (s *FamilyService) GetFamilyGenealogy(userID, familyID string) ([]*models.FamilyGenealogy, error) {
-	// 验证访问权限
-	if !s.isFamilyMember(userID, familyID) {
-		return nil, errors.New("您不是此家族圈的成员")
+// touchGenealogy 谱系有改动时递增本家族圈及各级上级的谱系版本，使缓存的谱系图失效
+func (s *FamilyService) touchGenealogy(familyID string) {
+	lineageIDs, err := s.genealogyLineageIDs(familyID)
+	if err != nil {
+		lineageIDs = []string{familyID}
 	}
+	s.db.Model(&models.Family{}).Where("id IN ?", lineageIDs).
+		UpdateColumn("genealogy_version", gorm.Expr("genealogy_version + 1"))
+}
 
-	var genealogies []*models.FamilyGenealogy
-	err := s.db.Preload("Parent").
-		Preload("Children").
-		Preload("Memorial").
-		Where("family_id = ?", familyID).
+// loadGenealogy 加载家族圈可见的谱系成员及其之间的关系；谱系跨支系，包括上级家族圈和对上级可见的支系
+func (s *FamilyService) loadGenealogy(familyID string) ([]*models.FamilyGenealogy, []*models.GenealogyRelation, error) {
+	familyIDs, err := s.familyScopeIDs(familyID, true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	persons := []*models.FamilyGenealogy{}
+	err = s.db.Preload("Memorial").
+		Where("family_id IN ?", familyIDs).
 		Order("generation ASC, person_name ASC").
-		Find(&genealogies).Error
+		Find(&persons).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	relations := []*models.GenealogyRelation{}
+	if len(persons) == 0 {
+		return persons, relations, nil
+	}
+	ids := make([]string, 0, len(persons))
+	for _, p := range persons {
+		ids = append(ids, p.ID)
+	}
+	err = s.db.Where("from_id IN ? AND to_id IN ?", ids, ids).
+		Order("created_at ASC").
+		Find(&relations).Error
+	return persons, relations, err
+}
+
+func genealogyPersonsByID(persons []*models.FamilyGenealogy) map[string]*models.FamilyGenealogy {
+	byID := make(map[string]*models.FamilyGenealogy, len(persons))
+	for _, p := range persons {
+		byID[p.ID] = p
+	}
+	return byID
+}
 
-	return genealogies, err
+func genealogyGraphOf(relations []*models.GenealogyRelation) *utils.GenealogyGraph {
+	edges := make([]utils.GenealogyEdge, 0, len(relations))
+	for _, r := range relations {
+		edges = append(edges, utils.GenealogyEdge{
+			ID:         r.ID,
+			Type:       r.Type,
+			FromID:     r.FromID,
+			ToID:       r.ToID,
+			ParentType: r.ParentType,
+		})
+	}
+	return utils.NewGenealogyGraph(edges)
 }
 
 // 更新家族谱系成员
 func (s *FamilyService) UpdateGenealogy(userID, familyID, genealogyID string, req *UpdateGenealogyRequest) error {
 	// 验证权限（管理员可以更新谱系）
-	if !s.isFamilyAdmin(userID, familyID) {
-		return errors.New("只有管理员可以更新家族谱系")
+	if err := s.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionEditGenealogy); err != nil {
+		return err
 	}
 
 	var genealogy models.FamilyGenealogy
@@ -1100,9 +2511,6 @@ func (s *FamilyService) UpdateGenealogy(userID, familyID, genealogyID string, re
 	if req.Generation != 0 {
 		updates["generation"] = req.Generation
 	}
-	if req.ParentID != "" {
-		updates["parent_id"] = req.ParentID
-	}
 	if req.Gender != "" {
 		updates["gender"] = req.Gender
 	}
@@ -1118,7 +2526,14 @@ func (s *FamilyService) UpdateGenealogy(userID, familyID, genealogyID string, re
 	if req.AvatarURL != "" {
 		updates["avatar_url"] = req.AvatarURL
 	}
-	if req.MemorialID != "" {
+	if req.MemorialID != "" && req.MemorialID != genealogy.MemorialID {
+		lineageIDs, err := s.genealogyLineageIDs(familyID)
+		if err != nil {
+			return err
+		}
+		if err := s.checkGenealogyMemorial(lineageIDs, req.MemorialID, genealogy.ID); err != nil {
+			return err
+		}
 		updates["memorial_id"] = req.MemorialID
 	}
 	if req.Position != "" {
@@ -1129,14 +2544,39 @@ func (s *FamilyService) UpdateGenealogy(userID, familyID, genealogyID string, re
 	}
 	updates["updated_at"] = time.Now()
 
-	return s.db.Model(&genealogy).Updates(updates).Error
+	// 按修改后的辈分和生卒日期校验
+	changed := genealogy
+	if req.PersonName != "" {
+		changed.PersonName = req.PersonName
+	}
+	if req.Generation != 0 {
+		changed.Generation = req.Generation
+	}
+	if req.Gender != "" {
+		changed.Gender = req.Gender
+	}
+	if req.BirthDate != nil {
+		changed.BirthDate = req.BirthDate
+	}
+	if req.DeathDate != nil {
+		changed.DeathDate = req.DeathDate
+	}
+	if err := s.validateGenealogyWrite(familyID, &changed, nil); err != nil {
+		return err
+	}
+
+	if err := s.db.Model(&genealogy).Updates(updates).Error; err != nil {
+		return err
+	}
+	s.touchGenealogy(familyID)
+	return nil
 }
 
 // 删除家族谱系成员
 func (s *FamilyService) DeleteGenealogy(userID, familyID, genealogyID string) error {
 	// 验证权限（管理员可以删除谱系）
-	if !s.isFamilyAdmin(userID, familyID) {
-		return errors.New("只有管理员可以删除家族谱系")
+	if err := s.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionEditGenealogy); err != nil {
+		return err
 	}
 
 	var genealogy models.FamilyGenealogy
@@ -1150,19 +2590,35 @@ func (s *FamilyService) DeleteGenealogy(userID, familyID, genealogyID string) er
 
 	// 检查是否有子代，如果有则不能删除
 	var childCount int64
-	s.db.Model(&models.FamilyGenealogy{}).Where("parent_id = ?", genealogyID).Count(&childCount)
+	s.db.Model(&models.GenealogyRelation{}).
+		Where("type = ? AND from_id = ?", utils.GenealogyRelationParent, genealogyID).
+		Count(&childCount)
 	if childCount > 0 {
 		return errors.New("该成员有子代记录，无法删除")
 	}
 
-	return s.db.Delete(&genealogy).Error
+	// 同时删除与父母、配偶的关系
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("from_id = ? OR to_id = ?", genealogyID, genealogyID).Delete(&models.GenealogyRelation{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.FamilyMember{}).Where("genealogy_id = ?", genealogyID).Update("genealogy_id", "").Error; err != nil {
+			return err
+		}
+		return tx.Delete(&genealogy).Error
+	})
+	if err != nil {
+		return err
+	}
+	s.touchGenealogy(familyID)
+	return nil
 }
 
 // 创建家族故事
 func (s *FamilyService) CreateFamilyStory(userID, familyID string, req *CreateFamilyStoryRequest) (*models.FamilyStory, error) {
-	// 验证访问权限
-	if !s.isFamilyMember(userID, familyID) {
-		return nil, errors.New("您不是此家族圈的成员")
+	// 验证权限
+	if err := s.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionPostStory); err != nil {
+		return nil, err
 	}
 
 	// 验证故事分类
@@ -1178,6 +2634,12 @@ func (s *FamilyService) CreateFamilyStory(userID, familyID string, req *CreateFa
 		return nil, errors.New("无效的故事分类")
 	}
 
+	// 敏感词检测
+	screen, err := s.contentFilter.Screen(ContentTypeFamilyStory, userID, "", req.Title, req.Content)
+	if err != nil {
+		return nil, err
+	}
+
 	// 序列化数组字段
 	charactersJSON, _ := json.Marshal(req.Characters)
 	mediaFilesJSON, _ := json.Marshal(req.MediaFiles)
@@ -1208,6 +2670,7 @@ func (s *FamilyService) CreateFamilyStory(userID, familyID string, req *CreateFa
 	if err := s.db.Create(story).Error; err != nil {
 		return nil, err
 	}
+	s.contentFilter.RecordFlagged(screen, story.ID)
 
 	// 重新查询包含关联数据
 	s.db.Preload("Author").First(story, "id = ?", story.ID)
@@ -1224,8 +2687,8 @@ func (s *FamilyService) CreateFamilyStory(userID, familyID string, req *CreateFa
 // 获取家族故事列表
 func (s *FamilyService) GetFamilyStories(userID, familyID string, category string, page, pageSize int) ([]*models.FamilyStory, int64, error) {
 	// 验证访问权限
-	if !s.isFamilyMember(userID, familyID) {
-		return nil, 0, errors.New("您不是此家族圈的成员")
+	if err := s.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionView); err != nil {
+		return nil, 0, err
 	}
 
 	var stories []*models.FamilyStory
@@ -1254,8 +2717,8 @@ func (s *FamilyService) GetFamilyStories(userID, familyID string, category strin
 // 获取家族故事详情
 func (s *FamilyService) GetFamilyStory(userID, familyID, storyID string) (*models.FamilyStory, error) {
 	// 验证访问权限
-	if !s.isFamilyMember(userID, familyID) {
-		return nil, errors.New("您不是此家族圈的成员")
+	if err := s.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionView); err != nil {
+		return nil, err
 	}
 
 	var story models.FamilyStory
@@ -1287,9 +2750,15 @@ func (s *FamilyService) UpdateFamilyStory(userID, familyID, storyID string, req
 		return err
 	}
 
-	// 验证权限（作者或管理员可以更新）
-	if story.AuthorID != userID && !s.isFamilyAdmin(userID, familyID) {
-		return errors.New("只有作者或管理员可以更新故事")
+	// 验证权限（作者本人，或有管理家族故事权限的成员）
+	if err := s.checkStoryPermission(userID, familyID, &story); err != nil {
+		return err
+	}
+
+	// 敏感词检测
+	screen, err := s.contentFilter.Screen(ContentTypeFamilyStory, userID, "", req.Title, req.Content)
+	if err != nil {
+		return err
 	}
 
 	// 构建更新字段
@@ -1329,7 +2798,11 @@ func (s *FamilyService) UpdateFamilyStory(userID, familyID, storyID string, req
 	}
 	updates["updated_at"] = time.Now()
 
-	return s.db.Model(&story).Updates(updates).Error
+	if err := s.db.Model(&story).Updates(updates).Error; err != nil {
+		return err
+	}
+	s.contentFilter.RecordFlagged(screen, story.ID)
+	return nil
 }
 
 // 删除家族故事
@@ -1343,9 +2816,9 @@ func (s *FamilyService) DeleteFamilyStory(userID, familyID, storyID string) erro
 		return err
 	}
 
-	// 验证权限（作者或管理员可以删除）
-	if story.AuthorID != userID && !s.isFamilyAdmin(userID, familyID) {
-		return errors.New("只有作者或管理员可以删除故事")
+	// 验证权限（作者本人，或有管理家族故事权限的成员）
+	if err := s.checkStoryPermission(userID, familyID, &story); err != nil {
+		return err
 	}
 
 	return s.db.Delete(&story).Error
@@ -1354,8 +2827,8 @@ func (s *FamilyService) DeleteFamilyStory(userID, familyID, storyID string) erro
 // 创建家族传统
 func (s *FamilyService) CreateFamilyTradition(userID, familyID string, req *CreateFamilyTraditionRequest) (*models.FamilyTradition, error) {
 	// 验证权限（管理员可以创建传统）
-	if !s.isFamilyAdmin(userID, familyID) {
-		return nil, errors.New("只有管理员可以创建家族传统")
+	if err := s.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionEditTradition); err != nil {
+		return nil, err
 	}
 
 	// 验证传统分类
@@ -1385,13 +2858,20 @@ func (s *FamilyService) CreateFamilyTradition(userID, familyID string, req *Crea
 		Meaning:     req.Meaning,
 		MediaFiles:  string(mediaFilesJSON),
 		IsActive:    req.IsActive,
+		RemindDays:  3,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
+	if req.Schedule != nil {
+		if err := applyTraditionSchedule(tradition, req.Schedule); err != nil {
+			return nil, err
+		}
+	}
 
 	if err := s.db.Create(tradition).Error; err != nil {
 		return nil, err
 	}
+	fillTraditionSchedule(tradition, time.Now())
 
 	// 记录活动
 	s.recordActivity(familyID, userID, "", "create_tradition", map[string]interface{}{
@@ -1405,8 +2885,8 @@ func (s *FamilyService) CreateFamilyTradition(userID, familyID string, req *Crea
 // 获取家族传统列表
 func (s *FamilyService) GetFamilyTraditions(userID, familyID string, category string, page, pageSize int) ([]*models.FamilyTradition, int64, error) {
 	// 验证访问权限
-	if !s.isFamilyMember(userID, familyID) {
-		return nil, 0, errors.New("您不是此家族圈的成员")
+	if err := s.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionView); err != nil {
+		return nil, 0, err
 	}
 
 	var traditions []*models.FamilyTradition
@@ -1428,14 +2908,19 @@ func (s *FamilyService) GetFamilyTraditions(userID, familyID string, category st
 		Limit(pageSize).
 		Find(&traditions).Error
 
+	now := time.Now()
+	for _, tradition := range traditions {
+		fillTraditionSchedule(tradition, now)
+	}
+
 	return traditions, total, err
 }
 
 // 更新家族传统
 func (s *FamilyService) UpdateFamilyTradition(userID, familyID, traditionID string, req *UpdateFamilyTraditionRequest) error {
 	// 验证权限（管理员可以更新传统）
-	if !s.isFamilyAdmin(userID, familyID) {
-		return errors.New("只有管理员可以更新家族传统")
+	if err := s.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionEditTradition); err != nil {
+		return err
 	}
 
 	var tradition models.FamilyTradition
@@ -1474,16 +2959,345 @@ func (s *FamilyService) UpdateFamilyTradition(userID, familyID, traditionID stri
 	if req.IsActive != nil {
 		updates["is_active"] = *req.IsActive
 	}
+	if req.Schedule != nil {
+		changed := tradition
+		if err := applyTraditionSchedule(&changed, req.Schedule); err != nil {
+			return err
+		}
+		updates["calendar_type"] = changed.CalendarType
+		updates["recurrence"] = changed.Recurrence
+		updates["schedule_year"] = changed.ScheduleYear
+		updates["schedule_month"] = changed.ScheduleMonth
+		updates["schedule_day"] = changed.ScheduleDay
+		updates["remind_days"] = changed.RemindDays
+		updates["reminded_for"] = nil
+	}
 	updates["updated_at"] = time.Now()
 
 	return s.db.Model(&tradition).Updates(updates).Error
 }
 
+// applyTraditionSchedule 校验并写入传统的日期安排，calendar 为空时清除
+func applyTraditionSchedule(tradition *models.FamilyTradition, req *TraditionScheduleRequest) error {
+	if req.RemindDays != nil {
+		tradition.RemindDays = *req.RemindDays
+	}
+	if req.Calendar == "" {
+		tradition.CalendarType = ""
+		tradition.Recurrence = ""
+		tradition.ScheduleYear = 0
+		tradition.ScheduleMonth = 0
+		tradition.ScheduleDay = 0
+		return nil
+	}
+
+	schedule := utils.TraditionSchedule{
+		Calendar:   req.Calendar,
+		Recurrence: req.Recurrence,
+		Year:       req.Year,
+		Month:      req.Month,
+		Day:        req.Day,
+	}
+	if schedule.Recurrence == "" {
+		schedule.Recurrence = utils.RecurrenceYearly
+	}
+	if schedule.Recurrence == utils.RecurrenceMonthly {
+		schedule.Month = 0
+	}
+	if schedule.Recurrence != utils.RecurrenceOnce {
+		schedule.Year = 0
+	}
+	if err := schedule.Validate(); err != nil {
+		return err
+	}
+
+	tradition.CalendarType = schedule.Calendar
+	tradition.Recurrence = schedule.Recurrence
+	tradition.ScheduleYear = schedule.Year
+	tradition.ScheduleMonth = schedule.Month
+	tradition.ScheduleDay = schedule.Day
+	return nil
+}
+
+// traditionSchedule 传统的日期安排，未设定日期时返回 false
+func traditionSchedule(tradition *models.FamilyTradition) (utils.TraditionSchedule, bool) {
+	if tradition.CalendarType == "" {
+		return utils.TraditionSchedule{}, false
+	}
+	return utils.TraditionSchedule{
+		Calendar:   tradition.CalendarType,
+		Recurrence: tradition.Recurrence,
+		Year:       tradition.ScheduleYear,
+		Month:      tradition.ScheduleMonth,
+		Day:        tradition.ScheduleDay,
+	}, true
+}
+
+// fillTraditionSchedule 填充下一次日期和日期安排的描述
+func fillTraditionSchedule(tradition *models.FamilyTradition, now time.Time) {
+	schedule, ok := traditionSchedule(tradition)
+	if !ok {
+		return
+	}
+	tradition.ScheduleText = schedule.Describe()
+	if next, ok := schedule.Next(now); ok {
+		tradition.NextDate = &next
+	}
+}
+
+// StartTraditionReminderScheduler 定期为临近的家族传统发送提醒
+func (s *FamilyService) StartTraditionReminderScheduler(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			if err := s.ProcessTraditionReminders(time.Now()); err != nil {
+				fmt.Printf("处理家族传统提醒失败: %v\n", err)
+			}
+		}
+	}()
+}
+
+// ProcessTraditionReminders 下一次日期进入提醒天数的传统，在家族动态中发布一条提醒；
+// 每一次只提醒一遍，可重复执行
+func (s *FamilyService) ProcessTraditionReminders(now time.Time) error {
+	var traditions []*models.FamilyTradition
+	err := s.db.Where("is_active = ? AND calendar_type <> ''", true).Find(&traditions).Error
+	if err != nil {
+		return err
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	for _, tradition := range traditions {
+		schedule, _ := traditionSchedule(tradition)
+		next, ok := schedule.Next(today)
+		if !ok || next.After(today.AddDate(0, 0, tradition.RemindDays)) {
+			continue
+		}
+		if tradition.RemindedFor != nil && tradition.RemindedFor.Format("2006-01-02") == next.Format("2006-01-02") {
+			continue
+		}
+
+		// 以条件更新认领，多个实例同时执行时只提醒一次
+		result := s.db.Model(&models.FamilyTradition{}).
+			Where("id = ? AND (reminded_for IS NULL OR reminded_for <> ?)", tradition.ID, next).
+			Update("reminded_for", next)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		content := map[string]interface{}{
+			"tradition_id": tradition.ID,
+			"name":         tradition.Name,
+			"date":         next.Format("2006-01-02"),
+			"schedule":     schedule.Describe(),
+			"days_left":    int(next.Sub(today).Hours() / 24),
+		}
+		if lunar, ok := utils.SolarToLunar(next); ok {
+			content["lunar_date"] = lunar.String()
+		}
+		s.recordActivity(tradition.FamilyID, "", "", "tradition_reminder", content)
+		s.notifyTraditionReminder(tradition, next, content["days_left"].(int))
+	}
+	return nil
+}
+
+// notifyTraditionReminder 通知家族圈成员传统临近
+func (s *FamilyService) notifyTraditionReminder(tradition *models.FamilyTradition, date time.Time, daysLeft int) {
+	var memberIDs []string
+	s.db.Model(&models.FamilyMember{}).Where("family_id = ?", tradition.FamilyID).Pluck("user_id", &memberIDs)
+
+	when := "今天"
+	if daysLeft > 0 {
+		when = fmt.Sprintf("%d天后（%s）", daysLeft, date.Format("2006-01-02"))
+	}
+	err := s.notifications.Notify(memberIDs, &NotificationInput{
+		Category: NotificationCategoryReminder,
+		Type:     NotificationTypeTraditionReminder,
+		Title:    "家族传统提醒",
+		Content:  fmt.Sprintf("%s是家族传统“%s”", when, tradition.Name),
+		Data:     map[string]interface{}{"family_id": tradition.FamilyID, "tradition_id": tradition.ID, "date": date.Format("2006-01-02")},
+	})
+	if err != nil {
+		fmt.Printf("发送家族传统 %s 提醒失败: %v\n", tradition.ID, err)
+	}
+}
+
+// 获取家族日历：家族传统、纪念馆逝者的生辰忌日和节日提醒、祭扫节日、已安排的追思会
+func (s *FamilyService) GetFamilyCalendar(userID, familyID string, from, to time.Time) ([]*FamilyCalendarEvent, error) {
+	if err := s.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionView); err != nil {
+		return nil, err
+	}
+	if to.Before(from) {
+		return nil, errors.New("结束日期不能早于开始日期")
+	}
+	if to.Sub(from) > 366*24*time.Hour {
+		return nil, errors.New("日历范围不能超过一年")
+	}
+
+	familyIDs, err := s.familyScopeIDs(familyID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	events := []*FamilyCalendarEvent{}
+	add := func(date time.Time, event *FamilyCalendarEvent) {
+		event.Date = date.Format("2006-01-02")
+		if lunar, ok := utils.SolarToLunar(date); ok {
+			event.LunarDate = lunar.String()
+		}
+		events = append(events, event)
+	}
+
+	// 家族传统
+	var traditions []*models.FamilyTradition
+	err = s.db.Where("family_id IN ? AND is_active = ? AND calendar_type <> ''", familyIDs, true).Find(&traditions).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, tradition := range traditions {
+		schedule, _ := traditionSchedule(tradition)
+		for _, date := range schedule.Occurrences(from, to) {
+			add(date, &FamilyCalendarEvent{
+				Type:        FamilyCalendarTradition,
+				Title:       tradition.Name,
+				Description: schedule.Describe(),
+				SourceID:    tradition.ID,
+				FamilyID:    tradition.FamilyID,
+			})
+		}
+	}
+
+	// 家族圈关联的纪念馆，私密纪念馆只对创建者显示
+	var memorialIDs []string
+	err = s.db.Model(&models.Memorial{}).
+		Where("id IN (?)", s.db.Model(&models.MemorialFamily{}).Select("memorial_id").Where("family_id IN ?", familyIDs)).
+		Where("status = ? AND (privacy_level <> ? OR creator_id = ?)", 1, 2, userID).
+		Pluck("id", &memorialIDs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if len(memorialIDs) > 0 {
+		// 纪念日提醒按重复规则展开，生辰忌日归为纪念日
+		var reminders []*models.MemorialReminder
+		err = s.db.Preload("Memorial").Where("memorial_id IN ? AND is_active = ?", memorialIDs, true).Find(&reminders).Error
+		if err != nil {
+			return nil, err
+		}
+		// 守丧日程的提醒附上为这一天安排的集体祭扫
+		var mourningReminderIDs []string
+		for _, reminder := range reminders {
+			if reminder.ReminderType == ReminderTypeMourning {
+				mourningReminderIDs = append(mourningReminderIDs, reminder.ID)
+			}
+		}
+		arranged := make(map[string]string)
+		if len(mourningReminderIDs) > 0 {
+			var observances []models.MourningObservance
+			err = s.db.Select("reminder_id", "collective_worship_id").
+				Where("reminder_id IN ? AND collective_worship_id <> ''", mourningReminderIDs).
+				Find(&observances).Error
+			if err != nil {
+				return nil, err
+			}
+			for _, observance := range observances {
+				arranged[observance.ReminderID] = observance.CollectiveWorshipID
+			}
+		}
+
+		for _, reminder := range reminders {
+			plan, ok := planReminder(reminder)
+			if !ok {
+				continue
+			}
+			eventType := FamilyCalendarReminder
+			switch reminder.ReminderType {
+			case ReminderTypeBirthday, ReminderTypeDeathAnniversary:
+				eventType = FamilyCalendarAnniversary
+			case ReminderTypeMourning:
+				eventType = FamilyCalendarMourning
+			}
+			for _, date := range plan.rule.Occurrences(plan.start, from, to) {
+				add(date, &FamilyCalendarEvent{
+					Type:                eventType,
+					Title:               reminder.Title,
+					Description:         reminder.Content,
+					SourceID:            reminder.ID,
+					MemorialID:          reminder.MemorialID,
+					CollectiveWorshipID: arranged[reminder.ID],
+				})
+			}
+		}
+
+		// 已安排或进行中的追思会
+		var memorialServices []*models.MemorialService
+		err = s.db.Where("memorial_id IN ? AND status IN ? AND start_time >= ? AND start_time < ?",
+			memorialIDs, []string{"scheduled", "ongoing"}, from, to.AddDate(0, 0, 1)).
+			Order("start_time ASC").
+			Find(&memorialServices).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, service := range memorialServices {
+			startTime := service.StartTime
+			add(startTime, &FamilyCalendarEvent{
+				Type:        FamilyCalendarMemorialService,
+				Title:       service.Title,
+				Description: service.Description,
+				SourceID:    service.ID,
+				MemorialID:  service.MemorialID,
+				StartTime:   &startTime,
+			})
+		}
+	}
+
+	// 祭扫节日
+	var festivals []models.FestivalConfig
+	if err := s.db.Where("is_active = ?", true).Find(&festivals).Error; err != nil {
+		return nil, err
+	}
+	for _, festival := range festivals {
+		month, day, ok := utils.ParseFestivalDate(festival.FestivalDate)
+		if !ok {
+			continue
+		}
+		schedule := utils.TraditionSchedule{
+			Calendar:   utils.CalendarSolar,
+			Recurrence: utils.RecurrenceYearly,
+			Month:      int(month),
+			Day:        day,
+		}
+		for _, date := range schedule.Occurrences(from, to) {
+			add(date, &FamilyCalendarEvent{
+				Type:        FamilyCalendarFestival,
+				Title:       festival.Name,
+				Description: festival.Description,
+				SourceID:    festival.ID,
+			})
+		}
+	}
+
+	// 按日期排列，同一天追思会按开始时间
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].Date != events[j].Date {
+			return events[i].Date < events[j].Date
+		}
+		if events[i].StartTime != nil && events[j].StartTime != nil {
+			return events[i].StartTime.Before(*events[j].StartTime)
+		}
+		return false
+	})
+	return events, nil
+}
+
 // 删除家族传统
 func (s *FamilyService) DeleteFamilyTradition(userID, familyID, traditionID string) error {
 	// 验证权限（管理员可以删除传统）
-	if !s.isFamilyAdmin(userID, familyID) {
-		return errors.New("只有管理员可以删除家族传统")
+	if err := s.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionEditTradition); err != nil {
+		return err
 	}
 
 	var tradition models.FamilyTradition
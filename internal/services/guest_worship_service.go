@@ -0,0 +1,357 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+	"yun-nian-memorial/internal/models"
+	"yun-nian-memorial/internal/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// GuestWorshipService 公开纪念馆的访客匿名祭扫
+// 访客先完成算术验证题换取设备令牌，之后凭令牌献花、点烛、上香、供奉，
+// 祭扫记录归属匿名访客用户，并以 GuestID 区分不同设备
+type GuestWorshipService struct {
+	db            *gorm.DB
+	contentFilter *ContentFilterService
+}
+
+func NewGuestWorshipService(db *gorm.DB) *GuestWorshipService {
+	return &GuestWorshipService{
+		db: db,
+	}
+}
+
+// SetContentFilter 设置敏感词过滤服务，访客祭扫留言需经过检测
+func (s *GuestWorshipService) SetContentFilter(contentFilter *ContentFilterService) {
+	s.contentFilter = contentFilter
+}
+
+// 匿名访客用户，访客祭扫记录的 UserID 统一指向该用户
+const (
+	AnonymousGuestUserID   = "00000000-0000-0000-0000-000000000000"
+	anonymousGuestOpenID   = "guest:anonymous"
+	anonymousGuestNickname = "匿名访客"
+)
+
+// 访客祭扫限制，比注册用户更严格
+const (
+	guestChallengeTTL = 5 * time.Minute
+	guestIdentityTTL  = 30 * 24 * time.Hour
+
+	guestChallengesPerIPHour    = 20 // 每个IP每小时可获取的验证题数
+	guestIdentitiesPerIPDay     = 5  // 每个IP每天可创建的访客身份数
+	guestWorshipPerHour         = 10 // 每个访客每小时祭扫次数
+	guestWorshipPerMemorialHour = 3  // 每个访客每小时在同一纪念馆的祭扫次数
+	guestMessageMaxLength       = 100
+)
+
+// 访客验证题
+type GuestChallengeResponse struct {
+	ChallengeID string    `json:"challenge_id"`
+	Question    string    `json:"question"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// 创建访客身份请求
+type CreateGuestSessionRequest struct {
+	ChallengeID string `json:"challenge_id" binding:"required"`
+	Answer      string `json:"answer" binding:"required"`
+}
+
+// 访客身份，Token 只在创建时返回一次
+type GuestSession struct {
+	GuestID     string    `json:"guest_id"`
+	DisplayName string    `json:"display_name"`
+	Token       string    `json:"token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// 访客祭扫设置
+type GuestWorshipSettings struct {
+	MemorialID        string `json:"memorial_id"`
+	AllowGuestWorship bool   `json:"allow_guest_worship"`
+	IsPublic          bool   `json:"is_public"` // 仅公开纪念馆开放访客祭扫
+	GuestWorshipCount int64  `json:"guest_worship_count"`
+}
+
+// 更新访客祭扫设置请求
+type UpdateGuestWorshipSettingsRequest struct {
+	AllowGuestWorship *bool `json:"allow_guest_worship" binding:"required"`
+}
+
+// CreateChallenge 生成访客验证题
+func (s *GuestWorshipService) CreateChallenge(ip string) (*GuestChallengeResponse, error) {
+	var count int64
+	s.db.Model(&models.GuestChallenge{}).
+		Where("ip_address = ? AND created_at > ?", ip, time.Now().Add(-time.Hour)).
+		Count(&count)
+	if count >= guestChallengesPerIPHour {
+		return nil, errors.New("请求过于频繁，请稍后再试")
+	}
+
+	arithmetic, err := utils.NewArithmeticChallenge()
+	if err != nil {
+		return nil, err
+	}
+
+	challenge := &models.GuestChallenge{
+		ID:        uuid.New().String(),
+		Question:  arithmetic.Question,
+		IPAddress: ip,
+		ExpiresAt: time.Now().Add(guestChallengeTTL),
+		CreatedAt: time.Now(),
+	}
+	challenge.AnswerHash = utils.HashChallengeAnswer(challenge.ID, arithmetic.Answer)
+
+	if err := s.db.Create(challenge).Error; err != nil {
+		return nil, err
+	}
+
+	return &GuestChallengeResponse{
+		ChallengeID: challenge.ID,
+		Question:    challenge.Question,
+		ExpiresAt:   challenge.ExpiresAt,
+	}, nil
+}
+
+// CreateSession 校验验证题答案并签发访客设备令牌，每道验证题只能作答一次
+func (s *GuestWorshipService) CreateSession(ip, userAgent string, req *CreateGuestSessionRequest) (*GuestSession, error) {
+	var count int64
+	s.db.Model(&models.GuestIdentity{}).
+		Where("ip_address = ? AND created_at > ?", ip, time.Now().Add(-24*time.Hour)).
+		Count(&count)
+	if count >= guestIdentitiesPerIPDay {
+		return nil, errors.New("访客身份创建过于频繁，请稍后再试")
+	}
+
+	// 先占用验证题，答错也会失效，防止穷举
+	now := time.Now()
+	result := s.db.Model(&models.GuestChallenge{}).
+		Where("id = ? AND used_at IS NULL AND expires_at > ?", req.ChallengeID, now).
+		Update("used_at", now)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("验证题不存在或已失效")
+	}
+
+	var challenge models.GuestChallenge
+	if err := s.db.First(&challenge, "id = ?", req.ChallengeID).Error; err != nil {
+		return nil, err
+	}
+	if !utils.CheckChallengeAnswer(challenge.ID, req.Answer, challenge.AnswerHash) {
+		return nil, errors.New("验证答案错误，请重新获取验证题")
+	}
+
+	if err := s.ensureAnonymousUser(); err != nil {
+		return nil, err
+	}
+
+	token, err := utils.NewGuestToken()
+	if err != nil {
+		return nil, err
+	}
+
+	identity := &models.GuestIdentity{
+		ID:        uuid.New().String(),
+		TokenHash: utils.HashGuestToken(token),
+		IPAddress: ip,
+		UserAgent: truncateRunes(userAgent, 255),
+		ExpiresAt: now.Add(guestIdentityTTL),
+		CreatedAt: now,
+	}
+	identity.DisplayName = "访客" + strings.ToUpper(identity.ID[len(identity.ID)-4:])
+
+	if err := s.db.Create(identity).Error; err != nil {
+		return nil, err
+	}
+
+	return &GuestSession{
+		GuestID:     identity.ID,
+		DisplayName: identity.DisplayName,
+		Token:       token,
+		ExpiresAt:   identity.ExpiresAt,
+	}, nil
+}
+
+// ResolveGuest 根据设备令牌查找访客身份
+func (s *GuestWorshipService) ResolveGuest(token string) (*models.GuestIdentity, error) {
+	if token == "" {
+		return nil, errors.New("访客身份无效或已过期")
+	}
+
+	var identity models.GuestIdentity
+	err := s.db.Where("token_hash = ? AND expires_at > ?", utils.HashGuestToken(token), time.Now()).First(&identity).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("访客身份无效或已过期")
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	s.db.Model(&identity).Update("last_seen_at", now)
+	identity.LastSeenAt = &now
+	return &identity, nil
+}
+
+// OfferFlowers 访客献花
+func (s *GuestWorshipService) OfferFlowers(token, memorialID string, req *OfferFlowersRequest) error {
+	content, err := buildFlowerContent(req)
+	if err != nil {
+		return err
+	}
+	return s.worship(token, memorialID, "flower", req.Message, content)
+}
+
+// LightCandle 访客点烛
+func (s *GuestWorshipService) LightCandle(token, memorialID string, req *LightCandleRequest) error {
+	return s.worship(token, memorialID, "candle", req.Message, buildCandleContent(req))
+}
+
+// OfferIncense 访客上香
+func (s *GuestWorshipService) OfferIncense(token, memorialID string, req *OfferIncenseRequest) error {
+	return s.worship(token, memorialID, "incense", req.Message, buildIncenseContent(req))
+}
+
+// OfferTribute 访客供奉供品
+func (s *GuestWorshipService) OfferTribute(token, memorialID string, req *OfferTributeRequest) error {
+	return s.worship(token, memorialID, "tribute", req.Message, buildTributeContent(req))
+}
+
+// worship 校验访客身份、纪念馆设置和频率限制后保存祭扫记录
+func (s *GuestWorshipService) worship(token, memorialID, worshipType, message string, content interface{}) error {
+	guest, err := s.ResolveGuest(token)
+	if err != nil {
+		return err
+	}
+
+	if err := s.validateGuestWorship(guest, memorialID); err != nil {
+		return err
+	}
+
+	if len([]rune(message)) > guestMessageMaxLength {
+		return errors.New("访客留言不能超过100字")
+	}
+	screen, err := s.contentFilter.Screen(ContentTypeGuestWorship, AnonymousGuestUserID, memorialID, message)
+	if err != nil {
+		return err
+	}
+
+	contentJSON, _ := json.Marshal(content)
+
+	record := &models.WorshipRecord{
+		ID:          uuid.New().String(),
+		MemorialID:  memorialID,
+		UserID:      AnonymousGuestUserID,
+		GuestID:     guest.ID,
+		WorshipType: worshipType,
+		Content:     string(contentJSON),
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	if err := createWorshipRecord(s.db, record); err != nil {
+		return err
+	}
+	s.contentFilter.RecordFlagged(screen, record.ID)
+
+	return nil
+}
+
+// validateGuestWorship 仅公开且开放访客祭扫的纪念馆允许访客祭扫
+func (s *GuestWorshipService) validateGuestWorship(guest *models.GuestIdentity, memorialID string) error {
+	var memorial models.Memorial
+	err := s.db.First(&memorial, "id = ? AND status = ?", memorialID, 1).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("纪念馆不存在")
+		}
+		return err
+	}
+
+	if memorial.PrivacyLevel != PrivacyLevelPublic || !memorial.AllowGuestWorship {
+		return errors.New("该纪念馆未开放访客祭扫")
+	}
+
+	since := time.Now().Add(-time.Hour)
+	var total, onMemorial int64
+	s.db.Model(&models.WorshipRecord{}).
+		Where("guest_id = ? AND created_at > ?", guest.ID, since).
+		Count(&total)
+	if total >= guestWorshipPerHour {
+		return errors.New("访客祭扫过于频繁，请稍后再试")
+	}
+	s.db.Model(&models.WorshipRecord{}).
+		Where("guest_id = ? AND memorial_id = ? AND created_at > ?", guest.ID, memorialID, since).
+		Count(&onMemorial)
+	if onMemorial >= guestWorshipPerMemorialHour {
+		return errors.New("访客祭扫过于频繁，请稍后再试")
+	}
+
+	return nil
+}
+
+// ensureAnonymousUser 确保匿名访客用户存在，访客祭扫记录关联到该用户
+func (s *GuestWorshipService) ensureAnonymousUser() error {
+	user := models.User{
+		ID:           AnonymousGuestUserID,
+		WechatOpenID: anonymousGuestOpenID,
+		Nickname:     anonymousGuestNickname,
+	}
+	return s.db.Where("id = ?", AnonymousGuestUserID).FirstOrCreate(&user).Error
+}
+
+// GetGuestWorshipSettings 获取纪念馆的访客祭扫设置
+func (s *GuestWorshipService) GetGuestWorshipSettings(userID, memorialID string) (*GuestWorshipSettings, error) {
+	memorial, err := s.validateOwner(userID, memorialID)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := &GuestWorshipSettings{
+		MemorialID:        memorialID,
+		AllowGuestWorship: memorial.AllowGuestWorship,
+		IsPublic:          memorial.PrivacyLevel == PrivacyLevelPublic,
+	}
+	s.db.Model(&models.WorshipRecord{}).
+		Where("memorial_id = ? AND guest_id <> ''", memorialID).
+		Count(&settings.GuestWorshipCount)
+
+	return settings, nil
+}
+
+// UpdateGuestWorshipSettings 开启或关闭访客祭扫
+func (s *GuestWorshipService) UpdateGuestWorshipSettings(userID, memorialID string, req *UpdateGuestWorshipSettingsRequest) (*GuestWorshipSettings, error) {
+	if _, err := s.validateOwner(userID, memorialID); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Model(&models.Memorial{}).Where("id = ?", memorialID).
+		Update("allow_guest_worship", *req.AllowGuestWorship).Error; err != nil {
+		return nil, err
+	}
+
+	return s.GetGuestWorshipSettings(userID, memorialID)
+}
+
+func (s *GuestWorshipService) validateOwner(userID, memorialID string) (*models.Memorial, error) {
+	var memorial models.Memorial
+	err := s.db.First(&memorial, "id = ? AND status = ?", memorialID, 1).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("纪念馆不存在")
+		}
+		return nil, err
+	}
+	if memorial.CreatorID != userID {
+		return nil, errors.New("只有纪念馆创建者可以设置访客祭扫")
+	}
+	return &memorial, nil
+}
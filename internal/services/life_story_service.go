@@ -4,13 +4,15 @@ import (
 	"errors"
 	"time"
 	"yun-nian-memorial/internal/models"
+	"yun-nian-memorial/internal/utils"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 type LifeStoryService struct {
-	db *gorm.DB
+	db            *gorm.DB
+	contentFilter *ContentFilterService
 }
 
 func NewLifeStoryService(db *gorm.DB) *LifeStoryService {
@@ -19,6 +21,11 @@ func NewLifeStoryService(db *gorm.DB) *LifeStoryService {
 	}
 }
 
+// SetContentFilter 设置敏感词过滤服务依赖
+func (s *LifeStoryService) SetContentFilter(contentFilter *ContentFilterService) {
+	s.contentFilter = contentFilter
+}
+
 // 创建生平故事请求
 type CreateLifeStoryRequest struct {
 	Title     string     `json:"title" binding:"required"`
@@ -66,6 +73,12 @@ func (s *LifeStoryService) CreateLifeStory(userID, memorialID string, req *Creat
 		return nil, err
 	}
 
+	// 敏感词检测
+	screen, err := s.contentFilter.Screen(ContentTypeLifeStory, userID, memorialID, req.Title, req.Content)
+	if err != nil {
+		return nil, err
+	}
+
 	tx := s.db.Begin()
 
 	story := &models.LifeStory{
@@ -111,6 +124,7 @@ func (s *LifeStoryService) CreateLifeStory(userID, memorialID string, req *Creat
 	if err := tx.Commit().Error; err != nil {
 		return nil, err
 	}
+	s.contentFilter.RecordFlagged(screen, story.ID)
 
 	// 重新查询包含媒体文件的故事
 	s.db.Preload("Media", func(db *gorm.DB) *gorm.DB {
@@ -191,6 +205,12 @@ func (s *LifeStoryService) UpdateLifeStory(userID, storyID string, req *UpdateLi
 		}
 	}
 
+	// 敏感词检测
+	screen, err := s.contentFilter.Screen(ContentTypeLifeStory, userID, story.MemorialID, req.Title, req.Content)
+	if err != nil {
+		return err
+	}
+
 	// 更新字段
 	updates := make(map[string]interface{})
 	if req.Title != "" {
@@ -216,7 +236,11 @@ func (s *LifeStoryService) UpdateLifeStory(userID, storyID string, req *UpdateLi
 	}
 	updates["updated_at"] = time.Now()
 
-	return s.db.Model(&story).Updates(updates).Error
+	if err := s.db.Model(&story).Updates(updates).Error; err != nil {
+		return err
+	}
+	s.contentFilter.RecordFlagged(screen, story.ID)
+	return nil
 }
 
 // 删除生平故事
@@ -362,14 +386,8 @@ func (s *LifeStoryService) validateMemorialAccess(userID, memorialID string) err
 	// 检查隐私设置
 	if memorial.PrivacyLevel == 2 { // 私密
 		if memorial.CreatorID != userID {
-			// 检查是否是家族成员
-			var count int64
-			s.db.Table("family_members fm").
-				Joins("JOIN memorial_families mf ON fm.family_id = mf.family_id").
-				Where("mf.memorial_id = ? AND fm.user_id = ?", memorialID, userID).
-				Count(&count)
-			
-			if count == 0 {
+			// 检查是否是家族成员（含宗族与支系继承的成员身份）
+			if !utils.NewPermissionManager(s.db).IsMemorialFamilyMember(userID, memorialID) {
 				return errors.New("无权访问此纪念馆")
 			}
 		}
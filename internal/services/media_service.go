@@ -1,8 +1,13 @@
 package services
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"mime/multipart"
+	"path/filepath"
+	"strings"
 	"yun-nian-memorial/internal/models"
 	"yun-nian-memorial/internal/utils"
 
@@ -28,6 +33,8 @@ type MediaFileResponse struct {
 	FileName    string `json:"file_name"`
 	FileSize    int64  `json:"file_size"`
 	Description string `json:"description"`
+	Duration    int    `json:"duration"`
+	PosterURL   string `json:"poster_url"`
 	CreatedAt   string `json:"created_at"`
 }
 
@@ -138,6 +145,12 @@ func (s *MediaService) UploadVideo(userID string, req *UploadMediaRequest, file
 		Description: req.Description,
 	}
 
+	// 探测真实时长与编码，不可播放的格式直接拒绝
+	if err := s.processMediaFile(mediaFile, relativePath); err != nil {
+		s.fileUploadManager.DeleteFile(relativePath)
+		return nil, err
+	}
+
 	// 只有在提供了 memorial_id 时才保存到数据库
 	if req.MemorialID != "" {
 		if err := s.db.Create(mediaFile).Error; err != nil {
@@ -193,6 +206,12 @@ func (s *MediaService) UploadAudio(userID string, req *UploadMediaRequest, file
 		Description: req.Description,
 	}
 
+	// 探测真实时长与编码，不可播放的格式直接拒绝
+	if err := s.processMediaFile(mediaFile, relativePath); err != nil {
+		s.fileUploadManager.DeleteFile(relativePath)
+		return nil, err
+	}
+
 	// 只有在提供了 memorial_id 时才保存到数据库
 	if req.MemorialID != "" {
 		if err := s.db.Create(mediaFile).Error; err != nil {
@@ -250,6 +269,8 @@ func (s *MediaService) GetMediaFiles(userID, memorialID string, fileType string,
 			FileName:    file.FileName,
 			FileSize:    file.FileSize,
 			Description: file.Description,
+			Duration:    file.Duration,
+			PosterURL:   file.PosterURL,
 			CreatedAt:   file.CreatedAt.Format("2006-01-02 15:04:05"),
 		})
 	}
@@ -347,3 +368,107 @@ func (s *MediaService) GetMediaFileStats(userID, memorialID string) (map[string]
 
 	return stats, nil
 }
+
+// ResolveMessageMedia 校验留言引用的媒体文件并返回探测后的文件记录
+// 媒体必须已上传到该纪念馆且类型与留言类型一致，时长以服务端探测结果为准
+func (s *MediaService) ResolveMessageMedia(memorialID, messageType, mediaFileID, mediaURL string) (*models.MediaFile, error) {
+	query := s.db.Where("memorial_id = ?", memorialID)
+	if mediaFileID != "" {
+		query = query.Where("id = ?", mediaFileID)
+	} else {
+		query = query.Where("file_url = ?", mediaURL)
+	}
+
+	var mediaFile models.MediaFile
+	if err := query.First(&mediaFile).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("媒体文件不存在或不属于该纪念馆")
+		}
+		return nil, fmt.Errorf("查询媒体文件失败: %v", err)
+	}
+
+	if mediaFile.FileType != messageType {
+		return nil, fmt.Errorf("媒体文件类型与留言类型不符")
+	}
+
+	// 早期上传的文件没有探测信息，此处补做
+	if mediaFile.ProbeStatus != MediaProbeStatusReady {
+		relativePath, err := s.fileUploadManager.GetRelativePath(mediaFile.FileURL)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.processMediaFile(&mediaFile, relativePath); err != nil {
+			s.db.Model(&mediaFile).Update("probe_status", MediaProbeStatusFailed)
+			return nil, err
+		}
+		if err := s.db.Save(&mediaFile).Error; err != nil {
+			return nil, fmt.Errorf("保存媒体探测结果失败: %v", err)
+		}
+	}
+
+	return &mediaFile, nil
+}
+
+// GetMediaFilesByIDs 批量获取媒体文件
+func (s *MediaService) GetMediaFilesByIDs(ids []string) (map[string]*models.MediaFile, error) {
+	result := make(map[string]*models.MediaFile)
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	var files []models.MediaFile
+	if err := s.db.Where("id IN ?", ids).Find(&files).Error; err != nil {
+		return nil, err
+	}
+	for i := range files {
+		result[files[i].ID] = &files[i]
+	}
+	return result, nil
+}
+
+// 媒体探测状态
+const (
+	MediaProbeStatusReady  = "ready"
+	MediaProbeStatusFailed = "failed"
+)
+
+// processMediaFile 探测音视频文件并生成波形或封面
+func (s *MediaService) processMediaFile(mediaFile *models.MediaFile, relativePath string) error {
+	localPath := s.fileUploadManager.GetLocalPath(relativePath)
+
+	probe, err := utils.ProbeMediaFile(localPath)
+	if err != nil {
+		return fmt.Errorf("媒体文件解析失败: %v", err)
+	}
+	if err := utils.ValidatePlayableMedia(probe, mediaFile.FileType); err != nil {
+		return err
+	}
+
+	mediaFile.Container = probe.Container
+	mediaFile.Codec = probe.Codec()
+	mediaFile.Duration = probe.DurationSeconds()
+	mediaFile.Width = probe.Width
+	mediaFile.Height = probe.Height
+	mediaFile.ProbeStatus = MediaProbeStatusReady
+
+	// 波形和封面只用于展示，生成失败不影响留言
+	switch mediaFile.FileType {
+	case "audio":
+		peaks, err := utils.GenerateWaveform(localPath, probe, utils.DefaultWaveformPoints)
+		if err != nil {
+			log.Printf("生成音频波形失败 file=%s: %v", mediaFile.ID, err)
+			break
+		}
+		waveformJSON, _ := json.Marshal(peaks)
+		mediaFile.Waveform = string(waveformJSON)
+	case "video":
+		posterPath := strings.TrimSuffix(relativePath, filepath.Ext(relativePath)) + "_poster.jpg"
+		if err := utils.GeneratePosterFrame(localPath, s.fileUploadManager.GetLocalPath(posterPath), probe); err != nil {
+			log.Printf("生成视频封面失败 file=%s: %v", mediaFile.ID, err)
+			break
+		}
+		mediaFile.PosterURL = s.fileUploadManager.GetFileURL(posterPath)
+	}
+
+	return nil
+}
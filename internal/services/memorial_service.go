@@ -61,6 +61,8 @@ func convertFlexibleDateToTimePtr(fd *FlexibleDate) *time.Time {
 type MemorialService struct {
 	db                *gorm.DB
 	permissionManager *utils.PermissionManager
+	contentFilter     *ContentFilterService
+	mourning          *MourningService
 }
 
 type CreateMemorialRequest struct {
@@ -108,6 +110,16 @@ func NewMemorialService(db *gorm.DB) *MemorialService {
 	}
 }
 
+// SetContentFilter 设置敏感词过滤服务依赖
+func (s *MemorialService) SetContentFilter(contentFilter *ContentFilterService) {
+	s.contentFilter = contentFilter
+}
+
+// SetMourningService 设置守丧日程服务依赖（逝世日期变化时重新推算或提示生成）
+func (s *MemorialService) SetMourningService(mourning *MourningService) {
+	s.mourning = mourning
+}
+
 // CreateMemorial 创建纪念馆
 func (s *MemorialService) CreateMemorial(userID string, req *CreateMemorialRequest) (*models.Memorial, error) {
 	// 验证输入参数
@@ -115,6 +127,12 @@ func (s *MemorialService) CreateMemorial(userID string, req *CreateMemorialReque
 		return nil, err
 	}
 
+	// 墓志铭敏感词检测
+	screen, err := s.contentFilter.Screen(ContentTypeEpitaph, userID, "", req.Epitaph)
+	if err != nil {
+		return nil, err
+	}
+
 	// 创建纪念馆
 	memorial := &models.Memorial{
 		ID:             utils.GenerateUUID(),
@@ -134,6 +152,11 @@ func (s *MemorialService) CreateMemorial(userID string, req *CreateMemorialReque
 	if err := s.db.Create(memorial).Error; err != nil {
 		return nil, fmt.Errorf("创建纪念馆失败: %v", err)
 	}
+	screen.MemorialID = memorial.ID
+	s.contentFilter.RecordFlagged(screen, memorial.ID)
+	if memorial.DeathDate != nil {
+		s.mourning.HandleDeathDateChanged(memorial.ID)
+	}
 
 	// 预加载创建者信息
 	if err := s.db.Preload("Creator").Where("id = ?", memorial.ID).First(memorial).Error; err != nil {
@@ -209,6 +232,12 @@ func (s *MemorialService) UpdateMemorial(userID, memorialID string, req *UpdateM
 		return err
 	}
 
+	// 墓志铭敏感词检测
+	screen, err := s.contentFilter.Screen(ContentTypeEpitaph, userID, memorialID, req.Epitaph)
+	if err != nil {
+		return err
+	}
+
 	// 构建更新数据
 	updates := make(map[string]interface{})
 	if req.DeceasedName != "" {
@@ -243,10 +272,21 @@ func (s *MemorialService) UpdateMemorial(userID, memorialID string, req *UpdateM
 		return fmt.Errorf("没有需要更新的信息")
 	}
 
+	// 记下原逝世日期，修改后同步守丧日程
+	var previous models.Memorial
+	if req.DeathDate != nil {
+		s.db.Select("id", "death_date").First(&previous, "id = ?", memorialID)
+	}
+
 	// 执行更新
 	if err := s.db.Model(&models.Memorial{}).Where("id = ?", memorialID).Updates(updates).Error; err != nil {
 		return fmt.Errorf("更新纪念馆失败: %v", err)
 	}
+	s.contentFilter.RecordFlagged(screen, memorialID)
+	if deathDate := convertFlexibleDateToTimePtr(req.DeathDate); deathDate != nil &&
+		(previous.DeathDate == nil || previous.DeathDate.Format("2006-01-02") != deathDate.Format("2006-01-02")) {
+		s.mourning.HandleDeathDateChanged(memorialID)
+	}
 
 	return nil
 }
@@ -593,12 +633,19 @@ func (s *MemorialService) UpdateEpitaph(userID, memorialID, epitaph string) erro
 		return fmt.Errorf("墓志铭不能超过500个字符")
 	}
 
+	// 敏感词检测
+	screen, err := s.contentFilter.Screen(ContentTypeEpitaph, userID, memorialID, epitaph)
+	if err != nil {
+		return err
+	}
+
 	// 更新墓志铭
 	if err := s.db.Model(&models.Memorial{}).
 		Where("id = ?", memorialID).
 		Update("epitaph", epitaph).Error; err != nil {
 		return fmt.Errorf("更新墓志铭失败: %v", err)
 	}
+	s.contentFilter.RecordFlagged(screen, memorialID)
 
 	return nil
 }
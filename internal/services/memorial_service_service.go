@@ -7,13 +7,16 @@ import (
 	"math/rand"
 	"time"
 	"yun-nian-memorial/internal/models"
+	"yun-nian-memorial/internal/utils"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 type MemorialServiceService struct {
-	db *gorm.DB
+	db            *gorm.DB
+	contentFilter *ContentFilterService
+	notifications *NotificationService
 }
 
 func NewMemorialServiceService(db *gorm.DB) *MemorialServiceService {
@@ -22,6 +25,16 @@ func NewMemorialServiceService(db *gorm.DB) *MemorialServiceService {
 	}
 }
 
+// SetNotificationService 设置通知服务依赖（追思会邀请）
+func (s *MemorialServiceService) SetNotificationService(notifications *NotificationService) {
+	s.notifications = notifications
+}
+
+// SetContentFilter 设置敏感词过滤服务依赖
+func (s *MemorialServiceService) SetContentFilter(contentFilter *ContentFilterService) {
+	s.contentFilter = contentFilter
+}
+
 // 创建追思会请求
 type CreateMemorialServiceRequest struct {
 	Title           string    `json:"title" binding:"required"`
@@ -267,6 +280,7 @@ func (s *MemorialServiceService) InviteParticipants(userID, serviceID string, re
 
 	tx := s.db.Begin()
 
+	var invitedIDs []string
 	for _, inviteeID := range req.UserIDs {
 		// 检查是否已经邀请过
 		var existingInvitation models.ServiceInvitation
@@ -295,9 +309,26 @@ func (s *MemorialServiceService) InviteParticipants(userID, serviceID string, re
 			tx.Rollback()
 			return err
 		}
+		invitedIDs = append(invitedIDs, inviteeID)
 	}
 
-	return tx.Commit().Error
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	var inviter models.User
+	s.db.Select("id", "nickname").First(&inviter, "id = ?", userID)
+	err = s.notifications.Notify(invitedIDs, &NotificationInput{
+		Category: NotificationCategoryInvitation,
+		Type:     NotificationTypeServiceInvitation,
+		Title:    "追思会邀请",
+		Content:  fmt.Sprintf("%s邀请您参加追思会“%s”，%s开始", inviter.Nickname, service.Title, service.StartTime.Format("2006-01-02 15:04")),
+		Data:     map[string]interface{}{"service_id": serviceID, "inviter_id": userID},
+	})
+	if err != nil {
+		fmt.Printf("发送追思会邀请通知失败: %v\n", err)
+	}
+	return nil
 }
 
 // 响应邀请
@@ -443,6 +474,14 @@ func (s *MemorialServiceService) SendChatMessage(userID, serviceID string, req *
 		return nil, errors.New("您不是此追思会的参与者")
 	}
 
+	// 敏感词检测
+	var memorialID string
+	s.db.Model(&models.MemorialService{}).Where("id = ?", serviceID).Pluck("memorial_id", &memorialID)
+	screen, err := s.contentFilter.Screen(ContentTypeChat, userID, memorialID, req.Content)
+	if err != nil {
+		return nil, err
+	}
+
 	message := &models.ServiceChat{
 		ID:          uuid.New().String(),
 		ServiceID:   serviceID,
@@ -456,6 +495,7 @@ func (s *MemorialServiceService) SendChatMessage(userID, serviceID string, req *
 	if err := s.db.Create(message).Error; err != nil {
 		return nil, err
 	}
+	s.contentFilter.RecordFlagged(screen, message.ID)
 
 	// 重新查询包含用户信息的消息
 	s.db.Preload("User").First(message, "id = ?", message.ID)
@@ -582,14 +622,8 @@ func (s *MemorialServiceService) validateMemorialAccess(userID, memorialID strin
 	// 检查隐私设置
 	if memorial.PrivacyLevel == 2 { // 私密
 		if memorial.CreatorID != userID {
-			// 检查是否是家族成员
-			var count int64
-			s.db.Table("family_members fm").
-				Joins("JOIN memorial_families mf ON fm.family_id = mf.family_id").
-				Where("mf.memorial_id = ? AND fm.user_id = ?", memorialID, userID).
-				Count(&count)
-			
-			if count == 0 {
+			// 检查是否是家族成员（含宗族与支系继承的成员身份）
+			if !utils.NewPermissionManager(s.db).IsMemorialFamilyMember(userID, memorialID) {
 				return errors.New("无权访问此纪念馆")
 			}
 		}
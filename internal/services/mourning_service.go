@@ -0,0 +1,618 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"yun-nian-memorial/internal/models"
+	"yun-nian-memorial/internal/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 守丧规则在系统配置中的存储位置，每套规则一条，值为 utils.MourningRuleSet 的 JSON
+const (
+	MourningRuleSetConfigType   = "mourning_rule_set"
+	mourningRuleSetConfigPrefix = "mourning_rule_sets."
+)
+
+// 守丧日程的默认值
+const (
+	defaultMourningStartTime = "10:00" // 为某一天安排集体祭扫时的默认开始时刻
+)
+
+// 守丧日程生成的提醒默认提前一天和当天各提醒一次，便于提前准备供品
+var defaultMourningLeadDays = []int{1, 0}
+
+type MourningService struct {
+	db                       *gorm.DB
+	familyService            *FamilyService
+	collectiveWorshipService *CollectiveWorshipService
+	notifications            *NotificationService
+	location                 *time.Location
+}
+
+func NewMourningService(db *gorm.DB) *MourningService {
+	return &MourningService{
+		db:       db,
+		location: time.Local,
+	}
+}
+
+// SetFamilyService 设置家族服务依赖（权限校验、家族动态）
+func (s *MourningService) SetFamilyService(familyService *FamilyService) {
+	s.familyService = familyService
+}
+
+// SetCollectiveWorshipService 设置集体祭扫服务依赖（为守丧日程安排集体祭扫）
+func (s *MourningService) SetCollectiveWorshipService(collectiveWorshipService *CollectiveWorshipService) {
+	s.collectiveWorshipService = collectiveWorshipService
+}
+
+// SetNotificationService 设置通知服务依赖（填写逝世日期后提示生成守丧日程）
+func (s *MourningService) SetNotificationService(notifications *NotificationService) {
+	s.notifications = notifications
+}
+
+// 生成守丧日程请求
+type GenerateMourningScheduleRequest struct {
+	RuleSet    string   `json:"rule_set"`    // 守丧规则，默认 secular 民间通行
+	Keys       []string `json:"keys"`        // 只生成其中几项，如 ["first_seven","hundred_days"]，为空生成全部
+	LeadDays   []int    `json:"lead_days"`   // 提前提醒天数，默认 [1,0]
+	RemindTime string   `json:"remind_time"` // 提醒发送时刻 HH:MM，默认 09:00
+}
+
+// 为守丧日程的某一天安排集体祭扫请求
+type MourningCollectiveWorshipRequest struct {
+	StartTime           string `json:"start_time"`                                               // 开始时刻 HH:MM，默认 10:00
+	DurationMinutes     int    `json:"duration_minutes" binding:"omitempty,min=5,max=180"`       // 默认30分钟
+	RemindBeforeMinutes *int   `json:"remind_before_minutes" binding:"omitempty,min=0,max=1440"` // 默认提前30分钟，0表示不提醒
+	SilenceSeconds      int    `json:"silence_seconds" binding:"omitempty,min=10,max=600"`       // 默认60秒
+}
+
+// MourningScheduleView 纪念馆的守丧日程，尚未生成时为按规则推算的预览
+type MourningScheduleView struct {
+	*models.MourningSchedule
+	Generated bool `json:"generated"`
+}
+
+// GetMourningRuleSets 获取可用的守丧规则：内置规则，系统配置中同名的规则覆盖内置规则，其余追加在后
+func (s *MourningService) GetMourningRuleSets() ([]utils.MourningRuleSet, error) {
+	ruleSets := utils.DefaultMourningRuleSets()
+	index := make(map[string]int)
+	for i, ruleSet := range ruleSets {
+		index[ruleSet.Key] = i
+	}
+
+	var configs []models.SystemConfig
+	err := s.db.Where("config_type = ? AND is_active = ?", MourningRuleSetConfigType, true).
+		Order("config_key ASC").Find(&configs).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, config := range configs {
+		var ruleSet utils.MourningRuleSet
+		if err := json.Unmarshal([]byte(config.ConfigValue), &ruleSet); err != nil {
+			// 单套规则格式错误不影响其它规则
+			continue
+		}
+		ruleSet.Key = strings.TrimPrefix(config.ConfigKey, mourningRuleSetConfigPrefix)
+		if !utils.ValidMourningRuleSetKey(ruleSet.Key) || ruleSet.Validate() != nil {
+			continue
+		}
+		if i, ok := index[ruleSet.Key]; ok {
+			ruleSets[i] = ruleSet
+		} else {
+			ruleSets = append(ruleSets, ruleSet)
+		}
+	}
+	return ruleSets, nil
+}
+
+// getMourningRuleSet 按标识获取守丧规则
+func (s *MourningService) getMourningRuleSet(key string) (*utils.MourningRuleSet, error) {
+	ruleSets, err := s.GetMourningRuleSets()
+	if err != nil {
+		return nil, err
+	}
+	for i := range ruleSets {
+		if ruleSets[i].Key == key {
+			return &ruleSets[i], nil
+		}
+	}
+	return nil, errors.New("守丧规则不存在")
+}
+
+// SetMourningRuleSet 保存一套守丧规则，与内置规则同名时覆盖内置规则
+func (s *MourningService) SetMourningRuleSet(key string, ruleSet *utils.MourningRuleSet) error {
+	if !utils.ValidMourningRuleSetKey(key) {
+		return errors.New("规则标识只能包含小写字母、数字和下划线")
+	}
+	ruleSet.Key = key
+	if err := ruleSet.Validate(); err != nil {
+		return err
+	}
+
+	value, err := json.Marshal(ruleSet)
+	if err != nil {
+		return err
+	}
+	configService := NewSystemConfigService(s.db)
+	return configService.SetSystemConfig(mourningRuleSetConfigPrefix+key, string(value), MourningRuleSetConfigType, "守丧规则: "+ruleSet.Name)
+}
+
+// DeleteMourningRuleSet 删除系统配置中的守丧规则，内置规则删除后恢复默认
+func (s *MourningService) DeleteMourningRuleSet(key string) error {
+	result := s.db.Where("config_key = ? AND config_type = ?", mourningRuleSetConfigPrefix+key, MourningRuleSetConfigType).
+		Delete(&models.SystemConfig{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("守丧规则不存在")
+	}
+	return nil
+}
+
+// GetMourningSchedule 获取纪念馆的守丧日程；尚未生成或指定了其他规则时，返回按该规则推算的预览
+func (s *MourningService) GetMourningSchedule(userID, familyID, memorialID, ruleSetKey string) (*MourningScheduleView, error) {
+	if err := s.familyService.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionView); err != nil {
+		return nil, err
+	}
+	memorial, err := s.loadMemorial(familyID, memorialID)
+	if err != nil {
+		return nil, err
+	}
+	if memorial.PrivacyLevel == 2 && memorial.CreatorID != userID {
+		return nil, errors.New("无权访问此纪念馆")
+	}
+
+	var schedule models.MourningSchedule
+	err = s.db.Preload("Observances", func(db *gorm.DB) *gorm.DB {
+		return db.Order("sort_order ASC")
+	}).First(&schedule, "memorial_id = ?", memorialID).Error
+	if err == nil && (ruleSetKey == "" || ruleSetKey == schedule.RuleSet) {
+		for i := range schedule.Observances {
+			decorateMourningObservance(&schedule.Observances[i])
+		}
+		return &MourningScheduleView{MourningSchedule: &schedule, Generated: true}, nil
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// 预览
+	if memorial.DeathDate == nil {
+		return nil, errors.New("纪念馆未填写逝世日期")
+	}
+	if ruleSetKey == "" {
+		ruleSetKey = utils.MourningRuleSetSecular
+	}
+	ruleSet, err := s.getMourningRuleSet(ruleSetKey)
+	if err != nil {
+		return nil, err
+	}
+	preview := &models.MourningSchedule{
+		MemorialID:  memorialID,
+		FamilyID:    familyID,
+		RuleSet:     ruleSet.Key,
+		RuleSetName: ruleSet.Name,
+		DeathDate:   *memorial.DeathDate,
+		Observances: s.buildObservances(ruleSet, *memorial.DeathDate),
+	}
+	for i := range preview.Observances {
+		decorateMourningObservance(&preview.Observances[i])
+	}
+	return &MourningScheduleView{MourningSchedule: preview}, nil
+}
+
+// GenerateMourningSchedule 按守丧规则生成纪念馆的守丧日程，并为尚未到来的每一天生成纪念日提醒。
+// 已有日程时整体替换，日期不变的项保留已安排的集体祭扫。
+func (s *MourningService) GenerateMourningSchedule(userID, familyID, memorialID string, req *GenerateMourningScheduleRequest) (*models.MourningSchedule, error) {
+	if err := s.familyService.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionSetReminder); err != nil {
+		return nil, err
+	}
+	memorial, err := s.loadMemorial(familyID, memorialID)
+	if err != nil {
+		return nil, err
+	}
+	if memorial.DeathDate == nil {
+		return nil, errors.New("纪念馆未填写逝世日期")
+	}
+
+	ruleSetKey := req.RuleSet
+	if ruleSetKey == "" {
+		ruleSetKey = utils.MourningRuleSetSecular
+	}
+	ruleSet, err := s.getMourningRuleSet(ruleSetKey)
+	if err != nil {
+		return nil, err
+	}
+	leadDays := defaultMourningLeadDays
+	if len(req.LeadDays) > 0 {
+		if leadDays, err = utils.ParseReminderLeadDays(req.LeadDays); err != nil {
+			return nil, err
+		}
+	}
+	remindTime := defaultRemindTime
+	if req.RemindTime != "" {
+		if _, _, err := utils.ParseRemindTime(req.RemindTime); err != nil {
+			return nil, err
+		}
+		remindTime = req.RemindTime
+	}
+	leadDaysJSON, _ := json.Marshal(leadDays)
+
+	observances := s.buildObservances(ruleSet, *memorial.DeathDate)
+	if len(req.Keys) > 0 {
+		selected := make(map[string]bool)
+		for _, key := range req.Keys {
+			selected[key] = true
+		}
+		var filtered []models.MourningObservance
+		for _, observance := range observances {
+			if selected[observance.Key] {
+				filtered = append(filtered, observance)
+				delete(selected, observance.Key)
+			}
+		}
+		if len(selected) > 0 {
+			return nil, errors.New("守丧日程项不存在")
+		}
+		observances = filtered
+	}
+
+	now := time.Now()
+	today := reminderDay(now, s.location)
+	schedule := &models.MourningSchedule{
+		ID:          uuid.New().String(),
+		MemorialID:  memorialID,
+		FamilyID:    familyID,
+		CreatorID:   userID,
+		RuleSet:     ruleSet.Key,
+		RuleSetName: ruleSet.Name,
+		DeathDate:   *memorial.DeathDate,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// 替换已有日程，日期不变的项沿用已安排的集体祭扫
+		arranged := make(map[string]string)
+		var previous models.MourningSchedule
+		err := tx.Preload("Observances").First(&previous, "memorial_id = ?", memorialID).Error
+		if err == nil {
+			for _, observance := range previous.Observances {
+				if observance.CollectiveWorshipID != "" {
+					arranged[observance.Key+"|"+observance.Date.Format("2006-01-02")] = observance.CollectiveWorshipID
+				}
+			}
+			if err := removeMourningSchedule(tx, &previous); err != nil {
+				return err
+			}
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		for i := range observances {
+			observance := &observances[i]
+			observance.ID = uuid.New().String()
+			observance.ScheduleID = schedule.ID
+			observance.CollectiveWorshipID = arranged[observance.Key+"|"+observance.Date.Format("2006-01-02")]
+			observance.CreatedAt = now
+			observance.UpdatedAt = now
+			if reminderDay(observance.Date, s.location).Before(today) {
+				continue
+			}
+
+			rites, offerings := mourningSuggestions(observance)
+			reminder := &models.MemorialReminder{
+				ID:           uuid.New().String(),
+				MemorialID:   memorialID,
+				ReminderType: ReminderTypeMourning,
+				ReminderDate: observance.Date,
+				Title:        observance.Name,
+				Content:      mourningSuggestionText(observance.Description, rites, offerings),
+				IsActive:     true,
+				CreatorID:    userID,
+				Rule:         utils.ReminderRule{DaysAfterDeath: observance.Days}.String(),
+				LeadDays:     string(leadDaysJSON),
+				RemindTime:   remindTime,
+				CreatedAt:    now,
+				UpdatedAt:    now,
+			}
+			if err := tx.Create(reminder).Error; err != nil {
+				return err
+			}
+			observance.ReminderID = reminder.ID
+		}
+
+		schedule.Observances = observances
+		return tx.Create(schedule).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range schedule.Observances {
+		decorateMourningObservance(&schedule.Observances[i])
+	}
+
+	// 记录活动
+	s.familyService.recordActivity(familyID, userID, memorialID, "mourning_schedule", map[string]interface{}{
+		"rule_set":      schedule.RuleSet,
+		"rule_set_name": schedule.RuleSetName,
+		"count":         len(schedule.Observances),
+	})
+
+	return schedule, nil
+}
+
+// DeleteMourningSchedule 删除纪念馆的守丧日程及其生成的提醒，已安排的集体祭扫不受影响
+func (s *MourningService) DeleteMourningSchedule(userID, familyID, memorialID string) error {
+	if err := s.familyService.permissions.CheckFamilyPermission(userID, familyID, utils.FamilyActionSetReminder); err != nil {
+		return err
+	}
+	if _, err := s.loadMemorial(familyID, memorialID); err != nil {
+		return err
+	}
+
+	var schedule models.MourningSchedule
+	if err := s.db.Preload("Observances").First(&schedule, "memorial_id = ?", memorialID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("守丧日程不存在")
+		}
+		return err
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return removeMourningSchedule(tx, &schedule)
+	})
+}
+
+// ScheduleMourningCollectiveWorship 为守丧日程中的某一天安排家族集体祭扫，标题、说明和祭扫方式取自日程
+func (s *MourningService) ScheduleMourningCollectiveWorship(userID, familyID, memorialID, key string, req *MourningCollectiveWorshipRequest) (*models.CollectiveWorship, error) {
+	memorial, err := s.loadMemorial(familyID, memorialID)
+	if err != nil {
+		return nil, err
+	}
+
+	var schedule models.MourningSchedule
+	if err := s.db.First(&schedule, "memorial_id = ?", memorialID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("守丧日程不存在")
+		}
+		return nil, err
+	}
+	var observance models.MourningObservance
+	if err := s.db.Where(&models.MourningObservance{ScheduleID: schedule.ID, Key: key}).First(&observance).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("守丧日程项不存在")
+		}
+		return nil, err
+	}
+	if observance.CollectiveWorshipID != "" {
+		var count int64
+		s.db.Model(&models.CollectiveWorship{}).
+			Where("id = ? AND status IN ?", observance.CollectiveWorshipID, []string{CollectiveWorshipScheduled, CollectiveWorshipOngoing}).
+			Count(&count)
+		if count > 0 {
+			return nil, errors.New("这一天已安排集体祭扫")
+		}
+	}
+
+	startTime := req.StartTime
+	if startTime == "" {
+		startTime = defaultMourningStartTime
+	}
+	hour, minute, err := utils.ParseRemindTime(startTime)
+	if err != nil {
+		return nil, errors.New("无效的开始时刻")
+	}
+	date := observance.Date.In(s.location)
+	scheduledAt := time.Date(date.Year(), date.Month(), date.Day(), hour, minute, 0, 0, s.location)
+
+	rites, offerings := mourningSuggestions(&observance)
+	event, err := s.collectiveWorshipService.ScheduleCollectiveWorship(userID, familyID, &ScheduleCollectiveWorshipRequest{
+		MemorialID:          memorialID,
+		Title:               truncateRunes(memorial.DeceasedName+observance.Name+"祭奠", 100),
+		Description:         truncateRunes(mourningSuggestionText(observance.Description, rites, offerings), 500),
+		WorshipType:         observance.WorshipType,
+		ScheduledAt:         &scheduledAt,
+		DurationMinutes:     req.DurationMinutes,
+		RemindBeforeMinutes: req.RemindBeforeMinutes,
+		SilenceSeconds:      req.SilenceSeconds,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Model(&observance).Update("collective_worship_id", event.ID).Error; err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// HandleDeathDateChanged 纪念馆填写或修改逝世日期后调用：已有守丧日程时按新日期重新推算，
+// 没有时若还有未到的日子，提示纪念馆创建者生成守丧日程
+func (s *MourningService) HandleDeathDateChanged(memorialID string) {
+	if s == nil {
+		return
+	}
+
+	var memorial models.Memorial
+	err := s.db.Select("id", "creator_id", "deceased_name", "death_date").First(&memorial, "id = ?", memorialID).Error
+	if err != nil || memorial.DeathDate == nil {
+		return
+	}
+
+	var schedule models.MourningSchedule
+	err = s.db.Preload("Observances").First(&schedule, "memorial_id = ?", memorialID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		s.offerMourningSchedule(&memorial)
+		return
+	}
+	if err == nil {
+		err = s.rescheduleObservances(&schedule, *memorial.DeathDate)
+	}
+	if err != nil {
+		fmt.Printf("更新纪念馆 %s 的守丧日程失败: %v\n", memorialID, err)
+	}
+}
+
+// rescheduleObservances 按新的逝世日期重新推算各项日期和提醒日期，已安排的集体祭扫不随之移动
+func (s *MourningService) rescheduleObservances(schedule *models.MourningSchedule, deathDate time.Time) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for _, observance := range schedule.Observances {
+			date, ok := mourningObservanceRule(&observance).Date(deathDate)
+			if !ok {
+				continue
+			}
+			date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, s.location)
+			if err := tx.Model(&models.MourningObservance{}).Where("id = ?", observance.ID).Update("date", date).Error; err != nil {
+				return err
+			}
+			if observance.ReminderID != "" {
+				if err := tx.Model(&models.MemorialReminder{}).Where("id = ?", observance.ReminderID).Update("reminder_date", date).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return tx.Model(&models.MourningSchedule{}).Where("id = ?", schedule.ID).Update("death_date", deathDate).Error
+	})
+}
+
+// offerMourningSchedule 默认规则下还有未到的日子时，通知纪念馆创建者可以生成守丧日程
+func (s *MourningService) offerMourningSchedule(memorial *models.Memorial) {
+	ruleSet, err := s.getMourningRuleSet(utils.MourningRuleSetSecular)
+	if err != nil {
+		return
+	}
+	today := reminderDay(time.Now(), s.location)
+	for _, rule := range ruleSet.Observances {
+		date, ok := rule.Date(*memorial.DeathDate)
+		if !ok || date.Before(today) {
+			continue
+		}
+
+		err := s.notifications.Notify([]string{memorial.CreatorID}, &NotificationInput{
+			Category: NotificationCategoryReminder,
+			Type:     NotificationTypeMourningOffer,
+			Title:    "生成守丧日程",
+			Content:  fmt.Sprintf("已填写%s的逝世日期，可以在家族圈中生成做七、百日、周年等守丧日程，按期提醒家人", memorial.DeceasedName),
+			Data: map[string]interface{}{
+				"memorial_id": memorial.ID,
+				"next":        rule.Name,
+				"date":        date.Format("2006-01-02"),
+			},
+		})
+		if err != nil {
+			fmt.Printf("发送纪念馆 %s 守丧日程提示失败: %v\n", memorial.ID, err)
+		}
+		return
+	}
+}
+
+// loadMemorial 校验纪念馆关联到家族圈并加载
+func (s *MourningService) loadMemorial(familyID, memorialID string) (*models.Memorial, error) {
+	var relation models.MemorialFamily
+	err := s.db.Where("memorial_id = ? AND family_id = ?", memorialID, familyID).First(&relation).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("纪念馆未关联到此家族圈")
+		}
+		return nil, err
+	}
+
+	var memorial models.Memorial
+	if err := s.db.First(&memorial, "id = ?", memorialID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("纪念馆不存在")
+		}
+		return nil, err
+	}
+	return &memorial, nil
+}
+
+// buildObservances 按规则和逝世日期推算各项，日期为所在时区的零点
+func (s *MourningService) buildObservances(ruleSet *utils.MourningRuleSet, deathDate time.Time) []models.MourningObservance {
+	observances := make([]models.MourningObservance, 0, len(ruleSet.Observances))
+	for i, rule := range ruleSet.Observances {
+		date, ok := rule.Date(deathDate)
+		if !ok {
+			continue
+		}
+		rites, _ := json.Marshal(append([]string{}, rule.Rites...))
+		offerings, _ := json.Marshal(append([]string{}, rule.Offerings...))
+		observances = append(observances, models.MourningObservance{
+			Key:         rule.Key,
+			Name:        rule.Name,
+			Days:        rule.Days,
+			Years:       rule.Years,
+			Calendar:    rule.Calendar,
+			Date:        time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, s.location),
+			Description: rule.Description,
+			Rites:       string(rites),
+			Offerings:   string(offerings),
+			WorshipType: rule.WorshipType,
+			SortOrder:   i,
+		})
+	}
+	return observances
+}
+
+// removeMourningSchedule 删除守丧日程、各项及生成的提醒
+func removeMourningSchedule(tx *gorm.DB, schedule *models.MourningSchedule) error {
+	var reminderIDs []string
+	for _, observance := range schedule.Observances {
+		if observance.ReminderID != "" {
+			reminderIDs = append(reminderIDs, observance.ReminderID)
+		}
+	}
+	if len(reminderIDs) > 0 {
+		if err := tx.Where("id IN ?", reminderIDs).Delete(&models.MemorialReminder{}).Error; err != nil {
+			return err
+		}
+	}
+	if err := tx.Where("schedule_id = ?", schedule.ID).Delete(&models.MourningObservance{}).Error; err != nil {
+		return err
+	}
+	return tx.Delete(&models.MourningSchedule{}, "id = ?", schedule.ID).Error
+}
+
+// mourningObservanceRule 日程项对应的推算规则
+func mourningObservanceRule(observance *models.MourningObservance) utils.MourningObservance {
+	return utils.MourningObservance{Days: observance.Days, Years: observance.Years, Calendar: observance.Calendar}
+}
+
+// decorateMourningObservance 填充农历日期和推算方式
+func decorateMourningObservance(observance *models.MourningObservance) {
+	if lunar, ok := utils.SolarToLunar(observance.Date); ok {
+		observance.LunarDate = lunar.String()
+	}
+	observance.Timing = mourningObservanceRule(observance).Describe()
+}
+
+// mourningSuggestions 日程项建议的仪式和供品
+func mourningSuggestions(observance *models.MourningObservance) (rites, offerings []string) {
+	json.Unmarshal([]byte(observance.Rites), &rites)
+	json.Unmarshal([]byte(observance.Offerings), &offerings)
+	return rites, offerings
+}
+
+// mourningSuggestionText 说明和建议的文字，用作提醒内容和集体祭扫说明
+func mourningSuggestionText(description string, rites, offerings []string) string {
+	var parts []string
+	if description != "" {
+		parts = append(parts, description)
+	}
+	if len(rites) > 0 {
+		parts = append(parts, "建议仪式："+strings.Join(rites, "、"))
+	}
+	if len(offerings) > 0 {
+		parts = append(parts, "建议供品："+strings.Join(offerings, "、"))
+	}
+	return strings.Join(parts, "；")
+}
@@ -0,0 +1,298 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"yun-nian-memorial/internal/config"
+	"yun-nian-memorial/internal/models"
+	"yun-nian-memorial/internal/utils"
+)
+
+// 通知渠道名称
+const (
+	NotificationChannelWechat = "wechat" // 微信订阅消息
+	NotificationChannelEmail  = "email"  // 邮件
+	NotificationChannelLog    = "log"    // 日志，用于开发调试和测试
+)
+
+// ErrNotificationSkipped 渠道不适用于该接收人（未绑定、未开启、未订阅等），投递记为跳过而不是失败，不会重试
+var ErrNotificationSkipped = errors.New("通知未发送")
+
+func notificationSkipped(reason string) error {
+	return fmt.Errorf("%w: %s", ErrNotificationSkipped, reason)
+}
+
+// NotificationRecipient 通知接收人及其渠道信息
+type NotificationRecipient struct {
+	UserID  string
+	OpenID  string
+	Setting *models.NotificationSetting
+}
+
+// NotificationChannel 站外通知渠道，站内信之外的每种推送方式实现一个
+type NotificationChannel interface {
+	Name() string
+	Send(recipient *NotificationRecipient, notification *models.Notification) error
+}
+
+// WechatSubscribeMessage 小程序订阅消息
+type WechatSubscribeMessage struct {
+	ToUser     string                       `json:"touser"`
+	TemplateID string                       `json:"template_id"`
+	Page       string                       `json:"page,omitempty"`
+	Data       map[string]map[string]string `json:"data"`
+}
+
+// WechatSubscribeClient 发送订阅消息的客户端，测试时可替换为桩实现
+type WechatSubscribeClient interface {
+	SendSubscribeMessage(message *WechatSubscribeMessage) error
+}
+
+// 微信接口返回的错误码
+const (
+	wechatErrInvalidToken = 40001 // access_token 无效
+	wechatErrTokenExpired = 42001 // access_token 过期
+	wechatErrUnsubscribed = 43101 // 用户未订阅或拒绝接收
+)
+
+// wechatAPIClient 调用微信服务端接口发送订阅消息，缓存 access_token 至过期前5分钟
+type wechatAPIClient struct {
+	appID      string
+	appSecret  string
+	baseURL    string
+	httpClient *http.Client
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+// NewWechatSubscribeClient 创建调用微信接口的订阅消息客户端
+func NewWechatSubscribeClient(appID, appSecret string) WechatSubscribeClient {
+	return &wechatAPIClient{
+		appID:      appID,
+		appSecret:  appSecret,
+		baseURL:    "https://api.weixin.qq.com",
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type wechatAPIResponse struct {
+	ErrCode     int    `json:"errcode"`
+	ErrMsg      string `json:"errmsg"`
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+func (c *wechatAPIClient) SendSubscribeMessage(message *WechatSubscribeMessage) error {
+	token, err := c.accessToken()
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	var result wechatAPIResponse
+	if err := c.call(http.MethodPost, "/cgi-bin/message/subscribe/send?access_token="+url.QueryEscape(token), body, &result); err != nil {
+		return err
+	}
+	switch result.ErrCode {
+	case 0:
+		return nil
+	case wechatErrInvalidToken, wechatErrTokenExpired:
+		c.mu.Lock()
+		c.token = ""
+		c.mu.Unlock()
+	case wechatErrUnsubscribed:
+		return notificationSkipped("用户未订阅该消息")
+	}
+	return fmt.Errorf("发送订阅消息失败: %d %s", result.ErrCode, result.ErrMsg)
+}
+
+func (c *wechatAPIClient) accessToken() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && time.Now().Before(c.expiresAt) {
+		return c.token, nil
+	}
+
+	path := fmt.Sprintf("/cgi-bin/token?grant_type=client_credential&appid=%s&secret=%s",
+		url.QueryEscape(c.appID), url.QueryEscape(c.appSecret))
+	var result wechatAPIResponse
+	if err := c.call(http.MethodGet, path, nil, &result); err != nil {
+		return "", err
+	}
+	if result.ErrCode != 0 || result.AccessToken == "" {
+		return "", fmt.Errorf("获取微信access_token失败: %d %s", result.ErrCode, result.ErrMsg)
+	}
+	c.token = result.AccessToken
+	c.expiresAt = time.Now().Add(time.Duration(result.ExpiresIn)*time.Second - 5*time.Minute)
+	return c.token, nil
+}
+
+func (c *wechatAPIClient) call(method, path string, body []byte, result interface{}) error {
+	req, err := http.NewRequest(method, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("微信接口返回 %d", resp.StatusCode)
+	}
+	return json.Unmarshal(data, result)
+}
+
+// WechatChannel 通过小程序订阅消息推送，按通知分类选用模板
+type WechatChannel struct {
+	client    WechatSubscribeClient
+	templates map[string]string
+	page      string
+}
+
+// NewWechatChannel 创建微信订阅消息渠道，templates 为通知分类到模板ID的映射
+func NewWechatChannel(client WechatSubscribeClient, templates map[string]string, page string) *WechatChannel {
+	return &WechatChannel{
+		client:    client,
+		templates: templates,
+		page:      page,
+	}
+}
+
+func (c *WechatChannel) Name() string {
+	return NotificationChannelWechat
+}
+
+func (c *WechatChannel) Send(recipient *NotificationRecipient, notification *models.Notification) error {
+	templateID := c.templates[notification.Category]
+	if templateID == "" {
+		return notificationSkipped("未配置订阅消息模板")
+	}
+	if !recipient.Setting.WechatEnabled {
+		return notificationSkipped("用户已关闭微信通知")
+	}
+	if recipient.OpenID == "" || strings.HasPrefix(recipient.OpenID, "guest:") {
+		return notificationSkipped("用户未绑定微信")
+	}
+
+	page := c.page
+	if page != "" {
+		page += "?id=" + notification.ID
+	}
+	return c.client.SendSubscribeMessage(&WechatSubscribeMessage{
+		ToUser:     recipient.OpenID,
+		TemplateID: templateID,
+		Page:       page,
+		Data:       utils.WechatSubscribeData(notification.Title, notification.Content, notification.CreatedAt),
+	})
+}
+
+// EmailChannel 通过 SMTP 发送邮件，只发给填写邮箱并开启邮件通知的用户
+type EmailChannel struct {
+	cfg config.SMTPConfig
+
+	// sendMail 默认为 smtp.SendMail，测试时可替换
+	sendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// NewEmailChannel 创建邮件渠道
+func NewEmailChannel(cfg config.SMTPConfig) *EmailChannel {
+	return &EmailChannel{
+		cfg:      cfg,
+		sendMail: smtp.SendMail,
+	}
+}
+
+func (c *EmailChannel) Name() string {
+	return NotificationChannelEmail
+}
+
+func (c *EmailChannel) Send(recipient *NotificationRecipient, notification *models.Notification) error {
+	if !recipient.Setting.EmailEnabled || recipient.Setting.Email == "" {
+		return notificationSkipped("用户未开启邮件通知")
+	}
+
+	var auth smtp.Auth
+	if c.cfg.Username != "" {
+		auth = smtp.PlainAuth("", c.cfg.Username, c.cfg.Password, c.cfg.Host)
+	}
+	from := c.cfg.From
+	if from == "" {
+		from = c.cfg.Username
+	}
+	msg := utils.BuildEmailMessage(from, recipient.Setting.Email, notification.Title, notification.Content, notification.CreatedAt)
+	addr := c.cfg.Host + ":" + strconv.Itoa(c.cfg.Port)
+	return c.sendMail(addr, auth, from, []string{recipient.Setting.Email}, msg)
+}
+
+// NotificationLogEntry 日志渠道记录的一次发送
+type NotificationLogEntry struct {
+	UserID         string
+	NotificationID string
+	Category       string
+	Type           string
+	Title          string
+	Content        string
+}
+
+// LogChannel 把通知写入日志并保留在内存中，用于开发调试和测试断言
+type LogChannel struct {
+	mu      sync.Mutex
+	entries []NotificationLogEntry
+}
+
+// NewLogChannel 创建日志渠道
+func NewLogChannel() *LogChannel {
+	return &LogChannel{}
+}
+
+func (c *LogChannel) Name() string {
+	return NotificationChannelLog
+}
+
+func (c *LogChannel) Send(recipient *NotificationRecipient, notification *models.Notification) error {
+	log.Printf("通知 [%s/%s] -> %s: %s %s", notification.Category, notification.Type, recipient.UserID, notification.Title, notification.Content)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = append(c.entries, NotificationLogEntry{
+		UserID:         recipient.UserID,
+		NotificationID: notification.ID,
+		Category:       notification.Category,
+		Type:           notification.Type,
+		Title:          notification.Title,
+		Content:        notification.Content,
+	})
+	return nil
+}
+
+// Entries 返回已记录的通知
+func (c *LogChannel) Entries() []NotificationLogEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]NotificationLogEntry(nil), c.entries...)
+}
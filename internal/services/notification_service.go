@@ -0,0 +1,446 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+	"yun-nian-memorial/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 通知分类
+const (
+	NotificationCategoryInvitation    = "invitation"     // 家族圈、追思会邀请
+	NotificationCategoryAccessRequest = "access_request" // 纪念馆访问申请及处理结果
+	NotificationCategoryReminder      = "reminder"       // 集体祭扫、家族传统、时光胶囊等提醒
+	NotificationCategoryModeration    = "moderation"     // 留言、祈福的审核结果
+)
+
+// 通知类型
+const (
+	NotificationTypeFamilyInvitation    = "family_invitation"
+	NotificationTypeServiceInvitation   = "service_invitation"
+	NotificationTypeAccessRequest       = "access_request"
+	NotificationTypeAccessRequestResult = "access_request_result"
+	NotificationTypeCollectiveWorship   = "collective_worship_reminder"
+	NotificationTypeTraditionReminder   = "tradition_reminder"
+	NotificationTypeMemorialReminder    = "memorial_reminder"
+	NotificationTypeMourningOffer       = "mourning_schedule_offer"
+	NotificationTypeTimeCapsuleUnlocked = "time_capsule_unlocked"
+	NotificationTypeModerationResult    = "moderation_result"
+)
+
+// 通知投递状态
+const (
+	NotificationDeliverySent    = "sent"
+	NotificationDeliveryFailed  = "failed"
+	NotificationDeliverySkipped = "skipped"
+)
+
+// 失败的投递最多尝试次数
+const notificationMaxAttempts = 3
+
+type NotificationService struct {
+	db       *gorm.DB
+	channels []NotificationChannel
+}
+
+func NewNotificationService(db *gorm.DB) *NotificationService {
+	return &NotificationService{
+		db: db,
+	}
+}
+
+// AddChannel 添加站外通知渠道，通知保存为站内信后依次投递到各渠道
+func (s *NotificationService) AddChannel(channel NotificationChannel) {
+	s.channels = append(s.channels, channel)
+}
+
+// NotificationInput 待发送的通知
+type NotificationInput struct {
+	Category string
+	Type     string
+	Title    string
+	Content  string
+	Data     interface{} // 跳转所需的业务数据，如 family_id
+}
+
+// 通知设置更新请求
+type UpdateNotificationSettingsRequest struct {
+	Email           *string  `json:"email" binding:"omitempty,email,max=100"`
+	EmailEnabled    *bool    `json:"email_enabled"`
+	WechatEnabled   *bool    `json:"wechat_enabled"`
+	MutedCategories []string `json:"muted_categories"`
+}
+
+// NotificationUnreadCount 未读通知数
+type NotificationUnreadCount struct {
+	Total      int64            `json:"total"`
+	Categories map[string]int64 `json:"categories"`
+}
+
+func isValidNotificationCategory(category string) bool {
+	switch category {
+	case NotificationCategoryInvitation, NotificationCategoryAccessRequest, NotificationCategoryReminder, NotificationCategoryModeration:
+		return true
+	}
+	return false
+}
+
+// Notify 给用户发送通知：每人保存一条站内信，再异步投递到站外渠道
+// 服务未设置时不做任何事，调用方无需判断；空用户ID和重复用户ID会被忽略
+func (s *NotificationService) Notify(userIDs []string, input *NotificationInput) error {
+	if s == nil || len(userIDs) == 0 {
+		return nil
+	}
+	if !isValidNotificationCategory(input.Category) {
+		return errors.New("无效的通知分类")
+	}
+
+	var data string
+	if input.Data != nil {
+		if jsonBytes, err := json.Marshal(input.Data); err == nil {
+			data = string(jsonBytes)
+		}
+	}
+
+	now := time.Now()
+	seen := make(map[string]bool)
+	var notifications []*models.Notification
+	for _, userID := range userIDs {
+		if userID == "" || seen[userID] {
+			continue
+		}
+		seen[userID] = true
+		notifications = append(notifications, &models.Notification{
+			ID:        uuid.New().String(),
+			UserID:    userID,
+			Category:  input.Category,
+			Type:      input.Type,
+			Title:     input.Title,
+			Content:   input.Content,
+			Data:      data,
+			CreatedAt: now,
+		})
+	}
+	if len(notifications) == 0 {
+		return nil
+	}
+	if err := s.db.Create(&notifications).Error; err != nil {
+		return err
+	}
+
+	if len(s.channels) > 0 {
+		go s.deliver(notifications)
+	}
+	return nil
+}
+
+// deliver 把通知投递到各渠道并登记投递结果
+func (s *NotificationService) deliver(notifications []*models.Notification) {
+	userIDs := make([]string, 0, len(notifications))
+	for _, notification := range notifications {
+		userIDs = append(userIDs, notification.UserID)
+	}
+	recipients := s.loadRecipients(userIDs)
+
+	for _, notification := range notifications {
+		recipient := recipients[notification.UserID]
+		for _, channel := range s.channels {
+			now := time.Now()
+			delivery := &models.NotificationDelivery{
+				ID:             uuid.New().String(),
+				NotificationID: notification.ID,
+				Channel:        channel.Name(),
+				CreatedAt:      now,
+				UpdatedAt:      now,
+			}
+			s.send(channel, recipient, notification, delivery)
+			if err := s.db.Create(delivery).Error; err != nil {
+				fmt.Printf("登记通知 %s 的投递记录失败: %v\n", notification.ID, err)
+			}
+		}
+	}
+}
+
+// send 发送一次并把结果写入投递记录
+func (s *NotificationService) send(channel NotificationChannel, recipient *NotificationRecipient, notification *models.Notification, delivery *models.NotificationDelivery) {
+	delivery.Attempts++
+	delivery.UpdatedAt = time.Now()
+	if isMutedCategory(recipient.Setting, notification.Category) {
+		delivery.Status = NotificationDeliverySkipped
+		delivery.Error = "用户已关闭该分类的推送"
+		return
+	}
+
+	err := channel.Send(recipient, notification)
+	switch {
+	case err == nil:
+		delivery.Status = NotificationDeliverySent
+		delivery.Error = ""
+		delivery.SentAt = &delivery.UpdatedAt
+	case errors.Is(err, ErrNotificationSkipped):
+		delivery.Status = NotificationDeliverySkipped
+		delivery.Error = err.Error()
+	default:
+		delivery.Status = NotificationDeliveryFailed
+		delivery.Error = truncateRunes(err.Error(), 490)
+	}
+}
+
+// loadRecipients 读取接收人的微信 openid 和通知设置，没有设置的用户按默认值
+func (s *NotificationService) loadRecipients(userIDs []string) map[string]*NotificationRecipient {
+	recipients := make(map[string]*NotificationRecipient, len(userIDs))
+	for _, userID := range userIDs {
+		recipients[userID] = &NotificationRecipient{UserID: userID, Setting: defaultNotificationSetting(userID)}
+	}
+
+	var users []*models.User
+	s.db.Select("id", "wechat_open_id").Where("id IN ?", userIDs).Find(&users)
+	for _, user := range users {
+		recipients[user.ID].OpenID = user.WechatOpenID
+	}
+	var settings []*models.NotificationSetting
+	s.db.Where("user_id IN ?", userIDs).Find(&settings)
+	for _, setting := range settings {
+		recipients[setting.UserID].Setting = setting
+	}
+	return recipients
+}
+
+func defaultNotificationSetting(userID string) *models.NotificationSetting {
+	return &models.NotificationSetting{
+		UserID:          userID,
+		WechatEnabled:   true,
+		MutedCategories: "[]",
+	}
+}
+
+func isMutedCategory(setting *models.NotificationSetting, category string) bool {
+	var muted []string
+	json.Unmarshal([]byte(setting.MutedCategories), &muted)
+	for _, c := range muted {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// StartNotificationRetrier 定期重试投递失败的通知
+func (s *NotificationService) StartNotificationRetrier(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if _, err := s.RetryFailedDeliveries(time.Now()); err != nil {
+				fmt.Printf("重试通知投递失败: %v\n", err)
+			}
+		}
+	}()
+}
+
+// RetryFailedDeliveries 重试未超过最多尝试次数、且上次尝试已过一段时间的失败投递，返回重试成功的数量
+// 每次重试前以条件更新认领，多个实例同时执行时不会重复发送
+func (s *NotificationService) RetryFailedDeliveries(now time.Time) (int, error) {
+	var deliveries []*models.NotificationDelivery
+	err := s.db.Where("status = ? AND attempts < ? AND updated_at <= ?",
+		NotificationDeliveryFailed, notificationMaxAttempts, now.Add(-5*time.Minute)).
+		Order("created_at ASC").
+		Limit(100).
+		Find(&deliveries).Error
+	if err != nil {
+		return 0, err
+	}
+
+	retried := 0
+	for _, delivery := range deliveries {
+		channel := s.channel(delivery.Channel)
+		if channel == nil {
+			continue
+		}
+		result := s.db.Model(&models.NotificationDelivery{}).
+			Where("id = ? AND attempts = ? AND status = ?", delivery.ID, delivery.Attempts, NotificationDeliveryFailed).
+			Updates(map[string]interface{}{"attempts": delivery.Attempts + 1, "updated_at": now})
+		if result.Error != nil {
+			return retried, result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		var notification models.Notification
+		if err := s.db.First(&notification, "id = ?", delivery.NotificationID).Error; err != nil {
+			continue
+		}
+		recipient := s.loadRecipients([]string{notification.UserID})[notification.UserID]
+		s.send(channel, recipient, &notification, delivery)
+		s.db.Model(&models.NotificationDelivery{}).Where("id = ?", delivery.ID).Updates(map[string]interface{}{
+			"status":     delivery.Status,
+			"error":      delivery.Error,
+			"sent_at":    delivery.SentAt,
+			"updated_at": delivery.UpdatedAt,
+		})
+		if delivery.Status == NotificationDeliverySent {
+			retried++
+		}
+	}
+	return retried, nil
+}
+
+func (s *NotificationService) channel(name string) NotificationChannel {
+	for _, channel := range s.channels {
+		if channel.Name() == name {
+			return channel
+		}
+	}
+	return nil
+}
+
+// 获取通知列表，category 为空表示全部分类
+func (s *NotificationService) GetNotifications(userID, category string, unreadOnly bool, page, pageSize int) ([]*models.Notification, int64, error) {
+	if category != "" && !isValidNotificationCategory(category) {
+		return nil, 0, errors.New("无效的通知分类")
+	}
+
+	query := s.db.Model(&models.Notification{}).Where("user_id = ?", userID)
+	if category != "" {
+		query = query.Where("category = ?", category)
+	}
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var notifications []*models.Notification
+	offset := (page - 1) * pageSize
+	err := query.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&notifications).Error
+	return notifications, total, err
+}
+
+// 获取未读通知数，按分类统计
+func (s *NotificationService) GetUnreadCount(userID string) (*NotificationUnreadCount, error) {
+	var rows []struct {
+		Category string
+		Count    int64
+	}
+	err := s.db.Model(&models.Notification{}).
+		Select("category, COUNT(*) AS count").
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Group("category").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	count := &NotificationUnreadCount{Categories: map[string]int64{
+		NotificationCategoryInvitation:    0,
+		NotificationCategoryAccessRequest: 0,
+		NotificationCategoryReminder:      0,
+		NotificationCategoryModeration:    0,
+	}}
+	for _, row := range rows {
+		count.Categories[row.Category] = row.Count
+		count.Total += row.Count
+	}
+	return count, nil
+}
+
+// 标记通知为已读
+func (s *NotificationService) MarkAsRead(userID, notificationID string) error {
+	var notification models.Notification
+	if err := s.db.First(&notification, "id = ? AND user_id = ?", notificationID, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("通知不存在")
+		}
+		return err
+	}
+	if notification.ReadAt != nil {
+		return nil
+	}
+	return s.db.Model(&notification).Update("read_at", time.Now()).Error
+}
+
+// 全部标记为已读，category 为空表示全部分类，返回本次标记的数量
+func (s *NotificationService) MarkAllAsRead(userID, category string) (int64, error) {
+	if category != "" && !isValidNotificationCategory(category) {
+		return 0, errors.New("无效的通知分类")
+	}
+	query := s.db.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID)
+	if category != "" {
+		query = query.Where("category = ?", category)
+	}
+	result := query.Update("read_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+// 删除通知
+func (s *NotificationService) DeleteNotification(userID, notificationID string) error {
+	result := s.db.Where("id = ? AND user_id = ?", notificationID, userID).Delete(&models.Notification{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("通知不存在")
+	}
+	return nil
+}
+
+// 获取通知设置，未设置过时返回默认值
+func (s *NotificationService) GetSettings(userID string) (*models.NotificationSetting, error) {
+	var setting models.NotificationSetting
+	err := s.db.First(&setting, "user_id = ?", userID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return defaultNotificationSetting(userID), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &setting, nil
+}
+
+// 更新通知设置，未传的字段保持不变
+func (s *NotificationService) UpdateSettings(userID string, req *UpdateNotificationSettingsRequest) (*models.NotificationSetting, error) {
+	setting, err := s.GetSettings(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Email != nil {
+		setting.Email = *req.Email
+	}
+	if req.EmailEnabled != nil {
+		setting.EmailEnabled = *req.EmailEnabled
+	}
+	if req.WechatEnabled != nil {
+		setting.WechatEnabled = *req.WechatEnabled
+	}
+	if req.MutedCategories != nil {
+		for _, category := range req.MutedCategories {
+			if !isValidNotificationCategory(category) {
+				return nil, errors.New("无效的通知分类")
+			}
+		}
+		muted, _ := json.Marshal(req.MutedCategories)
+		setting.MutedCategories = string(muted)
+	}
+	if setting.EmailEnabled && setting.Email == "" {
+		return nil, errors.New("开启邮件通知须填写邮箱")
+	}
+
+	setting.UpdatedAt = time.Now()
+	if setting.CreatedAt.IsZero() {
+		setting.CreatedAt = setting.UpdatedAt
+	}
+	if err := s.db.Save(setting).Error; err != nil {
+		return nil, err
+	}
+	return setting, nil
+}
@@ -5,13 +5,15 @@ import (
 	"fmt"
 	"time"
 	"yun-nian-memorial/internal/models"
+	"yun-nian-memorial/internal/utils"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 type PrivacyService struct {
-	db *gorm.DB
+	db            *gorm.DB
+	notifications *NotificationService
 }
 
 func NewPrivacyService(db *gorm.DB) *PrivacyService {
@@ -20,6 +22,11 @@ func NewPrivacyService(db *gorm.DB) *PrivacyService {
 	}
 }
 
+// SetNotificationService 设置通知服务依赖（访问申请及处理结果）
+func (s *PrivacyService) SetNotificationService(notifications *NotificationService) {
+	s.notifications = notifications
+}
+
 // 隐私级别常量
 const (
 	PrivacyLevelPublic = 0 // 公开
@@ -207,14 +214,8 @@ func (s *PrivacyService) CheckUserAccess(userID, memorialID string, permissionTy
 
 // 检查家族或特殊访问权限
 func (s *PrivacyService) checkFamilyOrSpecialAccess(userID, memorialID, permissionType string) (bool, error) {
-	// 检查是否为家族成员
-	var familyCount int64
-	s.db.Table("memorial_families mf").
-		Joins("JOIN family_members fm ON mf.family_id = fm.family_id").
-		Where("mf.memorial_id = ? AND fm.user_id = ?", memorialID, userID).
-		Count(&familyCount)
-
-	if familyCount > 0 {
+	// 检查是否为家族成员（含宗族与支系继承的成员身份）
+	if utils.NewPermissionManager(s.db).IsMemorialFamilyMember(userID, memorialID) {
 		return true, nil
 	}
 
@@ -240,6 +241,14 @@ func (s *PrivacyService) checkSpecialAccess(userID, memorialID, permissionType s
 
 // 申请访问权限
 func (s *PrivacyService) RequestAccess(userID, memorialID, message string) error {
+	var memorial models.Memorial
+	if err := s.db.Select("id", "creator_id", "deceased_name").First(&memorial, "id = ?", memorialID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("纪念馆不存在")
+		}
+		return err
+	}
+
 	// 检查是否已有待处理的申请
 	var existingRequest models.AccessRequest
 	err := s.db.Where("memorial_id = ? AND user_id = ? AND status = ?",
@@ -260,7 +269,24 @@ func (s *PrivacyService) RequestAccess(userID, memorialID, message string) error
 		UpdatedAt:  time.Now(),
 	}
 
-	return s.db.Create(request).Error
+	if err := s.db.Create(request).Error; err != nil {
+		return err
+	}
+
+	// 通知纪念馆创建者审批
+	var applicant models.User
+	s.db.Select("id", "nickname").First(&applicant, "id = ?", userID)
+	err = s.notifications.Notify([]string{memorial.CreatorID}, &NotificationInput{
+		Category: NotificationCategoryAccessRequest,
+		Type:     NotificationTypeAccessRequest,
+		Title:    "纪念馆访问申请",
+		Content:  fmt.Sprintf("%s申请访问“%s”纪念馆", applicant.Nickname, memorial.DeceasedName),
+		Data:     map[string]interface{}{"memorial_id": memorialID, "request_id": request.ID},
+	})
+	if err != nil {
+		fmt.Printf("发送访问申请通知失败: %v\n", err)
+	}
+	return nil
 }
 
 // 处理访问申请
@@ -310,6 +336,22 @@ func (s *PrivacyService) HandleAccessRequest(ownerID, requestID string, approve
 		}
 	}
 
+	// 通知申请人处理结果
+	result := "已通过"
+	if !approve {
+		result = "未通过"
+	}
+	err = s.notifications.Notify([]string{request.UserID}, &NotificationInput{
+		Category: NotificationCategoryAccessRequest,
+		Type:     NotificationTypeAccessRequestResult,
+		Title:    "访问申请结果",
+		Content:  fmt.Sprintf("您对“%s”纪念馆的访问申请%s", request.Memorial.DeceasedName, result),
+		Data:     map[string]interface{}{"memorial_id": request.MemorialID, "request_id": request.ID, "status": status},
+	})
+	if err != nil {
+		fmt.Printf("发送访问申请结果通知失败: %v\n", err)
+	}
+
 	return nil
 }
 
@@ -0,0 +1,486 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+	"yun-nian-memorial/internal/models"
+	"yun-nian-memorial/internal/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 纪念日提醒类型
+const (
+	ReminderTypeBirthday         = "birthday"          // 生辰
+	ReminderTypeDeathAnniversary = "death_anniversary" // 忌日
+	ReminderTypeFestival         = "festival"          // 节日
+	ReminderTypeScheduledPrayer  = "scheduled_prayer"  // 定时祈福
+	ReminderTypeMourning         = "mourning"          // 守丧日程（做七、百日、周年）
+)
+
+// 用户对某次提醒的处理
+const (
+	ReminderActionSnoozed   = "snoozed"   // 稍后提醒
+	ReminderActionDismissed = "dismissed" // 不再提醒
+)
+
+// 默认提醒发送时刻
+const defaultRemindTime = "09:00"
+
+// 每批处理的提醒数
+const reminderDispatchBatchSize = 200
+
+type ReminderService struct {
+	db            *gorm.DB
+	notifications *NotificationService
+	location      *time.Location
+}
+
+func NewReminderService(db *gorm.DB) *ReminderService {
+	return &ReminderService{
+		db:       db,
+		location: time.Local,
+	}
+}
+
+// SetNotificationService 设置通知服务依赖（发送到期提醒）
+func (s *ReminderService) SetNotificationService(notifications *NotificationService) {
+	s.notifications = notifications
+}
+
+// 稍后提醒请求
+type SnoozeReminderRequest struct {
+	Date    string `json:"date" binding:"required"`                    // 哪一次提醒，YYYY-MM-DD
+	Minutes int    `json:"minutes" binding:"required,min=5,max=10080"` // 推迟的分钟数，最多7天
+}
+
+// 不再提醒请求
+type DismissReminderRequest struct {
+	Date string `json:"date" binding:"required"` // 哪一次提醒，YYYY-MM-DD
+}
+
+// reminderPlan 解析后的提醒安排
+type reminderPlan struct {
+	rule     utils.ReminderRule
+	start    time.Time
+	leadDays []int
+	hour     int
+	minute   int
+}
+
+// planReminder 解析提醒的规则、提前量和发送时刻，逝世第N天的提醒需预加载纪念馆
+// 规则为空时生辰、忌日按每年重复，其他类型只提醒一次
+func planReminder(reminder *models.MemorialReminder) (*reminderPlan, bool) {
+	rule, err := utils.ParseReminderRule(reminder.Rule)
+	if err != nil {
+		return nil, false
+	}
+	if reminder.Rule == "" && (reminder.ReminderType == ReminderTypeBirthday || reminder.ReminderType == ReminderTypeDeathAnniversary) {
+		rule.Yearly = true
+	}
+	start, ok := rule.Start(reminder.ReminderDate, reminder.Memorial.DeathDate)
+	if !ok {
+		return nil, false
+	}
+
+	plan := &reminderPlan{rule: rule, start: start, leadDays: []int{0}}
+	var leadDays []int
+	if reminder.LeadDays != "" && json.Unmarshal([]byte(reminder.LeadDays), &leadDays) == nil {
+		if parsed, err := utils.ParseReminderLeadDays(leadDays); err == nil {
+			plan.leadDays = parsed
+		}
+	}
+	remindTime := reminder.RemindTime
+	if remindTime == "" {
+		remindTime = defaultRemindTime
+	}
+	if plan.hour, plan.minute, err = utils.ParseRemindTime(remindTime); err != nil {
+		plan.hour, plan.minute, _ = utils.ParseRemindTime(defaultRemindTime)
+	}
+	return plan, true
+}
+
+// fillReminderSchedule 填充下一次提醒日期和规则描述
+func fillReminderSchedule(reminder *models.MemorialReminder, from time.Time) {
+	plan, ok := planReminder(reminder)
+	if !ok {
+		return
+	}
+	reminder.RuleText = plan.rule.Describe(plan.start)
+	if next, ok := plan.rule.Next(plan.start, from); ok {
+		reminder.NextDate = &next
+	}
+}
+
+// reminderDay 把时间换算为所在时区的日期，用于和规则推算的日期比较
+func reminderDay(t time.Time, loc *time.Location) time.Time {
+	local := t.In(loc)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// StartReminderDispatcher 定期发送到期的纪念日提醒
+func (s *ReminderService) StartReminderDispatcher(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := s.ProcessDueReminders(time.Now()); err != nil {
+				fmt.Printf("发送纪念日提醒失败: %v\n", err)
+			}
+		}
+	}()
+}
+
+// ProcessDueReminders 发送到期的提醒和到时的稍后提醒
+//
+// 每次提醒的每个提前量只发送一次：先以版本号条件更新认领，认领成功的实例才发送。
+// 停机期间错过的提前量只补发最近的一个，提醒当天过后不再补发。
+func (s *ReminderService) ProcessDueReminders(now time.Time) error {
+	today := reminderDay(now, s.location)
+	horizon := today.AddDate(0, 0, utils.ReminderMaxLeadDays)
+
+	// 已登记的各次提醒，按提醒ID和日期索引
+	var occurrences []*models.ReminderOccurrence
+	err := s.db.Where("occurrence_date BETWEEN ? AND ?", today.Format("2006-01-02"), horizon.Format("2006-01-02")).
+		Find(&occurrences).Error
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]*models.ReminderOccurrence, len(occurrences))
+	for _, occurrence := range occurrences {
+		existing[occurrence.ReminderID+"|"+occurrence.OccurrenceDate.Format("2006-01-02")] = occurrence
+	}
+
+	lastID := ""
+	for {
+		var reminders []*models.MemorialReminder
+		err := s.db.Preload("Memorial").
+			Where("is_active = ? AND id > ?", true, lastID).
+			Order("id ASC").
+			Limit(reminderDispatchBatchSize).
+			Find(&reminders).Error
+		if err != nil {
+			return err
+		}
+		for _, reminder := range reminders {
+			s.dispatchReminder(reminder, existing, today, now)
+		}
+		if len(reminders) < reminderDispatchBatchSize {
+			break
+		}
+		lastID = reminders[len(reminders)-1].ID
+	}
+
+	return s.processSnoozedReminders(now)
+}
+
+// dispatchReminder 发送一条提醒在近期到期的各次
+func (s *ReminderService) dispatchReminder(reminder *models.MemorialReminder, existing map[string]*models.ReminderOccurrence, today, now time.Time) {
+	plan, ok := planReminder(reminder)
+	if !ok {
+		return
+	}
+	for _, date := range plan.rule.Occurrences(plan.start, today, today.AddDate(0, 0, plan.leadDays[0])) {
+		occurrence := existing[reminder.ID+"|"+date.Format("2006-01-02")]
+		var notified *int
+		if occurrence != nil {
+			notified = occurrence.NotifiedLead
+		}
+		lead, due := utils.DueReminderLead(date, plan.leadDays, plan.hour, plan.minute, notified, now, s.location)
+		if !due {
+			continue
+		}
+
+		if occurrence == nil {
+			var err error
+			if occurrence, err = s.ensureOccurrence(reminder.ID, date); err != nil {
+				fmt.Printf("登记提醒 %s 在 %s 的发送记录失败: %v\n", reminder.ID, date.Format("2006-01-02"), err)
+				continue
+			}
+			// 其他实例可能已经发送
+			if occurrence.NotifiedLead != nil && *occurrence.NotifiedLead <= lead {
+				continue
+			}
+		}
+
+		result := s.db.Model(&models.ReminderOccurrence{}).
+			Where("id = ? AND version = ?", occurrence.ID, occurrence.Version).
+			Updates(map[string]interface{}{
+				"notified_lead": lead,
+				"version":       occurrence.Version + 1,
+				"updated_at":    now,
+			})
+		if result.Error != nil {
+			fmt.Printf("认领提醒 %s 失败: %v\n", occurrence.ID, result.Error)
+			continue
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		recipients := s.excludeHandled(occurrence.ID, s.reminderAudience(reminder), now)
+		s.sendReminder(reminder, date, today, lead, recipients)
+	}
+}
+
+// ensureOccurrence 取得某次提醒的记录，没有时创建；并发创建时以唯一索引去重
+func (s *ReminderService) ensureOccurrence(reminderID string, date time.Time) (*models.ReminderOccurrence, error) {
+	var occurrence models.ReminderOccurrence
+	err := s.db.Where("reminder_id = ? AND occurrence_date = ?", reminderID, date.Format("2006-01-02")).First(&occurrence).Error
+	if err == nil {
+		return &occurrence, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	now := time.Now()
+	occurrence = models.ReminderOccurrence{
+		ID:             uuid.New().String(),
+		ReminderID:     reminderID,
+		OccurrenceDate: time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, s.location),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if err := s.db.Create(&occurrence).Error; err != nil {
+		// 其他实例已创建
+		var created models.ReminderOccurrence
+		if findErr := s.db.Where("reminder_id = ? AND occurrence_date = ?", reminderID, date.Format("2006-01-02")).First(&created).Error; findErr != nil {
+			return nil, err
+		}
+		return &created, nil
+	}
+	return &occurrence, nil
+}
+
+// reminderAudience 提醒的接收人：定时祈福只提醒设置人，其他提醒发给纪念馆关联家族圈的成员（包括继承成员身份的宗族与支系成员）和纪念馆创建者
+func (s *ReminderService) reminderAudience(reminder *models.MemorialReminder) []string {
+	if reminder.ReminderType == ReminderTypeScheduledPrayer {
+		if reminder.CreatorID != "" {
+			return []string{reminder.CreatorID}
+		}
+		return []string{reminder.Memorial.CreatorID}
+	}
+
+	userIDs, _ := utils.NewPermissionManager(s.db).GetMemorialFamilyMemberIDs(reminder.MemorialID)
+	if reminder.Memorial.CreatorID != "" {
+		userIDs = append(userIDs, reminder.Memorial.CreatorID)
+	}
+	return userIDs
+}
+
+// excludeHandled 去掉已选择不再提醒、或稍后提醒尚未到时的用户
+func (s *ReminderService) excludeHandled(occurrenceID string, userIDs []string, now time.Time) []string {
+	var handled []string
+	s.db.Model(&models.ReminderOccurrenceAction{}).
+		Where("occurrence_id = ? AND (status = ? OR (status = ? AND snoozed_until > ?))",
+			occurrenceID, ReminderActionDismissed, ReminderActionSnoozed, now).
+		Pluck("user_id", &handled)
+	if len(handled) == 0 {
+		return userIDs
+	}
+
+	skip := make(map[string]bool, len(handled))
+	for _, userID := range handled {
+		skip[userID] = true
+	}
+	var result []string
+	for _, userID := range userIDs {
+		if !skip[userID] {
+			result = append(result, userID)
+		}
+	}
+	return result
+}
+
+// sendReminder 发送一次提醒
+func (s *ReminderService) sendReminder(reminder *models.MemorialReminder, date, today time.Time, lead int, userIDs []string) {
+	days := int(date.Sub(today).Hours() / 24)
+	when := "今天"
+	switch {
+	case days == 1:
+		when = "明天"
+	case days > 1:
+		when = fmt.Sprintf("%d天后", days)
+	}
+	dateText := date.Format("2006-01-02")
+	if lunar, ok := utils.SolarToLunar(date); ok {
+		dateText += "，农历" + lunar.String()
+	}
+
+	content := fmt.Sprintf("%s（%s）是%s的%s", when, dateText, reminder.Memorial.DeceasedName, reminder.Title)
+	if reminder.ReminderType == ReminderTypeMourning && reminder.Content != "" {
+		// 守丧日程附上建议的仪式和供品，便于提前准备
+		content += "。" + reminder.Content
+	}
+
+	err := s.notifications.Notify(userIDs, &NotificationInput{
+		Category: NotificationCategoryReminder,
+		Type:     NotificationTypeMemorialReminder,
+		Title:    reminder.Title,
+		Content:  content,
+		Data: map[string]interface{}{
+			"reminder_id": reminder.ID,
+			"memorial_id": reminder.MemorialID,
+			"date":        date.Format("2006-01-02"),
+			"lead_days":   lead,
+		},
+	})
+	if err != nil {
+		fmt.Printf("发送纪念日提醒 %s 失败: %v\n", reminder.ID, err)
+	}
+}
+
+// processSnoozedReminders 给稍后提醒到时的用户再发一次，删除处理记录后该用户恢复正常提醒
+func (s *ReminderService) processSnoozedReminders(now time.Time) error {
+	var actions []*models.ReminderOccurrenceAction
+	err := s.db.Where("status = ? AND snoozed_until <= ?", ReminderActionSnoozed, now).
+		Order("snoozed_until ASC").
+		Limit(reminderDispatchBatchSize).
+		Find(&actions).Error
+	if err != nil {
+		return err
+	}
+
+	today := reminderDay(now, s.location)
+	for _, action := range actions {
+		// 以删除认领，多个实例同时执行时只发送一次；用户期间再次推迟的不会被删除
+		result := s.db.Where("id = ? AND status = ? AND snoozed_until <= ?", action.ID, ReminderActionSnoozed, now).
+			Delete(&models.ReminderOccurrenceAction{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		var occurrence models.ReminderOccurrence
+		if err := s.db.First(&occurrence, "id = ?", action.OccurrenceID).Error; err != nil {
+			continue
+		}
+		var reminder models.MemorialReminder
+		if err := s.db.Preload("Memorial").First(&reminder, "id = ? AND is_active = ?", occurrence.ReminderID, true).Error; err != nil {
+			continue
+		}
+		date := reminderDay(occurrence.OccurrenceDate, s.location)
+		if date.Before(today) {
+			continue
+		}
+		lead := 0
+		if occurrence.NotifiedLead != nil {
+			lead = *occurrence.NotifiedLead
+		}
+		s.sendReminder(&reminder, date, today, lead, []string{action.UserID})
+	}
+	return nil
+}
+
+// 稍后提醒：在指定分钟后再提醒一次，期间不再收到这次提醒的其他提前量
+func (s *ReminderService) SnoozeReminder(userID, reminderID string, req *SnoozeReminderRequest) (*models.ReminderOccurrenceAction, error) {
+	now := time.Now()
+	occurrence, err := s.occurrenceForUser(userID, reminderID, req.Date, now)
+	if err != nil {
+		return nil, err
+	}
+	until := now.Add(time.Duration(req.Minutes) * time.Minute)
+	if reminderDay(until, s.location).After(reminderDay(occurrence.OccurrenceDate, s.location)) {
+		return nil, errors.New("稍后提醒不能晚于提醒当天")
+	}
+	return s.saveAction(occurrence.ID, userID, ReminderActionSnoozed, &until, now)
+}
+
+// 不再提醒：这一次提醒的其余提前量和稍后提醒都不再发给该用户，不影响以后的各次
+func (s *ReminderService) DismissReminder(userID, reminderID string, req *DismissReminderRequest) (*models.ReminderOccurrenceAction, error) {
+	now := time.Now()
+	occurrence, err := s.occurrenceForUser(userID, reminderID, req.Date, now)
+	if err != nil {
+		return nil, err
+	}
+	return s.saveAction(occurrence.ID, userID, ReminderActionDismissed, nil, now)
+}
+
+// occurrenceForUser 校验用户能收到该提醒、日期是该提醒尚未过去的一次，返回这一次的记录
+func (s *ReminderService) occurrenceForUser(userID, reminderID, dateText string, now time.Time) (*models.ReminderOccurrence, error) {
+	date, err := time.Parse("2006-01-02", dateText)
+	if err != nil {
+		return nil, errors.New("日期格式错误，应为YYYY-MM-DD")
+	}
+
+	var reminder models.MemorialReminder
+	if err := s.db.Preload("Memorial").First(&reminder, "id = ? AND is_active = ?", reminderID, true).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("提醒不存在")
+		}
+		return nil, err
+	}
+
+	allowed := false
+	for _, audienceID := range s.reminderAudience(&reminder) {
+		if audienceID == userID {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return nil, errors.New("无权操作此提醒")
+	}
+
+	plan, ok := planReminder(&reminder)
+	if !ok || len(plan.rule.Occurrences(plan.start, date, date)) == 0 {
+		return nil, errors.New("该日期没有此提醒")
+	}
+	if date.Before(reminderDay(now, s.location)) {
+		return nil, errors.New("提醒已过期")
+	}
+	return s.ensureOccurrence(reminder.ID, date)
+}
+
+// saveAction 保存用户对某次提醒的处理，已有处理时覆盖
+func (s *ReminderService) saveAction(occurrenceID, userID, status string, snoozedUntil *time.Time, now time.Time) (*models.ReminderOccurrenceAction, error) {
+	var action models.ReminderOccurrenceAction
+	err := s.db.Where("occurrence_id = ? AND user_id = ?", occurrenceID, userID).First(&action).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		action = models.ReminderOccurrenceAction{
+			ID:           uuid.New().String(),
+			OccurrenceID: occurrenceID,
+			UserID:       userID,
+			CreatedAt:    now,
+		}
+	}
+	action.Status = status
+	action.SnoozedUntil = snoozedUntil
+	action.UpdatedAt = now
+	if err := s.db.Save(&action).Error; err != nil {
+		return nil, err
+	}
+	return &action, nil
+}
+
+// reminderActionsForUser 用户对各次提醒的处理，键为“提醒ID|日期”
+func reminderActionsForUser(db *gorm.DB, userID string, reminderIDs []string) map[string]*models.ReminderOccurrenceAction {
+	result := make(map[string]*models.ReminderOccurrenceAction)
+	if len(reminderIDs) == 0 {
+		return result
+	}
+
+	var rows []struct {
+		models.ReminderOccurrenceAction
+		ReminderID     string
+		OccurrenceDate time.Time
+	}
+	db.Table("reminder_occurrence_actions a").
+		Select("a.*, o.reminder_id, o.occurrence_date").
+		Joins("JOIN reminder_occurrences o ON o.id = a.occurrence_id").
+		Where("a.user_id = ? AND o.reminder_id IN ?", userID, reminderIDs).
+		Scan(&rows)
+	for i := range rows {
+		result[rows[i].ReminderID+"|"+rows[i].OccurrenceDate.Format("2006-01-02")] = &rows[i].ReminderOccurrenceAction
+	}
+	return result
+}
@@ -0,0 +1,477 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"yun-nian-memorial/internal/models"
+	"yun-nian-memorial/internal/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 报告导出数据来源与文件格式
+const (
+	ReportSourceReport     = "report"
+	ReportSourceStatistics = "statistics"
+
+	ReportFormatCSV  = "csv"
+	ReportFormatXLSX = "xlsx"
+	ReportFormatPDF  = "pdf"
+)
+
+// 导出任务状态
+const (
+	ReportExportPending    = "pending"
+	ReportExportProcessing = "processing"
+	ReportExportCompleted  = "completed"
+	ReportExportFailed     = "failed"
+)
+
+const (
+	// 导出文件保留天数
+	reportExportRetentionDays = 7
+	// 单个文件导出的原始记录上限
+	reportExportMaxRecords = 50000
+	// 排行榜人数
+	reportTopContributorLimit = 10
+)
+
+type ReportExportService struct {
+	db             *gorm.DB
+	worshipService *WorshipService
+	exportPath     string
+}
+
+func NewReportExportService(db *gorm.DB, exportPath string) *ReportExportService {
+	// 确保导出目录存在
+	if exportPath == "" {
+		exportPath = "./exports/reports"
+	}
+	os.MkdirAll(exportPath, 0755)
+
+	return &ReportExportService{
+		db:         db,
+		exportPath: exportPath,
+	}
+}
+
+// SetWorshipService 设置祭扫服务依赖（报告和统计数据来源）
+func (s *ReportExportService) SetWorshipService(worshipService *WorshipService) {
+	s.worshipService = worshipService
+}
+
+// CreateReportExportRequest 创建导出任务请求
+type CreateReportExportRequest struct {
+	Source string `json:"source" binding:"required,oneof=report statistics"`
+	Format string `json:"format" binding:"required,oneof=csv xlsx pdf"`
+	Period string `json:"period" binding:"omitempty,oneof=week month quarter year"` // 仅 report 来源使用，默认 month
+}
+
+// CreateReportExport 创建导出任务，文件在后台生成，完成后通过下载链接获取
+func (s *ReportExportService) CreateReportExport(userID, memorialID string, req *CreateReportExportRequest) (*models.WorshipReportExport, error) {
+	if err := s.worshipService.validateMemorialAccess(userID, memorialID); err != nil {
+		return nil, err
+	}
+
+	job := &models.WorshipReportExport{
+		ID:         uuid.New().String(),
+		MemorialID: memorialID,
+		UserID:     userID,
+		Source:     req.Source,
+		Format:     req.Format,
+		Status:     ReportExportPending,
+	}
+	if req.Source == ReportSourceReport {
+		_, job.Period = reportPeriodStart(req.Period, time.Now())
+	}
+	job.DownloadURL = fmt.Sprintf("/api/v1/worship/report-exports/%s/download", job.ID)
+
+	if err := s.db.Create(job).Error; err != nil {
+		return nil, err
+	}
+
+	// 异步生成文件
+	go s.processReportExport(job)
+
+	return job, nil
+}
+
+// processReportExport 生成导出文件并更新任务状态
+func (s *ReportExportService) processReportExport(job *models.WorshipReportExport) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.failReportExport(job, fmt.Errorf("生成报告异常: %v", r))
+		}
+	}()
+
+	s.db.Model(job).Update("status", ReportExportProcessing)
+
+	dataset, err := s.buildReportDataset(job)
+	if err != nil {
+		s.failReportExport(job, err)
+		return
+	}
+
+	timestamp := time.Now().Format("20060102_150405")
+	memorialName := strings.NewReplacer("/", "_", "\\", "_").Replace(dataset.MemorialName)
+	fileName := fmt.Sprintf("%s_%s_%s.%s", dataset.FilePrefix, memorialName, timestamp, job.Format)
+	filePath := filepath.Join(s.exportPath, fmt.Sprintf("%s.%s", job.ID, job.Format))
+
+	if err := s.writeReportFile(filePath, job.Format, dataset); err != nil {
+		os.Remove(filePath)
+		s.failReportExport(job, err)
+		return
+	}
+
+	fileSize := int64(0)
+	if fileInfo, _ := os.Stat(filePath); fileInfo != nil {
+		fileSize = fileInfo.Size()
+	}
+
+	completedAt := time.Now()
+	expiresAt := completedAt.AddDate(0, 0, reportExportRetentionDays)
+	s.db.Model(job).Updates(map[string]interface{}{
+		"status":       ReportExportCompleted,
+		"file_name":    fileName,
+		"file_path":    filePath,
+		"file_size":    fileSize,
+		"expires_at":   expiresAt,
+		"completed_at": completedAt,
+	})
+}
+
+func (s *ReportExportService) failReportExport(job *models.WorshipReportExport, err error) {
+	s.db.Model(job).Updates(map[string]interface{}{
+		"status":        ReportExportFailed,
+		"error_message": err.Error(),
+	})
+}
+
+// writeReportFile 按格式写出文件：CSV 仅包含原始记录，XLSX 每类数据一张工作表，PDF 为排版后的报告
+func (s *ReportExportService) writeReportFile(filePath, format string, dataset *reportDataset) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("创建导出文件失败: %v", err)
+	}
+	defer file.Close()
+
+	switch format {
+	case ReportFormatCSV:
+		err = utils.WriteReportCSV(file, dataset.Records)
+	case ReportFormatXLSX:
+		err = utils.WriteReportXLSX(file, dataset.sheets())
+	case ReportFormatPDF:
+		_, err = dataset.pdf().WriteTo(file)
+	default:
+		err = errors.New("不支持的导出格式")
+	}
+	if err != nil {
+		return fmt.Errorf("写入导出文件失败: %v", err)
+	}
+	return file.Sync()
+}
+
+// reportDataset 导出文件所需的全部数据，各格式从中取用
+type reportDataset struct {
+	Title           string
+	FilePrefix      string
+	MemorialName    string
+	PeriodLabel     string
+	GeneratedAt     string
+	Summary         utils.ReportTable
+	Offerings       utils.ReportTable
+	Trend           utils.ReportTable
+	Hourly          utils.ReportTable
+	Contributors    utils.ReportTable
+	Records         utils.ReportTable
+	Highlights      []string
+	Recommendations []string
+}
+
+// buildReportDataset 以发起人身份重新获取报告或统计数据，保证导出内容与接口返回一致
+func (s *ReportExportService) buildReportDataset(job *models.WorshipReportExport) (*reportDataset, error) {
+	var memorial models.Memorial
+	if err := s.db.First(&memorial, "id = ?", job.MemorialID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("纪念馆不存在")
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	dataset := &reportDataset{
+		MemorialName: memorial.DeceasedName,
+		GeneratedAt:  now.Format("2006-01-02 15:04:05"),
+		Summary:      utils.ReportTable{Name: "概览", Headers: []string{"指标", "数值"}},
+		Offerings:    utils.ReportTable{Name: "祭扫方式", Headers: []string{"祭扫方式", "次数"}},
+		Contributors: utils.ReportTable{Name: "访客排行", Headers: []string{"排名", "访客", "祭扫次数", "最近祭扫"}},
+	}
+
+	var since time.Time
+	switch job.Source {
+	case ReportSourceReport:
+		report, err := s.worshipService.GenerateWorshipReport(job.UserID, job.MemorialID, job.Period)
+		if err != nil {
+			return nil, err
+		}
+		since, _ = reportPeriodStart(job.Period, now)
+		dataset.Title = fmt.Sprintf("%s纪念馆 祭扫%s报", memorial.DeceasedName, getPeriodName(report.ReportPeriod))
+		dataset.FilePrefix = "祭扫报告"
+		dataset.PeriodLabel = fmt.Sprintf("%s 至 %s", report.Summary["period_start"], report.Summary["period_end"])
+		dataset.Highlights = report.Highlights
+		dataset.Recommendations = report.Recommendations
+
+		dataset.Summary.AddRow("统计周期", dataset.PeriodLabel)
+		dataset.Summary.AddRow("祭扫次数", report.Summary["total_records"])
+		dataset.Summary.AddRow("独立访客", report.Summary["unique_visitors"])
+		if typeStats, ok := report.Summary["type_statistics"].(map[string]int64); ok {
+			addOfferingRows(&dataset.Offerings, typeStats)
+		}
+
+		dataset.Trend = utils.ReportTable{Name: "每日趋势", Headers: []string{"日期", "祭扫次数", "访客数"}}
+		for _, day := range s.dailyActivity(job.MemorialID, since, now) {
+			dataset.Trend.AddRow(day.Date, day.WorshipCount, day.VisitorCount)
+		}
+		for i, visitor := range s.topContributors(job.MemorialID, since) {
+			dataset.Contributors.AddRow(i+1, visitor.UserName, visitor.Count, visitor.LastVisit)
+		}
+
+	case ReportSourceStatistics:
+		stats, err := s.worshipService.GetDetailedWorshipStatistics(job.UserID, job.MemorialID)
+		if err != nil {
+			return nil, err
+		}
+		dataset.Title = fmt.Sprintf("%s纪念馆 祭扫统计", memorial.DeceasedName)
+		dataset.FilePrefix = "祭扫统计"
+		dataset.PeriodLabel = "建馆至今"
+
+		dataset.Summary.AddRow("统计周期", dataset.PeriodLabel)
+		dataset.Summary.AddRow("祭扫次数", stats.TotalRecords)
+		dataset.Summary.AddRow("独立访客", stats.UniqueVisitors)
+		addOfferingRows(&dataset.Offerings, stats.TypeStatistics)
+
+		dataset.Trend = utils.ReportTable{Name: "月度趋势", Headers: []string{"月份", "祭扫次数"}}
+		for _, month := range stats.MonthlyTrend {
+			dataset.Trend.AddRow(month.Month, month.Count)
+		}
+		dataset.Hourly = utils.ReportTable{Name: "时段分布", Headers: []string{"小时", "祭扫次数"}}
+		for _, hour := range stats.HourlyPattern {
+			dataset.Hourly.AddRow(hour.Hour, hour.Count)
+		}
+		for i, visitor := range stats.TopVisitors {
+			dataset.Contributors.AddRow(i+1, visitor.UserName, visitor.Count, visitor.LastVisit)
+		}
+
+	default:
+		return nil, errors.New("不支持的数据来源")
+	}
+
+	records, err := s.rawRecords(job.MemorialID, since)
+	if err != nil {
+		return nil, err
+	}
+	dataset.Records = records
+	return dataset, nil
+}
+
+func addOfferingRows(table *utils.ReportTable, typeStats map[string]int64) {
+	for _, worshipType := range []string{"flower", "candle", "incense", "tribute", "prayer", "message"} {
+		table.AddRow(getWorshipTypeName(worshipType), typeStats[worshipType])
+	}
+}
+
+// dailyActivity 统计周期内每日祭扫次数和访客数
+func (s *ReportExportService) dailyActivity(memorialID string, since, until time.Time) []RecentActivityStats {
+	type dailyCount struct {
+		Day          string
+		WorshipCount int64
+		VisitorCount int64
+	}
+	var counts []dailyCount
+	s.db.Model(&models.WorshipRecord{}).
+		Select("DATE_FORMAT(created_at, '%Y-%m-%d') as day, COUNT(*) as worship_count, COUNT(DISTINCT user_id) as visitor_count").
+		Where("memorial_id = ? AND created_at >= ?", memorialID, since).
+		Group("day").
+		Scan(&counts)
+
+	countMap := make(map[string]dailyCount, len(counts))
+	for _, c := range counts {
+		countMap[c.Day] = c
+	}
+
+	var days []RecentActivityStats
+	for day := since; !day.After(until); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		days = append(days, RecentActivityStats{
+			Date:         date,
+			WorshipCount: countMap[date].WorshipCount,
+			VisitorCount: countMap[date].VisitorCount,
+		})
+	}
+	return days
+}
+
+// topContributors 周期内祭扫次数最多的访客
+func (s *ReportExportService) topContributors(memorialID string, since time.Time) []VisitorStats {
+	type visitorCount struct {
+		UserID    string
+		Count     int64
+		LastVisit time.Time
+	}
+	var counts []visitorCount
+	s.db.Model(&models.WorshipRecord{}).
+		Select("user_id, COUNT(*) as count, MAX(created_at) as last_visit").
+		Where("memorial_id = ? AND created_at >= ?", memorialID, since).
+		Group("user_id").
+		Order("count DESC").
+		Limit(reportTopContributorLimit).
+		Scan(&counts)
+
+	userIDs := make([]string, 0, len(counts))
+	for _, c := range counts {
+		userIDs = append(userIDs, c.UserID)
+	}
+	users := s.userNames(userIDs)
+
+	visitors := make([]VisitorStats, 0, len(counts))
+	for _, c := range counts {
+		visitors = append(visitors, VisitorStats{
+			UserID:    c.UserID,
+			UserName:  users[c.UserID],
+			Count:     c.Count,
+			LastVisit: c.LastVisit.Format("2006-01-02 15:04:05"),
+		})
+	}
+	return visitors
+}
+
+// rawRecords 导出原始祭扫记录，since 为零值时导出全部
+func (s *ReportExportService) rawRecords(memorialID string, since time.Time) (utils.ReportTable, error) {
+	table := utils.ReportTable{Name: "祭扫记录", Headers: []string{"时间", "访客", "祭扫方式", "类型", "数量", "寄语"}}
+
+	query := s.db.Where("memorial_id = ?", memorialID)
+	if !since.IsZero() {
+		query = query.Where("created_at >= ?", since)
+	}
+	var records []models.WorshipRecord
+	if err := query.Order("created_at DESC").Limit(reportExportMaxRecords).Find(&records).Error; err != nil {
+		return table, err
+	}
+
+	userIDs := make([]string, 0, len(records))
+	for _, record := range records {
+		userIDs = append(userIDs, record.UserID)
+	}
+	users := s.userNames(userIDs)
+
+	for _, record := range records {
+		detail := utils.SummarizeWorshipRecord(record.Content)
+		table.AddRow(record.CreatedAt, users[record.UserID], getWorshipTypeName(record.WorshipType), detail.Kind, detail.Quantity, detail.Message)
+	}
+	return table, nil
+}
+
+func (s *ReportExportService) userNames(userIDs []string) map[string]string {
+	names := make(map[string]string)
+	if len(userIDs) == 0 {
+		return names
+	}
+	var users []models.User
+	s.db.Select("id, nickname").Where("id IN ?", userIDs).Find(&users)
+	for _, user := range users {
+		names[user.ID] = user.Nickname
+	}
+	return names
+}
+
+// sheets XLSX 工作表：概览、祭扫方式、趋势、时段分布（仅统计）、访客排行、原始记录
+func (d *reportDataset) sheets() []utils.ReportTable {
+	sheets := []utils.ReportTable{d.Summary, d.Offerings, d.Trend}
+	if len(d.Hourly.Rows) > 0 {
+		sheets = append(sheets, d.Hourly)
+	}
+	return append(sheets, d.Contributors, d.Records)
+}
+
+// pdf 排版后的报告：访问概况、祭扫方式分布、访客排行，以及报告亮点与寄语
+func (d *reportDataset) pdf() *utils.PDFDocument {
+	doc := utils.NewPDFDocument(d.Title)
+	doc.Title(d.Title)
+	doc.Paragraph(fmt.Sprintf("统计周期：%s    生成时间：%s", d.PeriodLabel, d.GeneratedAt))
+
+	doc.Heading("访问概况")
+	doc.Table(d.Summary)
+
+	doc.Heading("祭扫方式分布")
+	labels := make([]string, 0, len(d.Offerings.Rows))
+	values := make([]float64, 0, len(d.Offerings.Rows))
+	for _, row := range d.Offerings.Rows {
+		labels = append(labels, utils.FormatReportCell(row[0]))
+		count, _ := row[1].(int64)
+		values = append(values, float64(count))
+	}
+	doc.BarChart(labels, values)
+
+	doc.Heading("访客排行")
+	if len(d.Contributors.Rows) == 0 {
+		doc.Paragraph("本期暂无祭扫记录")
+	} else {
+		doc.Table(d.Contributors)
+	}
+
+	if len(d.Highlights) > 0 {
+		doc.Heading("报告亮点")
+		for _, highlight := range d.Highlights {
+			doc.Paragraph("· " + highlight)
+		}
+	}
+	for _, recommendation := range d.Recommendations {
+		doc.Paragraph(recommendation)
+	}
+	return doc
+}
+
+// GetReportExports 获取用户在某纪念馆的导出任务
+func (s *ReportExportService) GetReportExports(userID, memorialID string) ([]models.WorshipReportExport, error) {
+	var jobs []models.WorshipReportExport
+	err := s.db.Where("user_id = ? AND memorial_id = ?", userID, memorialID).
+		Order("created_at DESC").Limit(50).Find(&jobs).Error
+	return jobs, err
+}
+
+// GetReportExport 获取导出任务详情
+func (s *ReportExportService) GetReportExport(userID, exportID string) (*models.WorshipReportExport, error) {
+	var job models.WorshipReportExport
+	err := s.db.Where("id = ? AND user_id = ?", exportID, userID).First(&job).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("导出任务不存在")
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// DownloadReportExport 获取可下载的导出文件
+func (s *ReportExportService) DownloadReportExport(userID, exportID string) (*models.WorshipReportExport, error) {
+	job, err := s.GetReportExport(userID, exportID)
+	if err != nil {
+		return nil, err
+	}
+
+	if job.Status != ReportExportCompleted {
+		return nil, errors.New("导出尚未完成")
+	}
+	if job.ExpiresAt != nil && job.ExpiresAt.Before(time.Now()) {
+		return nil, errors.New("下载链接已过期")
+	}
+	if _, err := os.Stat(job.FilePath); os.IsNotExist(err) {
+		return nil, errors.New("导出文件不存在")
+	}
+	return job, nil
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
 	"time"
 	"yun-nian-memorial/internal/config"
 	"yun-nian-memorial/internal/models"
@@ -17,8 +18,9 @@ import (
 )
 
 type UserService struct {
-	db     *gorm.DB
-	config *config.Config
+	db           *gorm.DB
+	config       *config.Config
+	statsService *WorshipStatsService
 }
 
 type WechatLoginRequest struct {
@@ -43,12 +45,16 @@ type WechatSessionResponse struct {
 
 // UpcomingReminderResponse 即将到来的提醒响应结构
 type UpcomingReminderResponse struct {
-	ID           string    `json:"id"`
-	ReminderType string    `json:"reminder_type"`
-	ReminderDate time.Time `json:"reminder_date"`
-	Title        string    `json:"title"`
-	Content      string    `json:"content"`
-	DaysUntil    int       `json:"days_until"`
+	ID           string     `json:"id"`
+	ReminderType string     `json:"reminder_type"`
+	ReminderDate time.Time  `json:"reminder_date"`
+	Title        string     `json:"title"`
+	Content      string     `json:"content"`
+	DaysUntil    int        `json:"days_until"`
+	Rule         string     `json:"rule"`
+	RuleText     string     `json:"rule_text"`
+	Status       string     `json:"status,omitempty"` // 本人对这一次的处理：snoozed稍后提醒|dismissed不再提醒
+	SnoozedUntil *time.Time `json:"snoozed_until,omitempty"`
 	Memorial     struct {
 		ID        string `json:"id"`
 		Name      string `json:"name"`
@@ -62,8 +68,9 @@ type UpcomingReminderResponse struct {
 
 func NewUserService(db *gorm.DB, config *config.Config) *UserService {
 	return &UserService{
-		db:     db,
-		config: config,
+		db:           db,
+		config:       config,
+		statsService: NewWorshipStatsService(db),
 	}
 }
 
@@ -82,6 +89,7 @@ func (s *UserService) WechatLogin(req *WechatLoginRequest) (*WechatLoginResponse
 	// 2. 查找或创建用户
 	var user models.User
 	var isNewUser bool
+	now := time.Now()
 
 	err = s.db.Where("wechat_open_id = ?", sessionResp.OpenID).First(&user).Error
 	if err != nil {
@@ -94,6 +102,7 @@ func (s *UserService) WechatLogin(req *WechatLoginRequest) (*WechatLoginResponse
 				Nickname:      req.Nickname,
 				AvatarURL:     req.Avatar,
 				Status:        1,
+				LastLoginAt:   &now,
 			}
 
 			if err := s.db.Create(&user).Error; err != nil {
@@ -111,6 +120,7 @@ func (s *UserService) WechatLogin(req *WechatLoginRequest) (*WechatLoginResponse
 		if req.Avatar != "" {
 			user.AvatarURL = req.Avatar
 		}
+		user.LastLoginAt = &now
 		if err := s.db.Save(&user).Error; err != nil {
 			return nil, fmt.Errorf("更新用户信息失败: %v", err)
 		}
@@ -317,7 +327,8 @@ func (s *UserService) GetUserMemorialDetails(userID string, page, pageSize int)
 
 		// 统计留言数量
 		var messageCount int64
-		s.db.Model(&models.Message{}).Where("memorial_id = ?", memorial.ID).Count(&messageCount)
+		s.db.Model(&models.Message{}).Where("memorial_id = ?", memorial.ID).
+			Where(messageUnlockedClause, time.Now()).Count(&messageCount)
 
 		// 获取最近访客
 		var recentVisitors []models.VisitorRecord
@@ -414,9 +425,8 @@ func (s *UserService) GetUserStatistics(userID string) (map[string]interface{},
 	s.db.Model(&models.Memorial{}).Where("creator_id = ? AND status = ?", userID, 1).Count(&memorialCount)
 	stats["memorialCount"] = memorialCount
 
-	// 统计祭扫次数
-	var worshipCount int64
-	s.db.Model(&models.WorshipRecord{}).Where("user_id = ?", userID).Count(&worshipCount)
+	// 统计祭扫次数（读取每日汇总）
+	_, worshipCount := s.statsService.UserTypeCounts(userID)
 	stats["worshipCount"] = worshipCount
 
 	// 统计参与的家族圈数量
@@ -431,16 +441,12 @@ func (s *UserService) GetUserStatistics(userID string) (map[string]interface{},
 
 	// 统计发布的留言数量
 	var messageCount int64
-	s.db.Model(&models.Message{}).Where("user_id = ?", userID).Count(&messageCount)
+	s.db.Model(&models.Message{}).Where("user_id = ?", userID).
+		Where(messageUnlockedClause, time.Now()).Count(&messageCount)
 	stats["messageCount"] = messageCount
 
-	// 统计最近7天的活动
-	sevenDaysAgo := time.Now().AddDate(0, 0, -7)
-	var recentWorshipCount int64
-	s.db.Model(&models.WorshipRecord{}).
-		Where("user_id = ? AND created_at >= ?", userID, sevenDaysAgo).
-		Count(&recentWorshipCount)
-	stats["recentWorshipCount"] = recentWorshipCount
+	// 统计最近7天（含今天）的活动
+	stats["recentWorshipCount"] = s.statsService.UserWorshipsSince(userID, time.Now().AddDate(0, 0, -6))
 
 	// 获取用户创建的纪念馆的总访客数
 	var totalVisitors int64
@@ -547,20 +553,35 @@ func (s *UserService) GetUpcomingReminders(userID string) ([]*UpcomingReminderRe
 		memorialIDs = append(memorialIDs, memorialID)
 	}
 
-	// 5. Query reminders within next 30 days
-	now := time.Now()
-	thirtyDaysLater := now.AddDate(0, 0, 30)
+	// 5. Query reminders and expand recurrence rules within next 30 days
+	today := reminderDay(time.Now(), time.Local)
+	thirtyDaysLater := today.AddDate(0, 0, 30)
 
-	var reminders []models.MemorialReminder
+	var allReminders []*models.MemorialReminder
 	err = s.db.Preload("Memorial").
-		Where("memorial_id IN ? AND is_active = ? AND reminder_date BETWEEN ? AND ?",
-			memorialIDs, true, now.Format("2006-01-02"), thirtyDaysLater.Format("2006-01-02")).
-		Order("reminder_date ASC").
-		Find(&reminders).Error
+		Where("memorial_id IN ? AND is_active = ?", memorialIDs, true).
+		Find(&allReminders).Error
 	if err != nil {
 		return nil, fmt.Errorf("查询纪念日提醒失败: %v", err)
 	}
 
+	var reminders []*models.MemorialReminder
+	var reminderIDs []string
+	for _, reminder := range allReminders {
+		// 定时祈福只给设置人看
+		if reminder.ReminderType == ReminderTypeScheduledPrayer && reminder.CreatorID != "" && reminder.CreatorID != userID {
+			continue
+		}
+		fillReminderSchedule(reminder, today)
+		if reminder.NextDate == nil || reminder.NextDate.After(thirtyDaysLater) {
+			continue
+		}
+		reminders = append(reminders, reminder)
+		reminderIDs = append(reminderIDs, reminder.ID)
+	}
+	sort.SliceStable(reminders, func(i, j int) bool { return reminders[i].NextDate.Before(*reminders[j].NextDate) })
+	actions := reminderActionsForUser(s.db, userID, reminderIDs)
+
 	// 6. Load family information
 	var families []models.Family
 	err = s.db.Where("id IN ?", familyIDs).Find(&families).Error
@@ -588,15 +609,21 @@ func (s *UserService) GetUpcomingReminders(userID string) ([]*UpcomingReminderRe
 		}
 
 		// Calculate days until reminder
-		daysUntil := int(reminder.ReminderDate.Sub(now).Hours() / 24)
+		daysUntil := int(reminder.NextDate.Sub(today).Hours() / 24)
 
 		response := &UpcomingReminderResponse{
 			ID:           reminder.ID,
 			ReminderType: reminder.ReminderType,
-			ReminderDate: reminder.ReminderDate,
+			ReminderDate: *reminder.NextDate,
 			Title:        reminder.Title,
 			Content:      reminder.Content,
 			DaysUntil:    daysUntil,
+			Rule:         reminder.Rule,
+			RuleText:     reminder.RuleText,
+		}
+		if action := actions[reminder.ID+"|"+reminder.NextDate.Format("2006-01-02")]; action != nil {
+			response.Status = action.Status
+			response.SnoozedUntil = action.SnoozedUntil
 		}
 
 		response.Memorial.ID = reminder.Memorial.ID
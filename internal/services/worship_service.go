@@ -4,9 +4,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 	"yun-nian-memorial/internal/models"
+	"yun-nian-memorial/internal/utils"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -14,12 +18,25 @@ import (
 
 type WorshipService struct {
 	db            *gorm.DB
+	statsService  *WorshipStatsService
 	familyService *FamilyService
+	mediaService  *MediaService
+	contentFilter *ContentFilterService
+	notifications *NotificationService
+
+	sentimentAnalyzer utils.SentimentAnalyzer
+
+	// 热门祈福推荐索引，由 RefreshPrayerSuggestions 定期重建
+	suggestionMu        sync.RWMutex
+	suggestionIndex     *utils.PrayerSuggestionIndex
+	suggestionFestivals []utils.FestivalWindow
 }
 
 func NewWorshipService(db *gorm.DB) *WorshipService {
 	return &WorshipService{
-		db: db,
+		db:                db,
+		statsService:      NewWorshipStatsService(db),
+		sentimentAnalyzer: utils.NewLexiconSentimentAnalyzer(utils.DefaultSentimentLexicon()),
 	}
 }
 
@@ -28,6 +45,26 @@ func (s *WorshipService) SetFamilyService(familyService *FamilyService) {
 	s.familyService = familyService
 }
 
+// SetMediaService 设置媒体服务依赖（用于音视频留言的探测与关联）
+func (s *WorshipService) SetMediaService(mediaService *MediaService) {
+	s.mediaService = mediaService
+}
+
+// SetContentFilter 设置敏感词过滤服务依赖
+func (s *WorshipService) SetContentFilter(contentFilter *ContentFilterService) {
+	s.contentFilter = contentFilter
+}
+
+// SetNotificationService 设置通知服务依赖（时光胶囊解锁、审核结果）
+func (s *WorshipService) SetNotificationService(notifications *NotificationService) {
+	s.notifications = notifications
+}
+
+// SetSentimentAnalyzer 替换情感分析器（如加载自定义词典或接入第三方服务）
+func (s *WorshipService) SetSentimentAnalyzer(analyzer utils.SentimentAnalyzer) {
+	s.sentimentAnalyzer = analyzer
+}
+
 // 献花请求结构
 type OfferFlowersRequest struct {
 	FlowerType   string `json:"flowerType" binding:"required"`     // 花卉类型：chrysanthemum|carnation|lily|rose
@@ -58,20 +95,52 @@ type OfferTributeRequest struct {
 	Message     string   `json:"message"`                        // 供奉留言
 }
 
+// 家族合祭请求结构：同一份祭品同时献给多位先人，祭品细节同集体祭扫献祭
+type FamilyWorshipRequest struct {
+	MemorialIDs []string `json:"memorial_ids" binding:"required,min=1,max=20,dive,required"`
+	CollectiveOfferingRequest
+}
+
 // 祈福请求结构
 type CreatePrayerRequest struct {
-	Content  string `json:"content" binding:"required"` // 祈福内容
-	IsPublic bool   `json:"is_public"`                  // 是否公开显示
+	Content      string `json:"content" binding:"required"`                                                                           // 祈福内容
+	IsPublic     bool   `json:"is_public"`                                                                                            // 是否公开显示
+	Relationship string `json:"relationship" binding:"omitempty,oneof=parent grandparent spouse sibling child relative friend other"` // 与逝者关系，用于个性化推荐
 }
 
 // 留言请求结构
 type CreateMessageRequest struct {
 	MessageType string `json:"message_type" binding:"required,oneof=text audio video"` // 留言类型
 	Content     string `json:"content"`                                                // 文字内容
+	MediaFileID string `json:"media_file_id"`                                          // 已上传的媒体文件ID（优先）
 	MediaURL    string `json:"media_url"`                                              // 音频/视频URL
-	Duration    int    `json:"duration"`                                               // 音频/视频时长
+	Duration    int    `json:"duration"`                                               // 客户端上报时长，仅作参考，以服务端探测为准
+
+	// 时光胶囊：到解锁时间前对所有人隐藏（作者除外），解锁后通知接收人
+	UnlockAt     string   `json:"unlock_at"`                                                // 解锁时间 (格式: "2006-01-02 15:04:05")，为空表示立即可见
+	Audience     string   `json:"audience" binding:"omitempty,oneof=everyone family users"` // 可见范围，默认所有人
+	RecipientIDs []string `json:"recipient_ids"`                                            // audience 为 users 时的接收人
 }
 
+// 留言可见范围，见 utils.CanViewMessage
+const (
+	MessageAudienceEveryone = utils.MessageAudienceEveryone
+	MessageAudienceFamily   = utils.MessageAudienceFamily
+	MessageAudienceUsers    = utils.MessageAudienceUsers
+)
+
+const (
+	// 指定接收人上限
+	maxCapsuleRecipients = 50
+	// 解锁时间最远可设置的年数
+	maxCapsuleYears = 100
+	// 每轮解锁通知处理的留言数
+	capsuleReleaseBatchSize = 100
+)
+
+// messageUnlockedClause 已解锁（或非时光胶囊）留言的查询条件，参数为当前时间
+const messageUnlockedClause = "unlock_at IS NULL OR unlock_at <= ?"
+
 // 献花内容结构
 type FlowerContent struct {
 	FlowerType   string    `json:"flower_type"`
@@ -103,14 +172,8 @@ type TributeContent struct {
 	Message     string   `json:"message"`
 }
 
-// 献花
-func (s *WorshipService) OfferFlowers(userID, memorialID string, req *OfferFlowersRequest) error {
-	// 验证纪念馆是否存在且用户有权限访问
-	if err := s.validateMemorialAccess(userID, memorialID); err != nil {
-		return err
-	}
-
-	// 构建献花内容
+// 构建献花内容，校验定时送花时间
+func buildFlowerContent(req *OfferFlowersRequest) (FlowerContent, error) {
 	content := FlowerContent{
 		FlowerType:  req.FlowerType,
 		Quantity:    req.Quantity,
@@ -122,13 +185,56 @@ func (s *WorshipService) OfferFlowers(userID, memorialID string, req *OfferFlowe
 	if req.IsScheduled && req.ScheduleTime != "" {
 		scheduleTime, err := time.Parse("2006-01-02 15:04:05", req.ScheduleTime)
 		if err != nil {
-			return errors.New("定时时间格式错误")
+			return content, errors.New("定时时间格式错误")
 		}
 		if scheduleTime.Before(time.Now()) {
-			return errors.New("定时时间不能早于当前时间")
+			return content, errors.New("定时时间不能早于当前时间")
 		}
 		content.ScheduleTime = scheduleTime
 	}
+	return content, nil
+}
+
+// 构建点烛内容，根据燃烧时长计算熄灭时间
+func buildCandleContent(req *LightCandleRequest) CandleContent {
+	expireTime := time.Now().Add(time.Duration(req.Duration) * time.Minute)
+	return CandleContent{
+		CandleType: req.CandleType,
+		Duration:   req.Duration,
+		Message:    req.Message,
+		ExpireTime: expireTime.Format("2006-01-02 15:04:05"),
+	}
+}
+
+// 构建上香内容
+func buildIncenseContent(req *OfferIncenseRequest) IncenseContent {
+	return IncenseContent{
+		IncenseCount: req.IncenseCount,
+		IncenseType:  req.IncenseType,
+		Message:      req.Message,
+	}
+}
+
+// 构建供品内容
+func buildTributeContent(req *OfferTributeRequest) TributeContent {
+	return TributeContent{
+		TributeType: req.TributeType,
+		Items:       req.Items,
+		Message:     req.Message,
+	}
+}
+
+// 献花
+func (s *WorshipService) OfferFlowers(userID, memorialID string, req *OfferFlowersRequest) error {
+	// 验证纪念馆是否存在且用户有权限访问
+	if err := s.validateMemorialAccess(userID, memorialID); err != nil {
+		return err
+	}
+
+	content, err := buildFlowerContent(req)
+	if err != nil {
+		return err
+	}
 
 	contentJSON, _ := json.Marshal(content)
 
@@ -143,7 +249,7 @@ func (s *WorshipService) OfferFlowers(userID, memorialID string, req *OfferFlowe
 		UpdatedAt:   time.Now(),
 	}
 
-	if err := s.db.Create(record).Error; err != nil {
+	if err := createWorshipRecord(s.db, record); err != nil {
 		return err
 	}
 
@@ -162,16 +268,7 @@ func (s *WorshipService) LightCandle(userID, memorialID string, req *LightCandle
 		return err
 	}
 
-	// 计算蜡烛熄灭时间
-	expireTime := time.Now().Add(time.Duration(req.Duration) * time.Minute)
-
-	// 构建点烛内容
-	content := CandleContent{
-		CandleType: req.CandleType,
-		Duration:   req.Duration,
-		Message:    req.Message,
-		ExpireTime: expireTime.Format("2006-01-02 15:04:05"),
-	}
+	content := buildCandleContent(req)
 
 	contentJSON, _ := json.Marshal(content)
 
@@ -186,7 +283,7 @@ func (s *WorshipService) LightCandle(userID, memorialID string, req *LightCandle
 		UpdatedAt:   time.Now(),
 	}
 
-	return s.db.Create(record).Error
+	return createWorshipRecord(s.db, record)
 }
 
 // 上香
@@ -196,12 +293,7 @@ func (s *WorshipService) OfferIncense(userID, memorialID string, req *OfferIncen
 		return err
 	}
 
-	// 构建上香内容
-	content := IncenseContent{
-		IncenseCount: req.IncenseCount,
-		IncenseType:  req.IncenseType,
-		Message:      req.Message,
-	}
+	content := buildIncenseContent(req)
 
 	contentJSON, _ := json.Marshal(content)
 
@@ -216,7 +308,7 @@ func (s *WorshipService) OfferIncense(userID, memorialID string, req *OfferIncen
 		UpdatedAt:   time.Now(),
 	}
 
-	return s.db.Create(record).Error
+	return createWorshipRecord(s.db, record)
 }
 
 // 供奉供品
@@ -226,12 +318,7 @@ func (s *WorshipService) OfferTribute(userID, memorialID string, req *OfferTribu
 		return err
 	}
 
-	// 构建供品内容
-	content := TributeContent{
-		TributeType: req.TributeType,
-		Items:       req.Items,
-		Message:     req.Message,
-	}
+	content := buildTributeContent(req)
 
 	contentJSON, _ := json.Marshal(content)
 
@@ -246,7 +333,72 @@ func (s *WorshipService) OfferTribute(userID, memorialID string, req *OfferTribu
 		UpdatedAt:   time.Now(),
 	}
 
-	return s.db.Create(record).Error
+	return createWorshipRecord(s.db, record)
+}
+
+// 家族合祭：在多个纪念馆同时献上同样的祭品，任一纪念馆无权访问时全部不生效
+func (s *WorshipService) OfferFamilyWorship(userID string, req *FamilyWorshipRequest) ([]*models.WorshipRecord, error) {
+	memorialIDs := make([]string, 0, len(req.MemorialIDs))
+	seen := make(map[string]bool, len(req.MemorialIDs))
+	for _, id := range req.MemorialIDs {
+		if !seen[id] {
+			seen[id] = true
+			memorialIDs = append(memorialIDs, id)
+		}
+	}
+	for _, id := range memorialIDs {
+		if err := s.validateMemorialAccess(userID, id); err != nil {
+			return nil, err
+		}
+	}
+
+	content, err := buildCollectiveOfferingContent(&req.CollectiveOfferingRequest)
+	if err != nil {
+		return nil, err
+	}
+	contentJSON, _ := json.Marshal(content)
+
+	now := time.Now()
+	records := make([]*models.WorshipRecord, 0, len(memorialIDs))
+	screens := make([]*ContentScreenResult, 0, len(memorialIDs))
+	for _, id := range memorialIDs {
+		screen, err := s.contentFilter.Screen(ContentTypeFamilyWorship, userID, id, req.Message)
+		if err != nil {
+			return nil, err
+		}
+		screens = append(screens, screen)
+		records = append(records, &models.WorshipRecord{
+			ID:          uuid.New().String(),
+			MemorialID:  id,
+			UserID:      userID,
+			WorshipType: req.WorshipType,
+			Content:     string(contentJSON),
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		})
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		for _, record := range records {
+			if err := createWorshipRecord(tx, record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, record := range records {
+		s.contentFilter.RecordFlagged(screens[i], record.ID)
+	}
+
+	// 同步到家族圈，每个家族圈一条合祭动态
+	if s.familyService != nil {
+		s.familyService.SyncFamilyWorshipActivity(userID, memorialIDs, req.WorshipType, content)
+	}
+
+	return records, nil
 }
 
 // 创建祈福
@@ -256,6 +408,12 @@ func (s *WorshipService) CreatePrayer(userID, memorialID string, req *CreatePray
 		return nil, err
 	}
 
+	// 敏感词检测
+	screen, err := s.contentFilter.Screen(ContentTypePrayer, userID, memorialID, req.Content)
+	if err != nil {
+		return nil, err
+	}
+
 	// 创建祈福记录
 	prayer := &models.Prayer{
 		ID:         uuid.New().String(),
@@ -265,11 +423,15 @@ func (s *WorshipService) CreatePrayer(userID, memorialID string, req *CreatePray
 		IsPublic:   req.IsPublic,
 		CreatedAt:  time.Now(),
 		UpdatedAt:  time.Now(),
+
+		Relationship:     req.Relationship,
+		ModerationStatus: s.initialModerationStatus(userID, memorialID),
 	}
 
 	if err := s.db.Create(prayer).Error; err != nil {
 		return nil, err
 	}
+	s.contentFilter.RecordFlagged(screen, prayer.ID)
 
 	// 同时创建祭扫记录
 	contentJSON, _ := json.Marshal(map[string]interface{}{
@@ -287,7 +449,7 @@ func (s *WorshipService) CreatePrayer(userID, memorialID string, req *CreatePray
 		UpdatedAt:   time.Now(),
 	}
 
-	s.db.Create(record)
+	createWorshipRecord(s.db, record)
 
 	return prayer, nil
 }
@@ -303,10 +465,22 @@ func (s *WorshipService) CreateMessage(userID, memorialID string, req *CreateMes
 	if req.MessageType == "text" && req.Content == "" {
 		return nil, errors.New("文字留言内容不能为空")
 	}
-	if (req.MessageType == "audio" || req.MessageType == "video") && req.MediaURL == "" {
+	if (req.MessageType == "audio" || req.MessageType == "video") && req.MediaURL == "" && req.MediaFileID == "" {
 		return nil, errors.New("音频/视频留言必须提供媒体文件")
 	}
 
+	// 时光胶囊设置
+	unlockAt, audience, recipientIDs, err := s.parseCapsuleOptions(userID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// 敏感词检测
+	screen, err := s.contentFilter.Screen(ContentTypeMessage, userID, memorialID, req.Content)
+	if err != nil {
+		return nil, err
+	}
+
 	// 创建留言记录
 	message := &models.Message{
 		ID:          uuid.New().String(),
@@ -314,23 +488,72 @@ func (s *WorshipService) CreateMessage(userID, memorialID string, req *CreateMes
 		UserID:      userID,
 		MessageType: req.MessageType,
 		Content:     req.Content,
-		MediaURL:    req.MediaURL,
-		Duration:    req.Duration,
+		UnlockAt:    unlockAt,
+		Audience:    audience,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
+
+		ModerationStatus: s.initialModerationStatus(userID, memorialID),
+		IsLocked:         unlockAt != nil,
+		RecipientIDs:     recipientIDs,
 	}
 
-	if err := s.db.Create(message).Error; err != nil {
-		return nil, err
+	// 音视频留言：校验媒体归属并使用服务端探测的时长
+	if req.MessageType == "audio" || req.MessageType == "video" {
+		if s.mediaService == nil {
+			return nil, errors.New("媒体服务未初始化")
+		}
+		mediaFile, err := s.mediaService.ResolveMessageMedia(memorialID, req.MessageType, req.MediaFileID, req.MediaURL)
+		if err != nil {
+			return nil, err
+		}
+		message.MediaFileID = mediaFile.ID
+		message.MediaURL = mediaFile.FileURL
+		message.Duration = mediaFile.Duration
+		message.MediaFile = mediaFile
 	}
 
-	// 同时创建祭扫记录
-	contentJSON, _ := json.Marshal(map[string]interface{}{
-		"message_type": req.MessageType,
-		"content":      req.Content,
-		"media_url":    req.MediaURL,
-		"duration":     req.Duration,
+	// 文字部分的情感分析结果随留言保存，供统计使用
+	s.applyMessageEmotion(message)
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(message).Error; err != nil {
+			return err
+		}
+		for _, recipientID := range recipientIDs {
+			recipient := &models.MessageRecipient{
+				ID:        uuid.New().String(),
+				MessageID: message.ID,
+				UserID:    recipientID,
+				CreatedAt: time.Now(),
+			}
+			if err := tx.Create(recipient).Error; err != nil {
+				return err
+			}
+		}
+		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+	s.contentFilter.RecordFlagged(screen, message.ID)
+
+	// 同时创建祭扫记录；时光胶囊和限定范围的留言不在祭扫记录中保存内容
+	recordContent := map[string]interface{}{
+		"message_type":  req.MessageType,
+		"content":       req.Content,
+		"media_file_id": message.MediaFileID,
+		"media_url":     message.MediaURL,
+		"duration":      message.Duration,
+	}
+	if message.UnlockAt != nil || message.Audience != MessageAudienceEveryone {
+		recordContent = map[string]interface{}{
+			"message_type": req.MessageType,
+			"message_id":   message.ID,
+			"time_capsule": message.UnlockAt != nil,
+		}
+	}
+	contentJSON, _ := json.Marshal(recordContent)
 
 	record := &models.WorshipRecord{
 		ID:          uuid.New().String(),
@@ -342,7 +565,7 @@ func (s *WorshipService) CreateMessage(userID, memorialID string, req *CreateMes
 		UpdatedAt:   time.Now(),
 	}
 
-	s.db.Create(record)
+	createWorshipRecord(s.db, record)
 
 	return message, nil
 }
@@ -387,13 +610,17 @@ func (s *WorshipService) GetPrayerWall(userID, memorialID string, page, pageSize
 	// 计算偏移量
 	offset := (page - 1) * pageSize
 
-	// 查询总数（只查询公开的祈福）
-	s.db.Model(&models.Prayer{}).Where("memorial_id = ? AND is_public = ?", memorialID, true).Count(&total)
-
-	// 查询记录，包含用户信息
-	err := s.db.Preload("User").
+	// 只展示公开且审核通过的祈福，作者可以看到自己待审核的内容
+	query := s.db.Model(&models.Prayer{}).
 		Where("memorial_id = ? AND is_public = ?", memorialID, true).
-		Order("created_at DESC").
+		Where("moderation_status = ? OR (moderation_status = ? AND user_id = ?)", ModerationStatusApproved, ModerationStatusPending, userID)
+
+	// 查询总数
+	query.Count(&total)
+
+	// 查询记录，包含用户信息，置顶内容优先
+	err := query.Preload("User").
+		Order("is_pinned DESC, pinned_at DESC, created_at DESC").
 		Offset(offset).
 		Limit(pageSize).
 		Find(&prayers).Error
@@ -414,18 +641,342 @@ func (s *WorshipService) GetTimeMessages(userID, memorialID string, page, pageSi
 	// 计算偏移量
 	offset := (page - 1) * pageSize
 
+	// 只展示审核通过、已解锁且在可见范围内的留言，作者可以看到自己待审核和未解锁的内容
+	query := s.db.Model(&models.Message{}).
+		Where("memorial_id = ?", memorialID).
+		Where("moderation_status = ? OR (moderation_status = ? AND user_id = ?)", ModerationStatusApproved, ModerationStatusPending, userID)
+	query = s.visibleMessages(query, userID, memorialID)
+
 	// 查询总数
-	s.db.Model(&models.Message{}).Where("memorial_id = ?", memorialID).Count(&total)
+	query.Count(&total)
 
-	// 查询记录，包含用户信息
-	err := s.db.Preload("User").
-		Where("memorial_id = ?", memorialID).
-		Order("created_at DESC").
+	// 查询记录，包含用户信息，置顶内容优先
+	err := query.Preload("User").
+		Order("is_pinned DESC, pinned_at DESC, created_at DESC").
 		Offset(offset).
 		Limit(pageSize).
 		Find(&messages).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	s.attachMessageMedia(messages)
+	s.attachCapsuleInfo(userID, messages)
+
+	return messages, total, nil
+}
+
+// attachMessageMedia 批量填充留言关联的媒体探测信息（封面、波形）
+func (s *WorshipService) attachMessageMedia(messages []*models.Message) {
+	if s.mediaService == nil {
+		return
+	}
+
+	var mediaFileIDs []string
+	for _, message := range messages {
+		if message.MediaFileID != "" {
+			mediaFileIDs = append(mediaFileIDs, message.MediaFileID)
+		}
+	}
+
+	mediaFiles, err := s.mediaService.GetMediaFilesByIDs(mediaFileIDs)
+	if err != nil {
+		return
+	}
+	for _, message := range messages {
+		message.MediaFile = mediaFiles[message.MediaFileID]
+	}
+}
+
+// parseCapsuleOptions 校验时光胶囊的解锁时间、可见范围和接收人
+func (s *WorshipService) parseCapsuleOptions(userID string, req *CreateMessageRequest) (*time.Time, string, []string, error) {
+	var unlockAt *time.Time
+	if req.UnlockAt != "" {
+		t, err := time.ParseInLocation("2006-01-02 15:04:05", req.UnlockAt, time.Local)
+		if err != nil {
+			return nil, "", nil, errors.New("解锁时间格式错误")
+		}
+		if !t.After(time.Now()) {
+			return nil, "", nil, errors.New("解锁时间必须晚于当前时间")
+		}
+		if t.After(time.Now().AddDate(maxCapsuleYears, 0, 0)) {
+			return nil, "", nil, fmt.Errorf("解锁时间不能超过%d年", maxCapsuleYears)
+		}
+		unlockAt = &t
+	}
+
+	audience := req.Audience
+	if audience == "" {
+		audience = MessageAudienceEveryone
+	}
+	if audience != MessageAudienceUsers {
+		if len(req.RecipientIDs) > 0 {
+			return nil, "", nil, errors.New("只有指定用户可见时才能设置接收人")
+		}
+		return unlockAt, audience, nil, nil
+	}
+
+	// 去重并排除作者本人
+	seen := make(map[string]bool)
+	var recipientIDs []string
+	for _, id := range req.RecipientIDs {
+		id = strings.TrimSpace(id)
+		if id == "" || id == userID || seen[id] {
+			continue
+		}
+		seen[id] = true
+		recipientIDs = append(recipientIDs, id)
+	}
+	if len(recipientIDs) == 0 {
+		return nil, "", nil, errors.New("指定用户可见时至少需要一位接收人")
+	}
+	if len(recipientIDs) > maxCapsuleRecipients {
+		return nil, "", nil, fmt.Errorf("接收人不能超过%d位", maxCapsuleRecipients)
+	}
+
+	var count int64
+	s.db.Model(&models.User{}).Where("id IN ?", recipientIDs).Count(&count)
+	if count != int64(len(recipientIDs)) {
+		return nil, "", nil, errors.New("接收人不存在")
+	}
+	return unlockAt, audience, recipientIDs, nil
+}
+
+// visibleMessages 限定观看者可见的留言：已解锁且在可见范围内，作者始终可以看到自己的留言。
+// 分页查询用的 SQL 版本，条件与 utils.CanViewMessage（见 filterVisibleMessages）一致，修改时需同步
+func (s *WorshipService) visibleMessages(query *gorm.DB, viewerID, memorialID string) *gorm.DB {
+	audiences := []string{MessageAudienceEveryone}
+	if s.isMemorialFamily(viewerID, memorialID) {
+		audiences = append(audiences, MessageAudienceFamily)
+	}
+
+	return query.Where("user_id = ? OR (("+messageUnlockedClause+") AND (audience IN ? OR (audience = ? AND id IN (?))))",
+		viewerID, time.Now(), audiences, MessageAudienceUsers,
+		s.db.Model(&models.MessageRecipient{}).Select("message_id").Where("user_id = ?", viewerID))
+}
+
+// filterVisibleMessages 按 utils.CanViewMessage 过滤纪念馆中观看者可见的留言，用于不分页的场景，如数据导出
+func filterVisibleMessages(db *gorm.DB, viewerID, memorialID string, messages []models.Message) []models.Message {
+	isFamily := isMemorialFamily(db, viewerID, memorialID)
+	var userMessageIDs []string
+	for _, message := range messages {
+		if message.Audience == MessageAudienceUsers {
+			userMessageIDs = append(userMessageIDs, message.ID)
+		}
+	}
+	received := make(map[string]bool)
+	if len(userMessageIDs) > 0 {
+		var ids []string
+		db.Model(&models.MessageRecipient{}).
+			Where("user_id = ? AND message_id IN ?", viewerID, userMessageIDs).
+			Pluck("message_id", &ids)
+		for _, id := range ids {
+			received[id] = true
+		}
+	}
+
+	now := time.Now()
+	visible := make([]models.Message, 0, len(messages))
+	for _, message := range messages {
+		viewer := utils.MessageViewer{
+			IsAuthor:    message.UserID == viewerID,
+			IsFamily:    isFamily,
+			IsRecipient: received[message.ID],
+		}
+		if utils.CanViewMessage(message.UnlockAt, message.Audience, viewer, now) {
+			visible = append(visible, message)
+		}
+	}
+	return visible
+}
+
+// isMemorialFamily 是否为纪念馆创建者或关联家族的成员
+func (s *WorshipService) isMemorialFamily(userID, memorialID string) bool {
+	return isMemorialFamily(s.db, userID, memorialID)
+}
+
+// isMemorialFamily 是否为纪念馆创建者或关联家族的成员（含继承的成员身份）
+func isMemorialFamily(db *gorm.DB, userID, memorialID string) bool {
+	var count int64
+	db.Model(&models.Memorial{}).Where("id = ? AND creator_id = ?", memorialID, userID).Count(&count)
+	if count > 0 {
+		return true
+	}
+
+	return utils.NewPermissionManager(db).IsMemorialFamilyMember(userID, memorialID)
+}
+
+// memorialFamilyUserIDs 纪念馆创建者及关联家族的全部成员，包括继承成员身份的宗族与支系成员，与 isMemorialFamily 的判断一致
+func memorialFamilyUserIDs(db *gorm.DB, memorialID string) []string {
+	var memorial models.Memorial
+	db.Select("id, creator_id").First(&memorial, "id = ?", memorialID)
+
+	memberIDs, _ := utils.NewPermissionManager(db).GetMemorialFamilyMemberIDs(memorialID)
+
+	if memorial.CreatorID != "" {
+		memberIDs = append(memberIDs, memorial.CreatorID)
+	}
+	return memberIDs
+}
+
+// attachCapsuleInfo 标记作者自己未解锁的留言，并为作者填充指定接收人
+func (s *WorshipService) attachCapsuleInfo(viewerID string, messages []*models.Message) {
+	now := time.Now()
+	var ownUserMessages []string
+	for _, message := range messages {
+		message.IsLocked = !utils.MessageUnlocked(message.UnlockAt, now)
+		if message.UserID == viewerID && message.Audience == MessageAudienceUsers {
+			ownUserMessages = append(ownUserMessages, message.ID)
+		}
+	}
+	if len(ownUserMessages) == 0 {
+		return
+	}
+
+	var recipients []models.MessageRecipient
+	s.db.Where("message_id IN ?", ownUserMessages).Find(&recipients)
+	recipientMap := make(map[string][]string)
+	for _, recipient := range recipients {
+		recipientMap[recipient.MessageID] = append(recipientMap[recipient.MessageID], recipient.UserID)
+	}
+	for _, message := range messages {
+		if message.UserID == viewerID {
+			message.RecipientIDs = recipientMap[message.ID]
+		}
+	}
+}
+
+// ReleaseDueTimeCapsules 处理到期且已审核通过的时光胶囊：登记接收人并发送解锁通知。
+// 通过条件更新认领留言，多实例同时运行时每个胶囊只通知一次，返回本轮处理的数量
+func (s *WorshipService) ReleaseDueTimeCapsules() (int, error) {
+	var messages []models.Message
+	err := s.db.Where("unlock_at IS NOT NULL AND unlock_at <= ? AND unlock_notified_at IS NULL AND moderation_status = ?",
+		time.Now(), ModerationStatusApproved).
+		Order("unlock_at ASC").
+		Limit(capsuleReleaseBatchSize).
+		Find(&messages).Error
+	if err != nil {
+		return 0, err
+	}
+
+	released := 0
+	for _, message := range messages {
+		now := time.Now()
+		result := s.db.Model(&models.Message{}).
+			Where("id = ? AND unlock_notified_at IS NULL", message.ID).
+			Update("unlock_notified_at", now)
+		if result.Error != nil || result.RowsAffected == 0 {
+			continue
+		}
 
-	return messages, total, err
+		// 面向家人或所有人的胶囊按解锁时的家族成员登记接收人
+		if message.Audience != MessageAudienceUsers {
+			for _, recipientID := range memorialFamilyUserIDs(s.db, message.MemorialID) {
+				if recipientID == message.UserID {
+					continue
+				}
+				var count int64
+				s.db.Model(&models.MessageRecipient{}).Where("message_id = ? AND user_id = ?", message.ID, recipientID).Count(&count)
+				if count > 0 {
+					continue
+				}
+				s.db.Create(&models.MessageRecipient{
+					ID:        uuid.New().String(),
+					MessageID: message.ID,
+					UserID:    recipientID,
+					CreatedAt: now,
+				})
+			}
+		}
+
+		var recipientIDs []string
+		s.db.Model(&models.MessageRecipient{}).
+			Where("message_id = ? AND notified_at IS NULL", message.ID).
+			Pluck("user_id", &recipientIDs)
+		s.db.Model(&models.MessageRecipient{}).
+			Where("message_id = ? AND notified_at IS NULL", message.ID).
+			Update("notified_at", now)
+		s.notifyTimeCapsuleUnlocked(&message, recipientIDs)
+		released++
+	}
+	return released, nil
+}
+
+// notifyTimeCapsuleUnlocked 通知接收人时光胶囊已解锁
+func (s *WorshipService) notifyTimeCapsuleUnlocked(message *models.Message, recipientIDs []string) {
+	var memorial models.Memorial
+	s.db.Select("id", "deceased_name").First(&memorial, "id = ?", message.MemorialID)
+	err := s.notifications.Notify(recipientIDs, &NotificationInput{
+		Category: NotificationCategoryReminder,
+		Type:     NotificationTypeTimeCapsuleUnlocked,
+		Title:    "时光胶囊已解锁",
+		Content:  fmt.Sprintf("“%s”纪念馆有一封留给您的时光胶囊已解锁", memorial.DeceasedName),
+		Data:     map[string]interface{}{"memorial_id": message.MemorialID, "message_id": message.ID},
+	})
+	if err != nil {
+		fmt.Printf("发送时光胶囊 %s 解锁通知失败: %v\n", message.ID, err)
+	}
+}
+
+// StartTimeCapsuleDispatcher 定期检查到期的时光胶囊
+func (s *WorshipService) StartTimeCapsuleDispatcher(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			if _, err := s.ReleaseDueTimeCapsules(); err != nil {
+				fmt.Printf("处理时光胶囊失败: %v\n", err)
+			}
+		}
+	}()
+}
+
+// GetReceivedTimeCapsules 获取寄给当前用户且已解锁的时光胶囊
+func (s *WorshipService) GetReceivedTimeCapsules(userID string, unreadOnly bool, page, pageSize int) ([]*models.MessageRecipient, int64, error) {
+	var recipients []*models.MessageRecipient
+	var total int64
+
+	offset := (page - 1) * pageSize
+
+	query := s.db.Model(&models.MessageRecipient{}).
+		Where("user_id = ? AND notified_at IS NOT NULL", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+
+	query.Count(&total)
+
+	err := query.Preload("Message").Preload("Message.User").Preload("Message.Memorial").
+		Order("notified_at DESC").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&recipients).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var messages []*models.Message
+	for _, recipient := range recipients {
+		if recipient.Message != nil {
+			messages = append(messages, recipient.Message)
+		}
+	}
+	s.attachMessageMedia(messages)
+
+	return recipients, total, nil
+}
+
+// MarkTimeCapsuleRead 标记时光胶囊为已读
+func (s *WorshipService) MarkTimeCapsuleRead(userID, messageID string) error {
+	result := s.db.Model(&models.MessageRecipient{}).
+		Where("message_id = ? AND user_id = ? AND notified_at IS NOT NULL", messageID, userID).
+		Update("read_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("时光胶囊不存在")
+	}
+	return nil
 }
 
 // 续烛功能
@@ -546,14 +1097,8 @@ func (s *WorshipService) validateMemorialAccess(userID, memorialID string) error
 	// 检查隐私设置
 	if memorial.PrivacyLevel == 2 { // 私密
 		if memorial.CreatorID != userID {
-			// 检查是否是家族成员
-			var count int64
-			s.db.Table("family_members fm").
-				Joins("JOIN memorial_families mf ON fm.family_id = mf.family_id").
-				Where("mf.memorial_id = ? AND fm.user_id = ?", memorialID, userID).
-				Count(&count)
-
-			if count == 0 {
+			// 检查是否是家族成员（含宗族与支系继承的成员身份）
+			if !utils.NewPermissionManager(s.db).IsMemorialFamilyMember(userID, memorialID) {
 				return errors.New("无权访问此纪念馆")
 			}
 		}
@@ -571,39 +1116,21 @@ func (s *WorshipService) GetWorshipStatistics(userID, memorialID string) (map[st
 
 	stats := make(map[string]interface{})
 
-	// 统计各类祭扫行为的数量
+	// 统计各类祭扫行为的数量（读取每日汇总）
+	typeCounts, totalVisits := s.statsService.MemorialTypeCounts(memorialID)
 	worshipTypes := []string{"flower", "candle", "incense", "tribute", "prayer", "message"}
-
 	for _, worshipType := range worshipTypes {
-		var count int64
-		s.db.Model(&models.WorshipRecord{}).
-			Where("memorial_id = ? AND worship_type = ?", memorialID, worshipType).
-			Count(&count)
-		stats[worshipType+"_count"] = count
+		stats[worshipType+"_count"] = typeCounts[worshipType]
 	}
 
 	// 统计总访问次数
-	var totalVisits int64
-	s.db.Model(&models.WorshipRecord{}).
-		Where("memorial_id = ?", memorialID).
-		Count(&totalVisits)
 	stats["total_visits"] = totalVisits
 
 	// 统计独立访客数
-	var uniqueVisitors int64
-	s.db.Model(&models.WorshipRecord{}).
-		Where("memorial_id = ?", memorialID).
-		Distinct("user_id").
-		Count(&uniqueVisitors)
-	stats["unique_visitors"] = uniqueVisitors
+	stats["unique_visitors"] = s.statsService.MemorialUniqueVisitors(memorialID)
 
-	// 统计最近7天的访问情况
-	sevenDaysAgo := time.Now().AddDate(0, 0, -7)
-	var recentVisits int64
-	s.db.Model(&models.WorshipRecord{}).
-		Where("memorial_id = ? AND created_at >= ?", memorialID, sevenDaysAgo).
-		Count(&recentVisits)
-	stats["recent_visits"] = recentVisits
+	// 统计最近7天（含今天）的访问情况
+	stats["recent_visits"] = s.statsService.MemorialWorshipsSince(memorialID, time.Now().AddDate(0, 0, -6))
 
 	return stats, nil
 }
@@ -668,14 +1195,15 @@ func (s *WorshipService) GeneratePrayerCard(templateID, content, userName string
 
 // 留言审核状态
 type MessageModerationStatus struct {
-	IsApproved bool   `json:"is_approved"`
-	Reason     string `json:"reason,omitempty"`
+	IsApproved bool                   `json:"is_approved"`
+	Reason     string                 `json:"reason,omitempty"`
+	Matches    []utils.SensitiveMatch `json:"matches,omitempty"`
 }
 
 // 审核留言内容
 func (s *WorshipService) ModerateMessage(messageID string) (*MessageModerationStatus, error) {
 	var message models.Message
-	err := s.db.First(&message, "id = ?", messageID).Error
+	err := s.db.Where(messageUnlockedClause, time.Now()).First(&message, "id = ?", messageID).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("留言不存在")
@@ -683,22 +1211,290 @@ func (s *WorshipService) ModerateMessage(messageID string) (*MessageModerationSt
 		return nil, err
 	}
 
-	// 这里应该调用内容审核服务
-	// 目前返回模拟的审核结果
 	status := &MessageModerationStatus{
 		IsApproved: true,
-		Reason:     "",
+	}
+	if s.contentFilter == nil {
+		return status, nil
 	}
 
-	// 实际实现中，这里会：
-	// 1. 调用腾讯云内容安全API审核文本/音频/视频
-	// 2. 检查敏感词
-	// 3. 更新留言的审核状态
-	// 4. 如果不通过，记录原因
+	// 音视频留言目前只检测文字部分
+	status.Matches = s.contentFilter.MatchText(message.Content)
+	if utils.HasBlockingMatch(status.Matches) {
+		status.IsApproved = false
+		status.Reason = "留言包含违规词汇"
+	} else if len(status.Matches) > 0 {
+		status.Reason = "留言包含需人工复核的词汇"
+	}
 
 	return status, nil
 }
 
+// 留言和祈福的审核状态
+const (
+	ModerationStatusPending  = "pending"  // 待馆主审核
+	ModerationStatusApproved = "approved" // 已公开
+	ModerationStatusRejected = "rejected" // 审核未通过
+	ModerationStatusHidden   = "hidden"   // 已隐藏
+)
+
+// 馆主审核操作
+const (
+	ModerationActionApprove = "approve"
+	ModerationActionReject  = "reject"
+	ModerationActionHide    = "hide"
+	ModerationActionPin     = "pin"
+	ModerationActionUnpin   = "unpin"
+)
+
+// 纪念馆审核设置
+type MemorialModerationSettings struct {
+	MemorialID      string `json:"memorial_id"`
+	RequireApproval bool   `json:"require_approval"`
+	PendingMessages int64  `json:"pending_messages"`
+	PendingPrayers  int64  `json:"pending_prayers"`
+}
+
+// 更新审核设置请求
+type UpdateModerationSettingsRequest struct {
+	RequireApproval *bool `json:"require_approval" binding:"required"`
+}
+
+// 馆主审核请求
+type ReviewWorshipContentRequest struct {
+	Action string `json:"action" binding:"required,oneof=approve reject hide pin unpin"`
+	Note   string `json:"note" binding:"max=255"`
+}
+
+// initialModerationStatus 新提交内容的初始状态：开启审核的纪念馆中，非馆主提交的内容需审核后公开
+func (s *WorshipService) initialModerationStatus(userID, memorialID string) string {
+	var memorial models.Memorial
+	if err := s.db.Select("creator_id", "require_approval").First(&memorial, "id = ?", memorialID).Error; err != nil {
+		return ModerationStatusApproved
+	}
+	if memorial.RequireApproval && memorial.CreatorID != userID {
+		return ModerationStatusPending
+	}
+	return ModerationStatusApproved
+}
+
+// validateMemorialOwner 验证用户是否为纪念馆创建者
+func (s *WorshipService) validateMemorialOwner(userID, memorialID string) (*models.Memorial, error) {
+	var memorial models.Memorial
+	err := s.db.First(&memorial, "id = ? AND status = ?", memorialID, 1).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("纪念馆不存在")
+		}
+		return nil, err
+	}
+	if memorial.CreatorID != userID {
+		return nil, errors.New("只有纪念馆创建者可以管理留言和祈福")
+	}
+	return &memorial, nil
+}
+
+// GetModerationSettings 获取纪念馆的留言审核设置
+func (s *WorshipService) GetModerationSettings(userID, memorialID string) (*MemorialModerationSettings, error) {
+	memorial, err := s.validateMemorialOwner(userID, memorialID)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := &MemorialModerationSettings{
+		MemorialID:      memorialID,
+		RequireApproval: memorial.RequireApproval,
+	}
+	s.db.Model(&models.Message{}).Where("memorial_id = ? AND moderation_status = ?", memorialID, ModerationStatusPending).
+		Where(messageUnlockedClause, time.Now()).Count(&settings.PendingMessages)
+	s.db.Model(&models.Prayer{}).Where("memorial_id = ? AND moderation_status = ?", memorialID, ModerationStatusPending).Count(&settings.PendingPrayers)
+
+	return settings, nil
+}
+
+// UpdateModerationSettings 开启或关闭先审后发
+// 关闭时已在队列中的内容保持待审核，需馆主逐条处理
+func (s *WorshipService) UpdateModerationSettings(userID, memorialID string, req *UpdateModerationSettingsRequest) (*MemorialModerationSettings, error) {
+	if _, err := s.validateMemorialOwner(userID, memorialID); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Model(&models.Memorial{}).Where("id = ?", memorialID).
+		Update("require_approval", *req.RequireApproval).Error; err != nil {
+		return nil, err
+	}
+
+	return s.GetModerationSettings(userID, memorialID)
+}
+
+// GetModerationQueue 获取馆主审核列表，contentType 为 message 或 prayer，status 为空时返回待审核内容
+func (s *WorshipService) GetModerationQueue(userID, memorialID, contentType, status string, page, pageSize int) (interface{}, int64, error) {
+	if _, err := s.validateMemorialOwner(userID, memorialID); err != nil {
+		return nil, 0, err
+	}
+
+	if status == "" {
+		status = ModerationStatusPending
+	}
+	if !isValidModerationStatus(status) {
+		return nil, 0, errors.New("无效的审核状态")
+	}
+
+	offset := (page - 1) * pageSize
+	var total int64
+
+	switch contentType {
+	case "message", "":
+		var messages []*models.Message
+		// 时光胶囊解锁后才进入审核列表，避免提前泄露内容
+		query := s.db.Model(&models.Message{}).
+			Where("memorial_id = ? AND moderation_status = ?", memorialID, status).
+			Where(messageUnlockedClause, time.Now())
+		query.Count(&total)
+		err := query.Preload("User").Order("created_at ASC").Offset(offset).Limit(pageSize).Find(&messages).Error
+		if err != nil {
+			return nil, 0, err
+		}
+		s.attachMessageMedia(messages)
+		return messages, total, nil
+	case "prayer":
+		var prayers []*models.Prayer
+		query := s.db.Model(&models.Prayer{}).Where("memorial_id = ? AND moderation_status = ?", memorialID, status)
+		query.Count(&total)
+		err := query.Preload("User").Order("created_at ASC").Offset(offset).Limit(pageSize).Find(&prayers).Error
+		return prayers, total, err
+	default:
+		return nil, 0, errors.New("不支持的内容类型")
+	}
+}
+
+// ReviewWorshipContent 馆主审核、隐藏或置顶留言/祈福
+func (s *WorshipService) ReviewWorshipContent(userID, contentType, contentID string, req *ReviewWorshipContentRequest) error {
+	var model interface{}
+	var memorialID, authorID, currentStatus string
+
+	switch contentType {
+	case "message":
+		var message models.Message
+		if err := s.db.First(&message, "id = ?", contentID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("留言不存在")
+			}
+			return err
+		}
+		model, memorialID, authorID, currentStatus = &message, message.MemorialID, message.UserID, message.ModerationStatus
+	case "prayer":
+		var prayer models.Prayer
+		if err := s.db.First(&prayer, "id = ?", contentID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("祈福不存在")
+			}
+			return err
+		}
+		model, memorialID, authorID, currentStatus = &prayer, prayer.MemorialID, prayer.UserID, prayer.ModerationStatus
+	default:
+		return errors.New("不支持的内容类型")
+	}
+
+	if _, err := s.validateMemorialOwner(userID, memorialID); err != nil {
+		return err
+	}
+
+	updates, err := moderationUpdates(currentStatus, req.Action)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if req.Action != ModerationActionPin && req.Action != ModerationActionUnpin {
+		updates["moderation_note"] = req.Note
+		updates["moderated_by"] = userID
+		updates["moderated_at"] = &now
+	}
+	updates["updated_at"] = now
+
+	if err := s.db.Model(model).Updates(updates).Error; err != nil {
+		return err
+	}
+
+	// 审核状态有变化时通知作者，置顶和作者本人操作不通知
+	if status, ok := updates["moderation_status"].(string); ok && status != currentStatus && authorID != userID {
+		notifyModerationResult(s.notifications, authorID, contentType, contentID, memorialID, status, req.Note)
+	}
+	return nil
+}
+
+// notifyModerationResult 通知作者留言、祈福的审核结果，馆主和平台管理员审核共用
+func notifyModerationResult(notifications *NotificationService, authorID, contentType, contentID, memorialID, status, note string) {
+	label := map[string]string{"message": "留言", "prayer": "祈福"}[contentType]
+	result := map[string]string{
+		ModerationStatusApproved: "已通过审核并公开",
+		ModerationStatusRejected: "未通过审核",
+		ModerationStatusHidden:   "已被纪念馆管理者隐藏",
+	}[status]
+	if result == "" {
+		return
+	}
+	content := fmt.Sprintf("您的%s%s", label, result)
+	if note != "" {
+		content += "：" + note
+	}
+	err := notifications.Notify([]string{authorID}, &NotificationInput{
+		Category: NotificationCategoryModeration,
+		Type:     NotificationTypeModerationResult,
+		Title:    label + "审核结果",
+		Content:  content,
+		Data:     map[string]interface{}{"content_type": contentType, "content_id": contentID, "memorial_id": memorialID, "status": status},
+	})
+	if err != nil {
+		fmt.Printf("发送审核结果通知失败: %v\n", err)
+	}
+}
+
+// moderationUpdates 根据当前状态和操作计算需要更新的字段
+func moderationUpdates(currentStatus, action string) (map[string]interface{}, error) {
+	updates := make(map[string]interface{})
+
+	switch action {
+	case ModerationActionApprove:
+		updates["moderation_status"] = ModerationStatusApproved
+	case ModerationActionReject:
+		if currentStatus != ModerationStatusPending {
+			return nil, errors.New("只能拒绝待审核的内容")
+		}
+		updates["moderation_status"] = ModerationStatusRejected
+		updates["is_pinned"] = false
+		updates["pinned_at"] = nil
+	case ModerationActionHide:
+		if currentStatus == ModerationStatusRejected {
+			return nil, errors.New("已拒绝的内容无需隐藏")
+		}
+		updates["moderation_status"] = ModerationStatusHidden
+		updates["is_pinned"] = false
+		updates["pinned_at"] = nil
+	case ModerationActionPin:
+		if currentStatus != ModerationStatusApproved {
+			return nil, errors.New("只能置顶已公开的内容")
+		}
+		updates["is_pinned"] = true
+		updates["pinned_at"] = time.Now()
+	case ModerationActionUnpin:
+		updates["is_pinned"] = false
+		updates["pinned_at"] = nil
+	default:
+		return nil, errors.New("无效的审核操作")
+	}
+
+	return updates, nil
+}
+
+func isValidModerationStatus(status string) bool {
+	switch status {
+	case ModerationStatusPending, ModerationStatusApproved, ModerationStatusRejected, ModerationStatusHidden:
+		return true
+	}
+	return false
+}
+
 // 获取用户的祭扫历史统计
 func (s *WorshipService) GetUserWorshipHistory(userID string, page, pageSize int) (map[string]interface{}, error) {
 	offset := (page - 1) * pageSize
@@ -769,15 +1565,24 @@ func (s *WorshipService) CreateScheduledPrayer(userID string, req *ScheduledPray
 		return errors.New("定时时间不能早于当前时间")
 	}
 
-	// 创建定时祈福记录
+	// 创建定时祈福记录，在定时的时刻提醒设置人；重复暂只支持每年，其他频率按一次提醒
+	rule := ""
+	if req.IsRecurring && req.RecurringType == "yearly" {
+		rule = "FREQ=YEARLY"
+	}
+	scheduleTime := req.ScheduleTime.In(time.Local)
 	reminder := &models.MemorialReminder{
 		ID:           uuid.New().String(),
 		MemorialID:   req.MemorialID,
-		ReminderType: "scheduled_prayer",
-		ReminderDate: req.ScheduleTime,
+		ReminderType: ReminderTypeScheduledPrayer,
+		ReminderDate: scheduleTime,
 		Title:        "定时祈福提醒",
 		Content:      req.Content,
 		IsActive:     true,
+		CreatorID:    userID,
+		Rule:         rule,
+		LeadDays:     "[0]",
+		RemindTime:   scheduleTime.Format("15:04"),
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
 	}
@@ -785,119 +1590,168 @@ func (s *WorshipService) CreateScheduledPrayer(userID string, req *ScheduledPray
 	return s.db.Create(reminder).Error
 }
 
-// 获取热门祈福内容（用于推荐）
-func (s *WorshipService) GetPopularPrayerContents(limit int) ([]string, error) {
-	// 这里应该基于祈福内容的点赞数、使用频率等来推荐
-	// 目前返回一些预设的热门祈福内容
-	popularContents := []string{
-		"愿您在天堂安好，保佑家人平安健康",
-		"思念如潮水，愿您安息",
-		"您的音容笑貌永远在我心中",
-		"愿您在另一个世界快乐无忧",
-		"感谢您给我们的爱，永远怀念您",
-		"愿您的灵魂得到安息，家人得到慰藉",
-		"您的教诲我们永远铭记在心",
-		"愿天堂没有病痛，只有快乐",
-		"您永远是我们心中最亮的星",
-		"愿您在天堂与亲人团聚",
-	}
-
-	if limit > len(popularContents) {
-		limit = len(popularContents)
-	}
+// 热门祈福推荐语料范围
+const (
+	prayerSuggestionCorpusMonths = 24
+	prayerSuggestionCorpusLimit  = 20000
+)
 
-	return popularContents[:limit], nil
+// defaultPrayerContents 语料不足时补充的预设祈福内容
+var defaultPrayerContents = []string{
+	"愿您在天堂安好，保佑家人平安健康",
+	"思念如潮水，愿您安息",
+	"您的音容笑貌永远在我心中",
+	"愿您在另一个世界快乐无忧",
+	"感谢您给我们的爱，永远怀念您",
+	"愿您的灵魂得到安息，家人得到慰藉",
+	"您的教诲我们永远铭记在心",
+	"愿天堂没有病痛，只有快乐",
+	"您永远是我们心中最亮的星",
+	"愿您在天堂与亲人团聚",
 }
 
-// EmotionAnalysisResult 情感分析结果
-type EmotionAnalysisResult struct {
-	Emotion    string   `json:"emotion"`    // happy|sad|nostalgic|grateful|peaceful
-	Confidence float64  `json:"confidence"` // 置信度 0-1
-	Keywords   []string `json:"keywords"`   // 关键词
-	Suggestion string   `json:"suggestion"` // 回复建议
+// PopularPrayerContents 热门祈福推荐结果
+type PopularPrayerContents struct {
+	Contents    []string                 `json:"contents"`
+	Suggestions []utils.PrayerSuggestion `json:"suggestions"`
+	Festival    string                   `json:"festival,omitempty"` // 当前所处的节日时段
+	UpdatedAt   string                   `json:"updated_at,omitempty"`
 }
 
-// 分析留言情感
-func (s *WorshipService) AnalyzeMessageEmotion(content string) (*EmotionAnalysisResult, error) {
-	// 这里应该调用情感分析API，目前返回模拟结果
-	// 实际实现中可以使用腾讯云文本分析API或其他NLP服务
-
-	// 简单的关键词匹配来模拟情感分析
-	sadKeywords := []string{"想念", "思念", "难过", "伤心", "离别", "痛苦"}
-	happyKeywords := []string{"快乐", "开心", "幸福", "美好", "温暖", "笑容"}
-	nostalgicKeywords := []string{"回忆", "往昔", "从前", "过去", "曾经", "那时"}
-	gratefulKeywords := []string{"感谢", "感恩", "谢谢", "感激", "恩情", "教诲"}
-	peacefulKeywords := []string{"安息", "安好", "平静", "宁静", "安详", "祝福"}
-
-	content = strings.ToLower(content)
-
-	// 统计各种情感关键词出现次数
-	emotionScores := map[string]int{
-		"sad":       0,
-		"happy":     0,
-		"nostalgic": 0,
-		"grateful":  0,
-		"peaceful":  0,
+// 获取热门祈福内容（用于推荐）
+// 按公开祈福的实际使用次数排序，结合与逝者的关系和当前节日个性化，语料不足时用预设内容补足
+func (s *WorshipService) GetPopularPrayerContents(limit int, relationship string) (*PopularPrayerContents, error) {
+	s.suggestionMu.RLock()
+	index, festivals := s.suggestionIndex, s.suggestionFestivals
+	s.suggestionMu.RUnlock()
+
+	// 首次请求时同步构建，之后由定时任务刷新
+	if index == nil {
+		if err := s.RefreshPrayerSuggestions(); err != nil {
+			return nil, err
+		}
+		s.suggestionMu.RLock()
+		index, festivals = s.suggestionIndex, s.suggestionFestivals
+		s.suggestionMu.RUnlock()
 	}
 
-	var foundKeywords []string
-
-	for _, keyword := range sadKeywords {
-		if strings.Contains(content, keyword) {
-			emotionScores["sad"]++
-			foundKeywords = append(foundKeywords, keyword)
-		}
+	result := &PopularPrayerContents{
+		Festival:  utils.FestivalAt(time.Now(), festivals),
+		UpdatedAt: index.BuiltAt.Format("2006-01-02 15:04:05"),
 	}
+	result.Suggestions = index.Rank(relationship, result.Festival, limit)
 
-	for _, keyword := range happyKeywords {
-		if strings.Contains(content, keyword) {
-			emotionScores["happy"]++
-			foundKeywords = append(foundKeywords, keyword)
+	seen := make(map[string]bool)
+	for _, suggestion := range result.Suggestions {
+		result.Contents = append(result.Contents, suggestion.Content)
+		seen[suggestion.Content] = true
+	}
+	for _, content := range defaultPrayerContents {
+		if len(result.Contents) >= limit {
+			break
+		}
+		if !seen[content] {
+			result.Contents = append(result.Contents, content)
 		}
 	}
+	return result, nil
+}
 
-	for _, keyword := range nostalgicKeywords {
-		if strings.Contains(content, keyword) {
-			emotionScores["nostalgic"]++
-			foundKeywords = append(foundKeywords, keyword)
-		}
+// RefreshPrayerSuggestions 从近两年公开且审核通过的祈福重建推荐索引，私密祈福不进入语料
+func (s *WorshipService) RefreshPrayerSuggestions() error {
+	var prayers []models.Prayer
+	err := s.db.Select("user_id, content, relationship, created_at").
+		Where("is_public = ? AND moderation_status = ? AND created_at >= ?",
+			true, ModerationStatusApproved, time.Now().AddDate(0, -prayerSuggestionCorpusMonths, 0)).
+		Order("created_at DESC").
+		Limit(prayerSuggestionCorpusLimit).
+		Find(&prayers).Error
+	if err != nil {
+		return err
 	}
 
-	for _, keyword := range gratefulKeywords {
-		if strings.Contains(content, keyword) {
-			emotionScores["grateful"]++
-			foundKeywords = append(foundKeywords, keyword)
-		}
+	samples := make([]utils.PrayerSample, 0, len(prayers))
+	for _, prayer := range prayers {
+		samples = append(samples, utils.PrayerSample{
+			UserID:       prayer.UserID,
+			Text:         prayer.Content,
+			Relationship: prayer.Relationship,
+			CreatedAt:    prayer.CreatedAt,
+		})
 	}
 
-	for _, keyword := range peacefulKeywords {
-		if strings.Contains(content, keyword) {
-			emotionScores["peaceful"]++
-			foundKeywords = append(foundKeywords, keyword)
+	festivals := s.loadFestivalWindows()
+	opts := utils.DefaultPrayerSuggestionOptions()
+	if s.contentFilter != nil {
+		// 命中任何敏感词的内容都不推荐
+		opts.Exclude = func(text string) bool {
+			return len(s.contentFilter.MatchText(text)) > 0
 		}
 	}
+	index := utils.BuildPrayerSuggestionIndex(samples, festivals, opts)
+
+	s.suggestionMu.Lock()
+	s.suggestionIndex = index
+	s.suggestionFestivals = festivals
+	s.suggestionMu.Unlock()
+	return nil
+}
 
-	// 找出得分最高的情感
-	maxScore := 0
-	dominantEmotion := "peaceful" // 默认为平静
+// loadFestivalWindows 读取启用的祭扫节日配置，节日前按提醒天数、节日后3天视为节日时段
+func (s *WorshipService) loadFestivalWindows() []utils.FestivalWindow {
+	var configs []models.FestivalConfig
+	s.db.Where("is_active = ?", true).Find(&configs)
 
-	for emotion, score := range emotionScores {
-		if score > maxScore {
-			maxScore = score
-			dominantEmotion = emotion
+	windows := make([]utils.FestivalWindow, 0, len(configs))
+	for _, config := range configs {
+		month, day, ok := utils.ParseFestivalDate(config.FestivalDate)
+		if !ok {
+			continue
 		}
+		windows = append(windows, utils.FestivalWindow{
+			Name:       config.Name,
+			Month:      month,
+			Day:        day,
+			DaysBefore: config.ReminderDays,
+			DaysAfter:  3,
+		})
 	}
+	return windows
+}
 
-	// 计算置信度
-	totalKeywords := len(foundKeywords)
-	confidence := 0.5 // 默认置信度
-	if totalKeywords > 0 {
-		confidence = float64(maxScore) / float64(totalKeywords)
-		if confidence > 1.0 {
-			confidence = 1.0
+// StartPrayerSuggestionRefresher 立即构建一次推荐索引，之后定期刷新
+func (s *WorshipService) StartPrayerSuggestionRefresher(interval time.Duration) {
+	go func() {
+		if err := s.RefreshPrayerSuggestions(); err != nil {
+			fmt.Printf("构建祈福推荐失败: %v\n", err)
+		}
+		ticker := time.NewTicker(interval)
+		for range ticker.C {
+			if err := s.RefreshPrayerSuggestions(); err != nil {
+				fmt.Printf("构建祈福推荐失败: %v\n", err)
+			}
 		}
+	}()
+}
+
+// EmotionAnalysisResult 情感分析结果
+type EmotionAnalysisResult struct {
+	Emotion    string             `json:"emotion"`    // happy|sad|nostalgic|grateful|peaceful
+	Confidence float64            `json:"confidence"` // 置信度 0-1
+	Polarity   float64            `json:"polarity"`   // 情感倾向 -1(消极) 到 1(积极)
+	Scores     map[string]float64 `json:"scores"`     // 各情感得分
+	Keywords   []string           `json:"keywords"`   // 关键词
+	Suggestion string             `json:"suggestion"` // 回复建议
+}
+
+// 分析留言情感
+func (s *WorshipService) AnalyzeMessageEmotion(content string) (*EmotionAnalysisResult, error) {
+	if s.sentimentAnalyzer == nil {
+		return nil, errors.New("情感分析服务未初始化")
 	}
 
+	result := s.sentimentAnalyzer.Analyze(content)
+
 	// 生成回复建议
 	suggestions := map[string]string{
 		"sad":       "您的思念之情让人动容，相信逝者能感受到您深深的爱意。时间会慢慢抚平伤痛，但美好的回忆会永远陪伴着您。",
@@ -908,13 +1762,137 @@ func (s *WorshipService) AnalyzeMessageEmotion(content string) (*EmotionAnalysis
 	}
 
 	return &EmotionAnalysisResult{
-		Emotion:    dominantEmotion,
-		Confidence: confidence,
-		Keywords:   foundKeywords,
-		Suggestion: suggestions[dominantEmotion],
+		Emotion:    result.Emotion,
+		Confidence: result.Confidence,
+		Polarity:   result.Polarity,
+		Scores:     result.Scores,
+		Keywords:   result.Keywords,
+		Suggestion: suggestions[result.Emotion],
 	}, nil
 }
 
+// applyMessageEmotion 分析留言文字并写入情感字段，纯音视频留言不做分析
+func (s *WorshipService) applyMessageEmotion(message *models.Message) {
+	if s.sentimentAnalyzer == nil || strings.TrimSpace(message.Content) == "" {
+		return
+	}
+	result := s.sentimentAnalyzer.Analyze(message.Content)
+	message.Emotion = result.Emotion
+	message.EmotionConfidence = result.Confidence
+	message.EmotionPolarity = result.Polarity
+}
+
+// 单次统计时补算历史留言情感的上限，避免一次请求处理过多数据
+const emotionBackfillBatchSize = 500
+
+// 去掉空格、制表符和换行后仍有内容，与 applyMessageEmotion 的 strings.TrimSpace 判断一致，
+// 否则只有空白的留言每次都会被重新查出并占满补算批次
+const messageHasContentClause = "TRIM(REPLACE(REPLACE(REPLACE(content, CHAR(9 USING utf8mb4), ''), CHAR(10 USING utf8mb4), ''), CHAR(13 USING utf8mb4), '')) <> ''"
+
+// backfillMessageEmotions 为功能上线前的历史留言补算情感并保存，之后统计直接读取
+func (s *WorshipService) backfillMessageEmotions(memorialID string) {
+	var messages []*models.Message
+	s.db.Select("id", "content").
+		Where("memorial_id = ? AND (emotion = '' OR emotion IS NULL)", memorialID).
+		Where(messageHasContentClause).
+		Limit(emotionBackfillBatchSize).
+		Find(&messages)
+
+	for _, message := range messages {
+		s.applyMessageEmotion(message)
+		if message.Emotion == "" {
+			continue
+		}
+		s.db.Model(&models.Message{}).Where("id = ?", message.ID).Updates(map[string]interface{}{
+			"emotion":            message.Emotion,
+			"emotion_confidence": message.EmotionConfidence,
+			"emotion_polarity":   message.EmotionPolarity,
+		})
+	}
+}
+
+// EmotionTrendPoint 月度情感趋势
+type EmotionTrendPoint struct {
+	Month       string           `json:"month"`
+	Total       int64            `json:"total"`
+	Emotions    map[string]int64 `json:"emotions"`
+	AvgPolarity float64          `json:"avg_polarity"`
+}
+
+// getMessageEmotionStats 汇总已保存的留言情感：整体分布和近12个月趋势
+func (s *WorshipService) getMessageEmotionStats(memorialID string) (map[string]int64, []EmotionTrendPoint) {
+	s.backfillMessageEmotions(memorialID)
+
+	distribution := make(map[string]int64, len(utils.Emotions))
+	for _, emotion := range utils.Emotions {
+		distribution[emotion] = 0
+	}
+
+	var totals []struct {
+		Emotion string
+		Count   int64
+	}
+	s.db.Model(&models.Message{}).
+		Select("emotion, COUNT(*) AS count").
+		Where("memorial_id = ? AND emotion <> ''", memorialID).
+		Where(messageUnlockedClause, time.Now()).
+		Group("emotion").
+		Scan(&totals)
+	for _, row := range totals {
+		distribution[row.Emotion] = row.Count
+	}
+
+	startMonth := time.Now().AddDate(0, -11, 0)
+	startMonth = time.Date(startMonth.Year(), startMonth.Month(), 1, 0, 0, 0, 0, startMonth.Location())
+
+	var rows []struct {
+		Month       string
+		Emotion     string
+		Count       int64
+		PolaritySum float64
+	}
+	s.db.Model(&models.Message{}).
+		Select("DATE_FORMAT(created_at, '%Y-%m') AS month, emotion, COUNT(*) AS count, SUM(emotion_polarity) AS polarity_sum").
+		Where("memorial_id = ? AND emotion <> '' AND created_at >= ?", memorialID, startMonth).
+		Where(messageUnlockedClause, time.Now()).
+		Group("month, emotion").
+		Scan(&rows)
+
+	type monthAgg struct {
+		point       EmotionTrendPoint
+		polaritySum float64
+	}
+	byMonth := make(map[string]*monthAgg)
+	for i := 0; i < 12; i++ {
+		month := startMonth.AddDate(0, i, 0).Format("2006-01")
+		emotions := make(map[string]int64, len(utils.Emotions))
+		for _, emotion := range utils.Emotions {
+			emotions[emotion] = 0
+		}
+		byMonth[month] = &monthAgg{point: EmotionTrendPoint{Month: month, Emotions: emotions}}
+	}
+	for _, row := range rows {
+		agg, ok := byMonth[row.Month]
+		if !ok {
+			continue
+		}
+		agg.point.Emotions[row.Emotion] += row.Count
+		agg.point.Total += row.Count
+		agg.polaritySum += row.PolaritySum
+	}
+
+	trend := make([]EmotionTrendPoint, 0, 12)
+	for i := 0; i < 12; i++ {
+		agg := byMonth[startMonth.AddDate(0, i, 0).Format("2006-01")]
+		if agg.point.Total > 0 {
+			agg.point.AvgPolarity = math.Round(agg.polaritySum/float64(agg.point.Total)*100) / 100
+		}
+		trend = append(trend, agg.point)
+	}
+
+	return distribution, trend
+}
+
 // 获取留言回复建议
 func (s *WorshipService) GetMessageReplySuggestions(messageType, content string) ([]string, error) {
 	suggestions := []string{}
@@ -1017,14 +1995,20 @@ func (s *WorshipService) GetMemorialMessageAnalytics(memorialID string) (map[str
 	// 统计各类型留言数量
 	var textCount, audioCount, videoCount int64
 
-	s.db.Model(&models.Message{}).Where("memorial_id = ? AND message_type = ?", memorialID, "text").Count(&textCount)
-	s.db.Model(&models.Message{}).Where("memorial_id = ? AND message_type = ?", memorialID, "audio").Count(&audioCount)
-	s.db.Model(&models.Message{}).Where("memorial_id = ? AND message_type = ?", memorialID, "video").Count(&videoCount)
+	// 未解锁的时光胶囊不计入统计
+	now := time.Now()
+	s.db.Model(&models.Message{}).Where("memorial_id = ? AND message_type = ?", memorialID, "text").
+		Where(messageUnlockedClause, now).Count(&textCount)
+	s.db.Model(&models.Message{}).Where("memorial_id = ? AND message_type = ?", memorialID, "audio").
+		Where(messageUnlockedClause, now).Count(&audioCount)
+	s.db.Model(&models.Message{}).Where("memorial_id = ? AND message_type = ?", memorialID, "video").
+		Where(messageUnlockedClause, now).Count(&videoCount)
 
 	// 统计最近30天的留言趋势
-	thirtyDaysAgo := time.Now().AddDate(0, 0, -30)
+	thirtyDaysAgo := now.AddDate(0, 0, -30)
 	var recentMessages []models.Message
 	s.db.Where("memorial_id = ? AND created_at >= ?", memorialID, thirtyDaysAgo).
+		Where(messageUnlockedClause, now).
 		Order("created_at ASC").
 		Find(&recentMessages)
 
@@ -1039,18 +2023,24 @@ func (s *WorshipService) GetMemorialMessageAnalytics(memorialID string) (map[str
 	var activeUsers int64
 	s.db.Model(&models.Message{}).
 		Where("memorial_id = ? AND created_at >= ?", memorialID, thirtyDaysAgo).
+		Where(messageUnlockedClause, now).
 		Distinct("user_id").
 		Count(&activeUsers)
 
+	// 情感分布与月度趋势（基于留言保存时的分析结果）
+	emotionDistribution, emotionTrend := s.getMessageEmotionStats(memorialID)
+
 	return map[string]interface{}{
 		"message_types": map[string]int64{
 			"text":  textCount,
 			"audio": audioCount,
 			"video": videoCount,
 		},
-		"daily_stats":    dailyStats,
-		"active_users":   activeUsers,
-		"total_messages": textCount + audioCount + videoCount,
+		"daily_stats":          dailyStats,
+		"active_users":         activeUsers,
+		"total_messages":       textCount + audioCount + videoCount,
+		"emotion_distribution": emotionDistribution,
+		"emotion_trend":        emotionTrend,
 	}, nil
 }
 
@@ -1098,109 +2088,67 @@ func (s *WorshipService) GetDetailedWorshipStatistics(userID, memorialID string)
 
 	stats := &WorshipRecordStats{}
 
-	// 总记录数
-	s.db.Model(&models.WorshipRecord{}).Where("memorial_id = ?", memorialID).Count(&stats.TotalRecords)
+	// 总记录数和各类型统计（读取每日汇总）
+	typeCounts, total := s.statsService.MemorialTypeCounts(memorialID)
+	stats.TotalRecords = total
 
 	// 独立访客数
-	s.db.Model(&models.WorshipRecord{}).Where("memorial_id = ?", memorialID).Distinct("user_id").Count(&stats.UniqueVisitors)
+	stats.UniqueVisitors = s.statsService.MemorialUniqueVisitors(memorialID)
 
 	// 各类型统计
 	stats.TypeStatistics = make(map[string]int64)
 	worshipTypes := []string{"flower", "candle", "incense", "tribute", "prayer", "message"}
 	for _, worshipType := range worshipTypes {
-		var count int64
-		s.db.Model(&models.WorshipRecord{}).
-			Where("memorial_id = ? AND worship_type = ?", memorialID, worshipType).
-			Count(&count)
-		stats.TypeStatistics[worshipType] = count
+		stats.TypeStatistics[worshipType] = typeCounts[worshipType]
 	}
 
 	// 月度趋势（最近12个月）
+	now := time.Now()
+	firstMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).AddDate(0, -11, 0)
+	monthlyCounts := s.statsService.MemorialMonthlyCounts(memorialID, firstMonth)
 	stats.MonthlyTrend = []MonthlyStats{}
-	for i := 11; i >= 0; i-- {
-		month := time.Now().AddDate(0, -i, 0)
-		monthStr := month.Format("2006-01")
-
-		var count int64
-		s.db.Model(&models.WorshipRecord{}).
-			Where("memorial_id = ? AND DATE_FORMAT(created_at, '%Y-%m') = ?", memorialID, monthStr).
-			Count(&count)
-
+	for i := 0; i < 12; i++ {
+		monthStr := firstMonth.AddDate(0, i, 0).Format("2006-01")
 		stats.MonthlyTrend = append(stats.MonthlyTrend, MonthlyStats{
 			Month: monthStr,
-			Count: count,
+			Count: monthlyCounts[monthStr],
 		})
 	}
 
 	// 小时分布模式
+	hourlyCounts := s.statsService.MemorialHourlyCounts(memorialID)
 	stats.HourlyPattern = []HourlyStats{}
 	for hour := 0; hour < 24; hour++ {
-		var count int64
-		s.db.Model(&models.WorshipRecord{}).
-			Where("memorial_id = ? AND HOUR(created_at) = ?", memorialID, hour).
-			Count(&count)
-
 		stats.HourlyPattern = append(stats.HourlyPattern, HourlyStats{
 			Hour:  hour,
-			Count: count,
+			Count: hourlyCounts[hour],
 		})
 	}
 
 	// 访客排行榜（前10名）
-	type visitorCount struct {
-		UserID string
-		Count  int64
-	}
-
-	var visitorCounts []visitorCount
-	s.db.Model(&models.WorshipRecord{}).
-		Select("user_id, COUNT(*) as count").
-		Where("memorial_id = ?", memorialID).
-		Group("user_id").
-		Order("count DESC").
-		Limit(10).
-		Scan(&visitorCounts)
-
 	stats.TopVisitors = []VisitorStats{}
-	for _, vc := range visitorCounts {
+	for _, visitor := range s.statsService.TopMemorialVisitors(memorialID, 10) {
 		var user models.User
-		s.db.First(&user, "id = ?", vc.UserID)
-
-		var lastRecord models.WorshipRecord
-		s.db.Where("memorial_id = ? AND user_id = ?", memorialID, vc.UserID).
-			Order("created_at DESC").
-			First(&lastRecord)
+		s.db.First(&user, "id = ?", visitor.UserID)
 
 		stats.TopVisitors = append(stats.TopVisitors, VisitorStats{
 			UserID:    user.ID,
 			UserName:  user.Nickname,
 			AvatarURL: user.AvatarURL,
-			Count:     vc.Count,
-			LastVisit: lastRecord.CreatedAt.Format("2006-01-02 15:04:05"),
+			Count:     visitor.WorshipCount,
+			LastVisit: visitor.LastWorshipAt.Format("2006-01-02 15:04:05"),
 		})
 	}
 
 	// 最近30天活动统计
+	dailyActivity := s.statsService.MemorialDailyActivity(memorialID, now.AddDate(0, 0, -29))
 	stats.RecentActivity = []RecentActivityStats{}
 	for i := 29; i >= 0; i-- {
-		date := time.Now().AddDate(0, 0, -i)
-		dateStr := date.Format("2006-01-02")
-
-		var worshipCount int64
-		s.db.Model(&models.WorshipRecord{}).
-			Where("memorial_id = ? AND DATE(created_at) = ?", memorialID, dateStr).
-			Count(&worshipCount)
-
-		var visitorCount int64
-		s.db.Model(&models.WorshipRecord{}).
-			Where("memorial_id = ? AND DATE(created_at) = ?", memorialID, dateStr).
-			Distinct("user_id").
-			Count(&visitorCount)
-
+		dateStr := now.AddDate(0, 0, -i).Format("2006-01-02")
 		stats.RecentActivity = append(stats.RecentActivity, RecentActivityStats{
 			Date:         dateStr,
-			WorshipCount: worshipCount,
-			VisitorCount: visitorCount,
+			WorshipCount: dailyActivity[dateStr].Worships,
+			VisitorCount: dailyActivity[dateStr].Visitors,
 		})
 	}
 
@@ -1225,11 +2173,13 @@ func (s *WorshipService) AnalyzeUserWorshipBehavior(userID string) (*UserWorship
 		UserID: userID,
 	}
 
-	// 总祭扫次数
-	s.db.Model(&models.WorshipRecord{}).Where("user_id = ?", userID).Count(&behavior.TotalWorships)
+	// 总祭扫次数和各类型祭扫频率（读取每日汇总）
+	typeCounts, total := s.statsService.UserTypeCounts(userID)
+	behavior.TotalWorships = total
 
-	// 参与的纪念馆数量
-	s.db.Model(&models.WorshipRecord{}).Where("user_id = ?", userID).Distinct("memorial_id").Count(&behavior.MemorialCount)
+	// 参与的纪念馆数量、首次和最近祭扫时间
+	memorialCount, firstWorship, lastWorship := s.statsService.UserMemorialSummary(userID)
+	behavior.MemorialCount = memorialCount
 
 	// 各类型祭扫频率
 	behavior.WorshipFrequency = make(map[string]int64)
@@ -1237,10 +2187,7 @@ func (s *WorshipService) AnalyzeUserWorshipBehavior(userID string) (*UserWorship
 	maxCount := int64(0)
 
 	for _, worshipType := range worshipTypes {
-		var count int64
-		s.db.Model(&models.WorshipRecord{}).
-			Where("user_id = ? AND worship_type = ?", userID, worshipType).
-			Count(&count)
+		count := typeCounts[worshipType]
 		behavior.WorshipFrequency[worshipType] = count
 
 		if count > maxCount {
@@ -1249,51 +2196,35 @@ func (s *WorshipService) AnalyzeUserWorshipBehavior(userID string) (*UserWorship
 		}
 	}
 
-	// 活跃时段分析
-	hourCounts := make(map[int]int64)
-	var records []models.WorshipRecord
-	s.db.Where("user_id = ?", userID).Find(&records)
-
-	for _, record := range records {
-		hour := record.CreatedAt.Hour()
-		hourCounts[hour]++
-	}
-
-	// 找出最活跃的时段（前3个）
+	// 活跃时段分析：找出最活跃的时段（前3个）
 	type hourCount struct {
 		hour  int
 		count int64
 	}
 
 	var sortedHours []hourCount
-	for hour, count := range hourCounts {
-		sortedHours = append(sortedHours, hourCount{hour, count})
-	}
-
-	// 简单排序（实际应该使用sort包）
-	for i := 0; i < len(sortedHours)-1; i++ {
-		for j := i + 1; j < len(sortedHours); j++ {
-			if sortedHours[i].count < sortedHours[j].count {
-				sortedHours[i], sortedHours[j] = sortedHours[j], sortedHours[i]
-			}
+	for hour, count := range s.statsService.UserHourlyCounts(userID) {
+		if count > 0 {
+			sortedHours = append(sortedHours, hourCount{hour, count})
 		}
 	}
+	sort.Slice(sortedHours, func(i, j int) bool {
+		if sortedHours[i].count != sortedHours[j].count {
+			return sortedHours[i].count > sortedHours[j].count
+		}
+		return sortedHours[i].hour < sortedHours[j].hour
+	})
 
 	behavior.ActiveHours = []int{}
 	for i := 0; i < len(sortedHours) && i < 3; i++ {
 		behavior.ActiveHours = append(behavior.ActiveHours, sortedHours[i].hour)
 	}
 
-	// 首次和最近祭扫时间
-	var firstRecord, lastRecord models.WorshipRecord
-	s.db.Where("user_id = ?", userID).Order("created_at ASC").First(&firstRecord)
-	s.db.Where("user_id = ?", userID).Order("created_at DESC").First(&lastRecord)
-
-	if firstRecord.ID != "" {
-		behavior.FirstWorship = firstRecord.CreatedAt.Format("2006-01-02 15:04:05")
+	if firstWorship != nil {
+		behavior.FirstWorship = firstWorship.Format("2006-01-02 15:04:05")
 	}
-	if lastRecord.ID != "" {
-		behavior.LastWorship = lastRecord.CreatedAt.Format("2006-01-02 15:04:05")
+	if lastWorship != nil {
+		behavior.LastWorship = lastWorship.Format("2006-01-02 15:04:05")
 	}
 
 	return behavior, nil
@@ -1325,20 +2256,7 @@ func (s *WorshipService) GenerateWorshipReport(userID, memorialID string, period
 	}
 
 	// 根据周期计算时间范围
-	var startTime time.Time
-	switch period {
-	case "week":
-		startTime = time.Now().AddDate(0, 0, -7)
-	case "month":
-		startTime = time.Now().AddDate(0, -1, 0)
-	case "quarter":
-		startTime = time.Now().AddDate(0, -3, 0)
-	case "year":
-		startTime = time.Now().AddDate(-1, 0, 0)
-	default:
-		startTime = time.Now().AddDate(0, -1, 0) // 默认一个月
-		period = "month"
-	}
+	startTime, period := reportPeriodStart(period, time.Now())
 
 	report := &WorshipReport{
 		MemorialID:   memorialID,
@@ -1408,6 +2326,22 @@ func (s *WorshipService) GenerateWorshipReport(userID, memorialID string, period
 	return report, nil
 }
 
+// reportPeriodStart 计算报告周期的起始时间，无效周期按一个月处理
+func reportPeriodStart(period string, now time.Time) (time.Time, string) {
+	switch period {
+	case "week":
+		return now.AddDate(0, 0, -7), period
+	case "month":
+		return now.AddDate(0, -1, 0), period
+	case "quarter":
+		return now.AddDate(0, -3, 0), period
+	case "year":
+		return now.AddDate(-1, 0, 0), period
+	default:
+		return now.AddDate(0, -1, 0), "month" // 默认一个月
+	}
+}
+
 // 辅助函数
 func getPeriodName(period string) string {
 	switch period {
@@ -0,0 +1,619 @@
+package services
+
+import (
+	"sort"
+	"time"
+	"yun-nian-memorial/internal/models"
+	"yun-nian-memorial/internal/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// WorshipStatsService 祭扫统计汇总
+// 纪念馆和用户的每日汇总（按小时、祭扫类型细分）以及访客累计在祭扫时增量维护，
+// 统计接口读取汇总表而不再扫描 worship_records；回填和一致性校验见 cmd/rollupstats
+type WorshipStatsService struct {
+	db *gorm.DB
+}
+
+func NewWorshipStatsService(db *gorm.DB) *WorshipStatsService {
+	return &WorshipStatsService{
+		db: db,
+	}
+}
+
+// 回填时批量写入的行数
+const rollupInsertBatchSize = 500
+
+// createWorshipRecord 保存祭扫记录，并在同一事务中更新统计汇总
+func createWorshipRecord(db *gorm.DB, record *models.WorshipRecord) error {
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = time.Now()
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(record).Error; err != nil {
+			return err
+		}
+		return applyWorshipRollup(tx, record)
+	})
+}
+
+// applyWorshipRollup 将一条新祭扫记录计入汇总
+func applyWorshipRollup(tx *gorm.DB, record *models.WorshipRecord) error {
+	at := record.CreatedAt
+	day := rollupDate(at)
+	now := time.Now()
+
+	firstToday, err := touchMemorialVisitor(tx, record.MemorialID, record.UserID, at)
+	if err != nil {
+		return err
+	}
+	var visitors int64
+	if firstToday {
+		visitors = 1
+	}
+
+	memorialRow := &models.MemorialWorshipDaily{
+		MemorialID:   record.MemorialID,
+		StatDate:     day,
+		StatHour:     at.Hour(),
+		WorshipType:  record.WorshipType,
+		WorshipCount: 1,
+		VisitorCount: visitors,
+		UpdatedAt:    now,
+	}
+	if err := tx.Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"worship_count": gorm.Expr("worship_count + ?", 1),
+			"visitor_count": gorm.Expr("visitor_count + ?", visitors),
+			"updated_at":    now,
+		}),
+	}).Create(memorialRow).Error; err != nil {
+		return err
+	}
+
+	userRow := &models.UserWorshipDaily{
+		UserID:       record.UserID,
+		StatDate:     day,
+		StatHour:     at.Hour(),
+		WorshipType:  record.WorshipType,
+		WorshipCount: 1,
+		UpdatedAt:    now,
+	}
+	return tx.Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"worship_count": gorm.Expr("worship_count + ?", 1),
+			"updated_at":    now,
+		}),
+	}).Create(userRow).Error
+}
+
+// touchMemorialVisitor 更新访客在纪念馆的累计祭扫，返回是否为访客当日首次祭扫该纪念馆。
+// 只用一条 upsert 写入：先加锁读、不存在再插入的做法在并发的首次祭扫下会因间隙锁死锁或主键冲突，
+// 导致整笔祭扫回滚。更新前的最近祭扫时间记在 previous_worship_at 中，由此判断是否当日首次
+func touchMemorialVisitor(tx *gorm.DB, memorialID, userID string, at time.Time) (bool, error) {
+	now := time.Now()
+	err := tx.Clauses(clause.OnConflict{
+		// previous_worship_at 必须排在 last_worship_at 之前：MySQL 按顺序赋值，后面的表达式读到的是已更新的值
+		DoUpdates: clause.Set{
+			{Column: clause.Column{Name: "previous_worship_at"}, Value: gorm.Expr("last_worship_at")},
+			{Column: clause.Column{Name: "worship_count"}, Value: gorm.Expr("worship_count + ?", 1)},
+			{Column: clause.Column{Name: "last_worship_at"}, Value: gorm.Expr("CASE WHEN last_worship_at < ? THEN ? ELSE last_worship_at END", at, at)},
+			{Column: clause.Column{Name: "first_worship_at"}, Value: gorm.Expr("CASE WHEN first_worship_at > ? THEN ? ELSE first_worship_at END", at, at)},
+			{Column: clause.Column{Name: "updated_at"}, Value: now},
+		},
+	}).Create(&models.MemorialVisitorStat{
+		MemorialID:     memorialID,
+		UserID:         userID,
+		WorshipCount:   1,
+		FirstWorshipAt: at,
+		LastWorshipAt:  at,
+		UpdatedAt:      now,
+	}).Error
+	if err != nil {
+		return false, err
+	}
+
+	// 本事务已持有该行的写锁，读到的是自己刚写入的值；新插入的行 previous_worship_at 为空
+	var stat models.MemorialVisitorStat
+	err = tx.Select("previous_worship_at").
+		Where("memorial_id = ? AND user_id = ?", memorialID, userID).
+		First(&stat).Error
+	if err != nil {
+		return false, err
+	}
+	return stat.PreviousWorshipAt == nil || stat.PreviousWorshipAt.Before(rollupDate(at)), nil
+}
+
+// rollupDate 汇总表的统计日期（当地时间零点）
+func rollupDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// ---------- 统计查询 ----------
+
+type rollupTypeCount struct {
+	WorshipType string
+	Total       int64
+}
+
+type rollupHourCount struct {
+	StatHour int
+	Total    int64
+}
+
+// MemorialTypeCounts 纪念馆各祭扫类型的累计次数及总次数
+func (s *WorshipStatsService) MemorialTypeCounts(memorialID string) (map[string]int64, int64) {
+	var rows []rollupTypeCount
+	s.db.Model(&models.MemorialWorshipDaily{}).
+		Select("worship_type, SUM(worship_count) AS total").
+		Where("memorial_id = ?", memorialID).
+		Group("worship_type").
+		Scan(&rows)
+	return typeCountMap(rows)
+}
+
+// MemorialUniqueVisitors 纪念馆的独立访客数
+func (s *WorshipStatsService) MemorialUniqueVisitors(memorialID string) int64 {
+	var count int64
+	s.db.Model(&models.MemorialVisitorStat{}).Where("memorial_id = ?", memorialID).Count(&count)
+	return count
+}
+
+// MemorialWorshipsSince 纪念馆自某日起的祭扫次数
+func (s *WorshipStatsService) MemorialWorshipsSince(memorialID string, since time.Time) int64 {
+	var total int64
+	s.db.Model(&models.MemorialWorshipDaily{}).
+		Select("COALESCE(SUM(worship_count), 0)").
+		Where("memorial_id = ? AND stat_date >= ?", memorialID, rollupDate(since)).
+		Scan(&total)
+	return total
+}
+
+// MemorialMonthlyCounts 纪念馆自某日起每月的祭扫次数，键为 2006-01
+func (s *WorshipStatsService) MemorialMonthlyCounts(memorialID string, since time.Time) map[string]int64 {
+	var rows []struct {
+		Month string
+		Total int64
+	}
+	s.db.Model(&models.MemorialWorshipDaily{}).
+		Select("DATE_FORMAT(stat_date, '%Y-%m') AS month, SUM(worship_count) AS total").
+		Where("memorial_id = ? AND stat_date >= ?", memorialID, rollupDate(since)).
+		Group("month").
+		Scan(&rows)
+
+	result := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		result[row.Month] = row.Total
+	}
+	return result
+}
+
+// MemorialHourlyCounts 纪念馆各小时的累计祭扫次数
+func (s *WorshipStatsService) MemorialHourlyCounts(memorialID string) map[int]int64 {
+	var rows []rollupHourCount
+	s.db.Model(&models.MemorialWorshipDaily{}).
+		Select("stat_hour, SUM(worship_count) AS total").
+		Where("memorial_id = ?", memorialID).
+		Group("stat_hour").
+		Scan(&rows)
+	return hourCountMap(rows)
+}
+
+// DailyWorshipActivity 某日的祭扫次数和独立访客数
+type DailyWorshipActivity struct {
+	Worships int64
+	Visitors int64
+}
+
+// MemorialDailyActivity 纪念馆自某日起每天的祭扫次数和独立访客数，键为 2006-01-02
+func (s *WorshipStatsService) MemorialDailyActivity(memorialID string, since time.Time) map[string]DailyWorshipActivity {
+	var rows []struct {
+		StatDate time.Time
+		Worships int64
+		Visitors int64
+	}
+	s.db.Model(&models.MemorialWorshipDaily{}).
+		Select("stat_date, SUM(worship_count) AS worships, SUM(visitor_count) AS visitors").
+		Where("memorial_id = ? AND stat_date >= ?", memorialID, rollupDate(since)).
+		Group("stat_date").
+		Scan(&rows)
+
+	result := make(map[string]DailyWorshipActivity, len(rows))
+	for _, row := range rows {
+		result[row.StatDate.Format(utils.RollupDateLayout)] = DailyWorshipActivity{
+			Worships: row.Worships,
+			Visitors: row.Visitors,
+		}
+	}
+	return result
+}
+
+// TopMemorialVisitors 纪念馆祭扫次数最多的访客
+func (s *WorshipStatsService) TopMemorialVisitors(memorialID string, limit int) []models.MemorialVisitorStat {
+	var stats []models.MemorialVisitorStat
+	s.db.Where("memorial_id = ?", memorialID).
+		Order("worship_count DESC, last_worship_at DESC").
+		Limit(limit).
+		Find(&stats)
+	return stats
+}
+
+// UserTypeCounts 用户各祭扫类型的累计次数及总次数
+func (s *WorshipStatsService) UserTypeCounts(userID string) (map[string]int64, int64) {
+	var rows []rollupTypeCount
+	s.db.Model(&models.UserWorshipDaily{}).
+		Select("worship_type, SUM(worship_count) AS total").
+		Where("user_id = ?", userID).
+		Group("worship_type").
+		Scan(&rows)
+	return typeCountMap(rows)
+}
+
+// UserWorshipsSince 用户自某日起的祭扫次数
+func (s *WorshipStatsService) UserWorshipsSince(userID string, since time.Time) int64 {
+	var total int64
+	s.db.Model(&models.UserWorshipDaily{}).
+		Select("COALESCE(SUM(worship_count), 0)").
+		Where("user_id = ? AND stat_date >= ?", userID, rollupDate(since)).
+		Scan(&total)
+	return total
+}
+
+// UserHourlyCounts 用户各小时的累计祭扫次数
+func (s *WorshipStatsService) UserHourlyCounts(userID string) map[int]int64 {
+	var rows []rollupHourCount
+	s.db.Model(&models.UserWorshipDaily{}).
+		Select("stat_hour, SUM(worship_count) AS total").
+		Where("user_id = ?", userID).
+		Group("stat_hour").
+		Scan(&rows)
+	return hourCountMap(rows)
+}
+
+// UserMemorialSummary 用户参与祭扫的纪念馆数量以及首次、最近祭扫时间
+func (s *WorshipStatsService) UserMemorialSummary(userID string) (int64, *time.Time, *time.Time) {
+	var stats []models.MemorialVisitorStat
+	s.db.Select("first_worship_at", "last_worship_at").Where("user_id = ?", userID).Find(&stats)
+
+	var first, last *time.Time
+	for i := range stats {
+		if first == nil || stats[i].FirstWorshipAt.Before(*first) {
+			first = &stats[i].FirstWorshipAt
+		}
+		if last == nil || stats[i].LastWorshipAt.After(*last) {
+			last = &stats[i].LastWorshipAt
+		}
+	}
+	return int64(len(stats)), first, last
+}
+
+func typeCountMap(rows []rollupTypeCount) (map[string]int64, int64) {
+	counts := make(map[string]int64, len(rows))
+	var total int64
+	for _, row := range rows {
+		counts[row.WorshipType] = row.Total
+		total += row.Total
+	}
+	return counts, total
+}
+
+func hourCountMap(rows []rollupHourCount) map[int]int64 {
+	counts := make(map[int]int64, len(rows))
+	for _, row := range rows {
+		counts[row.StatHour] = row.Total
+	}
+	return counts
+}
+
+// ---------- 回填与一致性校验 ----------
+
+// RebuildMemorialRollups 按原始祭扫记录重建纪念馆的每日汇总和访客累计
+// 重建期间新写入的记录可能被覆盖，建议在低峰期执行并在完成后运行一致性校验
+func (s *WorshipStatsService) RebuildMemorialRollups(memorialID string) error {
+	acc, _, err := s.accumulate(s.db.Where("memorial_id = ?", memorialID))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var dailies []models.MemorialWorshipDaily
+	for key, counts := range acc.Memorial {
+		date, err := time.ParseInLocation(utils.RollupDateLayout, key.Date, time.Local)
+		if err != nil {
+			return err
+		}
+		dailies = append(dailies, models.MemorialWorshipDaily{
+			MemorialID:   key.OwnerID,
+			StatDate:     date,
+			StatHour:     key.Hour,
+			WorshipType:  key.WorshipType,
+			WorshipCount: counts.Worships,
+			VisitorCount: counts.Visitors,
+			UpdatedAt:    now,
+		})
+	}
+	var visitors []models.MemorialVisitorStat
+	for key, totals := range acc.Visitors {
+		visitors = append(visitors, models.MemorialVisitorStat{
+			MemorialID:     key.MemorialID,
+			UserID:         key.UserID,
+			WorshipCount:   totals.Worships,
+			FirstWorshipAt: totals.FirstAt,
+			LastWorshipAt:  totals.LastAt,
+			UpdatedAt:      now,
+		})
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("memorial_id = ?", memorialID).Delete(&models.MemorialWorshipDaily{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("memorial_id = ?", memorialID).Delete(&models.MemorialVisitorStat{}).Error; err != nil {
+			return err
+		}
+		if len(dailies) > 0 {
+			if err := tx.CreateInBatches(dailies, rollupInsertBatchSize).Error; err != nil {
+				return err
+			}
+		}
+		if len(visitors) > 0 {
+			if err := tx.CreateInBatches(visitors, rollupInsertBatchSize).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// RebuildUserRollups 按原始祭扫记录重建用户的每日汇总
+func (s *WorshipStatsService) RebuildUserRollups(userID string) error {
+	acc, _, err := s.accumulate(s.db.Where("user_id = ?", userID))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var dailies []models.UserWorshipDaily
+	for key, count := range acc.User {
+		date, err := time.ParseInLocation(utils.RollupDateLayout, key.Date, time.Local)
+		if err != nil {
+			return err
+		}
+		dailies = append(dailies, models.UserWorshipDaily{
+			UserID:       key.OwnerID,
+			StatDate:     date,
+			StatHour:     key.Hour,
+			WorshipType:  key.WorshipType,
+			WorshipCount: count,
+			UpdatedAt:    now,
+		})
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.UserWorshipDaily{}).Error; err != nil {
+			return err
+		}
+		if len(dailies) > 0 {
+			return tx.CreateInBatches(dailies, rollupInsertBatchSize).Error
+		}
+		return nil
+	})
+}
+
+// BackfillRollups 重建所有纪念馆和用户的汇总，progress 在每个纪念馆或用户完成后回调
+func (s *WorshipStatsService) BackfillRollups(progress func(scope, id string, err error)) error {
+	var memorialIDs []string
+	if err := s.db.Model(&models.WorshipRecord{}).Distinct().Pluck("memorial_id", &memorialIDs).Error; err != nil {
+		return err
+	}
+	for _, memorialID := range memorialIDs {
+		err := s.RebuildMemorialRollups(memorialID)
+		if progress != nil {
+			progress("memorial", memorialID, err)
+		}
+	}
+
+	var userIDs []string
+	if err := s.db.Model(&models.WorshipRecord{}).Distinct().Pluck("user_id", &userIDs).Error; err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		err := s.RebuildUserRollups(userID)
+		if progress != nil {
+			progress("user", userID, err)
+		}
+	}
+	return nil
+}
+
+// RollupCheckOptions 一致性校验范围：指定 MemorialID 时只校验该纪念馆的汇总，
+// 指定 UserID 时只校验该用户的汇总，都为空时校验全部
+type RollupCheckOptions struct {
+	MemorialID string
+	UserID     string
+	Since      time.Time // 为零值时校验全部历史
+}
+
+// VisitorTotalDiff 访客累计祭扫次数不一致
+type VisitorTotalDiff struct {
+	MemorialID string `json:"memorial_id"`
+	UserID     string `json:"user_id"`
+	Expected   int64  `json:"expected"`
+	Actual     int64  `json:"actual"`
+}
+
+// RollupConsistencyReport 汇总表与原始记录的一致性校验结果
+type RollupConsistencyReport struct {
+	CheckedAt             time.Time                 `json:"checked_at"`
+	Since                 string                    `json:"since,omitempty"`
+	RecordsScanned        int64                     `json:"records_scanned"`
+	Consistent            bool                      `json:"consistent"`
+	MemorialDiffs         []utils.WorshipRollupDiff `json:"memorial_diffs"`      // 纪念馆按日期、小时、类型的祭扫次数
+	VisitorDiffs          []utils.WorshipRollupDiff `json:"visitor_diffs"`       // 纪念馆每日独立访客数
+	UserDiffs             []utils.WorshipRollupDiff `json:"user_diffs"`          // 用户按日期、小时、类型的祭扫次数
+	VisitorTotalDiffs     []VisitorTotalDiff        `json:"visitor_total_diffs"` // 访客累计（仅全部历史校验时）
+	InconsistentMemorials []string                  `json:"inconsistent_memorials"`
+	InconsistentUsers     []string                  `json:"inconsistent_users"`
+}
+
+// CheckRollupConsistency 用原始祭扫记录重新计算汇总并与汇总表比较
+func (s *WorshipStatsService) CheckRollupConsistency(opts RollupCheckOptions) (*RollupConsistencyReport, error) {
+	report := &RollupConsistencyReport{CheckedAt: time.Now()}
+
+	raw := s.db.Model(&models.WorshipRecord{})
+	memorialRollups := s.db.Model(&models.MemorialWorshipDaily{})
+	userRollups := s.db.Model(&models.UserWorshipDaily{})
+	visitorStats := s.db.Model(&models.MemorialVisitorStat{})
+
+	checkMemorials := opts.UserID == ""
+	checkUsers := opts.MemorialID == ""
+	if opts.MemorialID != "" {
+		raw = raw.Where("memorial_id = ?", opts.MemorialID)
+		memorialRollups = memorialRollups.Where("memorial_id = ?", opts.MemorialID)
+		visitorStats = visitorStats.Where("memorial_id = ?", opts.MemorialID)
+	}
+	if opts.UserID != "" {
+		raw = raw.Where("user_id = ?", opts.UserID)
+		userRollups = userRollups.Where("user_id = ?", opts.UserID)
+	}
+	if !opts.Since.IsZero() {
+		since := rollupDate(opts.Since)
+		report.Since = since.Format(utils.RollupDateLayout)
+		raw = raw.Where("created_at >= ?", since)
+		memorialRollups = memorialRollups.Where("stat_date >= ?", since)
+		userRollups = userRollups.Where("stat_date >= ?", since)
+	}
+
+	acc, scanned, err := s.accumulate(raw)
+	if err != nil {
+		return nil, err
+	}
+	report.RecordsScanned = scanned
+
+	memorials := make(map[string]struct{})
+	users := make(map[string]struct{})
+
+	if checkMemorials {
+		var rows []models.MemorialWorshipDaily
+		if err := memorialRollups.Find(&rows).Error; err != nil {
+			return nil, err
+		}
+		actual := make(map[utils.WorshipRollupKey]*utils.WorshipRollupCounts, len(rows))
+		for _, row := range rows {
+			actual[utils.WorshipRollupKey{
+				OwnerID:     row.MemorialID,
+				Date:        row.StatDate.Format(utils.RollupDateLayout),
+				Hour:        row.StatHour,
+				WorshipType: row.WorshipType,
+			}] = &utils.WorshipRollupCounts{Worships: row.WorshipCount, Visitors: row.VisitorCount}
+		}
+		actualWorships := make(map[utils.WorshipRollupKey]int64, len(actual))
+		for key, counts := range actual {
+			actualWorships[key] = counts.Worships
+		}
+
+		report.MemorialDiffs = utils.DiffWorshipRollups(acc.MemorialWorshipCounts(), actualWorships)
+		report.VisitorDiffs = utils.DiffWorshipRollups(acc.MemorialDailyVisitors(), utils.SumDailyVisitors(actual))
+		for _, diff := range append(report.MemorialDiffs, report.VisitorDiffs...) {
+			memorials[diff.Key.OwnerID] = struct{}{}
+		}
+
+		// 访客累计覆盖全部历史，只在不限日期时比较
+		if opts.Since.IsZero() {
+			var stats []models.MemorialVisitorStat
+			if err := visitorStats.Find(&stats).Error; err != nil {
+				return nil, err
+			}
+			report.VisitorTotalDiffs = diffVisitorTotals(acc.Visitors, stats)
+			for _, diff := range report.VisitorTotalDiffs {
+				memorials[diff.MemorialID] = struct{}{}
+			}
+		}
+	}
+
+	if checkUsers {
+		var rows []models.UserWorshipDaily
+		if err := userRollups.Find(&rows).Error; err != nil {
+			return nil, err
+		}
+		actual := make(map[utils.WorshipRollupKey]int64, len(rows))
+		for _, row := range rows {
+			actual[utils.WorshipRollupKey{
+				OwnerID:     row.UserID,
+				Date:        row.StatDate.Format(utils.RollupDateLayout),
+				Hour:        row.StatHour,
+				WorshipType: row.WorshipType,
+			}] = row.WorshipCount
+		}
+		report.UserDiffs = utils.DiffWorshipRollups(acc.User, actual)
+		for _, diff := range report.UserDiffs {
+			users[diff.Key.OwnerID] = struct{}{}
+		}
+	}
+
+	report.InconsistentMemorials = sortedKeys(memorials)
+	report.InconsistentUsers = sortedKeys(users)
+	report.Consistent = len(report.InconsistentMemorials) == 0 && len(report.InconsistentUsers) == 0
+	return report, nil
+}
+
+// accumulate 按时间顺序扫描原始祭扫记录并计算汇总
+func (s *WorshipStatsService) accumulate(query *gorm.DB) (*utils.WorshipRollupAccumulator, int64, error) {
+	rows, err := query.Model(&models.WorshipRecord{}).
+		Select("memorial_id, user_id, worship_type, created_at").
+		Order("created_at ASC, id ASC").
+		Rows()
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	acc := utils.NewWorshipRollupAccumulator()
+	var scanned int64
+	for rows.Next() {
+		var event utils.WorshipEvent
+		if err := rows.Scan(&event.MemorialID, &event.UserID, &event.WorshipType, &event.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		acc.Add(event)
+		scanned++
+	}
+	return acc, scanned, rows.Err()
+}
+
+func diffVisitorTotals(expected map[utils.MemorialVisitorKey]*utils.MemorialVisitorTotals, stats []models.MemorialVisitorStat) []VisitorTotalDiff {
+	actual := make(map[utils.MemorialVisitorKey]int64, len(stats))
+	for _, stat := range stats {
+		actual[utils.MemorialVisitorKey{MemorialID: stat.MemorialID, UserID: stat.UserID}] = stat.WorshipCount
+	}
+
+	var diffs []VisitorTotalDiff
+	for key, totals := range expected {
+		if actual[key] != totals.Worships {
+			diffs = append(diffs, VisitorTotalDiff{MemorialID: key.MemorialID, UserID: key.UserID, Expected: totals.Worships, Actual: actual[key]})
+		}
+	}
+	for key, count := range actual {
+		if _, ok := expected[key]; !ok {
+			diffs = append(diffs, VisitorTotalDiff{MemorialID: key.MemorialID, UserID: key.UserID, Actual: count})
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		if diffs[i].MemorialID != diffs[j].MemorialID {
+			return diffs[i].MemorialID < diffs[j].MemorialID
+		}
+		return diffs[i].UserID < diffs[j].UserID
+	})
+	return diffs
+}
+
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,59 @@
+package utils
+
+// CollectiveOffering 集体祭扫现场的一次献祭
+type CollectiveOffering struct {
+	UserID      string
+	WorshipType string
+}
+
+// CollectiveParticipantSummary 单个参与者在集体祭扫中的献祭情况
+type CollectiveParticipantSummary struct {
+	UserID        string         `json:"user_id"`
+	Nickname      string         `json:"nickname"`
+	OfferingCount int            `json:"offering_count"`
+	Offerings     map[string]int `json:"offerings"`
+}
+
+// CollectiveWorshipSummary 集体祭扫结束后的汇总
+type CollectiveWorshipSummary struct {
+	ParticipantCount int                            `json:"participant_count"`
+	OfferingCount    int                            `json:"offering_count"`
+	OfferingsByType  map[string]int                 `json:"offerings_by_type"`
+	Participants     []CollectiveParticipantSummary `json:"participants"`
+}
+
+// SummarizeCollectiveWorship 按到场顺序汇总参与者及其祭品
+// joined 为进入现场的用户（按进入时间排序），未进入现场却有祭品的用户追加在末尾
+func SummarizeCollectiveWorship(joined []string, offerings []CollectiveOffering) CollectiveWorshipSummary {
+	summary := CollectiveWorshipSummary{
+		OfferingsByType: make(map[string]int),
+		Participants:    []CollectiveParticipantSummary{},
+	}
+
+	index := make(map[string]int)
+	participant := func(userID string) *CollectiveParticipantSummary {
+		if i, ok := index[userID]; ok {
+			return &summary.Participants[i]
+		}
+		index[userID] = len(summary.Participants)
+		summary.Participants = append(summary.Participants, CollectiveParticipantSummary{
+			UserID:    userID,
+			Offerings: make(map[string]int),
+		})
+		return &summary.Participants[len(summary.Participants)-1]
+	}
+
+	for _, userID := range joined {
+		participant(userID)
+	}
+	for _, offering := range offerings {
+		p := participant(offering.UserID)
+		p.Offerings[offering.WorshipType]++
+		p.OfferingCount++
+		summary.OfferingsByType[offering.WorshipType]++
+		summary.OfferingCount++
+	}
+
+	summary.ParticipantCount = len(summary.Participants)
+	return summary
+}
@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarizeCollectiveWorship(t *testing.T) {
+	summary := SummarizeCollectiveWorship(
+		[]string{"u1", "u2", "u3", "u2"},
+		[]CollectiveOffering{
+			{UserID: "u2", WorshipType: "flower"},
+			{UserID: "u1", WorshipType: "incense"},
+			{UserID: "u2", WorshipType: "flower"},
+			{UserID: "u4", WorshipType: "candle"},
+		},
+	)
+
+	assert.Equal(t, 4, summary.ParticipantCount)
+	assert.Equal(t, 4, summary.OfferingCount)
+	assert.Equal(t, map[string]int{"flower": 2, "incense": 1, "candle": 1}, summary.OfferingsByType)
+
+	require.Len(t, summary.Participants, 4)
+	assert.Equal(t, []string{"u1", "u2", "u3", "u4"}, []string{
+		summary.Participants[0].UserID, summary.Participants[1].UserID,
+		summary.Participants[2].UserID, summary.Participants[3].UserID,
+	})
+	assert.Equal(t, 2, summary.Participants[1].OfferingCount)
+	assert.Equal(t, map[string]int{"flower": 2}, summary.Participants[1].Offerings)
+	// 到场但未献祭的成员也计入参与者
+	assert.Equal(t, 0, summary.Participants[2].OfferingCount)
+	assert.Empty(t, summary.Participants[2].Offerings)
+}
+
+func TestSummarizeCollectiveWorshipEmpty(t *testing.T) {
+	summary := SummarizeCollectiveWorship(nil, nil)
+	assert.Equal(t, 0, summary.ParticipantCount)
+	assert.NotNil(t, summary.Participants)
+	assert.NotNil(t, summary.OfferingsByType)
+}
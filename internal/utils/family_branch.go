@@ -0,0 +1,56 @@
+package utils
+
+// 支系内容对上级家族圈的可见性
+const (
+	FamilyVisibilityClan    = "clan"    // 上级家族圈成员可以查看，动态和提醒汇总到上级
+	FamilyVisibilityPrivate = "private" // 仅本支系成员可以查看，不向上汇总
+)
+
+// FamilyBranchMaxDepth 宗族下支系的最大层数
+const FamilyBranchMaxDepth = 5
+
+// IsValidFamilyVisibility 检查是否为有效的支系可见性
+func IsValidFamilyVisibility(visibility string) bool {
+	return visibility == FamilyVisibilityClan || visibility == FamilyVisibilityPrivate
+}
+
+// IsFamilyRollupVisible 支系内容是否对上级家族圈可见，未设置时视为可见
+func IsFamilyRollupVisible(visibility string) bool {
+	return visibility != FamilyVisibilityPrivate
+}
+
+// ResolveFamilyRole 计算用户在家族圈中的有效角色
+//
+// explicit 为用户在该家族圈的成员角色，有则直接使用；否则按继承规则：
+//   - ancestorRoles：用户在上级家族圈中的角色（不含被私密支系隔开的上级）。
+//     上级的所有者和管理员在支系中视为管理员，其余成员视为访客。
+//   - branchRoles：用户在下级支系中的角色。支系成员视为宗族的普通成员，支系访客仍为访客。
+//
+// 取继承角色中最高的一个，没有任何关系时返回空字符串。
+func ResolveFamilyRole(explicit string, ancestorRoles, branchRoles []string) string {
+	if explicit != "" {
+		return explicit
+	}
+
+	best := ""
+	consider := func(role string) {
+		if best == "" || FamilyRoleRank(role) > FamilyRoleRank(best) {
+			best = role
+		}
+	}
+	for _, role := range ancestorRoles {
+		if IsFamilyManagerRole(role) {
+			consider(FamilyRoleAdmin)
+		} else if IsValidFamilyRole(role) {
+			consider(FamilyRoleGuest)
+		}
+	}
+	for _, role := range branchRoles {
+		if role == FamilyRoleGuest {
+			consider(FamilyRoleGuest)
+		} else if IsValidFamilyRole(role) {
+			consider(FamilyRoleMember)
+		}
+	}
+	return best
+}
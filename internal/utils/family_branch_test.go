@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveFamilyRole(t *testing.T) {
+	// 本家族圈的角色优先
+	assert.Equal(t, FamilyRoleEditor, ResolveFamilyRole(FamilyRoleEditor, []string{FamilyRoleOwner}, nil))
+
+	// 宗族管理者在支系中为管理员，宗族其他成员为访客
+	assert.Equal(t, FamilyRoleAdmin, ResolveFamilyRole("", []string{FamilyRoleOwner}, nil))
+	assert.Equal(t, FamilyRoleAdmin, ResolveFamilyRole("", []string{FamilyRoleMember, FamilyRoleAdmin}, nil))
+	assert.Equal(t, FamilyRoleGuest, ResolveFamilyRole("", []string{FamilyRoleEditor}, nil))
+
+	// 支系成员在宗族中为普通成员，支系访客仍为访客
+	assert.Equal(t, FamilyRoleMember, ResolveFamilyRole("", nil, []string{FamilyRoleAdmin}))
+	assert.Equal(t, FamilyRoleGuest, ResolveFamilyRole("", nil, []string{FamilyRoleGuest}))
+	assert.Equal(t, FamilyRoleMember, ResolveFamilyRole("", []string{FamilyRoleMember}, []string{FamilyRoleGuest, FamilyRoleMember}))
+
+	// 没有任何关系
+	assert.Equal(t, "", ResolveFamilyRole("", nil, nil))
+	assert.Equal(t, "", ResolveFamilyRole("", []string{"unknown"}, nil))
+}
+
+func TestFamilyVisibility(t *testing.T) {
+	assert.True(t, IsValidFamilyVisibility(FamilyVisibilityClan))
+	assert.True(t, IsValidFamilyVisibility(FamilyVisibilityPrivate))
+	assert.False(t, IsValidFamilyVisibility(""))
+
+	assert.True(t, IsFamilyRollupVisible(""))
+	assert.True(t, IsFamilyRollupVisible(FamilyVisibilityClan))
+	assert.False(t, IsFamilyRollupVisible(FamilyVisibilityPrivate))
+}
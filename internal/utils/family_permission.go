@@ -0,0 +1,193 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+)
+
+// 家族圈角色，按权限从高到低排列
+const (
+	FamilyRoleOwner  = "owner"  // 所有者，拥有全部权限
+	FamilyRoleAdmin  = "admin"  // 管理员
+	FamilyRoleEditor = "editor" // 编辑，维护谱系、故事和传统
+	FamilyRoleMember = "member" // 普通成员
+	FamilyRoleGuest  = "guest"  // 访客，只能查看
+)
+
+// FamilyRoles 全部家族圈角色
+var FamilyRoles = []string{FamilyRoleOwner, FamilyRoleAdmin, FamilyRoleEditor, FamilyRoleMember, FamilyRoleGuest}
+
+// 家族圈中受权限矩阵控制的操作
+const (
+	FamilyActionView              = "view"               // 查看家族圈内容
+	FamilyActionEditFamily        = "edit_family"        // 修改家族圈信息
+	FamilyActionInvite            = "invite"             // 邀请成员、管理邀请链接和加入申请
+	FamilyActionManageMembers     = "manage_members"     // 移除成员
+	FamilyActionLinkMemorial      = "link_memorial"      // 关联或移除纪念馆
+	FamilyActionSetReminder       = "set_reminder"       // 设置和删除纪念日提醒
+	FamilyActionEditGenealogy     = "edit_genealogy"     // 编辑家族谱系
+	FamilyActionPostStory         = "post_story"         // 发布家族故事
+	FamilyActionManageStories     = "manage_stories"     // 修改或删除他人的故事
+	FamilyActionEditTradition     = "edit_tradition"     // 编辑家族传统
+	FamilyActionCollectiveWorship = "collective_worship" // 发起集体祭扫
+	FamilyActionManageBranches    = "manage_branches"    // 创建支系
+)
+
+// 只属于所有者、不能通过权限矩阵下放的操作
+const (
+	FamilyActionDeleteFamily      = "delete_family"      // 删除家族圈
+	FamilyActionSetRoles          = "set_roles"          // 设置成员角色
+	FamilyActionManagePermissions = "manage_permissions" // 修改权限矩阵
+	FamilyActionTransferOwnership = "transfer_ownership" // 转让家族圈
+)
+
+// FamilyActions 权限矩阵中可配置的操作，按展示顺序排列
+var FamilyActions = []string{
+	FamilyActionEditFamily,
+	FamilyActionInvite,
+	FamilyActionManageMembers,
+	FamilyActionLinkMemorial,
+	FamilyActionSetReminder,
+	FamilyActionEditGenealogy,
+	FamilyActionPostStory,
+	FamilyActionManageStories,
+	FamilyActionEditTradition,
+	FamilyActionCollectiveWorship,
+	FamilyActionManageBranches,
+}
+
+var familyActionLabels = map[string]string{
+	FamilyActionView:              "查看家族圈",
+	FamilyActionEditFamily:        "修改家族圈信息",
+	FamilyActionInvite:            "邀请成员",
+	FamilyActionManageMembers:     "移除成员",
+	FamilyActionLinkMemorial:      "关联纪念馆",
+	FamilyActionSetReminder:       "设置纪念日提醒",
+	FamilyActionEditGenealogy:     "编辑家族谱系",
+	FamilyActionPostStory:         "发布家族故事",
+	FamilyActionManageStories:     "管理家族故事",
+	FamilyActionEditTradition:     "编辑家族传统",
+	FamilyActionCollectiveWorship: "发起集体祭扫",
+	FamilyActionManageBranches:    "创建支系",
+	FamilyActionDeleteFamily:      "删除家族圈",
+	FamilyActionSetRoles:          "设置成员角色",
+	FamilyActionManagePermissions: "修改权限设置",
+	FamilyActionTransferOwnership: "转让家族圈",
+}
+
+var familyRoleRanks = map[string]int{
+	FamilyRoleOwner:  4,
+	FamilyRoleAdmin:  3,
+	FamilyRoleEditor: 2,
+	FamilyRoleMember: 1,
+	FamilyRoleGuest:  0,
+}
+
+// IsValidFamilyRole 检查是否为有效的家族圈角色
+func IsValidFamilyRole(role string) bool {
+	_, ok := familyRoleRanks[role]
+	return ok
+}
+
+// FamilyRoleRank 返回角色的级别，数值越大权限越高，无效角色返回 -1
+func FamilyRoleRank(role string) int {
+	if rank, ok := familyRoleRanks[role]; ok {
+		return rank
+	}
+	return -1
+}
+
+// IsFamilyManagerRole 所有者和管理员视为家族圈管理者
+func IsFamilyManagerRole(role string) bool {
+	return role == FamilyRoleOwner || role == FamilyRoleAdmin
+}
+
+// IsConfigurableFamilyAction 检查操作是否可以在权限矩阵中配置
+func IsConfigurableFamilyAction(action string) bool {
+	for _, a := range FamilyActions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// FamilyActionLabel 返回操作的中文名称
+func FamilyActionLabel(action string) string {
+	if label, ok := familyActionLabels[action]; ok {
+		return label
+	}
+	return action
+}
+
+// FamilyPermissionDeniedMessage 返回无权执行某操作时的错误信息
+func FamilyPermissionDeniedMessage(action string) string {
+	return fmt.Sprintf("您没有%s的权限", FamilyActionLabel(action))
+}
+
+// DefaultFamilyPermissionMatrix 返回默认权限矩阵：操作 -> 允许的角色
+func DefaultFamilyPermissionMatrix() map[string][]string {
+	return map[string][]string{
+		FamilyActionEditFamily:        {FamilyRoleOwner, FamilyRoleAdmin},
+		FamilyActionInvite:            {FamilyRoleOwner, FamilyRoleAdmin},
+		FamilyActionManageMembers:     {FamilyRoleOwner, FamilyRoleAdmin},
+		FamilyActionLinkMemorial:      {FamilyRoleOwner, FamilyRoleAdmin},
+		FamilyActionSetReminder:       {FamilyRoleOwner, FamilyRoleAdmin, FamilyRoleEditor},
+		FamilyActionEditGenealogy:     {FamilyRoleOwner, FamilyRoleAdmin, FamilyRoleEditor},
+		FamilyActionPostStory:         {FamilyRoleOwner, FamilyRoleAdmin, FamilyRoleEditor, FamilyRoleMember},
+		FamilyActionManageStories:     {FamilyRoleOwner, FamilyRoleAdmin, FamilyRoleEditor},
+		FamilyActionEditTradition:     {FamilyRoleOwner, FamilyRoleAdmin, FamilyRoleEditor},
+		FamilyActionCollectiveWorship: {FamilyRoleOwner, FamilyRoleAdmin},
+		FamilyActionManageBranches:    {FamilyRoleOwner, FamilyRoleAdmin},
+	}
+}
+
+// NormalizeFamilyPermissionRoles 校验并整理某项操作允许的角色：去重、按级别排序，所有者始终包含在内
+func NormalizeFamilyPermissionRoles(roles []string) ([]string, error) {
+	seen := map[string]bool{FamilyRoleOwner: true}
+	normalized := []string{FamilyRoleOwner}
+	for _, role := range roles {
+		if !IsValidFamilyRole(role) {
+			return nil, fmt.Errorf("无效的角色: %s", role)
+		}
+		if seen[role] {
+			continue
+		}
+		seen[role] = true
+		normalized = append(normalized, role)
+	}
+	sort.SliceStable(normalized, func(i, j int) bool {
+		return FamilyRoleRank(normalized[i]) > FamilyRoleRank(normalized[j])
+	})
+	return normalized, nil
+}
+
+// FamilyRoleAllowed 按权限矩阵判断角色能否执行操作
+//
+// 所有者可以执行任何操作；任何角色都可以查看；
+// 删除家族圈、设置角色、修改权限设置、转让家族圈只属于所有者，不受矩阵影响。
+func FamilyRoleAllowed(matrix map[string][]string, role, action string) bool {
+	if !IsValidFamilyRole(role) {
+		return false
+	}
+	if role == FamilyRoleOwner {
+		return true
+	}
+	if action == FamilyActionView {
+		return true
+	}
+	if !IsConfigurableFamilyAction(action) {
+		return false
+	}
+
+	roles, ok := matrix[action]
+	if !ok {
+		roles = DefaultFamilyPermissionMatrix()[action]
+	}
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFamilyRoleRank(t *testing.T) {
+	assert.Greater(t, FamilyRoleRank(FamilyRoleOwner), FamilyRoleRank(FamilyRoleAdmin))
+	assert.Greater(t, FamilyRoleRank(FamilyRoleAdmin), FamilyRoleRank(FamilyRoleEditor))
+	assert.Greater(t, FamilyRoleRank(FamilyRoleEditor), FamilyRoleRank(FamilyRoleMember))
+	assert.Greater(t, FamilyRoleRank(FamilyRoleMember), FamilyRoleRank(FamilyRoleGuest))
+	assert.Equal(t, -1, FamilyRoleRank("root"))
+	assert.False(t, IsValidFamilyRole("root"))
+}
+
+func TestFamilyRoleAllowedDefaults(t *testing.T) {
+	matrix := DefaultFamilyPermissionMatrix()
+
+	// 所有者拥有全部权限，包括只属于所有者的操作
+	for _, action := range append(FamilyActions, FamilyActionDeleteFamily, FamilyActionSetRoles, FamilyActionManagePermissions) {
+		assert.True(t, FamilyRoleAllowed(matrix, FamilyRoleOwner, action), action)
+	}
+	// 所有角色都可以查看
+	for _, role := range FamilyRoles {
+		assert.True(t, FamilyRoleAllowed(matrix, role, FamilyActionView), role)
+	}
+	// 只属于所有者的操作不下放
+	assert.False(t, FamilyRoleAllowed(matrix, FamilyRoleAdmin, FamilyActionDeleteFamily))
+	assert.False(t, FamilyRoleAllowed(matrix, FamilyRoleAdmin, FamilyActionSetRoles))
+
+	assert.True(t, FamilyRoleAllowed(matrix, FamilyRoleAdmin, FamilyActionInvite))
+	assert.False(t, FamilyRoleAllowed(matrix, FamilyRoleEditor, FamilyActionInvite))
+	assert.True(t, FamilyRoleAllowed(matrix, FamilyRoleEditor, FamilyActionEditGenealogy))
+	assert.False(t, FamilyRoleAllowed(matrix, FamilyRoleMember, FamilyActionEditGenealogy))
+	assert.True(t, FamilyRoleAllowed(matrix, FamilyRoleMember, FamilyActionPostStory))
+	assert.False(t, FamilyRoleAllowed(matrix, FamilyRoleGuest, FamilyActionPostStory))
+	assert.False(t, FamilyRoleAllowed(matrix, "", FamilyActionView))
+}
+
+func TestFamilyRoleAllowedOverride(t *testing.T) {
+	matrix := DefaultFamilyPermissionMatrix()
+	matrix[FamilyActionEditGenealogy] = []string{FamilyRoleOwner, FamilyRoleMember}
+	delete(matrix, FamilyActionInvite)
+
+	assert.True(t, FamilyRoleAllowed(matrix, FamilyRoleMember, FamilyActionEditGenealogy))
+	assert.False(t, FamilyRoleAllowed(matrix, FamilyRoleAdmin, FamilyActionEditGenealogy))
+	// 矩阵中缺少的操作按默认设置
+	assert.True(t, FamilyRoleAllowed(matrix, FamilyRoleAdmin, FamilyActionInvite))
+}
+
+func TestNormalizeFamilyPermissionRoles(t *testing.T) {
+	roles, err := NormalizeFamilyPermissionRoles([]string{FamilyRoleMember, FamilyRoleAdmin, FamilyRoleMember})
+	require.NoError(t, err)
+	assert.Equal(t, []string{FamilyRoleOwner, FamilyRoleAdmin, FamilyRoleMember}, roles)
+
+	roles, err = NormalizeFamilyPermissionRoles(nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{FamilyRoleOwner}, roles)
+
+	_, err = NormalizeFamilyPermissionRoles([]string{"root"})
+	assert.EqualError(t, err, "无效的角色: root")
+}
+
+func TestDefaultFamilyPermissionMatrixNormalized(t *testing.T) {
+	defaults := DefaultFamilyPermissionMatrix()
+	for _, action := range FamilyActions {
+		roles, err := NormalizeFamilyPermissionRoles(defaults[action])
+		require.NoError(t, err)
+		assert.Equal(t, defaults[action], roles, action)
+	}
+	assert.Equal(t, "您没有编辑家族谱系的权限", FamilyPermissionDeniedMessage(FamilyActionEditGenealogy))
+}
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"sort"
+	"time"
+)
+
+// FamilySuccessionCandidate 家族圈所有者继任候选人
+type FamilySuccessionCandidate struct {
+	UserID   string
+	Role     string
+	JoinedAt time.Time
+	Active   bool // 账号正常且近期活跃
+}
+
+// familySuccessionTier 继任顺位：管理员优先，其次编辑和普通成员，访客最后
+func familySuccessionTier(role string) int {
+	switch role {
+	case FamilyRoleAdmin:
+		return 0
+	case FamilyRoleEditor, FamilyRoleMember:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// PickFamilySuccessor 按继任策略选出新的所有者
+//
+// 活跃成员优先；同为活跃或同为不活跃时，先选加入最早的管理员，再选加入最早的成员，访客最后。
+// 候选人中的所有者会被忽略，没有合适人选时返回 false。
+func PickFamilySuccessor(candidates []FamilySuccessionCandidate) (FamilySuccessionCandidate, bool) {
+	eligible := make([]FamilySuccessionCandidate, 0, len(candidates))
+	for _, c := range candidates {
+		if c.Role == FamilyRoleOwner || !IsValidFamilyRole(c.Role) {
+			continue
+		}
+		eligible = append(eligible, c)
+	}
+	if len(eligible) == 0 {
+		return FamilySuccessionCandidate{}, false
+	}
+
+	sort.SliceStable(eligible, func(i, j int) bool {
+		a, b := eligible[i], eligible[j]
+		if a.Active != b.Active {
+			return a.Active
+		}
+		if ta, tb := familySuccessionTier(a.Role), familySuccessionTier(b.Role); ta != tb {
+			return ta < tb
+		}
+		if !a.JoinedAt.Equal(b.JoinedAt) {
+			return a.JoinedAt.Before(b.JoinedAt)
+		}
+		return a.UserID < b.UserID
+	})
+	return eligible[0], true
+}
@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPickFamilySuccessor(t *testing.T) {
+	base := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	day := func(n int) time.Time { return base.AddDate(0, 0, n) }
+
+	candidates := []FamilySuccessionCandidate{
+		{UserID: "guest", Role: FamilyRoleGuest, JoinedAt: day(0), Active: true},
+		{UserID: "member-old", Role: FamilyRoleMember, JoinedAt: day(1), Active: true},
+		{UserID: "editor", Role: FamilyRoleEditor, JoinedAt: day(2), Active: true},
+		{UserID: "admin-new", Role: FamilyRoleAdmin, JoinedAt: day(20), Active: true},
+		{UserID: "admin-old", Role: FamilyRoleAdmin, JoinedAt: day(10), Active: true},
+		{UserID: "owner", Role: FamilyRoleOwner, JoinedAt: day(-1), Active: true},
+	}
+
+	// 加入最早的管理员
+	successor, ok := PickFamilySuccessor(candidates)
+	assert.True(t, ok)
+	assert.Equal(t, "admin-old", successor.UserID)
+
+	// 没有管理员时选加入最早的成员，编辑与普通成员同一顺位
+	successor, ok = PickFamilySuccessor(candidates[:3])
+	assert.True(t, ok)
+	assert.Equal(t, "member-old", successor.UserID)
+
+	// 只剩访客时由访客继任
+	successor, ok = PickFamilySuccessor(candidates[:1])
+	assert.True(t, ok)
+	assert.Equal(t, "guest", successor.UserID)
+}
+
+func TestPickFamilySuccessorPrefersActive(t *testing.T) {
+	base := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	candidates := []FamilySuccessionCandidate{
+		{UserID: "admin-inactive", Role: FamilyRoleAdmin, JoinedAt: base, Active: false},
+		{UserID: "member-active", Role: FamilyRoleMember, JoinedAt: base.AddDate(1, 0, 0), Active: true},
+	}
+
+	successor, ok := PickFamilySuccessor(candidates)
+	assert.True(t, ok)
+	assert.Equal(t, "member-active", successor.UserID)
+
+	// 全部不活跃时仍按顺位选择
+	successor, ok = PickFamilySuccessor(candidates[:1])
+	assert.True(t, ok)
+	assert.Equal(t, "admin-inactive", successor.UserID)
+}
+
+func TestPickFamilySuccessorNone(t *testing.T) {
+	_, ok := PickFamilySuccessor(nil)
+	assert.False(t, ok)
+
+	_, ok = PickFamilySuccessor([]FamilySuccessionCandidate{{UserID: "owner", Role: FamilyRoleOwner}})
+	assert.False(t, ok)
+}
@@ -88,6 +88,23 @@ func (f *FileUploadManager) GetFileURL(relativePath string) string {
 	return "/uploads/" + strings.ReplaceAll(relativePath, "\\", "/")
 }
 
+// GetRelativePath 从文件URL解析出上传目录下的相对路径
+func (f *FileUploadManager) GetRelativePath(fileURL string) (string, error) {
+	if !strings.HasPrefix(fileURL, "/uploads/") {
+		return "", fmt.Errorf("非本站上传的文件")
+	}
+	relativePath := filepath.Clean(strings.TrimPrefix(fileURL, "/uploads/"))
+	if relativePath == "." || strings.HasPrefix(relativePath, "..") || filepath.IsAbs(relativePath) {
+		return "", fmt.Errorf("文件路径非法")
+	}
+	return relativePath, nil
+}
+
+// GetLocalPath 获取文件在服务器上的完整路径
+func (f *FileUploadManager) GetLocalPath(relativePath string) string {
+	return filepath.Join(f.uploadDir, relativePath)
+}
+
 // isAllowedFileType 检查是否为允许的文件类型
 func (f *FileUploadManager) isAllowedFileType(filename string) bool {
 	ext := strings.ToLower(filepath.Ext(filename))
@@ -0,0 +1,1023 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 支持的 GEDCOM 版本
+const (
+	GedcomVersion551 = "5.5.1"
+	GedcomVersion7   = "7.0"
+)
+
+// GEDCOM 中的自定义标签：关联的纪念馆ID
+const GedcomTagMemorial = "_MEMORIAL"
+
+// GedcomRefType 导出时 REFN 的 TYPE，用于再次导入时识别本系统的谱系成员
+const GedcomRefType = "yun-nian-memorial"
+
+// GedcomAchievementsPrefix 导出主要成就时使用的 NOTE 前缀
+const GedcomAchievementsPrefix = "主要成就："
+
+// 5.5.1 规定每行不超过 255 个字符，导出时长文本按此长度用 CONC 折行
+const gedcomLineChunkBytes = 200
+
+// GedcomNode GEDCOM 中的一行及其下级结构，CONC/CONT 已合并到 Value
+type GedcomNode struct {
+	Xref     string
+	Tag      string
+	Value    string
+	Children []*GedcomNode
+}
+
+// Child 返回第一个指定标签的下级结构
+func (n *GedcomNode) Child(tag string) *GedcomNode {
+	for _, c := range n.Children {
+		if c.Tag == tag {
+			return c
+		}
+	}
+	return nil
+}
+
+// ChildValue 返回第一个指定标签的下级结构的值
+func (n *GedcomNode) ChildValue(tag string) string {
+	if c := n.Child(tag); c != nil {
+		return c.Value
+	}
+	return ""
+}
+
+// ChildrenByTag 返回所有指定标签的下级结构
+func (n *GedcomNode) ChildrenByTag(tag string) []*GedcomNode {
+	var nodes []*GedcomNode
+	for _, c := range n.Children {
+		if c.Tag == tag {
+			nodes = append(nodes, c)
+		}
+	}
+	return nodes
+}
+
+// ParseGedcom 将 GEDCOM 文本解析为顶层记录列表，只支持 UTF-8 编码
+func ParseGedcom(data []byte) ([]*GedcomNode, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if bytes.HasPrefix(data, []byte("\xff\xfe")) || bytes.HasPrefix(data, []byte("\xfe\xff")) || !utf8.Valid(data) {
+		return nil, errors.New("仅支持 UTF-8 编码的 GEDCOM 文件")
+	}
+
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	var records []*GedcomNode
+	var stack []*GedcomNode // stack[i] 为当前 i 级结构
+	version7 := false
+
+	for i, raw := range strings.Split(text, "\n") {
+		line := strings.TrimLeft(raw, " \t")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		level, xref, tag, value, err := parseGedcomLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("GEDCOM 第 %d 行格式错误", i+1)
+		}
+		if level > len(stack) {
+			return nil, fmt.Errorf("GEDCOM 第 %d 行层级错误", i+1)
+		}
+
+		if tag == "CONC" || tag == "CONT" {
+			if level == 0 {
+				return nil, fmt.Errorf("GEDCOM 第 %d 行层级错误", i+1)
+			}
+			parent := stack[level-1]
+			if tag == "CONT" {
+				parent.Value += "\n"
+			}
+			parent.Value += unescapeGedcomValue(value, version7)
+			continue
+		}
+
+		node := &GedcomNode{Xref: xref, Tag: tag, Value: unescapeGedcomValue(value, version7)}
+		stack = stack[:level]
+		if level == 0 {
+			records = append(records, node)
+		} else {
+			parent := stack[level-1]
+			parent.Children = append(parent.Children, node)
+			// 在 HEAD.GEDC.VERS 出现后即可确定转义规则
+			if tag == "VERS" && len(stack) == 2 && stack[0].Tag == "HEAD" && stack[1].Tag == "GEDC" {
+				version7 = strings.HasPrefix(node.Value, "7")
+			}
+		}
+		stack = append(stack, node)
+	}
+
+	if len(records) == 0 || records[0].Tag != "HEAD" {
+		return nil, errors.New("不是有效的 GEDCOM 文件")
+	}
+	return records, nil
+}
+
+func parseGedcomLine(line string) (level int, xref, tag, value string, err error) {
+	parts := strings.SplitN(line, " ", 2)
+	level, err = strconv.Atoi(parts[0])
+	if err != nil || level < 0 || len(parts) < 2 {
+		return 0, "", "", "", errors.New("invalid line")
+	}
+	rest := parts[1]
+	if strings.HasPrefix(rest, "@") {
+		parts = strings.SplitN(rest, " ", 2)
+		xref = parts[0]
+		if len(parts) < 2 {
+			return 0, "", "", "", errors.New("invalid line")
+		}
+		rest = parts[1]
+	}
+	parts = strings.SplitN(rest, " ", 2)
+	tag = strings.TrimSpace(parts[0])
+	if tag == "" {
+		return 0, "", "", "", errors.New("invalid line")
+	}
+	if len(parts) == 2 {
+		value = parts[1]
+	}
+	return level, xref, tag, value, nil
+}
+
+// 5.5.1 中值里的 @@ 表示 @；7.0 只转义开头的 @@
+func unescapeGedcomValue(value string, version7 bool) string {
+	if isGedcomPointer(value) {
+		return value
+	}
+	if version7 {
+		if strings.HasPrefix(value, "@@") {
+			return value[1:]
+		}
+		return value
+	}
+	return strings.ReplaceAll(value, "@@", "@")
+}
+
+func escapeGedcomValue(value string, version7 bool) string {
+	if version7 {
+		if strings.HasPrefix(value, "@") {
+			return "@" + value
+		}
+		return value
+	}
+	return strings.ReplaceAll(value, "@", "@@")
+}
+
+func isGedcomPointer(value string) bool {
+	return len(value) > 2 && strings.HasPrefix(value, "@") && strings.HasSuffix(value, "@") &&
+		!strings.HasPrefix(value, "@#") && !strings.Contains(value, " ")
+}
+
+// GedcomPerson GEDCOM 中的个人记录（INDI）
+type GedcomPerson struct {
+	Xref         string
+	Name         string
+	Gender       string // male | female | 空表示未知
+	BirthDate    *time.Time
+	DeathDate    *time.Time
+	Biography    string
+	Achievements string
+	Occupation   string
+	AvatarURL    string
+	MemorialID   string // 自定义标签 _MEMORIAL
+	RefID        string // 本系统导出的谱系成员ID（REFN，TYPE 为 GedcomRefType）
+	ParentFams   []string
+	Pedigrees    map[string]string // 非亲生的 FAMC 对应的父母关系类型（adopted | step），按家庭 xref
+	SpouseFams   []string
+	Warnings     []string
+}
+
+// GedcomFamily GEDCOM 中的家庭记录（FAM）
+type GedcomFamily struct {
+	Xref         string
+	Husband      string
+	Wife         string
+	Children     []string
+	MarriageDate *time.Time
+	DivorceDate  *time.Time
+}
+
+// GedcomDocument 解析后的 GEDCOM 文件
+type GedcomDocument struct {
+	Version   string
+	Submitter string // 导出时的提交者名称，5.5.1 要求文件头引用提交者记录，为空时使用来源标识
+	Persons   []*GedcomPerson
+	Families  []*GedcomFamily
+	Warnings  []string
+}
+
+// DecodeGedcom 解析 GEDCOM 5.5.1 或 7.0 文件中的个人和家庭记录
+func DecodeGedcom(data []byte) (*GedcomDocument, error) {
+	records, err := ParseGedcom(data)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &GedcomDocument{}
+	if gedc := records[0].Child("GEDC"); gedc != nil {
+		doc.Version = gedc.ChildValue("VERS")
+	}
+	if doc.Version == "" {
+		doc.Version = GedcomVersion551
+	}
+	if !strings.HasPrefix(doc.Version, "5.5") && !strings.HasPrefix(doc.Version, "7") {
+		doc.Warnings = append(doc.Warnings, fmt.Sprintf("GEDCOM 版本 %s 未经测试，按 5.5.1 解析", doc.Version))
+	}
+	if charset := strings.ToUpper(records[0].ChildValue("CHAR")); charset != "" && charset != "UTF-8" && charset != "ASCII" {
+		return nil, errors.New("仅支持 UTF-8 编码的 GEDCOM 文件")
+	}
+
+	// 共享记录：NOTE/SNOTE 和 OBJE
+	shared := map[string]*GedcomNode{}
+	for _, r := range records {
+		if r.Xref != "" {
+			shared[r.Xref] = r
+		}
+	}
+
+	for _, r := range records {
+		switch r.Tag {
+		case "INDI":
+			if r.Xref == "" {
+				continue
+			}
+			doc.Persons = append(doc.Persons, decodeGedcomPerson(r, shared))
+		case "FAM":
+			if r.Xref == "" {
+				continue
+			}
+			family := &GedcomFamily{
+				Xref:    r.Xref,
+				Husband: r.ChildValue("HUSB"),
+				Wife:    r.ChildValue("WIFE"),
+			}
+			for _, c := range r.ChildrenByTag("CHIL") {
+				if c.Value != "" {
+					family.Children = append(family.Children, c.Value)
+				}
+			}
+			if marr := r.Child("MARR"); marr != nil {
+				family.MarriageDate, _ = ParseGedcomDate(marr.ChildValue("DATE"))
+			}
+			if div := r.Child("DIV"); div != nil {
+				family.DivorceDate, _ = ParseGedcomDate(div.ChildValue("DATE"))
+			}
+			doc.Families = append(doc.Families, family)
+		}
+	}
+
+	if len(doc.Persons) == 0 {
+		return nil, errors.New("GEDCOM 文件中没有个人记录")
+	}
+	return doc, nil
+}
+
+func decodeGedcomPerson(r *GedcomNode, shared map[string]*GedcomNode) *GedcomPerson {
+	person := &GedcomPerson{Xref: r.Xref}
+
+	if name := r.Child("NAME"); name != nil {
+		person.Name = ParseGedcomName(name.Value)
+		if person.Name == "" {
+			person.Name = strings.TrimSpace(name.ChildValue("SURN") + name.ChildValue("GIVN"))
+		}
+	}
+	if person.Name == "" {
+		person.Name = "佚名"
+		person.Warnings = append(person.Warnings, "缺少姓名")
+	}
+
+	switch strings.ToUpper(r.ChildValue("SEX")) {
+	case "M":
+		person.Gender = "male"
+	case "F":
+		person.Gender = "female"
+	default:
+		person.Warnings = append(person.Warnings, "性别未知")
+	}
+
+	person.BirthDate = decodeGedcomEventDate(r.Child("BIRT"), "出生", person)
+	person.DeathDate = decodeGedcomEventDate(r.Child("DEAT"), "逝世", person)
+
+	var notes []string
+	for _, tag := range []string{"NOTE", "SNOTE"} {
+		for _, n := range r.ChildrenByTag(tag) {
+			text := n.Value
+			if isGedcomPointer(text) {
+				text = ""
+				if rec := shared[n.Value]; rec != nil {
+					text = rec.Value
+				}
+			}
+			text = strings.TrimSpace(text)
+			if strings.HasPrefix(text, GedcomAchievementsPrefix) {
+				person.Achievements = strings.TrimSpace(strings.TrimPrefix(text, GedcomAchievementsPrefix))
+			} else if text != "" {
+				notes = append(notes, text)
+			}
+		}
+	}
+	person.Biography = strings.Join(notes, "\n\n")
+	person.Occupation = strings.TrimSpace(r.ChildValue("OCCU"))
+
+	for _, obje := range r.ChildrenByTag("OBJE") {
+		node := obje
+		if isGedcomPointer(obje.Value) {
+			node = shared[obje.Value]
+		}
+		if node != nil {
+			if file := strings.TrimSpace(node.ChildValue("FILE")); file != "" {
+				person.AvatarURL = file
+				break
+			}
+		}
+	}
+
+	person.MemorialID = strings.TrimSpace(r.ChildValue(GedcomTagMemorial))
+	for _, refn := range r.ChildrenByTag("REFN") {
+		if refn.ChildValue("TYPE") == GedcomRefType {
+			person.RefID = strings.TrimSpace(refn.Value)
+		}
+	}
+
+	// 有多个 FAMC 时，PEDI 为 birth 或未注明的优先
+	famc := r.ChildrenByTag("FAMC")
+	sort.SliceStable(famc, func(i, j int) bool {
+		return gedcomPedigreeRank(famc[i]) < gedcomPedigreeRank(famc[j])
+	})
+	for _, c := range famc {
+		person.ParentFams = append(person.ParentFams, c.Value)
+		if parentType := gedcomPedigreeType(c.ChildValue("PEDI")); parentType != GenealogyParentBiological {
+			if person.Pedigrees == nil {
+				person.Pedigrees = map[string]string{}
+			}
+			person.Pedigrees[c.Value] = parentType
+		}
+	}
+	for _, c := range r.ChildrenByTag("FAMS") {
+		person.SpouseFams = append(person.SpouseFams, c.Value)
+	}
+	return person
+}
+
+// PEDI 对应的父母关系类型：adopted 为收养或过继，foster 等其他值视为继父母
+func gedcomPedigreeType(pedi string) string {
+	switch strings.ToLower(strings.TrimSpace(pedi)) {
+	case "", "birth":
+		return GenealogyParentBiological
+	case "adopted":
+		return GenealogyParentAdopted
+	default:
+		return GenealogyParentStep
+	}
+}
+
+func gedcomPedigreeRank(famc *GedcomNode) int {
+	switch strings.ToLower(famc.ChildValue("PEDI")) {
+	case "", "birth":
+		return 0
+	default:
+		return 1
+	}
+}
+
+func decodeGedcomEventDate(event *GedcomNode, label string, person *GedcomPerson) *time.Time {
+	if event == nil {
+		return nil
+	}
+	value := strings.TrimSpace(event.ChildValue("DATE"))
+	if value == "" {
+		return nil
+	}
+	date, exact := ParseGedcomDate(value)
+	if date == nil {
+		person.Warnings = append(person.Warnings, fmt.Sprintf("无法识别的%s日期: %s", label, value))
+	} else if !exact {
+		person.Warnings = append(person.Warnings, fmt.Sprintf("%s日期不精确: %s", label, value))
+	}
+	return date
+}
+
+var gedcomMonths = map[string]time.Month{
+	"JAN": time.January, "FEB": time.February, "MAR": time.March, "APR": time.April,
+	"MAY": time.May, "JUN": time.June, "JUL": time.July, "AUG": time.August,
+	"SEP": time.September, "OCT": time.October, "NOV": time.November, "DEC": time.December,
+}
+
+var gedcomMonthNames = []string{"", "JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}
+
+// ParseGedcomDate 解析 GEDCOM 日期
+//
+// 支持“2 JAN 1930”“JAN 1930”“1930”以及 ABT/CAL/EST/BEF/AFT、BET…AND…、FROM…TO… 等形式，
+// 范围取起始日期，缺少的月、日按 1 计。exact 表示日期是否精确到日且没有修饰词。
+// 无法识别（包括非公历历法、公元前日期、纯文字描述）时返回 nil。
+func ParseGedcomDate(value string) (date *time.Time, exact bool) {
+	fields := strings.Fields(strings.ToUpper(value))
+	if len(fields) == 0 {
+		return nil, false
+	}
+
+	exact = true
+	switch fields[0] {
+	case "ABT", "CAL", "EST", "BEF", "AFT", "BET", "FROM", "TO", "INT":
+		exact = false
+		fields = fields[1:]
+	}
+	for i, f := range fields {
+		if f == "AND" || f == "TO" || strings.HasPrefix(f, "(") {
+			fields = fields[:i]
+			break
+		}
+	}
+
+	if len(fields) > 0 {
+		switch fields[0] {
+		case "@#DGREGORIAN@", "GREGORIAN":
+			fields = fields[1:]
+		case "@#DJULIAN@", "JULIAN":
+			exact = false
+			fields = fields[1:]
+		default:
+			if strings.HasPrefix(fields[0], "@#") || fields[0] == "HEBREW" || fields[0] == "FRENCH_R" {
+				return nil, false
+			}
+		}
+	}
+	if len(fields) == 0 || len(fields) > 3 {
+		return nil, false
+	}
+	if last := fields[len(fields)-1]; last == "BC" || last == "B.C." || last == "BCE" {
+		return nil, false
+	}
+
+	// 双年份写法 1750/51 取前一个
+	yearText := strings.SplitN(fields[len(fields)-1], "/", 2)[0]
+	year, err := strconv.Atoi(yearText)
+	if err != nil || year <= 0 {
+		return nil, false
+	}
+
+	month, day := time.January, 1
+	if len(fields) >= 2 {
+		m, ok := gedcomMonths[fields[len(fields)-2]]
+		if !ok {
+			return nil, false
+		}
+		month = m
+	} else {
+		exact = false
+	}
+	if len(fields) == 3 {
+		day, err = strconv.Atoi(fields[0])
+		if err != nil || day < 1 || day > 31 {
+			return nil, false
+		}
+	} else {
+		exact = false
+	}
+
+	t := time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+	if t.Day() != day {
+		return nil, false
+	}
+	return &t, exact
+}
+
+// FormatGedcomDate 按 GEDCOM 格式输出日期，如“2 JAN 1930”
+func FormatGedcomDate(t time.Time) string {
+	return fmt.Sprintf("%d %s %d", t.Day(), gedcomMonthNames[t.Month()], t.Year())
+}
+
+// 常见复姓，导出时用于拆分姓和名
+var chineseCompoundSurnames = []string{
+	"欧阳", "司马", "诸葛", "上官", "东方", "皇甫", "令狐", "慕容", "司徒", "夏侯", "公孙", "轩辕", "尉迟", "长孙", "宇文", "澹台",
+}
+
+func isHanName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !unicode.Is(unicode.Han, r) && r != '·' {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseGedcomName 将 GEDCOM 姓名（名 /姓/ 后缀）转换为显示用的姓名；中文姓名按姓在前、不加空格拼接
+func ParseGedcomName(value string) string {
+	value = strings.TrimSpace(value)
+	parts := strings.SplitN(value, "/", 3)
+	if len(parts) < 3 {
+		return strings.Join(strings.Fields(strings.ReplaceAll(value, "/", " ")), " ")
+	}
+
+	given := strings.TrimSpace(parts[0])
+	surname := strings.TrimSpace(parts[1])
+	suffix := strings.TrimSpace(parts[2])
+
+	if isHanName(strings.ReplaceAll(given+surname+suffix, " ", "")) {
+		return strings.ReplaceAll(surname+given+suffix, " ", "")
+	}
+	var words []string
+	for _, w := range []string{given, surname, suffix} {
+		if w != "" {
+			words = append(words, w)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// FormatGedcomName 将姓名拆分为 GEDCOM 的 NAME 值及姓、名
+//
+// 中文姓名按常见复姓或首字拆出姓氏，输出为“/张/三”；西文姓名以最后一个词为姓。
+func FormatGedcomName(name string) (value, surname, given string) {
+	name = strings.TrimSpace(name)
+	runes := []rune(name)
+	if isHanName(name) && len(runes) >= 2 && len(runes) <= 5 {
+		surname = string(runes[:1])
+		for _, compound := range chineseCompoundSurnames {
+			if strings.HasPrefix(name, compound) && len(runes) > 2 {
+				surname = compound
+				break
+			}
+		}
+		given = strings.TrimPrefix(name, surname)
+		return "/" + surname + "/" + given, surname, given
+	}
+
+	words := strings.Fields(name)
+	if len(words) >= 2 && !isHanName(name) {
+		surname = words[len(words)-1]
+		given = strings.Join(words[:len(words)-1], " ")
+		return given + " /" + surname + "/", surname, given
+	}
+	return name, "", ""
+}
+
+// GedcomParentLink 从家庭记录中得到的父母与子女关系
+type GedcomParentLink struct {
+	Parent     string
+	Child      string
+	ParentType string // biological | adopted | step
+}
+
+// GedcomParentLinks 列出父母与子女关系：家庭中的丈夫和妻子都是子女的父母，关系类型取 FAMC 的 PEDI
+//
+// 文件中存在循环的父子关系时，丢弃使关系成环的那一条。
+func GedcomParentLinks(doc *GedcomDocument) []GedcomParentLink {
+	persons := map[string]*GedcomPerson{}
+	for _, p := range doc.Persons {
+		persons[p.Xref] = p
+	}
+
+	graph := NewGenealogyGraph(nil)
+	seen := map[[2]string]bool{}
+	var links []GedcomParentLink
+	add := func(parent, child, parentType string) {
+		key := [2]string{parent, child}
+		if persons[parent] == nil || persons[child] == nil || parent == child || seen[key] {
+			return
+		}
+		if graph.IsAncestor(child, parent) {
+			return
+		}
+		seen[key] = true
+		graph.Add(GenealogyEdge{Type: GenealogyRelationParent, FromID: parent, ToID: child, ParentType: parentType})
+		links = append(links, GedcomParentLink{Parent: parent, Child: child, ParentType: parentType})
+	}
+
+	families := map[string]*GedcomFamily{}
+	for _, f := range doc.Families {
+		families[f.Xref] = f
+	}
+	for _, p := range doc.Persons {
+		for _, famXref := range p.ParentFams {
+			f := families[famXref]
+			if f == nil {
+				continue
+			}
+			parentType := GenealogyParentBiological
+			if t, ok := p.Pedigrees[famXref]; ok {
+				parentType = t
+			}
+			add(f.Husband, p.Xref, parentType)
+			add(f.Wife, p.Xref, parentType)
+		}
+	}
+	// 只在 FAM.CHIL 中出现、个人记录缺少 FAMC 的情况按亲生处理
+	for _, f := range doc.Families {
+		for _, child := range f.Children {
+			if p := persons[child]; p != nil && containsString(p.ParentFams, f.Xref) {
+				continue
+			}
+			add(f.Husband, child, GenealogyParentBiological)
+			add(f.Wife, child, GenealogyParentBiological)
+		}
+	}
+	return links
+}
+
+// GedcomGenerations 计算相对辈分
+//
+// 同一家庭中子女比父母低一辈，夫妻同辈。返回每个人的相对辈分（每个相连的群体中最高一辈为 0）
+// 以及所属群体的编号（群体中按 xref 排序最小者）。
+func GedcomGenerations(doc *GedcomDocument) (generation map[string]int, group map[string]string) {
+	type edge struct {
+		to    string
+		delta int
+	}
+	adj := map[string][]edge{}
+	persons := map[string]bool{}
+	var order []string
+	for _, p := range doc.Persons {
+		persons[p.Xref] = true
+		order = append(order, p.Xref)
+	}
+	link := func(a, b string, delta int) {
+		if !persons[a] || !persons[b] || a == b {
+			return
+		}
+		adj[a] = append(adj[a], edge{b, delta})
+		adj[b] = append(adj[b], edge{a, -delta})
+	}
+	for _, f := range doc.Families {
+		link(f.Husband, f.Wife, 0)
+		for _, child := range f.Children {
+			link(f.Husband, child, 1)
+			link(f.Wife, child, 1)
+		}
+	}
+	for _, p := range doc.Persons {
+		for _, famXref := range p.ParentFams {
+			for _, f := range doc.Families {
+				if f.Xref == famXref && !containsString(f.Children, p.Xref) {
+					link(f.Husband, p.Xref, 1)
+					link(f.Wife, p.Xref, 1)
+				}
+			}
+		}
+	}
+
+	sort.Strings(order)
+	generation = map[string]int{}
+	group = map[string]string{}
+	for _, start := range order {
+		if _, ok := group[start]; ok {
+			continue
+		}
+		members := []string{start}
+		generation[start] = 0
+		group[start] = start
+		for i := 0; i < len(members); i++ {
+			cur := members[i]
+			for _, e := range adj[cur] {
+				if _, ok := group[e.to]; ok {
+					continue
+				}
+				group[e.to] = start
+				generation[e.to] = generation[cur] + e.delta
+				members = append(members, e.to)
+			}
+		}
+
+		min := 0
+		for _, m := range members {
+			if generation[m] < min {
+				min = generation[m]
+			}
+		}
+		for _, m := range members {
+			generation[m] -= min
+		}
+	}
+	return generation, group
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// GenealogyPersonKey 谱系成员查重时使用的信息
+type GenealogyPersonKey struct {
+	ID        string
+	Name      string
+	Gender    string
+	BirthYear int // 0 表示未知
+}
+
+// 查重结果的可信度
+const (
+	GenealogyMatchExact    = "exact"    // 同一个人：本系统导出的ID相同，或姓名和出生年份都相同
+	GenealogyMatchPossible = "possible" // 可能是同一个人：姓名相同但缺少出生年份
+)
+
+func normalizeGenealogyName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), ""))
+}
+
+// FindGenealogyDuplicate 在已有谱系成员中查找与导入人员重复的记录
+//
+// refID 为导入文件中记录的本系统谱系成员ID，与已有成员ID相同时直接视为同一个人。
+// 否则要求姓名相同、性别不冲突：出生年份都已知且相同为 exact，有一方未知为 possible，年份不同则不算重复。
+func FindGenealogyDuplicate(person GenealogyPersonKey, refID string, existing []GenealogyPersonKey) (string, string) {
+	if refID != "" {
+		for _, e := range existing {
+			if e.ID == refID {
+				return e.ID, GenealogyMatchExact
+			}
+		}
+	}
+
+	name := normalizeGenealogyName(person.Name)
+	possible := ""
+	for _, e := range existing {
+		if normalizeGenealogyName(e.Name) != name {
+			continue
+		}
+		if person.Gender != "" && e.Gender != "" && person.Gender != e.Gender {
+			continue
+		}
+		if person.BirthYear != 0 && e.BirthYear != 0 {
+			if person.BirthYear == e.BirthYear {
+				return e.ID, GenealogyMatchExact
+			}
+			continue
+		}
+		if possible == "" {
+			possible = e.ID
+		}
+	}
+	if possible != "" {
+		return possible, GenealogyMatchPossible
+	}
+	return "", ""
+}
+
+// GedcomWriter 按指定版本输出 GEDCOM 文本
+type GedcomWriter struct {
+	buf      bytes.Buffer
+	version7 bool
+}
+
+// gedcomSubmitterXref 导出文件中唯一的提交者记录
+const gedcomSubmitterXref = "@U1@"
+
+// NewGedcomWriter 创建 GEDCOM 输出器并写入文件头；version 为 GedcomVersion551 或 GedcomVersion7。
+// 5.5.1 要求 HEAD.SUBM，同时写入名为 submitter（为空时为 source）的提交者记录
+func NewGedcomWriter(version, source, submitter string) *GedcomWriter {
+	w := &GedcomWriter{version7: version == GedcomVersion7}
+	w.Line(0, "", "HEAD", "")
+	if w.version7 {
+		w.Line(1, "", "GEDC", "")
+		w.Line(2, "", "VERS", GedcomVersion7)
+		w.Line(1, "", "SOUR", source)
+	} else {
+		w.Line(1, "", "SOUR", source)
+		w.Line(1, "", "GEDC", "")
+		w.Line(2, "", "VERS", GedcomVersion551)
+		w.Line(2, "", "FORM", "LINEAGE-LINKED")
+		w.Line(1, "", "CHAR", "UTF-8")
+	}
+	w.Line(1, "", "DATE", FormatGedcomDate(time.Now()))
+	if !w.version7 {
+		if submitter == "" {
+			submitter = source
+		}
+		w.Line(1, "", "SUBM", gedcomSubmitterXref)
+		w.Line(0, gedcomSubmitterXref, "SUBM", "")
+		w.Line(1, "", "NAME", submitter)
+	}
+	return w
+}
+
+// Line 写入一行；值中的换行转为 CONT，5.5.1 的长文本用 CONC 折行
+func (w *GedcomWriter) Line(level int, xref, tag, value string) {
+	lines := strings.Split(value, "\n")
+	for i, text := range lines {
+		lineLevel, lineTag := level, tag
+		if i > 0 {
+			lineLevel, lineTag = level+1, "CONT"
+		}
+		chunks := []string{text}
+		if !w.version7 && !isGedcomPointer(text) {
+			chunks = splitGedcomText(text)
+		}
+		for j, chunk := range chunks {
+			if j > 0 {
+				lineLevel, lineTag = level+1, "CONC"
+			}
+			w.writeLine(lineLevel, xref, lineTag, chunk)
+			xref = ""
+		}
+	}
+}
+
+func (w *GedcomWriter) writeLine(level int, xref, tag, value string) {
+	w.buf.WriteString(strconv.Itoa(level))
+	if xref != "" {
+		w.buf.WriteString(" " + xref)
+	}
+	w.buf.WriteString(" " + tag)
+	if value != "" {
+		if !isGedcomPointer(value) {
+			value = escapeGedcomValue(value, w.version7)
+		}
+		w.buf.WriteString(" " + value)
+	}
+	w.buf.WriteString("\n")
+}
+
+// 按字节长度切分，不拆开多字节字符，也不以空格开头或结尾（CONC 会丢失边界空格）
+func splitGedcomText(text string) []string {
+	if len(text) <= gedcomLineChunkBytes {
+		return []string{text}
+	}
+	var chunks []string
+	for len(text) > gedcomLineChunkBytes {
+		cut := gedcomLineChunkBytes
+		for cut > 0 && (!utf8.RuneStart(text[cut]) || text[cut] == ' ' || text[cut-1] == ' ') {
+			cut--
+		}
+		if cut == 0 {
+			cut = gedcomLineChunkBytes
+			for cut < len(text) && !utf8.RuneStart(text[cut]) {
+				cut++
+			}
+		}
+		chunks = append(chunks, text[:cut])
+		text = text[cut:]
+	}
+	return append(chunks, text)
+}
+
+// Bytes 写入文件尾并返回完整内容
+func (w *GedcomWriter) Bytes() []byte {
+	w.writeLine(0, "", "TRLR", "")
+	return w.buf.Bytes()
+}
+
+// EncodeGedcom 将个人和家庭记录输出为指定版本的 GEDCOM 文本
+func EncodeGedcom(doc *GedcomDocument, version, source string) []byte {
+	w := NewGedcomWriter(version, source, doc.Submitter)
+
+	type mediaRecord struct{ xref, file string }
+	var media []mediaRecord
+
+	for _, p := range doc.Persons {
+		w.Line(0, p.Xref, "INDI", "")
+		value, surname, given := FormatGedcomName(p.Name)
+		w.Line(1, "", "NAME", value)
+		if surname != "" {
+			w.Line(2, "", "GIVN", given)
+			w.Line(2, "", "SURN", surname)
+		}
+		switch p.Gender {
+		case "male":
+			w.Line(1, "", "SEX", "M")
+		case "female":
+			w.Line(1, "", "SEX", "F")
+		default:
+			w.Line(1, "", "SEX", "U")
+		}
+		if p.BirthDate != nil {
+			w.Line(1, "", "BIRT", "")
+			w.Line(2, "", "DATE", FormatGedcomDate(*p.BirthDate))
+		}
+		if p.DeathDate != nil {
+			w.Line(1, "", "DEAT", "")
+			w.Line(2, "", "DATE", FormatGedcomDate(*p.DeathDate))
+		} else if p.MemorialID != "" {
+			w.Line(1, "", "DEAT", "Y")
+		}
+		if p.Occupation != "" {
+			w.Line(1, "", "OCCU", p.Occupation)
+		}
+		if p.Biography != "" {
+			w.Line(1, "", "NOTE", p.Biography)
+		}
+		if p.Achievements != "" {
+			w.Line(1, "", "NOTE", GedcomAchievementsPrefix+p.Achievements)
+		}
+		if p.AvatarURL != "" {
+			// 7.0 的多媒体只能以共享记录的形式引用
+			if w.version7 {
+				xref := fmt.Sprintf("@O%d@", len(media)+1)
+				media = append(media, mediaRecord{xref, p.AvatarURL})
+				w.Line(1, "", "OBJE", xref)
+			} else {
+				w.Line(1, "", "OBJE", "")
+				w.Line(2, "", "FILE", p.AvatarURL)
+				w.Line(3, "", "FORM", gedcomMediaForm(p.AvatarURL, false))
+			}
+		}
+		for _, fam := range p.ParentFams {
+			w.Line(1, "", "FAMC", fam)
+			if pedi := gedcomPedigreeValue(p.Pedigrees[fam], w.version7); pedi != "" {
+				w.Line(2, "", "PEDI", pedi)
+			}
+		}
+		for _, fam := range p.SpouseFams {
+			w.Line(1, "", "FAMS", fam)
+		}
+		if p.RefID != "" {
+			w.Line(1, "", "REFN", p.RefID)
+			w.Line(2, "", "TYPE", GedcomRefType)
+		}
+		if p.MemorialID != "" {
+			w.Line(1, "", GedcomTagMemorial, p.MemorialID)
+		}
+	}
+
+	for _, f := range doc.Families {
+		w.Line(0, f.Xref, "FAM", "")
+		if f.Husband != "" {
+			w.Line(1, "", "HUSB", f.Husband)
+		}
+		if f.Wife != "" {
+			w.Line(1, "", "WIFE", f.Wife)
+		}
+		if f.MarriageDate != nil {
+			w.Line(1, "", "MARR", "")
+			w.Line(2, "", "DATE", FormatGedcomDate(*f.MarriageDate))
+		}
+		if f.DivorceDate != nil {
+			w.Line(1, "", "DIV", "")
+			w.Line(2, "", "DATE", FormatGedcomDate(*f.DivorceDate))
+		}
+		for _, child := range f.Children {
+			w.Line(1, "", "CHIL", child)
+		}
+	}
+
+	for _, m := range media {
+		w.Line(0, m.xref, "OBJE", "")
+		w.Line(1, "", "FILE", m.file)
+		w.Line(2, "", "FORM", gedcomMediaForm(m.file, true))
+	}
+	return w.Bytes()
+}
+
+// 父母关系类型对应的 PEDI：继父母没有对应值，按 foster 导出；亲生不输出
+func gedcomPedigreeValue(parentType string, version7 bool) string {
+	var pedi string
+	switch parentType {
+	case GenealogyParentAdopted:
+		pedi = "adopted"
+	case GenealogyParentStep:
+		pedi = "foster"
+	default:
+		return ""
+	}
+	if version7 {
+		return strings.ToUpper(pedi)
+	}
+	return pedi
+}
+
+// 5.5.1 的 FORM 为扩展名，7.0 为媒体类型
+func gedcomMediaForm(file string, version7 bool) string {
+	ext := strings.ToLower(file)
+	if i := strings.IndexAny(ext, "?#"); i >= 0 {
+		ext = ext[:i]
+	}
+	if i := strings.LastIndex(ext, "."); i >= 0 {
+		ext = ext[i+1:]
+	} else {
+		ext = "jpg"
+	}
+	if ext == "jpeg" {
+		ext = "jpg"
+	}
+	if !version7 {
+		return ext
+	}
+	switch ext {
+	case "jpg":
+		return "image/jpeg"
+	case "png", "gif", "bmp", "webp":
+		return "image/" + ext
+	default:
+		return "application/octet-stream"
+	}
+}
@@ -0,0 +1,344 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sampleGedcom551 = "\xef\xbb\xbf0 HEAD\r\n" +
+	"1 SOUR TEST\r\n" +
+	"1 GEDC\r\n" +
+	"2 VERS 5.5.1\r\n" +
+	"2 FORM LINEAGE-LINKED\r\n" +
+	"1 CHAR UTF-8\r\n" +
+	"0 @I1@ INDI\r\n" +
+	"1 NAME /张/德厚\r\n" +
+	"1 SEX M\r\n" +
+	"1 BIRT\r\n" +
+	"2 DATE ABT 1900\r\n" +
+	"1 DEAT\r\n" +
+	"2 DATE 3 MAR 1970\r\n" +
+	"1 NOTE 一生务农，\r\n" +
+	"2 CONC 乐善好施。\r\n" +
+	"2 CONT 邮箱 zhang@@example.com\r\n" +
+	"1 FAMS @F1@\r\n" +
+	"0 @I2@ INDI\r\n" +
+	"1 NAME 淑贞 /李/\r\n" +
+	"1 SEX F\r\n" +
+	"1 FAMS @F1@\r\n" +
+	"0 @I3@ INDI\r\n" +
+	"1 NAME /张/明\r\n" +
+	"1 SEX M\r\n" +
+	"1 BIRT\r\n" +
+	"2 DATE 12 JUN 1930\r\n" +
+	"1 OCCU 教师\r\n" +
+	"1 NOTE @N1@\r\n" +
+	"1 FAMC @F1@\r\n" +
+	"1 _MEMORIAL memorial-1\r\n" +
+	"0 @I4@ INDI\r\n" +
+	"1 NAME John /Smith/\r\n" +
+	"1 BIRT\r\n" +
+	"2 DATE @#DHEBREW@ 1 TSH 5700\r\n" +
+	"0 @F1@ FAM\r\n" +
+	"1 HUSB @I1@\r\n" +
+	"1 WIFE @I2@\r\n" +
+	"1 MARR\r\n" +
+	"2 DATE 1925\r\n" +
+	"1 CHIL @I3@\r\n" +
+	"0 @N1@ NOTE 主要成就：培养了三代学生\r\n" +
+	"0 TRLR\r\n"
+
+func TestDecodeGedcom551(t *testing.T) {
+	doc, err := DecodeGedcom([]byte(sampleGedcom551))
+	require.NoError(t, err)
+	assert.Equal(t, GedcomVersion551, doc.Version)
+	require.Len(t, doc.Persons, 4)
+	require.Len(t, doc.Families, 1)
+
+	father := doc.Persons[0]
+	assert.Equal(t, "张德厚", father.Name)
+	assert.Equal(t, "male", father.Gender)
+	require.NotNil(t, father.BirthDate)
+	assert.Equal(t, 1900, father.BirthDate.Year())
+	require.NotNil(t, father.DeathDate)
+	assert.Equal(t, "1970-03-03", father.DeathDate.Format("2006-01-02"))
+	assert.Equal(t, "一生务农，乐善好施。\n邮箱 zhang@example.com", father.Biography)
+	assert.Contains(t, father.Warnings, "出生日期不精确: ABT 1900")
+
+	assert.Equal(t, "李淑贞", doc.Persons[1].Name)
+
+	son := doc.Persons[2]
+	assert.Equal(t, "教师", son.Occupation)
+	assert.Equal(t, "培养了三代学生", son.Achievements)
+	assert.Equal(t, "", son.Biography)
+	assert.Equal(t, "memorial-1", son.MemorialID)
+	assert.Equal(t, []string{"@F1@"}, son.ParentFams)
+
+	other := doc.Persons[3]
+	assert.Equal(t, "John Smith", other.Name)
+	assert.Nil(t, other.BirthDate)
+	assert.Contains(t, other.Warnings, "性别未知")
+	assert.Contains(t, other.Warnings, "无法识别的出生日期: @#DHEBREW@ 1 TSH 5700")
+
+	family := doc.Families[0]
+	assert.Equal(t, "@I1@", family.Husband)
+	assert.Equal(t, "@I2@", family.Wife)
+	assert.Equal(t, []string{"@I3@"}, family.Children)
+	require.NotNil(t, family.MarriageDate)
+	assert.Equal(t, 1925, family.MarriageDate.Year())
+}
+
+func TestDecodeGedcomErrors(t *testing.T) {
+	_, err := DecodeGedcom([]byte("hello world"))
+	assert.Error(t, err)
+
+	_, err = DecodeGedcom([]byte("0 HEAD\n1 CHAR ANSEL\n0 @I1@ INDI\n0 TRLR\n"))
+	assert.EqualError(t, err, "仅支持 UTF-8 编码的 GEDCOM 文件")
+
+	_, err = DecodeGedcom([]byte("0 HEAD\n2 VERS 5.5.1\n"))
+	assert.EqualError(t, err, "GEDCOM 第 2 行层级错误")
+
+	_, err = DecodeGedcom([]byte("0 HEAD\n0 TRLR\n"))
+	assert.EqualError(t, err, "GEDCOM 文件中没有个人记录")
+}
+
+func TestParseGedcomDate(t *testing.T) {
+	cases := []struct {
+		value string
+		want  string
+		exact bool
+	}{
+		{"2 JAN 1930", "1930-01-02", true},
+		{"jan 1930", "1930-01-01", false},
+		{"1930", "1930-01-01", false},
+		{"ABT 12 MAR 1901", "1901-03-12", false},
+		{"BET 1900 AND 1910", "1900-01-01", false},
+		{"FROM 1 JUN 1940 TO 1945", "1940-06-01", false},
+		{"@#DGREGORIAN@ 5 MAY 1950", "1950-05-05", true},
+		{"GREGORIAN 5 MAY 1950", "1950-05-05", true},
+		{"@#DJULIAN@ 1 FEB 1700", "1700-02-01", false},
+		{"11 FEB 1750/51", "1750-02-11", true},
+	}
+	for _, c := range cases {
+		date, exact := ParseGedcomDate(c.value)
+		require.NotNil(t, date, c.value)
+		assert.Equal(t, c.want, date.Format("2006-01-02"), c.value)
+		assert.Equal(t, c.exact, exact, c.value)
+	}
+
+	for _, value := range []string{"", "(在抗战期间)", "31 FEB 1900", "100 BC", "HEBREW 1 TSH 5700", "spring 1900"} {
+		date, _ := ParseGedcomDate(value)
+		assert.Nil(t, date, value)
+	}
+
+	assert.Equal(t, "2 JAN 1930", FormatGedcomDate(time.Date(1930, 1, 2, 0, 0, 0, 0, time.Local)))
+}
+
+func TestGedcomNames(t *testing.T) {
+	assert.Equal(t, "张三", ParseGedcomName("/张/三"))
+	assert.Equal(t, "张三", ParseGedcomName("三 /张/"))
+	assert.Equal(t, "John Smith Jr.", ParseGedcomName("John /Smith/ Jr."))
+	assert.Equal(t, "Prince", ParseGedcomName("Prince"))
+
+	value, surname, given := FormatGedcomName("张三")
+	assert.Equal(t, "/张/三", value)
+	assert.Equal(t, "张", surname)
+	assert.Equal(t, "三", given)
+
+	value, surname, given = FormatGedcomName("欧阳修文")
+	assert.Equal(t, "/欧阳/修文", value)
+	assert.Equal(t, "欧阳", surname)
+	assert.Equal(t, "修文", given)
+
+	value, surname, _ = FormatGedcomName("John Smith")
+	assert.Equal(t, "John /Smith/", value)
+	assert.Equal(t, "Smith", surname)
+
+	value, surname, _ = FormatGedcomName("佚名")
+	assert.Equal(t, "/佚/名", value)
+	assert.Equal(t, "佚", surname)
+}
+
+func TestGedcomParentsAndGenerations(t *testing.T) {
+	doc := &GedcomDocument{
+		Persons: []*GedcomPerson{
+			{Xref: "@A@"}, {Xref: "@B@"}, {Xref: "@C@", ParentFams: []string{"@F1@"}},
+			{Xref: "@D@"}, {Xref: "@E@"}, {Xref: "@X@"},
+		},
+		Families: []*GedcomFamily{
+			{Xref: "@F1@", Husband: "@A@", Wife: "@B@", Children: []string{"@C@"}},
+			// 母亲一方的家庭，只有 CHIL 没有 FAMC
+			{Xref: "@F2@", Wife: "@D@", Children: []string{"@B@"}},
+			// 儿媳
+			{Xref: "@F3@", Husband: "@C@", Wife: "@E@"},
+		},
+	}
+
+	links := GedcomParentLinks(doc)
+	assert.Equal(t, []GedcomParentLink{
+		{Parent: "@A@", Child: "@C@", ParentType: GenealogyParentBiological},
+		{Parent: "@B@", Child: "@C@", ParentType: GenealogyParentBiological},
+		{Parent: "@D@", Child: "@B@", ParentType: GenealogyParentBiological},
+	}, links)
+
+	generation, group := GedcomGenerations(doc)
+	assert.Equal(t, 0, generation["@D@"])
+	assert.Equal(t, 1, generation["@A@"])
+	assert.Equal(t, 1, generation["@B@"])
+	assert.Equal(t, 2, generation["@C@"])
+	assert.Equal(t, 2, generation["@E@"])
+	assert.Equal(t, group["@A@"], group["@E@"])
+	assert.Equal(t, 0, generation["@X@"])
+	assert.NotEqual(t, group["@A@"], group["@X@"])
+}
+
+func TestGedcomParentCycle(t *testing.T) {
+	doc := &GedcomDocument{
+		Persons: []*GedcomPerson{{Xref: "@A@"}, {Xref: "@B@"}},
+		Families: []*GedcomFamily{
+			{Xref: "@F1@", Husband: "@A@", Children: []string{"@B@"}},
+			{Xref: "@F2@", Husband: "@B@", Children: []string{"@A@"}},
+		},
+	}
+	links := GedcomParentLinks(doc)
+	assert.Len(t, links, 1)
+}
+
+func TestGedcomPedigree(t *testing.T) {
+	data := "0 HEAD\n1 GEDC\n2 VERS 7.0\n" +
+		"0 @F@ INDI\n1 SEX M\n1 FAMS @F1@\n" +
+		"0 @U@ INDI\n1 SEX M\n1 FAMS @F2@\n" +
+		"0 @C@ INDI\n1 FAMC @F2@\n2 PEDI ADOPTED\n1 FAMC @F1@\n" +
+		"0 @F1@ FAM\n1 HUSB @F@\n1 CHIL @C@\n" +
+		"0 @F2@ FAM\n1 HUSB @U@\n1 CHIL @C@\n" +
+		"0 TRLR\n"
+	doc, err := DecodeGedcom([]byte(data))
+	require.NoError(t, err)
+
+	// 亲生家庭排在前面
+	assert.Equal(t, []string{"@F1@", "@F2@"}, doc.Persons[2].ParentFams)
+	assert.Equal(t, []GedcomParentLink{
+		{Parent: "@F@", Child: "@C@", ParentType: GenealogyParentBiological},
+		{Parent: "@U@", Child: "@C@", ParentType: GenealogyParentAdopted},
+	}, GedcomParentLinks(doc))
+
+	encoded := string(EncodeGedcom(doc, GedcomVersion551, "TEST"))
+	assert.Contains(t, encoded, "1 FAMC @F2@\n2 PEDI adopted\n")
+}
+
+func TestFindGenealogyDuplicate(t *testing.T) {
+	existing := []GenealogyPersonKey{
+		{ID: "g1", Name: "张三", Gender: "male", BirthYear: 1930},
+		{ID: "g2", Name: "李 四", Gender: "female"},
+		{ID: "g3", Name: "王五", Gender: "male", BirthYear: 1950},
+	}
+
+	id, match := FindGenealogyDuplicate(GenealogyPersonKey{Name: "张三", Gender: "male", BirthYear: 1930}, "", existing)
+	assert.Equal(t, "g1", id)
+	assert.Equal(t, GenealogyMatchExact, match)
+
+	// 出生年份不同不算重复
+	id, _ = FindGenealogyDuplicate(GenealogyPersonKey{Name: "张三", BirthYear: 1931}, "", existing)
+	assert.Equal(t, "", id)
+
+	// 缺少出生年份时为可能重复，姓名忽略空格
+	id, match = FindGenealogyDuplicate(GenealogyPersonKey{Name: "李四", BirthYear: 1940}, "", existing)
+	assert.Equal(t, "g2", id)
+	assert.Equal(t, GenealogyMatchPossible, match)
+
+	// 性别冲突
+	id, _ = FindGenealogyDuplicate(GenealogyPersonKey{Name: "王五", Gender: "female", BirthYear: 1950}, "", existing)
+	assert.Equal(t, "", id)
+
+	// 本系统导出的ID优先
+	id, match = FindGenealogyDuplicate(GenealogyPersonKey{Name: "改过的名字"}, "g3", existing)
+	assert.Equal(t, "g3", id)
+	assert.Equal(t, GenealogyMatchExact, match)
+}
+
+func TestEncodeGedcomRoundTrip(t *testing.T) {
+	birth := time.Date(1930, 6, 12, 0, 0, 0, 0, time.Local)
+	longBio := strings.Repeat("一生勤劳朴实，", 40)
+	doc := &GedcomDocument{
+		Persons: []*GedcomPerson{
+			{Xref: "@I1@", Name: "张德厚", Gender: "male", SpouseFams: []string{"@F1@"}, RefID: "g1"},
+			{
+				Xref: "@I2@", Name: "张明", Gender: "male", BirthDate: &birth,
+				Biography: longBio + "\n联系 a@b.com", Achievements: "桃李满天下", Occupation: "教师",
+				AvatarURL: "https://cdn.example.com/a.png", MemorialID: "m1", ParentFams: []string{"@F1@"}, RefID: "g2",
+			},
+		},
+		Families: []*GedcomFamily{{Xref: "@F1@", Husband: "@I1@", Children: []string{"@I2@"}}},
+	}
+
+	for _, version := range []string{GedcomVersion551, GedcomVersion7} {
+		data := EncodeGedcom(doc, version, "TEST")
+		text := string(data)
+		assert.True(t, strings.HasPrefix(text, "0 HEAD\n"), version)
+		assert.True(t, strings.HasSuffix(text, "0 TRLR\n"), version)
+		// 7.0 取消了行长度限制
+		if version == GedcomVersion551 {
+			for _, line := range strings.Split(text, "\n") {
+				assert.LessOrEqual(t, len(line), 255)
+			}
+		}
+
+		decoded, err := DecodeGedcom(data)
+		require.NoError(t, err, version)
+		assert.Equal(t, version, decoded.Version)
+		require.Len(t, decoded.Persons, 2)
+
+		son := decoded.Persons[1]
+		assert.Equal(t, "张明", son.Name)
+		assert.Equal(t, "male", son.Gender)
+		require.NotNil(t, son.BirthDate)
+		assert.True(t, birth.Equal(*son.BirthDate))
+		assert.Nil(t, son.DeathDate)
+		assert.Equal(t, longBio+"\n联系 a@b.com", son.Biography, version)
+		assert.Equal(t, "桃李满天下", son.Achievements)
+		assert.Equal(t, "教师", son.Occupation)
+		assert.Equal(t, "https://cdn.example.com/a.png", son.AvatarURL)
+		assert.Equal(t, "m1", son.MemorialID)
+		assert.Equal(t, "g2", son.RefID)
+		assert.Equal(t, []GedcomParentLink{{Parent: "@I1@", Child: "@I2@", ParentType: GenealogyParentBiological}}, GedcomParentLinks(decoded))
+	}
+
+	assert.Contains(t, string(EncodeGedcom(doc, GedcomVersion551, "TEST")), "a@@b.com")
+	assert.Contains(t, string(EncodeGedcom(doc, GedcomVersion7, "TEST")), "0 @O1@ OBJE")
+}
+
+func TestEncodeGedcomSubmitter(t *testing.T) {
+	doc := &GedcomDocument{Submitter: "张氏家族"}
+	records, err := ParseGedcom(EncodeGedcom(doc, GedcomVersion551, "TEST"))
+	require.NoError(t, err)
+	require.NotEmpty(t, records)
+
+	// 5.5.1 的 HEAD.SUBM 必须指向存在的提交者记录
+	head := records[0]
+	require.Equal(t, "HEAD", head.Tag)
+	pointer := head.ChildValue("SUBM")
+	require.NotEmpty(t, pointer)
+	var submitter *GedcomNode
+	for _, r := range records {
+		if r.Xref == pointer {
+			submitter = r
+		}
+	}
+	require.NotNil(t, submitter, pointer)
+	assert.Equal(t, "SUBM", submitter.Tag)
+	assert.Equal(t, "张氏家族", submitter.ChildValue("NAME"))
+
+	// 未指定提交者时使用来源标识
+	records, err = ParseGedcom(EncodeGedcom(&GedcomDocument{}, GedcomVersion551, "TEST"))
+	require.NoError(t, err)
+	assert.Equal(t, "TEST", records[1].ChildValue("NAME"))
+
+	// 7.0 中提交者可选
+	records, err = ParseGedcom(EncodeGedcom(doc, GedcomVersion7, "TEST"))
+	require.NoError(t, err)
+	assert.Equal(t, "", records[0].ChildValue("SUBM"))
+}
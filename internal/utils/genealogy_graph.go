@@ -0,0 +1,315 @@
+package utils
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// 谱系关系类型
+const (
+	GenealogyRelationParent = "parent" // 父母与子女，From 为父母，To 为子女
+	GenealogyRelationSpouse = "spouse" // 配偶
+)
+
+// 父母关系类型
+const (
+	GenealogyParentBiological = "biological" // 亲生
+	GenealogyParentAdopted    = "adopted"    // 收养或过继
+	GenealogyParentStep       = "step"       // 继父母
+)
+
+// GenealogyMaxBiologicalParents 每人最多的亲生父母数
+const GenealogyMaxBiologicalParents = 2
+
+// IsValidGenealogyParentType 检查是否为有效的父母关系类型
+func IsValidGenealogyParentType(parentType string) bool {
+	switch parentType {
+	case GenealogyParentBiological, GenealogyParentAdopted, GenealogyParentStep:
+		return true
+	}
+	return false
+}
+
+// GenealogyEdge 谱系图中的一条关系
+type GenealogyEdge struct {
+	ID         string
+	Type       string
+	FromID     string
+	ToID       string
+	ParentType string
+}
+
+// GenealogyGraph 由父母子女和配偶关系组成的谱系图
+type GenealogyGraph struct {
+	parents  map[string][]GenealogyEdge // 子女 -> 指向父母的关系
+	children map[string][]GenealogyEdge // 父母 -> 指向子女的关系
+	spouses  map[string][]GenealogyEdge
+}
+
+// NewGenealogyGraph 根据关系列表构建谱系图
+func NewGenealogyGraph(edges []GenealogyEdge) *GenealogyGraph {
+	g := &GenealogyGraph{
+		parents:  map[string][]GenealogyEdge{},
+		children: map[string][]GenealogyEdge{},
+		spouses:  map[string][]GenealogyEdge{},
+	}
+	for _, e := range edges {
+		g.Add(e)
+	}
+	for _, m := range []map[string][]GenealogyEdge{g.parents, g.children, g.spouses} {
+		for id := range m {
+			sortGenealogyEdges(m[id])
+		}
+	}
+	return g
+}
+
+// Add 加入一条关系
+func (g *GenealogyGraph) Add(e GenealogyEdge) {
+	if e.FromID == "" || e.ToID == "" || e.FromID == e.ToID {
+		return
+	}
+	switch e.Type {
+	case GenealogyRelationParent:
+		g.parents[e.ToID] = append(g.parents[e.ToID], e)
+		g.children[e.FromID] = append(g.children[e.FromID], e)
+	case GenealogyRelationSpouse:
+		g.spouses[e.FromID] = append(g.spouses[e.FromID], e)
+		g.spouses[e.ToID] = append(g.spouses[e.ToID], e)
+	}
+}
+
+// 亲生关系优先，其次按ID排序，保证遍历结果稳定
+func sortGenealogyEdges(edges []GenealogyEdge) {
+	rank := func(e GenealogyEdge) int {
+		switch e.ParentType {
+		case GenealogyParentBiological, "":
+			return 0
+		case GenealogyParentAdopted:
+			return 1
+		default:
+			return 2
+		}
+	}
+	sort.SliceStable(edges, func(i, j int) bool {
+		if ri, rj := rank(edges[i]), rank(edges[j]); ri != rj {
+			return ri < rj
+		}
+		if edges[i].FromID != edges[j].FromID {
+			return edges[i].FromID < edges[j].FromID
+		}
+		return edges[i].ToID < edges[j].ToID
+	})
+}
+
+// Parents 返回某人的父母关系
+func (g *GenealogyGraph) Parents(id string) []GenealogyEdge {
+	return g.parents[id]
+}
+
+// Children 返回某人的子女关系
+func (g *GenealogyGraph) Children(id string) []GenealogyEdge {
+	return g.children[id]
+}
+
+// Spouses 返回某人的配偶关系
+func (g *GenealogyGraph) Spouses(id string) []GenealogyEdge {
+	return g.spouses[id]
+}
+
+// BiologicalParentCount 返回某人已有的亲生父母数
+func (g *GenealogyGraph) BiologicalParentCount(id string) int {
+	count := 0
+	for _, e := range g.parents[id] {
+		if e.ParentType == GenealogyParentBiological || e.ParentType == "" {
+			count++
+		}
+	}
+	return count
+}
+
+// GenealogyKin 祖先或后代遍历结果中的一个人
+type GenealogyKin struct {
+	ID         string
+	Depth      int    // 相隔代数，父母或子女为 1
+	ViaID      string // 经由的上一个人（离起点近一代）
+	ParentType string // 与 ViaID 之间的父母关系类型
+}
+
+// Ancestors 按代数由近及远返回祖先，maxDepth 为 0 表示不限
+func (g *GenealogyGraph) Ancestors(id string, maxDepth int) []GenealogyKin {
+	return g.walk(id, maxDepth, func(cur string) []GenealogyEdge { return g.parents[cur] }, func(e GenealogyEdge) string { return e.FromID })
+}
+
+// Descendants 按代数由近及远返回后代，maxDepth 为 0 表示不限
+func (g *GenealogyGraph) Descendants(id string, maxDepth int) []GenealogyKin {
+	return g.walk(id, maxDepth, func(cur string) []GenealogyEdge { return g.children[cur] }, func(e GenealogyEdge) string { return e.ToID })
+}
+
+func (g *GenealogyGraph) walk(id string, maxDepth int, next func(string) []GenealogyEdge, other func(GenealogyEdge) string) []GenealogyKin {
+	seen := map[string]bool{id: true}
+	var result []GenealogyKin
+	level := []string{id}
+	for depth := 1; len(level) > 0 && (maxDepth <= 0 || depth <= maxDepth); depth++ {
+		var nextLevel []string
+		for _, cur := range level {
+			for _, e := range next(cur) {
+				to := other(e)
+				if seen[to] {
+					continue
+				}
+				seen[to] = true
+				result = append(result, GenealogyKin{ID: to, Depth: depth, ViaID: cur, ParentType: e.ParentType})
+				nextLevel = append(nextLevel, to)
+			}
+		}
+		level = nextLevel
+	}
+	return result
+}
+
+// IsAncestor 判断 ancestorID 是否为 id 的祖先
+func (g *GenealogyGraph) IsAncestor(ancestorID, id string) bool {
+	for _, kin := range g.Ancestors(id, 0) {
+		if kin.ID == ancestorID {
+			return true
+		}
+	}
+	return false
+}
+
+// 路径中每一步的关系：当前这个人是上一个人的……
+const (
+	GenealogyStepParent = "parent" // 父母
+	GenealogyStepChild  = "child"  // 子女
+	GenealogyStepSpouse = "spouse" // 配偶
+)
+
+// GenealogyStep 两人之间关系路径上的一步
+type GenealogyStep struct {
+	ID         string
+	Relation   string // 起点为空
+	ParentType string
+}
+
+// Path 返回两人之间最短的关系路径（含起点和终点），没有关系时返回 false
+//
+// 路径长度相同时优先经由亲生关系和婚姻关系，其次才是收养、过继和继父母关系。
+func (g *GenealogyGraph) Path(fromID, toID string) ([]GenealogyStep, bool) {
+	if fromID == toID {
+		return []GenealogyStep{{ID: fromID}}, true
+	}
+	prev, prevID, done := g.shortestPaths(fromID, toID)
+	if !done[toID] {
+		return nil, false
+	}
+	return genealogyPathTo(fromID, toID, prev, prevID), true
+}
+
+// PathsFrom 返回从 fromID 到每个有亲属关系的人的最短关系路径，含 fromID 本人
+func (g *GenealogyGraph) PathsFrom(fromID string) map[string][]GenealogyStep {
+	prev, prevID, done := g.shortestPaths(fromID, "")
+	paths := make(map[string][]GenealogyStep, len(done))
+	for id := range done {
+		paths[id] = genealogyPathTo(fromID, id, prev, prevID)
+	}
+	return paths
+}
+
+// shortestPaths 从 fromID 出发计算最短路径，toID 不为空时到达即停止
+func (g *GenealogyGraph) shortestPaths(fromID, toID string) (map[string]GenealogyStep, map[string]string, map[string]bool) {
+	// 每一步代价为 genealogyStepCost，非亲生的父母子女关系额外加 1；代价相同时按发现顺序
+	cost := map[string]int{fromID: 0}
+	prev := map[string]GenealogyStep{}
+	prevID := map[string]string{}
+	done := map[string]bool{}
+	pq := &genealogyQueue{}
+	heap.Push(pq, genealogyQueueItem{id: fromID})
+
+	for pq.Len() > 0 {
+		item := heap.Pop(pq).(genealogyQueueItem)
+		cur := item.id
+		if done[cur] {
+			continue
+		}
+		done[cur] = true
+		if cur == toID {
+			break
+		}
+
+		for _, step := range g.neighbors(cur) {
+			c := item.cost + genealogyStepCost
+			if step.Relation != GenealogyStepSpouse && step.ParentType != GenealogyParentBiological && step.ParentType != "" {
+				c++
+			}
+			if old, ok := cost[step.ID]; ok && old <= c {
+				continue
+			}
+			cost[step.ID] = c
+			prev[step.ID] = step
+			prevID[step.ID] = cur
+			pq.seq++
+			heap.Push(pq, genealogyQueueItem{id: step.ID, cost: c, seq: pq.seq})
+		}
+	}
+	return prev, prevID, done
+}
+
+func genealogyPathTo(fromID, toID string, prev map[string]GenealogyStep, prevID map[string]string) []GenealogyStep {
+	var path []GenealogyStep
+	for id := toID; id != fromID; id = prevID[id] {
+		path = append(path, prev[id])
+	}
+	path = append(path, GenealogyStep{ID: fromID})
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// 每一步的基础代价，须大于非亲生关系的额外代价累计，保证路径长度优先
+const genealogyStepCost = 100
+
+func (g *GenealogyGraph) neighbors(id string) []GenealogyStep {
+	var steps []GenealogyStep
+	for _, e := range g.parents[id] {
+		steps = append(steps, GenealogyStep{ID: e.FromID, Relation: GenealogyStepParent, ParentType: e.ParentType})
+	}
+	for _, e := range g.children[id] {
+		steps = append(steps, GenealogyStep{ID: e.ToID, Relation: GenealogyStepChild, ParentType: e.ParentType})
+	}
+	for _, e := range g.spouses[id] {
+		other := e.ToID
+		if other == id {
+			other = e.FromID
+		}
+		steps = append(steps, GenealogyStep{ID: other, Relation: GenealogyStepSpouse})
+	}
+	return steps
+}
+
+type genealogyQueueItem struct {
+	id   string
+	cost int
+	seq  int
+}
+
+type genealogyQueue struct {
+	items []genealogyQueueItem
+	seq   int
+}
+
+func (q *genealogyQueue) Len() int { return len(q.items) }
+func (q *genealogyQueue) Less(i, j int) bool {
+	if q.items[i].cost != q.items[j].cost {
+		return q.items[i].cost < q.items[j].cost
+	}
+	return q.items[i].seq < q.items[j].seq
+}
+func (q *genealogyQueue) Swap(i, j int)      { q.items[i], q.items[j] = q.items[j], q.items[i] }
+func (q *genealogyQueue) Push(x interface{}) { q.items = append(q.items, x.(genealogyQueueItem)) }
+func (q *genealogyQueue) Pop() interface{} {
+	item := q.items[len(q.items)-1]
+	q.items = q.items[:len(q.items)-1]
+	return item
+}
@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 祖父 gf、祖母 gm；父亲 f 与母亲 m；子女 c1、c2；c2 过继给伯父 u；c1 的继母 sm
+func sampleGenealogyGraph() *GenealogyGraph {
+	parent := func(from, to, parentType string) GenealogyEdge {
+		return GenealogyEdge{Type: GenealogyRelationParent, FromID: from, ToID: to, ParentType: parentType}
+	}
+	spouse := func(a, b string) GenealogyEdge {
+		return GenealogyEdge{Type: GenealogyRelationSpouse, FromID: a, ToID: b}
+	}
+	return NewGenealogyGraph([]GenealogyEdge{
+		spouse("gf", "gm"),
+		parent("gf", "f", GenealogyParentBiological),
+		parent("gm", "f", GenealogyParentBiological),
+		parent("gf", "u", GenealogyParentBiological),
+		spouse("f", "m"),
+		spouse("f", "sm"),
+		parent("f", "c1", GenealogyParentBiological),
+		parent("m", "c1", GenealogyParentBiological),
+		parent("sm", "c1", GenealogyParentStep),
+		parent("f", "c2", GenealogyParentBiological),
+		parent("u", "c2", GenealogyParentAdopted),
+	})
+}
+
+func TestGenealogyGraphAncestors(t *testing.T) {
+	g := sampleGenealogyGraph()
+
+	ancestors := g.Ancestors("c1", 0)
+	require.Len(t, ancestors, 5)
+	assert.Equal(t, GenealogyKin{ID: "f", Depth: 1, ViaID: "c1", ParentType: GenealogyParentBiological}, ancestors[0])
+	assert.Equal(t, GenealogyKin{ID: "m", Depth: 1, ViaID: "c1", ParentType: GenealogyParentBiological}, ancestors[1])
+	assert.Equal(t, GenealogyKin{ID: "sm", Depth: 1, ViaID: "c1", ParentType: GenealogyParentStep}, ancestors[2])
+	assert.Equal(t, 2, ancestors[3].Depth)
+
+	assert.Len(t, g.Ancestors("c1", 1), 3)
+	assert.Equal(t, 2, g.BiologicalParentCount("c1"))
+	assert.True(t, g.IsAncestor("gf", "c2"))
+	assert.False(t, g.IsAncestor("c2", "gf"))
+}
+
+func TestGenealogyGraphDescendants(t *testing.T) {
+	g := sampleGenealogyGraph()
+
+	descendants := g.Descendants("gf", 0)
+	ids := map[string]int{}
+	for _, kin := range descendants {
+		ids[kin.ID] = kin.Depth
+	}
+	assert.Equal(t, map[string]int{"f": 1, "u": 1, "c1": 2, "c2": 2}, ids)
+
+	adopted := g.Descendants("u", 0)
+	require.Len(t, adopted, 1)
+	assert.Equal(t, GenealogyParentAdopted, adopted[0].ParentType)
+}
+
+func TestGenealogyGraphPath(t *testing.T) {
+	g := sampleGenealogyGraph()
+
+	path, ok := g.Path("c1", "u")
+	require.True(t, ok)
+	var ids, relations []string
+	for _, step := range path {
+		ids = append(ids, step.ID)
+		relations = append(relations, step.Relation)
+	}
+	assert.Equal(t, []string{"c1", "f", "gf", "u"}, ids)
+	assert.Equal(t, []string{"", GenealogyStepParent, GenealogyStepParent, GenealogyStepChild}, relations)
+
+	path, ok = g.Path("m", "sm")
+	require.True(t, ok)
+	assert.Len(t, path, 3)
+	assert.Equal(t, GenealogyStepSpouse, path[1].Relation)
+
+	path, ok = g.Path("c1", "c1")
+	assert.True(t, ok)
+	assert.Len(t, path, 1)
+
+	_, ok = g.Path("c1", "stranger")
+	assert.False(t, ok)
+}
+
+func TestGenealogyGraphIgnoresSelfLoops(t *testing.T) {
+	g := NewGenealogyGraph([]GenealogyEdge{{Type: GenealogyRelationParent, FromID: "a", ToID: "a"}})
+	assert.Empty(t, g.Parents("a"))
+	assert.True(t, IsValidGenealogyParentType(GenealogyParentStep))
+	assert.False(t, IsValidGenealogyParentType("foster"))
+}
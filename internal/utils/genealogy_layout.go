@@ -0,0 +1,393 @@
+package utils
+
+import (
+	"sort"
+	"time"
+)
+
+// 谱系图样式
+const (
+	GenealogyStyleModern      = "modern"      // 现代卡片式，带头像
+	GenealogyStyleTraditional = "traditional" // 传统吊线图，竖排姓名
+)
+
+// GenealogyLayoutPerson 参与布局的谱系成员
+type GenealogyLayoutPerson struct {
+	ID         string
+	Name       string
+	Gender     string
+	Generation int
+	BirthDate  *time.Time
+	DeathDate  *time.Time
+	AvatarURL  string
+}
+
+// GenealogyLayoutOptions 布局参数，尺寸单位为像素
+type GenealogyLayoutOptions struct {
+	RootID    string          // 只绘制此人所在的一支，为空时绘制全部
+	Collapsed map[string]bool // 折叠这些人的后代
+	MaxDepth  int             // 从最上一代起最多绘制的代数，0 表示不限
+
+	NodeWidth  float64
+	NodeHeight float64
+	SpouseGap  float64 // 夫妻之间的间距
+	SiblingGap float64 // 相邻两支之间的最小间距
+	LevelGap   float64 // 上下两代之间的间距
+	Padding    float64
+}
+
+// NewGenealogyLayoutOptions 返回样式对应的默认尺寸
+func NewGenealogyLayoutOptions(style string) GenealogyLayoutOptions {
+	if style == GenealogyStyleTraditional {
+		return GenealogyLayoutOptions{NodeWidth: 44, NodeHeight: 156, SpouseGap: 14, SiblingGap: 20, LevelGap: 48, Padding: 40}
+	}
+	return GenealogyLayoutOptions{NodeWidth: 150, NodeHeight: 64, SpouseGap: 16, SiblingGap: 28, LevelGap: 56, Padding: 40}
+}
+
+// GenealogyLayoutNode 一个人在图中的位置（左上角）
+type GenealogyLayoutNode struct {
+	Person GenealogyLayoutPerson
+	X, Y   float64
+}
+
+// GenealogyLayoutUnit 图中的一个家庭：本支成员及其配偶，子女挂在其下
+type GenealogyLayoutUnit struct {
+	Nodes       []*GenealogyLayoutNode // 第一位为本支成员，其后为配偶
+	Level       int                    // 所在行，0 为最上一行
+	ParentType  string                 // 本支成员与上一代的父母关系
+	Parent      *GenealogyLayoutUnit
+	Children    []*GenealogyLayoutUnit
+	HiddenCount int // 因折叠或代数限制未绘制的后代人数
+
+	x     float64 // 相对上级家庭中心的偏移，布局后为绝对中心
+	width float64
+}
+
+// CenterX 家庭的水平中心
+func (u *GenealogyLayoutUnit) CenterX() float64 {
+	return u.x
+}
+
+// GenealogyLayout 布局结果
+type GenealogyLayout struct {
+	Width         float64
+	Height        float64
+	Levels        int
+	MinGeneration int // 第 0 行对应的辈分
+	Units         []*GenealogyLayoutUnit
+	Options       GenealogyLayoutOptions
+}
+
+// LayoutGenealogy 将谱系排成按辈分分行的整齐树形
+//
+// 每人挂在一位父母（优先亲生父亲）之下，无父母记录的配偶与本支成员并排；
+// 各支按轮廓紧凑排列，上一代居中于子女之上。
+func LayoutGenealogy(persons []GenealogyLayoutPerson, graph *GenealogyGraph, opts GenealogyLayoutOptions) *GenealogyLayout {
+	byID := make(map[string]*GenealogyLayoutPerson, len(persons))
+	sorted := make([]*GenealogyLayoutPerson, 0, len(persons))
+	for i := range persons {
+		byID[persons[i].ID] = &persons[i]
+		sorted = append(sorted, &persons[i])
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return layoutPersonLess(sorted[i], sorted[j]) })
+
+	// 挂靠的父母：优先亲生，其次父亲
+	primary := map[string]GenealogyEdge{}
+	for _, p := range sorted {
+		var best *GenealogyEdge
+		for _, e := range graph.Parents(p.ID) {
+			parent := byID[e.FromID]
+			if parent == nil {
+				continue
+			}
+			e := e
+			if best == nil || layoutParentRank(e, parent) < layoutParentRank(*best, byID[best.FromID]) {
+				best = &e
+			}
+		}
+		if best != nil {
+			primary[p.ID] = *best
+		}
+	}
+
+	unitOf := map[string]*GenealogyLayoutUnit{}
+	var units []*GenealogyLayoutUnit
+	newUnit := func(p *GenealogyLayoutPerson) *GenealogyLayoutUnit {
+		u := &GenealogyLayoutUnit{Nodes: []*GenealogyLayoutNode{{Person: *p}}}
+		unitOf[p.ID] = u
+		units = append(units, u)
+		return u
+	}
+	for _, p := range sorted {
+		if _, ok := primary[p.ID]; ok {
+			newUnit(p)
+		}
+	}
+	// 无父母记录的人：随配偶并排，否则自成一支（男方优先作为本支）
+	var rootless []*GenealogyLayoutPerson
+	for _, p := range sorted {
+		if _, ok := primary[p.ID]; !ok {
+			rootless = append(rootless, p)
+		}
+	}
+	sort.SliceStable(rootless, func(i, j int) bool {
+		if rootless[i].Generation != rootless[j].Generation {
+			return rootless[i].Generation < rootless[j].Generation
+		}
+		return rootless[i].Gender == "male" && rootless[j].Gender != "male"
+	})
+	for _, p := range rootless {
+		if unitOf[p.ID] != nil {
+			continue
+		}
+		var host *GenealogyLayoutUnit
+		for _, e := range graph.Spouses(p.ID) {
+			other := e.ToID
+			if other == p.ID {
+				other = e.FromID
+			}
+			if u := unitOf[other]; u != nil && u.Nodes[0].Person.ID == other {
+				host = u
+				break
+			}
+		}
+		if host != nil {
+			host.Nodes = append(host.Nodes, &GenealogyLayoutNode{Person: *p})
+			unitOf[p.ID] = host
+			continue
+		}
+		newUnit(p)
+	}
+
+	// 挂到父母所在的家庭下；数据有误成环时作为单独一支
+	for _, u := range units {
+		e, ok := primary[u.Nodes[0].Person.ID]
+		if !ok {
+			continue
+		}
+		parent := unitOf[e.FromID]
+		cycle := false
+		for cur := parent; cur != nil; cur = cur.Parent {
+			if cur == u {
+				cycle = true
+				break
+			}
+		}
+		if cycle {
+			continue
+		}
+		u.Parent = parent
+		u.ParentType = e.ParentType
+		parent.Children = append(parent.Children, u)
+	}
+	for _, u := range units {
+		sort.SliceStable(u.Children, func(i, j int) bool {
+			return layoutPersonLess(&u.Children[i].Nodes[0].Person, &u.Children[j].Nodes[0].Person)
+		})
+	}
+
+	var roots []*GenealogyLayoutUnit
+	if opts.RootID != "" {
+		if u := unitOf[opts.RootID]; u != nil {
+			roots = append(roots, u)
+		}
+	} else {
+		for _, u := range units {
+			if u.Parent == nil {
+				roots = append(roots, u)
+			}
+		}
+	}
+
+	layout := &GenealogyLayout{Options: opts}
+	if len(roots) == 0 {
+		layout.Width = 2 * opts.Padding
+		layout.Height = 2 * opts.Padding
+		return layout
+	}
+
+	layout.MinGeneration = roots[0].Nodes[0].Person.Generation
+	for _, r := range roots {
+		if g := r.Nodes[0].Person.Generation; g < layout.MinGeneration {
+			layout.MinGeneration = g
+		}
+	}
+	if opts.RootID != "" {
+		layout.MinGeneration = roots[0].Nodes[0].Person.Generation
+	}
+
+	// 确定各家庭所在行以及需要隐藏的后代
+	var visit func(u *GenealogyLayoutUnit, level, depth int)
+	visit = func(u *GenealogyLayoutUnit, level, depth int) {
+		u.Level = level
+		u.width = float64(len(u.Nodes))*opts.NodeWidth + float64(len(u.Nodes)-1)*opts.SpouseGap
+		layout.Units = append(layout.Units, u)
+		if level+1 > layout.Levels {
+			layout.Levels = level + 1
+		}
+		if opts.Collapsed[u.Nodes[0].Person.ID] || (opts.MaxDepth > 0 && depth+1 >= opts.MaxDepth) {
+			for _, c := range u.Children {
+				u.HiddenCount += layoutHide(c)
+			}
+			u.Children = nil
+			return
+		}
+		for _, c := range u.Children {
+			visit(c, level+1, depth+1)
+		}
+	}
+	for _, r := range roots {
+		level := r.Nodes[0].Person.Generation - layout.MinGeneration
+		if level < 0 || opts.RootID != "" {
+			level = 0
+		}
+		visit(r, level, 0)
+	}
+
+	// 按轮廓排列各支，再换算为绝对坐标
+	forest := &GenealogyLayoutUnit{Children: roots}
+	contour := layoutPlaceChildren(forest, opts, -1)
+	minX := 0.0
+	for _, c := range contour {
+		if c.left < minX {
+			minX = c.left
+		}
+	}
+	var place func(u *GenealogyLayoutUnit, parentX float64)
+	place = func(u *GenealogyLayoutUnit, parentX float64) {
+		u.x += parentX
+		y := opts.Padding + float64(u.Level)*(opts.NodeHeight+opts.LevelGap)
+		left := u.x - u.width/2
+		for i, n := range u.Nodes {
+			n.X = left + float64(i)*(opts.NodeWidth+opts.SpouseGap)
+			n.Y = y
+		}
+		for _, c := range u.Children {
+			place(c, u.x)
+		}
+	}
+	maxX := 0.0
+	for _, r := range roots {
+		place(r, opts.Padding-minX)
+	}
+	for _, u := range layout.Units {
+		if right := u.x + u.width/2; right > maxX {
+			maxX = right
+		}
+	}
+	layout.Width = maxX + opts.Padding
+	layout.Height = 2*opts.Padding + float64(layout.Levels)*opts.NodeHeight + float64(layout.Levels-1)*opts.LevelGap
+	return layout
+}
+
+// 轮廓：某一行相对于家庭中心的最左、最右位置
+type layoutContour struct {
+	left, right float64
+}
+
+// layoutPlaceChildren 依次排列子家庭并使其不重叠，返回从 u 的下一行起按绝对行号索引的轮廓
+func layoutPlaceChildren(u *GenealogyLayoutUnit, opts GenealogyLayoutOptions, level int) map[int]layoutContour {
+	merged := map[int]layoutContour{}
+	var offsets []float64
+	for i, c := range u.Children {
+		cc := layoutSubtree(c, opts)
+		shift := 0.0
+		if i > 0 {
+			overlap := false
+			for l, right := range merged {
+				if left, ok := cc[l]; ok {
+					need := right.right - left.left + opts.SiblingGap
+					if !overlap || need > shift {
+						shift = need
+						overlap = true
+					}
+				}
+			}
+			// 没有共同的行时整支排在右侧，避免看起来像上下代
+			if !overlap {
+				for _, right := range merged {
+					for _, left := range cc {
+						if need := right.right - left.left + opts.SiblingGap; need > shift {
+							shift = need
+						}
+					}
+				}
+			}
+		}
+		offsets = append(offsets, shift)
+		for l, c := range cc {
+			m, ok := merged[l]
+			if !ok {
+				merged[l] = layoutContour{c.left + shift, c.right + shift}
+				continue
+			}
+			if c.left+shift < m.left {
+				m.left = c.left + shift
+			}
+			if c.right+shift > m.right {
+				m.right = c.right + shift
+			}
+			merged[l] = m
+		}
+	}
+	if len(offsets) == 0 {
+		return merged
+	}
+
+	// 上一代居中于首末子女之上
+	center := (offsets[0] + offsets[len(offsets)-1]) / 2
+	if level < 0 {
+		center = 0
+	}
+	for i, c := range u.Children {
+		c.x = offsets[i] - center
+	}
+	for l, m := range merged {
+		merged[l] = layoutContour{m.left - center, m.right - center}
+	}
+	return merged
+}
+
+// layoutSubtree 排列一个家庭及其后代，返回以该家庭中心为原点、按绝对行号索引的轮廓
+func layoutSubtree(u *GenealogyLayoutUnit, opts GenealogyLayoutOptions) map[int]layoutContour {
+	contour := layoutPlaceChildren(u, opts, u.Level)
+	contour[u.Level] = layoutContour{-u.width / 2, u.width / 2}
+	return contour
+}
+
+// layoutHide 统计不绘制的后代人数
+func layoutHide(u *GenealogyLayoutUnit) int {
+	count := len(u.Nodes)
+	for _, c := range u.Children {
+		count += layoutHide(c)
+	}
+	return count
+}
+
+func layoutParentRank(e GenealogyEdge, parent *GenealogyLayoutPerson) int {
+	rank := 0
+	if e.ParentType != GenealogyParentBiological && e.ParentType != "" {
+		rank += 2
+	}
+	if parent.Gender != "male" {
+		rank++
+	}
+	return rank
+}
+
+// 按辈分、出生日期、姓名排序，未知出生日期排在后面
+func layoutPersonLess(a, b *GenealogyLayoutPerson) bool {
+	if a.Generation != b.Generation {
+		return a.Generation < b.Generation
+	}
+	if (a.BirthDate == nil) != (b.BirthDate == nil) {
+		return a.BirthDate != nil
+	}
+	if a.BirthDate != nil && !a.BirthDate.Equal(*b.BirthDate) {
+		return a.BirthDate.Before(*b.BirthDate)
+	}
+	if a.Name != b.Name {
+		return a.Name < b.Name
+	}
+	return a.ID < b.ID
+}
@@ -0,0 +1,139 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sampleLayoutPersons() []GenealogyLayoutPerson {
+	birth := time.Date(1930, 5, 1, 0, 0, 0, 0, time.UTC)
+	death := time.Date(2001, 3, 2, 0, 0, 0, 0, time.UTC)
+	return []GenealogyLayoutPerson{
+		{ID: "gf", Name: "张大山", Gender: "male", Generation: 1, BirthDate: &birth, DeathDate: &death},
+		{ID: "gm", Name: "李秀英", Gender: "female", Generation: 1},
+		{ID: "f", Name: "张建国", Gender: "male", Generation: 2},
+		{ID: "m", Name: "王芳", Gender: "female", Generation: 2},
+		{ID: "sm", Name: "赵敏", Gender: "female", Generation: 2},
+		{ID: "u", Name: "张建军", Gender: "male", Generation: 2},
+		{ID: "c1", Name: "张伟", Gender: "male", Generation: 3},
+		{ID: "c2", Name: "<张&明>", Gender: "male", Generation: 3, AvatarURL: "/uploads/a.png?x=1&y=2"},
+	}
+}
+
+func layoutUnitOf(layout *GenealogyLayout, id string) *GenealogyLayoutUnit {
+	for _, u := range layout.Units {
+		for _, n := range u.Nodes {
+			if n.Person.ID == id {
+				return u
+			}
+		}
+	}
+	return nil
+}
+
+func TestLayoutGenealogy(t *testing.T) {
+	for _, style := range []string{GenealogyStyleModern, GenealogyStyleTraditional} {
+		opts := NewGenealogyLayoutOptions(style)
+		layout := LayoutGenealogy(sampleLayoutPersons(), sampleGenealogyGraph(), opts)
+
+		require.Equal(t, 3, layout.Levels)
+		assert.Equal(t, 1, layout.MinGeneration)
+
+		// 无父母记录的配偶与本支成员同在一个家庭
+		gf := layoutUnitOf(layout, "gf")
+		require.NotNil(t, gf)
+		assert.Same(t, gf, layoutUnitOf(layout, "gm"))
+		f := layoutUnitOf(layout, "f")
+		assert.Len(t, f.Nodes, 3)
+		assert.Equal(t, 1, f.Level)
+
+		// c2 优先挂在亲生父亲下
+		assert.Same(t, f, layoutUnitOf(layout, "c2").Parent)
+
+		// 上一代居中于首末子女之上
+		for _, u := range layout.Units {
+			if len(u.Children) == 0 {
+				continue
+			}
+			first, last := u.Children[0], u.Children[len(u.Children)-1]
+			assert.InDelta(t, (first.CenterX()+last.CenterX())/2, u.CenterX(), 0.001)
+		}
+
+		// 同一行的节点互不重叠，且都在画布内
+		rows := map[float64][]*GenealogyLayoutNode{}
+		for _, u := range layout.Units {
+			for _, n := range u.Nodes {
+				rows[n.Y] = append(rows[n.Y], n)
+				assert.GreaterOrEqual(t, n.X, opts.Padding-0.001)
+				assert.LessOrEqual(t, n.X+opts.NodeWidth, layout.Width-opts.Padding+0.001)
+				assert.LessOrEqual(t, n.Y+opts.NodeHeight, layout.Height-opts.Padding+0.001)
+			}
+		}
+		for _, nodes := range rows {
+			for i := range nodes {
+				for j := i + 1; j < len(nodes); j++ {
+					a, b := nodes[i], nodes[j]
+					overlap := a.X < b.X+opts.NodeWidth && b.X < a.X+opts.NodeWidth
+					assert.False(t, overlap, "%s 与 %s 重叠", a.Person.ID, b.Person.ID)
+				}
+			}
+		}
+	}
+}
+
+func TestLayoutGenealogyCollapse(t *testing.T) {
+	opts := NewGenealogyLayoutOptions(GenealogyStyleModern)
+	opts.Collapsed = map[string]bool{"f": true}
+	layout := LayoutGenealogy(sampleLayoutPersons(), sampleGenealogyGraph(), opts)
+
+	assert.Equal(t, 2, layout.Levels)
+	assert.Equal(t, 2, layoutUnitOf(layout, "f").HiddenCount)
+	assert.Nil(t, layoutUnitOf(layout, "c1"))
+
+	opts = NewGenealogyLayoutOptions(GenealogyStyleModern)
+	opts.MaxDepth = 1
+	layout = LayoutGenealogy(sampleLayoutPersons(), sampleGenealogyGraph(), opts)
+	assert.Equal(t, 1, layout.Levels)
+	// f 一家三人、u 以及两个孙辈
+	assert.Equal(t, 6, layoutUnitOf(layout, "gf").HiddenCount)
+
+	opts = NewGenealogyLayoutOptions(GenealogyStyleModern)
+	opts.RootID = "f"
+	layout = LayoutGenealogy(sampleLayoutPersons(), sampleGenealogyGraph(), opts)
+	assert.Equal(t, 2, layout.Levels)
+	assert.Equal(t, 2, layout.MinGeneration)
+	assert.Nil(t, layoutUnitOf(layout, "gf"))
+	assert.Nil(t, layoutUnitOf(layout, "u"))
+}
+
+func TestRenderGenealogySVG(t *testing.T) {
+	for _, style := range []string{GenealogyStyleModern, GenealogyStyleTraditional} {
+		layout := LayoutGenealogy(sampleLayoutPersons(), sampleGenealogyGraph(), NewGenealogyLayoutOptions(style))
+		svg := RenderGenealogySVG(layout, GenealogySVGOptions{Style: style, Title: "张氏 & 族谱"})
+
+		dec := xml.NewDecoder(bytes.NewReader(svg))
+		for {
+			_, err := dec.Token()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err, style)
+		}
+		assert.Contains(t, string(svg), "张氏 &amp; 族谱")
+		if style == GenealogyStyleModern {
+			assert.Contains(t, string(svg), "1930–2001")
+		} else {
+			assert.Contains(t, string(svg), "第1世")
+			assert.Contains(t, string(svg), ">配<")
+		}
+	}
+
+	empty := RenderGenealogySVG(LayoutGenealogy(nil, NewGenealogyGraph(nil), NewGenealogyLayoutOptions("")), GenealogySVGOptions{})
+	assert.Contains(t, string(empty), "暂无谱系成员")
+}
@@ -0,0 +1,48 @@
+package utils
+
+import "time"
+
+// GenealogyMemorialKey 谱系成员或纪念馆逝者用于匹配的信息
+type GenealogyMemorialKey struct {
+	Name      string
+	BirthDate *time.Time
+	DeathDate *time.Time
+}
+
+// MatchGenealogyMemorial 判断谱系成员与纪念馆逝者是否为同一人，返回可信度和依据，不匹配时可信度为空
+//
+// 姓名须相同（忽略空格）。生卒日期双方都已知时须同年，否则不算匹配；
+// 至少一项日期吻合为 exact，日期都缺失时只凭姓名为 possible。
+func MatchGenealogyMemorial(person, memorial GenealogyMemorialKey) (string, []string) {
+	if normalizeGenealogyName(person.Name) == "" || normalizeGenealogyName(person.Name) != normalizeGenealogyName(memorial.Name) {
+		return "", nil
+	}
+	reasons := []string{"姓名相同"}
+
+	matched := 0
+	for _, pair := range []struct {
+		label string
+		a, b  *time.Time
+	}{
+		{"出生", person.BirthDate, memorial.BirthDate},
+		{"去世", person.DeathDate, memorial.DeathDate},
+	} {
+		if pair.a == nil || pair.b == nil {
+			continue
+		}
+		if pair.a.Year() != pair.b.Year() {
+			return "", nil
+		}
+		matched++
+		if sameDay(*pair.a, *pair.b) {
+			reasons = append(reasons, pair.label+"日期相同")
+		} else {
+			reasons = append(reasons, pair.label+"年份相同")
+		}
+	}
+
+	if matched > 0 {
+		return GenealogyMatchExact, reasons
+	}
+	return GenealogyMatchPossible, reasons
+}
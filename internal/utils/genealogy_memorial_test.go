@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchGenealogyMemorial(t *testing.T) {
+	person := GenealogyMemorialKey{Name: "张 大山", BirthDate: auditDate(1930, 5, 1), DeathDate: auditDate(2001, 3, 2)}
+
+	match, reasons := MatchGenealogyMemorial(person, GenealogyMemorialKey{Name: "张大山", BirthDate: auditDate(1930, 5, 1), DeathDate: auditDate(2001, 9, 9)})
+	assert.Equal(t, GenealogyMatchExact, match)
+	assert.Equal(t, []string{"姓名相同", "出生日期相同", "去世年份相同"}, reasons)
+
+	// 只有一方记录了日期，只凭姓名
+	match, reasons = MatchGenealogyMemorial(GenealogyMemorialKey{Name: "张大山"}, GenealogyMemorialKey{Name: "张大山", DeathDate: auditDate(2001, 3, 2)})
+	assert.Equal(t, GenealogyMatchPossible, match)
+	assert.Equal(t, []string{"姓名相同"}, reasons)
+
+	// 年份不同或姓名不同都不算
+	match, _ = MatchGenealogyMemorial(person, GenealogyMemorialKey{Name: "张大山", DeathDate: auditDate(2002, 3, 2)})
+	assert.Equal(t, "", match)
+	match, _ = MatchGenealogyMemorial(person, GenealogyMemorialKey{Name: "张小山", BirthDate: auditDate(1930, 5, 1)})
+	assert.Equal(t, "", match)
+	match, _ = MatchGenealogyMemorial(GenealogyMemorialKey{}, GenealogyMemorialKey{})
+	assert.Equal(t, "", match)
+}
@@ -0,0 +1,263 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrSVGRasterizerUnavailable 服务器未安装 rsvg-convert 时返回
+var ErrSVGRasterizerUnavailable = errors.New("图片转换工具不可用")
+
+// GenealogySVGOptions 谱系图绘制选项
+type GenealogySVGOptions struct {
+	Style string
+	Title string
+}
+
+// 两种样式的配色与字体
+type genealogySVGTheme struct {
+	background string
+	line       string
+	text       string
+	subtext    string
+	font       string
+}
+
+var genealogySVGThemes = map[string]genealogySVGTheme{
+	GenealogyStyleModern: {
+		background: "#fafafa", line: "#9aa5b1", text: "#1f2933", subtext: "#7b8794",
+		font: "PingFang SC, Microsoft YaHei, Noto Sans CJK SC, sans-serif",
+	},
+	GenealogyStyleTraditional: {
+		background: "#f6f0e1", line: "#8b1a1a", text: "#1a1a1a", subtext: "#5c4033",
+		font: "SimSun, Songti SC, Noto Serif CJK SC, serif",
+	},
+}
+
+const (
+	genealogySVGTitleHeight = 56
+	genealogySVGLabelWidth  = 64 // 传统样式左侧“第N世”标注的宽度
+)
+
+// GenealogySVGSize 谱系图的画布尺寸，含标题和传统样式左侧的世代标注
+func GenealogySVGSize(layout *GenealogyLayout, style string) (float64, float64) {
+	width := layout.Width
+	if style == GenealogyStyleTraditional {
+		width += genealogySVGLabelWidth
+	}
+	return width, layout.Height + genealogySVGTitleHeight
+}
+
+// RenderGenealogySVG 按布局绘制 SVG 谱系图
+func RenderGenealogySVG(layout *GenealogyLayout, opts GenealogySVGOptions) []byte {
+	traditional := opts.Style == GenealogyStyleTraditional
+	theme := genealogySVGThemes[GenealogyStyleModern]
+	if traditional {
+		theme = genealogySVGThemes[GenealogyStyleTraditional]
+	}
+	lo := layout.Options
+
+	offsetX := 0.0
+	if traditional {
+		offsetX = genealogySVGLabelWidth
+	}
+	width, height := GenealogySVGSize(layout, opts.Style)
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width="%s" height="%s" viewBox="0 0 %s %s" font-family="%s">`+"\n",
+		svgNum(width), svgNum(height), svgNum(width), svgNum(height), svgEscape(theme.font))
+	fmt.Fprintf(&b, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", theme.background)
+	if opts.Title != "" {
+		fmt.Fprintf(&b, `<text x="%s" y="38" text-anchor="middle" font-size="22" font-weight="bold" fill="%s">%s</text>`+"\n",
+			svgNum(width/2), theme.text, svgEscape(opts.Title))
+	}
+
+	if len(layout.Units) == 0 {
+		fmt.Fprintf(&b, `<text x="%s" y="%s" text-anchor="middle" font-size="14" fill="%s">暂无谱系成员</text>`+"\n",
+			svgNum(width/2), svgNum(height/2+genealogySVGTitleHeight/2), theme.subtext)
+		b.WriteString("</svg>\n")
+		return b.Bytes()
+	}
+
+	fmt.Fprintf(&b, `<g transform="translate(%s,%d)">`+"\n", svgNum(offsetX), genealogySVGTitleHeight)
+
+	if traditional {
+		for level := 0; level < layout.Levels; level++ {
+			y := lo.Padding + float64(level)*(lo.NodeHeight+lo.LevelGap) + lo.NodeHeight/2
+			fmt.Fprintf(&b, `<text x="%s" y="%s" text-anchor="middle" font-size="14" fill="%s">第%d世</text>`+"\n",
+				svgNum(-offsetX/2), svgNum(y), theme.subtext, layout.MinGeneration+level)
+		}
+	}
+
+	// 连线：上一代底部居中垂下，横线连接各子女，再垂到子女顶部；非亲生关系用虚线
+	for _, u := range layout.Units {
+		if len(u.Children) == 0 {
+			continue
+		}
+		bottom := u.Nodes[0].Y + lo.NodeHeight
+		mid := bottom + lo.LevelGap/2
+		left, right := u.CenterX(), u.CenterX()
+		for _, c := range u.Children {
+			x := c.Nodes[0].X + lo.NodeWidth/2
+			if x < left {
+				left = x
+			}
+			if x > right {
+				right = x
+			}
+		}
+		fmt.Fprintf(&b, `<path d="M%s %sV%sM%s %sH%s" fill="none" stroke="%s" stroke-width="1.5"/>`+"\n",
+			svgNum(u.CenterX()), svgNum(bottom), svgNum(mid), svgNum(left), svgNum(mid), svgNum(right), theme.line)
+		for _, c := range u.Children {
+			dash := ""
+			if c.ParentType != GenealogyParentBiological && c.ParentType != "" {
+				dash = ` stroke-dasharray="5 4"`
+			}
+			x := c.Nodes[0].X + lo.NodeWidth/2
+			fmt.Fprintf(&b, `<path d="M%s %sV%s" fill="none" stroke="%s" stroke-width="1.5"%s/>`+"\n",
+				svgNum(x), svgNum(mid), svgNum(c.Nodes[0].Y), theme.line, dash)
+		}
+	}
+
+	avatar := 0
+	for _, u := range layout.Units {
+		for i, n := range u.Nodes {
+			if i > 0 {
+				prev := u.Nodes[i-1]
+				gapX := prev.X + lo.NodeWidth + lo.SpouseGap/2
+				if traditional {
+					fmt.Fprintf(&b, `<text x="%s" y="%s" text-anchor="middle" font-size="12" fill="%s">配</text>`+"\n",
+						svgNum(gapX), svgNum(n.Y+16), theme.line)
+				} else {
+					fmt.Fprintf(&b, `<path d="M%s %sH%s" stroke="%s" stroke-width="1.5"/>`+"\n",
+						svgNum(prev.X+lo.NodeWidth), svgNum(n.Y+lo.NodeHeight/2), svgNum(n.X), theme.line)
+				}
+			}
+			if traditional {
+				writeTraditionalNode(&b, n, lo, theme)
+			} else {
+				avatar++
+				writeModernNode(&b, n, lo, theme, avatar)
+			}
+		}
+		if u.HiddenCount > 0 {
+			fmt.Fprintf(&b, `<text x="%s" y="%s" text-anchor="middle" font-size="12" fill="%s">+%d 人</text>`+"\n",
+				svgNum(u.CenterX()), svgNum(u.Nodes[0].Y+lo.NodeHeight+16), theme.subtext, u.HiddenCount)
+		}
+	}
+
+	b.WriteString("</g>\n</svg>\n")
+	return b.Bytes()
+}
+
+func writeModernNode(b *bytes.Buffer, n *GenealogyLayoutNode, lo GenealogyLayoutOptions, theme genealogySVGTheme, index int) {
+	p := n.Person
+	stroke := "#9aa5b1"
+	fill := "#e4e7eb"
+	switch p.Gender {
+	case "male":
+		stroke, fill = "#4a6fa5", "#dbe7f6"
+	case "female":
+		stroke, fill = "#b5646e", "#f6dde0"
+	}
+	fmt.Fprintf(b, `<rect x="%s" y="%s" width="%s" height="%s" rx="8" fill="#ffffff" stroke="%s" stroke-width="1.5"/>`+"\n",
+		svgNum(n.X), svgNum(n.Y), svgNum(lo.NodeWidth), svgNum(lo.NodeHeight), stroke)
+
+	cx, cy, r := n.X+30, n.Y+lo.NodeHeight/2, 22.0
+	if p.AvatarURL != "" {
+		fmt.Fprintf(b, `<clipPath id="avatar-%d"><circle cx="%s" cy="%s" r="%s"/></clipPath>`+"\n", index, svgNum(cx), svgNum(cy), svgNum(r))
+		fmt.Fprintf(b, `<image xlink:href="%s" x="%s" y="%s" width="%s" height="%s" preserveAspectRatio="xMidYMid slice" clip-path="url(#avatar-%d)"/>`+"\n",
+			svgEscape(p.AvatarURL), svgNum(cx-r), svgNum(cy-r), svgNum(2*r), svgNum(2*r), index)
+	} else {
+		initial := ""
+		if runes := []rune(p.Name); len(runes) > 0 {
+			initial = string(runes[0])
+		}
+		fmt.Fprintf(b, `<circle cx="%s" cy="%s" r="%s" fill="%s"/>`+"\n", svgNum(cx), svgNum(cy), svgNum(r), fill)
+		fmt.Fprintf(b, `<text x="%s" y="%s" text-anchor="middle" font-size="18" fill="%s">%s</text>`+"\n",
+			svgNum(cx), svgNum(cy+6), stroke, svgEscape(initial))
+	}
+
+	fmt.Fprintf(b, `<text x="%s" y="%s" font-size="15" fill="%s">%s</text>`+"\n",
+		svgNum(n.X+60), svgNum(n.Y+28), theme.text, svgEscape(clipName(p.Name, 6)))
+	if years := genealogyYears(p.BirthDate, p.DeathDate); years != "" {
+		fmt.Fprintf(b, `<text x="%s" y="%s" font-size="12" fill="%s">%s</text>`+"\n",
+			svgNum(n.X+60), svgNum(n.Y+48), theme.subtext, years)
+	}
+}
+
+// 传统样式：姓名竖排，生卒年写在下方
+func writeTraditionalNode(b *bytes.Buffer, n *GenealogyLayoutNode, lo GenealogyLayoutOptions, theme genealogySVGTheme) {
+	p := n.Person
+	x := n.X + lo.NodeWidth/2
+	for i, r := range []rune(clipName(p.Name, 4)) {
+		fmt.Fprintf(b, `<text x="%s" y="%s" text-anchor="middle" font-size="20" fill="%s">%s</text>`+"\n",
+			svgNum(x), svgNum(n.Y+30+float64(i)*24), theme.text, svgEscape(string(r)))
+	}
+	if p.BirthDate != nil {
+		fmt.Fprintf(b, `<text x="%s" y="%s" text-anchor="middle" font-size="11" fill="%s">%d</text>`+"\n",
+			svgNum(x), svgNum(n.Y+lo.NodeHeight-22), theme.subtext, p.BirthDate.Year())
+	}
+	if p.DeathDate != nil {
+		fmt.Fprintf(b, `<text x="%s" y="%s" text-anchor="middle" font-size="11" fill="%s">%d</text>`+"\n",
+			svgNum(x), svgNum(n.Y+lo.NodeHeight-8), theme.subtext, p.DeathDate.Year())
+	}
+}
+
+// genealogyYears 生卒年，如“1930–2001”，在世为“1930–”
+func genealogyYears(birth, death *time.Time) string {
+	if birth == nil && death == nil {
+		return ""
+	}
+	from, to := "?", ""
+	if birth != nil {
+		from = strconv.Itoa(birth.Year())
+	}
+	if death != nil {
+		to = strconv.Itoa(death.Year())
+	}
+	return from + "–" + to
+}
+
+// clipName 过长的姓名截断并加省略号
+func clipName(name string, max int) string {
+	runes := []rune(name)
+	if len(runes) <= max {
+		return name
+	}
+	return string(runes[:max-1]) + "…"
+}
+
+func svgNum(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func svgEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// RasterizeSVG 借助 rsvg-convert 将 SVG 转为 PNG，zoom 为缩放倍数
+func RasterizeSVG(svg []byte, zoom float64) ([]byte, error) {
+	bin, err := exec.LookPath("rsvg-convert")
+	if err != nil {
+		return nil, ErrSVGRasterizerUnavailable
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(bin, "--format", "png", "--zoom", strconv.FormatFloat(zoom, 'f', 2, 64))
+	cmd.Stdin = bytes.NewReader(svg)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("转换图片失败: %v %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
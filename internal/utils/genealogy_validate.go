@@ -0,0 +1,426 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 谱系问题严重程度
+const (
+	GenealogySeverityError   = "error"   // 数据不可能成立，写入时拒绝
+	GenealogySeverityWarning = "warning" // 可能有误，需要人工核对
+)
+
+// 谱系问题类型
+const (
+	GenealogyIssueCycle              = "cycle"               // 自己成为自己的祖先
+	GenealogyIssueGenerationMismatch = "generation_mismatch" // 子女辈分不低于父母
+	GenealogyIssueGenerationGap      = "generation_gap"      // 子女比父母低不止一辈
+	GenealogyIssueSpouseGeneration   = "spouse_generation"   // 配偶辈分不同
+	GenealogyIssueInvalidLifespan    = "invalid_lifespan"    // 去世早于出生
+	GenealogyIssueFutureDate         = "future_date"         // 日期晚于今天
+	GenealogyIssueLifespanTooLong    = "lifespan_too_long"   // 寿命过长
+	GenealogyIssueParentYounger      = "parent_younger"      // 父母比子女年轻
+	GenealogyIssueParentTooYoung     = "parent_too_young"    // 生育时年龄过小
+	GenealogyIssueParentDeceased     = "parent_deceased"     // 亲生父母在子女出生前已去世
+	GenealogyIssueTooManyParents     = "too_many_parents"    // 亲生父母超过两位
+	GenealogyIssueOrphanedRelation   = "orphaned_relation"   // 关系指向不存在的成员
+	GenealogyIssueDuplicatePerson    = "duplicate_person"    // 疑似重复录入
+)
+
+const (
+	// 生育年龄下限，低于此值提示核对
+	genealogyMinParentAge = 12
+	// 寿命上限，超过此值提示核对
+	genealogyMaxLifespan = 120
+	// 父亲去世后子女仍可能出生的最长天数
+	genealogyPosthumousDays = 300
+)
+
+// GenealogyAuditPerson 参与校验的谱系成员
+type GenealogyAuditPerson struct {
+	ID         string
+	Name       string
+	Gender     string
+	Generation int
+	BirthDate  *time.Time
+	DeathDate  *time.Time
+}
+
+// GenealogyIssue 校验发现的一个问题
+type GenealogyIssue struct {
+	Code        string   `json:"code"`
+	Severity    string   `json:"severity"`
+	PersonIDs   []string `json:"person_ids"`
+	RelationIDs []string `json:"relation_ids"`
+	Message     string   `json:"message"`
+	Suggestion  string   `json:"suggestion"`
+}
+
+// Key 问题的标识，用于比较修改前后新出现的问题
+func (i GenealogyIssue) Key() string {
+	persons := append([]string(nil), i.PersonIDs...)
+	relations := append([]string(nil), i.RelationIDs...)
+	sort.Strings(persons)
+	sort.Strings(relations)
+	return i.Code + "|" + strings.Join(persons, ",") + "|" + strings.Join(relations, ",")
+}
+
+// ValidateGenealogy 检查谱系的一致性：环、辈分、日期、孤立关系和重复成员，错误在前
+func ValidateGenealogy(persons []GenealogyAuditPerson, edges []GenealogyEdge) []GenealogyIssue {
+	v := &genealogyValidator{byID: make(map[string]*GenealogyAuditPerson, len(persons)), now: time.Now()}
+	for i := range persons {
+		v.byID[persons[i].ID] = &persons[i]
+	}
+
+	var valid []GenealogyEdge
+	for _, e := range edges {
+		var missing []string
+		for _, id := range []string{e.FromID, e.ToID} {
+			if v.byID[id] == nil {
+				missing = append(missing, id)
+			}
+		}
+		if len(missing) > 0 {
+			v.add(GenealogyIssue{
+				Code:        GenealogyIssueOrphanedRelation,
+				Severity:    GenealogySeverityWarning,
+				PersonIDs:   v.existing(e.FromID, e.ToID),
+				RelationIDs: []string{e.ID},
+				Message:     fmt.Sprintf("关系指向不存在的谱系成员 %s", strings.Join(missing, "、")),
+				Suggestion:  "删除该关系，或恢复被删除的成员",
+			})
+			continue
+		}
+		valid = append(valid, e)
+	}
+
+	for i := range persons {
+		v.checkPerson(&persons[i])
+	}
+	graph := NewGenealogyGraph(valid)
+	for _, e := range valid {
+		switch e.Type {
+		case GenealogyRelationParent:
+			v.checkParent(e)
+		case GenealogyRelationSpouse:
+			v.checkSpouse(e)
+		}
+	}
+	for i := range persons {
+		p := &persons[i]
+		if n := graph.BiologicalParentCount(p.ID); n > GenealogyMaxBiologicalParents {
+			v.add(GenealogyIssue{
+				Code:        GenealogyIssueTooManyParents,
+				Severity:    GenealogySeverityError,
+				PersonIDs:   []string{p.ID},
+				RelationIDs: edgeIDs(graph.Parents(p.ID)),
+				Message:     fmt.Sprintf("%s有 %d 位亲生父母", p.Name, n),
+				Suggestion:  "将多出的父母改为收养或继父母关系，或删除错误的关系",
+			})
+		}
+	}
+	v.checkCycles(graph, valid)
+	v.checkDuplicates(persons, graph)
+
+	sort.SliceStable(v.issues, func(i, j int) bool {
+		return v.issues[i].Severity == GenealogySeverityError && v.issues[j].Severity != GenealogySeverityError
+	})
+	return v.issues
+}
+
+type genealogyValidator struct {
+	byID   map[string]*GenealogyAuditPerson
+	now    time.Time
+	issues []GenealogyIssue
+}
+
+func (v *genealogyValidator) add(issue GenealogyIssue) {
+	if issue.PersonIDs == nil {
+		issue.PersonIDs = []string{}
+	}
+	if issue.RelationIDs == nil {
+		issue.RelationIDs = []string{}
+	}
+	v.issues = append(v.issues, issue)
+}
+
+func (v *genealogyValidator) existing(ids ...string) []string {
+	var result []string
+	for _, id := range ids {
+		if v.byID[id] != nil {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+func (v *genealogyValidator) checkPerson(p *GenealogyAuditPerson) {
+	for _, d := range []*time.Time{p.BirthDate, p.DeathDate} {
+		if d != nil && d.After(v.now) {
+			v.add(GenealogyIssue{
+				Code:       GenealogyIssueFutureDate,
+				Severity:   GenealogySeverityError,
+				PersonIDs:  []string{p.ID},
+				Message:    fmt.Sprintf("%s的生卒日期 %s 晚于今天", p.Name, d.Format("2006-01-02")),
+				Suggestion: "核对出生和去世日期",
+			})
+			break
+		}
+	}
+	if p.BirthDate == nil || p.DeathDate == nil {
+		return
+	}
+	if p.DeathDate.Before(*p.BirthDate) {
+		v.add(GenealogyIssue{
+			Code:       GenealogyIssueInvalidLifespan,
+			Severity:   GenealogySeverityError,
+			PersonIDs:  []string{p.ID},
+			Message:    fmt.Sprintf("%s的去世日期早于出生日期", p.Name),
+			Suggestion: "核对出生和去世日期，可能两者填反了",
+		})
+		return
+	}
+	if age := yearsBetween(*p.BirthDate, *p.DeathDate); age > genealogyMaxLifespan {
+		v.add(GenealogyIssue{
+			Code:       GenealogyIssueLifespanTooLong,
+			Severity:   GenealogySeverityWarning,
+			PersonIDs:  []string{p.ID},
+			Message:    fmt.Sprintf("%s享年 %d 岁", p.Name, age),
+			Suggestion: "核对出生和去世日期",
+		})
+	}
+}
+
+func (v *genealogyValidator) checkParent(e GenealogyEdge) {
+	parent, child := v.byID[e.FromID], v.byID[e.ToID]
+	ids, rel := []string{parent.ID, child.ID}, []string{e.ID}
+	biological := e.ParentType == GenealogyParentBiological || e.ParentType == ""
+
+	switch {
+	case child.Generation <= parent.Generation:
+		v.add(GenealogyIssue{
+			Code:        GenealogyIssueGenerationMismatch,
+			Severity:    GenealogySeverityError,
+			PersonIDs:   ids,
+			RelationIDs: rel,
+			Message:     fmt.Sprintf("%s（第%d代）的辈分不低于父母%s（第%d代）", child.Name, child.Generation, parent.Name, parent.Generation),
+			Suggestion:  fmt.Sprintf("将%s的辈分改为第%d代，或检查父母关系是否建反", child.Name, parent.Generation+1),
+		})
+	case child.Generation > parent.Generation+1:
+		v.add(GenealogyIssue{
+			Code:        GenealogyIssueGenerationGap,
+			Severity:    GenealogySeverityWarning,
+			PersonIDs:   ids,
+			RelationIDs: rel,
+			Message:     fmt.Sprintf("%s（第%d代）比父母%s（第%d代）低了 %d 辈", child.Name, child.Generation, parent.Name, parent.Generation, child.Generation-parent.Generation),
+			Suggestion:  fmt.Sprintf("将%s的辈分改为第%d代", child.Name, parent.Generation+1),
+		})
+	}
+
+	if parent.BirthDate != nil && child.BirthDate != nil {
+		if !parent.BirthDate.Before(*child.BirthDate) {
+			severity := GenealogySeverityWarning
+			if biological {
+				severity = GenealogySeverityError
+			}
+			v.add(GenealogyIssue{
+				Code:        GenealogyIssueParentYounger,
+				Severity:    severity,
+				PersonIDs:   ids,
+				RelationIDs: rel,
+				Message:     fmt.Sprintf("父母%s的出生日期不早于子女%s", parent.Name, child.Name),
+				Suggestion:  "核对两人的出生日期，或检查父母关系是否建反",
+			})
+		} else if age := yearsBetween(*parent.BirthDate, *child.BirthDate); biological && age < genealogyMinParentAge {
+			v.add(GenealogyIssue{
+				Code:        GenealogyIssueParentTooYoung,
+				Severity:    GenealogySeverityWarning,
+				PersonIDs:   ids,
+				RelationIDs: rel,
+				Message:     fmt.Sprintf("%s出生时，父母%s只有 %d 岁", child.Name, parent.Name, age),
+				Suggestion:  "核对两人的出生日期",
+			})
+		}
+	}
+
+	// 母亲须在子女出生时在世；父亲去世后约十个月内子女仍可能出生
+	if biological && parent.DeathDate != nil && child.BirthDate != nil {
+		limit := *parent.DeathDate
+		if parent.Gender == "male" {
+			limit = limit.AddDate(0, 0, genealogyPosthumousDays)
+		}
+		if child.BirthDate.After(limit) {
+			v.add(GenealogyIssue{
+				Code:        GenealogyIssueParentDeceased,
+				Severity:    GenealogySeverityError,
+				PersonIDs:   ids,
+				RelationIDs: rel,
+				Message:     fmt.Sprintf("%s出生时，亲生父母%s已去世", child.Name, parent.Name),
+				Suggestion:  "核对日期，或将关系改为收养或继父母",
+			})
+		}
+	}
+}
+
+func (v *genealogyValidator) checkSpouse(e GenealogyEdge) {
+	a, b := v.byID[e.FromID], v.byID[e.ToID]
+	if a.Generation == b.Generation {
+		return
+	}
+	v.add(GenealogyIssue{
+		Code:        GenealogyIssueSpouseGeneration,
+		Severity:    GenealogySeverityWarning,
+		PersonIDs:   []string{a.ID, b.ID},
+		RelationIDs: []string{e.ID},
+		Message:     fmt.Sprintf("配偶%s（第%d代）与%s（第%d代）辈分不同", a.Name, a.Generation, b.Name, b.Generation),
+		Suggestion:  "配偶通常为同一辈分，核对两人的辈分",
+	})
+}
+
+// checkCycles 找出父母关系中的环，每个环只报告一次
+func (v *genealogyValidator) checkCycles(graph *GenealogyGraph, edges []GenealogyEdge) {
+	const (
+		white = iota
+		grey
+		black
+	)
+	color := map[string]int{}
+	var stack []GenealogyEdge
+	seen := map[string]bool{}
+
+	var visit func(id string)
+	visit = func(id string) {
+		color[id] = grey
+		for _, e := range graph.Children(id) {
+			switch color[e.ToID] {
+			case white:
+				stack = append(stack, e)
+				visit(e.ToID)
+				stack = stack[:len(stack)-1]
+			case grey:
+				// 沿栈回溯到环的起点
+				cycle := []GenealogyEdge{e}
+				for i := len(stack) - 1; i >= 0 && stack[i].ToID != e.ToID; i-- {
+					cycle = append(cycle, stack[i])
+				}
+				var ids, names, rels []string
+				for i := len(cycle) - 1; i >= 0; i-- {
+					ids = append(ids, cycle[i].FromID)
+					names = append(names, v.byID[cycle[i].FromID].Name)
+					rels = append(rels, cycle[i].ID)
+				}
+				issue := GenealogyIssue{
+					Code:        GenealogyIssueCycle,
+					Severity:    GenealogySeverityError,
+					PersonIDs:   ids,
+					RelationIDs: rels,
+					Message:     fmt.Sprintf("父母关系成环：%s→%s", strings.Join(names, "→"), names[0]),
+					Suggestion:  "删除环中错误的一条父母关系",
+				}
+				if key := issue.Key(); !seen[key] {
+					seen[key] = true
+					v.add(issue)
+				}
+			}
+		}
+		color[id] = black
+	}
+
+	var starts []string
+	for _, e := range edges {
+		if e.Type == GenealogyRelationParent {
+			starts = append(starts, e.FromID)
+		}
+	}
+	sort.Strings(starts)
+	for _, id := range starts {
+		if color[id] == white {
+			visit(id)
+		}
+	}
+}
+
+// checkDuplicates 同名且生日相同，或同名同辈且有共同父母的，疑似重复录入
+func (v *genealogyValidator) checkDuplicates(persons []GenealogyAuditPerson, graph *GenealogyGraph) {
+	byName := map[string][]*GenealogyAuditPerson{}
+	var names []string
+	for i := range persons {
+		name := strings.Join(strings.Fields(persons[i].Name), "")
+		if name == "" {
+			continue
+		}
+		if _, ok := byName[name]; !ok {
+			names = append(names, name)
+		}
+		byName[name] = append(byName[name], &persons[i])
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		group := byName[name]
+		for i := 0; i < len(group); i++ {
+			for j := i + 1; j < len(group); j++ {
+				a, b := group[i], group[j]
+				if a.Gender != "" && b.Gender != "" && a.Gender != b.Gender {
+					continue
+				}
+				duplicate := false
+				switch {
+				case a.BirthDate != nil && b.BirthDate != nil:
+					duplicate = sameDay(*a.BirthDate, *b.BirthDate)
+				case a.Generation == b.Generation:
+					duplicate = shareParent(graph, a.ID, b.ID)
+				}
+				if !duplicate {
+					continue
+				}
+				v.add(GenealogyIssue{
+					Code:       GenealogyIssueDuplicatePerson,
+					Severity:   GenealogySeverityWarning,
+					PersonIDs:  []string{a.ID, b.ID},
+					Message:    fmt.Sprintf("%s可能被重复录入", name),
+					Suggestion: "确认是否为同一人，是则将关系迁移到其中一条记录后删除另一条",
+				})
+			}
+		}
+	}
+}
+
+func shareParent(graph *GenealogyGraph, a, b string) bool {
+	parents := map[string]bool{}
+	for _, e := range graph.Parents(a) {
+		parents[e.FromID] = true
+	}
+	for _, e := range graph.Parents(b) {
+		if parents[e.FromID] {
+			return true
+		}
+	}
+	return false
+}
+
+func edgeIDs(edges []GenealogyEdge) []string {
+	ids := make([]string, 0, len(edges))
+	for _, e := range edges {
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// yearsBetween 两个日期之间的周岁数
+func yearsBetween(from, to time.Time) int {
+	years := to.Year() - from.Year()
+	if to.Month() < from.Month() || (to.Month() == from.Month() && to.Day() < from.Day()) {
+		years--
+	}
+	return years
+}
@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func auditDate(year, month, day int) *time.Time {
+	t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	return &t
+}
+
+func issueCodes(issues []GenealogyIssue) map[string]GenealogyIssue {
+	codes := map[string]GenealogyIssue{}
+	for _, i := range issues {
+		if _, ok := codes[i.Code]; !ok {
+			codes[i.Code] = i
+		}
+	}
+	return codes
+}
+
+func TestValidateGenealogyClean(t *testing.T) {
+	persons := []GenealogyAuditPerson{
+		{ID: "f", Name: "张建国", Gender: "male", Generation: 1, BirthDate: auditDate(1950, 1, 1), DeathDate: auditDate(2010, 1, 1)},
+		{ID: "m", Name: "王芳", Gender: "female", Generation: 1, BirthDate: auditDate(1952, 1, 1)},
+		{ID: "c", Name: "张伟", Gender: "male", Generation: 2, BirthDate: auditDate(1978, 6, 1)},
+		// 父亲去世后数月出生
+		{ID: "p", Name: "张遗", Gender: "male", Generation: 2, BirthDate: auditDate(2010, 8, 1)},
+	}
+	edges := []GenealogyEdge{
+		{ID: "r1", Type: GenealogyRelationSpouse, FromID: "f", ToID: "m"},
+		{ID: "r2", Type: GenealogyRelationParent, FromID: "f", ToID: "c", ParentType: GenealogyParentBiological},
+		{ID: "r3", Type: GenealogyRelationParent, FromID: "m", ToID: "c", ParentType: GenealogyParentBiological},
+		{ID: "r4", Type: GenealogyRelationParent, FromID: "f", ToID: "p", ParentType: GenealogyParentBiological},
+	}
+	assert.Empty(t, ValidateGenealogy(persons, edges))
+}
+
+func TestValidateGenealogyIssues(t *testing.T) {
+	persons := []GenealogyAuditPerson{
+		{ID: "a", Name: "甲", Gender: "male", Generation: 1, BirthDate: auditDate(1950, 1, 1)},
+		{ID: "b", Name: "乙", Gender: "male", Generation: 2, BirthDate: auditDate(1940, 1, 1)},
+		{ID: "c", Name: "丙", Gender: "female", Generation: 4, BirthDate: auditDate(1990, 1, 1), DeathDate: auditDate(1980, 1, 1)},
+		{ID: "d", Name: "丁", Gender: "female", Generation: 1, BirthDate: auditDate(1930, 1, 1), DeathDate: auditDate(1960, 1, 1)},
+		{ID: "e", Name: "戊", Gender: "male", Generation: 2, BirthDate: auditDate(1965, 1, 1)},
+		{ID: "s", Name: "己", Gender: "female", Generation: 3},
+		{ID: "dup1", Name: "庚", Gender: "male", Generation: 3, BirthDate: auditDate(1970, 3, 3)},
+		{ID: "dup2", Name: " 庚", Gender: "male", Generation: 3, BirthDate: auditDate(1970, 3, 3)},
+	}
+	edges := []GenealogyEdge{
+		{ID: "r1", Type: GenealogyRelationParent, FromID: "a", ToID: "b", ParentType: GenealogyParentBiological},
+		{ID: "r2", Type: GenealogyRelationParent, FromID: "b", ToID: "c", ParentType: GenealogyParentBiological},
+		{ID: "r3", Type: GenealogyRelationParent, FromID: "d", ToID: "e", ParentType: GenealogyParentBiological},
+		{ID: "r4", Type: GenealogyRelationSpouse, FromID: "e", ToID: "s"},
+		{ID: "r5", Type: GenealogyRelationParent, FromID: "gone", ToID: "e", ParentType: GenealogyParentBiological},
+		{ID: "r6", Type: GenealogyRelationParent, FromID: "c", ToID: "a", ParentType: GenealogyParentAdopted},
+	}
+	issues := ValidateGenealogy(persons, edges)
+	codes := issueCodes(issues)
+
+	require.Contains(t, codes, GenealogyIssueCycle)
+	assert.ElementsMatch(t, []string{"a", "b", "c"}, codes[GenealogyIssueCycle].PersonIDs)
+	assert.ElementsMatch(t, []string{"r1", "r2", "r6"}, codes[GenealogyIssueCycle].RelationIDs)
+
+	assert.Equal(t, GenealogySeverityError, codes[GenealogyIssueGenerationMismatch].Severity)
+	assert.Equal(t, []string{"c", "a"}, codes[GenealogyIssueGenerationMismatch].PersonIDs)
+	assert.Contains(t, codes[GenealogyIssueGenerationMismatch].Suggestion, "第5代")
+	assert.Equal(t, GenealogySeverityWarning, codes[GenealogyIssueGenerationGap].Severity)
+	assert.Equal(t, GenealogySeverityError, codes[GenealogyIssueParentYounger].Severity)
+	assert.Equal(t, GenealogySeverityError, codes[GenealogyIssueInvalidLifespan].Severity)
+	assert.Equal(t, []string{"d", "e"}, codes[GenealogyIssueParentDeceased].PersonIDs)
+	assert.Equal(t, GenealogySeverityWarning, codes[GenealogyIssueSpouseGeneration].Severity)
+	assert.Equal(t, []string{"r5"}, codes[GenealogyIssueOrphanedRelation].RelationIDs)
+	assert.Equal(t, []string{"dup1", "dup2"}, codes[GenealogyIssueDuplicatePerson].PersonIDs)
+
+	// 错误排在警告之前
+	seenWarning := false
+	for _, i := range issues {
+		if i.Severity == GenealogySeverityWarning {
+			seenWarning = true
+		} else {
+			assert.False(t, seenWarning, "%s 排在警告之后", i.Code)
+		}
+	}
+}
+
+func TestValidateGenealogyParents(t *testing.T) {
+	persons := []GenealogyAuditPerson{
+		{ID: "p1", Name: "甲", Gender: "male", Generation: 1, BirthDate: auditDate(1970, 1, 1)},
+		{ID: "p2", Name: "乙", Gender: "female", Generation: 1},
+		{ID: "p3", Name: "丙", Gender: "female", Generation: 1},
+		{ID: "c", Name: "丁", Gender: "male", Generation: 2, BirthDate: auditDate(1980, 1, 1)},
+		{ID: "x", Name: "戊", Gender: "male", Generation: 2, BirthDate: auditDate(time.Now().Year()+1, 1, 1)},
+	}
+	edges := []GenealogyEdge{
+		{ID: "r1", Type: GenealogyRelationParent, FromID: "p1", ToID: "c", ParentType: GenealogyParentBiological},
+		{ID: "r2", Type: GenealogyRelationParent, FromID: "p2", ToID: "c", ParentType: GenealogyParentBiological},
+		{ID: "r3", Type: GenealogyRelationParent, FromID: "p3", ToID: "c", ParentType: GenealogyParentBiological},
+	}
+	codes := issueCodes(ValidateGenealogy(persons, edges))
+	assert.Equal(t, GenealogySeverityWarning, codes[GenealogyIssueParentTooYoung].Severity)
+	assert.Equal(t, []string{"r1", "r2", "r3"}, codes[GenealogyIssueTooManyParents].RelationIDs)
+	assert.Equal(t, []string{"x"}, codes[GenealogyIssueFutureDate].PersonIDs)
+	assert.NotContains(t, codes, GenealogyIssueDuplicatePerson)
+}
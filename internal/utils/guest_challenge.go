@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// ArithmeticChallenge 访客祭扫前的算术验证题
+type ArithmeticChallenge struct {
+	Question string
+	Answer   int
+}
+
+// NewArithmeticChallenge 生成一道20以内的加减法或10以内的乘法题，结果非负
+func NewArithmeticChallenge() (*ArithmeticChallenge, error) {
+	op, err := randomInt(3)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op {
+	case 0:
+		a, b, err := randomPair(1, 20)
+		if err != nil {
+			return nil, err
+		}
+		return &ArithmeticChallenge{Question: fmt.Sprintf("%d + %d = ?", a, b), Answer: a + b}, nil
+	case 1:
+		a, b, err := randomPair(1, 20)
+		if err != nil {
+			return nil, err
+		}
+		if a < b {
+			a, b = b, a
+		}
+		return &ArithmeticChallenge{Question: fmt.Sprintf("%d - %d = ?", a, b), Answer: a - b}, nil
+	default:
+		a, b, err := randomPair(2, 9)
+		if err != nil {
+			return nil, err
+		}
+		return &ArithmeticChallenge{Question: fmt.Sprintf("%d × %d = ?", a, b), Answer: a * b}, nil
+	}
+}
+
+// HashChallengeAnswer 验证题答案摘要，salt 使用验证题ID，避免保存明文答案
+func HashChallengeAnswer(salt string, answer int) string {
+	sum := sha256.Sum256([]byte(salt + ":" + strconv.Itoa(answer)))
+	return hex.EncodeToString(sum[:])
+}
+
+// CheckChallengeAnswer 校验用户输入的答案，允许首尾空白和全角数字
+func CheckChallengeAnswer(salt, input, answerHash string) bool {
+	normalized, _ := NormalizeSensitiveText(strings.TrimSpace(input))
+	answer, err := strconv.Atoi(string(normalized))
+	if err != nil {
+		return false
+	}
+	return HashChallengeAnswer(salt, answer) == answerHash
+}
+
+// NewGuestToken 生成访客设备令牌，服务端只保存其摘要
+func NewGuestToken() (string, error) {
+	return GenerateSecureRandomString(48)
+}
+
+// HashGuestToken 访客设备令牌摘要
+func HashGuestToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomPair(min, max int) (int, int, error) {
+	a, err := randomInt(max - min + 1)
+	if err != nil {
+		return 0, 0, err
+	}
+	b, err := randomInt(max - min + 1)
+	if err != nil {
+		return 0, 0, err
+	}
+	return a + min, b + min, nil
+}
+
+func randomInt(n int) (int, error) {
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(v.Int64()), nil
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArithmeticChallenge(t *testing.T) {
+	for i := 0; i < 200; i++ {
+		challenge, err := NewArithmeticChallenge()
+		require.NoError(t, err)
+
+		var a, b int
+		var op string
+		_, err = fmt.Sscanf(challenge.Question, "%d %s %d = ?", &a, &op, &b)
+		require.NoError(t, err, challenge.Question)
+
+		switch op {
+		case "+":
+			assert.Equal(t, a+b, challenge.Answer)
+		case "-":
+			assert.Equal(t, a-b, challenge.Answer)
+		case "×":
+			assert.Equal(t, a*b, challenge.Answer)
+		default:
+			t.Fatalf("unexpected operator in %q", challenge.Question)
+		}
+		assert.GreaterOrEqual(t, challenge.Answer, 0)
+	}
+}
+
+func TestCheckChallengeAnswer(t *testing.T) {
+	hash := HashChallengeAnswer("challenge-1", 12)
+
+	assert.True(t, CheckChallengeAnswer("challenge-1", "12", hash))
+	assert.True(t, CheckChallengeAnswer("challenge-1", " １２ ", hash))
+	assert.False(t, CheckChallengeAnswer("challenge-1", "13", hash))
+	assert.False(t, CheckChallengeAnswer("challenge-2", "12", hash))
+	assert.False(t, CheckChallengeAnswer("challenge-1", "twelve", hash))
+}
+
+func TestGuestToken(t *testing.T) {
+	token, err := NewGuestToken()
+	require.NoError(t, err)
+	assert.Len(t, token, 48)
+
+	other, err := NewGuestToken()
+	require.NoError(t, err)
+	assert.NotEqual(t, token, other)
+	assert.NotEqual(t, HashGuestToken(token), HashGuestToken(other))
+	assert.Len(t, HashGuestToken(token), 64)
+}
@@ -0,0 +1,527 @@
+package utils
+
+import (
+	"strings"
+	"time"
+)
+
+// 称谓的地区用法
+const (
+	KinshipRegionStandard  = "standard"  // 普通话通用（默认）
+	KinshipRegionNorth     = "north"     // 北方
+	KinshipRegionSouth     = "south"     // 南方
+	KinshipRegionCantonese = "cantonese" // 粤语
+)
+
+// 各地区与通用称谓不同的叫法
+var kinshipRegionTerms = map[string]map[string]string{
+	KinshipRegionNorth: {
+		"父亲": "爸爸", "母亲": "妈妈",
+		"祖父": "爷爷", "祖母": "奶奶", "外祖父": "姥爷", "外祖母": "姥姥",
+		"曾祖父": "太爷爷", "曾祖母": "太奶奶", "外曾祖父": "太姥爷", "外曾祖母": "太姥姥",
+		"伯父": "大爷", "伯母": "大娘", "姨妈": "姨",
+	},
+	KinshipRegionSouth: {
+		"父亲": "爸爸", "母亲": "妈妈",
+		"祖父": "爷爷", "祖母": "奶奶", "外祖父": "外公", "外祖母": "外婆",
+		"曾祖父": "太公", "曾祖母": "太婆", "外曾祖父": "外太公", "外曾祖母": "外太婆",
+		"伯父": "伯伯", "姨妈": "阿姨",
+	},
+	KinshipRegionCantonese: {
+		"父亲": "爸爸", "母亲": "妈妈",
+		"祖父": "阿爷", "祖母": "阿嫲", "外祖父": "阿公", "外祖母": "阿婆",
+		"曾祖父": "太公", "曾祖母": "太嫲", "外曾祖父": "太公", "外曾祖母": "太婆",
+		"伯父": "阿伯", "伯母": "伯娘", "叔叔": "阿叔", "婶婶": "阿婶", "姑姑": "姑妈",
+		"舅舅": "舅父", "舅妈": "妗母", "姐姐": "家姐",
+		"公公": "家公", "婆婆": "家婆", "岳父": "外父", "岳母": "外母",
+	},
+}
+
+// IsValidKinshipRegion 检查是否为支持的称谓地区
+func IsValidKinshipRegion(region string) bool {
+	return region == KinshipRegionStandard || kinshipRegionTerms[region] != nil
+}
+
+// KinshipPerson 计算称谓时用到的个人信息
+type KinshipPerson struct {
+	Gender    string // male | female，未知时为空
+	BirthDate *time.Time
+}
+
+// KinshipStep 关系路径上的一步，Relation 为 GenealogyStepParent 等，表示 Person 是上一个人的父母、子女或配偶
+type KinshipStep struct {
+	Relation   string
+	ParentType string
+	Person     KinshipPerson
+}
+
+// KinshipTerm 按关系路径计算 self 对路径终点的称谓
+//
+// 区分父系和母系、长幼和性别；性别或长幼无法确定时列出所有可能，以“/”分隔，如“哥哥/弟弟”。
+// 没有对应称谓的远亲按路径描述，如“母亲的丈夫的父亲”。
+func KinshipTerm(self KinshipPerson, steps []KinshipStep, region string) string {
+	if len(steps) == 0 {
+		return "本人"
+	}
+	terms := kinshipTerms(self, steps)
+	if len(terms) == 0 {
+		return kinshipDescribe(steps)
+	}
+
+	override := kinshipRegionTerms[region]
+	seen := map[string]bool{}
+	var result []string
+	for _, term := range terms {
+		if r, ok := override[term]; ok {
+			term = r
+		}
+		if !seen[term] {
+			seen[term] = true
+			result = append(result, term)
+		}
+	}
+	return strings.Join(result, "/")
+}
+
+// 性别、长幼的三态判断
+type kinBool int8
+
+const (
+	kinUnknown kinBool = iota
+	kinYes
+	kinNo
+)
+
+func kinMale(p KinshipPerson) kinBool {
+	switch p.Gender {
+	case "male":
+		return kinYes
+	case "female":
+		return kinNo
+	}
+	return kinUnknown
+}
+
+// kinOlder a 是否比 b 年长
+func kinOlder(a, b KinshipPerson) kinBool {
+	if a.BirthDate == nil || b.BirthDate == nil || a.BirthDate.Equal(*b.BirthDate) {
+		return kinUnknown
+	}
+	if a.BirthDate.Before(*b.BirthDate) {
+		return kinYes
+	}
+	return kinNo
+}
+
+func (b kinBool) and(o kinBool) kinBool {
+	if b == kinNo || o == kinNo {
+		return kinNo
+	}
+	if b == kinYes && o == kinYes {
+		return kinYes
+	}
+	return kinUnknown
+}
+
+func (b kinBool) not() kinBool {
+	switch b {
+	case kinYes:
+		return kinNo
+	case kinNo:
+		return kinYes
+	}
+	return kinUnknown
+}
+
+// kinTerms 候选称谓
+type kinTerms []string
+
+func kt(terms ...string) kinTerms {
+	return terms
+}
+
+// kinPick 按条件选择称谓，条件未知时两者都保留
+func kinPick(c kinBool, yes, no kinTerms) kinTerms {
+	switch c {
+	case kinYes:
+		return yes
+	case kinNo:
+		return no
+	}
+	return append(append(kinTerms{}, yes...), no...)
+}
+
+// then 拼接称谓的前后两部分
+func (a kinTerms) then(b kinTerms) kinTerms {
+	var result kinTerms
+	for _, x := range a {
+		for _, y := range b {
+			result = append(result, x+y)
+		}
+	}
+	return result
+}
+
+// kinPath 先向上若干代到共同祖先、再向下若干代的血亲路径
+type kinPath struct {
+	self  KinshipPerson
+	ups   []KinshipStep
+	downs []KinshipStep
+}
+
+func (p kinPath) target() KinshipPerson {
+	if len(p.downs) > 0 {
+		return p.downs[len(p.downs)-1].Person
+	}
+	if len(p.ups) > 0 {
+		return p.ups[len(p.ups)-1].Person
+	}
+	return p.self
+}
+
+func parseKinPath(self KinshipPerson, steps []KinshipStep) (kinPath, int) {
+	p := kinPath{self: self}
+	i := 0
+	for ; i < len(steps) && steps[i].Relation == GenealogyStepParent; i++ {
+		p.ups = append(p.ups, steps[i])
+	}
+	for ; i < len(steps) && steps[i].Relation == GenealogyStepChild; i++ {
+		p.downs = append(p.downs, steps[i])
+	}
+	return p, i
+}
+
+// kinPaternalLine 路径上的人是否都是男性（同姓一脉）
+func kinPaternalLine(steps []KinshipStep) kinBool {
+	line := kinYes
+	for _, s := range steps {
+		line = line.and(kinMale(s.Person))
+	}
+	return line
+}
+
+func kinshipTerms(self KinshipPerson, steps []KinshipStep) kinTerms {
+	if steps[0].Relation == GenealogyStepSpouse {
+		spouse := steps[0].Person
+		blood, n := parseKinPath(spouse, steps[1:])
+		return kinshipInLaw(self, spouse, blood, steps[1+n:])
+	}
+
+	blood, n := parseKinPath(self, steps)
+	rest := steps[n:]
+	switch {
+	case len(rest) == 0:
+		return kinshipBlood(blood)
+	case len(rest) == 1 && rest[0].Relation == GenealogyStepSpouse:
+		return kinshipSpouseOf(blood, rest[0].Person)
+	case len(rest) == 2 && rest[0].Relation == GenealogyStepSpouse && rest[1].Relation == GenealogyStepParent &&
+		len(blood.ups) == 0 && len(blood.downs) == 1:
+		// 子女配偶的父母
+		return kinPick(kinMale(rest[1].Person), kt("亲家公"), kt("亲家母"))
+	}
+	return nil
+}
+
+// 经由配偶的亲属，没有专门称谓的随配偶称呼
+func kinshipInLaw(self, spouse KinshipPerson, blood kinPath, rest []KinshipStep) kinTerms {
+	// 本人为丈夫时是妻子一方的亲属
+	husband := kinMale(self)
+	if husband == kinUnknown {
+		husband = kinMale(spouse).not()
+	}
+	u, d := len(blood.ups), len(blood.downs)
+	t := blood.target()
+	male := kinMale(t)
+
+	switch {
+	case len(rest) == 0 && u == 0 && d == 0:
+		return kinPick(kinMale(spouse), kt("丈夫"), kt("妻子"))
+	case len(rest) == 0 && u == 1 && d == 0:
+		return kinPick(husband, kinPick(male, kt("岳父"), kt("岳母")), kinPick(male, kt("公公"), kt("婆婆")))
+	case len(rest) == 0 && u == 0 && d == 1:
+		return kinPick(male, kt("继子"), kt("继女"))
+	case len(rest) == 0 && u == 1 && d == 1:
+		older := kinOlder(t, spouse)
+		return kinPick(husband,
+			kinPick(male, kinPick(older, kt("大舅子"), kt("小舅子")), kinPick(older, kt("大姨子"), kt("小姨子"))),
+			kinPick(male, kinPick(older, kt("大伯子"), kt("小叔子")), kinPick(older, kt("大姑子"), kt("小姑子"))))
+	case len(rest) == 1 && rest[0].Relation == GenealogyStepSpouse && u == 1 && d == 1:
+		sibling := kinMale(t)
+		if husband == kinYes && sibling == kinNo {
+			return kt("连襟")
+		}
+		if husband == kinYes && sibling == kinYes {
+			return kt("舅嫂")
+		}
+		if husband == kinNo && sibling == kinYes {
+			return kt("妯娌")
+		}
+	case len(rest) == 0 && u == 1 && d == 2 && husband == kinYes && kinMale(blood.downs[0].Person) == kinYes:
+		return kinPick(male, kt("内侄"), kt("内侄女"))
+	}
+
+	switch {
+	case len(rest) == 0:
+		return kinshipBlood(blood)
+	case len(rest) == 1 && rest[0].Relation == GenealogyStepSpouse:
+		return kinshipSpouseOf(blood, rest[0].Person)
+	}
+	return nil
+}
+
+func kinshipBlood(p kinPath) kinTerms {
+	u, d := len(p.ups), len(p.downs)
+	male := kinMale(p.target())
+	switch {
+	case u == 0 && d == 0:
+		return kt("本人")
+	case d == 0:
+		return kinAncestor(p.ups, male)
+	case u == 0:
+		return kinDescendant(p.downs, male)
+	case u == d:
+		return kinSameGeneration(p, male)
+	case d < u:
+		return kinElder(p, male, false)
+	default:
+		return kinJunior(p, male, false)
+	}
+}
+
+// 血亲 X 的配偶（X 为 p 的终点）
+func kinshipSpouseOf(p kinPath, spouse KinshipPerson) kinTerms {
+	u, d := len(p.ups), len(p.downs)
+	x := p.target()
+	xMale := kinMale(x)
+	if xMale == kinUnknown {
+		xMale = kinMale(spouse).not()
+	}
+
+	switch {
+	case u == 0 && d == 0:
+		return nil
+	case d == 0:
+		// 长辈的配偶但不是自己的长辈
+		if u == 1 {
+			return kinPick(kinMale(spouse), kt("继父"), kt("继母"))
+		}
+		return kt("继").then(kinAncestor(p.ups, kinMale(spouse)))
+	case u == 0:
+		if d == 1 {
+			return kinPick(xMale, kt("儿媳"), kt("女婿"))
+		}
+		if d >= len(kinDescendantLevels) {
+			return nil
+		}
+		level := kinDescendantLevels[d]
+		stem := kinPick(kinMale(p.downs[0].Person), kt(level), kt("外"+level))
+		return stem.then(kinPick(xMale, kt("媳"), kt("女婿")))
+	case u == 1 && d == 1:
+		older := kinOlder(x, p.self)
+		return kinPick(xMale, kinPick(older, kt("嫂子"), kt("弟媳")), kinPick(older, kt("姐夫"), kt("妹夫")))
+	case u == 2 && d == 2:
+		older := kinOlder(x, p.self)
+		tang := kinMale(p.ups[0].Person).and(kinMale(p.downs[0].Person))
+		forms := kinPick(xMale, kinPick(older, kt("嫂"), kt("弟媳")), kinPick(older, kt("姐夫"), kt("妹夫")))
+		return kinPick(tang, kt("堂"), kt("表")).then(forms)
+	case d < u:
+		return kinElder(p, xMale, true)
+	case d > u:
+		return kinJunior(p, xMale, true)
+	}
+	return nil
+}
+
+var kinAncestorLevels = []string{"", "", "祖", "曾祖", "高祖", "天祖", "烈祖", "太祖", "远祖", "鼻祖"}
+
+var kinDescendantLevels = []string{"", "", "孙", "曾孙", "玄孙", "来孙", "晜孙", "仍孙", "云孙", "耳孙"}
+
+// 直系长辈：父亲、祖父、外祖父、曾外祖父……
+func kinAncestor(ups []KinshipStep, male kinBool) kinTerms {
+	u := len(ups)
+	if u == 1 {
+		switch ups[0].ParentType {
+		case GenealogyParentAdopted:
+			return kinPick(male, kt("养父"), kt("养母"))
+		case GenealogyParentStep:
+			return kinPick(male, kt("继父"), kt("继母"))
+		}
+		return kinPick(male, kt("父亲"), kt("母亲"))
+	}
+	if u >= len(kinAncestorLevels) {
+		return nil
+	}
+
+	level := kinAncestorLevels[u]
+	// 父系中间经过女性时称“曾外祖父”等，母系称“外曾祖父”等
+	paternal := level
+	for _, s := range ups[1 : u-1] {
+		if kinMale(s.Person) == kinNo {
+			paternal = strings.Replace(level, "祖", "外祖", 1)
+			break
+		}
+	}
+	stem := kinPick(kinMale(ups[0].Person), kt(paternal), kt("外"+level))
+	return stem.then(kinPick(male, kt("父"), kt("母")))
+}
+
+// 直系晚辈：儿子、孙子、外孙女……
+func kinDescendant(downs []KinshipStep, male kinBool) kinTerms {
+	d := len(downs)
+	if d == 1 {
+		switch downs[0].ParentType {
+		case GenealogyParentAdopted:
+			return kinPick(male, kt("养子"), kt("养女"))
+		case GenealogyParentStep:
+			return kinPick(male, kt("继子"), kt("继女"))
+		}
+		return kinPick(male, kt("儿子"), kt("女儿"))
+	}
+	if d >= len(kinDescendantLevels) {
+		return nil
+	}
+
+	level := kinDescendantLevels[d]
+	son := level
+	if d == 2 {
+		son = "孙子"
+	}
+	return kinPick(kinMale(downs[0].Person),
+		kinPick(male, kt(son), kt(level+"女")),
+		kinPick(male, kt("外"+level), kt("外"+level+"女")))
+}
+
+// 同辈：兄弟姐妹、堂表兄弟姐妹
+func kinSameGeneration(p kinPath, male kinBool) kinTerms {
+	u := len(p.ups)
+	older := kinOlder(p.target(), p.self)
+	if u == 1 {
+		return kinPick(male, kinPick(older, kt("哥哥"), kt("弟弟")), kinPick(older, kt("姐姐"), kt("妹妹")))
+	}
+
+	sibling := kinPick(male, kinPick(older, kt("哥"), kt("弟")), kinPick(older, kt("姐"), kt("妹")))
+	// 父亲的兄弟的子女为堂亲，其余为表亲
+	line := kinPaternalLine(p.ups[:u-1]).and(kinPaternalLine(p.downs[:len(p.downs)-1]))
+	switch u {
+	case 2:
+		return kinPick(line, kt("堂"), kt("表")).then(sibling)
+	case 3:
+		return kinPick(line, kt("从堂"), kt("表")).then(sibling)
+	}
+	if line != kinYes {
+		return nil
+	}
+	return kt("族").then(kinPick(male, kinPick(older, kt("兄"), kt("弟")), kinPick(older, kt("姐"), kt("妹"))))
+}
+
+// 旁系长辈及其配偶：伯父、姑姑、舅妈、伯祖父、表姑……
+func kinElder(p kinPath, xMale kinBool, spouse bool) kinTerms {
+	u, d := len(p.ups), len(p.downs)
+	gap := u - d
+	// 与 X 同辈的自己的直系长辈
+	ref := p.ups[gap-1].Person
+	older := kinOlder(p.target(), ref)
+	fatherSide := kinMale(p.ups[0].Person)
+
+	switch {
+	case d == 1 && gap == 1:
+		if spouse {
+			return kinPick(fatherSide,
+				kinPick(xMale, kinPick(older, kt("伯母"), kt("婶婶")), kt("姑父")),
+				kinPick(xMale, kt("舅妈"), kt("姨父")))
+		}
+		return kinPick(fatherSide,
+			kinPick(xMale, kinPick(older, kt("伯父"), kt("叔叔")), kt("姑姑")),
+			kinPick(xMale, kt("舅舅"), kt("姨妈")))
+	case d == 1 && (gap == 2 || gap == 3):
+		prefix := ""
+		if gap == 3 {
+			prefix = "曾"
+		}
+		maleSuffix, femaleSuffix := "父", "母"
+		if spouse {
+			maleSuffix, femaleSuffix = "母", "父"
+		}
+		refMale := kinMale(ref)
+		forms := kinPick(xMale,
+			kinPick(refMale, kinPick(older, kt("伯祖"), kt("叔祖")), kt("舅祖")).then(kt(maleSuffix)),
+			kinPick(refMale, kt("姑祖"), kt("姨祖")).then(kt(femaleSuffix)))
+		return kinPick(fatherSide, kt(""), kt("外")).then(kt(prefix)).then(forms)
+	case d == 2 && gap == 1:
+		tang := kinMale(p.ups[1].Person).and(kinMale(p.downs[0].Person))
+		var forms kinTerms
+		if spouse {
+			forms = kinPick(fatherSide,
+				kinPick(xMale, kinPick(older, kt("伯母"), kt("婶")), kt("姑父")),
+				kinPick(xMale, kt("舅妈"), kt("姨父")))
+		} else {
+			forms = kinPick(fatherSide,
+				kinPick(xMale, kinPick(older, kt("伯"), kt("叔")), kt("姑")),
+				kinPick(xMale, kt("舅"), kt("姨")))
+		}
+		return kinPick(tang, kt("堂"), kt("表")).then(forms)
+	}
+	return nil
+}
+
+// 旁系晚辈及其配偶：侄子、外甥女、侄孙、堂侄、侄媳……
+func kinJunior(p kinPath, xMale kinBool, spouse bool) kinTerms {
+	u, d := len(p.ups), len(p.downs)
+	gap := d - u
+
+	var stems kinTerms
+	switch {
+	case u == 1 && gap <= 2:
+		stems = kinPick(kinMale(p.downs[0].Person), kt("侄"), kt("外甥"))
+		if gap == 2 {
+			stems = stems.then(kt("孙"))
+		}
+	case u == 2 && gap == 1:
+		tang := kinMale(p.ups[0].Person).and(kinMale(p.downs[0].Person))
+		stems = kinPick(tang, kt("堂"), kt("表")).then(kinPick(kinMale(p.downs[1].Person), kt("侄"), kt("外甥")))
+	default:
+		return nil
+	}
+
+	var males, females kinTerms
+	for _, stem := range stems {
+		switch {
+		case spouse:
+			males = append(males, stem+"媳")
+			females = append(females, stem+"女婿")
+		case stem == "侄":
+			males = append(males, "侄子")
+			females = append(females, "侄女")
+		default:
+			males = append(males, stem)
+			females = append(females, stem+"女")
+		}
+	}
+	return kinPick(xMale, males, females)
+}
+
+// 没有对应称谓时按路径描述
+func kinshipDescribe(steps []KinshipStep) string {
+	parts := make([]string, 0, len(steps))
+	for _, s := range steps {
+		male := kinMale(s.Person)
+		var words [3]string
+		switch s.Relation {
+		case GenealogyStepParent:
+			words = [3]string{"父亲", "母亲", "父母"}
+		case GenealogyStepChild:
+			words = [3]string{"儿子", "女儿", "子女"}
+		default:
+			words = [3]string{"丈夫", "妻子", "配偶"}
+		}
+		switch male {
+		case kinYes:
+			parts = append(parts, words[0])
+		case kinNo:
+			parts = append(parts, words[1])
+		default:
+			parts = append(parts, words[2])
+		}
+	}
+	return strings.Join(parts, "的")
+}
@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func kinshipFixture() (map[string]KinshipPerson, *GenealogyGraph) {
+	born := func(year int) *time.Time {
+		t := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+		return &t
+	}
+	male := func(year int) KinshipPerson {
+		p := KinshipPerson{Gender: "male"}
+		if year > 0 {
+			p.BirthDate = born(year)
+		}
+		return p
+	}
+	female := func(year int) KinshipPerson {
+		p := KinshipPerson{Gender: "female"}
+		if year > 0 {
+			p.BirthDate = born(year)
+		}
+		return p
+	}
+	persons := map[string]KinshipPerson{
+		"ggf": male(1900), "gf": male(1932), "gm": female(1934), "gfb": male(1930), "gfbd": female(1958),
+		"mgf": male(1935), "mgm": female(1936), "mggm": female(1910),
+		"f": male(1960), "m": female(1962), "u": male(1955), "a": female(1964), "mu": male(1958), "mw": female(1959),
+		"me": male(1990), "sis": female(1985), "bro": male(1995), "bro2": male(0), "uc": male(1988), "ac": female(1992),
+		"w": female(1991), "wf": male(1965), "wb": male(1988),
+		"sh": male(1983), "ss": male(2010),
+		"s": male(2015), "sw": female(2016), "swf": male(1985),
+		"ad": female(2018),
+	}
+
+	parent := func(from, to string) GenealogyEdge {
+		return GenealogyEdge{Type: GenealogyRelationParent, FromID: from, ToID: to, ParentType: GenealogyParentBiological}
+	}
+	spouse := func(a, b string) GenealogyEdge {
+		return GenealogyEdge{Type: GenealogyRelationSpouse, FromID: a, ToID: b}
+	}
+	adopted := parent("me", "ad")
+	adopted.ParentType = GenealogyParentAdopted
+	graph := NewGenealogyGraph([]GenealogyEdge{
+		parent("ggf", "gf"), parent("ggf", "gfb"), parent("gfb", "gfbd"),
+		parent("gf", "f"), parent("gm", "f"), parent("gf", "u"), parent("gf", "a"),
+		parent("mggm", "mgm"), parent("mgf", "m"), parent("mgm", "m"), parent("mgf", "mu"), spouse("mu", "mw"),
+		parent("f", "me"), parent("m", "me"), parent("f", "sis"), parent("f", "bro"), parent("f", "bro2"),
+		parent("u", "uc"), parent("a", "ac"),
+		spouse("me", "w"), parent("wf", "w"), parent("wf", "wb"),
+		spouse("sis", "sh"), parent("sis", "ss"),
+		parent("me", "s"), spouse("s", "sw"), parent("swf", "sw"),
+		adopted,
+	})
+	return persons, graph
+}
+
+func TestKinshipTerm(t *testing.T) {
+	persons, graph := kinshipFixture()
+	paths := graph.PathsFrom("me")
+	term := func(id, region string) string {
+		path, ok := paths[id]
+		if !assert.True(t, ok, id) {
+			return ""
+		}
+		steps := make([]KinshipStep, 0, len(path)-1)
+		for _, s := range path[1:] {
+			steps = append(steps, KinshipStep{Relation: s.Relation, ParentType: s.ParentType, Person: persons[s.ID]})
+		}
+		return KinshipTerm(persons["me"], steps, region)
+	}
+
+	cases := map[string]string{
+		"me": "本人", "f": "父亲", "gf": "祖父", "mgf": "外祖父", "ggf": "曾祖父", "mggm": "外曾祖母",
+		"u": "伯父", "a": "姑姑", "mu": "舅舅", "mw": "舅妈", "gfb": "伯祖父", "gfbd": "堂姑",
+		"sis": "姐姐", "bro": "弟弟", "bro2": "哥哥/弟弟", "uc": "堂哥", "ac": "表妹",
+		"w": "妻子", "wf": "岳父", "wb": "大舅子", "sh": "姐夫", "ss": "外甥",
+		"s": "儿子", "sw": "儿媳", "swf": "亲家公", "ad": "养女",
+	}
+	for id, want := range cases {
+		assert.Equal(t, want, term(id, KinshipRegionStandard), id)
+	}
+
+	assert.Equal(t, "姥姥", term("mgm", KinshipRegionNorth))
+	assert.Equal(t, "外婆", term("mgm", KinshipRegionSouth))
+	assert.Equal(t, "阿嫲", term("gm", KinshipRegionCantonese))
+	assert.Equal(t, "堂哥", term("uc", KinshipRegionNorth))
+}
+
+func TestKinshipTermFallback(t *testing.T) {
+	steps := []KinshipStep{
+		{Relation: GenealogyStepParent, Person: KinshipPerson{Gender: "female"}},
+		{Relation: GenealogyStepSpouse, Person: KinshipPerson{Gender: "male"}},
+		{Relation: GenealogyStepParent, Person: KinshipPerson{}},
+	}
+	assert.Equal(t, "母亲的丈夫的父母", KinshipTerm(KinshipPerson{Gender: "male"}, steps, ""))
+
+	sibling := []KinshipStep{
+		{Relation: GenealogyStepParent, Person: KinshipPerson{Gender: "male"}},
+		{Relation: GenealogyStepChild, Person: KinshipPerson{}},
+	}
+	assert.Equal(t, "哥哥/弟弟/姐姐/妹妹", KinshipTerm(KinshipPerson{}, sibling, ""))
+	assert.True(t, IsValidKinshipRegion(KinshipRegionCantonese))
+	assert.False(t, IsValidKinshipRegion("hakka"))
+}
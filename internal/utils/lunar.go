@@ -0,0 +1,186 @@
+package utils
+
+import "time"
+
+// 农历数据 1900-2100 年，每年一项：
+// 低 4 位为闰月月份（0 表示无闰月），第 5-16 位依次为正月至腊月是否为大月（30天），
+// 第 17 位为闰月是否为大月。
+var lunarInfo = [...]int{
+	0x04bd8, 0x04ae0, 0x0a570, 0x054d5, 0x0d260, 0x0d950, 0x16554, 0x056a0, 0x09ad0, 0x055d2, // 1900-1909
+	0x04ae0, 0x0a5b6, 0x0a4d0, 0x0d250, 0x1d255, 0x0b540, 0x0d6a0, 0x0ada2, 0x095b0, 0x14977, // 1910-1919
+	0x04970, 0x0a4b0, 0x0b4b5, 0x06a50, 0x06d40, 0x1ab54, 0x02b60, 0x09570, 0x052f2, 0x04970, // 1920-1929
+	0x06566, 0x0d4a0, 0x0ea50, 0x16a95, 0x05ad0, 0x02b60, 0x186e3, 0x092e0, 0x1c8d7, 0x0c950, // 1930-1939
+	0x0d4a0, 0x1d8a6, 0x0b550, 0x056a0, 0x1a5b4, 0x025d0, 0x092d0, 0x0d2b2, 0x0a950, 0x0b557, // 1940-1949
+	0x06ca0, 0x0b550, 0x15355, 0x04da0, 0x0a5b0, 0x14573, 0x052b0, 0x0a9a8, 0x0e950, 0x06aa0, // 1950-1959
+	0x0aea6, 0x0ab50, 0x04b60, 0x0aae4, 0x0a570, 0x05260, 0x0f263, 0x0d950, 0x05b57, 0x056a0, // 1960-1969
+	0x096d0, 0x04dd5, 0x04ad0, 0x0a4d0, 0x0d4d4, 0x0d250, 0x0d558, 0x0b540, 0x0b6a0, 0x195a6, // 1970-1979
+	0x095b0, 0x049b0, 0x0a974, 0x0a4b0, 0x0b27a, 0x06a50, 0x06d40, 0x0af46, 0x0ab60, 0x09570, // 1980-1989
+	0x04af5, 0x04970, 0x064b0, 0x074a3, 0x0ea50, 0x06b58, 0x05ac0, 0x0ab60, 0x096d5, 0x092e0, // 1990-1999
+	0x0c960, 0x0d954, 0x0d4a0, 0x0da50, 0x07552, 0x056a0, 0x0abb7, 0x025d0, 0x092d0, 0x0cab5, // 2000-2009
+	0x0a950, 0x0b4a0, 0x0baa4, 0x0ad50, 0x055d9, 0x04ba0, 0x0a5b0, 0x15176, 0x052b0, 0x0a930, // 2010-2019
+	0x07954, 0x06aa0, 0x0ad50, 0x05b52, 0x04b60, 0x0a6e6, 0x0a4e0, 0x0d260, 0x0ea65, 0x0d530, // 2020-2029
+	0x05aa0, 0x076a3, 0x096d0, 0x04afb, 0x04ad0, 0x0a4d0, 0x1d0b6, 0x0d250, 0x0d520, 0x0dd45, // 2030-2039
+	0x0b5a0, 0x056d0, 0x055b2, 0x049b0, 0x0a577, 0x0a4b0, 0x0aa50, 0x1b255, 0x06d20, 0x0ada0, // 2040-2049
+	0x14b63, 0x09370, 0x049f8, 0x04970, 0x064b0, 0x168a6, 0x0ea50, 0x06b20, 0x1a6c4, 0x0aae0, // 2050-2059
+	0x092e0, 0x0d2e3, 0x0c960, 0x0d557, 0x0d4a0, 0x0da50, 0x05d55, 0x056a0, 0x0a6d0, 0x055d4, // 2060-2069
+	0x052d0, 0x0a9b8, 0x0a950, 0x0b4a0, 0x0b6a6, 0x0ad50, 0x055a0, 0x0aba4, 0x0a5b0, 0x052b0, // 2070-2079
+	0x0b273, 0x06930, 0x07337, 0x06aa0, 0x0ad50, 0x14b55, 0x04b60, 0x0a570, 0x054e4, 0x0d160, // 2080-2089
+	0x0e968, 0x0d520, 0x0daa0, 0x16aa6, 0x056d0, 0x04ae0, 0x0a9d4, 0x0a2d0, 0x0d150, 0x0f252, // 2090-2099
+	0x0d520, // 2100
+}
+
+// 支持换算的农历年份范围
+const (
+	LunarMinYear = 1900
+	LunarMaxYear = 2100
+)
+
+// 农历 1900 年正月初一
+var lunarEpoch = time.Date(1900, 1, 31, 0, 0, 0, 0, time.UTC)
+
+// LunarDate 农历日期
+type LunarDate struct {
+	Year   int
+	Month  int
+	Day    int
+	IsLeap bool // 是否为闰月
+}
+
+// LunarLeapMonth 返回农历年的闰月月份，没有闰月时返回 0
+func LunarLeapMonth(year int) int {
+	return lunarInfo[year-LunarMinYear] & 0xf
+}
+
+// LunarMonthDays 返回农历月的天数（29 或 30），leap 表示闰月
+func LunarMonthDays(year, month int, leap bool) int {
+	info := lunarInfo[year-LunarMinYear]
+	if leap {
+		if info&0x10000 != 0 {
+			return 30
+		}
+		return 29
+	}
+	if info&(0x10000>>uint(month)) != 0 {
+		return 30
+	}
+	return 29
+}
+
+func lunarYearDays(year int) int {
+	days := 0
+	for month := 1; month <= 12; month++ {
+		days += LunarMonthDays(year, month, false)
+	}
+	if LunarLeapMonth(year) != 0 {
+		days += LunarMonthDays(year, 0, true)
+	}
+	return days
+}
+
+// SolarToLunar 公历转农历，只看 t 的年月日；超出 1900-2100 年时返回 false
+func SolarToLunar(t time.Time) (LunarDate, bool) {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	offset := int(day.Sub(lunarEpoch).Hours() / 24)
+	if offset < 0 {
+		return LunarDate{}, false
+	}
+
+	year := LunarMinYear
+	for ; year <= LunarMaxYear; year++ {
+		days := lunarYearDays(year)
+		if offset < days {
+			break
+		}
+		offset -= days
+	}
+	if year > LunarMaxYear {
+		return LunarDate{}, false
+	}
+
+	leapMonth := LunarLeapMonth(year)
+	for month := 1; month <= 12; month++ {
+		days := LunarMonthDays(year, month, false)
+		if offset < days {
+			return LunarDate{Year: year, Month: month, Day: offset + 1}, true
+		}
+		offset -= days
+		if month == leapMonth {
+			days = LunarMonthDays(year, month, true)
+			if offset < days {
+				return LunarDate{Year: year, Month: month, Day: offset + 1, IsLeap: true}, true
+			}
+			offset -= days
+		}
+	}
+	return LunarDate{}, false
+}
+
+// LunarToSolar 农历转公历（UTC 零点），日期不存在（如小月三十、当年没有该闰月）时返回 false
+func LunarToSolar(date LunarDate) (time.Time, bool) {
+	if date.Year < LunarMinYear || date.Year > LunarMaxYear || date.Month < 1 || date.Month > 12 || date.Day < 1 {
+		return time.Time{}, false
+	}
+	leapMonth := LunarLeapMonth(date.Year)
+	if date.IsLeap && leapMonth != date.Month {
+		return time.Time{}, false
+	}
+	if date.Day > LunarMonthDays(date.Year, date.Month, date.IsLeap) {
+		return time.Time{}, false
+	}
+
+	offset := 0
+	for year := LunarMinYear; year < date.Year; year++ {
+		offset += lunarYearDays(year)
+	}
+	for month := 1; month < date.Month; month++ {
+		offset += LunarMonthDays(date.Year, month, false)
+		if month == leapMonth {
+			offset += LunarMonthDays(date.Year, month, true)
+		}
+	}
+	if date.IsLeap {
+		offset += LunarMonthDays(date.Year, date.Month, false)
+	}
+	offset += date.Day - 1
+	return lunarEpoch.AddDate(0, 0, offset), true
+}
+
+var (
+	lunarMonthNames = [...]string{"正", "二", "三", "四", "五", "六", "七", "八", "九", "十", "冬", "腊"}
+	lunarDigits     = [...]string{"一", "二", "三", "四", "五", "六", "七", "八", "九", "十"}
+)
+
+// LunarMonthName 农历月份名称，如“正月”“冬月”
+func LunarMonthName(month int, leap bool) string {
+	if month < 1 || month > 12 {
+		return ""
+	}
+	name := lunarMonthNames[month-1] + "月"
+	if leap {
+		name = "闰" + name
+	}
+	return name
+}
+
+// LunarDayName 农历日名称，如“初一”“十五”“廿九”
+func LunarDayName(day int) string {
+	switch {
+	case day < 1 || day > 30:
+		return ""
+	case day <= 10:
+		return "初" + lunarDigits[day-1]
+	case day < 20:
+		return "十" + lunarDigits[day-11]
+	case day == 20:
+		return "二十"
+	case day < 30:
+		return "廿" + lunarDigits[day-21]
+	default:
+		return "三十"
+	}
+}
+
+// String 农历日期的中文写法，如“闰四月十五”
+func (d LunarDate) String() string {
+	return LunarMonthName(d.Month, d.IsLeap) + LunarDayName(d.Day)
+}
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLunarToSolar(t *testing.T) {
+	// 春节
+	for year, want := range map[int]string{
+		1900: "1900-01-31", 1950: "1950-02-17", 2000: "2000-02-05", 2023: "2023-01-22",
+		2024: "2024-02-10", 2025: "2025-01-29", 2026: "2026-02-17", 2100: "2100-02-09",
+	} {
+		date, ok := LunarToSolar(LunarDate{Year: year, Month: 1, Day: 1})
+		require.True(t, ok)
+		assert.Equal(t, want, date.Format("2006-01-02"), "%d 年春节", year)
+	}
+
+	// 2025 年闰六月
+	date, ok := LunarToSolar(LunarDate{Year: 2025, Month: 6, Day: 1, IsLeap: true})
+	require.True(t, ok)
+	assert.Equal(t, "2025-07-25", date.Format("2006-01-02"))
+
+	_, ok = LunarToSolar(LunarDate{Year: 2024, Month: 6, Day: 1, IsLeap: true})
+	assert.False(t, ok, "2024 年没有闰六月")
+	_, ok = LunarToSolar(LunarDate{Year: 2024, Month: 12, Day: 30})
+	assert.False(t, ok, "2024 年腊月是小月")
+	_, ok = LunarToSolar(LunarDate{Year: 1899, Month: 1, Day: 1})
+	assert.False(t, ok)
+}
+
+func TestSolarToLunar(t *testing.T) {
+	date, ok := SolarToLunar(time.Date(2024, 9, 17, 20, 0, 0, 0, time.Local))
+	require.True(t, ok)
+	assert.Equal(t, LunarDate{Year: 2024, Month: 8, Day: 15}, date)
+	assert.Equal(t, "八月十五", date.String())
+
+	date, ok = SolarToLunar(time.Date(2033, 12, 22, 0, 0, 0, 0, time.UTC))
+	require.True(t, ok)
+	assert.Equal(t, "闰冬月初一", date.String())
+
+	_, ok = SolarToLunar(time.Date(1900, 1, 30, 0, 0, 0, 0, time.UTC))
+	assert.False(t, ok)
+
+	// 逐日往返换算
+	for day := time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC); day.Year() < 2040; day = day.AddDate(0, 0, 1) {
+		lunar, ok := SolarToLunar(day)
+		require.True(t, ok)
+		back, ok := LunarToSolar(lunar)
+		require.True(t, ok)
+		require.Equal(t, day, back, "%s", lunar)
+	}
+}
+
+func TestLunarNames(t *testing.T) {
+	assert.Equal(t, "正月初一", LunarDate{Month: 1, Day: 1}.String())
+	assert.Equal(t, "腊月廿九", LunarDate{Month: 12, Day: 29}.String())
+	assert.Equal(t, "闰四月二十", LunarDate{Month: 4, Day: 20, IsLeap: true}.String())
+	assert.Equal(t, "十月三十", LunarDate{Month: 10, Day: 30}.String())
+	assert.Equal(t, "十一", LunarDayName(11))
+}
@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+// ErrMediaToolUnavailable 服务器未安装 ffmpeg 时返回
+var ErrMediaToolUnavailable = errors.New("媒体处理工具不可用")
+
+// DefaultWaveformPoints 祈福墙播放条默认采样点数
+const DefaultWaveformPoints = 64
+
+// GenerateWaveform 生成音频波形峰值（0-1），用于播放条展示
+// PCM WAV 直接读取采样，其它格式借助 ffmpeg 解码为单声道 PCM
+func GenerateWaveform(path string, probe *MediaProbeResult, points int) ([]float64, error) {
+	if points <= 0 {
+		points = DefaultWaveformPoints
+	}
+
+	if probe.Container == "wav" && probe.AudioCodec == "pcm" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		format, err := readWAVFormat(f)
+		if err != nil {
+			return nil, err
+		}
+		if format.BitsPerSample != 16 {
+			return nil, fmt.Errorf("%w: %d位PCM", ErrUnsupportedMediaFormat, format.BitsPerSample)
+		}
+		if _, err := f.Seek(format.DataOffset, io.SeekStart); err != nil {
+			return nil, err
+		}
+		totalSamples := format.DataSize / int64(format.BlockAlign)
+		return waveformPeaks(io.LimitReader(f, format.DataSize), int(format.Channels), totalSamples, points)
+	}
+
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return nil, ErrMediaToolUnavailable
+	}
+
+	// 8kHz 单声道足够绘制波形
+	const sampleRate = 8000
+	cmd := exec.Command(ffmpeg, "-v", "error", "-i", path, "-ac", "1", "-ar", strconv.Itoa(sampleRate), "-f", "s16le", "-")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("启动ffmpeg失败: %v", err)
+	}
+
+	totalSamples := int64(probe.Duration * sampleRate)
+	peaks, peakErr := waveformPeaks(stdout, 1, totalSamples, points)
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("解码音频失败: %v", err)
+	}
+	return peaks, peakErr
+}
+
+// waveformPeaks 将 16 位小端 PCM 分桶并取每桶峰值
+func waveformPeaks(r io.Reader, channels int, totalSamples int64, points int) ([]float64, error) {
+	if channels <= 0 {
+		channels = 1
+	}
+	if totalSamples <= 0 {
+		return nil, ErrCorruptedMedia
+	}
+
+	bucketSize := totalSamples / int64(points)
+	if bucketSize < 1 {
+		bucketSize = 1
+	}
+
+	peaks := make([]float64, 0, points)
+	reader := bufio.NewReader(r)
+	frame := make([]byte, 2*channels)
+	var peak float64
+	var count int64
+
+	for {
+		if _, err := io.ReadFull(reader, frame); err != nil {
+			break
+		}
+		for ch := 0; ch < channels; ch++ {
+			sample := math.Abs(float64(int16(binary.LittleEndian.Uint16(frame[ch*2:])))) / 32768.0
+			if sample > peak {
+				peak = sample
+			}
+		}
+		count++
+		if count == bucketSize {
+			if len(peaks) < points {
+				peaks = append(peaks, math.Round(peak*100)/100)
+			}
+			peak, count = 0, 0
+		}
+	}
+	if count > 0 && len(peaks) < points {
+		peaks = append(peaks, math.Round(peak*100)/100)
+	}
+
+	return peaks, nil
+}
+
+// GeneratePosterFrame 截取视频画面作为封面图
+func GeneratePosterFrame(videoPath, outputPath string, probe *MediaProbeResult) error {
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return ErrMediaToolUnavailable
+	}
+
+	// 默认取第1秒，过短的视频取中间帧
+	at := 1.0
+	if probe.Duration < 2 {
+		at = probe.Duration / 2
+	}
+
+	cmd := exec.Command(ffmpeg, "-v", "error", "-y",
+		"-ss", strconv.FormatFloat(at, 'f', 2, 64),
+		"-i", videoPath,
+		"-frames:v", "1",
+		"-vf", "scale='min(720,iw)':-2",
+		outputPath)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("生成视频封面失败: %v %s", err, string(output))
+	}
+	return nil
+}
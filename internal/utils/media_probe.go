@@ -0,0 +1,550 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// 媒体探测相关错误
+var (
+	ErrUnsupportedMediaFormat = errors.New("不支持的媒体格式")
+	ErrCorruptedMedia         = errors.New("媒体文件已损坏或无法解析")
+)
+
+// moov 盒子允许读取的最大字节数，避免异常文件耗尽内存
+const maxMoovSize = 16 * 1024 * 1024
+
+// MediaProbeResult 媒体文件探测结果（基于容器头信息，而非客户端上报）
+type MediaProbeResult struct {
+	Container  string  `json:"container"`   // mp4|mov|mp3|wav
+	MediaKind  string  `json:"media_kind"`  // audio|video
+	VideoCodec string  `json:"video_codec"` // h264|hevc|...
+	AudioCodec string  `json:"audio_codec"` // aac|mp3|pcm|...
+	Duration   float64 `json:"duration"`    // 时长（秒）
+	Width      int     `json:"width"`
+	Height     int     `json:"height"`
+	SampleRate int     `json:"sample_rate"`
+	Channels   int     `json:"channels"`
+}
+
+// Codec 返回主编码格式（视频优先）
+func (r *MediaProbeResult) Codec() string {
+	if r.VideoCodec != "" {
+		return r.VideoCodec
+	}
+	return r.AudioCodec
+}
+
+// DurationSeconds 返回向上取整的时长（秒）
+func (r *MediaProbeResult) DurationSeconds() int {
+	return int(math.Ceil(r.Duration))
+}
+
+// 小程序端可直接播放的编码组合
+var playableCodecs = map[string]map[string]bool{
+	"video": {"h264": true, "hevc": true},
+	"audio": {"aac": true, "mp3": true, "pcm": true},
+}
+
+// ValidatePlayableMedia 检查探测结果是否为指定类型的可播放媒体
+func ValidatePlayableMedia(result *MediaProbeResult, expectedKind string) error {
+	if result.MediaKind != expectedKind {
+		return fmt.Errorf("媒体类型不符，期望%s，实际为%s", expectedKind, result.MediaKind)
+	}
+	if !playableCodecs[expectedKind][result.Codec()] {
+		return fmt.Errorf("%w: %s/%s", ErrUnsupportedMediaFormat, result.Container, result.Codec())
+	}
+	if expectedKind == "video" && result.AudioCodec != "" && !playableCodecs["audio"][result.AudioCodec] {
+		return fmt.Errorf("%w: 音轨编码 %s", ErrUnsupportedMediaFormat, result.AudioCodec)
+	}
+	if result.Duration <= 0 {
+		return ErrCorruptedMedia
+	}
+	return nil
+}
+
+// ProbeMediaFile 读取媒体文件容器头，获取真实时长与编码信息
+func ProbeMediaFile(path string) (*MediaProbeResult, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开媒体文件失败: %v", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("读取媒体文件信息失败: %v", err)
+	}
+
+	return ProbeMedia(f, info.Size())
+}
+
+// ProbeMedia 根据文件魔数识别容器并解析
+func ProbeMedia(r io.ReadSeeker, size int64) (*MediaProbeResult, error) {
+	header := make([]byte, 12)
+	n, err := io.ReadFull(r, header)
+	if err != nil && n < 4 {
+		return nil, ErrCorruptedMedia
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case n >= 8 && string(header[4:8]) == "ftyp":
+		return probeMP4(r, size)
+	case n >= 12 && string(header[0:4]) == "RIFF" && string(header[8:12]) == "WAVE":
+		return probeWAV(r)
+	case string(header[0:3]) == "ID3" || (header[0] == 0xFF && header[1]&0xE0 == 0xE0):
+		return probeMP3(r, size)
+	case string(header[0:4]) == "OggS":
+		return nil, fmt.Errorf("%w: ogg", ErrUnsupportedMediaFormat)
+	case bytes.Equal(header[0:4], []byte{0x1A, 0x45, 0xDF, 0xA3}):
+		return nil, fmt.Errorf("%w: webm/mkv", ErrUnsupportedMediaFormat)
+	case n >= 12 && string(header[0:4]) == "RIFF" && string(header[8:11]) == "AVI":
+		return nil, fmt.Errorf("%w: avi", ErrUnsupportedMediaFormat)
+	case string(header[0:3]) == "FLV":
+		return nil, fmt.Errorf("%w: flv", ErrUnsupportedMediaFormat)
+	case bytes.Equal(header[0:4], []byte{0x30, 0x26, 0xB2, 0x75}):
+		return nil, fmt.Errorf("%w: wmv/asf", ErrUnsupportedMediaFormat)
+	}
+
+	return nil, ErrUnsupportedMediaFormat
+}
+
+// ---------------------------------------------------------------------------
+// MP4 / MOV (ISO BMFF)
+// ---------------------------------------------------------------------------
+
+type mp4Box struct {
+	boxType string
+	payload []byte
+}
+
+// readMP4Boxes 解析一段内存中的子盒子
+func readMP4Boxes(data []byte) []mp4Box {
+	var boxes []mp4Box
+	for len(data) >= 8 {
+		size := uint64(binary.BigEndian.Uint32(data[0:4]))
+		boxType := string(data[4:8])
+		headerSize := uint64(8)
+		if size == 1 {
+			if len(data) < 16 {
+				break
+			}
+			size = binary.BigEndian.Uint64(data[8:16])
+			headerSize = 16
+		} else if size == 0 {
+			size = uint64(len(data))
+		}
+		if size < headerSize || size > uint64(len(data)) {
+			break
+		}
+		boxes = append(boxes, mp4Box{boxType: boxType, payload: data[headerSize:size]})
+		data = data[size:]
+	}
+	return boxes
+}
+
+func findMP4Box(boxes []mp4Box, boxType string) *mp4Box {
+	for i := range boxes {
+		if boxes[i].boxType == boxType {
+			return &boxes[i]
+		}
+	}
+	return nil
+}
+
+func probeMP4(r io.ReadSeeker, size int64) (*MediaProbeResult, error) {
+	result := &MediaProbeResult{Container: "mp4"}
+
+	// 遍历顶层盒子，跳过 mdat 只读取 ftyp 与 moov
+	var offset int64
+	var moov []byte
+	for offset < size {
+		if _, err := r.Seek(offset, io.SeekStart); err != nil {
+			return nil, err
+		}
+		head := make([]byte, 16)
+		if _, err := io.ReadFull(r, head[:8]); err != nil {
+			break
+		}
+		boxSize := int64(binary.BigEndian.Uint32(head[0:4]))
+		boxType := string(head[4:8])
+		headerSize := int64(8)
+		if boxSize == 1 {
+			if _, err := io.ReadFull(r, head[8:16]); err != nil {
+				return nil, ErrCorruptedMedia
+			}
+			boxSize = int64(binary.BigEndian.Uint64(head[8:16]))
+			headerSize = 16
+		} else if boxSize == 0 {
+			boxSize = size - offset
+		}
+		if boxSize < headerSize {
+			return nil, ErrCorruptedMedia
+		}
+
+		switch boxType {
+		case "ftyp":
+			payload := make([]byte, 4)
+			if _, err := io.ReadFull(r, payload); err == nil && string(payload) == "qt  " {
+				result.Container = "mov"
+			}
+		case "moov":
+			if boxSize-headerSize > maxMoovSize {
+				return nil, ErrCorruptedMedia
+			}
+			moov = make([]byte, boxSize-headerSize)
+			if _, err := io.ReadFull(r, moov); err != nil {
+				return nil, ErrCorruptedMedia
+			}
+		}
+		if moov != nil {
+			break
+		}
+		offset += boxSize
+	}
+
+	if moov == nil {
+		return nil, ErrCorruptedMedia
+	}
+
+	children := readMP4Boxes(moov)
+	if mvhd := findMP4Box(children, "mvhd"); mvhd != nil {
+		result.Duration = parseMP4Duration(mvhd.payload)
+	}
+
+	for _, trak := range children {
+		if trak.boxType != "trak" {
+			continue
+		}
+		trakChildren := readMP4Boxes(trak.payload)
+		mdia := findMP4Box(trakChildren, "mdia")
+		if mdia == nil {
+			continue
+		}
+		mdiaChildren := readMP4Boxes(mdia.payload)
+		hdlr := findMP4Box(mdiaChildren, "hdlr")
+		if hdlr == nil || len(hdlr.payload) < 12 {
+			continue
+		}
+		handler := string(hdlr.payload[8:12])
+		codec := ""
+		if minf := findMP4Box(mdiaChildren, "minf"); minf != nil {
+			if stbl := findMP4Box(readMP4Boxes(minf.payload), "stbl"); stbl != nil {
+				if stsd := findMP4Box(readMP4Boxes(stbl.payload), "stsd"); stsd != nil && len(stsd.payload) >= 16 {
+					codec = mp4CodecName(string(stsd.payload[12:16]))
+				}
+			}
+		}
+
+		switch handler {
+		case "vide":
+			if result.VideoCodec == "" {
+				result.VideoCodec = codec
+				if tkhd := findMP4Box(trakChildren, "tkhd"); tkhd != nil && len(tkhd.payload) >= 8 {
+					p := tkhd.payload
+					result.Width = int(binary.BigEndian.Uint32(p[len(p)-8:len(p)-4]) >> 16)
+					result.Height = int(binary.BigEndian.Uint32(p[len(p)-4:]) >> 16)
+				}
+			}
+		case "soun":
+			if result.AudioCodec == "" {
+				result.AudioCodec = codec
+			}
+		}
+	}
+
+	switch {
+	case result.VideoCodec != "":
+		result.MediaKind = "video"
+	case result.AudioCodec != "":
+		result.MediaKind = "audio"
+	default:
+		return nil, ErrCorruptedMedia
+	}
+
+	return result, nil
+}
+
+// parseMP4Duration 解析 mvhd 中的时长
+func parseMP4Duration(p []byte) float64 {
+	if len(p) < 1 {
+		return 0
+	}
+	var timescale, duration uint64
+	if p[0] == 1 {
+		if len(p) < 32 {
+			return 0
+		}
+		timescale = uint64(binary.BigEndian.Uint32(p[20:24]))
+		duration = binary.BigEndian.Uint64(p[24:32])
+	} else {
+		if len(p) < 20 {
+			return 0
+		}
+		timescale = uint64(binary.BigEndian.Uint32(p[12:16]))
+		duration = uint64(binary.BigEndian.Uint32(p[16:20]))
+	}
+	if timescale == 0 {
+		return 0
+	}
+	return float64(duration) / float64(timescale)
+}
+
+func mp4CodecName(fourcc string) string {
+	switch fourcc {
+	case "avc1", "avc3":
+		return "h264"
+	case "hvc1", "hev1":
+		return "hevc"
+	case "mp4a":
+		return "aac"
+	case ".mp3":
+		return "mp3"
+	case "Opus":
+		return "opus"
+	case "vp09":
+		return "vp9"
+	case "av01":
+		return "av1"
+	case "mp4v":
+		return "mpeg4"
+	}
+	return fourcc
+}
+
+// ---------------------------------------------------------------------------
+// WAV
+// ---------------------------------------------------------------------------
+
+// wavFormat WAV 格式块信息
+type wavFormat struct {
+	AudioFormat   uint16
+	Channels      uint16
+	SampleRate    uint32
+	ByteRate      uint32
+	BlockAlign    uint16
+	BitsPerSample uint16
+	DataOffset    int64
+	DataSize      int64
+}
+
+func readWAVFormat(r io.ReadSeeker) (*wavFormat, error) {
+	if _, err := r.Seek(12, io.SeekStart); err != nil {
+		return nil, err
+	}
+	format := &wavFormat{}
+	var hasFmt bool
+	offset := int64(12)
+	for {
+		chunk := make([]byte, 8)
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			break
+		}
+		chunkID := string(chunk[0:4])
+		chunkSize := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+		offset += 8
+
+		switch chunkID {
+		case "fmt ":
+			if chunkSize < 16 {
+				return nil, ErrCorruptedMedia
+			}
+			body := make([]byte, 16)
+			if _, err := io.ReadFull(r, body); err != nil {
+				return nil, ErrCorruptedMedia
+			}
+			format.AudioFormat = binary.LittleEndian.Uint16(body[0:2])
+			format.Channels = binary.LittleEndian.Uint16(body[2:4])
+			format.SampleRate = binary.LittleEndian.Uint32(body[4:8])
+			format.ByteRate = binary.LittleEndian.Uint32(body[8:12])
+			format.BlockAlign = binary.LittleEndian.Uint16(body[12:14])
+			format.BitsPerSample = binary.LittleEndian.Uint16(body[14:16])
+			// 块对齐用作除数，PCM 的块对齐必须等于每个采样帧的字节数
+			if format.Channels == 0 || format.BlockAlign == 0 {
+				return nil, ErrCorruptedMedia
+			}
+			if format.AudioFormat == 1 && int(format.BlockAlign) != int(format.Channels)*int(format.BitsPerSample)/8 {
+				return nil, ErrCorruptedMedia
+			}
+			hasFmt = true
+		case "data":
+			if !hasFmt {
+				return nil, ErrCorruptedMedia
+			}
+			format.DataOffset = offset
+			format.DataSize = chunkSize
+			return format, nil
+		}
+
+		// 块按偶数字节对齐
+		offset += chunkSize + chunkSize%2
+		if _, err := r.Seek(offset, io.SeekStart); err != nil {
+			break
+		}
+	}
+	return nil, ErrCorruptedMedia
+}
+
+func probeWAV(r io.ReadSeeker) (*MediaProbeResult, error) {
+	format, err := readWAVFormat(r)
+	if err != nil {
+		return nil, err
+	}
+	if format.ByteRate == 0 {
+		return nil, ErrCorruptedMedia
+	}
+
+	codec := "pcm"
+	if format.AudioFormat != 1 {
+		codec = fmt.Sprintf("wav-0x%04x", format.AudioFormat)
+	}
+
+	return &MediaProbeResult{
+		Container:  "wav",
+		MediaKind:  "audio",
+		AudioCodec: codec,
+		Duration:   float64(format.DataSize) / float64(format.ByteRate),
+		SampleRate: int(format.SampleRate),
+		Channels:   int(format.Channels),
+	}, nil
+}
+
+// ---------------------------------------------------------------------------
+// MP3
+// ---------------------------------------------------------------------------
+
+var (
+	mp3BitratesV1L3 = [16]int{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0}
+	mp3BitratesV2L3 = [16]int{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0}
+	mp3SampleRates  = map[int][3]int{
+		3: {44100, 48000, 32000}, // MPEG-1
+		2: {22050, 24000, 16000}, // MPEG-2
+		0: {11025, 12000, 8000},  // MPEG-2.5
+	}
+)
+
+// mp3FrameHeader MP3 帧头信息
+type mp3FrameHeader struct {
+	version    int // 3: MPEG-1, 2: MPEG-2, 0: MPEG-2.5
+	bitrate    int // kbps
+	sampleRate int
+	channels   int
+}
+
+func parseMP3FrameHeader(h []byte) (*mp3FrameHeader, bool) {
+	if len(h) < 4 || h[0] != 0xFF || h[1]&0xE0 != 0xE0 {
+		return nil, false
+	}
+	version := int(h[1]>>3) & 0x03
+	layer := int(h[1]>>1) & 0x03
+	if version == 1 || layer != 1 { // 保留版本，或非 Layer III
+		return nil, false
+	}
+	bitrateIndex := int(h[2] >> 4)
+	sampleIndex := int(h[2]>>2) & 0x03
+	if bitrateIndex == 0 || bitrateIndex == 15 || sampleIndex == 3 {
+		return nil, false
+	}
+
+	header := &mp3FrameHeader{version: version, sampleRate: mp3SampleRates[version][sampleIndex], channels: 2}
+	if version == 3 {
+		header.bitrate = mp3BitratesV1L3[bitrateIndex]
+	} else {
+		header.bitrate = mp3BitratesV2L3[bitrateIndex]
+	}
+	if h[3]>>6 == 3 {
+		header.channels = 1
+	}
+	return header, true
+}
+
+func (h *mp3FrameHeader) samplesPerFrame() int {
+	if h.version == 3 {
+		return 1152
+	}
+	return 576
+}
+
+func (h *mp3FrameHeader) sideInfoSize() int {
+	if h.version == 3 {
+		if h.channels == 1 {
+			return 17
+		}
+		return 32
+	}
+	if h.channels == 1 {
+		return 9
+	}
+	return 17
+}
+
+func probeMP3(r io.ReadSeeker, size int64) (*MediaProbeResult, error) {
+	// 跳过 ID3v2 标签
+	var audioStart int64
+	tag := make([]byte, 10)
+	if _, err := io.ReadFull(r, tag); err != nil {
+		return nil, ErrCorruptedMedia
+	}
+	if string(tag[0:3]) == "ID3" {
+		tagSize := int64(tag[6]&0x7F)<<21 | int64(tag[7]&0x7F)<<14 | int64(tag[8]&0x7F)<<7 | int64(tag[9]&0x7F)
+		audioStart = 10 + tagSize
+		if tag[5]&0x10 != 0 {
+			audioStart += 10
+		}
+	}
+
+	// 在前 64KB 内查找第一个有效帧
+	if _, err := r.Seek(audioStart, io.SeekStart); err != nil {
+		return nil, ErrCorruptedMedia
+	}
+	buf := make([]byte, 64*1024)
+	n, _ := io.ReadFull(r, buf)
+	buf = buf[:n]
+
+	for i := 0; i+4 <= len(buf); i++ {
+		header, ok := parseMP3FrameHeader(buf[i : i+4])
+		if !ok {
+			continue
+		}
+		frameStart := audioStart + int64(i)
+		result := &MediaProbeResult{
+			Container:  "mp3",
+			MediaKind:  "audio",
+			AudioCodec: "mp3",
+			SampleRate: header.sampleRate,
+			Channels:   header.channels,
+		}
+
+		// VBR 文件优先读取 Xing/Info 头中的总帧数
+		xingOffset := i + 4 + header.sideInfoSize()
+		if xingOffset+12 <= len(buf) {
+			marker := string(buf[xingOffset : xingOffset+4])
+			if marker == "Xing" || marker == "Info" {
+				flags := binary.BigEndian.Uint32(buf[xingOffset+4 : xingOffset+8])
+				if flags&0x01 != 0 {
+					frames := binary.BigEndian.Uint32(buf[xingOffset+8 : xingOffset+12])
+					result.Duration = float64(frames) * float64(header.samplesPerFrame()) / float64(header.sampleRate)
+					return result, nil
+				}
+			}
+		}
+
+		// 否则按 CBR 估算
+		audioBytes := size - frameStart
+		if audioBytes <= 0 || header.bitrate == 0 {
+			return nil, ErrCorruptedMedia
+		}
+		result.Duration = float64(audioBytes*8) / float64(header.bitrate*1000)
+		return result, nil
+	}
+
+	return nil, ErrCorruptedMedia
+}
@@ -0,0 +1,173 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func buildWAV(sampleRate, channels, seconds int) []byte {
+	dataSize := sampleRate * channels * 2 * seconds
+	buf := &bytes.Buffer{}
+	buf.WriteString("RIFF")
+	binary.Write(buf, binary.LittleEndian, uint32(36+dataSize))
+	buf.WriteString("WAVEfmt ")
+	binary.Write(buf, binary.LittleEndian, uint32(16))
+	binary.Write(buf, binary.LittleEndian, uint16(1))
+	binary.Write(buf, binary.LittleEndian, uint16(channels))
+	binary.Write(buf, binary.LittleEndian, uint32(sampleRate))
+	binary.Write(buf, binary.LittleEndian, uint32(sampleRate*channels*2))
+	binary.Write(buf, binary.LittleEndian, uint16(channels*2))
+	binary.Write(buf, binary.LittleEndian, uint16(16))
+	buf.WriteString("data")
+	binary.Write(buf, binary.LittleEndian, uint32(dataSize))
+	buf.Write(make([]byte, dataSize))
+	return buf.Bytes()
+}
+
+func mp4Atom(boxType string, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.BigEndian, uint32(8+len(body)))
+	buf.WriteString(boxType)
+	buf.Write(body)
+	return buf.Bytes()
+}
+
+func mp4Track(handler, fourcc string, width, height int) []byte {
+	tkhd := make([]byte, 84)
+	binary.BigEndian.PutUint32(tkhd[76:80], uint32(width<<16))
+	binary.BigEndian.PutUint32(tkhd[80:84], uint32(height<<16))
+
+	hdlr := make([]byte, 24)
+	copy(hdlr[8:12], handler)
+
+	stsd := make([]byte, 16)
+	binary.BigEndian.PutUint32(stsd[4:8], 1)
+	copy(stsd[12:16], fourcc)
+
+	return mp4Atom("trak",
+		mp4Atom("tkhd", tkhd),
+		mp4Atom("mdia",
+			mp4Atom("hdlr", hdlr),
+			mp4Atom("minf", mp4Atom("stbl", mp4Atom("stsd", stsd)))))
+}
+
+func buildMP4(timescale, duration uint32, tracks ...[]byte) []byte {
+	mvhd := make([]byte, 100)
+	binary.BigEndian.PutUint32(mvhd[12:16], timescale)
+	binary.BigEndian.PutUint32(mvhd[16:20], duration)
+
+	ftyp := mp4Atom("ftyp", []byte("isom"), make([]byte, 4))
+	mdat := mp4Atom("mdat", make([]byte, 1024))
+	moov := mp4Atom("moov", append([][]byte{mp4Atom("mvhd", mvhd)}, tracks...)...)
+	return bytes.Join([][]byte{ftyp, mdat, moov}, nil)
+}
+
+func TestProbeMediaWAV(t *testing.T) {
+	data := buildWAV(8000, 1, 3)
+	result, err := ProbeMedia(bytes.NewReader(data), int64(len(data)))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "wav", result.Container)
+	assert.Equal(t, "audio", result.MediaKind)
+	assert.Equal(t, "pcm", result.AudioCodec)
+	assert.Equal(t, 3, result.DurationSeconds())
+	assert.NoError(t, ValidatePlayableMedia(result, "audio"))
+}
+
+func TestProbeMediaRejectsCorruptedWAVFormat(t *testing.T) {
+	// fmt 块内偏移：声道数 22，块对齐 32
+	for name, patch := range map[string]struct {
+		offset int
+		value  uint16
+	}{
+		"block_align_zero": {32, 0},
+		"channels_zero":    {22, 0},
+		"block_align_off":  {32, 3},
+	} {
+		data := buildWAV(8000, 1, 1)
+		binary.LittleEndian.PutUint16(data[patch.offset:], patch.value)
+		_, err := ProbeMedia(bytes.NewReader(data), int64(len(data)))
+		assert.True(t, errors.Is(err, ErrCorruptedMedia), name)
+	}
+
+	// 构造的头部不能使波形生成除以零
+	data := buildWAV(8000, 1, 1)
+	binary.LittleEndian.PutUint16(data[32:], 0)
+	path := filepath.Join(t.TempDir(), "crafted.wav")
+	assert.NoError(t, os.WriteFile(path, data, 0644))
+	_, err := GenerateWaveform(path, &MediaProbeResult{Container: "wav", AudioCodec: "pcm", Duration: 1}, 8)
+	assert.True(t, errors.Is(err, ErrCorruptedMedia))
+}
+
+func TestProbeMediaMP4Video(t *testing.T) {
+	data := buildMP4(1000, 12500,
+		mp4Track("vide", "avc1", 1280, 720),
+		mp4Track("soun", "mp4a", 0, 0))
+	result, err := ProbeMedia(bytes.NewReader(data), int64(len(data)))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "video", result.MediaKind)
+	assert.Equal(t, "h264", result.VideoCodec)
+	assert.Equal(t, "aac", result.AudioCodec)
+	assert.Equal(t, 1280, result.Width)
+	assert.Equal(t, 720, result.Height)
+	assert.Equal(t, 13, result.DurationSeconds())
+	assert.NoError(t, ValidatePlayableMedia(result, "video"))
+
+	// 视频文件不能作为音频留言
+	assert.Error(t, ValidatePlayableMedia(result, "audio"))
+}
+
+func TestProbeMediaMP3CBR(t *testing.T) {
+	// MPEG-1 Layer III, 128kbps, 44.1kHz, 立体声
+	frame := []byte{0xFF, 0xFB, 0x90, 0x00}
+	data := append(frame, make([]byte, 160000-len(frame))...)
+	result, err := ProbeMedia(bytes.NewReader(data), int64(len(data)))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "mp3", result.AudioCodec)
+	assert.Equal(t, 44100, result.SampleRate)
+	assert.InDelta(t, 10.0, result.Duration, 0.01)
+}
+
+func TestProbeMediaRejectsUnsupported(t *testing.T) {
+	for name, header := range map[string][]byte{
+		"ogg":  []byte("OggS\x00\x02\x00\x00\x00\x00\x00\x00"),
+		"webm": {0x1A, 0x45, 0xDF, 0xA3, 0, 0, 0, 0, 0, 0, 0, 0},
+		"flv":  []byte("FLV\x01\x05\x00\x00\x00\x09\x00\x00\x00"),
+		"text": []byte("hello world!"),
+	} {
+		_, err := ProbeMedia(bytes.NewReader(header), int64(len(header)))
+		assert.True(t, errors.Is(err, ErrUnsupportedMediaFormat), name)
+	}
+
+	// 编码不受支持的 MP4 也应拒绝
+	data := buildMP4(1000, 5000, mp4Track("vide", "vp09", 640, 480))
+	result, err := ProbeMedia(bytes.NewReader(data), int64(len(data)))
+	assert.NoError(t, err)
+	assert.True(t, errors.Is(ValidatePlayableMedia(result, "video"), ErrUnsupportedMediaFormat))
+}
+
+func TestWaveformPeaks(t *testing.T) {
+	samples := &bytes.Buffer{}
+	for i := 0; i < 800; i++ {
+		value := int16(0)
+		if i >= 400 {
+			value = 16384
+		}
+		binary.Write(samples, binary.LittleEndian, value)
+	}
+
+	peaks, err := waveformPeaks(samples, 1, 800, 8)
+	assert.NoError(t, err)
+	assert.Len(t, peaks, 8)
+	assert.Equal(t, 0.0, peaks[0])
+	assert.Equal(t, 0.5, peaks[7])
+}
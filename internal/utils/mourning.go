@@ -0,0 +1,255 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// 守丧规则的参数上限
+const (
+	MourningMaxObservances = 20  // 每套规则最多 20 项
+	MourningMaxYears       = 100 // 第 N 个忌日的上限
+	MourningMaxSuggestions = 10  // 每项最多 10 条建议仪式或供品
+)
+
+// 内置的守丧规则
+const (
+	MourningRuleSetSecular  = "secular"  // 民间通行
+	MourningRuleSetBuddhist = "buddhist" // 佛教
+)
+
+var mourningKeyPattern = regexp.MustCompile(`^[a-z0-9_]{1,32}$`)
+
+// MourningObservance 守丧规则中的一项，按逝世后第 N 天（逝世当天为第 1 天）或第 N 个忌日推算日期
+type MourningObservance struct {
+	Key         string   `json:"key"`                    // 标识，如 first_seven
+	Name        string   `json:"name"`                   // 名称，如 头七
+	Days        int      `json:"days,omitempty"`         // 逝世第 N 天
+	Years       int      `json:"years,omitempty"`        // 第 N 个忌日
+	Calendar    string   `json:"calendar,omitempty"`     // 忌日按 lunar 农历或 solar 公历推算，默认农历
+	Description string   `json:"description,omitempty"`  // 说明
+	Rites       []string `json:"rites,omitempty"`        // 建议的仪式
+	Offerings   []string `json:"offerings,omitempty"`    // 建议的供品
+	WorshipType string   `json:"worship_type,omitempty"` // 安排集体祭扫时建议的祭扫方式 flower|candle|incense|tribute
+}
+
+// MourningRuleSet 一套守丧规则，不同地区、宗教的做法各用一套
+type MourningRuleSet struct {
+	Key         string               `json:"key"`
+	Name        string               `json:"name"`
+	Region      string               `json:"region,omitempty"` // 适用地区，为空表示通行
+	Description string               `json:"description,omitempty"`
+	Observances []MourningObservance `json:"observances"`
+}
+
+// ValidMourningRuleSetKey 规则标识只能包含小写字母、数字和下划线
+func ValidMourningRuleSetKey(key string) bool {
+	return mourningKeyPattern.MatchString(key)
+}
+
+// Validate 校验规则并补全默认值（忌日默认按农历）
+func (r *MourningRuleSet) Validate() error {
+	invalid := func(format string, args ...interface{}) error {
+		return errors.New("无效的守丧规则：" + fmt.Sprintf(format, args...))
+	}
+
+	if r.Name == "" || len([]rune(r.Name)) > 50 {
+		return invalid("名称不能为空且不超过50字")
+	}
+	if len(r.Observances) == 0 || len(r.Observances) > MourningMaxObservances {
+		return invalid("须有1至%d项", MourningMaxObservances)
+	}
+
+	seen := make(map[string]bool)
+	for i := range r.Observances {
+		o := &r.Observances[i]
+		if !mourningKeyPattern.MatchString(o.Key) || seen[o.Key] {
+			return invalid("第%d项的标识无效或重复", i+1)
+		}
+		seen[o.Key] = true
+		if o.Name == "" || len([]rune(o.Name)) > 50 {
+			return invalid("第%d项的名称不能为空且不超过50字", i+1)
+		}
+		switch {
+		case o.Days > 0 && o.Years == 0:
+			if o.Days > ReminderMaxDaysAfterDeath || o.Calendar != "" {
+				return invalid("第%d项的天数无效", i+1)
+			}
+		case o.Years > 0 && o.Days == 0:
+			if o.Years > MourningMaxYears {
+				return invalid("第%d项的年数无效", i+1)
+			}
+			switch o.Calendar {
+			case "":
+				o.Calendar = CalendarLunar
+			case CalendarLunar, CalendarSolar:
+			default:
+				return invalid("第%d项的历法无效", i+1)
+			}
+		default:
+			return invalid("第%d项须且只能填写天数或年数之一", i+1)
+		}
+		if len(o.Rites) > MourningMaxSuggestions || len(o.Offerings) > MourningMaxSuggestions {
+			return invalid("第%d项的建议仪式或供品过多", i+1)
+		}
+		switch o.WorshipType {
+		case "", "flower", "candle", "incense", "tribute":
+		default:
+			return invalid("第%d项的祭扫方式无效", i+1)
+		}
+	}
+	return nil
+}
+
+// Date 按逝世日期推算这一项的日期
+func (o MourningObservance) Date(deathDate time.Time) (time.Time, bool) {
+	death := calendarDay(deathDate)
+	if o.Days > 0 {
+		return death.AddDate(0, 0, o.Days-1), true
+	}
+
+	calendar := o.Calendar
+	if calendar == "" {
+		calendar = CalendarLunar
+	}
+	// 从逝世当年（农历为农历年）起算第 N 年的同月同日，逝世在闰月的取同名的非闰月
+	schedule, year, ok := ReminderRule{Yearly: true, Calendar: calendar}.yearlySchedule(death)
+	if !ok {
+		return time.Time{}, false
+	}
+	return schedule.dateIn(year+o.Years, schedule.Month, false)
+}
+
+// Describe 推算方式的中文描述，如“逝世第7天”“第3个农历忌日”
+func (o MourningObservance) Describe() string {
+	if o.Days > 0 {
+		return fmt.Sprintf("逝世第%d天", o.Days)
+	}
+	if o.Calendar == CalendarSolar {
+		return fmt.Sprintf("第%d个公历忌日", o.Years)
+	}
+	return fmt.Sprintf("第%d个农历忌日", o.Years)
+}
+
+// sevens 做七：头七至七七
+func sevens(rites map[int][]string, offerings map[int][]string, worshipType string, descriptions map[int]string) []MourningObservance {
+	names := []string{"头七", "二七", "三七", "四七", "五七", "六七", "七七"}
+	keys := []string{"first_seven", "second_seven", "third_seven", "fourth_seven", "fifth_seven", "sixth_seven", "seventh_seven"}
+	observances := make([]MourningObservance, 0, len(names))
+	for i, name := range names {
+		n := i + 1
+		observances = append(observances, MourningObservance{
+			Key:         keys[i],
+			Name:        name,
+			Days:        n * 7,
+			Description: descriptions[n],
+			Rites:       rites[n],
+			Offerings:   offerings[n],
+			WorshipType: worshipType,
+		})
+	}
+	return observances
+}
+
+// DefaultMourningRuleSets 内置的守丧规则：民间通行和佛教，管理员可在系统配置中覆盖或增加地区做法
+func DefaultMourningRuleSets() []MourningRuleSet {
+	secularRites := []string{"上香", "供饭", "焚化纸钱"}
+	secularOfferings := []string{"逝者生前爱吃的饭菜", "香烛", "纸钱"}
+	secular := MourningRuleSet{
+		Key:         MourningRuleSetSecular,
+		Name:        "民间通行",
+		Description: "逝世当天为第一天，每七天一祭，至七七四十九天；之后做百日、周年和三周年，忌日按农历。",
+		Observances: sevens(
+			map[int][]string{
+				1: {"设灵位", "上香", "供饭", "守灵"},
+				2: secularRites, 3: secularRites, 4: secularRites,
+				5: {"上香", "供饭", "焚化纸钱", "亲友聚祭"},
+				6: secularRites,
+				7: {"上香", "供饭", "焚化纸钱", "撤除灵位"},
+			},
+			map[int][]string{
+				1: {"逝者生前爱吃的饭菜", "香烛", "纸钱", "长明灯"},
+				2: secularOfferings, 3: secularOfferings, 4: secularOfferings,
+				5: {"逝者生前爱吃的饭菜", "香烛", "纸钱", "纸伞"},
+				6: secularOfferings,
+				7: {"整桌饭菜", "香烛", "纸钱"},
+			},
+			"tribute",
+			map[int]string{
+				1: "头七是做七之始，家人设灵位供奉",
+				5: "五七较为隆重，多由女儿操办",
+				7: "七七又称断七、满七，做七到此结束",
+			},
+		),
+	}
+	secular.Observances = append(secular.Observances,
+		MourningObservance{
+			Key: "hundred_days", Name: "百日", Days: 100,
+			Description: "逝世满百日，亲友聚祭",
+			Rites:       []string{"扫墓", "上香", "焚化纸钱"},
+			Offerings:   []string{"饭菜", "水果", "香烛", "纸钱"},
+			WorshipType: "tribute",
+		},
+		MourningObservance{
+			Key: "first_anniversary", Name: "周年", Years: 1, Calendar: CalendarLunar,
+			Description: "逝世一周年",
+			Rites:       []string{"扫墓", "上香", "焚化纸钱"},
+			Offerings:   []string{"饭菜", "水果", "香烛", "纸钱"},
+			WorshipType: "tribute",
+		},
+		MourningObservance{
+			Key: "third_anniversary", Name: "三周年", Years: 3, Calendar: CalendarLunar,
+			Description: "逝世三周年，守孝期满，又称除服",
+			Rites:       []string{"扫墓", "上香", "焚化纸钱", "除服"},
+			Offerings:   []string{"饭菜", "水果", "香烛", "纸钱"},
+			WorshipType: "tribute",
+		},
+	)
+
+	buddhistRites := []string{"诵《地藏经》", "念佛回向"}
+	buddhistOfferings := []string{"鲜花", "清水", "水果", "素斋"}
+	buddhist := MourningRuleSet{
+		Key:         MourningRuleSetBuddhist,
+		Name:        "佛教",
+		Description: "七七四十九天内每七日诵经念佛，为亡者回向超度；不杀生，以素斋、鲜花供养，不焚化纸钱。",
+		Observances: sevens(
+			map[int][]string{
+				1: buddhistRites, 2: buddhistRites, 3: buddhistRites, 4: buddhistRites,
+				5: buddhistRites, 6: buddhistRites,
+				7: {"诵《地藏经》", "念佛回向", "供佛斋僧"},
+			},
+			map[int][]string{
+				1: buddhistOfferings, 2: buddhistOfferings, 3: buddhistOfferings, 4: buddhistOfferings,
+				5: buddhistOfferings, 6: buddhistOfferings, 7: buddhistOfferings,
+			},
+			"incense",
+			map[int]string{
+				7: "七七圆满，多请法师诵经超度",
+			},
+		),
+	}
+	buddhist.Observances = append(buddhist.Observances,
+		MourningObservance{
+			Key: "hundred_days", Name: "百日", Days: 100,
+			Rites:       []string{"诵经", "念佛回向"},
+			Offerings:   buddhistOfferings,
+			WorshipType: "incense",
+		},
+		MourningObservance{
+			Key: "first_anniversary", Name: "周年", Years: 1, Calendar: CalendarLunar,
+			Rites:       []string{"诵经", "念佛回向", "放生"},
+			Offerings:   buddhistOfferings,
+			WorshipType: "incense",
+		},
+		MourningObservance{
+			Key: "third_anniversary", Name: "三周年", Years: 3, Calendar: CalendarLunar,
+			Rites:       []string{"诵经", "念佛回向", "放生"},
+			Offerings:   buddhistOfferings,
+			WorshipType: "incense",
+		},
+	)
+
+	return []MourningRuleSet{secular, buddhist}
+}
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultMourningRuleSets(t *testing.T) {
+	sets := DefaultMourningRuleSets()
+	require.Len(t, sets, 2)
+	for _, set := range sets {
+		require.NoError(t, set.Validate(), set.Key)
+		assert.Len(t, set.Observances, 10, set.Key)
+		assert.Equal(t, "头七", set.Observances[0].Name)
+		assert.Equal(t, 49, set.Observances[6].Days)
+	}
+}
+
+func TestMourningObservanceDate(t *testing.T) {
+	death := ruleDay(2025, 3, 1)
+	date, ok := MourningObservance{Days: 7}.Date(death)
+	require.True(t, ok)
+	assert.Equal(t, ruleDay(2025, 3, 7), date)
+	date, _ = MourningObservance{Days: 49}.Date(death)
+	assert.Equal(t, ruleDay(2025, 4, 18), date)
+	date, _ = MourningObservance{Days: 100}.Date(death)
+	assert.Equal(t, ruleDay(2025, 6, 8), date)
+
+	// 2024-04-05 为农历二月廿七，周年为次年农历二月廿七
+	date, ok = MourningObservance{Years: 1}.Date(ruleDay(2024, 4, 5))
+	require.True(t, ok)
+	assert.Equal(t, ruleDay(2025, 3, 26), date)
+
+	date, _ = MourningObservance{Years: 1, Calendar: CalendarSolar}.Date(ruleDay(2024, 2, 29))
+	assert.Equal(t, ruleDay(2025, 2, 28), date)
+
+	assert.Equal(t, "逝世第7天", MourningObservance{Days: 7}.Describe())
+	assert.Equal(t, "第3个农历忌日", MourningObservance{Years: 3}.Describe())
+}
+
+func TestMourningRuleSetValidate(t *testing.T) {
+	set := MourningRuleSet{
+		Name: "闽南",
+		Observances: []MourningObservance{
+			{Key: "first_seven", Name: "头七", Days: 8},
+			{Key: "first_anniversary", Name: "对年", Years: 1},
+		},
+	}
+	require.NoError(t, set.Validate())
+	assert.Equal(t, CalendarLunar, set.Observances[1].Calendar)
+
+	for _, o := range []MourningObservance{
+		{Key: "a", Name: "甲", Days: 7, Years: 1},
+		{Key: "a", Name: "甲"},
+		{Key: "A", Name: "甲", Days: 7},
+		{Key: "a", Name: "", Days: 7},
+		{Key: "a", Name: "甲", Days: 7, Calendar: CalendarLunar},
+		{Key: "a", Name: "甲", Years: 1, Calendar: "hebrew"},
+		{Key: "a", Name: "甲", Days: 7, WorshipType: "paper"},
+	} {
+		invalid := MourningRuleSet{Name: "测试", Observances: []MourningObservance{o}}
+		assert.Error(t, invalid.Validate(), "%+v", o)
+	}
+
+	duplicated := MourningRuleSet{Name: "测试", Observances: []MourningObservance{
+		{Key: "a", Name: "甲", Days: 7},
+		{Key: "a", Name: "乙", Days: 14},
+	}}
+	assert.Error(t, duplicated.Validate())
+	assert.Error(t, (&MourningRuleSet{Name: "测试"}).Validate())
+
+	assert.True(t, ValidMourningRuleSetKey("secular_minnan"))
+	assert.False(t, ValidMourningRuleSetKey("闽南"))
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"mime"
+	"strings"
+	"time"
+)
+
+// 微信订阅消息 thing 类字段的最大字数
+const wechatThingMaxRunes = 20
+
+// BuildEmailMessage 生成 UTF-8 纯文本邮件，主题按 RFC 2047 编码、正文按 base64 编码，可直接交给 smtp.SendMail
+func BuildEmailMessage(from, to, subject, body string, date time.Time) []byte {
+	var buf bytes.Buffer
+	header := func(key, value string) {
+		buf.WriteString(key + ": " + value + "\r\n")
+	}
+	header("From", from)
+	header("To", to)
+	header("Subject", mime.BEncoding.Encode("UTF-8", subject))
+	header("Date", date.Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=UTF-8")
+	header("Content-Transfer-Encoding", "base64")
+	buf.WriteString("\r\n")
+
+	encoded := base64.StdEncoding.EncodeToString([]byte(body))
+	for len(encoded) > 76 {
+		buf.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	buf.WriteString(encoded + "\r\n")
+	return buf.Bytes()
+}
+
+// WechatSubscribeData 生成订阅消息的模板数据：thing1 标题、thing2 内容、time3 时间
+//
+// thing 类字段最多 20 个字，超出时截断并以“…”结尾，换行等空白合并为一个空格。
+func WechatSubscribeData(title, content string, at time.Time) map[string]map[string]string {
+	return map[string]map[string]string{
+		"thing1": {"value": wechatThing(title)},
+		"thing2": {"value": wechatThing(content)},
+		"time3":  {"value": at.Format("2006-01-02 15:04")},
+	}
+}
+
+func wechatThing(text string) string {
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+	if len(runes) > wechatThingMaxRunes {
+		runes = append(runes[:wechatThingMaxRunes-1], '…')
+	}
+	return string(runes)
+}
@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildEmailMessage(t *testing.T) {
+	date := time.Date(2025, 4, 4, 9, 30, 0, 0, time.FixedZone("CST", 8*3600))
+	body := strings.Repeat("清明将至，请记得为爷爷扫墓。", 10)
+	msg := string(BuildEmailMessage("noreply@example.com", "user@example.com", "清明提醒", body, date))
+
+	parts := strings.SplitN(msg, "\r\n\r\n", 2)
+	require.Len(t, parts, 2)
+	assert.Contains(t, parts[0], "From: noreply@example.com\r\n")
+	assert.Contains(t, parts[0], "To: user@example.com\r\n")
+	assert.Contains(t, parts[0], "Subject: =?UTF-8?b?5riF5piO5o+Q6YaS?=\r\n")
+	assert.Contains(t, parts[0], "Date: Fri, 04 Apr 2025 09:30:00 +0800\r\n")
+	assert.True(t, strings.HasSuffix(parts[0], "Content-Transfer-Encoding: base64"))
+
+	lines := strings.Split(strings.TrimSuffix(parts[1], "\r\n"), "\r\n")
+	for _, line := range lines {
+		assert.LessOrEqual(t, len(line), 76)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.Join(lines, ""))
+	require.NoError(t, err)
+	assert.Equal(t, body, string(decoded))
+}
+
+func TestWechatSubscribeData(t *testing.T) {
+	at := time.Date(2025, 4, 4, 9, 30, 0, 0, time.UTC)
+	data := WechatSubscribeData("家族圈邀请", "张伟邀请您加入“张氏家族”家族圈，\n一起缅怀先人、传承家风", at)
+
+	assert.Equal(t, "家族圈邀请", data["thing1"]["value"])
+	assert.Equal(t, 20, len([]rune(data["thing2"]["value"])))
+	assert.True(t, strings.HasSuffix(data["thing2"]["value"], "…"))
+	assert.NotContains(t, data["thing2"]["value"], "\n")
+	assert.Equal(t, "2025-04-04 09:30", data["time3"]["value"])
+}
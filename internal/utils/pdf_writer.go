@@ -0,0 +1,276 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A4 纸张尺寸与版心边距（单位：pt）
+const (
+	pdfPageWidth  = 595.28
+	pdfPageHeight = 841.89
+	pdfMargin     = 50.0
+)
+
+// PDFDocument 简单的报表 PDF 生成器，支持标题、段落、表格和横向柱状图，内容超出时自动分页。
+// 中文使用 PDF 标准 CJK 字体 STSong-Light（UniGB-UCS2-H 编码），不嵌入字体文件，
+// 由阅读器提供对应字体或自动替换，因此生成的文件很小
+type PDFDocument struct {
+	title string
+	pages []*bytes.Buffer
+	page  *bytes.Buffer
+	y     float64
+}
+
+// NewPDFDocument 创建 PDF 文档，title 写入文档属性
+func NewPDFDocument(title string) *PDFDocument {
+	doc := &PDFDocument{title: title}
+	doc.AddPage()
+	return doc
+}
+
+// PageCount 当前页数
+func (d *PDFDocument) PageCount() int {
+	return len(d.pages)
+}
+
+// AddPage 新起一页
+func (d *PDFDocument) AddPage() {
+	d.page = &bytes.Buffer{}
+	d.pages = append(d.pages, d.page)
+	d.y = pdfPageHeight - pdfMargin
+}
+
+// ensureSpace 剩余空间不足 height 时换页
+func (d *PDFDocument) ensureSpace(height float64) {
+	if d.y-height < pdfMargin {
+		d.AddPage()
+	}
+}
+
+// Title 居中大标题
+func (d *PDFDocument) Title(text string) {
+	const size = 20.0
+	d.ensureSpace(size * 2)
+	d.y -= size
+	x := (pdfPageWidth - PDFTextWidth(text, size)) / 2
+	d.text(x, d.y, size, text)
+	d.y -= size
+}
+
+// Heading 小节标题
+func (d *PDFDocument) Heading(text string) {
+	const size = 14.0
+	d.ensureSpace(size * 3)
+	d.y -= size * 1.5
+	d.text(pdfMargin, d.y, size, text)
+	d.y -= size * 0.8
+}
+
+// Paragraph 自动折行的正文段落
+func (d *PDFDocument) Paragraph(text string) {
+	const size = 11.0
+	lineHeight := size * 1.6
+	for _, line := range WrapPDFText(text, size, pdfPageWidth-2*pdfMargin) {
+		d.ensureSpace(lineHeight)
+		d.y -= lineHeight
+		d.text(pdfMargin, d.y+size*0.4, size, line)
+	}
+}
+
+// Table 绘制等宽列表格，表头灰底，单元格内容过长时截断；跨页时在新页重复表头
+func (d *PDFDocument) Table(table ReportTable) {
+	const size = 10.0
+	const rowHeight = 20.0
+	columns := len(table.Headers)
+	for _, row := range table.Rows {
+		if len(row) > columns {
+			columns = len(row)
+		}
+	}
+	if columns == 0 {
+		return
+	}
+	colWidth := (pdfPageWidth - 2*pdfMargin) / float64(columns)
+
+	drawRow := func(cells []string, header bool) {
+		top := d.y
+		if header {
+			fmt.Fprintf(d.page, "0.9 g %.2f %.2f %.2f %.2f re f 0 g\n", pdfMargin, top-rowHeight, colWidth*float64(columns), rowHeight)
+		}
+		for i := 0; i < columns; i++ {
+			x := pdfMargin + colWidth*float64(i)
+			fmt.Fprintf(d.page, "0.5 w %.2f %.2f %.2f %.2f re S\n", x, top-rowHeight, colWidth, rowHeight)
+			if i < len(cells) {
+				text := TruncatePDFText(cells[i], size, colWidth-8)
+				d.text(x+4, top-rowHeight+6, size, text)
+			}
+		}
+		d.y -= rowHeight
+	}
+
+	d.y -= 4
+	d.ensureSpace(rowHeight * 2)
+	if len(table.Headers) > 0 {
+		drawRow(table.Headers, true)
+	}
+	for _, row := range table.Rows {
+		if d.y-rowHeight < pdfMargin {
+			d.AddPage()
+			if len(table.Headers) > 0 {
+				drawRow(table.Headers, true)
+			}
+		}
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = FormatReportCell(cell)
+		}
+		drawRow(cells, false)
+	}
+	d.y -= 6
+}
+
+// BarChart 横向柱状图，柱长按最大值等比缩放，柱尾标注数值
+func (d *PDFDocument) BarChart(labels []string, values []float64) {
+	const size = 10.0
+	const barHeight = 14.0
+	const gap = 6.0
+	labelWidth := 80.0
+	maxValue := 0.0
+	for _, v := range values {
+		if v > maxValue {
+			maxValue = v
+		}
+	}
+	maxBar := pdfPageWidth - 2*pdfMargin - labelWidth - 50
+
+	d.y -= 4
+	for i, label := range labels {
+		if i >= len(values) {
+			break
+		}
+		d.ensureSpace(barHeight + gap)
+		d.y -= barHeight + gap
+		d.text(pdfMargin, d.y+3, size, TruncatePDFText(label, size, labelWidth-6))
+		width := 0.0
+		if maxValue > 0 {
+			width = maxBar * values[i] / maxValue
+		}
+		if width > 0 {
+			fmt.Fprintf(d.page, "0.36 0.54 0.66 rg %.2f %.2f %.2f %.2f re f 0 g\n", pdfMargin+labelWidth, d.y, width, barHeight)
+		}
+		d.text(pdfMargin+labelWidth+width+4, d.y+3, size, FormatReportCell(values[i]))
+	}
+	d.y -= gap
+}
+
+func (d *PDFDocument) text(x, y, size float64, text string) {
+	if text == "" {
+		return
+	}
+	fmt.Fprintf(d.page, "BT /F1 %.1f Tf %.2f %.2f Td <%s> Tj ET\n", size, x, y, pdfEncodeText(text))
+}
+
+// WriteTo 输出完整的 PDF 文件
+func (d *PDFDocument) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	var offsets []int
+
+	newObject := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xE2\xE3\xCF\xD3\n")
+
+	// 固定对象：1 目录 2 页面树 3 字体 4 CID字体 5 字体描述 6 文档信息；之后每页两个对象（页面、内容流）
+	const firstPageObj = 7
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPageObj+i*2)
+	}
+	newObject("<< /Type /Catalog /Pages 2 0 R >>")
+	newObject(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	newObject("<< /Type /Font /Subtype /Type0 /BaseFont /STSong-Light /Encoding /UniGB-UCS2-H /DescendantFonts [4 0 R] >>")
+	newObject("<< /Type /Font /Subtype /CIDFontType0 /BaseFont /STSong-Light /CIDSystemInfo << /Registry (Adobe) /Ordering (GB1) /Supplement 2 >> /FontDescriptor 5 0 R /DW 1000 /W [1 95 500] >>")
+	newObject("<< /Type /FontDescriptor /FontName /STSong-Light /Flags 6 /FontBBox [-25 -254 1000 880] /ItalicAngle 0 /Ascent 880 /Descent -120 /CapHeight 880 /StemV 93 >>")
+	newObject(fmt.Sprintf("<< /Title <FEFF%s> /Producer (yun-nian-memorial) >>", pdfEncodeText(d.title)))
+
+	for i, page := range d.pages {
+		newObject(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, firstPageObj+i*2+1))
+		newObject(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.Len(), page.String()))
+	}
+
+	xrefOffset := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info 6 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xrefOffset)
+
+	return buf.WriteTo(w)
+}
+
+// pdfEncodeText 将文本编码为 UCS-2 大端十六进制串，基本平面以外的字符替换为问号
+func pdfEncodeText(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		if r > 0xFFFF || r < 0x20 {
+			r = '?'
+		}
+		fmt.Fprintf(&b, "%04X", r)
+	}
+	return b.String()
+}
+
+// PDFTextWidth 估算文本宽度：ASCII 为半角，其余字符为全角
+func PDFTextWidth(text string, size float64) float64 {
+	width := 0.0
+	for _, r := range text {
+		if r < 0x80 {
+			width += size * 0.5
+		} else {
+			width += size
+		}
+	}
+	return width
+}
+
+// TruncatePDFText 截断文本使其不超过指定宽度，截断时以省略号结尾
+func TruncatePDFText(text string, size, maxWidth float64) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if PDFTextWidth(text, size) <= maxWidth {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 && PDFTextWidth(string(runes)+"…", size) > maxWidth {
+		runes = runes[:len(runes)-1]
+	}
+	if len(runes) == 0 {
+		return ""
+	}
+	return string(runes) + "…"
+}
+
+// WrapPDFText 按宽度折行，保留原有换行
+func WrapPDFText(text string, size, maxWidth float64) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		var line []rune
+		width := 0.0
+		for _, r := range paragraph {
+			w := PDFTextWidth(string(r), size)
+			if width+w > maxWidth && len(line) > 0 {
+				lines = append(lines, string(line))
+				line, width = nil, 0
+			}
+			line = append(line, r)
+			width += w
+		}
+		lines = append(lines, string(line))
+	}
+	return lines
+}
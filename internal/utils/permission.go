@@ -1,7 +1,9 @@
 package utils
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"yun-nian-memorial/internal/models"
 
 	"github.com/google/uuid"
@@ -43,15 +45,9 @@ func (pm *PermissionManager) CanAccessMemorial(userID, memorialID string) (bool,
 		return false, "", fmt.Errorf("无权访问私密纪念馆")
 	}
 
-	// 3. 家族可见的纪念馆，检查是否为家族成员
+	// 3. 家族可见的纪念馆，检查是否为家族成员（含宗族与支系继承的成员身份）
 	if memorial.PrivacyLevel == 1 {
-		var count int64
-		pm.db.Table("memorial_families mf").
-			Joins("JOIN family_members fm ON mf.family_id = fm.family_id").
-			Where("mf.memorial_id = ? AND fm.user_id = ?", memorialID, userID).
-			Count(&count)
-
-		if count > 0 {
+		if pm.IsMemorialFamilyMember(userID, memorialID) {
 			return true, "family", nil
 		}
 	}
@@ -98,7 +94,7 @@ func (pm *PermissionManager) CanManageFamily(userID, familyID string) (bool, err
 		return false, err
 	}
 
-	if !canAccess || role != "admin" {
+	if !canAccess || !IsFamilyManagerRole(role) {
 		return false, fmt.Errorf("需要管理员权限")
 	}
 
@@ -117,10 +113,11 @@ func (pm *PermissionManager) IsMemorialOwner(userID, memorialID string) (bool, e
 	return true, nil
 }
 
-// IsFamilyAdmin 检查用户是否为家族管理员
+// IsFamilyAdmin 检查用户是否为家族管理员（所有者或管理员）
 func (pm *PermissionManager) IsFamilyAdmin(userID, familyID string) (bool, error) {
 	var member models.FamilyMember
-	err := pm.db.Where("family_id = ? AND user_id = ? AND role = ?", familyID, userID, "admin").First(&member).Error
+	err := pm.db.Where("family_id = ? AND user_id = ? AND role IN ?", familyID, userID,
+		[]string{FamilyRoleOwner, FamilyRoleAdmin}).First(&member).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return false, nil
@@ -130,6 +127,276 @@ func (pm *PermissionManager) IsFamilyAdmin(userID, familyID string) (bool, error
 	return true, nil
 }
 
+// GetFamilyRole 获取用户在家族圈中的有效角色，非成员时按宗族与支系的继承规则计算
+func (pm *PermissionManager) GetFamilyRole(userID, familyID string) (string, error) {
+	var member models.FamilyMember
+	err := pm.db.Where("family_id = ? AND user_id = ?", familyID, userID).First(&member).Error
+	if err == nil {
+		return member.Role, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return "", fmt.Errorf("查询家族成员失败: %v", err)
+	}
+
+	ancestorIDs, branchIDs, err := pm.inheritingFamilyIDs(familyID)
+	if err != nil {
+		return "", err
+	}
+
+	var ancestorRoles, branchRoles []string
+	if len(ancestorIDs) > 0 {
+		pm.db.Model(&models.FamilyMember{}).
+			Where("family_id IN ? AND user_id = ?", ancestorIDs, userID).
+			Pluck("role", &ancestorRoles)
+	}
+	if len(branchIDs) > 0 {
+		pm.db.Model(&models.FamilyMember{}).
+			Where("family_id IN ? AND user_id = ?", branchIDs, userID).
+			Pluck("role", &branchRoles)
+	}
+
+	role := ResolveFamilyRole("", ancestorRoles, branchRoles)
+	if role == "" {
+		return "", fmt.Errorf("您不是此家族圈的成员")
+	}
+	return role, nil
+}
+
+// inheritingFamilyIDs 成员身份可以继承到本家族圈的上级和下级家族圈：
+// 上级沿途遇到私密支系即不再继承，下级为全部支系
+func (pm *PermissionManager) inheritingFamilyIDs(familyID string) ([]string, []string, error) {
+	chain, err := pm.familyChain(familyID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var ancestorIDs []string
+	for i := 0; i+1 < len(chain); i++ {
+		if !IsFamilyRollupVisible(chain[i].Visibility) {
+			break
+		}
+		ancestorIDs = append(ancestorIDs, chain[i+1].ID)
+	}
+
+	branchIDs, err := pm.GetFamilyBranchIDs(familyID, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ancestorIDs, branchIDs, nil
+}
+
+// GetFamilyMemberUserIDs 获取家族圈中有效角色不为空的全部用户：本身的成员，以及按 GetFamilyRole 的规则继承成员身份的用户
+func (pm *PermissionManager) GetFamilyMemberUserIDs(familyID string) ([]string, error) {
+	ancestorIDs, branchIDs, err := pm.inheritingFamilyIDs(familyID)
+	if err != nil {
+		return nil, err
+	}
+	familyIDs := append(append([]string{familyID}, ancestorIDs...), branchIDs...)
+
+	var userIDs []string
+	err = pm.db.Model(&models.FamilyMember{}).
+		Where("family_id IN ?", familyIDs).
+		Distinct().
+		Pluck("user_id", &userIDs).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询家族成员失败: %v", err)
+	}
+	return userIDs, nil
+}
+
+// familyChain 返回家族圈及其各级上级，从自身开始，家族圈不存在时返回空
+func (pm *PermissionManager) familyChain(familyID string) ([]models.Family, error) {
+	var chain []models.Family
+	currentID := familyID
+	for depth := 0; depth <= FamilyBranchMaxDepth && currentID != ""; depth++ {
+		var family models.Family
+		err := pm.db.Select("id, parent_id, visibility").First(&family, "id = ?", currentID).Error
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				break
+			}
+			return nil, fmt.Errorf("查询家族圈失败: %v", err)
+		}
+		chain = append(chain, family)
+
+		currentID = ""
+		if family.ParentID != nil {
+			currentID = *family.ParentID
+		}
+	}
+	return chain, nil
+}
+
+// GetFamilyAncestorIDs 获取家族圈的各级上级ID，由近及远
+func (pm *PermissionManager) GetFamilyAncestorIDs(familyID string) ([]string, error) {
+	chain, err := pm.familyChain(familyID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(chain))
+	for i := 1; i < len(chain); i++ {
+		ids = append(ids, chain[i].ID)
+	}
+	return ids, nil
+}
+
+// GetFamilyBranchIDs 获取家族圈的各级下级支系ID；rollupOnly 为 true 时跳过私密支系及其下级
+func (pm *PermissionManager) GetFamilyBranchIDs(familyID string, rollupOnly bool) ([]string, error) {
+	var ids []string
+	level := []string{familyID}
+	for depth := 0; depth < FamilyBranchMaxDepth && len(level) > 0; depth++ {
+		var branches []models.Family
+		if err := pm.db.Select("id, visibility").Where("parent_id IN ?", level).Find(&branches).Error; err != nil {
+			return nil, fmt.Errorf("查询支系失败: %v", err)
+		}
+
+		level = nil
+		for _, branch := range branches {
+			if rollupOnly && !IsFamilyRollupVisible(branch.Visibility) {
+				continue
+			}
+			ids = append(ids, branch.ID)
+			level = append(level, branch.ID)
+		}
+	}
+	return ids, nil
+}
+
+// IsMemorialFamilyMember 检查用户是否为纪念馆关联家族圈的成员，包括宗族与支系之间继承的成员身份
+func (pm *PermissionManager) IsMemorialFamilyMember(userID, memorialID string) bool {
+	var count int64
+	pm.db.Table("memorial_families mf").
+		Joins("JOIN family_members fm ON mf.family_id = fm.family_id").
+		Where("mf.memorial_id = ? AND fm.user_id = ?", memorialID, userID).
+		Count(&count)
+	if count > 0 {
+		return true
+	}
+
+	var familyIDs []string
+	pm.db.Model(&models.MemorialFamily{}).Where("memorial_id = ?", memorialID).Pluck("family_id", &familyIDs)
+	for _, familyID := range familyIDs {
+		if _, err := pm.GetFamilyRole(userID, familyID); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// GetMemorialFamilyMemberIDs 获取纪念馆关联家族圈的全部成员，包括宗族与支系之间继承的成员身份，与 IsMemorialFamilyMember 一致
+func (pm *PermissionManager) GetMemorialFamilyMemberIDs(memorialID string) ([]string, error) {
+	var familyIDs []string
+	if err := pm.db.Model(&models.MemorialFamily{}).Where("memorial_id = ?", memorialID).Pluck("family_id", &familyIDs).Error; err != nil {
+		return nil, fmt.Errorf("查询纪念馆家族失败: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	var userIDs []string
+	for _, familyID := range familyIDs {
+		memberIDs, err := pm.GetFamilyMemberUserIDs(familyID)
+		if err != nil {
+			return nil, err
+		}
+		for _, userID := range memberIDs {
+			if !seen[userID] {
+				seen[userID] = true
+				userIDs = append(userIDs, userID)
+			}
+		}
+	}
+	return userIDs, nil
+}
+
+// GetUserFamilyScopeIDs 获取用户能查看其内容的全部家族圈ID：所属家族圈、其各级上级，以及对上级可见的下级支系
+func (pm *PermissionManager) GetUserFamilyScopeIDs(userID string) ([]string, error) {
+	var familyIDs []string
+	if err := pm.db.Model(&models.FamilyMember{}).Where("user_id = ?", userID).Pluck("family_id", &familyIDs).Error; err != nil {
+		return nil, fmt.Errorf("查询用户家族失败: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	var scope []string
+	add := func(ids ...string) {
+		for _, id := range ids {
+			if !seen[id] {
+				seen[id] = true
+				scope = append(scope, id)
+			}
+		}
+	}
+	for _, familyID := range familyIDs {
+		add(familyID)
+		ancestors, err := pm.GetFamilyAncestorIDs(familyID)
+		if err != nil {
+			return nil, err
+		}
+		add(ancestors...)
+		branches, err := pm.GetFamilyBranchIDs(familyID, true)
+		if err != nil {
+			return nil, err
+		}
+		add(branches...)
+	}
+	return scope, nil
+}
+
+// GetFamilyPermissionMatrix 获取家族圈的权限矩阵：默认设置叠加所有者的修改
+func (pm *PermissionManager) GetFamilyPermissionMatrix(familyID string) (map[string][]string, error) {
+	matrix := DefaultFamilyPermissionMatrix()
+
+	var overrides []models.FamilyPermission
+	if err := pm.db.Where("family_id = ?", familyID).Find(&overrides).Error; err != nil {
+		return nil, fmt.Errorf("查询家族圈权限设置失败: %v", err)
+	}
+	for _, override := range overrides {
+		if !IsConfigurableFamilyAction(override.Action) {
+			continue
+		}
+		roles := []string{}
+		for _, role := range strings.Split(override.Roles, ",") {
+			if role = strings.TrimSpace(role); role != "" {
+				roles = append(roles, role)
+			}
+		}
+		matrix[override.Action] = roles
+	}
+
+	return matrix, nil
+}
+
+// CanFamily 检查用户能否在家族圈中执行操作
+func (pm *PermissionManager) CanFamily(userID, familyID, action string) (bool, error) {
+	role, err := pm.GetFamilyRole(userID, familyID)
+	if err != nil {
+		return false, err
+	}
+	if role == FamilyRoleOwner || action == FamilyActionView {
+		return true, nil
+	}
+
+	matrix, err := pm.GetFamilyPermissionMatrix(familyID)
+	if err != nil {
+		return false, err
+	}
+	return FamilyRoleAllowed(matrix, role, action), nil
+}
+
+// CheckFamilyPermission 检查用户能否在家族圈中执行操作，不能执行时返回统一的错误信息
+//
+// 查看类操作对非成员返回“您不是此家族圈的成员”，其余操作返回 FamilyPermissionDeniedMessage。
+func (pm *PermissionManager) CheckFamilyPermission(userID, familyID, action string) error {
+	allowed, err := pm.CanFamily(userID, familyID, action)
+	if err != nil {
+		if action != FamilyActionView && err.Error() == "您不是此家族圈的成员" {
+			return errors.New(FamilyPermissionDeniedMessage(action))
+		}
+		return err
+	}
+	if !allowed {
+		return errors.New(FamilyPermissionDeniedMessage(action))
+	}
+	return nil
+}
+
 // GetUserFamilies 获取用户所属的家族列表
 func (pm *PermissionManager) GetUserFamilies(userID string) ([]models.Family, error) {
 	var families []models.Family
@@ -153,21 +420,28 @@ func (pm *PermissionManager) GetUserAccessibleMemorials(userID string, page, pag
 	// 构建查询条件：用户创建的纪念馆 + 用户所属家族的纪念馆
 	query := pm.db.Model(&models.Memorial{}).Where("status = ?", 1)
 
-	// 子查询：用户所属家族的纪念馆
-	familyMemorialsQuery := pm.db.Table("memorial_families mf").
-		Select("mf.memorial_id").
-		Joins("JOIN family_members fm ON mf.family_id = fm.family_id").
-		Where("fm.user_id = ?", userID)
+	// 用户能查看的家族圈（含宗族与支系）关联的纪念馆
+	familyIDs, err := pm.GetUserFamilyScopeIDs(userID)
+	if err != nil {
+		return nil, 0, err
+	}
 
 	// 最终查询条件
-	query = query.Where("creator_id = ? OR id IN (?)", userID, familyMemorialsQuery)
+	if len(familyIDs) > 0 {
+		familyMemorialsQuery := pm.db.Table("memorial_families mf").
+			Select("mf.memorial_id").
+			Where("mf.family_id IN ?", familyIDs)
+		query = query.Where("creator_id = ? OR id IN (?)", userID, familyMemorialsQuery)
+	} else {
+		query = query.Where("creator_id = ?", userID)
+	}
 
 	// 计算总数
 	query.Count(&total)
 
 	// 分页查询
 	offset := (page - 1) * pageSize
-	err := query.Order("created_at DESC").
+	err = query.Order("created_at DESC").
 		Offset(offset).
 		Limit(pageSize).
 		Find(&memorials).Error
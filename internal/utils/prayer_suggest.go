@@ -0,0 +1,311 @@
+package utils
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// PrayerSample 推荐语料中的一条公开祈福
+type PrayerSample struct {
+	UserID       string
+	Text         string
+	Relationship string
+	CreatedAt    time.Time
+}
+
+// FestivalWindow 节日及其前后的祭扫时段（公历月日）
+type FestivalWindow struct {
+	Name       string
+	Month      time.Month
+	Day        int
+	DaysBefore int
+	DaysAfter  int
+}
+
+// ParseFestivalDate 解析 MM-DD 格式的节日日期
+func ParseFestivalDate(date string) (time.Month, int, bool) {
+	parts := strings.Split(date, "-")
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	month, err1 := strconv.Atoi(parts[0])
+	day, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil || month < 1 || month > 12 || day < 1 || day > 31 {
+		return 0, 0, false
+	}
+	return time.Month(month), day, true
+}
+
+// FestivalAt 返回 t 所在的节日时段名称，不在任何时段内时返回空串；
+// 多个时段重叠时取日期最近的节日
+func FestivalAt(t time.Time, festivals []FestivalWindow) string {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	best, bestDistance := "", math.MaxInt32
+	for _, f := range festivals {
+		// 跨年的时段（如除夕前后）需要同时检查前后一年
+		for _, year := range []int{t.Year() - 1, t.Year(), t.Year() + 1} {
+			date := time.Date(year, f.Month, f.Day, 0, 0, 0, 0, time.UTC)
+			offset := int(day.Sub(date).Hours() / 24)
+			if offset < -f.DaysBefore || offset > f.DaysAfter {
+				continue
+			}
+			distance := offset
+			if distance < 0 {
+				distance = -distance
+			}
+			if distance < bestDistance {
+				best, bestDistance = f.Name, distance
+			}
+		}
+	}
+	return best
+}
+
+// PrayerSuggestionOptions 推荐索引构建参数
+type PrayerSuggestionOptions struct {
+	SimilarityThreshold float64 // 归一化后字符二元组的 Jaccard 相似度阈值，达到即视为近似重复
+	MinContributors     int     // 至少由多少位不同用户发布过，避免把个人化的内容推荐给他人
+	MinLength           int     // 推荐内容最短字符数
+	MaxLength           int     // 推荐内容最长字符数
+	MaxSuggestions      int     // 索引保留的候选数量
+	Exclude             func(text string) bool
+}
+
+// DefaultPrayerSuggestionOptions 默认构建参数
+func DefaultPrayerSuggestionOptions() PrayerSuggestionOptions {
+	return PrayerSuggestionOptions{
+		SimilarityThreshold: 0.6,
+		MinContributors:     3,
+		MinLength:           4,
+		MaxLength:           60,
+		MaxSuggestions:      500,
+	}
+}
+
+// PrayerSuggestion 推荐的祈福内容
+type PrayerSuggestion struct {
+	Content      string  `json:"content"`
+	UsageCount   int     `json:"usage_count"`        // 近似内容的发布次数
+	Contributors int     `json:"contributors"`       // 发布过的用户数
+	Festival     string  `json:"festival,omitempty"` // 该内容集中使用的节日
+	Score        float64 `json:"score"`
+}
+
+// PrayerSuggestionIndex 由公开祈福聚类得到的推荐索引，构建后只读，可并发排序
+type PrayerSuggestionIndex struct {
+	BuiltAt     time.Time
+	SampleCount int
+	clusters    []*prayerCluster
+}
+
+type prayerCluster struct {
+	bigrams        map[string]struct{}
+	variants       map[string]int
+	users          map[string]struct{}
+	total          int
+	byRelationship map[string]int
+	byFestival     map[string]int
+	representative string
+}
+
+// 个性化权重：关系和节日的使用次数按对数加权
+const (
+	prayerRelationshipWeight = 1.0
+	prayerFestivalWeight     = 1.5
+	// 主要在某个节日使用的内容（占比达到该值）在其它时间降权
+	prayerSeasonalShare   = 0.6
+	prayerOffSeasonFactor = 0.5
+)
+
+// BuildPrayerSuggestionIndex 将公开祈福按近似重复聚类，统计使用次数以及按关系、节日的分布
+func BuildPrayerSuggestionIndex(samples []PrayerSample, festivals []FestivalWindow, opts PrayerSuggestionOptions) *PrayerSuggestionIndex {
+	index := &PrayerSuggestionIndex{BuiltAt: time.Now(), SampleCount: len(samples)}
+
+	var clusters []*prayerCluster
+	exact := make(map[string]*prayerCluster)
+	inverted := make(map[string][]int)
+
+	for _, sample := range samples {
+		text := strings.TrimSpace(sample.Text)
+		normalized, _ := NormalizeSensitiveText(text)
+		if len(normalized) == 0 {
+			continue
+		}
+		key := string(normalized)
+
+		cluster := exact[key]
+		if cluster == nil {
+			grams := textBigrams(normalized)
+			cluster = findSimilarCluster(clusters, inverted, grams, opts.SimilarityThreshold)
+			if cluster == nil {
+				cluster = &prayerCluster{
+					bigrams:        grams,
+					variants:       make(map[string]int),
+					users:          make(map[string]struct{}),
+					byRelationship: make(map[string]int),
+					byFestival:     make(map[string]int),
+				}
+				for gram := range grams {
+					inverted[gram] = append(inverted[gram], len(clusters))
+				}
+				clusters = append(clusters, cluster)
+			}
+			exact[key] = cluster
+		}
+
+		cluster.total++
+		cluster.variants[text]++
+		cluster.users[sample.UserID] = struct{}{}
+		if sample.Relationship != "" {
+			cluster.byRelationship[sample.Relationship]++
+		}
+		if festival := FestivalAt(sample.CreatedAt, festivals); festival != "" {
+			cluster.byFestival[festival]++
+		}
+	}
+
+	for _, cluster := range clusters {
+		cluster.representative = pickRepresentative(cluster.variants)
+		length := utf8.RuneCountInString(cluster.representative)
+		if len(cluster.users) < opts.MinContributors || length < opts.MinLength || length > opts.MaxLength {
+			continue
+		}
+		if opts.Exclude != nil && opts.Exclude(cluster.representative) {
+			continue
+		}
+		// 排序阶段不再需要二元组
+		cluster.bigrams = nil
+		index.clusters = append(index.clusters, cluster)
+	}
+
+	sort.SliceStable(index.clusters, func(i, j int) bool {
+		return index.clusters[i].total > index.clusters[j].total
+	})
+	if opts.MaxSuggestions > 0 && len(index.clusters) > opts.MaxSuggestions {
+		index.clusters = index.clusters[:opts.MaxSuggestions]
+	}
+	return index
+}
+
+// Len 索引中的候选数量
+func (idx *PrayerSuggestionIndex) Len() int {
+	if idx == nil {
+		return 0
+	}
+	return len(idx.clusters)
+}
+
+// Rank 按使用次数排序，并根据与逝者的关系和当前节日个性化加权
+func (idx *PrayerSuggestionIndex) Rank(relationship, festival string, limit int) []PrayerSuggestion {
+	if idx == nil || limit <= 0 {
+		return nil
+	}
+
+	suggestions := make([]PrayerSuggestion, 0, len(idx.clusters))
+	for _, cluster := range idx.clusters {
+		score := math.Log1p(float64(cluster.total))
+		if relationship != "" {
+			score += prayerRelationshipWeight * math.Log1p(float64(cluster.byRelationship[relationship]))
+		}
+
+		seasonal, share := cluster.dominantFestival()
+		if festival != "" {
+			score += prayerFestivalWeight * math.Log1p(float64(cluster.byFestival[festival]))
+		}
+		if seasonal != "" && seasonal != festival && share >= prayerSeasonalShare {
+			score *= prayerOffSeasonFactor
+		}
+
+		suggestion := PrayerSuggestion{
+			Content:      cluster.representative,
+			UsageCount:   cluster.total,
+			Contributors: len(cluster.users),
+			Score:        math.Round(score*1000) / 1000,
+		}
+		if share >= prayerSeasonalShare {
+			suggestion.Festival = seasonal
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		if suggestions[i].UsageCount != suggestions[j].UsageCount {
+			return suggestions[i].UsageCount > suggestions[j].UsageCount
+		}
+		return suggestions[i].Content < suggestions[j].Content
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
+
+// dominantFestival 使用次数最多的节日及其占总使用次数的比例
+func (c *prayerCluster) dominantFestival() (string, float64) {
+	best, bestCount := "", 0
+	for festival, count := range c.byFestival {
+		if count > bestCount || (count == bestCount && festival < best) {
+			best, bestCount = festival, count
+		}
+	}
+	if bestCount == 0 {
+		return "", 0
+	}
+	return best, float64(bestCount) / float64(c.total)
+}
+
+// findSimilarCluster 通过二元组倒排索引查找相似度达到阈值的已有聚类
+func findSimilarCluster(clusters []*prayerCluster, inverted map[string][]int, grams map[string]struct{}, threshold float64) *prayerCluster {
+	shared := make(map[int]int)
+	for gram := range grams {
+		for _, id := range inverted[gram] {
+			shared[id]++
+		}
+	}
+
+	bestID, bestScore := -1, 0.0
+	for id, count := range shared {
+		union := len(grams) + len(clusters[id].bigrams) - count
+		score := float64(count) / float64(union)
+		if score > bestScore || (score == bestScore && id < bestID) {
+			bestID, bestScore = id, score
+		}
+	}
+	if bestID < 0 || bestScore < threshold {
+		return nil
+	}
+	return clusters[bestID]
+}
+
+// textBigrams 字符二元组集合，单字文本使用单字本身
+func textBigrams(runes []rune) map[string]struct{} {
+	grams := make(map[string]struct{})
+	if len(runes) == 1 {
+		grams[string(runes)] = struct{}{}
+		return grams
+	}
+	for i := 0; i+1 < len(runes); i++ {
+		grams[string(runes[i:i+2])] = struct{}{}
+	}
+	return grams
+}
+
+// pickRepresentative 选出现次数最多的原文作为展示内容，次数相同时取较短者
+func pickRepresentative(variants map[string]int) string {
+	best, bestCount := "", 0
+	for text, count := range variants {
+		if count > bestCount ||
+			(count == bestCount && (utf8.RuneCountInString(text) < utf8.RuneCountInString(best) ||
+				(utf8.RuneCountInString(text) == utf8.RuneCountInString(best) && text < best))) {
+			best, bestCount = text, count
+		}
+	}
+	return best
+}
@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testFestivals = []FestivalWindow{
+	{Name: "清明节", Month: time.April, Day: 5, DaysBefore: 3, DaysAfter: 3},
+	{Name: "除夕", Month: time.December, Day: 31, DaysBefore: 3, DaysAfter: 3},
+}
+
+func samplesOf(text, relationship string, users int, at time.Time) []PrayerSample {
+	samples := make([]PrayerSample, 0, users)
+	for i := 0; i < users; i++ {
+		samples = append(samples, PrayerSample{
+			UserID:       fmt.Sprintf("%s-%s-%d", text, relationship, i),
+			Text:         text,
+			Relationship: relationship,
+			CreatedAt:    at,
+		})
+	}
+	return samples
+}
+
+func TestFestivalAt(t *testing.T) {
+	assert.Equal(t, "清明节", FestivalAt(time.Date(2024, 4, 2, 10, 0, 0, 0, time.Local), testFestivals))
+	assert.Equal(t, "清明节", FestivalAt(time.Date(2024, 4, 8, 23, 0, 0, 0, time.Local), testFestivals))
+	assert.Equal(t, "", FestivalAt(time.Date(2024, 4, 9, 0, 0, 0, 0, time.Local), testFestivals))
+	// 跨年时段
+	assert.Equal(t, "除夕", FestivalAt(time.Date(2025, 1, 2, 0, 0, 0, 0, time.Local), testFestivals))
+
+	month, day, ok := ParseFestivalDate("04-05")
+	assert.True(t, ok)
+	assert.Equal(t, time.April, month)
+	assert.Equal(t, 5, day)
+	_, _, ok = ParseFestivalDate("4/5")
+	assert.False(t, ok)
+}
+
+func TestPrayerSuggestionNearDuplicates(t *testing.T) {
+	plain := time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local)
+	var samples []PrayerSample
+	samples = append(samples, samplesOf("愿您在天堂安好，保佑家人平安健康", "", 3, plain)...)
+	samples = append(samples, samplesOf("愿您在天堂安好保佑家人平安健康！", "", 2, plain)...)
+	samples = append(samples, samplesOf("願您在天堂安好，保佑家人平安健康", "", 1, plain)...)
+	samples = append(samples, samplesOf("思念如潮水，愿您安息", "", 3, plain)...)
+	// 只有一位用户发布过的内容不进入推荐
+	samples = append(samples, samplesOf("爷爷，小明今天考上大学了", "", 1, plain)...)
+
+	index := BuildPrayerSuggestionIndex(samples, testFestivals, DefaultPrayerSuggestionOptions())
+	require.Equal(t, 2, index.Len())
+
+	ranked := index.Rank("", "", 10)
+	require.Len(t, ranked, 2)
+	assert.Equal(t, "愿您在天堂安好，保佑家人平安健康", ranked[0].Content)
+	assert.Equal(t, 6, ranked[0].UsageCount)
+	assert.Equal(t, 6, ranked[0].Contributors)
+	assert.Equal(t, "思念如潮水，愿您安息", ranked[1].Content)
+}
+
+func TestPrayerSuggestionPersonalisation(t *testing.T) {
+	plain := time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local)
+	qingming := time.Date(2024, 4, 4, 9, 0, 0, 0, time.Local)
+
+	var samples []PrayerSample
+	samples = append(samples, samplesOf("愿您在天堂安好", "", 8, plain)...)
+	samples = append(samples, samplesOf("妈妈，女儿永远爱您", "parent", 5, plain)...)
+	samples = append(samples, samplesOf("清明时节，思念绵绵", "", 5, qingming)...)
+
+	index := BuildPrayerSuggestionIndex(samples, testFestivals, DefaultPrayerSuggestionOptions())
+
+	general := index.Rank("", "", 3)
+	assert.Equal(t, "愿您在天堂安好", general[0].Content)
+	// 清明专用的内容在其它时间降权
+	assert.Equal(t, "清明时节，思念绵绵", general[2].Content)
+	assert.Equal(t, "清明节", general[2].Festival)
+
+	assert.Equal(t, "妈妈，女儿永远爱您", index.Rank("parent", "", 3)[0].Content)
+	assert.Equal(t, "清明时节，思念绵绵", index.Rank("", "清明节", 3)[0].Content)
+}
+
+func TestPrayerSuggestionExclude(t *testing.T) {
+	plain := time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local)
+	samples := append(samplesOf("愿您在天堂安好", "", 3, plain), samplesOf("短", "", 5, plain)...)
+
+	opts := DefaultPrayerSuggestionOptions()
+	opts.Exclude = func(text string) bool { return text == "愿您在天堂安好" }
+	index := BuildPrayerSuggestionIndex(samples, nil, opts)
+	assert.Equal(t, 0, index.Len())
+
+	var empty *PrayerSuggestionIndex
+	assert.Nil(t, empty.Rank("", "", 5))
+}
@@ -0,0 +1,552 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+)
+
+// QRCode 二维码模块矩阵。只实现邀请链接等短文本需要的部分：
+// 字节模式、纠错等级 M、版本 1-10（最多 213 字节），按规范选择惩罚分最低的掩码
+type QRCode struct {
+	Version int
+	Size    int
+	modules [][]bool
+}
+
+// ErrQRCodeTooLong 内容超出支持的最大容量
+var ErrQRCodeTooLong = errors.New("二维码内容过长")
+
+// 纠错等级 M 下各版本的分块：每块纠错码字数、各组块数与每块数据码字数
+type qrBlockLayout struct {
+	ecPerBlock int
+	groups     [][2]int // {块数, 每块数据码字数}
+}
+
+var qrLayoutsM = []qrBlockLayout{
+	1:  {10, [][2]int{{1, 16}}},
+	2:  {16, [][2]int{{1, 28}}},
+	3:  {26, [][2]int{{1, 44}}},
+	4:  {18, [][2]int{{2, 32}}},
+	5:  {24, [][2]int{{2, 43}}},
+	6:  {16, [][2]int{{4, 27}}},
+	7:  {18, [][2]int{{4, 31}}},
+	8:  {22, [][2]int{{2, 38}, {2, 39}}},
+	9:  {22, [][2]int{{3, 36}, {2, 37}}},
+	10: {26, [][2]int{{4, 43}, {1, 44}}},
+}
+
+// 各版本校正图形的中心坐标
+var qrAlignmentPositions = [][]int{
+	2:  {6, 18},
+	3:  {6, 22},
+	4:  {6, 26},
+	5:  {6, 30},
+	6:  {6, 34},
+	7:  {6, 22, 38},
+	8:  {6, 24, 42},
+	9:  {6, 26, 46},
+	10: {6, 28, 50},
+}
+
+const qrMaxVersion = 10
+
+func (l qrBlockLayout) dataCodewords() int {
+	total := 0
+	for _, group := range l.groups {
+		total += group[0] * group[1]
+	}
+	return total
+}
+
+// EncodeQRCode 将文本编码为二维码，自动选择能容纳内容的最小版本
+func EncodeQRCode(text string) (*QRCode, error) {
+	data := []byte(text)
+
+	version := 0
+	for v := 1; v <= qrMaxVersion; v++ {
+		countBits := 8
+		if v >= 10 {
+			countBits = 16
+		}
+		if 4+countBits+len(data)*8 <= qrLayoutsM[v].dataCodewords()*8 {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, ErrQRCodeTooLong
+	}
+
+	codewords := qrInterleave(version, qrDataCodewords(version, data))
+
+	q := newQRCode(version)
+	function := q.drawFunctionPatterns()
+	q.drawCodewords(codewords, function)
+
+	bestMask, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		q.applyMask(mask, function)
+		q.drawFormatBits(mask)
+		if penalty := q.penalty(); bestPenalty < 0 || penalty < bestPenalty {
+			bestMask, bestPenalty = mask, penalty
+		}
+		q.applyMask(mask, function) // 掩码为异或，再次应用即还原
+	}
+	q.applyMask(bestMask, function)
+	q.drawFormatBits(bestMask)
+
+	return q, nil
+}
+
+// Dark 返回第 y 行第 x 列是否为深色模块
+func (q *QRCode) Dark(x, y int) bool {
+	if x < 0 || y < 0 || x >= q.Size || y >= q.Size {
+		return false
+	}
+	return q.modules[y][x]
+}
+
+// PNG 渲染为黑白 PNG，scale 为每个模块的像素数，四周保留 4 个模块的静区
+func (q *QRCode) PNG(scale int) ([]byte, error) {
+	if scale < 1 {
+		scale = 1
+	}
+	const quiet = 4
+	width := (q.Size + quiet*2) * scale
+
+	img := image.NewGray(image.Rect(0, 0, width, width))
+	for i := range img.Pix {
+		img.Pix[i] = 0xFF
+	}
+	for y := 0; y < q.Size; y++ {
+		for x := 0; x < q.Size; x++ {
+			if !q.modules[y][x] {
+				continue
+			}
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetGray((x+quiet)*scale+dx, (y+quiet)*scale+dy, color.Gray{Y: 0})
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SVG 渲染为 SVG，每个模块为一个单位，四周保留 4 个模块的静区
+func (q *QRCode) SVG() string {
+	const quiet = 4
+	width := q.Size + quiet*2
+
+	var path strings.Builder
+	for y := 0; y < q.Size; y++ {
+		for x := 0; x < q.Size; x++ {
+			if q.modules[y][x] {
+				fmt.Fprintf(&path, "M%d,%dh1v1h-1z", x+quiet, y+quiet)
+			}
+		}
+	}
+
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+
+		`<rect width="100%%" height="100%%" fill="#FFFFFF"/><path d="%s" fill="#000000"/></svg>`,
+		width, width, path.String())
+}
+
+func newQRCode(version int) *QRCode {
+	size := version*4 + 17
+	modules := make([][]bool, size)
+	for i := range modules {
+		modules[i] = make([]bool, size)
+	}
+	return &QRCode{Version: version, Size: size, modules: modules}
+}
+
+// qrDataCodewords 字节模式编码：模式指示符、字符计数、数据、终止符和填充码字
+func qrDataCodewords(version int, data []byte) []byte {
+	capacity := qrLayoutsM[version].dataCodewords()
+
+	var bits qrBitBuffer
+	bits.append(0x4, 4)
+	if version >= 10 {
+		bits.append(len(data), 16)
+	} else {
+		bits.append(len(data), 8)
+	}
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+
+	terminator := capacity*8 - len(bits)
+	if terminator > 4 {
+		terminator = 4
+	}
+	bits.append(0, terminator)
+	bits.append(0, (8-len(bits)%8)%8)
+
+	codewords := make([]byte, 0, capacity)
+	for i := 0; i < len(bits); i += 8 {
+		var b byte
+		for j := 0; j < 8; j++ {
+			b = b<<1 | bits[i+j]
+		}
+		codewords = append(codewords, b)
+	}
+	for pad := byte(0xEC); len(codewords) < capacity; pad ^= 0xEC ^ 0x11 {
+		codewords = append(codewords, pad)
+	}
+	return codewords
+}
+
+// qrInterleave 分块计算纠错码，并按列交错数据码字和纠错码字
+func qrInterleave(version int, data []byte) []byte {
+	layout := qrLayoutsM[version]
+	divisor := reedSolomonDivisor(layout.ecPerBlock)
+
+	var blocks, ecBlocks [][]byte
+	offset := 0
+	for _, group := range layout.groups {
+		for i := 0; i < group[0]; i++ {
+			block := data[offset : offset+group[1]]
+			offset += group[1]
+			blocks = append(blocks, block)
+			ecBlocks = append(ecBlocks, reedSolomonRemainder(block, divisor))
+		}
+	}
+
+	var result []byte
+	maxLen := len(blocks[len(blocks)-1])
+	for i := 0; i < maxLen; i++ {
+		for _, block := range blocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+	for i := 0; i < layout.ecPerBlock; i++ {
+		for _, ec := range ecBlocks {
+			result = append(result, ec[i])
+		}
+	}
+	return result
+}
+
+// drawFunctionPatterns 绘制定位、分隔、定时、校正图形和版本信息，返回功能区标记
+func (q *QRCode) drawFunctionPatterns() [][]bool {
+	function := make([][]bool, q.Size)
+	for i := range function {
+		function[i] = make([]bool, q.Size)
+	}
+	set := func(x, y int, dark bool) {
+		q.modules[y][x] = dark
+		function[y][x] = true
+	}
+
+	// 定时图形
+	for i := 0; i < q.Size; i++ {
+		set(6, i, i%2 == 0)
+		set(i, 6, i%2 == 0)
+	}
+
+	// 定位图形及分隔符
+	for _, corner := range [][2]int{{3, 3}, {q.Size - 4, 3}, {3, q.Size - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := corner[0]+dx, corner[1]+dy
+				if x < 0 || y < 0 || x >= q.Size || y >= q.Size {
+					continue
+				}
+				dist := qrMaxInt(qrAbs(dx), qrAbs(dy))
+				set(x, y, dist != 2 && dist != 4)
+			}
+		}
+	}
+
+	// 校正图形，跳过与定位图形重叠的三个角
+	if q.Version >= 2 {
+		positions := qrAlignmentPositions[q.Version]
+		last := len(positions) - 1
+		for i, cy := range positions {
+			for j, cx := range positions {
+				if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+					continue
+				}
+				for dy := -2; dy <= 2; dy++ {
+					for dx := -2; dx <= 2; dx++ {
+						set(cx+dx, cy+dy, qrMaxInt(qrAbs(dx), qrAbs(dy)) != 1)
+					}
+				}
+			}
+		}
+	}
+
+	// 格式信息区域先占位，选定掩码后再写入；固定的深色模块
+	for i := 0; i < 9; i++ {
+		function[8][i] = true
+		function[i][8] = true
+	}
+	for i := 0; i < 8; i++ {
+		function[8][q.Size-1-i] = true
+		function[q.Size-1-i][8] = true
+	}
+	set(8, q.Size-8, true)
+
+	// 版本信息（版本 7 及以上）
+	if q.Version >= 7 {
+		bits := qrVersionBits(q.Version)
+		for i := 0; i < 18; i++ {
+			dark := (bits>>i)&1 == 1
+			a, b := q.Size-11+i%3, i/3
+			set(a, b, dark)
+			set(b, a, dark)
+		}
+	}
+
+	return function
+}
+
+// drawFormatBits 写入纠错等级 M 和掩码编号组成的格式信息
+func (q *QRCode) drawFormatBits(mask int) {
+	bits := qrFormatBits(mask)
+	bit := func(i int) bool { return (bits>>i)&1 == 1 }
+
+	for i := 0; i <= 5; i++ {
+		q.modules[i][8] = bit(i)
+	}
+	q.modules[7][8] = bit(6)
+	q.modules[8][8] = bit(7)
+	q.modules[8][7] = bit(8)
+	for i := 9; i < 15; i++ {
+		q.modules[8][14-i] = bit(i)
+	}
+
+	for i := 0; i < 8; i++ {
+		q.modules[8][q.Size-1-i] = bit(i)
+	}
+	for i := 8; i < 15; i++ {
+		q.modules[q.Size-15+i][8] = bit(i)
+	}
+	q.modules[q.Size-8][8] = true
+}
+
+// drawCodewords 按之字形从右下角开始逐列放置码字，跳过功能区
+func (q *QRCode) drawCodewords(codewords []byte, function [][]bool) {
+	i := 0
+	total := len(codewords) * 8
+	for right := q.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < q.Size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = q.Size - 1 - vert
+				}
+				if function[y][x] || i >= total {
+					continue
+				}
+				q.modules[y][x] = (codewords[i>>3]>>(7-uint(i&7)))&1 == 1
+				i++
+			}
+		}
+	}
+}
+
+// applyMask 对数据区域应用掩码图形
+func (q *QRCode) applyMask(mask int, function [][]bool) {
+	for y := 0; y < q.Size; y++ {
+		for x := 0; x < q.Size; x++ {
+			if function[y][x] {
+				continue
+			}
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert {
+				q.modules[y][x] = !q.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty 按规范的四条规则计算掩码惩罚分：同色连续、2x2 同色块、类定位图形、深浅比例
+func (q *QRCode) penalty() int {
+	score := 0
+
+	line := func(get func(i int) bool) {
+		run := 1
+		for i := 1; i <= q.Size; i++ {
+			if i < q.Size && get(i) == get(i-1) {
+				run++
+				continue
+			}
+			if run >= 5 {
+				score += run - 2
+			}
+			run = 1
+		}
+
+		// 1:1:3:1:1 的类定位图形，前后有 4 个浅色模块
+		pattern := []bool{true, false, true, true, true, false, true}
+		for i := 0; i+7 <= q.Size; i++ {
+			match := true
+			for k, dark := range pattern {
+				if get(i+k) != dark {
+					match = false
+					break
+				}
+			}
+			if !match {
+				continue
+			}
+			lightBefore, lightAfter := true, true
+			for k := 1; k <= 4; k++ {
+				if i-k >= 0 && get(i-k) {
+					lightBefore = false
+				}
+				if i+6+k < q.Size && get(i+6+k) {
+					lightAfter = false
+				}
+			}
+			if lightBefore || lightAfter {
+				score += 40
+			}
+		}
+	}
+	for y := 0; y < q.Size; y++ {
+		line(func(i int) bool { return q.modules[y][i] })
+	}
+	for x := 0; x < q.Size; x++ {
+		line(func(i int) bool { return q.modules[i][x] })
+	}
+
+	dark := 0
+	for y := 0; y < q.Size; y++ {
+		for x := 0; x < q.Size; x++ {
+			if q.modules[y][x] {
+				dark++
+			}
+			if x+1 < q.Size && y+1 < q.Size {
+				c := q.modules[y][x]
+				if q.modules[y][x+1] == c && q.modules[y+1][x] == c && q.modules[y+1][x+1] == c {
+					score += 3
+				}
+			}
+		}
+	}
+
+	total := q.Size * q.Size
+	deviation := qrAbs(dark*20-total*10) / total
+	score += deviation * 10
+
+	return score
+}
+
+// qrFormatBits 纠错等级 M（格式位 00）与掩码编号的 BCH(15,5) 编码
+func qrFormatBits(mask int) int {
+	data := mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	return (data<<10 | rem) ^ 0x5412
+}
+
+// qrVersionBits 版本号的 BCH(18,6) 编码
+func qrVersionBits(version int) int {
+	rem := version
+	for i := 0; i < 12; i++ {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+	}
+	return version<<12 | rem
+}
+
+type qrBitBuffer []byte
+
+func (b *qrBitBuffer) append(value, length int) {
+	for i := length - 1; i >= 0; i-- {
+		*b = append(*b, byte(value>>uint(i))&1)
+	}
+}
+
+// reedSolomonDivisor 生成 GF(256) 上指定次数的 Reed-Solomon 生成多项式（去掉首项系数）
+func reedSolomonDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return result
+}
+
+// reedSolomonRemainder 计算数据多项式除以生成多项式的余数，即纠错码字
+func reedSolomonRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i := range result {
+			result[i] ^= gfMultiply(divisor[i], factor)
+		}
+	}
+	return result
+}
+
+// gfMultiply GF(256) 乘法，本原多项式 x^8+x^4+x^3+x^2+1
+func gfMultiply(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int((y>>uint(i))&1) * int(x)
+	}
+	return byte(z)
+}
+
+func qrAbs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func qrMaxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,221 @@
+package utils
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReedSolomonRemainder(t *testing.T) {
+	// 规范示例 "HELLO WORLD"（1-M）的数据码字及纠错码字
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	expected := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	assert.Equal(t, expected, reedSolomonRemainder(data, reedSolomonDivisor(10)))
+}
+
+func TestQRFormatAndVersionBits(t *testing.T) {
+	assert.Equal(t, 0x5412, qrFormatBits(0)) // 101010000010010
+	assert.Equal(t, 0x4F97, qrFormatBits(6)) // 100111110010111
+	assert.Equal(t, 0x07C94, qrVersionBits(7))
+}
+
+func TestEncodeQRCodeVersionSelection(t *testing.T) {
+	q, err := EncodeQRCode("hello")
+	require.NoError(t, err)
+	assert.Equal(t, 1, q.Version)
+	assert.Equal(t, 21, q.Size)
+
+	link := "https://example.com/family/invite/" + strings.Repeat("a", 48)
+	q, err = EncodeQRCode(link)
+	require.NoError(t, err)
+	assert.Equal(t, 5, q.Version)
+
+	q, err = EncodeQRCode(strings.Repeat("x", 213))
+	require.NoError(t, err)
+	assert.Equal(t, 10, q.Version)
+
+	_, err = EncodeQRCode(strings.Repeat("x", 214))
+	assert.ErrorIs(t, err, ErrQRCodeTooLong)
+}
+
+func TestEncodeQRCodeFunctionPatterns(t *testing.T) {
+	q, err := EncodeQRCode("https://example.com/family/invite/token")
+	require.NoError(t, err)
+
+	// 三个定位图形：外框深色、分隔符浅色、中心 3x3 深色
+	for _, corner := range [][2]int{{0, 0}, {q.Size - 7, 0}, {0, q.Size - 7}} {
+		x, y := corner[0], corner[1]
+		assert.True(t, q.Dark(x, y))
+		assert.True(t, q.Dark(x+6, y+6))
+		assert.False(t, q.Dark(x+1, y+1))
+		assert.True(t, q.Dark(x+3, y+3))
+	}
+	assert.False(t, q.Dark(7, 7))
+	// 定时图形交替
+	for i := 8; i < q.Size-8; i++ {
+		assert.Equal(t, i%2 == 0, q.Dark(i, 6))
+		assert.Equal(t, i%2 == 0, q.Dark(6, i))
+	}
+	// 固定深色模块
+	assert.True(t, q.Dark(8, q.Size-8))
+}
+
+func TestEncodeQRCodeRoundTrip(t *testing.T) {
+	for _, text := range []string{
+		"A",
+		"https://example.com/family/invite/Zk3pQ9xYv2LmN8rT",
+		"家族圈邀请：" + strings.Repeat("云念", 20),
+		strings.Repeat("0123456789", 21),
+	} {
+		q, err := EncodeQRCode(text)
+		require.NoError(t, err)
+		assert.Equal(t, text, decodeQRCodeForTest(t, q), "version %d", q.Version)
+	}
+}
+
+func TestQRCodeRender(t *testing.T) {
+	q, err := EncodeQRCode("hello")
+	require.NoError(t, err)
+
+	data, err := q.PNG(4)
+	require.NoError(t, err)
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, (21+8)*4, img.Bounds().Dx())
+
+	svg := q.SVG()
+	assert.True(t, strings.HasPrefix(svg, "<svg"))
+	assert.Contains(t, svg, `viewBox="0 0 29 29"`)
+}
+
+// decodeQRCodeForTest 按格式信息去掉掩码，逐列读回码字，解交错后解析字节模式数据
+func decodeQRCodeForTest(t *testing.T, q *QRCode) string {
+	t.Helper()
+
+	bits := 0
+	for i := 0; i <= 5; i++ {
+		if q.Dark(8, i) {
+			bits |= 1 << i
+		}
+	}
+	if q.Dark(8, 7) {
+		bits |= 1 << 6
+	}
+	if q.Dark(8, 8) {
+		bits |= 1 << 7
+	}
+	if q.Dark(7, 8) {
+		bits |= 1 << 8
+	}
+	for i := 9; i < 15; i++ {
+		if q.Dark(14-i, 8) {
+			bits |= 1 << i
+		}
+	}
+	mask := -1
+	for m := 0; m < 8; m++ {
+		if qrFormatBits(m) == bits {
+			mask = m
+		}
+	}
+	require.NotEqual(t, -1, mask, "格式信息无法识别")
+
+	clone := newQRCode(q.Version)
+	function := clone.drawFunctionPatterns()
+	for y := 0; y < q.Size; y++ {
+		copy(clone.modules[y], q.modules[y])
+	}
+	clone.applyMask(mask, function)
+
+	layout := qrLayoutsM[q.Version]
+	total := layout.dataCodewords()
+	for _, group := range layout.groups {
+		total += group[0] * layout.ecPerBlock
+	}
+	raw := make([]byte, total)
+	i := 0
+	for right := q.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < q.Size; vert++ {
+			for j := 0; j < 2; j++ {
+				x, y := right-j, vert
+				if (right+1)&2 == 0 {
+					y = q.Size - 1 - vert
+				}
+				if function[y][x] || i >= total*8 {
+					continue
+				}
+				if clone.modules[y][x] {
+					raw[i>>3] |= 1 << (7 - uint(i&7))
+				}
+				i++
+			}
+		}
+	}
+
+	// 解交错并校验每块纠错码
+	var blocks [][]byte
+	for _, group := range layout.groups {
+		for k := 0; k < group[0]; k++ {
+			blocks = append(blocks, make([]byte, 0, group[1]))
+		}
+	}
+	sizes := make([]int, 0, len(blocks))
+	for _, group := range layout.groups {
+		for k := 0; k < group[0]; k++ {
+			sizes = append(sizes, group[1])
+		}
+	}
+	pos := 0
+	for col := 0; col < sizes[len(sizes)-1]; col++ {
+		for b := range blocks {
+			if col < sizes[b] {
+				blocks[b] = append(blocks[b], raw[pos])
+				pos++
+			}
+		}
+	}
+	divisor := reedSolomonDivisor(layout.ecPerBlock)
+	for col := 0; col < layout.ecPerBlock; col++ {
+		for b := range blocks {
+			ec := reedSolomonRemainder(blocks[b][:sizes[b]], divisor)
+			require.Equal(t, ec[col], raw[pos], "纠错码不一致")
+			pos++
+		}
+	}
+
+	var data []byte
+	for _, block := range blocks {
+		data = append(data, block...)
+	}
+
+	var stream qrBitBuffer
+	for _, b := range data {
+		stream.append(int(b), 8)
+	}
+	read := func(n int) int {
+		v := 0
+		for k := 0; k < n; k++ {
+			v = v<<1 | int(stream[k])
+		}
+		stream = stream[n:]
+		return v
+	}
+	require.Equal(t, 0x4, read(4), "应为字节模式")
+	countBits := 8
+	if q.Version >= 10 {
+		countBits = 16
+	}
+	length := read(countBits)
+	out := make([]byte, length)
+	for k := range out {
+		out[k] = byte(read(8))
+	}
+	return string(out)
+}
@@ -0,0 +1,300 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 纪念日提醒规则支持的参数上限
+const (
+	ReminderMaxLeadDays       = 30    // 最多提前 30 天提醒
+	ReminderMaxLeadTimes      = 5     // 最多 5 个提前量
+	ReminderMaxDaysAfterDeath = 10000 // 逝世后第 N 天的上限
+)
+
+var errInvalidReminderRule = errors.New("无效的提醒规则")
+
+// ReminderRule 纪念日提醒的重复规则，取 RFC 5545 RRULE 的一个子集：
+//
+//	""                                  仅提醒日期当天一次
+//	FREQ=YEARLY                         每年公历同月同日
+//	FREQ=YEARLY;RSCALE=CHINESE          每年农历同月同日（RFC 7529）
+//	FREQ=YEARLY;INTERVAL=2;COUNT=5      每两年一次，共五次；UNTIL=20300101 表示截止日期
+//	X-DAYS-AFTER-DEATH=49               逝世第 49 天（逝世当天为第 1 天），可与 FREQ 组合为此后每年
+//
+// 年份重复时以首次日期所在的年（农历为农历年）起算，当年没有这一天时取月末，与家族传统一致。
+type ReminderRule struct {
+	Yearly         bool
+	Calendar       string // CalendarSolar 或 CalendarLunar
+	Interval       int
+	Count          int
+	Until          *time.Time
+	DaysAfterDeath int
+}
+
+// ParseReminderRule 解析提醒规则，空字符串表示只提醒一次
+func ParseReminderRule(rule string) (ReminderRule, error) {
+	r := ReminderRule{Calendar: CalendarSolar, Interval: 1}
+	rule = strings.TrimPrefix(strings.TrimSpace(rule), "RRULE:")
+	if rule == "" {
+		return r, nil
+	}
+
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(rule, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return r, errInvalidReminderRule
+		}
+		key, value := strings.ToUpper(strings.TrimSpace(kv[0])), strings.ToUpper(strings.TrimSpace(kv[1]))
+		if seen[key] {
+			return r, errInvalidReminderRule
+		}
+		seen[key] = true
+
+		switch key {
+		case "FREQ":
+			if value != "YEARLY" {
+				return r, errInvalidReminderRule
+			}
+			r.Yearly = true
+		case "RSCALE":
+			switch value {
+			case "GREGORIAN":
+				r.Calendar = CalendarSolar
+			case "CHINESE":
+				r.Calendar = CalendarLunar
+			default:
+				return r, errInvalidReminderRule
+			}
+		case "INTERVAL", "COUNT", "X-DAYS-AFTER-DEATH":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return r, errInvalidReminderRule
+			}
+			switch key {
+			case "INTERVAL":
+				r.Interval = n
+			case "COUNT":
+				r.Count = n
+			default:
+				if n > ReminderMaxDaysAfterDeath {
+					return r, errInvalidReminderRule
+				}
+				r.DaysAfterDeath = n
+			}
+		case "UNTIL":
+			until, err := time.Parse("20060102", strings.SplitN(value, "T", 2)[0])
+			if err != nil {
+				return r, errInvalidReminderRule
+			}
+			r.Until = &until
+		case "SKIP":
+			// 当年没有这一天时总是取月末，只接受与之相同的 BACKWARD
+			if value != "BACKWARD" {
+				return r, errInvalidReminderRule
+			}
+		default:
+			return r, errInvalidReminderRule
+		}
+	}
+
+	// 重复相关的参数只能和 FREQ 一起使用，COUNT 与 UNTIL 不能同时出现
+	if !r.Yearly && (seen["RSCALE"] || seen["INTERVAL"] || seen["COUNT"] || seen["UNTIL"] || seen["SKIP"]) {
+		return r, errInvalidReminderRule
+	}
+	if r.Count > 0 && r.Until != nil {
+		return r, errInvalidReminderRule
+	}
+	return r, nil
+}
+
+// String 规则的规范写法，可重新解析
+func (r ReminderRule) String() string {
+	var parts []string
+	if r.Yearly {
+		parts = append(parts, "FREQ=YEARLY")
+		if r.Calendar == CalendarLunar {
+			parts = append(parts, "RSCALE=CHINESE")
+		}
+		if r.Interval > 1 {
+			parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+		}
+		if r.Count > 0 {
+			parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+		}
+		if r.Until != nil {
+			parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+		}
+	}
+	if r.DaysAfterDeath > 0 {
+		parts = append(parts, "X-DAYS-AFTER-DEATH="+strconv.Itoa(r.DaysAfterDeath))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Start 首次提醒的日期：逝世第 N 天的规则按逝世日期推算（没有逝世日期时返回 false），否则为提醒日期
+func (r ReminderRule) Start(reminderDate time.Time, deathDate *time.Time) (time.Time, bool) {
+	if r.DaysAfterDeath > 0 {
+		if deathDate == nil {
+			return time.Time{}, false
+		}
+		return calendarDay(*deathDate).AddDate(0, 0, r.DaysAfterDeath-1), true
+	}
+	return calendarDay(reminderDate), true
+}
+
+// Occurrences 返回从 start 起按规则重复、落在 from 至 to 之间（含两端，只看年月日）的日期
+func (r ReminderRule) Occurrences(start, from, to time.Time) []time.Time {
+	start = calendarDay(start)
+	from, to = calendarDay(from), calendarDay(to)
+
+	if !r.Yearly {
+		if start.Before(from) || start.After(to) {
+			return nil
+		}
+		return []time.Time{start}
+	}
+
+	schedule, firstYear, ok := r.yearlySchedule(start)
+	if !ok {
+		return nil
+	}
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+
+	var dates []time.Time
+	for year, n := firstYear, 0; year <= to.Year(); year, n = year+interval, n+1 {
+		if r.Count > 0 && n >= r.Count {
+			break
+		}
+		date, ok := schedule.dateIn(year, schedule.Month, false)
+		if !ok || (r.Until != nil && date.After(*r.Until)) {
+			break
+		}
+		if !date.Before(from) && !date.After(to) && !date.Before(start) {
+			dates = append(dates, date)
+		}
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+	return dates
+}
+
+// Next 返回 from 当天或之后最近的一次，两年内没有时返回 false
+func (r ReminderRule) Next(start, from time.Time) (time.Time, bool) {
+	dates := r.Occurrences(start, from, from.AddDate(2, 0, 0))
+	if len(dates) == 0 {
+		return time.Time{}, false
+	}
+	return dates[0], true
+}
+
+// yearlySchedule 把首次日期换算为每年重复的月日，返回起算年（农历为农历年）
+func (r ReminderRule) yearlySchedule(start time.Time) (TraditionSchedule, int, bool) {
+	if r.Calendar == CalendarLunar {
+		lunar, ok := SolarToLunar(start)
+		if !ok {
+			return TraditionSchedule{}, 0, false
+		}
+		// 首次日期在闰月时，之后每年落在同名的非闰月
+		return TraditionSchedule{Calendar: CalendarLunar, Recurrence: RecurrenceYearly, Month: lunar.Month, Day: lunar.Day}, lunar.Year, true
+	}
+	return TraditionSchedule{Calendar: CalendarSolar, Recurrence: RecurrenceYearly, Month: int(start.Month()), Day: start.Day()}, start.Year(), true
+}
+
+// Describe 规则的中文描述，如“每年农历三月初五”“逝世第49天”“逝世第100天起每年”
+func (r ReminderRule) Describe(start time.Time) string {
+	var text string
+	if r.DaysAfterDeath > 0 {
+		text = fmt.Sprintf("逝世第%d天", r.DaysAfterDeath)
+		if !r.Yearly {
+			return text
+		}
+		text += "起"
+	}
+	if !r.Yearly {
+		return start.Format("2006年1月2日")
+	}
+
+	prefix := "每年"
+	if r.Interval > 1 {
+		prefix = fmt.Sprintf("每%d年", r.Interval)
+	}
+	if r.DaysAfterDeath > 0 {
+		text += prefix
+	} else if schedule, _, ok := r.yearlySchedule(start); ok {
+		schedule.Recurrence = RecurrenceYearly
+		text = prefix + strings.TrimPrefix(schedule.Describe(), "每年")
+	}
+	if r.Count > 0 {
+		text += fmt.Sprintf("，共%d次", r.Count)
+	}
+	if r.Until != nil {
+		text += "，至" + r.Until.Format("2006年1月2日")
+	}
+	return text
+}
+
+// ParseReminderLeadDays 校验提前提醒天数，去重后从大到小排列，为空时只在当天提醒
+func ParseReminderLeadDays(leadDays []int) ([]int, error) {
+	if len(leadDays) == 0 {
+		return []int{0}, nil
+	}
+	if len(leadDays) > ReminderMaxLeadTimes {
+		return nil, errors.New("无效的提前提醒天数")
+	}
+	seen := make(map[int]bool)
+	var result []int
+	for _, days := range leadDays {
+		if days < 0 || days > ReminderMaxLeadDays {
+			return nil, errors.New("无效的提前提醒天数")
+		}
+		if !seen[days] {
+			seen[days] = true
+			result = append(result, days)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(result)))
+	return result, nil
+}
+
+// ParseRemindTime 解析“时:分”格式的提醒时刻
+func ParseRemindTime(text string) (hour, minute int, err error) {
+	t, err := time.Parse("15:04", text)
+	if err != nil {
+		return 0, 0, errors.New("无效的提醒时间")
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// ReminderFireAt 某次提醒提前 leadDays 天、在 loc 时区的 hour:minute 发送的时间
+func ReminderFireAt(date time.Time, leadDays, hour, minute int, loc *time.Location) time.Time {
+	day := date.AddDate(0, 0, -leadDays)
+	return time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, loc)
+}
+
+// DueReminderLead 返回此刻应发送的提前量：已到发送时间、比已发送的提前量更近的里面最近的一个，
+// 错过的更早提前量不再补发。leadDays 从大到小排列，notified 为已发送的最近提前量，未发送过为 nil。
+// 提醒当天过后不再发送。
+func DueReminderLead(date time.Time, leadDays []int, hour, minute int, notified *int, now time.Time, loc *time.Location) (int, bool) {
+	local := now.In(loc)
+	if calendarDay(date).Before(time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)) {
+		return 0, false
+	}
+	due, found := 0, false
+	for _, lead := range leadDays {
+		if notified != nil && lead >= *notified {
+			continue
+		}
+		if !ReminderFireAt(date, lead, hour, minute, loc).After(now) {
+			due, found = lead, true
+		}
+	}
+	return due, found
+}
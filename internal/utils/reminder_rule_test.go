@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ruleDay(year, month, day int) time.Time {
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestParseReminderRule(t *testing.T) {
+	for _, rule := range []string{
+		"",
+		"FREQ=YEARLY",
+		"FREQ=YEARLY;RSCALE=CHINESE",
+		"FREQ=YEARLY;INTERVAL=2;COUNT=5",
+		"FREQ=YEARLY;UNTIL=20300101",
+		"X-DAYS-AFTER-DEATH=49",
+		"FREQ=YEARLY;X-DAYS-AFTER-DEATH=100",
+	} {
+		r, err := ParseReminderRule(rule)
+		require.NoError(t, err, rule)
+		assert.Equal(t, rule, r.String())
+	}
+
+	r, err := ParseReminderRule("RRULE:freq=yearly;rscale=chinese;skip=backward")
+	require.NoError(t, err)
+	assert.Equal(t, "FREQ=YEARLY;RSCALE=CHINESE", r.String())
+
+	for _, rule := range []string{
+		"FREQ=MONTHLY",
+		"FREQ=YEARLY;COUNT=0",
+		"FREQ=YEARLY;COUNT=2;UNTIL=20300101",
+		"FREQ=YEARLY;FREQ=YEARLY",
+		"RSCALE=CHINESE",
+		"FREQ=YEARLY;RSCALE=HEBREW",
+		"FREQ=YEARLY;BYMONTH=4",
+		"X-DAYS-AFTER-DEATH=0",
+		"FREQ",
+	} {
+		_, err := ParseReminderRule(rule)
+		assert.Error(t, err, rule)
+	}
+}
+
+func TestReminderRuleOccurrences(t *testing.T) {
+	yearly, _ := ParseReminderRule("FREQ=YEARLY")
+	assert.Equal(t, []time.Time{ruleDay(2024, 2, 29), ruleDay(2025, 2, 28)},
+		yearly.Occurrences(ruleDay(2020, 2, 29), ruleDay(2024, 1, 1), ruleDay(2025, 12, 31)))
+	// 首次日期之前没有
+	assert.Empty(t, yearly.Occurrences(ruleDay(2026, 5, 1), ruleDay(2024, 1, 1), ruleDay(2025, 12, 31)))
+
+	// 农历每年：2024-04-05 为农历二月廿七
+	lunar, _ := ParseReminderRule("FREQ=YEARLY;RSCALE=CHINESE")
+	assert.Equal(t, []time.Time{ruleDay(2025, 3, 26), ruleDay(2026, 4, 14)},
+		lunar.Occurrences(ruleDay(2024, 4, 5), ruleDay(2025, 1, 1), ruleDay(2026, 12, 31)))
+
+	limited, _ := ParseReminderRule("FREQ=YEARLY;INTERVAL=2;COUNT=2")
+	assert.Equal(t, []time.Time{ruleDay(2020, 6, 1), ruleDay(2022, 6, 1)},
+		limited.Occurrences(ruleDay(2020, 6, 1), ruleDay(2019, 1, 1), ruleDay(2030, 12, 31)))
+
+	until, _ := ParseReminderRule("FREQ=YEARLY;UNTIL=20220601")
+	assert.Len(t, until.Occurrences(ruleDay(2020, 6, 1), ruleDay(2019, 1, 1), ruleDay(2030, 12, 31)), 3)
+
+	once, _ := ParseReminderRule("")
+	assert.Equal(t, []time.Time{ruleDay(2025, 4, 4)}, once.Occurrences(ruleDay(2025, 4, 4), ruleDay(2025, 4, 4), ruleDay(2025, 4, 4)))
+}
+
+func TestReminderRuleDaysAfterDeath(t *testing.T) {
+	death := ruleDay(2025, 3, 1)
+	r, _ := ParseReminderRule("X-DAYS-AFTER-DEATH=7")
+	start, ok := r.Start(time.Time{}, &death)
+	require.True(t, ok)
+	assert.Equal(t, ruleDay(2025, 3, 7), start)
+	assert.Equal(t, "逝世第7天", r.Describe(start))
+
+	_, ok = r.Start(time.Time{}, nil)
+	assert.False(t, ok)
+
+	r, _ = ParseReminderRule("FREQ=YEARLY;X-DAYS-AFTER-DEATH=100")
+	start, _ = r.Start(time.Time{}, &death)
+	assert.Equal(t, ruleDay(2025, 6, 8), start)
+	next, ok := r.Next(start, ruleDay(2025, 7, 1))
+	require.True(t, ok)
+	assert.Equal(t, ruleDay(2026, 6, 8), next)
+	assert.Equal(t, "逝世第100天起每年", r.Describe(start))
+}
+
+func TestReminderRuleDescribe(t *testing.T) {
+	r, _ := ParseReminderRule("FREQ=YEARLY;RSCALE=CHINESE")
+	assert.Equal(t, "每年农历二月廿七", r.Describe(ruleDay(2024, 4, 5)))
+	r, _ = ParseReminderRule("FREQ=YEARLY;INTERVAL=2;COUNT=3")
+	assert.Equal(t, "每2年4月5日，共3次", r.Describe(ruleDay(2024, 4, 5)))
+	r, _ = ParseReminderRule("")
+	assert.Equal(t, "2024年4月5日", r.Describe(ruleDay(2024, 4, 5)))
+}
+
+func TestDueReminderLead(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	date := ruleDay(2025, 4, 10)
+	leads, err := ParseReminderLeadDays([]int{0, 3, 3, 1})
+	require.NoError(t, err)
+	assert.Equal(t, []int{3, 1, 0}, leads)
+
+	at := func(day, hour int) time.Time { return time.Date(2025, 4, day, hour, 0, 0, 0, loc) }
+
+	_, ok := DueReminderLead(date, leads, 9, 0, nil, at(7, 8), loc)
+	assert.False(t, ok)
+	lead, ok := DueReminderLead(date, leads, 9, 0, nil, at(7, 9), loc)
+	assert.True(t, ok)
+	assert.Equal(t, 3, lead)
+
+	// 已发送提前 3 天的，提前 1 天的到时再发
+	three := 3
+	_, ok = DueReminderLead(date, leads, 9, 0, &three, at(8, 12), loc)
+	assert.False(t, ok)
+	lead, _ = DueReminderLead(date, leads, 9, 0, &three, at(9, 10), loc)
+	assert.Equal(t, 1, lead)
+
+	// 停机错过的只补发最近的一个，当天过后不再发送
+	lead, _ = DueReminderLead(date, leads, 9, 0, nil, at(10, 20), loc)
+	assert.Equal(t, 0, lead)
+	zero := 0
+	_, ok = DueReminderLead(date, leads, 9, 0, &zero, at(10, 21), loc)
+	assert.False(t, ok)
+	_, ok = DueReminderLead(date, leads, 9, 0, nil, at(11, 9), loc)
+	assert.False(t, ok)
+
+	_, err = ParseReminderLeadDays([]int{31})
+	assert.Error(t, err)
+	_, _, err = ParseRemindTime("25:00")
+	assert.Error(t, err)
+}
@@ -0,0 +1,308 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReportTable 报表中的一张二维表，对应一个 CSV 文件、一个 XLSX 工作表或 PDF 中的一个表格。
+// 单元格支持字符串、整数、浮点数和时间，数值在 XLSX 中写为数字单元格以便直接制作图表
+type ReportTable struct {
+	Name    string
+	Headers []string
+	Rows    [][]interface{}
+}
+
+// AddRow 追加一行
+func (t *ReportTable) AddRow(cells ...interface{}) {
+	t.Rows = append(t.Rows, cells)
+}
+
+// FormatReportCell 将单元格格式化为文本
+func FormatReportCell(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.Format("2006-01-02 15:04:05")
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// WorshipRecordSummary 原始记录表中一条祭扫记录的类型、数量和寄语
+type WorshipRecordSummary struct {
+	Kind     string
+	Quantity int
+	Message  string
+}
+
+// worshipRecordDetail 各类祭扫内容的公共字段
+type worshipRecordDetail struct {
+	FlowerType   string   `json:"flower_type"`
+	CandleType   string   `json:"candle_type"`
+	IncenseType  string   `json:"incense_type"`
+	TributeType  string   `json:"tribute_type"`
+	Quantity     int      `json:"quantity"`
+	IncenseCount int      `json:"incense_count"`
+	Items        []string `json:"items"`
+	Message      string   `json:"message"`
+	Content      string   `json:"content"`   // 祈福、留言的文字
+	IsPublic     *bool    `json:"is_public"` // 祈福是否公开
+}
+
+// SummarizeWorshipRecord 解析祭扫记录的 JSON 内容。献花、点烛等的寄语在 message 中，
+// 祈福、留言的文字在 content 中；不公开的祈福不导出文字
+func SummarizeWorshipRecord(content string) WorshipRecordSummary {
+	var detail worshipRecordDetail
+	if content != "" {
+		json.Unmarshal([]byte(content), &detail)
+	}
+
+	summary := WorshipRecordSummary{
+		Quantity: detail.Quantity,
+		Message:  detail.Message,
+	}
+	for _, kind := range []string{detail.FlowerType, detail.CandleType, detail.IncenseType, detail.TributeType, strings.Join(detail.Items, "、")} {
+		if kind != "" {
+			summary.Kind = kind
+			break
+		}
+	}
+	if summary.Quantity == 0 {
+		summary.Quantity = detail.IncenseCount
+	}
+	if summary.Quantity == 0 {
+		summary.Quantity = len(detail.Items)
+	}
+	if summary.Message == "" && (detail.IsPublic == nil || *detail.IsPublic) {
+		summary.Message = detail.Content
+	}
+	return summary
+}
+
+// WriteReportCSV 以 CSV 格式写出表格。文件带 UTF-8 BOM，保证 Excel 直接打开时中文不乱码
+func WriteReportCSV(w io.Writer, table ReportTable) error {
+	if _, err := w.Write([]byte("\xEF\xBB\xBF")); err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	if len(table.Headers) > 0 {
+		if err := writer.Write(table.Headers); err != nil {
+			return err
+		}
+	}
+	for _, row := range table.Rows {
+		record := make([]string, len(row))
+		for i, cell := range row {
+			record[i] = FormatReportCell(cell)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteReportXLSX 以 XLSX 格式写出多张工作表，每张表首行为加粗表头并冻结。
+// 只依赖标准库生成 Office Open XML，字符串使用内联字符串，不需要共享字符串表
+func WriteReportXLSX(w io.Writer, tables []ReportTable) error {
+	if len(tables) == 0 {
+		return fmt.Errorf("至少需要一张工作表")
+	}
+
+	zw := zip.NewWriter(w)
+	names := xlsxSheetNames(tables)
+
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", xlsxContentTypes(len(tables))},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbook(names)},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels(len(tables))},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for _, f := range files {
+		if err := writeZipEntry(zw, f.name, f.content); err != nil {
+			return err
+		}
+	}
+
+	for i, table := range tables {
+		if err := writeZipEntry(zw, fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), xlsxSheet(table)); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func writeZipEntry(zw *zip.Writer, name, content string) error {
+	writer, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(writer, content)
+	return err
+}
+
+const xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+
+// 样式 0 为默认样式，样式 1 为加粗表头
+const xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts><fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills><borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders><cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs><cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs></styleSheet>`
+
+func xlsxContentTypes(sheetCount int) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for i := 1; i <= sheetCount; i++ {
+		fmt.Fprintf(&b, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i)
+	}
+	b.WriteString(`</Types>`)
+	return b.String()
+}
+
+func xlsxWorkbook(names []string) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	for i, name := range names {
+		fmt.Fprintf(&b, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xmlEscape(name), i+1, i+1)
+	}
+	b.WriteString(`</sheets></workbook>`)
+	return b.String()
+}
+
+func xlsxWorkbookRels(sheetCount int) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i := 1; i <= sheetCount; i++ {
+		fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i, i)
+	}
+	fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, sheetCount+1)
+	b.WriteString(`</Relationships>`)
+	return b.String()
+}
+
+func xlsxSheet(table ReportTable) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	if len(table.Headers) > 0 {
+		b.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	}
+	b.WriteString(`<sheetData>`)
+
+	rowNum := 0
+	if len(table.Headers) > 0 {
+		rowNum++
+		fmt.Fprintf(&b, `<row r="%d">`, rowNum)
+		for col, header := range table.Headers {
+			xlsxWriteCell(&b, col, rowNum, header, 1)
+		}
+		b.WriteString(`</row>`)
+	}
+	for _, row := range table.Rows {
+		rowNum++
+		fmt.Fprintf(&b, `<row r="%d">`, rowNum)
+		for col, cell := range row {
+			xlsxWriteCell(&b, col, rowNum, cell, 0)
+		}
+		b.WriteString(`</row>`)
+	}
+	b.WriteString(`</sheetData></worksheet>`)
+	return b.String()
+}
+
+func xlsxWriteCell(b *strings.Builder, col, row int, value interface{}, style int) {
+	ref := XLSXColumnName(col) + strconv.Itoa(row)
+	styleAttr := ""
+	if style > 0 {
+		styleAttr = fmt.Sprintf(` s="%d"`, style)
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		fmt.Fprintf(b, `<c r="%s"%s><v>%s</v></c>`, ref, styleAttr, FormatReportCell(v))
+	case bool:
+		flag := 0
+		if v {
+			flag = 1
+		}
+		fmt.Fprintf(b, `<c r="%s"%s t="b"><v>%d</v></c>`, ref, styleAttr, flag)
+	default:
+		fmt.Fprintf(b, `<c r="%s"%s t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, styleAttr, xmlEscape(FormatReportCell(v)))
+	}
+}
+
+// XLSXColumnName 将从0开始的列序号转换为 A、B、…、Z、AA 形式的列名
+func XLSXColumnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+// xlsxSheetNames 生成合法且不重复的工作表名：不超过31个字符，不含 []:*?/\
+func xlsxSheetNames(tables []ReportTable) []string {
+	replacer := strings.NewReplacer("[", "(", "]", ")", ":", "-", "*", "-", "?", "-", "/", "-", "\\", "-")
+	used := make(map[string]bool)
+	names := make([]string, len(tables))
+	for i, table := range tables {
+		base := strings.TrimSpace(replacer.Replace(table.Name))
+		if base == "" {
+			base = fmt.Sprintf("Sheet%d", i+1)
+		}
+		base = truncateSheetName(base, 31)
+
+		name := base
+		for n := 2; used[strings.ToLower(name)]; n++ {
+			suffix := fmt.Sprintf("(%d)", n)
+			name = truncateSheetName(base, 31-len(suffix)) + suffix
+		}
+		used[strings.ToLower(name)] = true
+		names[i] = name
+	}
+	return names
+}
+
+func truncateSheetName(name string, limit int) string {
+	runes := []rune(name)
+	if len(runes) > limit {
+		return string(runes[:limit])
+	}
+	return name
+}
+
+func xmlEscape(text string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(text))
+	return buf.String()
+}
@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteReportCSV(t *testing.T) {
+	table := ReportTable{Headers: []string{"时间", "访客", "内容"}}
+	table.AddRow(time.Date(2024, 4, 4, 9, 30, 0, 0, time.Local), "张三", "愿您安息，\"一路走好\"")
+	table.AddRow("", int64(3), 1.5)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteReportCSV(&buf, table))
+
+	out := buf.String()
+	assert.True(t, strings.HasPrefix(out, "\xEF\xBB\xBF时间,访客,内容\n"))
+	assert.Contains(t, out, "2024-04-04 09:30:00,张三,\"愿您安息，\"\"一路走好\"\"\"\n")
+	assert.Contains(t, out, ",3,1.5\n")
+}
+
+func TestSummarizeWorshipRecord(t *testing.T) {
+	flower := SummarizeWorshipRecord(`{"flower_type":"菊花","quantity":3,"message":"思念"}`)
+	assert.Equal(t, WorshipRecordSummary{Kind: "菊花", Quantity: 3, Message: "思念"}, flower)
+
+	incense := SummarizeWorshipRecord(`{"incense_type":"檀香","incense_count":3}`)
+	assert.Equal(t, WorshipRecordSummary{Kind: "檀香", Quantity: 3}, incense)
+
+	tribute := SummarizeWorshipRecord(`{"items":["苹果","月饼"]}`)
+	assert.Equal(t, WorshipRecordSummary{Kind: "苹果、月饼", Quantity: 2}, tribute)
+
+	// 祈福、留言的文字在 content 中
+	prayer := SummarizeWorshipRecord(`{"content":"愿您安息","is_public":true}`)
+	assert.Equal(t, "愿您安息", prayer.Message)
+	message := SummarizeWorshipRecord(`{"message_type":"text","content":"想念您"}`)
+	assert.Equal(t, "想念您", message.Message)
+
+	// 不公开的祈福和时光胶囊不导出文字
+	assert.Equal(t, "", SummarizeWorshipRecord(`{"content":"私人心愿","is_public":false}`).Message)
+	assert.Equal(t, "", SummarizeWorshipRecord(`{"message_type":"text","message_id":"m1","time_capsule":true}`).Message)
+
+	assert.Equal(t, WorshipRecordSummary{}, SummarizeWorshipRecord(""))
+}
+
+func TestWriteReportXLSX(t *testing.T) {
+	summary := ReportTable{Name: "概览", Headers: []string{"指标", "数值"}}
+	summary.AddRow("祭扫次数", int64(12))
+	summary.AddRow("备注", "<清明>")
+	records := ReportTable{Name: "概览", Headers: []string{"时间"}}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteReportXLSX(&buf, []ReportTable{summary, records}))
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(data)
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/styles.xml", "xl/worksheets/sheet1.xml", "xl/worksheets/sheet2.xml"} {
+		assert.Contains(t, files, name)
+	}
+	// 重名工作表自动加序号
+	assert.Contains(t, files["xl/workbook.xml"], `name="概览"`)
+	assert.Contains(t, files["xl/workbook.xml"], `name="概览(2)"`)
+
+	sheet := files["xl/worksheets/sheet1.xml"]
+	assert.Contains(t, sheet, `<c r="A1" s="1" t="inlineStr"><is><t xml:space="preserve">指标</t></is></c>`)
+	assert.Contains(t, sheet, `<c r="B2"><v>12</v></c>`)
+	assert.Contains(t, sheet, `&lt;清明&gt;`)
+}
+
+func TestXLSXColumnName(t *testing.T) {
+	assert.Equal(t, "A", XLSXColumnName(0))
+	assert.Equal(t, "Z", XLSXColumnName(25))
+	assert.Equal(t, "AA", XLSXColumnName(26))
+	assert.Equal(t, "AZ", XLSXColumnName(51))
+	assert.Equal(t, "BA", XLSXColumnName(52))
+}
+
+func TestPDFDocumentStructure(t *testing.T) {
+	doc := NewPDFDocument("祭扫报告")
+	doc.Title("祭扫报告")
+	doc.Paragraph("统计周期 2024-01-01 至 2024-12-31")
+
+	table := ReportTable{Headers: []string{"排名", "访客", "次数"}}
+	for i := 1; i <= 60; i++ {
+		table.AddRow(i, fmt.Sprintf("访客%d", i), int64(100-i))
+	}
+	doc.Table(table)
+	doc.BarChart([]string{"献花", "上香"}, []float64{5, 2})
+	assert.Greater(t, doc.PageCount(), 1)
+
+	var buf bytes.Buffer
+	_, err := doc.WriteTo(&buf)
+	require.NoError(t, err)
+	out := buf.String()
+
+	assert.True(t, strings.HasPrefix(out, "%PDF-1.4\n"))
+	assert.True(t, strings.HasSuffix(out, "%%EOF\n"))
+	// "祭扫" 的 UCS-2 编码
+	assert.Contains(t, out, "<796D626B")
+	assert.Contains(t, out, fmt.Sprintf("/Count %d", doc.PageCount()))
+
+	// 交叉引用表中的偏移量必须指向对应对象
+	xrefStart, err := strconv.Atoi(regexp.MustCompile(`startxref\n(\d+)`).FindStringSubmatch(out)[1])
+	require.NoError(t, err)
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllStringSubmatch(out[xrefStart:], -1)
+	require.NotEmpty(t, entries)
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(entry[1])
+		assert.True(t, strings.HasPrefix(out[offset:], fmt.Sprintf("%d 0 obj", i+1)), "object %d", i+1)
+	}
+}
+
+func TestPDFTextHelpers(t *testing.T) {
+	assert.Equal(t, 20.0, PDFTextWidth("ab中", 10))
+	assert.Equal(t, "纪念…", TruncatePDFText("纪念馆祭扫", 10, 30))
+	assert.Equal(t, "abc", TruncatePDFText("abc", 10, 30))
+	assert.Equal(t, []string{"一二三", "四五", "", "六"}, WrapPDFText("一二三四五\n\n六", 10, 30))
+	assert.Equal(t, "003F0041", pdfEncodeText("😀A"))
+}
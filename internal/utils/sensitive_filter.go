@@ -0,0 +1,344 @@
+package utils
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// 敏感词处理级别
+const (
+	SensitiveLevelBlock  = "block"  // 直接拦截
+	SensitiveLevelReview = "review" // 允许发布但进入人工复核
+)
+
+// SensitiveWord 敏感词条目（由管理员在系统配置中维护）
+type SensitiveWord struct {
+	Word     string `json:"word"`
+	Pinyin   string `json:"pinyin,omitempty"`   // 空格分隔的拼音，如 "sha bi"，用于识别拼音规避
+	Category string `json:"category,omitempty"` // abuse辱骂 illegal违法 political涉政 ad广告
+	Level    string `json:"level,omitempty"`    // block|review，默认 block
+}
+
+// SensitiveMatch 单次命中详情
+type SensitiveMatch struct {
+	Word     string `json:"word"`
+	Category string `json:"category"`
+	Level    string `json:"level"`
+	Variant  string `json:"variant"` // exact原文 pinyin全拼 initials首字母 mixed汉字拼音混写
+	Matched  string `json:"matched"` // 原文中被命中的片段
+	Start    int    `json:"start"`   // 原文字符偏移（按字符计）
+	End      int    `json:"end"`
+}
+
+// acNode Aho-Corasick 自动机节点
+type acNode struct {
+	children map[rune]*acNode
+	fail     *acNode
+	outputs  []int
+}
+
+func newACNode() *acNode {
+	return &acNode{children: make(map[rune]*acNode)}
+}
+
+type sensitivePattern struct {
+	runes   []rune
+	wordIdx int
+	variant string
+	ascii   bool
+}
+
+// SensitiveFilter 基于 Aho-Corasick 的多模式敏感词匹配器
+// 构建后只读，可在多个 goroutine 间共享
+type SensitiveFilter struct {
+	root     *acNode
+	words    []SensitiveWord
+	patterns []sensitivePattern
+}
+
+// 拼音混写变体只对短词生成，避免组合爆炸
+const maxMixedVariantRunes = 4
+
+// NewSensitiveFilter 根据词表构建匹配器
+func NewSensitiveFilter(words []SensitiveWord) *SensitiveFilter {
+	f := &SensitiveFilter{root: newACNode()}
+	seen := make(map[string]bool)
+
+	for _, w := range words {
+		normalized, _ := NormalizeSensitiveText(w.Word)
+		if len(normalized) == 0 {
+			continue
+		}
+		if w.Level != SensitiveLevelReview {
+			w.Level = SensitiveLevelBlock
+		}
+		f.words = append(f.words, w)
+		wordIdx := len(f.words) - 1
+
+		for _, v := range sensitiveVariants(normalized, w.Pinyin) {
+			key := string(v.runes)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			v.wordIdx = wordIdx
+			v.ascii = isASCIIRunes(v.runes)
+			f.addPattern(v)
+		}
+	}
+
+	f.buildFailLinks()
+	return f
+}
+
+// sensitiveVariants 生成原词、全拼、首字母及汉字拼音混写变体
+func sensitiveVariants(normalized []rune, pinyin string) []sensitivePattern {
+	variants := []sensitivePattern{{runes: normalized, variant: "exact"}}
+
+	syllables := strings.Fields(strings.ToLower(pinyin))
+	if len(syllables) == 0 {
+		return variants
+	}
+
+	full := []rune(strings.Join(syllables, ""))
+	variants = append(variants, sensitivePattern{runes: full, variant: "pinyin"})
+
+	initials := make([]rune, 0, len(syllables))
+	for _, s := range syllables {
+		initials = append(initials, []rune(s)[0])
+	}
+	// 单字母缩写误伤太多，至少两个字母才收录
+	if len(initials) >= 2 {
+		variants = append(variants, sensitivePattern{runes: initials, variant: "initials"})
+	}
+
+	// 每个字可以是汉字或拼音，如"法lun功"
+	if len(syllables) == len(normalized) && len(normalized) <= maxMixedVariantRunes {
+		total := 1 << len(normalized)
+		for mask := 1; mask < total-1; mask++ {
+			var mixed []rune
+			for i, r := range normalized {
+				if mask&(1<<i) != 0 {
+					mixed = append(mixed, []rune(syllables[i])...)
+				} else {
+					mixed = append(mixed, r)
+				}
+			}
+			variants = append(variants, sensitivePattern{runes: mixed, variant: "mixed"})
+		}
+	}
+
+	return variants
+}
+
+func (f *SensitiveFilter) addPattern(p sensitivePattern) {
+	node := f.root
+	for _, r := range p.runes {
+		next, ok := node.children[r]
+		if !ok {
+			next = newACNode()
+			node.children[r] = next
+		}
+		node = next
+	}
+	f.patterns = append(f.patterns, p)
+	node.outputs = append(node.outputs, len(f.patterns)-1)
+}
+
+func (f *SensitiveFilter) buildFailLinks() {
+	queue := make([]*acNode, 0)
+	for _, child := range f.root.children {
+		child.fail = f.root
+		queue = append(queue, child)
+	}
+
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for r, child := range node.children {
+			fail := node.fail
+			for fail != nil {
+				if next, ok := fail.children[r]; ok {
+					child.fail = next
+					break
+				}
+				fail = fail.fail
+			}
+			if child.fail == nil {
+				child.fail = f.root
+			}
+			child.outputs = append(child.outputs, child.fail.outputs...)
+			queue = append(queue, child)
+		}
+	}
+}
+
+// WordCount 返回有效词条数量
+func (f *SensitiveFilter) WordCount() int {
+	return len(f.words)
+}
+
+// Match 返回文本中所有敏感词命中（按出现位置排序，同一位置只保留最长命中）
+func (f *SensitiveFilter) Match(text string) []SensitiveMatch {
+	if f == nil || len(f.patterns) == 0 || text == "" {
+		return nil
+	}
+
+	original := []rune(text)
+	normalized, positions := NormalizeSensitiveText(text)
+
+	var matches []SensitiveMatch
+	node := f.root
+	for i, r := range normalized {
+		for node != f.root && node.children[r] == nil {
+			node = node.fail
+		}
+		if next, ok := node.children[r]; ok {
+			node = next
+		}
+
+		for _, idx := range node.outputs {
+			p := f.patterns[idx]
+			startNorm := i - len(p.runes) + 1
+			// 纯字母模式要求单词边界，避免 "usb" 命中 "sb"
+			if p.ascii && (isASCIILetterAt(normalized, startNorm-1) || isASCIILetterAt(normalized, i+1)) {
+				continue
+			}
+			start, end := positions[startNorm], positions[i]+1
+			word := f.words[p.wordIdx]
+			matches = append(matches, SensitiveMatch{
+				Word:     word.Word,
+				Category: word.Category,
+				Level:    word.Level,
+				Variant:  p.variant,
+				Matched:  string(original[start:end]),
+				Start:    start,
+				End:      end,
+			})
+		}
+	}
+
+	return dedupeSensitiveMatches(matches)
+}
+
+// dedupeSensitiveMatches 去除被更长命中覆盖的重复结果
+func dedupeSensitiveMatches(matches []SensitiveMatch) []SensitiveMatch {
+	if len(matches) <= 1 {
+		return matches
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Start != matches[j].Start {
+			return matches[i].Start < matches[j].Start
+		}
+		return matches[i].End > matches[j].End
+	})
+
+	result := matches[:0]
+	lastEnd := -1
+	for _, m := range matches {
+		if m.End <= lastEnd {
+			continue
+		}
+		result = append(result, m)
+		lastEnd = m.End
+	}
+	return result
+}
+
+// HasBlockingMatch 是否存在需要拦截的命中
+func HasBlockingMatch(matches []SensitiveMatch) bool {
+	for _, m := range matches {
+		if m.Level == SensitiveLevelBlock {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeSensitiveText 归一化文本：全角转半角、大写转小写、繁体转简体，
+// 并去掉穿插在字间的空白、标点和零宽字符。返回归一化后的字符及其在原文中的位置
+func NormalizeSensitiveText(text string) ([]rune, []int) {
+	original := []rune(text)
+	normalized := make([]rune, 0, len(original))
+	positions := make([]int, 0, len(original))
+
+	for i, r := range original {
+		r = toHalfWidth(r)
+		if isSensitiveNoise(r) {
+			continue
+		}
+		r = unicode.ToLower(r)
+		if simplified, ok := traditionalToSimplified[r]; ok {
+			r = simplified
+		}
+		normalized = append(normalized, r)
+		positions = append(positions, i)
+	}
+
+	return normalized, positions
+}
+
+func toHalfWidth(r rune) rune {
+	switch {
+	case r == 0x3000:
+		return ' '
+	case r >= 0xFF01 && r <= 0xFF5E:
+		return r - 0xFEE0
+	}
+	return r
+}
+
+func isSensitiveNoise(r rune) bool {
+	switch r {
+	case 0x200B, 0x200C, 0x200D, 0x2060, 0xFEFF:
+		return true
+	}
+	return unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.Is(unicode.Mn, r)
+}
+
+func isASCIIRunes(runes []rune) bool {
+	for _, r := range runes {
+		if r > unicode.MaxASCII {
+			return false
+		}
+	}
+	return true
+}
+
+func isASCIILetterAt(runes []rune, i int) bool {
+	if i < 0 || i >= len(runes) {
+		return false
+	}
+	r := runes[i]
+	return r >= 'a' && r <= 'z'
+}
+
+// 常用繁体字到简体字映射（覆盖规避检测中的高频字）
+var traditionalToSimplified = buildTraditionalMap(
+	"們個來說話請這國學時會對麼還沒後樣經過發動現頭點長開關問題見實無從與東車將軍華書"+
+		"記資錢銀買賣錯誤讓應該愛幫處聲歲歡樂氣當體電腦網絡視聽讀寫廣場專業傳統機構組織協"+
+		"議選舉黨權殺滅亂搶賭販槍彈藥詐騙貸幣賤婦媽爺兒孫親戰爭醫療禮墳紀館靈彌顯滾雞豬貓"+
+		"馬鳥魚龍鳳獨惡罵嗎爛腫賊蟲屍飛輪門義壓鎮獄穢頻號碼聯係導領產陸臺灣島區縣鄉員歷憲"+
+		"運遊誌報紙圖畫詞語譯認識證據變態殘廢傷礙鬥級約線維邊麗嚴廳偽匯夥術團強彎徵戶掛換"+
+		"數斷昇樓標歸殼準滿漢燈獎環畢監盤確穩競築簽範糧紅納練總績繼罰腳臉舊藝虛衛補裝覺觀"+
+		"計訊評試誰調談講謝護負財貨質費賽趕蹤軟載輕農連週達遷郵醜針鐵鐘陽際隨險雙離難須順"+
+		"預風養驗髮鬆黃齊齒錄劉陳張楊趙吳鄭馮蔣韓葉蘇盧許鄧魯羅簡傑",
+	"们个来说话请这国学时会对么还没后样经过发动现头点长开关问题见实无从与东车将军华书"+
+		"记资钱银买卖错误让应该爱帮处声岁欢乐气当体电脑网络视听读写广场专业传统机构组织协"+
+		"议选举党权杀灭乱抢赌贩枪弹药诈骗贷币贱妇妈爷儿孙亲战争医疗礼坟纪馆灵弥显滚鸡猪猫"+
+		"马鸟鱼龙凤独恶骂吗烂肿贼虫尸飞轮门义压镇狱秽频号码联系导领产陆台湾岛区县乡员历宪"+
+		"运游志报纸图画词语译认识证据变态残废伤碍斗级约线维边丽严厅伪汇伙术团强弯征户挂换"+
+		"数断升楼标归壳准满汉灯奖环毕监盘确稳竞筑签范粮红纳练总绩继罚脚脸旧艺虚卫补装觉观"+
+		"计讯评试谁调谈讲谢护负财货质费赛赶踪软载轻农连周达迁邮丑针铁钟阳际随险双离难须顺"+
+		"预风养验发松黄齐齿录刘陈张杨赵吴郑冯蒋韩叶苏卢许邓鲁罗简杰",
+)
+
+func buildTraditionalMap(traditional, simplified string) map[rune]rune {
+	t, s := []rune(traditional), []rune(simplified)
+	m := make(map[rune]rune, len(t))
+	for i := range t {
+		m[t[i]] = s[i]
+	}
+	return m
+}
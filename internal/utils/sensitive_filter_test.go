@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSensitiveFilter() *SensitiveFilter {
+	return NewSensitiveFilter([]SensitiveWord{
+		{Word: "傻逼", Pinyin: "sha bi", Category: "abuse"},
+		{Word: "法轮功", Pinyin: "fa lun gong", Category: "political"},
+		{Word: "代开发票", Category: "ad", Level: SensitiveLevelReview},
+	})
+}
+
+func TestSensitiveFilterExactMatch(t *testing.T) {
+	f := newTestSensitiveFilter()
+
+	matches := f.Match("你真是个傻逼啊")
+	assert.Len(t, matches, 1)
+	assert.Equal(t, "傻逼", matches[0].Word)
+	assert.Equal(t, "exact", matches[0].Variant)
+	assert.Equal(t, "傻逼", matches[0].Matched)
+	assert.Equal(t, 4, matches[0].Start)
+	assert.True(t, HasBlockingMatch(matches))
+
+	assert.Empty(t, f.Match("愿您在天堂安好"))
+}
+
+func TestSensitiveFilterEvasionVariants(t *testing.T) {
+	f := newTestSensitiveFilter()
+
+	cases := map[string]string{
+		"傻 * 逼":    "exact",    // 插入空白和符号
+		"法輪功":      "exact",    // 繁体
+		"ＳＨＡＢＩ":    "pinyin",   // 全角大写拼音
+		"sb一个":     "initials", // 首字母缩写
+		"法lun功好":   "mixed",    // 汉字拼音混写
+		"傻\u200b逼": "exact",    // 零宽字符
+	}
+	for text, variant := range cases {
+		matches := f.Match(text)
+		if assert.Len(t, matches, 1, text) {
+			assert.Equal(t, variant, matches[0].Variant, text)
+		}
+	}
+}
+
+func TestSensitiveFilterASCIIWordBoundary(t *testing.T) {
+	f := newTestSensitiveFilter()
+
+	assert.Empty(t, f.Match("请插上usb接口"))
+	assert.Empty(t, f.Match("shabiz"))
+	assert.Len(t, f.Match("你 sb"), 1)
+}
+
+func TestSensitiveFilterReviewLevel(t *testing.T) {
+	f := newTestSensitiveFilter()
+
+	matches := f.Match("专业代开发票")
+	assert.Len(t, matches, 1)
+	assert.Equal(t, SensitiveLevelReview, matches[0].Level)
+	assert.False(t, HasBlockingMatch(matches))
+}
+
+func TestSensitiveFilterNilSafe(t *testing.T) {
+	var f *SensitiveFilter
+	assert.Nil(t, f.Match("傻逼"))
+	assert.Equal(t, 0, NewSensitiveFilter(nil).WordCount())
+}
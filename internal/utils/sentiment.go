@@ -0,0 +1,429 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// 情感类别
+const (
+	EmotionSad       = "sad"
+	EmotionHappy     = "happy"
+	EmotionNostalgic = "nostalgic"
+	EmotionGrateful  = "grateful"
+	EmotionPeaceful  = "peaceful"
+)
+
+// Emotions 所有情感类别（用于统计时补齐零值）
+var Emotions = []string{EmotionSad, EmotionHappy, EmotionNostalgic, EmotionGrateful, EmotionPeaceful}
+
+// emotionPolarity 各情感的极性，用于计算整体情感倾向
+var emotionPolarity = map[string]float64{
+	EmotionSad:       -1,
+	EmotionHappy:     1,
+	EmotionNostalgic: 0,
+	EmotionGrateful:  1,
+	EmotionPeaceful:  0.5,
+}
+
+// negatedEmotion 否定后转向的情感，如"不难过"视为平静、"不开心"视为悲伤；未列出的情感被否定后忽略
+var negatedEmotion = map[string]string{
+	EmotionSad:      EmotionPeaceful,
+	EmotionHappy:    EmotionSad,
+	EmotionPeaceful: EmotionSad,
+}
+
+const (
+	negatedWeight  = 0.6 // 否定后转向情感的权重衰减
+	contrastDecay  = 0.5 // 转折词之前分句的权重衰减
+	noEvidenceConf = 0.3 // 没有命中任何情感词时的置信度
+)
+
+// SentimentResult 情感分析结果
+type SentimentResult struct {
+	Emotion    string             `json:"emotion"`    // 主导情感
+	Confidence float64            `json:"confidence"` // 置信度 0-1
+	Polarity   float64            `json:"polarity"`   // 情感倾向 -1(消极) 到 1(积极)
+	Scores     map[string]float64 `json:"scores"`     // 各情感得分
+	Keywords   []string           `json:"keywords"`   // 命中的情感词（含修饰词）
+}
+
+// SentimentAnalyzer 情感分析器接口，便于替换为第三方 NLP 服务
+type SentimentAnalyzer interface {
+	Analyze(text string) *SentimentResult
+}
+
+// SentimentLexicon 情感词典
+type SentimentLexicon struct {
+	Emotions  map[string]map[string]float64 // 情感 -> 词 -> 权重
+	Negations map[string]bool               // 否定词
+	Degrees   map[string]float64            // 程度副词 -> 倍率
+	Contrasts map[string]bool               // 转折词
+}
+
+// NewSentimentLexicon 创建空词典
+func NewSentimentLexicon() *SentimentLexicon {
+	return &SentimentLexicon{
+		Emotions:  make(map[string]map[string]float64),
+		Negations: make(map[string]bool),
+		Degrees:   make(map[string]float64),
+		Contrasts: make(map[string]bool),
+	}
+}
+
+// AddEmotionWord 添加情感词，同一个词只归属一种情感
+func (l *SentimentLexicon) AddEmotionWord(emotion, word string, weight float64) {
+	for _, words := range l.Emotions {
+		delete(words, word)
+	}
+	if l.Emotions[emotion] == nil {
+		l.Emotions[emotion] = make(map[string]float64)
+	}
+	l.Emotions[emotion][word] = weight
+}
+
+// Merge 合并另一个词典，同名词条以 other 为准
+func (l *SentimentLexicon) Merge(other *SentimentLexicon) {
+	for emotion, words := range other.Emotions {
+		for word, weight := range words {
+			l.AddEmotionWord(emotion, word, weight)
+		}
+	}
+	for word := range other.Negations {
+		l.Negations[word] = true
+	}
+	for word, multiplier := range other.Degrees {
+		l.Degrees[word] = multiplier
+	}
+	for word := range other.Contrasts {
+		l.Contrasts[word] = true
+	}
+}
+
+// LoadSentimentLexicon 从文件加载词典
+func LoadSentimentLexicon(path string) (*SentimentLexicon, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开情感词典失败: %v", err)
+	}
+	defer f.Close()
+	return ParseSentimentLexicon(f)
+}
+
+// ParseSentimentLexicon 解析词典文本。格式为分节的"词 [权重]"列表，# 开头为注释：
+//
+//	[emotion:sad]
+//	难过 1.0
+//	[negation]
+//	不
+//	[degree]
+//	非常 1.8
+//	[contrast]
+//	但是
+func ParseSentimentLexicon(r io.Reader) (*SentimentLexicon, error) {
+	lexicon := NewSentimentLexicon()
+	scanner := bufio.NewScanner(r)
+	section := ""
+	lineNo := 0
+
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+
+		fields := strings.Fields(line)
+		word := fields[0]
+		weight := 1.0
+		if len(fields) > 1 {
+			value, err := strconv.ParseFloat(fields[1], 64)
+			if err != nil {
+				return nil, fmt.Errorf("情感词典第%d行权重格式错误: %s", lineNo, fields[1])
+			}
+			weight = value
+		}
+
+		switch {
+		case strings.HasPrefix(section, "emotion:"):
+			emotion := strings.TrimPrefix(section, "emotion:")
+			if _, ok := emotionPolarity[emotion]; !ok {
+				return nil, fmt.Errorf("情感词典第%d行情感类别无效: %s", lineNo, emotion)
+			}
+			lexicon.AddEmotionWord(emotion, word, weight)
+		case section == "negation":
+			lexicon.Negations[word] = true
+		case section == "degree":
+			lexicon.Degrees[word] = weight
+		case section == "contrast":
+			lexicon.Contrasts[word] = true
+		default:
+			return nil, fmt.Errorf("情感词典第%d行不在有效分节中", lineNo)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return lexicon, nil
+}
+
+// LexiconSentimentAnalyzer 基于词典的中文情感分析器：
+// 正向最大匹配分词，按分句处理否定词、程度副词和转折词
+type LexiconSentimentAnalyzer struct {
+	lexicon    *SentimentLexicon
+	wordEmo    map[string]string
+	wordWeight map[string]float64
+	maxWordLen int
+}
+
+// NewLexiconSentimentAnalyzer 根据词典创建分析器
+func NewLexiconSentimentAnalyzer(lexicon *SentimentLexicon) *LexiconSentimentAnalyzer {
+	a := &LexiconSentimentAnalyzer{
+		lexicon:    lexicon,
+		wordEmo:    make(map[string]string),
+		wordWeight: make(map[string]float64),
+		maxWordLen: 1,
+	}
+
+	track := func(word string) {
+		if n := len([]rune(word)); n > a.maxWordLen {
+			a.maxWordLen = n
+		}
+	}
+	for emotion, words := range lexicon.Emotions {
+		for word, weight := range words {
+			a.wordEmo[word] = emotion
+			a.wordWeight[word] = weight
+			track(word)
+		}
+	}
+	for word := range lexicon.Negations {
+		track(word)
+	}
+	for word := range lexicon.Degrees {
+		track(word)
+	}
+	for word := range lexicon.Contrasts {
+		track(word)
+	}
+	return a
+}
+
+// Segment 按词典做正向最大匹配分词，标点和空白作为分句标记 ""
+func (a *LexiconSentimentAnalyzer) Segment(text string) []string {
+	runes := []rune(strings.ToLower(text))
+	var tokens []string
+
+	for i := 0; i < len(runes); {
+		if unicode.IsPunct(runes[i]) || unicode.IsSpace(runes[i]) || unicode.IsSymbol(runes[i]) {
+			if len(tokens) > 0 && tokens[len(tokens)-1] != "" {
+				tokens = append(tokens, "")
+			}
+			i++
+			continue
+		}
+
+		matched := 1
+		for n := a.maxWordLen; n > 1; n-- {
+			if i+n > len(runes) {
+				continue
+			}
+			if a.isKnownWord(string(runes[i : i+n])) {
+				matched = n
+				break
+			}
+		}
+		tokens = append(tokens, string(runes[i:i+matched]))
+		i += matched
+	}
+	return tokens
+}
+
+func (a *LexiconSentimentAnalyzer) isKnownWord(word string) bool {
+	if _, ok := a.wordEmo[word]; ok {
+		return true
+	}
+	if _, ok := a.lexicon.Degrees[word]; ok {
+		return true
+	}
+	return a.lexicon.Negations[word] || a.lexicon.Contrasts[word]
+}
+
+// Analyze 分析文本情感
+func (a *LexiconSentimentAnalyzer) Analyze(text string) *SentimentResult {
+	scores := make(map[string]float64, len(Emotions))
+	for _, emotion := range Emotions {
+		scores[emotion] = 0
+	}
+	var keywords []string
+
+	// 修饰词状态在遇到情感词或分句结束时重置
+	negations := 0
+	degree := 1.0
+	var modifiers []string
+	reset := func() {
+		negations, degree, modifiers = 0, 1.0, nil
+	}
+
+	for _, token := range a.Segment(text) {
+		switch {
+		case token == "":
+			reset()
+		case a.lexicon.Contrasts[token]:
+			// 转折之后的内容才是重点
+			for emotion := range scores {
+				scores[emotion] *= contrastDecay
+			}
+			reset()
+		case a.lexicon.Negations[token]:
+			negations++
+			modifiers = append(modifiers, token)
+		case a.lexicon.Degrees[token] != 0:
+			multiplier := a.lexicon.Degrees[token]
+			if negations > 0 {
+				// "不很开心"中否定在前，程度被削弱
+				multiplier = 1 + (multiplier-1)/2
+			}
+			degree *= multiplier
+			modifiers = append(modifiers, token)
+		default:
+			emotion, ok := a.wordEmo[token]
+			if !ok {
+				continue
+			}
+			weight := a.wordWeight[token] * degree
+			if negations%2 == 1 {
+				emotion, ok = negatedEmotion[emotion]
+				weight *= negatedWeight
+			}
+			if ok {
+				scores[emotion] += weight
+				keywords = append(keywords, strings.Join(modifiers, "")+token)
+			}
+			reset()
+		}
+	}
+
+	return buildSentimentResult(scores, keywords)
+}
+
+func buildSentimentResult(scores map[string]float64, keywords []string) *SentimentResult {
+	total := 0.0
+	for _, score := range scores {
+		total += score
+	}
+
+	result := &SentimentResult{
+		Emotion:  EmotionPeaceful,
+		Scores:   make(map[string]float64, len(scores)),
+		Keywords: keywords,
+	}
+	for emotion, score := range scores {
+		result.Scores[emotion] = roundTo(score, 2)
+	}
+	if result.Keywords == nil {
+		result.Keywords = []string{}
+	}
+
+	if total <= 0 {
+		result.Confidence = noEvidenceConf
+		return result
+	}
+
+	// 按固定顺序取最高分，保证同分时结果稳定
+	ordered := append([]string(nil), Emotions...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return scores[ordered[i]] > scores[ordered[j]]
+	})
+	result.Emotion = ordered[0]
+
+	// 置信度 = 主导情感占比 × 证据强度（命中越多越可信）
+	share := scores[result.Emotion] / total
+	evidence := 1 - math.Exp(-total)
+	result.Confidence = roundTo(share*(0.5+0.5*evidence), 2)
+
+	polarity := 0.0
+	for emotion, score := range scores {
+		polarity += emotionPolarity[emotion] * score
+	}
+	result.Polarity = roundTo(polarity/total, 2)
+
+	return result
+}
+
+func roundTo(value float64, digits int) float64 {
+	p := math.Pow(10, float64(digits))
+	return math.Round(value*p) / p
+}
+
+// DefaultSentimentLexicon 内置的悼念场景情感词典
+func DefaultSentimentLexicon() *SentimentLexicon {
+	lexicon := NewSentimentLexicon()
+
+	emotionWords := map[string]map[string]float64{
+		EmotionSad: {
+			"想念": 0.8, "思念": 0.8, "难过": 1, "伤心": 1, "悲伤": 1, "离别": 0.7, "痛苦": 1,
+			"心痛": 1, "悲痛": 1.2, "哀痛": 1.2, "沉痛": 1, "痛心": 1, "心碎": 1.2, "流泪": 0.8,
+			"泪流": 0.8, "哭泣": 0.9, "遗憾": 0.8, "不舍": 0.8, "舍不得": 0.8, "孤单": 0.8,
+			"孤独": 0.8, "寂寞": 0.8, "失去": 0.7, "后悔": 0.9, "悔恨": 1, "惋惜": 0.8,
+			"伤感": 0.9, "难受": 1, "不安": 0.7, "牵挂": 0.6, "哀思": 0.6, "告别": 0.7,
+		},
+		EmotionHappy: {
+			"快乐": 1, "开心": 1, "幸福": 1, "高兴": 1, "欢乐": 0.9, "喜悦": 1, "笑容": 0.7,
+			"欣慰": 0.9, "骄傲": 0.8, "自豪": 0.8, "美好": 0.7, "温暖": 0.7, "甜蜜": 0.8,
+			"愉快": 0.9, "欢笑": 0.8,
+		},
+		EmotionNostalgic: {
+			"回忆": 0.8, "往昔": 0.9, "从前": 0.7, "过去": 0.5, "曾经": 0.6, "那时": 0.6,
+			"小时候": 0.8, "当年": 0.7, "记得": 0.6, "怀念": 0.9, "往事": 0.8, "童年": 0.7,
+			"忆起": 0.8, "想起": 0.6,
+		},
+		EmotionGrateful: {
+			"感谢": 1, "感恩": 1, "谢谢": 0.9, "感激": 1, "恩情": 1, "教诲": 0.9, "养育": 0.8,
+			"栽培": 0.8, "报答": 0.8, "恩德": 1, "多亏": 0.7, "养育之恩": 1.2,
+		},
+		EmotionPeaceful: {
+			"安息": 1, "安好": 0.9, "平静": 0.9, "宁静": 0.9, "安详": 1, "祝福": 0.7, "保佑": 0.7,
+			"一路走好": 0.9, "放心": 0.7, "释然": 0.9, "安心": 0.8, "平安": 0.8, "无忧": 0.8,
+			"安宁": 0.9, "长眠": 0.8,
+		},
+	}
+	for emotion, words := range emotionWords {
+		for word, weight := range words {
+			lexicon.AddEmotionWord(emotion, word, weight)
+		}
+	}
+
+	for _, word := range []string{
+		"不", "没", "没有", "无", "非", "莫", "勿", "别", "未", "不再", "并非", "从未", "从不",
+		"不用", "不必", "不会", "无需", "不要", "再也不",
+	} {
+		lexicon.Negations[word] = true
+	}
+
+	for word, multiplier := range map[string]float64{
+		"极其": 2, "万分": 2, "无比": 1.9, "非常": 1.8, "十分": 1.7, "最": 1.8, "特别": 1.6,
+		"格外": 1.6, "深深": 1.6, "太": 1.6, "很": 1.5, "尤其": 1.5, "更加": 1.5, "更": 1.4,
+		"越来越": 1.4, "如此": 1.4, "好": 1.3, "那么": 1.3, "永远": 1.3,
+		"比较": 0.8, "还算": 0.8, "有点": 0.7, "有些": 0.7, "稍微": 0.6, "略微": 0.6,
+	} {
+		lexicon.Degrees[word] = multiplier
+	}
+
+	for _, word := range []string{"但是", "但", "可是", "然而", "不过", "只是"} {
+		lexicon.Contrasts[word] = true
+	}
+
+	return lexicon
+}
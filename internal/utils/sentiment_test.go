@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSentimentAnalyzer() *LexiconSentimentAnalyzer {
+	return NewLexiconSentimentAnalyzer(DefaultSentimentLexicon())
+}
+
+func TestSentimentBasicEmotions(t *testing.T) {
+	a := newTestSentimentAnalyzer()
+
+	cases := map[string]string{
+		"我很想念您，心里很难过": EmotionSad,
+		"感谢您的教诲和恩情":   EmotionGrateful,
+		"愿您安息，一切安好":   EmotionPeaceful,
+		"还记得小时候的往事":   EmotionNostalgic,
+		"看到孩子们幸福快乐":   EmotionHappy,
+	}
+	for text, emotion := range cases {
+		assert.Equal(t, emotion, a.Analyze(text).Emotion, text)
+	}
+}
+
+func TestSentimentNegation(t *testing.T) {
+	a := newTestSentimentAnalyzer()
+
+	result := a.Analyze("妈妈，我不难过")
+	assert.Equal(t, EmotionPeaceful, result.Emotion)
+	assert.Equal(t, 0.0, result.Scores[EmotionSad])
+	assert.Contains(t, result.Keywords, "不难过")
+
+	assert.Equal(t, EmotionSad, a.Analyze("这些年一点也不开心").Emotion)
+
+	// 双重否定还原原义
+	assert.Equal(t, EmotionSad, a.Analyze("没有不想念您的日子").Emotion)
+}
+
+func TestSentimentDegreeAndConfidence(t *testing.T) {
+	a := newTestSentimentAnalyzer()
+
+	strong := a.Analyze("非常难过")
+	weak := a.Analyze("有点难过")
+	assert.Greater(t, strong.Scores[EmotionSad], weak.Scores[EmotionSad])
+	assert.Greater(t, strong.Confidence, weak.Confidence)
+	assert.Less(t, strong.Polarity, 0.0)
+
+	none := a.Analyze("今天天气晴")
+	assert.Equal(t, EmotionPeaceful, none.Emotion)
+	assert.Equal(t, 0.3, none.Confidence)
+	assert.Empty(t, none.Keywords)
+}
+
+func TestSentimentContrast(t *testing.T) {
+	a := newTestSentimentAnalyzer()
+
+	// 转折之后的情感占主导
+	assert.Equal(t, EmotionGrateful, a.Analyze("虽然很难过，但是更感恩您的养育").Emotion)
+}
+
+func TestParseSentimentLexicon(t *testing.T) {
+	lexicon, err := ParseSentimentLexicon(strings.NewReader(`
+# 自定义词典
+[emotion:sad]
+肝肠寸断 1.5
+[degree]
+超级 1.7
+[negation]
+甭
+`))
+	assert.NoError(t, err)
+
+	merged := DefaultSentimentLexicon()
+	merged.Merge(lexicon)
+	a := NewLexiconSentimentAnalyzer(merged)
+
+	result := a.Analyze("超级肝肠寸断")
+	assert.Equal(t, EmotionSad, result.Emotion)
+	assert.InDelta(t, 2.55, result.Scores[EmotionSad], 0.001)
+	assert.Equal(t, EmotionPeaceful, a.Analyze("甭难过").Emotion)
+
+	_, err = ParseSentimentLexicon(strings.NewReader("[emotion:angry]\n生气"))
+	assert.Error(t, err)
+	_, err = ParseSentimentLexicon(strings.NewReader("难过 1"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,42 @@
+package utils
+
+import "time"
+
+// 留言可见范围
+const (
+	MessageAudienceEveryone = "everyone" // 所有人
+	MessageAudienceFamily   = "family"   // 纪念馆创建者和关联家族成员
+	MessageAudienceUsers    = "users"    // 指定用户
+)
+
+// MessageViewer 观看者与留言、纪念馆的关系
+type MessageViewer struct {
+	IsAuthor    bool // 留言作者
+	IsFamily    bool // 纪念馆创建者或关联家族成员
+	IsRecipient bool // 留言的指定接收人
+}
+
+// MessageUnlocked 留言在 now 时是否已解锁，没有解锁时间的普通留言始终已解锁
+func MessageUnlocked(unlockAt *time.Time, now time.Time) bool {
+	return unlockAt == nil || !unlockAt.After(now)
+}
+
+// CanViewMessage 观看者能否看到留言：作者始终可以看到自己的留言，
+// 其他人须留言已解锁且在可见范围内。与 WorshipService.visibleMessages 的查询条件一致
+func CanViewMessage(unlockAt *time.Time, audience string, viewer MessageViewer, now time.Time) bool {
+	if viewer.IsAuthor {
+		return true
+	}
+	if !MessageUnlocked(unlockAt, now) {
+		return false
+	}
+	switch audience {
+	case MessageAudienceEveryone:
+		return true
+	case MessageAudienceFamily:
+		return viewer.IsFamily
+	case MessageAudienceUsers:
+		return viewer.IsRecipient
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageUnlocked(t *testing.T) {
+	now := time.Date(2025, 4, 4, 10, 0, 0, 0, time.Local)
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Minute)
+
+	assert.True(t, MessageUnlocked(nil, now))
+	assert.True(t, MessageUnlocked(&past, now))
+	assert.True(t, MessageUnlocked(&now, now))
+	assert.False(t, MessageUnlocked(&future, now))
+}
+
+func TestCanViewMessage(t *testing.T) {
+	now := time.Date(2025, 4, 4, 10, 0, 0, 0, time.Local)
+	past := now.Add(-time.Hour)
+	future := now.AddDate(1, 0, 0)
+
+	author := MessageViewer{IsAuthor: true}
+	stranger := MessageViewer{}
+	family := MessageViewer{IsFamily: true}
+	recipient := MessageViewer{IsRecipient: true}
+
+	// 未解锁：只有作者可见
+	for _, audience := range []string{MessageAudienceEveryone, MessageAudienceFamily, MessageAudienceUsers} {
+		assert.True(t, CanViewMessage(&future, audience, author, now), audience)
+		assert.False(t, CanViewMessage(&future, audience, stranger, now), audience)
+		assert.False(t, CanViewMessage(&future, audience, family, now), audience)
+		assert.False(t, CanViewMessage(&future, audience, recipient, now), audience)
+	}
+
+	// 已解锁：按可见范围
+	cases := []struct {
+		audience string
+		viewer   MessageViewer
+		want     bool
+	}{
+		{MessageAudienceEveryone, stranger, true},
+		{MessageAudienceEveryone, family, true},
+		{MessageAudienceFamily, stranger, false},
+		{MessageAudienceFamily, family, true},
+		{MessageAudienceFamily, recipient, false},
+		{MessageAudienceUsers, stranger, false},
+		{MessageAudienceUsers, family, false},
+		{MessageAudienceUsers, recipient, true},
+		{MessageAudienceUsers, author, true},
+		{"unknown", stranger, false},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, CanViewMessage(&past, c.audience, c.viewer, now), "%s %+v", c.audience, c.viewer)
+		assert.Equal(t, c.want, CanViewMessage(nil, c.audience, c.viewer, now), "%s %+v", c.audience, c.viewer)
+	}
+}
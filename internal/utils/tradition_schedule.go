@@ -0,0 +1,170 @@
+package utils
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// 家族传统日期的历法
+const (
+	CalendarSolar = "solar" // 公历
+	CalendarLunar = "lunar" // 农历
+)
+
+// 家族传统的重复方式
+const (
+	RecurrenceYearly  = "yearly"  // 每年
+	RecurrenceMonthly = "monthly" // 每月，农历含闰月
+	RecurrenceOnce    = "once"    // 仅一次
+)
+
+// TraditionSchedule 家族传统的日期安排
+//
+// 每年、每月重复时按年月日推算当年、当月的日期，该月没有这一天时取月末，
+// 如农历小月的三十取廿九、公历平年的 2 月 29 日取 28 日。农历每年重复只落在非闰月。
+type TraditionSchedule struct {
+	Calendar   string
+	Recurrence string
+	Year       int // 仅一次时使用，农历为农历年
+	Month      int // 每月重复时不使用
+	Day        int
+}
+
+// Validate 校验日期安排
+func (s TraditionSchedule) Validate() error {
+	if s.Calendar != CalendarSolar && s.Calendar != CalendarLunar {
+		return errors.New("无效的历法")
+	}
+	maxDay := 31
+	if s.Calendar == CalendarLunar {
+		maxDay = 30
+	}
+	if s.Day < 1 || s.Day > maxDay {
+		return errors.New("无效的传统日期")
+	}
+
+	switch s.Recurrence {
+	case RecurrenceMonthly:
+		return nil
+	case RecurrenceYearly:
+		if s.Month < 1 || s.Month > 12 {
+			return errors.New("无效的传统日期")
+		}
+		// 公历按闰年校验，2 月 29 日允许
+		if s.Calendar == CalendarSolar && s.Day > solarMonthDays(2000, s.Month) {
+			return errors.New("无效的传统日期")
+		}
+		return nil
+	case RecurrenceOnce:
+		if s.Year == 0 {
+			return errors.New("仅一次的传统须指定年份")
+		}
+		if _, ok := s.exactDate(); !ok {
+			return errors.New("无效的传统日期")
+		}
+		return nil
+	default:
+		return errors.New("无效的重复方式")
+	}
+}
+
+func (s TraditionSchedule) exactDate() (time.Time, bool) {
+	if s.Calendar == CalendarLunar {
+		return LunarToSolar(LunarDate{Year: s.Year, Month: s.Month, Day: s.Day})
+	}
+	if s.Month < 1 || s.Month > 12 || s.Day > solarMonthDays(s.Year, s.Month) {
+		return time.Time{}, false
+	}
+	return time.Date(s.Year, time.Month(s.Month), s.Day, 0, 0, 0, 0, time.UTC), true
+}
+
+// Occurrences 返回 from 至 to 之间（含两端，只看年月日）的日期，按时间先后排列
+func (s TraditionSchedule) Occurrences(from, to time.Time) []time.Time {
+	start := calendarDay(from)
+	end := calendarDay(to)
+
+	var dates []time.Time
+	add := func(date time.Time, ok bool) {
+		if ok && !date.Before(start) && !date.After(end) {
+			dates = append(dates, date)
+		}
+	}
+
+	switch s.Recurrence {
+	case RecurrenceOnce:
+		add(s.exactDate())
+	case RecurrenceYearly:
+		// 农历年跨公历年，多看前一年
+		for year := start.Year() - 1; year <= end.Year(); year++ {
+			add(s.dateIn(year, s.Month, false))
+		}
+	case RecurrenceMonthly:
+		for year := start.Year() - 1; year <= end.Year(); year++ {
+			for month := 1; month <= 12; month++ {
+				add(s.dateIn(year, month, false))
+				if s.Calendar == CalendarLunar && year >= LunarMinYear && year <= LunarMaxYear && LunarLeapMonth(year) == month {
+					add(s.dateIn(year, month, true))
+				}
+			}
+		}
+	}
+
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+	return dates
+}
+
+// Next 返回 from 当天或之后最近的一次，两年内没有时返回 false
+func (s TraditionSchedule) Next(from time.Time) (time.Time, bool) {
+	dates := s.Occurrences(from, from.AddDate(2, 0, 0))
+	if len(dates) == 0 {
+		return time.Time{}, false
+	}
+	return dates[0], true
+}
+
+// dateIn 指定年月中的这一天，该月没有这一天时取月末
+func (s TraditionSchedule) dateIn(year, month int, leap bool) (time.Time, bool) {
+	if s.Calendar == CalendarLunar {
+		if year < LunarMinYear || year > LunarMaxYear {
+			return time.Time{}, false
+		}
+		day := s.Day
+		if days := LunarMonthDays(year, month, leap); day > days {
+			day = days
+		}
+		return LunarToSolar(LunarDate{Year: year, Month: month, Day: day, IsLeap: leap})
+	}
+	day := s.Day
+	if days := solarMonthDays(year, month); day > days {
+		day = days
+	}
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC), true
+}
+
+// Describe 日期安排的中文描述，如“每年农历正月初一”“每月农历十五”“每年4月5日”
+func (s TraditionSchedule) Describe() string {
+	prefix := map[string]string{RecurrenceYearly: "每年", RecurrenceMonthly: "每月"}[s.Recurrence]
+	if s.Recurrence == RecurrenceOnce {
+		prefix = strconv.Itoa(s.Year) + "年"
+	}
+	if s.Calendar == CalendarLunar {
+		if s.Recurrence == RecurrenceMonthly {
+			return prefix + "农历" + LunarDayName(s.Day)
+		}
+		return prefix + "农历" + LunarMonthName(s.Month, false) + LunarDayName(s.Day)
+	}
+	if s.Recurrence == RecurrenceMonthly {
+		return prefix + strconv.Itoa(s.Day) + "日"
+	}
+	return prefix + strconv.Itoa(s.Month) + "月" + strconv.Itoa(s.Day) + "日"
+}
+
+func calendarDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func solarMonthDays(year, month int) int {
+	return time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
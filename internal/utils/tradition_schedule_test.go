@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scheduleDates(dates []time.Time) []string {
+	out := make([]string, 0, len(dates))
+	for _, d := range dates {
+		out = append(out, d.Format("2006-01-02"))
+	}
+	return out
+}
+
+func TestTraditionScheduleValidate(t *testing.T) {
+	assert.NoError(t, TraditionSchedule{Calendar: CalendarLunar, Recurrence: RecurrenceYearly, Month: 12, Day: 30}.Validate())
+	assert.NoError(t, TraditionSchedule{Calendar: CalendarSolar, Recurrence: RecurrenceYearly, Month: 2, Day: 29}.Validate())
+	assert.NoError(t, TraditionSchedule{Calendar: CalendarLunar, Recurrence: RecurrenceMonthly, Day: 15}.Validate())
+
+	assert.EqualError(t, TraditionSchedule{Calendar: "islamic", Recurrence: RecurrenceYearly, Month: 1, Day: 1}.Validate(), "无效的历法")
+	assert.EqualError(t, TraditionSchedule{Calendar: CalendarSolar, Recurrence: "weekly", Month: 1, Day: 1}.Validate(), "无效的重复方式")
+	assert.EqualError(t, TraditionSchedule{Calendar: CalendarSolar, Recurrence: RecurrenceYearly, Month: 4, Day: 31}.Validate(), "无效的传统日期")
+	assert.EqualError(t, TraditionSchedule{Calendar: CalendarLunar, Recurrence: RecurrenceYearly, Month: 1, Day: 31}.Validate(), "无效的传统日期")
+	assert.EqualError(t, TraditionSchedule{Calendar: CalendarSolar, Recurrence: RecurrenceOnce, Month: 1, Day: 1}.Validate(), "仅一次的传统须指定年份")
+	assert.EqualError(t, TraditionSchedule{Calendar: CalendarLunar, Recurrence: RecurrenceOnce, Year: 2024, Month: 12, Day: 30}.Validate(), "无效的传统日期")
+}
+
+func TestTraditionScheduleOccurrences(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	// 除夕祭祖：2024 年腊月小月，取廿九
+	eve := TraditionSchedule{Calendar: CalendarLunar, Recurrence: RecurrenceYearly, Month: 12, Day: 30}
+	assert.Equal(t, []string{"2024-02-09", "2025-01-28"}, scheduleDates(eve.Occurrences(from, to)))
+
+	// 2 月 29 日在平年取 28 日
+	leapDay := TraditionSchedule{Calendar: CalendarSolar, Recurrence: RecurrenceYearly, Month: 2, Day: 29}
+	assert.Equal(t, []string{"2024-02-29", "2025-02-28"}, scheduleDates(leapDay.Occurrences(from, to)))
+
+	// 每月农历十五含闰六月
+	full := TraditionSchedule{Calendar: CalendarLunar, Recurrence: RecurrenceMonthly, Day: 15}
+	dates := scheduleDates(full.Occurrences(time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 9, 30, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, []string{"2025-07-09", "2025-08-08", "2025-09-06"}, dates)
+
+	once := TraditionSchedule{Calendar: CalendarSolar, Recurrence: RecurrenceOnce, Year: 2024, Month: 10, Day: 1}
+	assert.Equal(t, []string{"2024-10-01"}, scheduleDates(once.Occurrences(from, to)))
+	assert.Empty(t, once.Occurrences(to, to.AddDate(1, 0, 0)))
+}
+
+func TestTraditionScheduleNext(t *testing.T) {
+	newYear := TraditionSchedule{Calendar: CalendarLunar, Recurrence: RecurrenceYearly, Month: 1, Day: 1}
+	next, ok := newYear.Next(time.Date(2025, 1, 29, 23, 0, 0, 0, time.UTC))
+	require.True(t, ok)
+	assert.Equal(t, "2025-01-29", next.Format("2006-01-02"), "当天也算")
+
+	next, ok = newYear.Next(time.Date(2025, 1, 30, 0, 0, 0, 0, time.UTC))
+	require.True(t, ok)
+	assert.Equal(t, "2026-02-17", next.Format("2006-01-02"))
+
+	assert.Equal(t, "每年农历正月初一", newYear.Describe())
+	assert.Equal(t, "每月农历十五", TraditionSchedule{Calendar: CalendarLunar, Recurrence: RecurrenceMonthly, Day: 15}.Describe())
+	assert.Equal(t, "每年4月5日", TraditionSchedule{Calendar: CalendarSolar, Recurrence: RecurrenceYearly, Month: 4, Day: 5}.Describe())
+}
@@ -0,0 +1,170 @@
+package utils
+
+import (
+	"sort"
+	"time"
+)
+
+// RollupDateLayout 汇总表统计日期格式
+const RollupDateLayout = "2006-01-02"
+
+// WorshipEvent 参与汇总的一条祭扫记录
+type WorshipEvent struct {
+	MemorialID  string
+	UserID      string
+	WorshipType string
+	CreatedAt   time.Time
+}
+
+// WorshipRollupKey 汇总行的维度：纪念馆或用户、日期、小时、祭扫类型
+type WorshipRollupKey struct {
+	OwnerID     string
+	Date        string
+	Hour        int
+	WorshipType string
+}
+
+// WorshipRollupCounts 纪念馆汇总行的数值
+// Visitors 为当日首次祭扫落在该行的访客数，同一日期各行相加即为当日独立访客数
+type WorshipRollupCounts struct {
+	Worships int64
+	Visitors int64
+}
+
+// MemorialVisitorKey 纪念馆与访客
+type MemorialVisitorKey struct {
+	MemorialID string
+	UserID     string
+}
+
+// MemorialVisitorTotals 访客在纪念馆的累计祭扫
+type MemorialVisitorTotals struct {
+	Worships int64
+	FirstAt  time.Time
+	LastAt   time.Time
+}
+
+type visitorDay struct {
+	MemorialID string
+	UserID     string
+	Date       string
+}
+
+// WorshipRollupAccumulator 由原始祭扫记录计算汇总数据，用于回填和一致性校验；
+// 记录按时间顺序加入时，访客数归入当日首次祭扫所在的行，与增量维护的结果一致
+type WorshipRollupAccumulator struct {
+	Memorial map[WorshipRollupKey]*WorshipRollupCounts
+	User     map[WorshipRollupKey]int64
+	Visitors map[MemorialVisitorKey]*MemorialVisitorTotals
+
+	seen map[visitorDay]struct{}
+}
+
+// NewWorshipRollupAccumulator 创建汇总计算器
+func NewWorshipRollupAccumulator() *WorshipRollupAccumulator {
+	return &WorshipRollupAccumulator{
+		Memorial: make(map[WorshipRollupKey]*WorshipRollupCounts),
+		User:     make(map[WorshipRollupKey]int64),
+		Visitors: make(map[MemorialVisitorKey]*MemorialVisitorTotals),
+		seen:     make(map[visitorDay]struct{}),
+	}
+}
+
+// Add 加入一条祭扫记录
+func (a *WorshipRollupAccumulator) Add(e WorshipEvent) {
+	date := e.CreatedAt.Format(RollupDateLayout)
+	hour := e.CreatedAt.Hour()
+
+	memorialKey := WorshipRollupKey{OwnerID: e.MemorialID, Date: date, Hour: hour, WorshipType: e.WorshipType}
+	counts := a.Memorial[memorialKey]
+	if counts == nil {
+		counts = &WorshipRollupCounts{}
+		a.Memorial[memorialKey] = counts
+	}
+	counts.Worships++
+
+	day := visitorDay{MemorialID: e.MemorialID, UserID: e.UserID, Date: date}
+	if _, ok := a.seen[day]; !ok {
+		a.seen[day] = struct{}{}
+		counts.Visitors++
+	}
+
+	a.User[WorshipRollupKey{OwnerID: e.UserID, Date: date, Hour: hour, WorshipType: e.WorshipType}]++
+
+	visitorKey := MemorialVisitorKey{MemorialID: e.MemorialID, UserID: e.UserID}
+	totals := a.Visitors[visitorKey]
+	if totals == nil {
+		totals = &MemorialVisitorTotals{FirstAt: e.CreatedAt, LastAt: e.CreatedAt}
+		a.Visitors[visitorKey] = totals
+	}
+	totals.Worships++
+	if e.CreatedAt.Before(totals.FirstAt) {
+		totals.FirstAt = e.CreatedAt
+	}
+	if e.CreatedAt.After(totals.LastAt) {
+		totals.LastAt = e.CreatedAt
+	}
+}
+
+// MemorialWorshipCounts 纪念馆汇总的祭扫次数
+func (a *WorshipRollupAccumulator) MemorialWorshipCounts() map[WorshipRollupKey]int64 {
+	result := make(map[WorshipRollupKey]int64, len(a.Memorial))
+	for key, counts := range a.Memorial {
+		result[key] = counts.Worships
+	}
+	return result
+}
+
+// MemorialDailyVisitors 纪念馆每日独立访客数，键中只有 OwnerID 和 Date
+func (a *WorshipRollupAccumulator) MemorialDailyVisitors() map[WorshipRollupKey]int64 {
+	return SumDailyVisitors(a.Memorial)
+}
+
+// SumDailyVisitors 按纪念馆和日期汇总访客数
+func SumDailyVisitors(rows map[WorshipRollupKey]*WorshipRollupCounts) map[WorshipRollupKey]int64 {
+	result := make(map[WorshipRollupKey]int64)
+	for key, counts := range rows {
+		if counts.Visitors == 0 {
+			continue
+		}
+		result[WorshipRollupKey{OwnerID: key.OwnerID, Date: key.Date}] += counts.Visitors
+	}
+	return result
+}
+
+// WorshipRollupDiff 汇总数据与原始记录不一致的一项
+type WorshipRollupDiff struct {
+	Key      WorshipRollupKey `json:"key"`
+	Expected int64            `json:"expected"` // 按原始记录计算的值
+	Actual   int64            `json:"actual"`   // 汇总表中的值
+}
+
+// DiffWorshipRollups 比较按原始记录计算的值与汇总表中的值，缺失视为0，结果按维度排序
+func DiffWorshipRollups(expected, actual map[WorshipRollupKey]int64) []WorshipRollupDiff {
+	var diffs []WorshipRollupDiff
+	for key, value := range expected {
+		if actual[key] != value {
+			diffs = append(diffs, WorshipRollupDiff{Key: key, Expected: value, Actual: actual[key]})
+		}
+	}
+	for key, value := range actual {
+		if _, ok := expected[key]; !ok && value != 0 {
+			diffs = append(diffs, WorshipRollupDiff{Key: key, Expected: 0, Actual: value})
+		}
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		a, b := diffs[i].Key, diffs[j].Key
+		if a.OwnerID != b.OwnerID {
+			return a.OwnerID < b.OwnerID
+		}
+		if a.Date != b.Date {
+			return a.Date < b.Date
+		}
+		if a.Hour != b.Hour {
+			return a.Hour < b.Hour
+		}
+		return a.WorshipType < b.WorshipType
+	})
+	return diffs
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorshipRollupAccumulator(t *testing.T) {
+	day1 := time.Date(2024, 4, 4, 9, 30, 0, 0, time.Local)
+	day2 := time.Date(2024, 4, 5, 20, 0, 0, 0, time.Local)
+
+	acc := NewWorshipRollupAccumulator()
+	acc.Add(WorshipEvent{MemorialID: "m1", UserID: "u1", WorshipType: "flower", CreatedAt: day1})
+	acc.Add(WorshipEvent{MemorialID: "m1", UserID: "u1", WorshipType: "candle", CreatedAt: day1.Add(2 * time.Hour)})
+	acc.Add(WorshipEvent{MemorialID: "m1", UserID: "u2", WorshipType: "flower", CreatedAt: day1.Add(10 * time.Minute)})
+	acc.Add(WorshipEvent{MemorialID: "m1", UserID: "u1", WorshipType: "flower", CreatedAt: day2})
+	acc.Add(WorshipEvent{MemorialID: "m2", UserID: "u1", WorshipType: "incense", CreatedAt: day2})
+
+	flower := WorshipRollupKey{OwnerID: "m1", Date: "2024-04-04", Hour: 9, WorshipType: "flower"}
+	require.Contains(t, acc.Memorial, flower)
+	assert.Equal(t, int64(2), acc.Memorial[flower].Worships)
+	assert.Equal(t, int64(2), acc.Memorial[flower].Visitors)
+	// u1 当日已计入访客，11点的点烛不再计数
+	candle := WorshipRollupKey{OwnerID: "m1", Date: "2024-04-04", Hour: 11, WorshipType: "candle"}
+	assert.Equal(t, int64(1), acc.Memorial[candle].Worships)
+	assert.Equal(t, int64(0), acc.Memorial[candle].Visitors)
+
+	visitors := acc.MemorialDailyVisitors()
+	assert.Equal(t, int64(2), visitors[WorshipRollupKey{OwnerID: "m1", Date: "2024-04-04"}])
+	assert.Equal(t, int64(1), visitors[WorshipRollupKey{OwnerID: "m1", Date: "2024-04-05"}])
+	assert.Equal(t, int64(1), visitors[WorshipRollupKey{OwnerID: "m2", Date: "2024-04-05"}])
+
+	assert.Equal(t, int64(2), acc.User[WorshipRollupKey{OwnerID: "u1", Date: "2024-04-05", Hour: 20, WorshipType: "flower"}]+
+		acc.User[WorshipRollupKey{OwnerID: "u1", Date: "2024-04-05", Hour: 20, WorshipType: "incense"}])
+
+	totals := acc.Visitors[MemorialVisitorKey{MemorialID: "m1", UserID: "u1"}]
+	require.NotNil(t, totals)
+	assert.Equal(t, int64(3), totals.Worships)
+	assert.Equal(t, day1, totals.FirstAt)
+	assert.Equal(t, day2, totals.LastAt)
+	assert.Len(t, acc.Visitors, 3)
+}
+
+func TestDiffWorshipRollups(t *testing.T) {
+	a := WorshipRollupKey{OwnerID: "m1", Date: "2024-04-04", Hour: 9, WorshipType: "flower"}
+	b := WorshipRollupKey{OwnerID: "m1", Date: "2024-04-04", Hour: 10, WorshipType: "flower"}
+	c := WorshipRollupKey{OwnerID: "m2", Date: "2024-04-04", Hour: 9, WorshipType: "candle"}
+
+	expected := map[WorshipRollupKey]int64{a: 3, b: 1}
+	actual := map[WorshipRollupKey]int64{a: 3, c: 2}
+
+	diffs := DiffWorshipRollups(expected, actual)
+	require.Len(t, diffs, 2)
+	assert.Equal(t, WorshipRollupDiff{Key: b, Expected: 1, Actual: 0}, diffs[0])
+	assert.Equal(t, WorshipRollupDiff{Key: c, Expected: 0, Actual: 2}, diffs[1])
+
+	assert.Empty(t, DiffWorshipRollups(expected, map[WorshipRollupKey]int64{a: 3, b: 1, c: 0}))
+}